@@ -20,6 +20,9 @@ import (
 	"github.com/psschand/callcenter/internal/middleware"
 	"github.com/psschand/callcenter/internal/repository"
 	"github.com/psschand/callcenter/internal/service"
+	"github.com/psschand/callcenter/internal/sms"
+	"github.com/psschand/callcenter/internal/storage"
+	"github.com/psschand/callcenter/internal/transcription"
 	ws "github.com/psschand/callcenter/internal/websocket"
 	"github.com/psschand/callcenter/pkg/jwt"
 	"github.com/psschand/callcenter/pkg/response"
@@ -67,8 +70,11 @@ func main() {
 	didRepo := repository.NewDIDRepository(db)
 	queueRepo := repository.NewQueueRepository(db)
 	queueMemberRepo := repository.NewQueueMemberRepository(db)
+	ivrRepo := repository.NewIVRMenuRepository(db)
 	cdrRepo := repository.NewCDRRepository(db)
 	agentStateRepo := repository.NewAgentStateRepository(db)
+	agentHistoryRepo := repository.NewAgentStateHistoryRepository(db)
+	breakReasonRepo := repository.NewAgentBreakReasonRepository(db)
 	ticketRepo := repository.NewTicketRepository(db)
 	ticketMessageRepo := repository.NewTicketMessageRepository(db)
 	contactRepo := repository.NewContactRepository(db)
@@ -78,6 +84,21 @@ func main() {
 	chatAgentRepo := repository.NewChatAgentRepository(db)
 	chatTransferRepo := repository.NewChatTransferRepository(db)
 	webhookRepo := repository.NewWebhookRepository(db)
+	monitorRepo := repository.NewMonitorSessionRepository(db)
+	recordingRepo := repository.NewCallRecordingRepository(db)
+	voicemailRepo := repository.NewVoicemailRepository(db)
+	mailboxRepo := repository.NewVoicemailMailboxRepository(db)
+	transcriptionJobRepo := repository.NewTranscriptionJobRepository(db)
+	transcriptRepo := repository.NewTranscriptRepository(db)
+	smsRepo := repository.NewSMSMessageRepository(db)
+	psEndpointRepo := repository.NewPsEndpointRepository(db)
+	psAuthRepo := repository.NewPsAuthRepository(db)
+	trunkRepo := repository.NewSIPTrunkRepository(db)
+	outboundRouteRepo := repository.NewOutboundRouteRepository(db)
+	blacklistRepo := repository.NewBlacklistRepository(db)
+	scheduleRepo := repository.NewScheduleRepository(db)
+	surveyRepo := repository.NewSurveyRepository(db)
+	queueCallbackRepo := repository.NewQueueCallbackRepository(db)
 
 	log.Println("Repositories initialized")
 
@@ -122,6 +143,9 @@ func main() {
 	// log.Println("Event broadcaster initialized (WebSocket + Webhooks)")
 	_ = webhookRepo // Mark as intentionally unused for now
 
+	// Call events are broadcast to WebSocket clients
+	eventBroadcaster := ws.NewEventBroadcaster(hub)
+
 	// Initialize Asterisk ARI client and handler
 	ariClient := asterisk.NewARIClient(
 		cfg.Asterisk.ARIURL,
@@ -132,38 +156,127 @@ func main() {
 
 	callHandler := asterisk.NewCallHandler(ariClient)
 
-	// Add event handler to broadcast call events via WebSocket
-	callHandler.AddEventHandler(func(event asterisk.ARIEvent) {
-		// Broadcast ARI events to WebSocket clients
-		// You can filter or transform events here
-		log.Printf("Broadcasting ARI event: %s", event.Type)
+	// Route inbound calls by the dialed DID
+	callHandler.SetDIDResolver(didRepo)
+	callHandler.SetIVRMenuLoader(ivrRepo)
+	callHandler.SetMailboxLoader(mailboxRepo)
+	callHandler.SetQueueStore(queueRepo, queueMemberRepo, agentStateRepo)
+	callHandler.SetQueueCallbackStore(queueCallbackRepo)
+	callHandler.SetOutboundRouteLoader(outboundRouteRepo)
+	callHandler.SetScheduleLoader(scheduleRepo)
+	callHandler.SetSurveyLoader(surveyRepo)
+	callHandler.SetRoutingConfig(asterisk.RoutingConfig{
+		TrunkEndpoint:        cfg.Asterisk.TrunkEndpoint,
+		DialTimeout:          cfg.Asterisk.DialTimeout,
+		UnknownDIDTreatment:  cfg.Asterisk.UnknownDIDTreatment,
+		InactiveDIDTreatment: cfg.Asterisk.InactiveDIDTreatment,
+		RejectSound:          cfg.Asterisk.RejectSound,
 	})
 
-	// Start ARI call handler
+	// Initialize recording and voicemail storage
+	urlSigner := storage.NewURLSigner(cfg.Storage.SigningKey, cfg.Storage.PublicURL)
+	fileStore, err := storage.New(cfg.Storage, urlSigner)
+	if err != nil {
+		log.Fatalf("Failed to initialize storage: %v", err)
+	}
+	log.Printf("Recording storage initialized (%s backend)", fileStore.Backend())
+
+	// Initialize speech-to-text
+	transcriptionProvider, err := transcription.New(cfg.Transcription)
+	if err != nil {
+		log.Fatalf("Failed to initialize transcription: %v", err)
+	}
+
+	// Initialize SMS
+	smsProvider, err := sms.New(cfg.SMS)
+	if err != nil {
+		log.Fatalf("Failed to initialize SMS: %v", err)
+	}
+
+	// Initialize services
+	tenantService := service.NewTenantService(tenantRepo)
+	softphoneService := service.NewSoftphoneService(psEndpointRepo, psAuthRepo, userRepo, roleRepo, service.SoftphoneConfig{
+		WSSURL:         cfg.Softphone.WSSURL,
+		Domain:         cfg.Softphone.Domain,
+		Transport:      cfg.Softphone.Transport,
+		Context:        cfg.Softphone.Context,
+		Codecs:         cfg.Softphone.Codecs,
+		MaxContacts:    cfg.Softphone.MaxContacts,
+		ICEServers:     cfg.Softphone.ICEServers,
+		TURNUsername:   cfg.Softphone.TURNUsername,
+		TURNCredential: cfg.Softphone.TURNCredential,
+	})
+	authService := service.NewAuthService(userRepo, tenantRepo, roleRepo, jwtService, softphoneService)
+	userService := service.NewUserService(userRepo, roleRepo, tenantRepo, softphoneService)
+	didService := service.NewDIDService(didRepo, tenantRepo, queueRepo, userRepo, ivrRepo, scheduleRepo, surveyRepo)
+	queueService := service.NewQueueService(queueRepo, queueMemberRepo, tenantRepo, userRepo, roleRepo, scheduleRepo, surveyRepo)
+	scheduleService := service.NewScheduleService(scheduleRepo, roleRepo)
+	ivrService := service.NewIVRService(ivrRepo, tenantRepo, queueRepo)
+	agentStateService := service.NewAgentStateService(agentStateRepo, agentHistoryRepo, breakReasonRepo, userRepo, roleRepo, queueRepo, queueMemberRepo, tenantRepo, cdrRepo, chatSessionRepo, eventBroadcaster)
+	if err := agentStateService.ResumeWrapups(context.Background()); err != nil {
+		log.Printf("Warning: failed to resume agent wrap-ups: %v", err)
+	}
+	agentReportService := service.NewAgentReportService(agentHistoryRepo, breakReasonRepo, userRepo, roleRepo, chatAgentRepo)
+	ticketService := service.NewTicketService(ticketRepo, ticketMessageRepo, contactRepo, userRepo)
+	transcriptionService := service.NewTranscriptionService(transcriptionJobRepo, transcriptRepo, tenantRepo, roleRepo, transcriptionProvider)
+	recordingService := service.NewRecordingService(recordingRepo, cdrRepo, tenantRepo, queueRepo, roleRepo, fileStore, transcriptionService, cfg.Asterisk.RecordingPath, cfg.Storage.SignedURLExpiry)
+	callHandler.SetRecordingPolicy(recordingService.ShouldRecord)
+	callHandler.SetRecordingListener(recordingService.OnRecording)
+	cdrService := service.NewCDRService(cdrRepo, userRepo, roleRepo, recordingService)
+	callHandler.SetCallDetailListener(cdrService.OnCallDetail)
+	surveyService := service.NewSurveyService(surveyRepo, cdrRepo, userRepo, roleRepo)
+	queueCallbackService := service.NewQueueCallbackService(queueCallbackRepo, didRepo, roleRepo, callHandler)
+	callHandler.SetSurveyListener(surveyService.OnSurveyResult)
+	callService := service.NewCallService(callHandler, userRepo, roleRepo, didRepo, queueRepo, cdrRepo, recordingService, agentStateService, eventBroadcaster)
+	callHandler.SetOutboundCallListener(callService.OnOutboundCall)
+	callHandler.SetCallEventListener(callService.OnCallEvent)
+	trunkService := service.NewTrunkService(trunkRepo, outboundRouteRepo, psEndpointRepo, roleRepo, callHandler)
+	dialplanService := service.NewDialplanService(didRepo, roleRepo, callHandler, service.DialplanConfig{
+		Path:    cfg.Asterisk.DialplanPath,
+		AppName: cfg.Asterisk.AppName,
+	})
+	didService.SetChangeListener(dialplanService.OnDIDChanged)
+	monitorService := service.NewMonitorService(callHandler, monitorRepo, roleRepo)
+	callHandler.SetMonitorListener(monitorService.OnMonitorSession)
+	blacklistService := service.NewBlacklistService(blacklistRepo, roleRepo)
+	callHandler.SetCallerScreen(blacklistService.ScreenCall)
+	smsService := service.NewSMSService(smsRepo, didRepo, tenantRepo, roleRepo, smsProvider, cfg.SMS.SegmentCost, eventBroadcaster, webhookManager)
+	voicemailService := service.NewVoicemailService(voicemailRepo, mailboxRepo, didRepo, roleRepo, fileStore, transcriptionService, cfg.Asterisk.RecordingPath, eventBroadcaster)
+	callHandler.SetVoicemailListener(voicemailService.OnVoicemail)
+
+	// Start ARI call handler once every listener is set, so that no call
+	// arriving at startup goes unrecorded or unscreened
 	ariCtx, ariCancel := context.WithCancel(context.Background())
 	defer ariCancel()
 
 	if err := callHandler.Start(ariCtx); err != nil {
-		log.Printf("Warning: Failed to start ARI handler: %v (call features disabled)", err)
+		log.Printf("Warning: Failed to connect to ARI: %v (retrying in background)", err)
 	} else {
 		log.Println("Asterisk ARI handler started successfully")
 	}
+	if err := monitorService.CloseOrphaned(context.Background()); err != nil {
+		log.Printf("Warning: failed to close orphaned monitor sessions: %v", err)
+	}
+	if err := queueCallbackService.RestoreOrphaned(context.Background()); err != nil {
+		log.Printf("Warning: failed to restore open queue callbacks: %v", err)
+	}
 
-	// Initialize services
-	authService := service.NewAuthService(userRepo, tenantRepo, roleRepo, jwtService)
-	tenantService := service.NewTenantService(tenantRepo)
-	userService := service.NewUserService(userRepo, roleRepo, tenantRepo)
-	didService := service.NewDIDService(didRepo, tenantRepo, queueRepo, userRepo)
-	queueService := service.NewQueueService(queueRepo, queueMemberRepo, tenantRepo, userRepo, roleRepo)
-	cdrService := service.NewCDRService(cdrRepo, userRepo)
-	agentStateService := service.NewAgentStateService(agentStateRepo, userRepo)
-	ticketService := service.NewTicketService(ticketRepo, ticketMessageRepo, contactRepo, userRepo)
-	chatService := service.NewChatService(chatWidgetRepo, chatSessionRepo, chatMessageRepo, chatAgentRepo, chatTransferRepo, userRepo)
-
-	// Set WebSocket hub for real-time chat updates
-	hubAdapter := ws.NewHubAdapter(hub)
-	chatService.SetWebSocketHub(hubAdapter)
-	log.Println("Chat service configured with WebSocket support")
+	storageArchiver := service.NewStorageArchiver(recordingRepo, voicemailRepo, cdrRepo, tenantRepo, fileStore, transcriptionService, service.StorageArchiverConfig{
+		UploadInterval:    cfg.Storage.UploadInterval,
+		RetentionInterval: cfg.Storage.RetentionInterval,
+		PurgeSpool:        cfg.Storage.PurgeSpool,
+	})
+	archiverCtx, archiverCancel := context.WithCancel(context.Background())
+	defer archiverCancel()
+	storageArchiver.Start(archiverCtx)
+	if transcriptionProvider != nil {
+		service.NewTranscriber(transcriptionJobRepo, transcriptRepo, recordingRepo, voicemailRepo, tenantRepo, transcriptionProvider, fileStore, service.TranscriberConfig{
+			Workers:      cfg.Transcription.Workers,
+			PollInterval: cfg.Transcription.PollInterval,
+			MaxAttempts:  cfg.Transcription.MaxAttempts,
+			Language:     cfg.Transcription.Language,
+		}).Start(archiverCtx)
+	}
 
 	// Initialize AI Chat Services (Gemini + RAG)
 	geminiAPIKey := os.Getenv("GEMINI_API_KEY")
@@ -171,6 +284,17 @@ func main() {
 		log.Printf("Warning: GEMINI_API_KEY not set - AI chat features will be disabled")
 	}
 	aiAgentService := chat.NewAIAgentService(db, geminiAPIKey)
+
+	chatService := service.NewChatService(chatWidgetRepo, chatSessionRepo, chatMessageRepo, chatAgentRepo, chatTransferRepo, userRepo, scheduleRepo, agentStateService, smsService, aiAgentService)
+	smsService.SetInboundListener(chatService.OnInboundSMS)
+	smsService.SetStatusListener(chatService.OnSMSStatus)
+	smsService.SetSenderScreen(blacklistService.ScreenSMS)
+
+	// Set WebSocket hub for real-time chat updates
+	hubAdapter := ws.NewHubAdapter(hub)
+	chatService.SetWebSocketHub(hubAdapter)
+	log.Println("Chat service configured with WebSocket support")
+
 	aiChatService := chat.NewChatService(db, aiAgentService)
 	knowledgeBaseService := chat.NewKnowledgeBaseService(db, aiAgentService)
 	log.Println("AI Chat services initialized (Gemini + RAG)")
@@ -183,8 +307,24 @@ func main() {
 	userHandler := handler.NewUserHandler(userService)
 	didHandler := handler.NewDIDHandler(didService)
 	queueHandler := handler.NewQueueHandler(queueService)
+	ivrHandler := handler.NewIVRHandler(ivrService)
 	cdrHandler := handler.NewCDRHandler(cdrService)
+	callsHandler := handler.NewCallHandler(callService)
+	monitorHandler := handler.NewMonitorHandler(monitorService)
+	recordingHandler := handler.NewRecordingHandler(recordingService)
+	voicemailHandler := handler.NewVoicemailHandler(voicemailService)
+	transcriptHandler := handler.NewTranscriptHandler(transcriptionService, recordingService, voicemailService)
+	smsHandler := handler.NewSMSHandler(smsService, smsProvider)
+	softphoneHandler := handler.NewSoftphoneHandler(softphoneService)
+	trunkHandler := handler.NewTrunkHandler(trunkService)
+	dialplanHandler := handler.NewDialplanHandler(dialplanService)
+	blacklistHandler := handler.NewBlacklistHandler(blacklistService)
+	scheduleHandler := handler.NewScheduleHandler(scheduleService)
+	surveyHandler := handler.NewSurveyHandler(surveyService)
+	queueCallbackHandler := handler.NewQueueCallbackHandler(queueCallbackService)
+	fileHandler := handler.NewFileHandler(fileStore, urlSigner)
 	agentStateHandler := handler.NewAgentStateHandler(agentStateService)
+	agentReportHandler := handler.NewAgentReportHandler(agentReportService)
 	ticketHandler := handler.NewTicketHandler(ticketService)
 	chatHandler := handler.NewChatHandler(chatService)
 	webhookHandler := handler.NewWebhookHandler(webhookRepo, webhookManager)
@@ -222,11 +362,20 @@ func main() {
 			return
 		}
 
+		// Calls cannot be handled while ARI is disconnected or reconnecting,
+		// but the rest of the API still works, so it is reported as degraded
+		// rather than failing the check
 		stats, _ := database.GetStats()
+		ariStatus := callHandler.ConnectionStatus()
+		status := "ok"
+		if ariStatus.State != asterisk.ConnectionStateConnected {
+			status = "degraded"
+		}
 		response.Success(c, gin.H{
-			"status":   "ok",
+			"status":   status,
 			"database": "connected",
 			"stats":    stats,
+			"ari":      ariStatus,
 		})
 	})
 
@@ -251,8 +400,19 @@ func main() {
 			publicChat.POST("/end", publicChatHandler.EndSession)
 			publicChat.GET("/session/:session_id", publicChatHandler.GetSessionHistory)
 			publicChat.GET("/status/:session_id", publicChatHandler.GetSessionStatus)
+			publicChat.GET("/widget/:widget_key/status", publicChatHandler.GetWidgetStatus)
+		}
+
+		// SMS provider callbacks (authenticated by the provider's signature)
+		smsWebhooks := v1.Group("/sms/webhooks")
+		{
+			smsWebhooks.POST("/inbound", smsHandler.Inbound)
+			smsWebhooks.POST("/status", smsHandler.Status)
 		}
 
+		// Signed file downloads (the URL's signature stands in for auth)
+		v1.GET("/files/*key", fileHandler.Download)
+
 		// Protected auth routes
 		authProtected := v1.Group("/auth")
 		authProtected.Use(middleware.Auth(jwtService))
@@ -293,6 +453,35 @@ func main() {
 				users.PUT("/:id/role", userHandler.UpdateRole)
 				users.POST("/:id/activate", userHandler.Activate)
 				users.POST("/:id/deactivate", userHandler.Deactivate)
+				users.POST("/:id/softphone/rotate-password", softphoneHandler.RotateUserPassword)
+			}
+
+			// Softphone routes
+			softphone := protected.Group("/softphone")
+			{
+				softphone.GET("", softphoneHandler.GetConfig)
+				softphone.POST("/rotate-password", softphoneHandler.RotatePassword)
+			}
+
+			// SIP trunk routes
+			trunks := protected.Group("/trunks")
+			{
+				trunks.GET("", trunkHandler.ListTrunks)
+				trunks.POST("", trunkHandler.CreateTrunk)
+				trunks.GET("/:id", trunkHandler.GetTrunk)
+				trunks.PUT("/:id", trunkHandler.UpdateTrunk)
+				trunks.DELETE("/:id", trunkHandler.DeleteTrunk)
+				trunks.GET("/:id/status", trunkHandler.GetTrunkStatus)
+			}
+
+			// Outbound route routes
+			outboundRoutes := protected.Group("/outbound-routes")
+			{
+				outboundRoutes.GET("", trunkHandler.ListRoutes)
+				outboundRoutes.POST("", trunkHandler.CreateRoute)
+				outboundRoutes.GET("/:id", trunkHandler.GetRoute)
+				outboundRoutes.PUT("/:id", trunkHandler.UpdateRoute)
+				outboundRoutes.DELETE("/:id", trunkHandler.DeleteRoute)
 			}
 
 			// DID routes
@@ -308,11 +497,56 @@ func main() {
 				dids.GET("/available", didHandler.GetAvailable)
 			}
 
+			// Dialplan routes
+			dialplan := protected.Group("/dialplan")
+			{
+				dialplan.GET("/preview", dialplanHandler.Preview)
+				dialplan.POST("/apply", dialplanHandler.Apply)
+			}
+
+			// Blacklist routes
+			blacklist := protected.Group("/blacklist")
+			{
+				blacklist.GET("", blacklistHandler.List)
+				blacklist.POST("", blacklistHandler.Create)
+				blacklist.POST("/import", blacklistHandler.Import)
+				blacklist.GET("/attempts", blacklistHandler.ListAttempts)
+				blacklist.GET("/:id", blacklistHandler.Get)
+				blacklist.PUT("/:id", blacklistHandler.Update)
+				blacklist.DELETE("/:id", blacklistHandler.Delete)
+			}
+
+			// Schedule routes
+			schedules := protected.Group("/schedules")
+			{
+				schedules.GET("", scheduleHandler.List)
+				schedules.POST("", scheduleHandler.Create)
+				schedules.GET("/:id", scheduleHandler.Get)
+				schedules.PUT("/:id", scheduleHandler.Update)
+				schedules.DELETE("/:id", scheduleHandler.Delete)
+				schedules.GET("/:id/status", scheduleHandler.Status)
+			}
+
+			// Post-call survey routes
+			surveys := protected.Group("/surveys")
+			{
+				surveys.GET("", surveyHandler.List)
+				surveys.POST("", surveyHandler.Create)
+				surveys.GET("/responses", surveyHandler.ListResponses)
+				surveys.GET("/reports/scores", surveyHandler.Report)
+				surveys.GET("/:id", surveyHandler.Get)
+				surveys.PUT("/:id", surveyHandler.Update)
+				surveys.DELETE("/:id", surveyHandler.Delete)
+			}
+
 			// Queue routes
 			queues := protected.Group("/queues")
 			{
 				queues.POST("", queueHandler.Create)
 				queues.GET("", queueHandler.List)
+				queues.GET("/callbacks", queueCallbackHandler.List)
+				queues.GET("/callbacks/:callbackId", queueCallbackHandler.Get)
+				queues.POST("/callbacks/:callbackId/cancel", queueCallbackHandler.Cancel)
 				queues.GET("/:id", queueHandler.Get)
 				queues.PUT("/:id", queueHandler.Update)
 				queues.DELETE("/:id", queueHandler.Delete)
@@ -322,6 +556,93 @@ func main() {
 				queues.PUT("/members/:memberId", queueHandler.UpdateMember)
 			}
 
+			// IVR routes
+			ivr := protected.Group("/ivr-menus")
+			{
+				ivr.POST("", ivrHandler.Create)
+				ivr.GET("", ivrHandler.List)
+				ivr.GET("/:id", ivrHandler.Get)
+				ivr.PUT("/:id", ivrHandler.Update)
+				ivr.DELETE("/:id", ivrHandler.Delete)
+				ivr.POST("/:id/options", ivrHandler.AddOption)
+				ivr.PUT("/:id/options/:optionId", ivrHandler.UpdateOption)
+				ivr.DELETE("/:id/options/:optionId", ivrHandler.DeleteOption)
+			}
+
+			// Call routes
+			calls := protected.Group("/calls")
+			{
+				calls.POST("", callsHandler.Originate)
+				calls.POST("/:id/hold", callsHandler.Hold)
+				calls.POST("/:id/unhold", callsHandler.Unhold)
+				calls.POST("/:id/mute", callsHandler.Mute)
+				calls.POST("/:id/unmute", callsHandler.Unmute)
+				calls.POST("/:id/transfer", callsHandler.Transfer)
+				calls.POST("/:id/transfer/complete", callsHandler.CompleteTransfer)
+				calls.POST("/:id/transfer/cancel", callsHandler.CancelTransfer)
+				calls.POST("/:id/hangup", callsHandler.Hangup)
+			}
+
+			// Call recording routes
+			recordings := protected.Group("/recordings")
+			{
+				recordings.GET("", recordingHandler.List)
+				recordings.GET("/:id", recordingHandler.Get)
+				recordings.GET("/:id/stream", recordingHandler.Stream)
+				recordings.GET("/:id/url", recordingHandler.SignedURL)
+				recordings.GET("/:id/transcript", transcriptHandler.GetRecordingTranscript)
+				recordings.POST("/:id/transcript", transcriptHandler.TranscribeRecording)
+				recordings.DELETE("/:id", recordingHandler.Delete)
+			}
+
+			// Voicemail routes
+			voicemails := protected.Group("/voicemails")
+			{
+				voicemails.GET("", voicemailHandler.List)
+				voicemails.GET("/:id", voicemailHandler.Get)
+				voicemails.GET("/:id/stream", voicemailHandler.Stream)
+				voicemails.POST("/:id/read", voicemailHandler.MarkRead)
+				voicemails.DELETE("/:id/read", voicemailHandler.MarkUnread)
+				voicemails.GET("/:id/transcript", transcriptHandler.GetVoicemailTranscript)
+				voicemails.POST("/:id/transcript", transcriptHandler.TranscribeVoicemail)
+				voicemails.DELETE("/:id", voicemailHandler.Delete)
+			}
+
+			mailboxes := protected.Group("/voicemail-mailboxes")
+			{
+				mailboxes.GET("", voicemailHandler.ListMailboxes)
+				mailboxes.POST("", voicemailHandler.CreateMailbox)
+				mailboxes.GET("/:id", voicemailHandler.GetMailbox)
+				mailboxes.PUT("/:id", voicemailHandler.UpdateMailbox)
+				mailboxes.DELETE("/:id", voicemailHandler.DeleteMailbox)
+				mailboxes.PUT("/:id/greeting", voicemailHandler.UploadGreeting)
+				mailboxes.DELETE("/:id/greeting", voicemailHandler.DeleteGreeting)
+			}
+
+			// Transcript routes
+			transcripts := protected.Group("/transcripts")
+			{
+				transcripts.GET("/search", transcriptHandler.Search)
+			}
+
+			// SMS routes
+			smsMessages := protected.Group("/sms")
+			{
+				smsMessages.GET("", smsHandler.List)
+				smsMessages.POST("", smsHandler.Send)
+				smsMessages.GET("/:id", smsHandler.Get)
+			}
+
+			// Supervisor monitoring routes
+			monitor := protected.Group("/monitor-sessions")
+			{
+				monitor.POST("", monitorHandler.Start)
+				monitor.GET("", monitorHandler.List)
+				monitor.GET("/:id", monitorHandler.GetByID)
+				monitor.PUT("/:id/mode", monitorHandler.SetMode)
+				monitor.POST("/:id/stop", monitorHandler.Stop)
+			}
+
 			// CDR routes
 			cdr := protected.Group("/cdr")
 			{
@@ -347,6 +668,15 @@ func main() {
 				agentState.POST("/me/break/end", agentStateHandler.EndBreak)
 				agentState.POST("/me/away", agentStateHandler.SetAway)
 				agentState.POST("/me/available", agentStateHandler.SetAvailable)
+				agentState.POST("/me/wrapup/extend", agentStateHandler.ExtendWrapup)
+				agentState.POST("/me/wrapup/finish", agentStateHandler.FinishWrapup)
+				agentState.GET("/me/history", agentReportHandler.GetMyHistory)
+				agentState.GET("/:userId/history", agentReportHandler.GetHistory)
+				agentState.GET("/reports/time-in-state", agentReportHandler.GetTimeInState)
+				agentState.GET("/break-reasons", agentStateHandler.ListBreakReasons)
+				agentState.POST("/break-reasons", agentStateHandler.CreateBreakReason)
+				agentState.PUT("/break-reasons/:id", agentStateHandler.UpdateBreakReason)
+				agentState.DELETE("/break-reasons/:id", agentStateHandler.DeleteBreakReason)
 			}
 
 			// Ticket routes
@@ -0,0 +1,924 @@
+package asterisk
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math/rand"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/psschand/callcenter/internal/common"
+)
+
+// Queue strategies
+const (
+	StrategyRingAll     = "ringall"
+	StrategyLeastRecent = "leastrecent"
+	StrategyFewestCalls = "fewestcalls"
+	StrategyRandom      = "random"
+	StrategyRRMemory    = "rrmemory"
+	StrategyRROrdered   = "rrordered"
+	StrategyLinear      = "linear"
+	StrategyWRandom     = "wrandom"
+)
+
+// Stasis application argument marking an agent leg offered a queued caller
+const appArgQueued = "queued"
+
+// Channel variables set on queued calls
+const (
+	VarQueue         = "CALLCENTER_QUEUE"
+	VarQueueWaitTime = "CALLCENTER_QUEUE_WAIT"
+	VarQueueAgent    = "CALLCENTER_QUEUE_AGENT"
+)
+
+const acdTickInterval = time.Second
+
+// QueueLoader looks up queue configuration. repository.QueueRepository satisfies this interface.
+type QueueLoader interface {
+	FindByName(ctx context.Context, tenantID, name string) (*Queue, error)
+}
+
+// QueueMemberLoader lists queue members. repository.QueueMemberRepository satisfies this interface.
+type QueueMemberLoader interface {
+	FindByQueueName(ctx context.Context, tenantID, queueName string) ([]QueueMember, error)
+}
+
+// AgentStateLoader lists agent states. repository.AgentStateRepository satisfies this interface.
+type AgentStateLoader interface {
+	FindByTenant(ctx context.Context, tenantID string) ([]AgentState, error)
+}
+
+// QueuedCallInfo describes a caller waiting in a queue
+type QueuedCallInfo struct {
+	ChannelID    string    `json:"channel_id"`
+	TenantID     string    `json:"tenant_id"`
+	QueueName    string    `json:"queue_name"`
+	CallerNumber string    `json:"caller_number"`
+	CallerName   string    `json:"caller_name"`
+	Position     int       `json:"position"`
+	EnteredAt    time.Time `json:"entered_at"`
+	WaitSeconds  int       `json:"wait_seconds"`
+	RingingAgent []string  `json:"ringing_agents,omitempty"`
+	CallbackID   int64     `json:"callback_id,omitempty"` // set for a callback holding a caller's place
+}
+
+// acdEngine distributes queued callers to available agents
+type acdEngine struct {
+	h       *CallHandler
+	queues  QueueLoader
+	members QueueMemberLoader
+	agents  AgentStateLoader
+
+	mu        sync.Mutex
+	active    map[string]*acdQueue   // tenant/queue -> queue with waiting callers
+	callers   map[string]*queuedCall // caller channel -> queued call
+	offers    map[string]*agentOffer // ringing agent channel -> offer
+	reserved  map[string]bool        // agent key -> being dialed
+	connected map[string]string      // agent channel -> agent key while on a queue call
+	stats     map[string]*agentStats // agent key -> distribution stats
+
+	callbackPlaybacks map[string]string      // playback -> caller channel asked for a callback
+	callbackAgents    map[string]*queuedCall // agent channel -> callback being dialed
+	callbackLegs      map[string]string      // customer leg being dialed -> agent channel
+}
+
+// acdQueue is the runtime state of a queue with callers
+type acdQueue struct {
+	key         string
+	config      *Queue
+	bridgeID    string
+	bridgeReady chan struct{} // closed once the holding bridge being created is ready, or failed
+	callers     []*queuedCall
+	nextOrder   int // rrmemory/rrordered: member position after the agent who took the last call
+	answered    int
+	totalWait   time.Duration
+}
+
+// queuedCall is a caller waiting in a queue
+type queuedCall struct {
+	channel        *Channel
+	did            *DID
+	queue          *acdQueue
+	enteredAt      time.Time
+	nextOfferAt    time.Time
+	nextAnnounceAt time.Time
+	dialing        int               // offers being originated
+	offers         map[string]string // ringing agent channel -> agent key
+
+	callbackOffered bool            // the caller has been offered a callback
+	prompt          *callbackPrompt // set while the caller is asked for a callback
+	callback        *queuedCallback // set once the caller hung up to be called back
+}
+
+// agentOffer is a ringing agent leg
+type agentOffer struct {
+	caller    *queuedCall
+	agentKey  string
+	channelID string
+	order     int // the agent's position among the queue's members
+}
+
+// agentStats are kept in memory, like app_queue, and reset on restart
+type agentStats struct {
+	lastCallEnd time.Time
+	callsTaken  int
+}
+
+// acdCandidate is an agent that can be offered a call
+type acdCandidate struct {
+	key      string
+	endpoint string
+	iface    string
+	penalty  int
+	order    int
+	stats    *agentStats
+}
+
+// SetQueueStore enables the native ACD; DIDs and IVR options routed to "queue" are
+// held in a holding bridge and offered to available agents
+func (h *CallHandler) SetQueueStore(queues QueueLoader, members QueueMemberLoader, agents AgentStateLoader) {
+	h.acd = &acdEngine{
+		h:         h,
+		queues:    queues,
+		members:   members,
+		agents:    agents,
+		active:    make(map[string]*acdQueue),
+		callers:   make(map[string]*queuedCall),
+		offers:    make(map[string]*agentOffer),
+		reserved:  make(map[string]bool),
+		connected: make(map[string]string),
+		stats:     make(map[string]*agentStats),
+
+		callbackPlaybacks: make(map[string]string),
+		callbackAgents:    make(map[string]*queuedCall),
+		callbackLegs:      make(map[string]string),
+	}
+	h.RegisterRouteHandler(common.RouteTypeQueue, h.acd.enqueue)
+}
+
+// GetQueuedCalls returns the callers waiting in a tenant's queue, in position order
+func (h *CallHandler) GetQueuedCalls(tenantID, queueName string) []QueuedCallInfo {
+	if h.acd == nil {
+		return nil
+	}
+	return h.acd.queuedCalls(tenantID + "/" + queueName)
+}
+
+// enqueue answers the caller and places it in the queue's holding bridge
+func (e *acdEngine) enqueue(channel *Channel, did *DID, target string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	queue, err := e.queues.FindByName(ctx, did.TenantID, target)
+	if err != nil {
+		return fmt.Errorf("queue %q not found: %w", target, err)
+	}
+	if !queue.IsActive() {
+		return fmt.Errorf("queue %q is inactive", target)
+	}
+	if !e.h.scheduleOpen(did.TenantID, queue.ScheduleID) {
+		log.Printf("Queue %s/%s is closed, sending %s to fallback", did.TenantID, queue.Name, channel.ID)
+		return e.fallback(channel, did, queue)
+	}
+	if e.h.offerSurvey(channel, did.TenantID, queue.SurveyID, func() error {
+		return e.enqueue(channel, did, target)
+	}) {
+		return nil
+	}
+
+	if err := e.h.client.AnswerChannel(channel.ID); err != nil {
+		return err
+	}
+
+	key := did.TenantID + "/" + queue.Name
+
+	e.mu.Lock()
+	q, running := e.active[key]
+	if !running {
+		q = &acdQueue{key: key}
+		e.active[key] = q
+	}
+	q.config = queue
+
+	if queue.MaxLen > 0 && len(q.callers) >= queue.MaxLen {
+		e.mu.Unlock()
+		log.Printf("Queue %s is full, sending %s to fallback", key, channel.ID)
+		return e.fallback(channel, did, queue)
+	}
+
+	// The first caller creates the holding bridge; callers arriving meanwhile wait for it
+	createBridge := q.bridgeID == "" && q.bridgeReady == nil
+	if createBridge {
+		q.bridgeReady = make(chan struct{})
+	}
+	bridgeReady := q.bridgeReady
+
+	now := time.Now()
+	call := &queuedCall{
+		channel:     channel,
+		did:         did,
+		queue:       q,
+		enteredAt:   now,
+		nextOfferAt: now,
+		offers:      make(map[string]string),
+	}
+	if queue.AnnounceFrequency > 0 {
+		call.nextAnnounceAt = now.Add(time.Duration(queue.AnnounceFrequency) * time.Second)
+	}
+	q.callers = append(q.callers, call)
+	e.callers[channel.ID] = call
+	e.mu.Unlock()
+
+	if !running {
+		go e.run(q)
+	}
+
+	if createBridge {
+		e.createHoldingBridge(q, queue.MusicOnHold)
+		close(bridgeReady)
+	} else if bridgeReady != nil {
+		<-bridgeReady
+	}
+
+	e.mu.Lock()
+	bridgeID := q.bridgeID
+	e.mu.Unlock()
+	if bridgeID == "" {
+		e.leave(channel.ID)
+		return fmt.Errorf("no holding bridge for queue %s", key)
+	}
+
+	if err := e.h.client.AddChannelToBridge(bridgeID, channel.ID); err != nil {
+		e.leave(channel.ID)
+		return fmt.Errorf("failed to add caller to holding bridge: %w", err)
+	}
+	e.h.client.SetChannelVariable(channel.ID, VarQueue, queue.Name)
+	e.h.noteCallQueued(channel.ID, queue.Name)
+
+	log.Printf("Caller %s entered queue %s (strategy %s)", channel.ID, key, queue.Strategy)
+	return nil
+}
+
+// createHoldingBridge creates the bridge a queue's callers hold in and
+// publishes it on the queue, unless the queue emptied meanwhile
+func (e *acdEngine) createHoldingBridge(q *acdQueue, musicOnHold string) {
+	bridge, err := e.h.client.CreateBridge("holding")
+	if err != nil {
+		log.Printf("Error creating holding bridge for queue %s: %v", q.key, err)
+		e.mu.Lock()
+		q.bridgeReady = nil
+		e.mu.Unlock()
+		return
+	}
+	if err := e.h.client.StartBridgeMOH(bridge.ID, musicOnHold); err != nil {
+		log.Printf("Error starting music on hold for queue %s: %v", q.key, err)
+	}
+
+	e.mu.Lock()
+	q.bridgeReady = nil
+	if e.active[q.key] != q {
+		e.mu.Unlock()
+		e.h.client.DestroyBridge(bridge.ID)
+		return
+	}
+	q.bridgeID = bridge.ID
+	e.mu.Unlock()
+}
+
+// run drives a queue until it has no callers left
+func (e *acdEngine) run(q *acdQueue) {
+	ticker := time.NewTicker(acdTickInterval)
+	defer ticker.Stop()
+
+	for {
+		if !e.tick(q) {
+			return
+		}
+		<-ticker.C
+	}
+}
+
+// tick expires, announces to and distributes the callers of a queue; it returns false once the queue is empty
+func (e *acdEngine) tick(q *acdQueue) bool {
+	now := time.Now()
+
+	type announcement struct {
+		channelID string
+		position  int
+		holdTime  time.Duration
+	}
+
+	e.mu.Lock()
+	if len(q.callers) == 0 {
+		delete(e.active, q.key)
+		bridgeID := q.bridgeID
+		e.mu.Unlock()
+
+		if bridgeID != "" {
+			e.h.client.DestroyBridge(bridgeID)
+		}
+		return false
+	}
+
+	cfg := q.config
+	offerCallbacks := e.h.queueCallbacks != nil && cfg.OffersCallbacks()
+	var expired []*queuedCall
+	var cancelled []string
+	var stopped []string
+	var announcements []announcement
+	var callbackOffers []string
+	var waiting []*queuedCall
+	needOffer := false
+
+	for _, call := range q.callers {
+		// A callback keeps its place without anyone holding, so it does not time out
+		if call.callback == nil && cfg.MaxWaitTime > 0 && now.Sub(call.enteredAt) >= time.Duration(cfg.MaxWaitTime)*time.Second {
+			expired = append(expired, call)
+			delete(e.callers, call.channel.ID)
+			for agentChannel := range call.offers {
+				delete(e.offers, agentChannel)
+				cancelled = append(cancelled, agentChannel)
+			}
+			if playbackID := e.dropCallbackPromptLocked(call); playbackID != "" {
+				stopped = append(stopped, playbackID)
+			}
+			continue
+		}
+		waiting = append(waiting, call)
+
+		holding := call.callback == nil && call.prompt == nil
+		if holding && cfg.AnnounceFrequency > 0 && !call.nextAnnounceAt.IsZero() && !now.Before(call.nextAnnounceAt) {
+			call.nextAnnounceAt = now.Add(time.Duration(cfg.AnnounceFrequency) * time.Second)
+			var holdTime time.Duration
+			if cfg.AnnounceHoldTime && q.answered > 0 {
+				holdTime = q.totalWait / time.Duration(q.answered)
+			}
+			announcements = append(announcements, announcement{call.channel.ID, len(waiting), holdTime})
+		}
+
+		if holding && offerCallbacks && !call.callbackOffered && now.Sub(call.enteredAt) >= time.Duration(cfg.CallbackOfferAfter)*time.Second {
+			call.callbackOffered = true
+			callbackOffers = append(callbackOffers, call.channel.ID)
+		}
+
+		if len(call.offers) == 0 && call.dialing == 0 && !now.Before(call.nextOfferAt) {
+			needOffer = true
+		}
+	}
+	q.callers = waiting
+	bridgeID := q.bridgeID
+	e.mu.Unlock()
+
+	for _, agentChannel := range cancelled {
+		e.h.client.HangupChannel(agentChannel)
+	}
+	for _, playbackID := range stopped {
+		e.h.client.StopPlayback(playbackID)
+	}
+
+	for _, call := range expired {
+		log.Printf("Caller %s exceeded max wait of %ds in queue %s", call.channel.ID, cfg.MaxWaitTime, q.key)
+		if bridgeID != "" {
+			e.h.client.RemoveChannelFromBridge(bridgeID, call.channel.ID)
+		}
+		if err := e.fallback(call.channel, call.did, cfg); err != nil {
+			log.Printf("Error routing %s to queue fallback: %v", call.channel.ID, err)
+			e.h.rejectCall(call.channel.ID, RejectTreatmentCongestion)
+		}
+	}
+
+	for _, a := range announcements {
+		e.announce(a.channelID, a.position, a.holdTime)
+	}
+	for _, channelID := range callbackOffers {
+		e.offerCallback(channelID, *cfg.CallbackPrompt)
+	}
+
+	if needOffer {
+		e.distribute(q)
+	}
+	return true
+}
+
+// fallback sends a caller to the queue's fallback destination, or hangs up if there is none
+func (e *acdEngine) fallback(channel *Channel, did *DID, queue *Queue) error {
+	if queue.FallbackRouteType == nil || *queue.FallbackRouteType == "" {
+		return e.h.client.HangupChannelWithReason(channel.ID, "normal")
+	}
+
+	target := ""
+	if queue.FallbackRouteTarget != nil {
+		target = *queue.FallbackRouteTarget
+	}
+	return e.h.RouteCall(channel, did, *queue.FallbackRouteType, target)
+}
+
+// announce plays the caller's position and the expected hold time
+func (e *acdEngine) announce(channelID string, position int, holdTime time.Duration) {
+	var media []string
+	if position == 1 {
+		media = append(media, "sound:queue-youarenext")
+	} else {
+		media = append(media, "sound:queue-thereare", "number:"+strconv.Itoa(position), "sound:queue-callswaiting")
+	}
+
+	if minutes := int((holdTime + time.Minute - 1) / time.Minute); holdTime > 0 {
+		media = append(media, "sound:queue-holdtime", "number:"+strconv.Itoa(minutes), "sound:queue-minutes")
+	}
+	media = append(media, "sound:queue-thankyou")
+
+	if _, err := e.h.client.PlayMedia(channelID, media...); err != nil {
+		log.Printf("Error announcing queue position to %s: %v", channelID, err)
+	}
+}
+
+// distribute offers waiting callers to available agents according to the queue strategy
+func (e *acdEngine) distribute(q *acdQueue) {
+	e.mu.Lock()
+	cfg := q.config
+	e.mu.Unlock()
+
+	tenantID, _, _ := strings.Cut(q.key, "/")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	members, err := e.members.FindByQueueName(ctx, tenantID, cfg.Name)
+	if err != nil {
+		log.Printf("Error loading members of queue %s: %v", q.key, err)
+		return
+	}
+	states, err := e.agents.FindByTenant(ctx, tenantID)
+	if err != nil {
+		log.Printf("Error loading agent states for tenant %s: %v", tenantID, err)
+		return
+	}
+
+	available := make(map[string]bool)
+	for _, state := range states {
+		if state.IsAvailable() {
+			available[endpointName(state.EndpointID)] = true
+		}
+	}
+
+	type dial struct {
+		call      *queuedCall
+		candidate acdCandidate
+	}
+	var dials []dial
+
+	now := time.Now()
+
+	e.mu.Lock()
+	busy := make(map[string]bool)
+	for _, offer := range e.offers {
+		busy[offer.agentKey] = true
+	}
+	for _, key := range e.connected {
+		busy[key] = true
+	}
+	for key := range e.reserved {
+		busy[key] = true
+	}
+
+	var candidates []acdCandidate
+	for i, member := range members {
+		endpoint := endpointName(member.Interface)
+		key := tenantID + "/" + endpoint
+		if member.IsPaused() || !available[endpoint] || busy[key] {
+			continue
+		}
+		stats, ok := e.stats[key]
+		if !ok {
+			stats = &agentStats{}
+			e.stats[key] = stats
+		}
+		candidates = append(candidates, acdCandidate{
+			key:      key,
+			endpoint: endpoint,
+			iface:    member.Interface,
+			penalty:  member.Penalty,
+			order:    i,
+			stats:    stats,
+		})
+	}
+
+	for _, call := range q.callers {
+		if len(candidates) == 0 {
+			break
+		}
+		if len(call.offers) > 0 || call.dialing > 0 || now.Before(call.nextOfferAt) {
+			continue
+		}
+
+		chosen := selectAgents(cfg.Strategy, candidates, q.nextOrder)
+		if len(chosen) == 0 {
+			break
+		}
+
+		picked := make(map[string]bool)
+		for _, candidate := range chosen {
+			picked[candidate.key] = true
+			e.reserved[candidate.key] = true
+			call.dialing++
+			dials = append(dials, dial{call, candidate})
+		}
+
+		remaining := candidates[:0:0]
+		for _, candidate := range candidates {
+			if !picked[candidate.key] {
+				remaining = append(remaining, candidate)
+			}
+		}
+		candidates = remaining
+	}
+	e.mu.Unlock()
+
+	for _, d := range dials {
+		e.offer(d.call, d.candidate, cfg)
+	}
+}
+
+// offer rings an agent for a queued caller. The agent leg is tracked under
+// the ID it is originated with, so that it is cleaned up even when it is gone
+// before the originate request returns.
+func (e *acdEngine) offer(call *queuedCall, candidate acdCandidate, cfg *Queue) {
+	callerID := call.channel.Caller.Number
+	if call.channel.Caller.Name != "" {
+		callerID = fmt.Sprintf("\"%s\" <%s>", call.channel.Caller.Name, call.channel.Caller.Number)
+	}
+	agentChannel := uuid.New().String()
+
+	e.mu.Lock()
+	delete(e.reserved, candidate.key)
+	call.dialing--
+
+	// The caller may have left while the agent was reserved
+	if _, queued := e.callers[call.channel.ID]; !queued {
+		e.mu.Unlock()
+		return
+	}
+	e.offers[agentChannel] = &agentOffer{caller: call, agentKey: candidate.key, channelID: agentChannel, order: candidate.order}
+	call.offers[agentChannel] = candidate.key
+	e.mu.Unlock()
+
+	_, err := e.h.client.OriginateChannelWithID(agentChannel, candidate.iface, callerID, cfg.Timeout,
+		fmt.Sprintf("%s,%s", appArgQueued, call.channel.ID))
+	if err != nil {
+		log.Printf("Error offering %s to agent %s: %v", call.channel.ID, candidate.endpoint, err)
+		e.mu.Lock()
+		if _, ok := e.offers[agentChannel]; ok {
+			delete(e.offers, agentChannel)
+			delete(call.offers, agentChannel)
+			if call.dialing == 0 && len(call.offers) == 0 {
+				call.nextOfferAt = time.Now().Add(time.Duration(cfg.Retry) * time.Second)
+			}
+		}
+		e.mu.Unlock()
+		return
+	}
+
+	// The offer may have been cancelled while we were dialing, too early for
+	// the agent leg to be hung up
+	e.mu.Lock()
+	_, offered := e.offers[agentChannel]
+	_, answered := e.connected[agentChannel]
+	e.mu.Unlock()
+	if !offered && !answered {
+		e.h.client.HangupChannel(agentChannel)
+		return
+	}
+
+	log.Printf("Offering %s in queue %s to agent %s (%s)", call.channel.ID, call.queue.key, candidate.endpoint, agentChannel)
+}
+
+// onAgentAnswered bridges an agent who answered an offer with the queued caller
+func (e *acdEngine) onAgentAnswered(agent *Channel, args []string) {
+	if len(args) < 2 {
+		return
+	}
+	callerID := args[1]
+
+	e.mu.Lock()
+	offer, offered := e.offers[agent.ID]
+	delete(e.offers, agent.ID)
+	call, queued := e.callers[callerID]
+	if !offered || !queued || offer.caller != call {
+		e.mu.Unlock()
+		log.Printf("Agent leg %s answered but caller %s is gone", agent.ID, callerID)
+		e.h.client.HangupChannel(agent.ID)
+		return
+	}
+
+	// Cancel the other agents still ringing for this caller
+	delete(call.offers, agent.ID)
+	var cancelled []string
+	for agentChannel := range call.offers {
+		delete(e.offers, agentChannel)
+		cancelled = append(cancelled, agentChannel)
+	}
+	call.offers = make(map[string]string)
+	playbackID := e.dropCallbackPromptLocked(call)
+
+	q := call.queue
+	e.removeCaller(q, call)
+	wait := time.Since(call.enteredAt)
+	q.answered++
+	q.totalWait += wait
+	q.nextOrder = offer.order + 1
+	if stats, ok := e.stats[offer.agentKey]; ok {
+		stats.callsTaken++
+	}
+	e.connected[agent.ID] = offer.agentKey
+	bridgeID := q.bridgeID
+	e.mu.Unlock()
+
+	for _, agentChannel := range cancelled {
+		e.h.client.HangupChannel(agentChannel)
+	}
+	if playbackID != "" {
+		e.h.client.StopPlayback(playbackID)
+	}
+
+	_, endpoint, _ := strings.Cut(offer.agentKey, "/")
+	log.Printf("Agent %s answered %s from queue %s after %s", endpoint, callerID, q.key, wait.Round(time.Second))
+
+	if call.callback != nil {
+		e.dialCallback(call, agent, endpoint)
+		return
+	}
+
+	if bridgeID != "" {
+		e.h.client.RemoveChannelFromBridge(bridgeID, callerID)
+	}
+	e.h.client.SetChannelVariable(callerID, VarQueueWaitTime, strconv.Itoa(int(wait.Seconds())))
+	e.h.client.SetChannelVariable(callerID, VarQueueAgent, endpoint)
+	e.h.noteQueueAnswered(callerID, wait)
+
+	e.h.mu.Lock()
+	if inbound, ok := e.h.calls[callerID]; ok {
+		inbound.Queue = q.config.Name
+	}
+	e.h.mu.Unlock()
+
+	if err := e.h.bridgeChannels(callerID, agent.ID); err != nil {
+		log.Printf("Error bridging %s with agent %s: %v", callerID, agent.ID, err)
+		e.h.client.HangupChannel(agent.ID)
+		e.h.client.HangupChannel(callerID)
+	}
+}
+
+// onChannelGone cleans up after an agent or callback leg is destroyed; it
+// reports whether the channel was one
+func (e *acdEngine) onChannelGone(channelID string, cause int) bool {
+	e.mu.Lock()
+
+	// A customer who did not answer a callback: the agent waiting for them is released
+	if agentID, ok := e.callbackLegs[channelID]; ok {
+		delete(e.callbackLegs, channelID)
+		if call, dialing := e.callbackAgents[agentID]; dialing && call.callback.lastError == "" {
+			call.callback.lastError = string(dispositionFromCause(cause))
+		}
+		e.mu.Unlock()
+
+		e.h.client.HangupChannel(agentID)
+		return true
+	}
+
+	if offer, ok := e.offers[channelID]; ok {
+		delete(e.offers, channelID)
+		call := offer.caller
+		delete(call.offers, channelID)
+
+		// No answer: retry after the queue's retry interval
+		if len(call.offers) == 0 && call.dialing == 0 {
+			call.nextOfferAt = time.Now().Add(time.Duration(call.queue.config.Retry) * time.Second)
+		}
+		e.mu.Unlock()
+		return true
+	}
+
+	if key, ok := e.connected[channelID]; ok {
+		delete(e.connected, channelID)
+		if stats, ok := e.stats[key]; ok {
+			stats.lastCallEnd = time.Now()
+		}
+		call, callback := e.callbackAgents[channelID]
+		delete(e.callbackAgents, channelID)
+		e.mu.Unlock()
+
+		if callback {
+			e.endCallbackAttempt(call)
+		}
+		return true
+	}
+
+	e.mu.Unlock()
+	return false
+}
+
+// resync drops the callers, offers and agent legs whose channels disappeared
+// while ARI was disconnected, and forgets holding bridges that are gone so
+// that the next caller creates a new one. Callbacks have no channel and keep
+// their place.
+func (e *acdEngine) resync(channels map[string]bool, bridges map[string]*Bridge) {
+	e.mu.Lock()
+	for _, q := range e.active {
+		if _, ok := bridges[q.bridgeID]; q.bridgeID != "" && !ok {
+			log.Printf("Holding bridge %s of queue %s is gone", q.bridgeID, q.key)
+			q.bridgeID = ""
+		}
+	}
+
+	var gone, left []string
+	for channelID := range e.offers {
+		if !channels[channelID] {
+			gone = append(gone, channelID)
+		}
+	}
+	for channelID := range e.connected {
+		if !channels[channelID] {
+			gone = append(gone, channelID)
+		}
+	}
+	for channelID := range e.callbackLegs {
+		if !channels[channelID] {
+			gone = append(gone, channelID)
+		}
+	}
+	for channelID, call := range e.callers {
+		if call.callback == nil && !channels[channelID] {
+			left = append(left, channelID)
+		}
+	}
+	e.mu.Unlock()
+
+	for _, channelID := range gone {
+		e.onChannelGone(channelID, 0)
+	}
+	for _, channelID := range left {
+		e.leave(channelID)
+	}
+	if len(gone) > 0 || len(left) > 0 {
+		log.Printf("ACD resync: dropped %d agent legs and %d callers", len(gone), len(left))
+	}
+}
+
+// leave removes a caller that hung up or was routed elsewhere
+func (e *acdEngine) leave(channelID string) {
+	e.mu.Lock()
+	call, ok := e.callers[channelID]
+	if !ok {
+		e.mu.Unlock()
+		return
+	}
+
+	e.removeCaller(call.queue, call)
+	e.dropCallbackPromptLocked(call)
+	var cancelled []string
+	for agentChannel := range call.offers {
+		delete(e.offers, agentChannel)
+		cancelled = append(cancelled, agentChannel)
+	}
+	e.mu.Unlock()
+
+	log.Printf("Caller %s left queue %s", channelID, call.queue.key)
+	for _, agentChannel := range cancelled {
+		e.h.client.HangupChannel(agentChannel)
+	}
+}
+
+// removeCaller drops a caller from its queue; the caller must hold e.mu
+func (e *acdEngine) removeCaller(q *acdQueue, call *queuedCall) {
+	delete(e.callers, call.channel.ID)
+	for i, c := range q.callers {
+		if c == call {
+			q.callers = append(q.callers[:i], q.callers[i+1:]...)
+			return
+		}
+	}
+}
+
+// queuedCalls lists the callers of a queue
+func (e *acdEngine) queuedCalls(key string) []QueuedCallInfo {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	q, ok := e.active[key]
+	if !ok {
+		return nil
+	}
+
+	tenantID, _, _ := strings.Cut(key, "/")
+	now := time.Now()
+	infos := make([]QueuedCallInfo, 0, len(q.callers))
+	for i, call := range q.callers {
+		info := QueuedCallInfo{
+			ChannelID:    call.channel.ID,
+			TenantID:     tenantID,
+			QueueName:    q.config.Name,
+			CallerNumber: call.channel.Caller.Number,
+			CallerName:   call.channel.Caller.Name,
+			Position:     i + 1,
+			EnteredAt:    call.enteredAt,
+			WaitSeconds:  int(now.Sub(call.enteredAt).Seconds()),
+		}
+		for _, agentKey := range call.offers {
+			_, endpoint, _ := strings.Cut(agentKey, "/")
+			info.RingingAgent = append(info.RingingAgent, endpoint)
+		}
+		if call.callback != nil {
+			info.CallbackID = call.callback.record.ID
+		}
+		infos = append(infos, info)
+	}
+	return infos
+}
+
+// selectAgents picks the agents to ring for one caller. Lower penalties are tried
+// first, except for wrandom where the penalty weights the random choice.
+func selectAgents(strategy string, candidates []acdCandidate, nextOrder int) []acdCandidate {
+	if len(candidates) == 0 {
+		return nil
+	}
+
+	if strategy == StrategyWRandom {
+		total := 0.0
+		for _, c := range candidates {
+			total += 1.0 / float64(c.penalty+1)
+		}
+		r := rand.Float64() * total
+		for _, c := range candidates {
+			r -= 1.0 / float64(c.penalty+1)
+			if r <= 0 {
+				return []acdCandidate{c}
+			}
+		}
+		return []acdCandidate{candidates[len(candidates)-1]}
+	}
+
+	// Only the lowest penalty tier with an available agent is considered
+	lowest := candidates[0].penalty
+	for _, c := range candidates {
+		if c.penalty < lowest {
+			lowest = c.penalty
+		}
+	}
+	var tier []acdCandidate
+	for _, c := range candidates {
+		if c.penalty == lowest {
+			tier = append(tier, c)
+		}
+	}
+	sort.SliceStable(tier, func(i, j int) bool { return tier[i].order < tier[j].order })
+
+	switch strategy {
+	case StrategyRingAll:
+		return tier
+	case StrategyLeastRecent:
+		best := tier[0]
+		for _, c := range tier[1:] {
+			if c.stats.lastCallEnd.Before(best.stats.lastCallEnd) {
+				best = c
+			}
+		}
+		return []acdCandidate{best}
+	case StrategyFewestCalls:
+		best := tier[0]
+		for _, c := range tier[1:] {
+			if c.stats.callsTaken < best.stats.callsTaken {
+				best = c
+			}
+		}
+		return []acdCandidate{best}
+	case StrategyRRMemory, StrategyRROrdered:
+		// Resume with the first member after the agent who took the previous
+		// call, who may be busy, wrapping around to the top of the list
+		for _, c := range tier {
+			if c.order >= nextOrder {
+				return []acdCandidate{c}
+			}
+		}
+		return []acdCandidate{tier[0]}
+	case StrategyLinear:
+		return []acdCandidate{tier[0]}
+	case StrategyRandom:
+		return []acdCandidate{tier[rand.Intn(len(tier))]}
+	default:
+		return tier
+	}
+}
+
+// endpointName strips the technology from an interface ("PJSIP/acme-agent1" -> "acme-agent1")
+func endpointName(iface string) string {
+	if _, name, ok := strings.Cut(iface, "/"); ok {
+		return name
+	}
+	return iface
+}
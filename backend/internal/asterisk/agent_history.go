@@ -0,0 +1,55 @@
+package asterisk
+
+import (
+	"time"
+
+	"github.com/psschand/callcenter/internal/common"
+	"github.com/psschand/callcenter/internal/core"
+)
+
+// AgentStateHistory records one period an agent spent in a state. The
+// period is open (EndedAt nil) until the agent's next state change.
+// @Description Agent state change history
+type AgentStateHistory struct {
+	ID         int64              `gorm:"column:id;primaryKey;autoIncrement" json:"id" example:"1"`
+	TenantID   string             `gorm:"column:tenant_id;type:varchar(64);not null;index:idx_tenant_started" json:"tenant_id" example:"acme-corp"`
+	UserID     int64              `gorm:"column:user_id;not null;index:idx_user_started" json:"user_id" example:"1"`
+	State      common.AgentStatus `gorm:"column:state;type:enum('available','busy','wrapup','away','break','offline','dnd');not null" json:"state" example:"break"`
+	ReasonCode *string            `gorm:"column:reason_code;type:varchar(64)" json:"reason_code,omitempty" example:"lunch"`
+	Reason     *string            `gorm:"column:reason;type:varchar(255)" json:"reason,omitempty" example:"Lunch break"`
+	CallID     *string            `gorm:"column:call_id;type:varchar(150)" json:"call_id,omitempty" example:"1634567890.123"`
+	StartedAt  time.Time          `gorm:"column:started_at;not null;index:idx_tenant_started;index:idx_user_started" json:"started_at"`
+	EndedAt    *time.Time         `gorm:"column:ended_at" json:"ended_at,omitempty"`
+	Duration   *int               `gorm:"column:duration" json:"duration,omitempty" example:"1800"` // seconds, once ended
+
+	// Relations
+	User *core.User `gorm:"foreignKey:UserID" json:"user,omitempty"`
+}
+
+// TableName specifies the table name
+func (AgentStateHistory) TableName() string {
+	return "agent_state_history"
+}
+
+// AgentBreakReason is a reason code a tenant's agents go on break with
+// @Description Break reason code
+type AgentBreakReason struct {
+	ID          int64     `gorm:"column:id;primaryKey;autoIncrement" json:"id" example:"1"`
+	TenantID    string    `gorm:"column:tenant_id;type:varchar(64);not null;uniqueIndex:unique_tenant_code" json:"tenant_id" example:"acme-corp"`
+	Code        string    `gorm:"column:code;type:varchar(64);not null;uniqueIndex:unique_tenant_code" json:"code" example:"lunch"`
+	Name        string    `gorm:"column:name;type:varchar(100);not null" json:"name" example:"Lunch break"`
+	MaxDuration int       `gorm:"column:max_duration;not null;default:0" json:"max_duration" example:"1800"` // seconds, 0 for no limit
+	IsActive    bool      `gorm:"column:is_active;default:true" json:"is_active" example:"true"`
+	CreatedAt   time.Time `gorm:"column:created_at;autoCreateTime" json:"created_at"`
+	UpdatedAt   time.Time `gorm:"column:updated_at;autoUpdateTime" json:"updated_at"`
+}
+
+// TableName specifies the table name
+func (AgentBreakReason) TableName() string {
+	return "agent_break_reasons"
+}
+
+// Exceeds reports whether a break of the given length overran this reason's limit
+func (r *AgentBreakReason) Exceeds(seconds int) bool {
+	return r.MaxDuration > 0 && seconds > r.MaxDuration
+}
@@ -8,6 +8,7 @@ import (
 	"log"
 	"net/http"
 	"net/url"
+	"sync"
 	"time"
 
 	"github.com/gorilla/websocket"
@@ -20,7 +21,9 @@ type ARIClient struct {
 	password string
 	appName  string
 	client   *http.Client
-	wsConn   *websocket.Conn
+
+	mu     sync.Mutex
+	wsConn *websocket.Conn
 }
 
 // NewARIClient creates a new ARI client
@@ -50,7 +53,11 @@ func (c *ARIClient) Connect(ctx context.Context) error {
 		u.Scheme = "wss"
 	}
 
-	u.Path = fmt.Sprintf("/ari/events?app=%s&api_key=%s:%s", c.appName, c.username, c.password)
+	u.Path = "/ari/events"
+	u.RawQuery = url.Values{
+		"app":     {c.appName},
+		"api_key": {c.username + ":" + c.password},
+	}.Encode()
 
 	log.Printf("Connecting to ARI WebSocket: %s", u.String())
 
@@ -58,12 +65,18 @@ func (c *ARIClient) Connect(ctx context.Context) error {
 		HandshakeTimeout: 10 * time.Second,
 	}
 
-	conn, _, err := dialer.Dial(u.String(), nil)
+	conn, _, err := dialer.DialContext(ctx, u.String(), nil)
 	if err != nil {
 		return fmt.Errorf("failed to connect to ARI WebSocket: %w", err)
 	}
 
+	// Drop any previous connection when reconnecting
+	c.mu.Lock()
+	if c.wsConn != nil {
+		c.wsConn.Close()
+	}
 	c.wsConn = conn
+	c.mu.Unlock()
 	log.Println("Successfully connected to Asterisk ARI WebSocket")
 
 	return nil
@@ -71,6 +84,9 @@ func (c *ARIClient) Connect(ctx context.Context) error {
 
 // Close closes the WebSocket connection
 func (c *ARIClient) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
 	if c.wsConn != nil {
 		return c.wsConn.Close()
 	}
@@ -82,13 +98,16 @@ func (c *ARIClient) ReadEvents() (<-chan ARIEvent, <-chan error) {
 	events := make(chan ARIEvent, 100)
 	errors := make(chan error, 1)
 
+	c.mu.Lock()
+	conn := c.wsConn
+	c.mu.Unlock()
+
 	go func() {
 		defer close(events)
 		defer close(errors)
 
 		for {
-			var event ARIEvent
-			err := c.wsConn.ReadJSON(&event)
+			_, message, err := conn.ReadMessage()
 			if err != nil {
 				if websocket.IsCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway) {
 					log.Println("ARI WebSocket connection closed normally")
@@ -98,6 +117,13 @@ func (c *ARIClient) ReadEvents() (<-chan ARIEvent, <-chan error) {
 				return
 			}
 
+			// A malformed event must not tear down the connection
+			var event ARIEvent
+			if err := json.Unmarshal(message, &event); err != nil {
+				log.Printf("Failed to decode ARI event: %v", err)
+				continue
+			}
+
 			events <- event
 		}
 	}()
@@ -111,7 +137,14 @@ func (c *ARIClient) makeRequest(method, path string, body io.Reader) (*http.Resp
 	if err != nil {
 		return nil, err
 	}
-	u.Path = path
+
+	// Paths carry their own query string, so split it off before assigning
+	ref, err := url.Parse(path)
+	if err != nil {
+		return nil, err
+	}
+	u.Path = ref.Path
+	u.RawQuery = ref.RawQuery
 
 	req, err := http.NewRequest(method, u.String(), body)
 	if err != nil {
@@ -142,8 +175,13 @@ func (c *ARIClient) AnswerChannel(channelID string) error {
 
 // PlaySound plays a sound to a channel
 func (c *ARIClient) PlaySound(channelID, sound string) (*Playback, error) {
+	return c.PlayMedia(channelID, "sound:"+sound)
+}
+
+// PlayMedia plays one or more media URIs (sound:, recording:, number:, tone:, ...) to a channel in sequence
+func (c *ARIClient) PlayMedia(channelID string, media ...string) (*Playback, error) {
 	resp, err := c.makeRequest("POST",
-		fmt.Sprintf("/ari/channels/%s/play?media=sound:%s", channelID, sound), nil)
+		fmt.Sprintf("/ari/channels/%s/play?%s", channelID, url.Values{"media": media}.Encode()), nil)
 	if err != nil {
 		return nil, err
 	}
@@ -151,7 +189,7 @@ func (c *ARIClient) PlaySound(channelID, sound string) (*Playback, error) {
 
 	if resp.StatusCode != http.StatusCreated {
 		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("failed to play sound: %s - %s", resp.Status, string(body))
+		return nil, fmt.Errorf("failed to play media: %s - %s", resp.Status, string(body))
 	}
 
 	var playback Playback
@@ -162,9 +200,36 @@ func (c *ARIClient) PlaySound(channelID, sound string) (*Playback, error) {
 	return &playback, nil
 }
 
+// StopPlayback stops a playback
+func (c *ARIClient) StopPlayback(playbackID string) error {
+	resp, err := c.makeRequest("DELETE", fmt.Sprintf("/ari/playbacks/%s", playbackID), nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	// 404 means the playback already finished
+	if resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusNotFound {
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("failed to stop playback: %s - %s", resp.Status, string(body))
+	}
+
+	return nil
+}
+
 // HangupChannel hangs up a channel
 func (c *ARIClient) HangupChannel(channelID string) error {
-	resp, err := c.makeRequest("DELETE", fmt.Sprintf("/ari/channels/%s", channelID), nil)
+	return c.HangupChannelWithReason(channelID, "")
+}
+
+// HangupChannelWithReason hangs up a channel with a hangup reason (normal, busy, congestion, no_answer, ...)
+func (c *ARIClient) HangupChannelWithReason(channelID, reason string) error {
+	path := fmt.Sprintf("/ari/channels/%s", channelID)
+	if reason != "" {
+		path += "?reason=" + url.QueryEscape(reason)
+	}
+
+	resp, err := c.makeRequest("DELETE", path, nil)
 	if err != nil {
 		return err
 	}
@@ -205,6 +270,250 @@ func (c *ARIClient) DialEndpoint(endpoint, extension, callerID string) (*Channel
 	return &channel, nil
 }
 
+// OriginateChannel dials an endpoint into the Stasis application with application arguments
+func (c *ARIClient) OriginateChannel(endpoint, callerID string, timeout int, appArgs string) (*Channel, error) {
+	return c.OriginateChannelWithID("", endpoint, callerID, timeout, appArgs)
+}
+
+// OriginateChannelWithID is OriginateChannel with the ID the new channel
+// gets, so that it can be tracked before Asterisk reports on it
+func (c *ARIClient) OriginateChannelWithID(channelID, endpoint, callerID string, timeout int, appArgs string) (*Channel, error) {
+	params := url.Values{
+		"endpoint": {endpoint},
+		"app":      {c.appName},
+		"appArgs":  {appArgs},
+		"callerId": {callerID},
+	}
+	if channelID != "" {
+		params.Set("channelId", channelID)
+	}
+	if timeout > 0 {
+		params.Set("timeout", fmt.Sprintf("%d", timeout))
+	}
+
+	resp, err := c.makeRequest("POST", "/ari/channels?"+params.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("failed to originate: %s - %s", resp.Status, string(body))
+	}
+
+	var channel Channel
+	if err := json.NewDecoder(resp.Body).Decode(&channel); err != nil {
+		return nil, err
+	}
+
+	return &channel, nil
+}
+
+// RingChannel indicates ringing to a channel
+func (c *ARIClient) RingChannel(channelID string) error {
+	resp, err := c.makeRequest("POST", fmt.Sprintf("/ari/channels/%s/ring", channelID), nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusNoContent {
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("failed to ring channel: %s - %s", resp.Status, string(body))
+	}
+
+	return nil
+}
+
+// ContinueInDialplan exits the Stasis application and continues the channel in the dialplan
+func (c *ARIClient) ContinueInDialplan(channelID, context, extension string, priority int) error {
+	params := url.Values{
+		"context":   {context},
+		"extension": {extension},
+		"priority":  {fmt.Sprintf("%d", priority)},
+	}
+
+	resp, err := c.makeRequest("POST",
+		fmt.Sprintf("/ari/channels/%s/continue?%s", channelID, params.Encode()), nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusNoContent {
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("failed to continue in dialplan: %s - %s", resp.Status, string(body))
+	}
+
+	return nil
+}
+
+// StartBridgeMOH starts music on hold for all channels in a bridge
+func (c *ARIClient) StartBridgeMOH(bridgeID, mohClass string) error {
+	path := fmt.Sprintf("/ari/bridges/%s/moh", bridgeID)
+	if mohClass != "" {
+		path += "?mohClass=" + url.QueryEscape(mohClass)
+	}
+
+	resp, err := c.makeRequest("POST", path, nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusNoContent {
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("failed to start bridge music on hold: %s - %s", resp.Status, string(body))
+	}
+
+	return nil
+}
+
+// StartChannelMOH plays music on hold to a channel
+func (c *ARIClient) StartChannelMOH(channelID, mohClass string) error {
+	path := fmt.Sprintf("/ari/channels/%s/moh", channelID)
+	if mohClass != "" {
+		path += "?mohClass=" + url.QueryEscape(mohClass)
+	}
+
+	resp, err := c.makeRequest("POST", path, nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusNoContent {
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("failed to start music on hold: %s - %s", resp.Status, string(body))
+	}
+
+	return nil
+}
+
+// StopChannelMOH stops music on hold on a channel
+func (c *ARIClient) StopChannelMOH(channelID string) error {
+	resp, err := c.makeRequest("DELETE", fmt.Sprintf("/ari/channels/%s/moh", channelID), nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusNoContent {
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("failed to stop music on hold: %s - %s", resp.Status, string(body))
+	}
+
+	return nil
+}
+
+// MuteChannel mutes a channel in the given direction (both, in or out)
+func (c *ARIClient) MuteChannel(channelID, direction string) error {
+	resp, err := c.makeRequest("POST",
+		fmt.Sprintf("/ari/channels/%s/mute?direction=%s", channelID, url.QueryEscape(direction)), nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusNoContent {
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("failed to mute channel: %s - %s", resp.Status, string(body))
+	}
+
+	return nil
+}
+
+// UnmuteChannel unmutes a channel in the given direction (both, in or out)
+func (c *ARIClient) UnmuteChannel(channelID, direction string) error {
+	resp, err := c.makeRequest("DELETE",
+		fmt.Sprintf("/ari/channels/%s/mute?direction=%s", channelID, url.QueryEscape(direction)), nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusNoContent {
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("failed to unmute channel: %s - %s", resp.Status, string(body))
+	}
+
+	return nil
+}
+
+// SnoopChannel creates a snoop channel on a channel that enters the Stasis
+// application with application arguments. spy and whisper are directions
+// (none, both, in or out) for audio heard from and injected into the channel.
+func (c *ARIClient) SnoopChannel(channelID, spy, whisper, appArgs string) (*Channel, error) {
+	params := url.Values{
+		"app":     {c.appName},
+		"appArgs": {appArgs},
+		"spy":     {spy},
+		"whisper": {whisper},
+	}
+
+	resp, err := c.makeRequest("POST",
+		fmt.Sprintf("/ari/channels/%s/snoop?%s", channelID, params.Encode()), nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("failed to snoop channel: %s - %s", resp.Status, string(body))
+	}
+
+	var channel Channel
+	if err := json.NewDecoder(resp.Body).Decode(&channel); err != nil {
+		return nil, err
+	}
+
+	return &channel, nil
+}
+
+// ListChannels lists all channels known to Asterisk
+func (c *ARIClient) ListChannels() ([]Channel, error) {
+	resp, err := c.makeRequest("GET", "/ari/channels", nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("failed to list channels: %s - %s", resp.Status, string(body))
+	}
+
+	var channels []Channel
+	if err := json.NewDecoder(resp.Body).Decode(&channels); err != nil {
+		return nil, err
+	}
+
+	return channels, nil
+}
+
+// ListBridges lists all bridges known to Asterisk
+func (c *ARIClient) ListBridges() ([]Bridge, error) {
+	resp, err := c.makeRequest("GET", "/ari/bridges", nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("failed to list bridges: %s - %s", resp.Status, string(body))
+	}
+
+	var bridges []Bridge
+	if err := json.NewDecoder(resp.Body).Decode(&bridges); err != nil {
+		return nil, err
+	}
+
+	return bridges, nil
+}
+
 // CreateBridge creates a mixing bridge
 func (c *ARIClient) CreateBridge(bridgeType string) (*Bridge, error) {
 	resp, err := c.makeRequest("POST",
@@ -277,6 +586,31 @@ func (c *ARIClient) DestroyBridge(bridgeID string) error {
 	return nil
 }
 
+// RecordBridge starts recording the mixed audio of a bridge. An existing
+// recording with the same name is appended to, so a call that is re-bridged
+// after a transfer keeps a single file.
+func (c *ARIClient) RecordBridge(bridgeID, name, format string) (*Recording, error) {
+	resp, err := c.makeRequest("POST",
+		fmt.Sprintf("/ari/bridges/%s/record?name=%s&format=%s&ifExists=append&terminateOn=none",
+			bridgeID, url.QueryEscape(name), url.QueryEscape(format)), nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusCreated {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("failed to record bridge: %s - %s", resp.Status, string(body))
+	}
+
+	var recording Recording
+	if err := json.NewDecoder(resp.Body).Decode(&recording); err != nil {
+		return nil, err
+	}
+
+	return &recording, nil
+}
+
 // StartRecording starts recording a channel
 func (c *ARIClient) StartRecording(channelID, name, format string) (*Recording, error) {
 	resp, err := c.makeRequest("POST",
@@ -300,6 +634,30 @@ func (c *ARIClient) StartRecording(channelID, name, format string) (*Recording,
 	return &recording, nil
 }
 
+// RecordMessage records a caller leaving a message: after a beep, until they
+// press # or hang up, fall silent for maxSilence seconds or reach maxDuration
+func (c *ARIClient) RecordMessage(channelID, name, format string, maxDuration, maxSilence int) (*Recording, error) {
+	resp, err := c.makeRequest("POST",
+		fmt.Sprintf("/ari/channels/%s/record?name=%s&format=%s&maxDurationSeconds=%d&maxSilenceSeconds=%d&beep=true&terminateOn=%s&ifExists=overwrite",
+			channelID, url.QueryEscape(name), url.QueryEscape(format), maxDuration, maxSilence, url.QueryEscape("#")), nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusCreated {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("failed to record message: %s - %s", resp.Status, string(body))
+	}
+
+	var recording Recording
+	if err := json.NewDecoder(resp.Body).Decode(&recording); err != nil {
+		return nil, err
+	}
+
+	return &recording, nil
+}
+
 // StopRecording stops a recording
 func (c *ARIClient) StopRecording(recordingName string) error {
 	resp, err := c.makeRequest("POST",
@@ -358,3 +716,42 @@ func (c *ARIClient) SetChannelVariable(channelID, variable, value string) error
 
 	return nil
 }
+
+// GetEndpoint gets an endpoint's state and the channels it has up
+func (c *ARIClient) GetEndpoint(technology, resource string) (*Endpoint, error) {
+	resp, err := c.makeRequest("GET", fmt.Sprintf("/ari/endpoints/%s/%s",
+		url.PathEscape(technology), url.PathEscape(resource)), nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("failed to get endpoint: %s - %s", resp.Status, string(body))
+	}
+
+	var endpoint Endpoint
+	if err := json.NewDecoder(resp.Body).Decode(&endpoint); err != nil {
+		return nil, err
+	}
+
+	return &endpoint, nil
+}
+
+// ReloadModule reloads an Asterisk module, e.g. so it picks up realtime
+// objects it only reads at load time
+func (c *ARIClient) ReloadModule(module string) error {
+	resp, err := c.makeRequest("PUT", fmt.Sprintf("/ari/asterisk/modules/%s", url.PathEscape(module)), nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusNoContent {
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("failed to reload module: %s - %s", resp.Status, string(body))
+	}
+
+	return nil
+}
@@ -0,0 +1,491 @@
+package asterisk
+
+import (
+	"encoding/json"
+	"strings"
+	"time"
+)
+
+// Event is a strongly-typed ARI event. Handlers registered with
+// AddEventHandler can type-switch on ARIEvent.Event to get the full payload.
+type Event interface {
+	EventType() string
+}
+
+// ariTimeLayouts are the timestamp formats Asterisk emits (it omits the colon in the zone offset)
+var ariTimeLayouts = []string{
+	"2006-01-02T15:04:05.000-0700",
+	"2006-01-02T15:04:05-0700",
+	time.RFC3339Nano,
+}
+
+// ARITime is a timestamp as serialized by ARI
+type ARITime struct {
+	time.Time
+}
+
+// UnmarshalJSON parses ARI timestamps
+func (t *ARITime) UnmarshalJSON(data []byte) error {
+	s := strings.Trim(string(data), `"`)
+	if s == "" || s == "null" {
+		return nil
+	}
+
+	var err error
+	for _, layout := range ariTimeLayouts {
+		var parsed time.Time
+		if parsed, err = time.Parse(layout, s); err == nil {
+			t.Time = parsed
+			return nil
+		}
+	}
+	return err
+}
+
+// MarshalJSON serializes the timestamp as RFC 3339
+func (t ARITime) MarshalJSON() ([]byte, error) {
+	if t.IsZero() {
+		return []byte("null"), nil
+	}
+	return json.Marshal(t.Time)
+}
+
+// UnmarshalJSON decodes the common envelope, keeps the raw payload and
+// decodes the typed event for the event type
+func (e *ARIEvent) UnmarshalJSON(data []byte) error {
+	type envelope ARIEvent
+	var env envelope
+	if err := json.Unmarshal(data, &env); err != nil {
+		return err
+	}
+	*e = ARIEvent(env)
+
+	e.Raw = append(json.RawMessage(nil), data...)
+
+	if err := json.Unmarshal(data, &e.Data); err != nil {
+		return err
+	}
+
+	event, err := decodeEvent(e.Type, data)
+	if err != nil {
+		return err
+	}
+	e.Event = event
+
+	return nil
+}
+
+// decodeEvent decodes the payload into the typed struct for eventType.
+// Unknown types are preserved as *UnknownEvent.
+func decodeEvent(eventType string, data []byte) (Event, error) {
+	var event Event
+
+	switch eventType {
+	case EventStasisStart:
+		event = &StasisStartEvent{}
+	case EventStasisEnd:
+		event = &StasisEndEvent{}
+	case EventChannelCreated:
+		event = &ChannelCreatedEvent{}
+	case EventChannelDestroyed:
+		event = &ChannelDestroyedEvent{}
+	case EventChannelStateChange:
+		event = &ChannelStateChangeEvent{}
+	case EventChannelDtmfReceived:
+		event = &ChannelDtmfReceivedEvent{}
+	case EventChannelHangupRequest:
+		event = &ChannelHangupRequestEvent{}
+	case EventChannelCallerId:
+		event = &ChannelCallerIdEvent{}
+	case EventChannelConnectedLine:
+		event = &ChannelConnectedLineEvent{}
+	case EventChannelDialplan:
+		event = &ChannelDialplanEvent{}
+	case EventChannelVarset:
+		event = &ChannelVarsetEvent{}
+	case EventChannelUserevent:
+		event = &ChannelUsereventEvent{}
+	case EventChannelHold:
+		event = &ChannelHoldEvent{}
+	case EventChannelUnhold:
+		event = &ChannelUnholdEvent{}
+	case EventChannelTalkingStarted:
+		event = &ChannelTalkingStartedEvent{}
+	case EventChannelTalkingFinished:
+		event = &ChannelTalkingFinishedEvent{}
+	case EventDial:
+		event = &DialEvent{}
+	case EventBridgeCreated:
+		event = &BridgeCreatedEvent{}
+	case EventBridgeDestroyed:
+		event = &BridgeDestroyedEvent{}
+	case EventBridgeMerged:
+		event = &BridgeMergedEvent{}
+	case EventBridgeBlindTransfer:
+		event = &BridgeBlindTransferEvent{}
+	case EventBridgeAttendedTransfer:
+		event = &BridgeAttendedTransferEvent{}
+	case EventChannelEnteredBridge:
+		event = &ChannelEnteredBridgeEvent{}
+	case EventChannelLeftBridge:
+		event = &ChannelLeftBridgeEvent{}
+	case EventPlaybackStarted:
+		event = &PlaybackStartedEvent{}
+	case EventPlaybackContinuing:
+		event = &PlaybackContinuingEvent{}
+	case EventPlaybackFinished:
+		event = &PlaybackFinishedEvent{}
+	case EventRecordingStarted:
+		event = &RecordingStartedEvent{}
+	case EventRecordingFinished:
+		event = &RecordingFinishedEvent{}
+	case EventRecordingFailed:
+		event = &RecordingFailedEvent{}
+	case EventEndpointStateChange:
+		event = &EndpointStateChangeEvent{}
+	case EventPeerStatusChange:
+		event = &PeerStatusChangeEvent{}
+	case EventDeviceStateChanged:
+		event = &DeviceStateChangedEvent{}
+	case EventContactStatusChange:
+		event = &ContactStatusChangeEvent{}
+	case EventApplicationReplaced:
+		event = &ApplicationReplacedEvent{}
+	case EventTextMessageReceived:
+		event = &TextMessageReceivedEvent{}
+	default:
+		return &UnknownEvent{Type: eventType, Raw: append(json.RawMessage(nil), data...)}, nil
+	}
+
+	if err := json.Unmarshal(data, event); err != nil {
+		return nil, err
+	}
+	return event, nil
+}
+
+// EventBase holds the fields present on every ARI event
+type EventBase struct {
+	Type        string  `json:"type"`
+	Timestamp   ARITime `json:"timestamp"`
+	Application string  `json:"application,omitempty"`
+	AsteriskID  string  `json:"asterisk_id,omitempty"`
+}
+
+// EventType returns the ARI event type
+func (e *EventBase) EventType() string { return e.Type }
+
+// UnknownEvent preserves an event type this package does not model
+type UnknownEvent struct {
+	Type string
+	Raw  json.RawMessage
+}
+
+// EventType returns the ARI event type
+func (e *UnknownEvent) EventType() string { return e.Type }
+
+// Channel events
+
+// StasisStartEvent is sent when a channel enters the application
+type StasisStartEvent struct {
+	EventBase
+	Args           []string `json:"args"`
+	Channel        *Channel `json:"channel"`
+	ReplaceChannel *Channel `json:"replace_channel,omitempty"`
+}
+
+// StasisEndEvent is sent when a channel leaves the application
+type StasisEndEvent struct {
+	EventBase
+	Channel *Channel `json:"channel"`
+}
+
+// ChannelCreatedEvent is sent when a channel is created
+type ChannelCreatedEvent struct {
+	EventBase
+	Channel *Channel `json:"channel"`
+}
+
+// ChannelDestroyedEvent is sent when a channel is destroyed
+type ChannelDestroyedEvent struct {
+	EventBase
+	Cause    int      `json:"cause"`
+	CauseTxt string   `json:"cause_txt"`
+	Channel  *Channel `json:"channel"`
+}
+
+// ChannelStateChangeEvent is sent when a channel's state changes
+type ChannelStateChangeEvent struct {
+	EventBase
+	Channel *Channel `json:"channel"`
+}
+
+// ChannelDtmfReceivedEvent is sent when a DTMF digit is received on a channel
+type ChannelDtmfReceivedEvent struct {
+	EventBase
+	Digit      string   `json:"digit"`
+	DurationMs int      `json:"duration_ms"`
+	Channel    *Channel `json:"channel"`
+}
+
+// ChannelHangupRequestEvent is sent when a hangup is requested on a channel
+type ChannelHangupRequestEvent struct {
+	EventBase
+	Cause   int      `json:"cause,omitempty"`
+	Soft    bool     `json:"soft,omitempty"`
+	Channel *Channel `json:"channel"`
+}
+
+// ChannelCallerIdEvent is sent when a channel's caller ID changes
+type ChannelCallerIdEvent struct {
+	EventBase
+	CallerPresentation    int      `json:"caller_presentation"`
+	CallerPresentationTxt string   `json:"caller_presentation_txt"`
+	Channel               *Channel `json:"channel"`
+}
+
+// ChannelConnectedLineEvent is sent when a channel's connected line changes
+type ChannelConnectedLineEvent struct {
+	EventBase
+	Channel *Channel `json:"channel"`
+}
+
+// ChannelDialplanEvent is sent when a channel changes dialplan location
+type ChannelDialplanEvent struct {
+	EventBase
+	DialplanApp     string   `json:"dialplan_app"`
+	DialplanAppData string   `json:"dialplan_app_data"`
+	Channel         *Channel `json:"channel"`
+}
+
+// ChannelVarsetEvent is sent when a channel variable changes. Channel is nil for global variables.
+type ChannelVarsetEvent struct {
+	EventBase
+	Variable string   `json:"variable"`
+	Value    string   `json:"value"`
+	Channel  *Channel `json:"channel,omitempty"`
+}
+
+// ChannelUsereventEvent is sent for a UserEvent raised from the dialplan or ARI
+type ChannelUsereventEvent struct {
+	EventBase
+	EventName string                 `json:"eventname"`
+	Channel   *Channel               `json:"channel,omitempty"`
+	Bridge    *Bridge                `json:"bridge,omitempty"`
+	Endpoint  *Endpoint              `json:"endpoint,omitempty"`
+	Userevent map[string]interface{} `json:"userevent"`
+}
+
+// ChannelHoldEvent is sent when a channel is placed on hold
+type ChannelHoldEvent struct {
+	EventBase
+	MusicClass string   `json:"musicclass,omitempty"`
+	Channel    *Channel `json:"channel"`
+}
+
+// ChannelUnholdEvent is sent when a channel is taken off hold
+type ChannelUnholdEvent struct {
+	EventBase
+	Channel *Channel `json:"channel"`
+}
+
+// ChannelTalkingStartedEvent is sent when talk detection sees speech start
+type ChannelTalkingStartedEvent struct {
+	EventBase
+	Channel *Channel `json:"channel"`
+}
+
+// ChannelTalkingFinishedEvent is sent when talk detection sees speech end
+type ChannelTalkingFinishedEvent struct {
+	EventBase
+	Duration int      `json:"duration"` // milliseconds of talking
+	Channel  *Channel `json:"channel"`
+}
+
+// DialEvent is sent as a dial operation progresses
+type DialEvent struct {
+	EventBase
+	Caller     *Channel `json:"caller,omitempty"`
+	Peer       *Channel `json:"peer"`
+	Forward    string   `json:"forward,omitempty"`
+	Forwarded  *Channel `json:"forwarded,omitempty"`
+	Dialstring string   `json:"dialstring,omitempty"`
+	Dialstatus string   `json:"dialstatus"`
+}
+
+// Bridge events
+
+// BridgeCreatedEvent is sent when a bridge is created
+type BridgeCreatedEvent struct {
+	EventBase
+	Bridge *Bridge `json:"bridge"`
+}
+
+// BridgeDestroyedEvent is sent when a bridge is destroyed
+type BridgeDestroyedEvent struct {
+	EventBase
+	Bridge *Bridge `json:"bridge"`
+}
+
+// BridgeMergedEvent is sent when one bridge is merged into another
+type BridgeMergedEvent struct {
+	EventBase
+	Bridge     *Bridge `json:"bridge"`
+	BridgeFrom *Bridge `json:"bridge_from"`
+}
+
+// BridgeBlindTransferEvent is sent when a blind transfer is performed
+type BridgeBlindTransferEvent struct {
+	EventBase
+	Channel        *Channel `json:"channel"`
+	ReplaceChannel *Channel `json:"replace_channel,omitempty"`
+	Transferee     *Channel `json:"transferee,omitempty"`
+	Exten          string   `json:"exten"`
+	Context        string   `json:"context"`
+	Result         string   `json:"result"`
+	IsExternal     bool     `json:"is_external"`
+	Bridge         *Bridge  `json:"bridge,omitempty"`
+}
+
+// BridgeAttendedTransferEvent is sent when an attended transfer is performed
+type BridgeAttendedTransferEvent struct {
+	EventBase
+	TransfererFirstLeg         *Channel `json:"transferer_first_leg"`
+	TransfererSecondLeg        *Channel `json:"transferer_second_leg"`
+	ReplaceChannel             *Channel `json:"replace_channel,omitempty"`
+	Transferee                 *Channel `json:"transferee,omitempty"`
+	TransferTarget             *Channel `json:"transfer_target,omitempty"`
+	Result                     string   `json:"result"`
+	IsExternal                 bool     `json:"is_external"`
+	TransfererFirstLegBridge   *Bridge  `json:"transferer_first_leg_bridge,omitempty"`
+	TransfererSecondLegBridge  *Bridge  `json:"transferer_second_leg_bridge,omitempty"`
+	DestinationType            string   `json:"destination_type"`
+	DestinationBridge          string   `json:"destination_bridge,omitempty"`
+	DestinationApplication     string   `json:"destination_application,omitempty"`
+	DestinationLinkFirstLeg    *Channel `json:"destination_link_first_leg,omitempty"`
+	DestinationLinkSecondLeg   *Channel `json:"destination_link_second_leg,omitempty"`
+	DestinationThreewayChannel *Channel `json:"destination_threeway_channel,omitempty"`
+	DestinationThreewayBridge  *Bridge  `json:"destination_threeway_bridge,omitempty"`
+}
+
+// ChannelEnteredBridgeEvent is sent when a channel enters a bridge
+type ChannelEnteredBridgeEvent struct {
+	EventBase
+	Bridge  *Bridge  `json:"bridge"`
+	Channel *Channel `json:"channel,omitempty"`
+}
+
+// ChannelLeftBridgeEvent is sent when a channel leaves a bridge
+type ChannelLeftBridgeEvent struct {
+	EventBase
+	Bridge  *Bridge  `json:"bridge"`
+	Channel *Channel `json:"channel"`
+}
+
+// Media events
+
+// PlaybackStartedEvent is sent when a playback starts
+type PlaybackStartedEvent struct {
+	EventBase
+	Playback *Playback `json:"playback"`
+}
+
+// PlaybackContinuingEvent is sent when a playback moves to the next media URI
+type PlaybackContinuingEvent struct {
+	EventBase
+	Playback *Playback `json:"playback"`
+}
+
+// PlaybackFinishedEvent is sent when a playback finishes
+type PlaybackFinishedEvent struct {
+	EventBase
+	Playback *Playback `json:"playback"`
+}
+
+// RecordingStartedEvent is sent when a recording starts
+type RecordingStartedEvent struct {
+	EventBase
+	Recording *Recording `json:"recording"`
+}
+
+// RecordingFinishedEvent is sent when a recording finishes
+type RecordingFinishedEvent struct {
+	EventBase
+	Recording *Recording `json:"recording"`
+}
+
+// RecordingFailedEvent is sent when a recording fails
+type RecordingFailedEvent struct {
+	EventBase
+	Recording *Recording `json:"recording"`
+}
+
+// Endpoint and device events
+
+// EndpointStateChangeEvent is sent when an endpoint changes state
+type EndpointStateChangeEvent struct {
+	EventBase
+	Endpoint *Endpoint `json:"endpoint"`
+}
+
+// PeerStatusChangeEvent is sent when a SIP peer's status changes
+type PeerStatusChangeEvent struct {
+	EventBase
+	Endpoint *Endpoint `json:"endpoint"`
+	Peer     Peer      `json:"peer"`
+}
+
+// ContactStatusChangeEvent is sent when a PJSIP contact's status changes
+type ContactStatusChangeEvent struct {
+	EventBase
+	Endpoint    *Endpoint   `json:"endpoint"`
+	ContactInfo ContactInfo `json:"contact_info"`
+}
+
+// DeviceStateChangedEvent is sent when a subscribed device state changes
+type DeviceStateChangedEvent struct {
+	EventBase
+	DeviceState DeviceState `json:"device_state"`
+}
+
+// ApplicationReplacedEvent is sent when another WebSocket takes over the application
+type ApplicationReplacedEvent struct {
+	EventBase
+}
+
+// TextMessageReceivedEvent is sent when an out-of-call text message arrives
+type TextMessageReceivedEvent struct {
+	EventBase
+	Message  TextMessage `json:"message"`
+	Endpoint *Endpoint   `json:"endpoint,omitempty"`
+}
+
+// Peer is the status of a SIP peer
+type Peer struct {
+	PeerStatus string `json:"peer_status"`
+	Cause      string `json:"cause,omitempty"`
+	Address    string `json:"address,omitempty"`
+	Port       string `json:"port,omitempty"`
+	Time       string `json:"time,omitempty"`
+}
+
+// ContactInfo is the status of a PJSIP contact
+type ContactInfo struct {
+	URI           string `json:"uri"`
+	ContactStatus string `json:"contact_status"`
+	AOR           string `json:"aor"`
+	RoundtripUsec string `json:"roundtrip_usec,omitempty"`
+}
+
+// DeviceState is the state of a device
+type DeviceState struct {
+	Name  string `json:"name"`
+	State string `json:"state"`
+}
+
+// TextMessage is an out-of-call text message
+type TextMessage struct {
+	From      string            `json:"from"`
+	To        string            `json:"to"`
+	Body      string            `json:"body"`
+	Variables map[string]string `json:"variables,omitempty"`
+}
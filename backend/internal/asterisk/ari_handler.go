@@ -5,7 +5,8 @@ import (
 	"fmt"
 	"log"
 	"sync"
-	"time"
+
+	"github.com/psschand/callcenter/internal/common"
 )
 
 // CallHandler handles ARI call events
@@ -15,6 +16,75 @@ type CallHandler struct {
 	activeChannels map[string]*Channel
 	activeBridges  map[string]*Bridge
 	eventHandlers  []EventHandler
+	connStatus     ConnectionStatus
+
+	// Inbound routing
+	dids                DIDResolver
+	routing             RoutingConfig
+	routeHandlers       map[common.RouteType]RouteHandler
+	calls               map[string]*Call
+	pendingDials        map[string]string // outbound channel -> channel that dialed it
+	peers               map[string]string // bridged channel -> other leg
+	callBridges         map[string]string // bridged channel -> bridge
+	hangupAfterPlayback map[string]string // playback -> channel
+
+	// IVR
+	ivr          IVRMenuLoader
+	ivrSessions  map[string]*ivrSession
+	ivrPlaybacks map[string]string // playback -> channel
+
+	// Voicemail
+	mailboxes           MailboxLoader
+	voicemailListener   VoicemailListener
+	voicemailSessions   map[string]*voicemailSession
+	voicemailPlaybacks  map[string]string           // playback -> channel
+	voicemailRecordings map[string]VoicemailMessage // recording name -> message
+	voicemailFallbacks  map[string]string           // caller channel -> endpoint whose mailbox takes the call if unanswered
+
+	// Queues
+	acd            *acdEngine
+	queueCallbacks QueueCallbackStore
+
+	// Outbound trunks
+	outboundRoutes OutboundRouteLoader
+	trunkFailovers map[string]trunkFailover // dialed trunk leg -> trunks left to try
+
+	// Blocked callers
+	callerScreen CallerScreen
+
+	// Business hours
+	schedules ScheduleLoader
+
+	// Post-call surveys
+	surveys         SurveyLoader
+	surveyListener  SurveyListener
+	surveySessions  map[string]*surveySession
+	surveyPlaybacks map[string]string // playback -> channel
+
+	// Click-to-call
+	outboundCalls  map[string]*OutboundCall
+	outboundLegs   map[string]string // agent or destination channel -> outbound call
+	outboundEvents chan OutboundCall
+
+	// Call control
+	held        map[string]bool
+	transfers   map[string]*attendedTransfer // transferred channel -> transfer
+	consultLegs map[string]string            // transfer target channel -> transferred channel
+
+	// Supervisor monitoring
+	monitors        map[int64]*monitorSession
+	monitorLegs     map[string]int64 // supervisor or snoop channel -> monitor session
+	monitorListener MonitorSessionListener
+
+	// Call recording
+	recordingPolicy   RecordingPolicy
+	recordingListener RecordingListener
+	recordings        map[string]*callRecording // recording name -> call
+
+	// Call detail records
+	callDetails        map[string]*callDetail // caller channel -> segment being built
+	callDetailListener CallDetailListener
+	callEvents         chan CallEvent
 }
 
 // EventHandler is a function that handles ARI events
@@ -22,12 +92,40 @@ type EventHandler func(event ARIEvent)
 
 // NewCallHandler creates a new call handler
 func NewCallHandler(client *ARIClient) *CallHandler {
-	return &CallHandler{
-		client:         client,
-		activeChannels: make(map[string]*Channel),
-		activeBridges:  make(map[string]*Bridge),
-		eventHandlers:  []EventHandler{},
+	h := &CallHandler{
+		client:              client,
+		activeChannels:      make(map[string]*Channel),
+		activeBridges:       make(map[string]*Bridge),
+		eventHandlers:       []EventHandler{},
+		connStatus:          ConnectionStatus{State: ConnectionStateDisconnected},
+		routing:             DefaultRoutingConfig(),
+		routeHandlers:       make(map[common.RouteType]RouteHandler),
+		calls:               make(map[string]*Call),
+		pendingDials:        make(map[string]string),
+		peers:               make(map[string]string),
+		callBridges:         make(map[string]string),
+		hangupAfterPlayback: make(map[string]string),
+		ivrSessions:         make(map[string]*ivrSession),
+		ivrPlaybacks:        make(map[string]string),
+		voicemailSessions:   make(map[string]*voicemailSession),
+		voicemailPlaybacks:  make(map[string]string),
+		voicemailRecordings: make(map[string]VoicemailMessage),
+		voicemailFallbacks:  make(map[string]string),
+		trunkFailovers:      make(map[string]trunkFailover),
+		surveySessions:      make(map[string]*surveySession),
+		surveyPlaybacks:     make(map[string]string),
+		outboundCalls:       make(map[string]*OutboundCall),
+		outboundLegs:        make(map[string]string),
+		held:                make(map[string]bool),
+		transfers:           make(map[string]*attendedTransfer),
+		consultLegs:         make(map[string]string),
+		monitors:            make(map[int64]*monitorSession),
+		monitorLegs:         make(map[string]int64),
+		recordings:          make(map[string]*callRecording),
+		callDetails:         make(map[string]*callDetail),
 	}
+	h.registerDefaultRouteHandlers()
+	return h
 }
 
 // AddEventHandler adds an event handler
@@ -35,40 +133,21 @@ func (h *CallHandler) AddEventHandler(handler EventHandler) {
 	h.eventHandlers = append(h.eventHandlers, handler)
 }
 
-// Start starts listening for ARI events
+// Start starts listening for ARI events. The connection is supervised until ctx
+// is cancelled: if the initial connect fails the error is returned, but the
+// handler keeps retrying in the background and recovers once Asterisk is up.
 func (h *CallHandler) Start(ctx context.Context) error {
-	if err := h.client.Connect(ctx); err != nil {
-		return fmt.Errorf("failed to connect to ARI: %w", err)
+	h.setConnectionState(ConnectionStateConnecting, nil)
+
+	err := h.client.Connect(ctx)
+	if err != nil {
+		h.setConnectionState(ConnectionStateReconnecting, err)
+		err = fmt.Errorf("failed to connect to ARI: %w", err)
 	}
 
-	events, errors := h.client.ReadEvents()
-
-	go func() {
-		for {
-			select {
-			case <-ctx.Done():
-				log.Println("Stopping ARI call handler")
-				h.client.Close()
-				return
-
-			case event, ok := <-events:
-				if !ok {
-					log.Println("ARI events channel closed")
-					return
-				}
-				h.handleEvent(event)
-
-			case err, ok := <-errors:
-				if !ok {
-					log.Println("ARI errors channel closed")
-					return
-				}
-				log.Printf("ARI error: %v", err)
-			}
-		}
-	}()
+	go h.supervise(ctx, err == nil)
 
-	return nil
+	return err
 }
 
 // handleEvent processes an ARI event
@@ -100,6 +179,10 @@ func (h *CallHandler) handleEvent(event ARIEvent) {
 		h.onBridgeCreated(event)
 	case EventBridgeDestroyed:
 		h.onBridgeDestroyed(event)
+	case EventPlaybackFinished:
+		h.onPlaybackFinished(event)
+	case EventRecordingFinished, EventRecordingFailed:
+		h.onRecordingEnded(event)
 	}
 }
 
@@ -118,29 +201,40 @@ func (h *CallHandler) onStasisStart(event ARIEvent) {
 	h.activeChannels[channel.ID] = channel
 	h.mu.Unlock()
 
-	// Answer the call
-	if err := h.client.AnswerChannel(channel.ID); err != nil {
-		log.Printf("Error answering channel %s: %v", channel.ID, err)
+	// Outbound legs we originated enter Stasis when they answer
+	if len(event.Args) > 0 && event.Args[0] == appArgDialed {
+		if len(event.Args) > 1 {
+			h.onOutboundDestAnswered(channel, event.Args[1])
+		}
+		go h.onDialedAnswered(channel, event.Args)
 		return
 	}
-
-	// Play greeting
-	if _, err := h.client.PlaySound(channel.ID, "hello-world"); err != nil {
-		log.Printf("Error playing sound on channel %s: %v", channel.ID, err)
+	if len(event.Args) > 0 && event.Args[0] == appArgConsult {
+		go h.onConsultAnswered(channel, event.Args)
+		return
+	}
+	if len(event.Args) > 0 && event.Args[0] == appArgOriginate {
+		go h.onOriginateAgentAnswered(channel, event.Args)
+		return
+	}
+	if len(event.Args) > 0 && event.Args[0] == appArgMonitor {
+		go h.onMonitorAnswered(channel, event.Args)
+		return
+	}
+	if len(event.Args) > 0 && event.Args[0] == appArgSnoop {
+		go h.onSnoopStarted(channel, event.Args)
+		return
+	}
+	if len(event.Args) > 0 && event.Args[0] == appArgQueued && h.acd != nil {
+		go h.acd.onAgentAnswered(channel, event.Args)
+		return
+	}
+	if len(event.Args) > 0 && event.Args[0] == appArgCallback && h.acd != nil {
+		go h.acd.onCallbackAnswered(channel, event.Args)
+		return
 	}
 
-	// Auto-hangup after 30 seconds (for testing)
-	go func() {
-		time.Sleep(30 * time.Second)
-		h.mu.RLock()
-		_, exists := h.activeChannels[channel.ID]
-		h.mu.RUnlock()
-
-		if exists {
-			log.Printf("Auto-hanging up channel %s after 30s", channel.ID)
-			h.client.HangupChannel(channel.ID)
-		}
-	}()
+	go h.routeInboundCall(channel, event.Args)
 }
 
 // onStasisEnd handles call end
@@ -155,6 +249,8 @@ func (h *CallHandler) onStasisEnd(event ARIEvent) {
 	h.mu.Lock()
 	delete(h.activeChannels, channel.ID)
 	h.mu.Unlock()
+
+	go h.releaseCall(channel.ID)
 }
 
 // onChannelStateChange handles channel state changes
@@ -184,36 +280,55 @@ func (h *CallHandler) onChannelDestroyed(event ARIEvent) {
 
 	h.mu.Lock()
 	delete(h.activeChannels, channel.ID)
+	inboundID, wasDialing := h.pendingDials[channel.ID]
+	delete(h.pendingDials, channel.ID)
 	h.mu.Unlock()
+
+	cause := 0
+	if destroyed, ok := event.Event.(*ChannelDestroyedEvent); ok {
+		cause = destroyed.Cause
+	}
+
+	// An originated leg that never entered Stasis was not answered; if its
+	// trunk could not place the call, the next trunk is tried
+	failover, failingOver := h.takeTrunkFailover(channel.ID, cause)
+	if wasDialing {
+		if failingOver {
+			go h.failOverTrunk(channel.ID, failover, cause)
+		} else {
+			h.noteCallDisposition(inboundID, dispositionFromCause(cause))
+			go h.onDialFailed(channel.ID, inboundID)
+		}
+	}
+
+	if h.acd != nil {
+		h.acd.onChannelGone(channel.ID, cause)
+	}
+
+	h.onOutboundLegDestroyed(channel.ID, cause)
+
+	// A transfer target that never answered
+	go h.endTransferLeg(channel.ID)
+
+	// A supervisor who never answered
+	go h.endMonitorLeg(channel.ID)
 }
 
 // onDTMFReceived handles DTMF events
 func (h *CallHandler) onDTMFReceived(event ARIEvent) {
-	if event.Channel == nil {
+	dtmf, ok := event.Event.(*ChannelDtmfReceivedEvent)
+	if !ok || dtmf.Channel == nil {
 		return
 	}
 
-	// DTMF digit is in the event data
-	digit := ""
-	if d, ok := event.Data["digit"].(string); ok {
-		digit = d
-	}
+	log.Printf("DTMF received on channel %s: %s", dtmf.Channel.ID, dtmf.Digit)
 
-	log.Printf("DTMF received on channel %s: %s", event.Channel.ID, digit)
-
-	// Handle DTMF menu
-	switch digit {
-	case "1":
-		// Transfer to extension 100
-		log.Printf("Transferring to extension 100")
-		h.TransferToExtension(event.Channel.ID, "PJSIP/100")
-	case "2":
-		// Transfer to extension 101
-		log.Printf("Transferring to extension 101")
-		h.TransferToExtension(event.Channel.ID, "PJSIP/101")
-	case "#":
-		// Hangup
-		h.client.HangupChannel(event.Channel.ID)
+	// Digits only drive the channel's IVR menu, survey, queue callback offer
+	// or voicemail greeting, if it is in one
+	if !h.onIVRDigit(dtmf.Channel.ID, dtmf.Digit) &&
+		!h.onSurveyDigit(dtmf.Channel.ID, dtmf.Digit) &&
+		!h.onQueueCallbackDigit(dtmf.Channel.ID, dtmf.Digit) {
+		h.onVoicemailDigit(dtmf.Channel.ID)
 	}
 }
 
@@ -261,38 +376,6 @@ func (h *CallHandler) onBridgeDestroyed(event ARIEvent) {
 	h.mu.Unlock()
 }
 
-// TransferToExtension transfers a call to an extension
-func (h *CallHandler) TransferToExtension(channelID, endpoint string) error {
-	// Create a bridge
-	bridge, err := h.client.CreateBridge("mixing")
-	if err != nil {
-		return fmt.Errorf("failed to create bridge: %w", err)
-	}
-
-	// Add the original channel to the bridge
-	if err := h.client.AddChannelToBridge(bridge.ID, channelID); err != nil {
-		return fmt.Errorf("failed to add channel to bridge: %w", err)
-	}
-
-	// Dial the extension
-	outbound, err := h.client.DialEndpoint(endpoint, "", "CallCenter")
-	if err != nil {
-		h.client.DestroyBridge(bridge.ID)
-		return fmt.Errorf("failed to dial endpoint: %w", err)
-	}
-
-	// Add the outbound channel to the bridge
-	if err := h.client.AddChannelToBridge(bridge.ID, outbound.ID); err != nil {
-		h.client.HangupChannel(outbound.ID)
-		h.client.DestroyBridge(bridge.ID)
-		return fmt.Errorf("failed to add outbound channel to bridge: %w", err)
-	}
-
-	log.Printf("Transferred channel %s to %s via bridge %s", channelID, endpoint, bridge.ID)
-
-	return nil
-}
-
 // GetActiveChannels returns all active channels
 func (h *CallHandler) GetActiveChannels() map[string]*Channel {
 	h.mu.RLock()
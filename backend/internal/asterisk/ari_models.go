@@ -1,18 +1,24 @@
 package asterisk
 
-import "time"
+import (
+	"encoding/json"
+)
 
-// ARIEvent represents an event from Asterisk ARI
+// ARIEvent represents an event from Asterisk ARI. The common fields are
+// decoded for convenience; Event holds the typed payload for the event type.
 type ARIEvent struct {
 	Type        string                 `json:"type"`
-	Timestamp   time.Time              `json:"timestamp"`
+	Timestamp   ARITime                `json:"timestamp"`
 	Application string                 `json:"application,omitempty"`
 	Channel     *Channel               `json:"channel,omitempty"`
 	Playback    *Playback              `json:"playback,omitempty"`
 	Recording   *Recording             `json:"recording,omitempty"`
 	Bridge      *Bridge                `json:"bridge,omitempty"`
 	Endpoint    *Endpoint              `json:"endpoint,omitempty"`
-	Data        map[string]interface{} `json:"-"` // For additional fields
+	Args        []string               `json:"args,omitempty"` // StasisStart application arguments
+	Event       Event                  `json:"-"`              // Typed payload, *UnknownEvent for unmodelled types
+	Raw         json.RawMessage        `json:"-"`              // Original JSON
+	Data        map[string]interface{} `json:"-"`              // All fields, untyped
 }
 
 // Channel represents an ARI channel
@@ -24,7 +30,7 @@ type Channel struct {
 	Connected    CallerID          `json:"connected"`
 	AccountCode  string            `json:"accountcode"`
 	Dialplan     DialplanCEP       `json:"dialplan"`
-	CreationTime time.Time         `json:"creationtime"`
+	CreationTime ARITime           `json:"creationtime"`
 	Language     string            `json:"language"`
 	ChannelVars  map[string]string `json:"channelvars,omitempty"`
 }
@@ -46,23 +52,24 @@ type DialplanCEP struct {
 
 // Bridge represents an ARI bridge
 type Bridge struct {
-	ID           string    `json:"id"`
-	Technology   string    `json:"technology"`
-	BridgeType   string    `json:"bridge_type"`
-	BridgeClass  string    `json:"bridge_class"`
-	Creator      string    `json:"creator"`
-	Name         string    `json:"name"`
-	Channels     []string  `json:"channels"`
-	CreationTime time.Time `json:"creationtime"`
+	ID           string   `json:"id"`
+	Technology   string   `json:"technology"`
+	BridgeType   string   `json:"bridge_type"`
+	BridgeClass  string   `json:"bridge_class"`
+	Creator      string   `json:"creator"`
+	Name         string   `json:"name"`
+	Channels     []string `json:"channels"`
+	CreationTime ARITime  `json:"creationtime"`
 }
 
 // Playback represents a media playback
 type Playback struct {
-	ID        string `json:"id"`
-	MediaURI  string `json:"media_uri"`
-	TargetURI string `json:"target_uri"`
-	Language  string `json:"language"`
-	State     string `json:"state"`
+	ID           string `json:"id"`
+	MediaURI     string `json:"media_uri"`
+	TargetURI    string `json:"target_uri"`
+	Language     string `json:"language"`
+	State        string `json:"state"`
+	NextMediaURI string `json:"next_media_uri,omitempty"`
 }
 
 // Recording represents a recording
@@ -74,6 +81,7 @@ type Recording struct {
 	TalkingDuration int    `json:"talking_duration,omitempty"`
 	Silence         int    `json:"silence_duration,omitempty"`
 	TargetURI       string `json:"target_uri"`
+	Cause           string `json:"cause,omitempty"` // Set when the recording failed
 }
 
 // Endpoint represents a SIP endpoint
@@ -102,6 +110,7 @@ const (
 	EventChannelUnhold          = "ChannelUnhold"
 	EventChannelTalkingStarted  = "ChannelTalkingStarted"
 	EventChannelTalkingFinished = "ChannelTalkingFinished"
+	EventDial                   = "Dial"
 
 	EventBridgeCreated          = "BridgeCreated"
 	EventBridgeDestroyed        = "BridgeDestroyed"
@@ -111,8 +120,9 @@ const (
 	EventChannelEnteredBridge   = "ChannelEnteredBridge"
 	EventChannelLeftBridge      = "ChannelLeftBridge"
 
-	EventPlaybackStarted  = "PlaybackStarted"
-	EventPlaybackFinished = "PlaybackFinished"
+	EventPlaybackStarted    = "PlaybackStarted"
+	EventPlaybackContinuing = "PlaybackContinuing"
+	EventPlaybackFinished   = "PlaybackFinished"
 
 	EventRecordingStarted  = "RecordingStarted"
 	EventRecordingFinished = "RecordingFinished"
@@ -120,6 +130,8 @@ const (
 
 	EventEndpointStateChange = "EndpointStateChange"
 	EventPeerStatusChange    = "PeerStatusChange"
+	EventContactStatusChange = "ContactStatusChange"
+	EventDeviceStateChanged  = "DeviceStateChanged"
 
 	EventApplicationReplaced = "ApplicationReplaced"
 	EventTextMessageReceived = "TextMessageReceived"
@@ -0,0 +1,102 @@
+package asterisk
+
+import (
+	"strings"
+	"time"
+
+	"github.com/psschand/callcenter/internal/common"
+	"github.com/psschand/callcenter/internal/core"
+)
+
+// BlacklistAnonymous is the pattern that matches callers withholding their
+// number
+const BlacklistAnonymous = "anonymous"
+
+// BlacklistEntry blocks calls and SMS from numbers matching PhoneNumber
+// until ExpiresAt. PhoneNumber is a number, a pattern where ? stands for any
+// digit and a trailing * for any remaining digits, or BlacklistAnonymous.
+// Numbers are compared by their digits only, so +1555* matches 15551234567.
+// @Description Blocked caller number or pattern
+type BlacklistEntry struct {
+	ID              int64                 `gorm:"column:id;primaryKey;autoIncrement" json:"id" example:"1"`
+	TenantID        string                `gorm:"column:tenant_id;type:varchar(36);not null;uniqueIndex:idx_tenant_phone" json:"tenant_id" example:"acme-corp"`
+	PhoneNumber     string                `gorm:"column:phone_number;type:varchar(50);not null;uniqueIndex:idx_tenant_phone" json:"phone_number" example:"+1900*"`
+	Reason          *string               `gorm:"column:reason;type:text" json:"reason,omitempty" example:"Robocaller"`
+	BlockCalls      bool                  `gorm:"column:block_calls;not null;default:true" json:"block_calls" example:"true"`
+	BlockSMS        bool                  `gorm:"column:block_sms;not null;default:true" json:"block_sms" example:"true"`
+	Treatment       common.BlockTreatment `gorm:"column:treatment;type:enum('reject','busy','message','voicemail');not null;default:reject" json:"treatment" example:"reject"`
+	TreatmentTarget *string               `gorm:"column:treatment_target;type:varchar(255)" json:"treatment_target,omitempty" example:"custom/blocked"`
+	AddedBy         *int64                `gorm:"column:added_by" json:"added_by,omitempty" example:"1"`
+	ExpiresAt       *time.Time            `gorm:"column:expires_at;index" json:"expires_at,omitempty"`
+	CreatedAt       time.Time             `gorm:"column:created_at;autoCreateTime" json:"created_at"`
+	UpdatedAt       time.Time             `gorm:"column:updated_at;autoUpdateTime" json:"updated_at"`
+
+	// Relations
+	Tenant *core.Tenant `gorm:"foreignKey:TenantID" json:"tenant,omitempty"`
+}
+
+// TableName specifies the table name
+func (BlacklistEntry) TableName() string {
+	return "blacklist"
+}
+
+// IsExpired checks if the entry no longer blocks anything
+func (e *BlacklistEntry) IsExpired(now time.Time) bool {
+	return e.ExpiresAt != nil && !now.Before(*e.ExpiresAt)
+}
+
+// Matches checks if a caller number matches the entry
+func (e *BlacklistEntry) Matches(number string) bool {
+	digits := blacklistDigits(number)
+	if strings.EqualFold(e.PhoneNumber, BlacklistAnonymous) {
+		return digits == ""
+	}
+	if digits == "" {
+		return false
+	}
+
+	pattern := blacklistDigits(e.PhoneNumber)
+	prefix := strings.HasSuffix(pattern, "*")
+	pattern = strings.TrimSuffix(pattern, "*")
+	if len(digits) < len(pattern) || (!prefix && len(digits) != len(pattern)) {
+		return false
+	}
+	for i := 0; i < len(pattern); i++ {
+		if pattern[i] != '?' && pattern[i] != digits[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// blacklistDigits keeps the digits and wildcards of a number or pattern
+func blacklistDigits(number string) string {
+	var b strings.Builder
+	for _, r := range number {
+		if (r >= '0' && r <= '9') || r == '?' || r == '*' {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// BlockedAttempt records a call or SMS refused because its sender matched a
+// blacklist entry
+// @Description Call or SMS refused from a blocked number
+type BlockedAttempt struct {
+	ID               int64                  `gorm:"column:id;primaryKey;autoIncrement" json:"id" example:"1"`
+	TenantID         string                 `gorm:"column:tenant_id;type:varchar(64);not null;index:idx_tenant_created" json:"tenant_id" example:"acme-corp"`
+	BlacklistEntryID *int64                 `gorm:"column:blacklist_id;index" json:"blacklist_id,omitempty" example:"1"`
+	Pattern          string                 `gorm:"column:pattern;type:varchar(50);not null" json:"pattern" example:"+1900*"`
+	Source           common.BlockedSource   `gorm:"column:source;type:enum('call','sms');not null" json:"source" example:"call"`
+	CallerNumber     string                 `gorm:"column:caller_number;type:varchar(50);not null" json:"caller_number" example:"+19005551234"`
+	DIDID            *int64                 `gorm:"column:did_id" json:"did_id,omitempty" example:"1"`
+	DIDNumber        string                 `gorm:"column:did_number;type:varchar(32);not null" json:"did_number" example:"+15551234567"`
+	Treatment        *common.BlockTreatment `gorm:"column:treatment;type:varchar(20)" json:"treatment,omitempty" example:"reject"`
+	CreatedAt        time.Time              `gorm:"column:created_at;autoCreateTime;index:idx_tenant_created" json:"created_at"`
+}
+
+// TableName specifies the table name
+func (BlockedAttempt) TableName() string {
+	return "blacklist_attempts"
+}
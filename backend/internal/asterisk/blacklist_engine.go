@@ -0,0 +1,54 @@
+package asterisk
+
+import (
+	"log"
+
+	"github.com/psschand/callcenter/internal/common"
+)
+
+// CallerScreen looks up the blacklist entry blocking a caller from calling a
+// DID, returning nil if the call may go ahead
+type CallerScreen func(did *DID, callerNumber string) *BlacklistEntry
+
+// SetCallerScreen sets the screen inbound calls from blocked numbers are
+// caught by. It is set before Start so that no call gets through unscreened.
+func (h *CallHandler) SetCallerScreen(screen CallerScreen) {
+	h.mu.Lock()
+	h.callerScreen = screen
+	h.mu.Unlock()
+}
+
+// screenCaller returns the blacklist entry blocking an inbound call, if any
+func (h *CallHandler) screenCaller(channel *Channel, did *DID) *BlacklistEntry {
+	h.mu.RLock()
+	screen := h.callerScreen
+	h.mu.RUnlock()
+
+	if screen == nil {
+		return nil
+	}
+	return screen(did, channel.Caller.Number)
+}
+
+// blockCall applies an entry's treatment to a blocked call that is not sent
+// to voicemail
+func (h *CallHandler) blockCall(channelID string, entry *BlacklistEntry) {
+	log.Printf("Blocking call %s matching blacklist entry %d (%s)", channelID, entry.ID, entry.Treatment)
+
+	switch entry.Treatment {
+	case common.BlockTreatmentBusy:
+		h.rejectCall(channelID, RejectTreatmentBusy)
+	case common.BlockTreatmentMessage:
+		sound := h.routing.RejectSound
+		if entry.TreatmentTarget != nil && *entry.TreatmentTarget != "" {
+			sound = *entry.TreatmentTarget
+		}
+		h.noteCallDisposition(channelID, common.CallDispositionFailed)
+		if err := h.announceAndHangup(channelID, sound); err != nil {
+			log.Printf("Error playing blocked message to channel %s: %v", channelID, err)
+			h.client.HangupChannel(channelID)
+		}
+	default:
+		h.rejectCall(channelID, RejectTreatmentHangup)
+	}
+}
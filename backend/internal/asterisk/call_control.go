@@ -0,0 +1,624 @@
+package asterisk
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/psschand/callcenter/internal/common"
+)
+
+// Stasis application argument marking the target leg of an attended transfer
+const appArgConsult = "consult"
+
+// Mute directions
+const (
+	MuteDirectionBoth = "both"
+	MuteDirectionIn   = "in"
+	MuteDirectionOut  = "out"
+)
+
+// Call control errors
+var (
+	ErrCallNotFound        = errors.New("call not found")
+	ErrCallNotBridged      = errors.New("call is not connected to another party")
+	ErrTransferInProgress  = errors.New("a transfer is already in progress for this call")
+	ErrNoTransfer          = errors.New("no transfer in progress for this call")
+	ErrTransferNotAnswered = errors.New("transfer target has not answered yet")
+)
+
+// attendedTransfer is a consultation between the transferring party and the
+// transfer target while the transferred party waits on hold
+type attendedTransfer struct {
+	channelID       string // party being transferred
+	agentID         string // transferring party
+	targetID        string // consulted party
+	routeType       common.RouteType
+	target          string
+	bridgeID        string // bridge of the original call
+	consultBridgeID string
+	connected       bool
+	agentGone       bool // transferring party hung up before the target answered
+}
+
+// ChannelTenant returns the tenant owning the call a channel belongs to
+func (h *CallHandler) ChannelTenant(channelID string) (string, bool) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	if _, ok := h.activeChannels[channelID]; !ok {
+		return "", false
+	}
+	return h.channelTenantLocked(channelID)
+}
+
+// ChannelParties returns the endpoints taking part in the call a channel
+// belongs to: its own, that of the party it is connected to and, during an
+// attended transfer, those of the transferring and consulted parties
+func (h *CallHandler) ChannelParties(channelID string) []string {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	if _, ok := h.activeChannels[channelID]; !ok {
+		return nil
+	}
+
+	related := []string{channelID}
+	if peerID, ok := h.peers[channelID]; ok {
+		related = append(related, peerID)
+	}
+	if transferredID, ok := h.consultLegs[channelID]; ok {
+		related = append(related, transferredID)
+	}
+	for _, t := range h.transfers {
+		if t.channelID == channelID || t.agentID == channelID || t.targetID == channelID {
+			related = append(related, t.channelID, t.agentID, t.targetID)
+		}
+	}
+
+	var parties []string
+	seen := make(map[string]bool)
+	add := func(endpoint string) {
+		if endpoint != "" && !seen[endpoint] {
+			seen[endpoint] = true
+			parties = append(parties, endpoint)
+		}
+	}
+	for _, id := range related {
+		if channel, ok := h.activeChannels[id]; ok {
+			add(channelEndpoint(channel.Name))
+		}
+		if callID, ok := h.outboundLegs[id]; ok {
+			if call, ok := h.outboundCalls[callID]; ok {
+				add(call.AgentEndpoint)
+			}
+		}
+	}
+	return parties
+}
+
+// channelTenantLocked resolves a channel's tenant from its own call or the party it is connected to; h.mu must be held
+func (h *CallHandler) channelTenantLocked(channelID string) (string, bool) {
+	related := []string{channelID}
+	if peerID, ok := h.peers[channelID]; ok {
+		related = append(related, peerID)
+	}
+	if transferredID, ok := h.consultLegs[channelID]; ok {
+		related = append(related, transferredID)
+	}
+	for _, t := range h.transfers {
+		if t.agentID == channelID {
+			related = append(related, t.channelID)
+		}
+	}
+
+	for _, id := range related {
+		if call, ok := h.calls[id]; ok {
+			return call.TenantID, true
+		}
+		if callID, ok := h.outboundLegs[id]; ok {
+			if call, ok := h.outboundCalls[callID]; ok {
+				return call.TenantID, true
+			}
+		}
+	}
+	return "", false
+}
+
+// transferDIDLocked returns the DID a transferred call is routed as; h.mu must be held
+func (h *CallHandler) transferDIDLocked(channelID, tenantID string) *DID {
+	related := []string{channelID}
+	if peerID, ok := h.peers[channelID]; ok {
+		related = append(related, peerID)
+	}
+
+	for _, id := range related {
+		if call, ok := h.calls[id]; ok && call.DID != nil {
+			return call.DID
+		}
+		if callID, ok := h.outboundLegs[id]; ok {
+			if call, ok := h.outboundCalls[callID]; ok {
+				return &DID{ID: call.DIDID, TenantID: tenantID, Number: call.CallerID, Status: common.DIDStatusActive}
+			}
+		}
+	}
+	return &DID{TenantID: tenantID, Status: common.DIDStatusActive}
+}
+
+// adoptCallLocked keeps a transferred channel attributed to its tenant once the
+// leg that owned the call is gone; h.mu must be held
+func (h *CallHandler) adoptCallLocked(channel *Channel, tenantID string, did *DID) {
+	if _, ok := h.calls[channel.ID]; ok {
+		return
+	}
+	h.calls[channel.ID] = &Call{
+		ChannelID:    channel.ID,
+		TenantID:     tenantID,
+		DID:          did,
+		CallerNumber: channel.Caller.Number,
+		CallerName:   channel.Caller.Name,
+		StartedAt:    time.Now(),
+	}
+}
+
+// Hold plays music on hold to a channel and stops its audio reaching the other party
+func (h *CallHandler) Hold(channelID, mohClass string) error {
+	h.mu.Lock()
+	if _, ok := h.activeChannels[channelID]; !ok {
+		h.mu.Unlock()
+		return ErrCallNotFound
+	}
+	if h.held[channelID] {
+		h.mu.Unlock()
+		return nil
+	}
+	h.held[channelID] = true
+	h.mu.Unlock()
+
+	if err := h.client.StartChannelMOH(channelID, mohClass); err != nil {
+		h.mu.Lock()
+		delete(h.held, channelID)
+		h.mu.Unlock()
+		return err
+	}
+	if err := h.client.MuteChannel(channelID, MuteDirectionIn); err != nil {
+		log.Printf("Error muting held channel %s: %v", channelID, err)
+	}
+	return nil
+}
+
+// Unhold resumes a held channel
+func (h *CallHandler) Unhold(channelID string) error {
+	h.mu.Lock()
+	if _, ok := h.activeChannels[channelID]; !ok {
+		h.mu.Unlock()
+		return ErrCallNotFound
+	}
+	held := h.held[channelID]
+	delete(h.held, channelID)
+	h.mu.Unlock()
+
+	if !held {
+		return nil
+	}
+	h.resume(channelID)
+	return nil
+}
+
+// IsOnHold reports whether a channel is on hold
+func (h *CallHandler) IsOnHold(channelID string) bool {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.held[channelID]
+}
+
+// resume stops music on hold and restores the audio of a channel
+func (h *CallHandler) resume(channelID string) {
+	if err := h.client.StopChannelMOH(channelID); err != nil {
+		log.Printf("Error stopping music on hold on %s: %v", channelID, err)
+	}
+	if err := h.client.UnmuteChannel(channelID, MuteDirectionIn); err != nil {
+		log.Printf("Error unmuting channel %s: %v", channelID, err)
+	}
+}
+
+// Mute mutes a channel in the given direction
+func (h *CallHandler) Mute(channelID, direction string) error {
+	if !h.isActive(channelID) {
+		return ErrCallNotFound
+	}
+	return h.client.MuteChannel(channelID, direction)
+}
+
+// Unmute unmutes a channel in the given direction
+func (h *CallHandler) Unmute(channelID, direction string) error {
+	if !h.isActive(channelID) {
+		return ErrCallNotFound
+	}
+	return h.client.UnmuteChannel(channelID, direction)
+}
+
+// Hangup hangs up a channel with a reason (normal, busy, congestion, ...)
+func (h *CallHandler) Hangup(channelID, reason string) error {
+	if !h.isActive(channelID) {
+		return ErrCallNotFound
+	}
+	if reason == "" {
+		reason = "normal"
+	}
+	return h.client.HangupChannelWithReason(channelID, reason)
+}
+
+// isActive reports whether a channel is in the Stasis application
+func (h *CallHandler) isActive(channelID string) bool {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	_, ok := h.activeChannels[channelID]
+	return ok
+}
+
+// BlindTransfer disconnects a channel from the party it is talking to and routes it to a new destination
+func (h *CallHandler) BlindTransfer(channelID string, routeType common.RouteType, target string) error {
+	h.mu.Lock()
+	channel, ok := h.activeChannels[channelID]
+	if !ok {
+		h.mu.Unlock()
+		return ErrCallNotFound
+	}
+	if _, busy := h.transfers[channelID]; busy {
+		h.mu.Unlock()
+		return ErrTransferInProgress
+	}
+
+	tenantID, _ := h.channelTenantLocked(channelID)
+	did := h.transferDIDLocked(channelID, tenantID)
+
+	peerID := h.peers[channelID]
+	bridgeID := h.callBridges[channelID]
+	delete(h.peers, channelID)
+	delete(h.callBridges, channelID)
+	if peerID != "" {
+		delete(h.peers, peerID)
+		delete(h.callBridges, peerID)
+	}
+
+	h.adoptCallLocked(channel, tenantID, did)
+	held := h.held[channelID]
+	delete(h.held, channelID)
+	h.mu.Unlock()
+
+	if bridgeID != "" {
+		h.client.RemoveChannelFromBridge(bridgeID, channelID)
+		h.client.DestroyBridge(bridgeID)
+	}
+	if peerID != "" {
+		h.client.HangupChannel(peerID)
+	}
+	if held {
+		h.resume(channelID)
+	}
+
+	h.transferCallDetail(channelID, CallTransferBlind, routeType, target, "")
+	log.Printf("Blind transfer of %s to %s %s", channelID, routeType, target)
+
+	if err := h.RouteCall(channel, did, routeType, target); err != nil {
+		h.rejectCall(channelID, RejectTreatmentCongestion)
+		return err
+	}
+	return nil
+}
+
+// TransferToExtension blind transfers a call to an endpoint
+func (h *CallHandler) TransferToExtension(channelID, endpoint string) error {
+	return h.BlindTransfer(channelID, common.RouteTypeEndpoint, endpoint)
+}
+
+// StartAttendedTransfer puts a channel on hold and connects the party it was
+// talking to with the transfer target for a consultation
+func (h *CallHandler) StartAttendedTransfer(channelID string, routeType common.RouteType, target string) error {
+	var dialStrings []string
+	switch routeType {
+	case common.RouteTypeEndpoint:
+		endpoint := target
+		if !strings.Contains(endpoint, "/") {
+			endpoint = "PJSIP/" + endpoint
+		}
+		dialStrings = []string{endpoint}
+	case common.RouteTypeExternal:
+		h.mu.RLock()
+		tenantID, _ := h.channelTenantLocked(channelID)
+		h.mu.RUnlock()
+
+		var err error
+		if dialStrings, err = h.trunkDialStrings(tenantID, target); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("attended transfer to %s is not supported", routeType)
+	}
+
+	h.mu.Lock()
+	channel, ok := h.activeChannels[channelID]
+	if !ok {
+		h.mu.Unlock()
+		return ErrCallNotFound
+	}
+	if _, busy := h.transfers[channelID]; busy {
+		h.mu.Unlock()
+		return ErrTransferInProgress
+	}
+	agentID, bridged := h.peers[channelID]
+	if !bridged {
+		h.mu.Unlock()
+		return ErrCallNotBridged
+	}
+
+	tenantID, _ := h.channelTenantLocked(channelID)
+	did := h.transferDIDLocked(channelID, tenantID)
+	h.adoptCallLocked(channel, tenantID, did)
+
+	callerID := channel.Caller.Number
+	if routeType == common.RouteTypeExternal {
+		callerID = did.Number
+	}
+
+	t := &attendedTransfer{
+		channelID: channelID,
+		agentID:   agentID,
+		routeType: routeType,
+		target:    target,
+		bridgeID:  h.callBridges[channelID],
+	}
+	h.transfers[channelID] = t
+
+	// Unlink the legs so neither hanging up during the consultation releases the other
+	delete(h.peers, channelID)
+	delete(h.peers, agentID)
+	delete(h.callBridges, agentID)
+	h.mu.Unlock()
+
+	if t.bridgeID != "" {
+		h.client.RemoveChannelFromBridge(t.bridgeID, agentID)
+	}
+	if err := h.client.StartChannelMOH(channelID, ""); err != nil {
+		log.Printf("Error starting music on hold on %s: %v", channelID, err)
+	}
+	h.client.RingChannel(agentID)
+
+	consult, endpoint, err := h.originateFirst(dialStrings, callerID,
+		fmt.Sprintf("%s,%s", appArgConsult, channelID))
+	if err != nil {
+		h.cancelTransfer(channelID)
+		return err
+	}
+
+	h.mu.Lock()
+	if t.targetID == "" {
+		t.targetID = consult.ID
+	}
+	h.consultLegs[consult.ID] = channelID
+	h.mu.Unlock()
+
+	log.Printf("Attended transfer of %s: consulting %s (%s)", channelID, endpoint, consult.ID)
+	return nil
+}
+
+// onConsultAnswered bridges the transferring party with the transfer target
+func (h *CallHandler) onConsultAnswered(target *Channel, args []string) {
+	if len(args) < 2 {
+		return
+	}
+	channelID := args[1]
+
+	h.mu.Lock()
+	t, ok := h.transfers[channelID]
+	if ok {
+		t.targetID = target.ID
+		t.connected = true
+		h.consultLegs[target.ID] = channelID
+	}
+	h.mu.Unlock()
+
+	if !ok {
+		log.Printf("Transfer target %s answered but the transfer of %s is over", target.ID, channelID)
+		h.client.HangupChannel(target.ID)
+		return
+	}
+
+	// The transferring party already hung up: hand the call straight over
+	if t.agentGone {
+		h.completeTransfer(channelID)
+		return
+	}
+
+	bridge, err := h.client.CreateBridge("mixing")
+	if err == nil {
+		for _, id := range []string{t.agentID, target.ID} {
+			if err = h.client.AddChannelToBridge(bridge.ID, id); err != nil {
+				break
+			}
+		}
+	}
+	if err != nil {
+		log.Printf("Error bridging consultation for %s: %v", channelID, err)
+		if bridge != nil {
+			h.client.DestroyBridge(bridge.ID)
+		}
+		h.client.HangupChannel(target.ID)
+		return
+	}
+
+	h.mu.Lock()
+	t.consultBridgeID = bridge.ID
+	h.mu.Unlock()
+}
+
+// CompleteAttendedTransfer connects the transferred party with the transfer target and drops the transferring party
+func (h *CallHandler) CompleteAttendedTransfer(channelID string) error {
+	h.mu.RLock()
+	t, ok := h.transfers[channelID]
+	connected := ok && t.connected
+	h.mu.RUnlock()
+
+	if !ok {
+		return ErrNoTransfer
+	}
+	if !connected {
+		return ErrTransferNotAnswered
+	}
+	return h.completeTransfer(channelID)
+}
+
+// CancelAttendedTransfer drops the transfer target and reconnects the original parties
+func (h *CallHandler) CancelAttendedTransfer(channelID string) error {
+	h.mu.RLock()
+	_, ok := h.transfers[channelID]
+	h.mu.RUnlock()
+
+	if !ok {
+		return ErrNoTransfer
+	}
+	return h.cancelTransfer(channelID)
+}
+
+// takeTransfer removes a transfer from the handler so that only one party finishes it
+func (h *CallHandler) takeTransfer(channelID string) *attendedTransfer {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	t, ok := h.transfers[channelID]
+	if !ok {
+		return nil
+	}
+	delete(h.transfers, channelID)
+	if t.targetID != "" {
+		delete(h.consultLegs, t.targetID)
+	}
+	return t
+}
+
+// completeTransfer moves the transfer target into the original call
+func (h *CallHandler) completeTransfer(channelID string) error {
+	t := h.takeTransfer(channelID)
+	if t == nil {
+		return ErrNoTransfer
+	}
+
+	if t.consultBridgeID != "" {
+		h.client.DestroyBridge(t.consultBridgeID)
+	}
+	h.resume(channelID)
+
+	if err := h.client.AddChannelToBridge(t.bridgeID, t.targetID); err != nil {
+		log.Printf("Error completing transfer of %s to %s: %v", channelID, t.targetID, err)
+		h.client.HangupChannel(t.targetID)
+		h.client.HangupChannel(channelID)
+		return err
+	}
+
+	h.mu.Lock()
+	h.peers[channelID] = t.targetID
+	h.peers[t.targetID] = channelID
+	h.callBridges[t.targetID] = t.bridgeID
+	delete(h.held, channelID)
+	h.mu.Unlock()
+
+	h.transferCallDetail(channelID, CallTransferAttended, t.routeType, t.target, t.targetID)
+
+	if !t.agentGone {
+		h.client.HangupChannel(t.agentID)
+	}
+
+	log.Printf("Attended transfer of %s to %s completed", channelID, t.targetID)
+	return nil
+}
+
+// cancelTransfer drops the transfer target and returns the transferring party to the call
+func (h *CallHandler) cancelTransfer(channelID string) error {
+	t := h.takeTransfer(channelID)
+	if t == nil {
+		return ErrNoTransfer
+	}
+
+	if t.targetID != "" {
+		h.client.HangupChannel(t.targetID)
+	}
+	if t.consultBridgeID != "" {
+		h.client.DestroyBridge(t.consultBridgeID)
+	}
+
+	// Nobody is left to take the call back
+	if t.agentGone {
+		h.client.HangupChannel(channelID)
+		return nil
+	}
+
+	h.resume(channelID)
+	if err := h.client.AddChannelToBridge(t.bridgeID, t.agentID); err != nil {
+		log.Printf("Error returning %s to call %s: %v", t.agentID, channelID, err)
+		h.client.HangupChannel(channelID)
+		return err
+	}
+
+	h.mu.Lock()
+	h.peers[channelID] = t.agentID
+	h.peers[t.agentID] = channelID
+	h.callBridges[t.agentID] = t.bridgeID
+	delete(h.held, channelID)
+	h.mu.Unlock()
+
+	log.Printf("Attended transfer of %s cancelled", channelID)
+	return nil
+}
+
+// endTransferLeg tidies up an attended transfer when one of its parties hangs up
+func (h *CallHandler) endTransferLeg(channelID string) {
+	h.mu.Lock()
+	var agentOf *attendedTransfer
+	for _, t := range h.transfers {
+		if t.agentID == channelID {
+			agentOf = t
+		}
+	}
+	transferredID, isTarget := h.consultLegs[channelID]
+	_, isTransferred := h.transfers[channelID]
+	h.mu.Unlock()
+
+	switch {
+	case isTransferred:
+		// The held party left: the consultation carries on as a call of its own
+		t := h.takeTransfer(channelID)
+		if t == nil {
+			return
+		}
+		if !t.connected || t.consultBridgeID == "" {
+			if t.targetID != "" {
+				h.client.HangupChannel(t.targetID)
+			}
+			h.client.HangupChannel(t.agentID)
+			return
+		}
+		h.mu.Lock()
+		h.peers[t.agentID] = t.targetID
+		h.peers[t.targetID] = t.agentID
+		h.callBridges[t.agentID] = t.consultBridgeID
+		h.callBridges[t.targetID] = t.consultBridgeID
+		h.mu.Unlock()
+
+	case agentOf != nil:
+		h.mu.Lock()
+		connected := agentOf.connected
+		agentOf.agentGone = true
+		h.mu.Unlock()
+
+		// Hanging up after the target answered completes the transfer
+		if connected {
+			h.completeTransfer(agentOf.channelID)
+		}
+
+	case isTarget:
+		h.cancelTransfer(transferredID)
+	}
+}
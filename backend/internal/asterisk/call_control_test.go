@@ -0,0 +1,64 @@
+package asterisk
+
+import (
+	"slices"
+	"testing"
+)
+
+// newPartiesHandler returns a handler with a caller connected to acme-agent1
+// and another call of acme-agent2 in progress
+func newPartiesHandler() *CallHandler {
+	h := NewCallHandler(nil)
+	for _, channel := range []*Channel{
+		{ID: "caller", Name: "PJSIP/twilio_trunk-00000001"},
+		{ID: "agent1", Name: "PJSIP/acme-agent1-00000002"},
+		{ID: "other", Name: "PJSIP/twilio_trunk-00000003"},
+		{ID: "agent2", Name: "PJSIP/acme-agent2-00000004"},
+	} {
+		h.activeChannels[channel.ID] = channel
+	}
+	h.calls["caller"] = &Call{ChannelID: "caller", TenantID: "acme-corp"}
+	h.calls["other"] = &Call{ChannelID: "other", TenantID: "acme-corp"}
+	h.peers["caller"], h.peers["agent1"] = "agent1", "caller"
+	h.peers["other"], h.peers["agent2"] = "agent2", "other"
+	return h
+}
+
+func TestChannelParties(t *testing.T) {
+	h := newPartiesHandler()
+
+	for _, channelID := range []string{"caller", "agent1"} {
+		parties := h.ChannelParties(channelID)
+		if !slices.Contains(parties, "acme-agent1") {
+			t.Errorf("ChannelParties(%q) = %v, want acme-agent1 among them", channelID, parties)
+		}
+		if slices.Contains(parties, "acme-agent2") {
+			t.Errorf("ChannelParties(%q) = %v, want acme-agent2 left out", channelID, parties)
+		}
+	}
+
+	if parties := h.ChannelParties("missing"); parties != nil {
+		t.Errorf("ChannelParties of an unknown channel = %v, want none", parties)
+	}
+}
+
+func TestChannelPartiesDuringAttendedTransfer(t *testing.T) {
+	h := newPartiesHandler()
+
+	// acme-agent1 consults acme-agent3 while the caller waits on hold
+	h.activeChannels["target"] = &Channel{ID: "target", Name: "PJSIP/acme-agent3-00000005"}
+	h.transfers["caller"] = &attendedTransfer{channelID: "caller", agentID: "agent1", targetID: "target"}
+	h.consultLegs["target"] = "caller"
+
+	for _, channelID := range []string{"caller", "agent1", "target"} {
+		parties := h.ChannelParties(channelID)
+		for _, endpoint := range []string{"acme-agent1", "acme-agent3"} {
+			if !slices.Contains(parties, endpoint) {
+				t.Errorf("ChannelParties(%q) = %v, want %s among them", channelID, parties, endpoint)
+			}
+		}
+	}
+	if parties := h.ChannelParties("other"); slices.Contains(parties, "acme-agent3") {
+		t.Errorf("ChannelParties of an unrelated call = %v, want acme-agent3 left out", parties)
+	}
+}
@@ -0,0 +1,97 @@
+package asterisk
+
+import (
+	"log"
+	"time"
+)
+
+// Call progress events
+const (
+	CallEventIncoming    = "incoming"    // a caller reached a DID
+	CallEventQueued      = "queued"      // the caller entered a queue
+	CallEventQueueLeft   = "queue_left"  // the caller left a queue, answered or not
+	CallEventAnswered    = "answered"    // the caller was connected to a party
+	CallEventTransferred = "transferred" // the caller was transferred away from the party it was connected to
+	CallEventEnded       = "ended"       // the caller hung up or was hung up
+)
+
+// Number of call events that can wait for the listener
+const callEventBuffer = 1024
+
+// CallEvent reports the progress of a call from the caller's point of view
+type CallEvent struct {
+	Type          string
+	TenantID      string
+	ChannelID     string // caller channel
+	CallerNumber  string
+	CallerName    string
+	Destination   string
+	QueueName     string
+	AgentEndpoint string // party the caller is, or was until the event, connected to
+	TransferredTo string
+	Duration      int // seconds connected, for ended and transferred events
+	Outbound      bool
+	Timestamp     time.Time
+}
+
+// CallEventListener is notified of call events in the order they happen
+type CallEventListener func(event CallEvent)
+
+// SetCallEventListener sets the listener notified of call events. Events are
+// delivered one at a time so that, for example, a call's answer is always
+// seen before its end.
+func (h *CallHandler) SetCallEventListener(listener CallEventListener) {
+	events := make(chan CallEvent, callEventBuffer)
+	h.mu.Lock()
+	h.callEvents = events
+	h.mu.Unlock()
+
+	go func() {
+		for event := range events {
+			listener(event)
+		}
+	}()
+}
+
+// newCallEvent builds a call event from a caller's call detail segment
+func newCallEvent(eventType string, detail *CallDetail) CallEvent {
+	event := CallEvent{
+		Type:          eventType,
+		TenantID:      detail.TenantID,
+		ChannelID:     detail.UniqueID,
+		CallerNumber:  detail.CallerNumber,
+		CallerName:    detail.CallerName,
+		Destination:   detail.Destination,
+		QueueName:     detail.QueueName,
+		AgentEndpoint: detail.AgentEndpoint,
+		TransferredTo: detail.TransferredTo,
+		Outbound:      detail.Outbound,
+		Timestamp:     time.Now(),
+	}
+	if detail.AnsweredAt != nil {
+		end := detail.EndedAt
+		if end.IsZero() {
+			end = event.Timestamp
+		}
+		event.Duration = int(end.Sub(*detail.AnsweredAt).Seconds())
+	}
+	return event
+}
+
+// notifyCallEvents passes call events to the listener. It must not be called with h.mu held.
+func (h *CallHandler) notifyCallEvents(events ...CallEvent) {
+	h.mu.RLock()
+	listener := h.callEvents
+	h.mu.RUnlock()
+	if listener == nil {
+		return
+	}
+
+	for _, event := range events {
+		select {
+		case listener <- event:
+		default:
+			log.Printf("Call event listener is backed up, dropping %s event of %s", event.Type, event.ChannelID)
+		}
+	}
+}
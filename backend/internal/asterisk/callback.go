@@ -0,0 +1,73 @@
+package asterisk
+
+import (
+	"time"
+
+	"github.com/psschand/callcenter/internal/core"
+)
+
+// Queue callback statuses
+const (
+	CallbackStatusPending   = "pending"   // waiting for its turn in the queue
+	CallbackStatusDialing   = "dialing"   // an agent took it and the customer is being dialed
+	CallbackStatusConnected = "connected" // the customer is talking to the agent
+	CallbackStatusCompleted = "completed"
+	CallbackStatusFailed    = "failed" // every attempt went unanswered, or the callback was lost
+	CallbackStatusCancelled = "cancelled"
+)
+
+// QueueCallback is a queued caller's request to be called back instead of
+// waiting on hold. It keeps the caller's place in the queue; when it reaches
+// the head an agent is rung and the customer is dialed once the agent answers.
+// @Description Callback requested by a caller waiting in a queue
+type QueueCallback struct {
+	ID             int64      `gorm:"column:id;primaryKey;autoIncrement" json:"id" example:"1"`
+	TenantID       string     `gorm:"column:tenant_id;type:varchar(64);not null;index:idx_tenant_requested" json:"tenant_id" example:"acme-corp"`
+	QueueName      string     `gorm:"column:queue_name;type:varchar(128);not null;index" json:"queue_name" example:"support"`
+	UniqueID       string     `gorm:"column:uniqueid;type:varchar(150);not null;index" json:"uniqueid" example:"1634567890.123"`
+	LinkedID       string     `gorm:"column:linkedid;type:varchar(150);not null" json:"linkedid" example:"1634567890.123"`
+	DIDID          *int64     `gorm:"column:did_id" json:"did_id,omitempty" example:"1"`
+	CallerNumber   string     `gorm:"column:caller_number;type:varchar(80);not null" json:"caller_number" example:"+15551234567"`
+	CallerName     string     `gorm:"column:caller_name;type:varchar(80);not null" json:"caller_name" example:"John Doe"`
+	CallbackNumber string     `gorm:"column:callback_number;type:varchar(80);not null" json:"callback_number" example:"+15551234567"`
+	Status         string     `gorm:"column:status;type:enum('pending','dialing','connected','completed','failed','cancelled');not null;default:pending;index" json:"status" example:"pending"`
+	Attempts       int        `gorm:"column:attempts;not null;default:0" json:"attempts" example:"1"`
+	MaxAttempts    int        `gorm:"column:max_attempts;not null" json:"max_attempts" example:"3"`
+	WaitTime       int        `gorm:"column:wait_time;not null;default:0" json:"wait_time" example:"95"` // seconds held before asking for the callback
+	AgentEndpoint  *string    `gorm:"column:agent_endpoint;type:varchar(128)" json:"agent_endpoint,omitempty" example:"acme-agent1"`
+	LastError      *string    `gorm:"column:last_error;type:varchar(255)" json:"last_error,omitempty" example:"BUSY"`
+	RequestedAt    time.Time  `gorm:"column:requested_at;not null;index:idx_tenant_requested" json:"requested_at"`
+	LastAttemptAt  *time.Time `gorm:"column:last_attempt_at" json:"last_attempt_at,omitempty"`
+	NextAttemptAt  *time.Time `gorm:"column:next_attempt_at" json:"next_attempt_at,omitempty"`
+	ConnectedAt    *time.Time `gorm:"column:connected_at" json:"connected_at,omitempty"`
+	EndedAt        *time.Time `gorm:"column:ended_at" json:"ended_at,omitempty"`
+	CreatedAt      time.Time  `gorm:"column:created_at;autoCreateTime" json:"created_at"`
+	UpdatedAt      time.Time  `gorm:"column:updated_at;autoUpdateTime" json:"updated_at"`
+
+	// Relations
+	Tenant *core.Tenant `gorm:"foreignKey:TenantID" json:"tenant,omitempty"`
+}
+
+// TableName specifies the table name
+func (QueueCallback) TableName() string {
+	return "queue_callbacks"
+}
+
+// IsOpen checks if the callback has yet to be completed, failed or cancelled
+func (c *QueueCallback) IsOpen() bool {
+	switch c.Status {
+	case CallbackStatusPending, CallbackStatusDialing, CallbackStatusConnected:
+		return true
+	}
+	return false
+}
+
+// IsValidCallbackStatus checks if status is a known callback status
+func IsValidCallbackStatus(status string) bool {
+	switch status {
+	case CallbackStatusPending, CallbackStatusDialing, CallbackStatusConnected,
+		CallbackStatusCompleted, CallbackStatusFailed, CallbackStatusCancelled:
+		return true
+	}
+	return false
+}
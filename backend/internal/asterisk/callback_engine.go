@@ -0,0 +1,778 @@
+package asterisk
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+)
+
+// Stasis application argument marking the customer leg of a queue callback
+const appArgCallback = "callback"
+
+// Callback input settings
+const (
+	callbackRequestDigit = "1"
+	callbackConfirmDigit = "1"
+	callbackInputTimeout = 5 * time.Second
+	callbackMaxEntries   = 2
+	callbackMinDigits    = 7
+	callbackMaxDigits    = 15
+)
+
+// Stock sounds played while the caller confirms the number to call back
+const (
+	callbackEnteredSound   = "sound:you-entered"
+	callbackCorrectSound   = "sound:if-correct-press"
+	callbackEnterSound     = "sound:vm-enter-num-to-call"
+	callbackThenPoundSound = "sound:vm-then-pound"
+	callbackThankYouSound  = "sound:auth-thankyou"
+	callbackGoodbyeSound   = "sound:vm-goodbye"
+)
+
+// Steps of asking a queued caller whether they want a callback
+const (
+	callbackStepOffer   = "offer"   // the queue's callback prompt is playing
+	callbackStepConfirm = "confirm" // the caller is asked to confirm the number
+	callbackStepEnter   = "enter"   // the caller is entering another number
+)
+
+// Queue callback errors
+var (
+	ErrCallbackNotQueued  = errors.New("callback is not waiting in a queue")
+	ErrCallbackInProgress = errors.New("callback is already being dialed")
+	ErrCallbackQueueGone  = errors.New("callback queue no longer exists")
+)
+
+// QueueCallbackStore saves the callbacks callers request.
+// repository.QueueCallbackRepository satisfies this interface.
+type QueueCallbackStore interface {
+	Create(ctx context.Context, callback *QueueCallback) error
+	Update(ctx context.Context, callback *QueueCallback) error
+}
+
+// callbackPrompt tracks a queued caller being asked whether they want a callback
+type callbackPrompt struct {
+	step       string
+	number     string // number to call back, once known
+	digits     string // number being entered
+	entries    int    // numbers entered that could not be called back
+	playbackID string
+	generation int // Bumped on every state change so stale timers are ignored
+	timer      *time.Timer
+}
+
+// queuedCallback is a callback holding a caller's place in a queue. Its
+// queued call has a placeholder channel named after the callback, with the
+// number to call back as its caller ID.
+type queuedCallback struct {
+	record     *QueueCallback
+	agentID    string // agent leg while the customer is dialed
+	customerID string // customer leg until it answers
+	answered   bool
+	lastError  string // why the current attempt failed
+}
+
+// SetQueueCallbackStore enables queue callbacks. Callers holding in a queue
+// that offers callbacks are asked, once they have waited its
+// callback_offer_after, whether they want to hang up and be called back.
+func (h *CallHandler) SetQueueCallbackStore(store QueueCallbackStore) {
+	h.queueCallbacks = store
+}
+
+// CancelQueueCallback removes a callback waiting in a queue. It fails with
+// ErrCallbackInProgress while an agent is taking it and ErrCallbackNotQueued
+// if it is not waiting in any queue.
+func (h *CallHandler) CancelQueueCallback(tenantID string, id int64) error {
+	if h.acd == nil {
+		return ErrCallbackNotQueued
+	}
+	return h.acd.cancelCallback(tenantID, id)
+}
+
+// RestoreQueueCallback puts a callback saved before a restart back in its
+// queue, at the place its caller had. did is the number the caller dialed,
+// which the customer sees when called back. It fails with
+// ErrCallbackQueueGone if the queue no longer exists.
+func (h *CallHandler) RestoreQueueCallback(record *QueueCallback, did *DID) error {
+	if h.acd == nil || h.queueCallbacks == nil {
+		return ErrCallbackQueueGone
+	}
+	return h.acd.restoreCallback(record, did)
+}
+
+// onQueueCallbackDigit collects a DTMF digit; it reports whether the channel
+// is holding in a queue that offered it a callback
+func (h *CallHandler) onQueueCallbackDigit(channelID, digit string) bool {
+	return h.acd != nil && h.acd.onCallbackDigit(channelID, digit)
+}
+
+// onQueueCallbackPlaybackFinished waits for the caller's answer once a
+// callback prompt has played; it reports whether the playback was one
+func (h *CallHandler) onQueueCallbackPlaybackFinished(playbackID string) bool {
+	return h.acd != nil && h.acd.onCallbackPlaybackFinished(playbackID)
+}
+
+// callbackChannelID names the placeholder channel of a callback in its queue
+func callbackChannelID(id int64) string {
+	return fmt.Sprintf("callback-%d", id)
+}
+
+// validCallbackNumber checks a number can be dialed back: digits with an
+// optional leading +, which rules out anonymous caller IDs and extensions
+func validCallbackNumber(number string) bool {
+	digits := strings.TrimPrefix(number, "+")
+	if len(digits) < callbackMinDigits || len(digits) > callbackMaxDigits {
+		return false
+	}
+	for _, c := range digits {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// offerCallback plays the queue's callback prompt to a caller on hold
+func (e *acdEngine) offerCallback(channelID, prompt string) {
+	e.mu.Lock()
+	call, ok := e.callers[channelID]
+	if !ok || call.prompt != nil {
+		e.mu.Unlock()
+		return
+	}
+	call.prompt = &callbackPrompt{step: callbackStepOffer}
+	e.mu.Unlock()
+
+	log.Printf("Offering %s a callback in queue %s", channelID, call.queue.key)
+	e.playCallbackPrompt(channelID, mediaURI(prompt))
+}
+
+// playCallbackPrompt plays media to a caller being asked for a callback
+func (e *acdEngine) playCallbackPrompt(channelID string, media ...string) {
+	playback, err := e.h.client.PlayMedia(channelID, media...)
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	call, ok := e.callers[channelID]
+	if !ok || call.prompt == nil {
+		return
+	}
+	if err != nil {
+		log.Printf("Error playing callback prompt on %s: %v", channelID, err)
+		e.armCallbackTimer(channelID, call.prompt)
+		return
+	}
+	call.prompt.playbackID = playback.ID
+	e.callbackPlaybacks[playback.ID] = channelID
+}
+
+// onCallbackPlaybackFinished starts the input timeout once a prompt has
+// played; it reports whether the playback was a callback prompt
+func (e *acdEngine) onCallbackPlaybackFinished(playbackID string) bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	channelID, ok := e.callbackPlaybacks[playbackID]
+	delete(e.callbackPlaybacks, playbackID)
+	if !ok {
+		return false
+	}
+	if call, queued := e.callers[channelID]; queued && call.prompt != nil && call.prompt.playbackID == playbackID {
+		call.prompt.playbackID = ""
+		e.armCallbackTimer(channelID, call.prompt)
+	}
+	return true
+}
+
+// onCallbackDigit collects a DTMF digit from a caller who was offered a
+// callback. A caller who let the offer pass may still press 1 while holding.
+func (e *acdEngine) onCallbackDigit(channelID, digit string) bool {
+	e.mu.Lock()
+	call, ok := e.callers[channelID]
+	if !ok || call.callback != nil || !call.callbackOffered {
+		e.mu.Unlock()
+		return false
+	}
+
+	prompt := call.prompt
+	if prompt == nil {
+		e.mu.Unlock()
+		if digit == callbackRequestDigit {
+			e.askCallbackNumber(channelID)
+		}
+		return true
+	}
+
+	// Barge-in: input interrupts the prompt
+	playbackID := prompt.playbackID
+	prompt.playbackID = ""
+	delete(e.callbackPlaybacks, playbackID)
+	stopCallbackTimer(prompt)
+
+	step := prompt.step
+	number := prompt.number
+	entered := false
+	if step == callbackStepEnter {
+		// # ends the number
+		if digit != "#" {
+			prompt.digits += digit
+		}
+		entered = digit == "#" || len(prompt.digits) >= callbackMaxDigits
+		if !entered {
+			e.armCallbackTimer(channelID, prompt)
+		}
+	}
+	e.mu.Unlock()
+
+	if playbackID != "" {
+		e.h.client.StopPlayback(playbackID)
+	}
+
+	switch step {
+	case callbackStepOffer:
+		if digit == callbackRequestDigit {
+			e.askCallbackNumber(channelID)
+		} else {
+			e.dropCallbackPrompt(channelID)
+		}
+	case callbackStepConfirm:
+		if digit == callbackConfirmDigit {
+			go e.requestCallback(channelID, number)
+		} else {
+			e.promptCallbackNumber(channelID, false)
+		}
+	case callbackStepEnter:
+		if entered {
+			e.enterCallbackNumber(channelID)
+		}
+	}
+	return true
+}
+
+// onCallbackTimeout fires when the caller does not answer a prompt. A caller
+// who lets the offer or the confirmation pass keeps holding.
+func (e *acdEngine) onCallbackTimeout(channelID string, generation int) {
+	e.mu.Lock()
+	call, ok := e.callers[channelID]
+	if !ok || call.prompt == nil || call.prompt.generation != generation {
+		e.mu.Unlock()
+		return
+	}
+	step := call.prompt.step
+	e.mu.Unlock()
+
+	if step == callbackStepEnter {
+		e.enterCallbackNumber(channelID)
+		return
+	}
+	e.dropCallbackPrompt(channelID)
+}
+
+// askCallbackNumber asks the caller to confirm the number they called from,
+// or to enter one if it cannot be called back
+func (e *acdEngine) askCallbackNumber(channelID string) {
+	e.mu.Lock()
+	call, ok := e.callers[channelID]
+	if !ok {
+		e.mu.Unlock()
+		return
+	}
+	if call.prompt == nil {
+		call.prompt = &callbackPrompt{}
+	}
+	number := call.channel.Caller.Number
+	e.mu.Unlock()
+
+	if !validCallbackNumber(number) {
+		e.promptCallbackNumber(channelID, false)
+		return
+	}
+	e.confirmCallbackNumber(channelID, number, false)
+}
+
+// confirmCallbackNumber reads a number back to the caller, who presses 1 if it is right
+func (e *acdEngine) confirmCallbackNumber(channelID, number string, entered bool) {
+	if !e.setCallbackStep(channelID, callbackStepConfirm, number) {
+		return
+	}
+
+	var media []string
+	if entered {
+		media = append(media, callbackEnteredSound)
+	}
+	media = append(media, "digits:"+strings.TrimPrefix(number, "+"), callbackCorrectSound, "digits:"+callbackConfirmDigit)
+	e.playCallbackPrompt(channelID, media...)
+}
+
+// promptCallbackNumber asks the caller to enter the number to call back
+func (e *acdEngine) promptCallbackNumber(channelID string, invalid bool) {
+	if !e.setCallbackStep(channelID, callbackStepEnter, "") {
+		return
+	}
+
+	var media []string
+	if invalid {
+		media = append(media, ivrDefaultInvalidSound)
+	}
+	media = append(media, callbackEnterSound, callbackThenPoundSound)
+	e.playCallbackPrompt(channelID, media...)
+}
+
+// enterCallbackNumber checks the number the caller entered; a caller who
+// does not enter one that can be called back keeps holding
+func (e *acdEngine) enterCallbackNumber(channelID string) {
+	e.mu.Lock()
+	call, ok := e.callers[channelID]
+	if !ok || call.prompt == nil {
+		e.mu.Unlock()
+		return
+	}
+	number := call.prompt.digits
+	valid := validCallbackNumber(number)
+	if !valid {
+		call.prompt.entries++
+	}
+	giveUp := call.prompt.entries >= callbackMaxEntries
+	e.mu.Unlock()
+
+	switch {
+	case valid:
+		e.confirmCallbackNumber(channelID, number, true)
+	case giveUp:
+		e.dropCallbackPrompt(channelID)
+	default:
+		e.promptCallbackNumber(channelID, number != "")
+	}
+}
+
+// setCallbackStep moves a caller's prompt on to the next step; it reports
+// whether the caller is still being asked
+func (e *acdEngine) setCallbackStep(channelID, step, number string) bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	call, ok := e.callers[channelID]
+	if !ok || call.prompt == nil {
+		return false
+	}
+	stopCallbackTimer(call.prompt)
+	call.prompt.step = step
+	call.prompt.number = number
+	call.prompt.digits = ""
+	return true
+}
+
+// dropCallbackPrompt stops asking a caller about a callback; they keep holding
+func (e *acdEngine) dropCallbackPrompt(channelID string) {
+	e.mu.Lock()
+	call, ok := e.callers[channelID]
+	var playbackID string
+	if ok {
+		playbackID = e.dropCallbackPromptLocked(call)
+	}
+	e.mu.Unlock()
+
+	if playbackID != "" {
+		e.h.client.StopPlayback(playbackID)
+	}
+	if ok {
+		log.Printf("Caller %s kept holding in queue %s", channelID, call.queue.key)
+	}
+}
+
+// dropCallbackPromptLocked clears a caller's prompt, returning the playback
+// to stop if one is playing; the caller must hold e.mu
+func (e *acdEngine) dropCallbackPromptLocked(call *queuedCall) string {
+	prompt := call.prompt
+	if prompt == nil {
+		return ""
+	}
+	call.prompt = nil
+	stopCallbackTimer(prompt)
+	delete(e.callbackPlaybacks, prompt.playbackID)
+	return prompt.playbackID
+}
+
+// requestCallback saves the callback the caller confirmed and lets them
+// hang up; the callback takes over their place in the queue
+func (e *acdEngine) requestCallback(channelID, number string) {
+	e.mu.Lock()
+	call, ok := e.callers[channelID]
+	if !ok || call.prompt == nil {
+		e.mu.Unlock()
+		return
+	}
+	e.dropCallbackPromptLocked(call)
+	cfg := call.queue.config
+	wait := time.Since(call.enteredAt)
+	e.mu.Unlock()
+
+	now := time.Now()
+	record := &QueueCallback{
+		TenantID:       call.did.TenantID,
+		QueueName:      cfg.Name,
+		UniqueID:       channelID,
+		LinkedID:       channelID,
+		CallerNumber:   call.channel.Caller.Number,
+		CallerName:     call.channel.Caller.Name,
+		CallbackNumber: number,
+		Status:         CallbackStatusPending,
+		MaxAttempts:    cfg.CallbackMaxAttempts,
+		WaitTime:       int(wait.Seconds()),
+		RequestedAt:    now,
+	}
+	if record.MaxAttempts < 1 {
+		record.MaxAttempts = 1
+	}
+	if call.did.ID != 0 {
+		didID := call.did.ID
+		record.DIDID = &didID
+	}
+	e.h.mu.RLock()
+	if detail, ok := e.h.callDetails[channelID]; ok {
+		record.LinkedID = detail.LinkedID
+	}
+	e.h.mu.RUnlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	err := e.h.queueCallbacks.Create(ctx, record)
+	cancel()
+	if err != nil {
+		log.Printf("Error saving callback for %s, caller keeps holding: %v", channelID, err)
+		return
+	}
+
+	e.mu.Lock()
+	if current, queued := e.callers[channelID]; !queued || current != call {
+		e.mu.Unlock()
+		log.Printf("Caller %s left queue %s before callback %d was set up", channelID, call.queue.key, record.ID)
+		record.Status = CallbackStatusCancelled
+		record.EndedAt = &now
+		e.saveCallback(*record)
+		return
+	}
+
+	// Agents ringing for the caller are rung again for the callback
+	playbackID := e.dropCallbackPromptLocked(call)
+	var cancelled []string
+	for agentChannel := range call.offers {
+		delete(e.offers, agentChannel)
+		cancelled = append(cancelled, agentChannel)
+	}
+	call.offers = make(map[string]string)
+	call.nextOfferAt = now
+
+	delete(e.callers, channelID)
+	call.channel = &Channel{
+		ID:     callbackChannelID(record.ID),
+		Caller: CallerID{Name: call.channel.Caller.Name, Number: number},
+	}
+	call.callback = &queuedCallback{record: record}
+	e.callers[call.channel.ID] = call
+	bridgeID := call.queue.bridgeID
+	e.mu.Unlock()
+
+	for _, agentChannel := range cancelled {
+		e.h.client.HangupChannel(agentChannel)
+	}
+	if playbackID != "" {
+		e.h.client.StopPlayback(playbackID)
+	}
+
+	log.Printf("Caller %s in queue %s will be called back at %s (callback %d)", channelID, call.queue.key, number, record.ID)
+
+	if bridgeID != "" {
+		e.h.client.RemoveChannelFromBridge(bridgeID, channelID)
+	}
+	// The caller left the queue without abandoning it
+	e.h.noteQueueAnswered(channelID, wait)
+
+	playback, err := e.h.client.PlayMedia(channelID, callbackThankYouSound, callbackGoodbyeSound)
+	if err != nil {
+		e.h.client.HangupChannel(channelID)
+		return
+	}
+	e.h.mu.Lock()
+	e.h.hangupAfterPlayback[playback.ID] = channelID
+	e.h.mu.Unlock()
+}
+
+// dialCallback dials the customer of a callback an agent answered; the agent
+// hears music on hold until the customer answers
+func (e *acdEngine) dialCallback(call *queuedCall, agent *Channel, endpoint string) {
+	e.mu.Lock()
+	cb := call.callback
+	now := time.Now()
+	cb.agentID = agent.ID
+	cb.customerID = ""
+	cb.answered = false
+	cb.lastError = ""
+	record := cb.record
+	record.Status = CallbackStatusDialing
+	record.Attempts++
+	record.AgentEndpoint = &endpoint
+	record.LastAttemptAt = &now
+	record.NextAttemptAt = nil
+	snapshot := *record
+	e.callbackAgents[agent.ID] = call
+	cfg := call.queue.config
+	e.mu.Unlock()
+
+	e.saveCallback(snapshot)
+	log.Printf("Agent %s took callback %d, dialing %s (attempt %d of %d)", endpoint, record.ID, record.CallbackNumber, snapshot.Attempts, snapshot.MaxAttempts)
+
+	if err := e.h.client.StartChannelMOH(agent.ID, cfg.MusicOnHold); err != nil {
+		log.Printf("Error starting music on hold for agent %s: %v", agent.ID, err)
+	}
+
+	// The customer sees the number they called
+	dialStrings, err := e.h.trunkDialStrings(record.TenantID, record.CallbackNumber)
+	var customer *Channel
+	if err == nil {
+		customer, _, err = e.h.originateFirst(dialStrings, call.did.Number, fmt.Sprintf("%s,%s", appArgCallback, agent.ID))
+	}
+	if err != nil {
+		log.Printf("Error dialing callback %d: %v", record.ID, err)
+		e.mu.Lock()
+		cb.lastError = err.Error()
+		e.mu.Unlock()
+		e.h.client.HangupChannel(agent.ID)
+		return
+	}
+
+	e.mu.Lock()
+	if _, dialing := e.callbackAgents[agent.ID]; !dialing {
+		// The agent hung up while we were dialing
+		e.mu.Unlock()
+		e.h.client.HangupChannel(customer.ID)
+		return
+	}
+	cb.customerID = customer.ID
+	e.callbackLegs[customer.ID] = agent.ID
+	e.mu.Unlock()
+}
+
+// onCallbackAnswered bridges a customer who answered a callback with the agent waiting for them
+func (e *acdEngine) onCallbackAnswered(customer *Channel, args []string) {
+	if len(args) < 2 {
+		return
+	}
+	agentID := args[1]
+
+	e.mu.Lock()
+	delete(e.callbackLegs, customer.ID)
+	call, dialing := e.callbackAgents[agentID]
+	if !dialing || call.callback.customerID != customer.ID {
+		e.mu.Unlock()
+		log.Printf("Callback leg %s answered but agent %s is gone", customer.ID, agentID)
+		e.h.client.HangupChannel(customer.ID)
+		return
+	}
+	now := time.Now()
+	cb := call.callback
+	cb.customerID = ""
+	cb.answered = true
+	cb.record.Status = CallbackStatusConnected
+	cb.record.ConnectedAt = &now
+	snapshot := *cb.record
+	e.mu.Unlock()
+
+	e.saveCallback(snapshot)
+	log.Printf("Callback %d answered by %s", snapshot.ID, snapshot.CallbackNumber)
+
+	e.h.client.StopChannelMOH(agentID)
+	if err := e.h.bridgeChannels(customer.ID, agentID); err != nil {
+		log.Printf("Error bridging callback %d: %v", snapshot.ID, err)
+		e.h.client.HangupChannel(customer.ID)
+		e.h.client.HangupChannel(agentID)
+	}
+}
+
+// endCallbackAttempt settles a callback once its agent leg is gone. A call
+// that connected is completed; otherwise the callback goes back to the head
+// of its queue after the retry interval, or fails once out of attempts.
+func (e *acdEngine) endCallbackAttempt(call *queuedCall) {
+	e.mu.Lock()
+	cb := call.callback
+	record := cb.record
+	now := time.Now()
+
+	// The agent may have hung up while the customer was still ringing
+	customerID := cb.customerID
+	if customerID != "" {
+		delete(e.callbackLegs, customerID)
+	}
+	if !cb.answered && cb.lastError == "" {
+		cb.lastError = "agent hung up"
+	}
+
+	var q *acdQueue
+	restart := false
+	switch {
+	case cb.answered:
+		record.Status = CallbackStatusCompleted
+		record.EndedAt = &now
+	case record.Attempts >= record.MaxAttempts:
+		record.Status = CallbackStatusFailed
+		record.LastError = &cb.lastError
+		record.EndedAt = &now
+	default:
+		next := now.Add(time.Duration(call.queue.config.CallbackRetry) * time.Second)
+		record.Status = CallbackStatusPending
+		record.LastError = &cb.lastError
+		record.NextAttemptAt = &next
+		q, restart = e.requeueCallbackLocked(call, next)
+	}
+	cb.agentID = ""
+	cb.customerID = ""
+	cb.answered = false
+	cb.lastError = ""
+	snapshot := *record
+	e.mu.Unlock()
+
+	if customerID != "" {
+		e.h.client.HangupChannel(customerID)
+	}
+	if restart {
+		go e.run(q)
+	}
+
+	e.saveCallback(snapshot)
+	if snapshot.Status == CallbackStatusPending {
+		log.Printf("Callback %d attempt %d failed (%s), retrying at %s", snapshot.ID, snapshot.Attempts, *snapshot.LastError, snapshot.NextAttemptAt.Format(time.TimeOnly))
+	} else {
+		log.Printf("Callback %d %s after %d attempts", snapshot.ID, snapshot.Status, snapshot.Attempts)
+	}
+}
+
+// requeueCallbackLocked puts a callback back at the head of its queue,
+// reporting whether the queue has to be started again; the caller must hold e.mu
+func (e *acdEngine) requeueCallbackLocked(call *queuedCall, next time.Time) (*acdQueue, bool) {
+	key := call.queue.key
+	q, running := e.active[key]
+	if !running {
+		q = &acdQueue{key: key, config: call.queue.config}
+		e.active[key] = q
+	}
+	call.queue = q
+	call.nextOfferAt = next
+	call.offers = make(map[string]string)
+	q.callers = append([]*queuedCall{call}, q.callers...)
+	e.callers[call.channel.ID] = call
+	return q, !running
+}
+
+// restoreCallback queues a saved callback ahead of the callers who entered
+// the queue after its caller did
+func (e *acdEngine) restoreCallback(record *QueueCallback, did *DID) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	queue, err := e.queues.FindByName(ctx, record.TenantID, record.QueueName)
+	if err != nil {
+		return ErrCallbackQueueGone
+	}
+
+	now := time.Now()
+	call := &queuedCall{
+		channel: &Channel{
+			ID:     callbackChannelID(record.ID),
+			Caller: CallerID{Name: record.CallerName, Number: record.CallbackNumber},
+		},
+		did:             did,
+		enteredAt:       record.RequestedAt.Add(-time.Duration(record.WaitTime) * time.Second),
+		nextOfferAt:     now,
+		offers:          make(map[string]string),
+		callbackOffered: true,
+		callback:        &queuedCallback{record: record},
+	}
+
+	key := record.TenantID + "/" + queue.Name
+	e.mu.Lock()
+	q, running := e.active[key]
+	if !running {
+		q = &acdQueue{key: key, config: queue}
+		e.active[key] = q
+	}
+	call.queue = q
+	position := len(q.callers)
+	for i, c := range q.callers {
+		if c.enteredAt.After(call.enteredAt) {
+			position = i
+			break
+		}
+	}
+	q.callers = append(q.callers[:position], append([]*queuedCall{call}, q.callers[position:]...)...)
+	e.callers[call.channel.ID] = call
+	e.mu.Unlock()
+
+	if !running {
+		go e.run(q)
+	}
+	log.Printf("Callback %d restored to queue %s at position %d", record.ID, key, position+1)
+	return nil
+}
+
+// cancelCallback removes a tenant's callback from its queue
+func (e *acdEngine) cancelCallback(tenantID string, id int64) error {
+	e.mu.Lock()
+	for _, call := range e.callbackAgents {
+		if record := call.callback.record; record.ID == id && record.TenantID == tenantID {
+			e.mu.Unlock()
+			return ErrCallbackInProgress
+		}
+	}
+
+	call, ok := e.callers[callbackChannelID(id)]
+	if !ok || call.callback.record.TenantID != tenantID {
+		e.mu.Unlock()
+		return ErrCallbackNotQueued
+	}
+	e.removeCaller(call.queue, call)
+	var cancelled []string
+	for agentChannel := range call.offers {
+		delete(e.offers, agentChannel)
+		cancelled = append(cancelled, agentChannel)
+	}
+	e.mu.Unlock()
+
+	log.Printf("Callback %d cancelled in queue %s", id, call.queue.key)
+	for _, agentChannel := range cancelled {
+		e.h.client.HangupChannel(agentChannel)
+	}
+	return nil
+}
+
+// saveCallback stores a callback's progress
+func (e *acdEngine) saveCallback(record QueueCallback) {
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		if err := e.h.queueCallbacks.Update(ctx, &record); err != nil {
+			log.Printf("Error saving callback %d: %v", record.ID, err)
+		}
+	}()
+}
+
+// armCallbackTimer schedules the input timeout; the caller must hold e.mu
+func (e *acdEngine) armCallbackTimer(channelID string, prompt *callbackPrompt) {
+	stopCallbackTimer(prompt)
+	generation := prompt.generation
+	prompt.timer = time.AfterFunc(callbackInputTimeout, func() {
+		e.onCallbackTimeout(channelID, generation)
+	})
+}
+
+// stopCallbackTimer cancels the input timeout and invalidates one that already fired
+func stopCallbackTimer(prompt *callbackPrompt) {
+	prompt.generation++
+	if prompt.timer != nil {
+		prompt.timer.Stop()
+		prompt.timer = nil
+	}
+}
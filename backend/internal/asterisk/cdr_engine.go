@@ -0,0 +1,308 @@
+package asterisk
+
+import (
+	"log"
+	"strings"
+	"time"
+
+	"github.com/psschand/callcenter/internal/common"
+)
+
+// Ways a call detail segment ends other than hanging up
+const (
+	CallTransferBlind    = "blind"
+	CallTransferAttended = "attended"
+)
+
+// CallDetail is one leg of a call, from the caller's point of view, as it is
+// written to the CDR. A call that is transferred is written as a segment per
+// party that handled it, all sharing the LinkedID of the call.
+type CallDetail struct {
+	LinkedID      string
+	Sequence      int
+	Outbound      bool // the caller is the destination of a click-to-call
+	TenantID      string
+	UniqueID      string // caller channel
+	Channel       string
+	DstChannel    string
+	CallerNumber  string
+	CallerName    string
+	Destination   string
+	Context       string
+	DIDID         *int64
+	LastApp       string
+	LastData      string
+	QueueName     string
+	QueueWaitTime int
+	AgentEndpoint string // endpoint of the party the caller was connected to
+	StartedAt     time.Time
+	AnsweredAt    *time.Time
+	EndedAt       time.Time
+	Disposition   common.CallDisposition
+	Transfer      string // CallTransferBlind or CallTransferAttended if the segment ended in a transfer
+	TransferredTo string
+}
+
+// CallDetailListener is notified of each call detail segment once it is over
+type CallDetailListener func(detail CallDetail)
+
+// callDetail is the call detail segment being built for a caller channel
+type callDetail struct {
+	CallDetail
+	queueEnteredAt *time.Time
+	queueLeft      bool
+	agentChannelID string
+}
+
+// SetCallDetailListener sets the listener notified of finished call detail segments
+func (h *CallHandler) SetCallDetailListener(listener CallDetailListener) {
+	h.callDetailListener = listener
+}
+
+// startCallDetailLocked opens a call detail segment for a caller channel; h.mu must be held
+func (h *CallHandler) startCallDetailLocked(channel *Channel, call *Call, linkedID string, sequence int) *callDetail {
+	detail := &callDetail{CallDetail: CallDetail{
+		LinkedID:     linkedID,
+		Sequence:     sequence,
+		TenantID:     call.TenantID,
+		UniqueID:     channel.ID,
+		Channel:      channel.Name,
+		CallerNumber: call.CallerNumber,
+		CallerName:   call.CallerName,
+		Context:      channel.Dialplan.Context,
+		StartedAt:    time.Now(),
+	}}
+	if call.DID != nil {
+		detail.Destination = call.DID.Number
+		if call.DID.ID != 0 {
+			didID := call.DID.ID
+			detail.DIDID = &didID
+		}
+	}
+	h.callDetails[channel.ID] = detail
+	return detail
+}
+
+// noteCallStarted reports a new caller; the segment must have been started
+func (h *CallHandler) noteCallStarted(channelID string) {
+	h.mu.RLock()
+	detail, ok := h.callDetails[channelID]
+	var event CallEvent
+	if ok {
+		event = newCallEvent(CallEventIncoming, &detail.CallDetail)
+	}
+	h.mu.RUnlock()
+
+	if ok {
+		h.notifyCallEvents(event)
+	}
+}
+
+// noteCallRoute records the route a caller is sent down
+func (h *CallHandler) noteCallRoute(channelID string, routeType common.RouteType, target string) {
+	h.mu.Lock()
+	detail, ok := h.callDetails[channelID]
+	if !ok {
+		h.mu.Unlock()
+		return
+	}
+	detail.LastApp = string(routeType)
+	detail.LastData = target
+
+	// Routed on from a queue without being answered: the wait is over
+	var events []CallEvent
+	if detail.queueEnteredAt != nil && !detail.queueLeft {
+		detail.QueueWaitTime = int(time.Since(*detail.queueEnteredAt).Seconds())
+		detail.queueLeft = true
+		events = append(events, newCallEvent(CallEventQueueLeft, &detail.CallDetail))
+	}
+	h.mu.Unlock()
+
+	h.notifyCallEvents(events...)
+}
+
+// noteCallQueued records a caller entering a queue
+func (h *CallHandler) noteCallQueued(channelID, queueName string) {
+	h.mu.Lock()
+	detail, ok := h.callDetails[channelID]
+	var event CallEvent
+	if ok {
+		now := time.Now()
+		detail.QueueName = queueName
+		detail.queueEnteredAt = &now
+		detail.queueLeft = false
+		detail.QueueWaitTime = 0
+		event = newCallEvent(CallEventQueued, &detail.CallDetail)
+	}
+	h.mu.Unlock()
+
+	if ok {
+		h.notifyCallEvents(event)
+	}
+}
+
+// noteQueueAnswered records how long a queued caller waited for an agent
+func (h *CallHandler) noteQueueAnswered(channelID string, wait time.Duration) {
+	h.mu.Lock()
+	detail, ok := h.callDetails[channelID]
+	var event CallEvent
+	if ok {
+		detail.QueueWaitTime = int(wait.Seconds())
+		detail.queueLeft = true
+		event = newCallEvent(CallEventQueueLeft, &detail.CallDetail)
+	}
+	h.mu.Unlock()
+
+	if ok {
+		h.notifyCallEvents(event)
+	}
+}
+
+// noteCallConnected marks a caller answered by the party it was bridged with
+func (h *CallHandler) noteCallConnected(channelID, peerID string) {
+	h.mu.Lock()
+	detail, ok := h.callDetails[channelID]
+	var event CallEvent
+	if ok {
+		h.connectCallDetailLocked(detail, peerID)
+		event = newCallEvent(CallEventAnswered, &detail.CallDetail)
+	}
+	h.mu.Unlock()
+
+	if ok {
+		h.notifyCallEvents(event)
+	}
+}
+
+// connectCallDetailLocked records the party a segment is connected to; h.mu must be held
+func (h *CallHandler) connectCallDetailLocked(detail *callDetail, peerID string) {
+	if detail.AnsweredAt == nil {
+		now := time.Now()
+		detail.AnsweredAt = &now
+	}
+	detail.agentChannelID = peerID
+	if peer, ok := h.activeChannels[peerID]; ok {
+		detail.DstChannel = peer.Name
+		detail.AgentEndpoint = channelEndpoint(peer.Name)
+	}
+}
+
+// noteCallDisposition records why a caller that was never connected ended
+func (h *CallHandler) noteCallDisposition(channelID string, disposition common.CallDisposition) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if detail, ok := h.callDetails[channelID]; ok && detail.AnsweredAt == nil {
+		detail.Disposition = disposition
+	}
+}
+
+// transferCallDetail closes a caller's segment as transferred and opens the
+// next one. targetID is the transfer target's channel when it is already
+// connected (attended transfers).
+func (h *CallHandler) transferCallDetail(channelID, kind string, routeType common.RouteType, target, targetID string) {
+	h.mu.Lock()
+	var finished *CallDetail
+	var events []CallEvent
+	linkedID := channelID
+	sequence := 0
+	outbound := false
+
+	if detail, ok := h.callDetails[channelID]; ok {
+		detail.Transfer = kind
+		detail.TransferredTo = string(routeType) + ":" + target
+		finished = h.finishCallDetailLocked(detail)
+		events = append(events, newCallEvent(CallEventTransferred, finished))
+		linkedID = detail.LinkedID
+		sequence = detail.Sequence + 1
+		outbound = detail.Outbound
+	} else if callID, ok := h.outboundLegs[channelID]; ok {
+		// The destination of a click-to-call: its first segment is the
+		// click-to-call's own record
+		linkedID = callID
+		sequence = 1
+		outbound = true
+	}
+
+	channel, active := h.activeChannels[channelID]
+	call, known := h.calls[channelID]
+	if active && known {
+		next := h.startCallDetailLocked(channel, call, linkedID, sequence)
+		next.Outbound = outbound
+		next.Destination = target
+		next.LastApp = string(routeType)
+		next.LastData = target
+		if targetID != "" {
+			h.connectCallDetailLocked(next, targetID)
+			events = append(events, newCallEvent(CallEventAnswered, &next.CallDetail))
+		}
+	}
+	h.mu.Unlock()
+
+	if finished != nil {
+		h.notifyCallDetail(*finished)
+	}
+	h.notifyCallEvents(events...)
+}
+
+// endCallDetail closes a caller's segment when it leaves the application
+func (h *CallHandler) endCallDetail(channelID string) {
+	h.mu.Lock()
+	detail, ok := h.callDetails[channelID]
+	var finished *CallDetail
+	var events []CallEvent
+	if ok {
+		abandoned := detail.queueEnteredAt != nil && !detail.queueLeft
+		finished = h.finishCallDetailLocked(detail)
+		if abandoned {
+			events = append(events, newCallEvent(CallEventQueueLeft, finished))
+		}
+		events = append(events, newCallEvent(CallEventEnded, finished))
+	}
+	h.mu.Unlock()
+
+	if finished != nil {
+		h.notifyCallDetail(*finished)
+	}
+	h.notifyCallEvents(events...)
+}
+
+// finishCallDetailLocked removes a segment and fills in its outcome; h.mu must be held
+func (h *CallHandler) finishCallDetailLocked(detail *callDetail) *CallDetail {
+	delete(h.callDetails, detail.UniqueID)
+
+	detail.EndedAt = time.Now()
+	if detail.queueEnteredAt != nil && !detail.queueLeft {
+		// Abandoned in the queue
+		detail.QueueWaitTime = int(detail.EndedAt.Sub(*detail.queueEnteredAt).Seconds())
+	}
+	switch {
+	case detail.AnsweredAt != nil:
+		detail.Disposition = common.CallDispositionAnswered
+	case detail.Disposition == "":
+		detail.Disposition = common.CallDispositionNoAnswer
+	}
+
+	finished := detail.CallDetail
+	return &finished
+}
+
+// notifyCallDetail passes a finished call detail segment to the listener
+func (h *CallHandler) notifyCallDetail(detail CallDetail) {
+	log.Printf("Call %s segment %d ended (%s)", detail.LinkedID, detail.Sequence, detail.Disposition)
+	if h.callDetailListener != nil {
+		go h.callDetailListener(detail)
+	}
+}
+
+// channelEndpoint extracts the endpoint from a channel name ("PJSIP/acme-agent1-00000002" -> "acme-agent1")
+func channelEndpoint(name string) string {
+	_, endpoint, ok := strings.Cut(name, "/")
+	if !ok {
+		return name
+	}
+	if i := strings.LastIndex(endpoint, "-"); i > 0 {
+		endpoint = endpoint[:i]
+	}
+	return endpoint
+}
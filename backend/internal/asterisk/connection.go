@@ -0,0 +1,257 @@
+package asterisk
+
+import (
+	"context"
+	"errors"
+	"log"
+	"math/rand"
+	"strings"
+	"time"
+)
+
+// Reconnect backoff bounds for the ARI events WebSocket
+const (
+	reconnectInitialBackoff = 1 * time.Second
+	reconnectMaxBackoff     = 30 * time.Second
+)
+
+var errConnectionClosed = errors.New("ARI events connection closed")
+
+// ConnectionState is the state of the ARI events connection
+type ConnectionState string
+
+// Connection states
+const (
+	ConnectionStateDisconnected ConnectionState = "disconnected"
+	ConnectionStateConnecting   ConnectionState = "connecting"
+	ConnectionStateConnected    ConnectionState = "connected"
+	ConnectionStateReconnecting ConnectionState = "reconnecting"
+)
+
+// ConnectionStatus reports the health of the ARI connection
+type ConnectionStatus struct {
+	State             ConnectionState `json:"state"`
+	ConnectedSince    *time.Time      `json:"connected_since,omitempty"`
+	LastError         string          `json:"last_error,omitempty"`
+	LastErrorAt       *time.Time      `json:"last_error_at,omitempty"`
+	ReconnectAttempts int             `json:"reconnect_attempts"`
+	LastResyncAt      *time.Time      `json:"last_resync_at,omitempty"`
+	ActiveChannels    int             `json:"active_channels"`
+	ActiveBridges     int             `json:"active_bridges"`
+}
+
+// ConnectionStatus returns the current ARI connection status
+func (h *CallHandler) ConnectionStatus() ConnectionStatus {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	status := h.connStatus
+	status.ActiveChannels = len(h.activeChannels)
+	status.ActiveBridges = len(h.activeBridges)
+	return status
+}
+
+// IsConnected reports whether the ARI events connection is up
+func (h *CallHandler) IsConnected() bool {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.connStatus.State == ConnectionStateConnected
+}
+
+// setConnectionState records a state transition and the error that caused it, if any
+func (h *CallHandler) setConnectionState(state ConnectionState, err error) {
+	now := time.Now()
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.connStatus.State = state
+	switch state {
+	case ConnectionStateConnected:
+		h.connStatus.ConnectedSince = &now
+		h.connStatus.ReconnectAttempts = 0
+	case ConnectionStateReconnecting, ConnectionStateDisconnected:
+		h.connStatus.ConnectedSince = nil
+	}
+
+	if err != nil {
+		h.connStatus.LastError = err.Error()
+		h.connStatus.LastErrorAt = &now
+	}
+}
+
+// supervise keeps the ARI events connection up until ctx is cancelled,
+// reconnecting with exponential backoff and resyncing state after each connect
+func (h *CallHandler) supervise(ctx context.Context, connected bool) {
+	backoff := reconnectInitialBackoff
+
+	for {
+		if !connected {
+			if err := h.client.Connect(ctx); err != nil {
+				if ctx.Err() != nil {
+					break
+				}
+
+				h.mu.Lock()
+				h.connStatus.ReconnectAttempts++
+				h.mu.Unlock()
+				h.setConnectionState(ConnectionStateReconnecting, err)
+
+				wait := backoff + time.Duration(rand.Int63n(int64(backoff)/2+1))
+				log.Printf("ARI connection failed: %v (retrying in %s)", err, wait.Round(time.Millisecond))
+
+				select {
+				case <-ctx.Done():
+				case <-time.After(wait):
+				}
+				if ctx.Err() != nil {
+					break
+				}
+
+				backoff *= 2
+				if backoff > reconnectMaxBackoff {
+					backoff = reconnectMaxBackoff
+				}
+				continue
+			}
+		}
+
+		connected = false
+		backoff = reconnectInitialBackoff
+		h.setConnectionState(ConnectionStateConnected, nil)
+
+		if err := h.resync(); err != nil {
+			log.Printf("ARI resync failed: %v", err)
+		}
+
+		err := h.consume(ctx)
+		if ctx.Err() != nil {
+			break
+		}
+
+		log.Printf("ARI connection lost: %v", err)
+		h.setConnectionState(ConnectionStateReconnecting, err)
+	}
+
+	log.Println("Stopping ARI call handler")
+	h.client.Close()
+	h.setConnectionState(ConnectionStateDisconnected, nil)
+}
+
+// consume dispatches events from the current connection until it drops
+func (h *CallHandler) consume(ctx context.Context) error {
+	events, errs := h.client.ReadEvents()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+
+		case event, ok := <-events:
+			if !ok {
+				// The reader reports why it stopped, if it knows
+				if err, ok := <-errs; ok {
+					return err
+				}
+				return errConnectionClosed
+			}
+			h.handleEvent(event)
+
+		case err, ok := <-errs:
+			if !ok {
+				return errConnectionClosed
+			}
+			return err
+		}
+	}
+}
+
+// resync rebuilds the channel and bridge tables from Asterisk after a (re)connect
+// and drops call state for channels that disappeared while we were away
+func (h *CallHandler) resync() error {
+	channels, err := h.client.ListChannels()
+	if err != nil {
+		return err
+	}
+
+	bridges, err := h.client.ListBridges()
+	if err != nil {
+		return err
+	}
+
+	activeChannels := make(map[string]*Channel)
+	for i := range channels {
+		if h.inApplication(&channels[i]) {
+			activeChannels[channels[i].ID] = &channels[i]
+		}
+	}
+
+	activeBridges := make(map[string]*Bridge)
+	for i := range bridges {
+		if bridges[i].BridgeClass == "stasis" {
+			activeBridges[bridges[i].ID] = &bridges[i]
+		}
+	}
+
+	now := time.Now()
+	var failedDials [][2]string
+
+	h.mu.Lock()
+	h.activeChannels = activeChannels
+	h.activeBridges = activeBridges
+
+	for id := range h.calls {
+		if _, ok := activeChannels[id]; !ok {
+			delete(h.calls, id)
+		}
+	}
+	for id := range h.peers {
+		if _, ok := activeChannels[id]; !ok {
+			delete(h.peers, id)
+		}
+	}
+	for id, bridgeID := range h.callBridges {
+		_, channelOK := activeChannels[id]
+		_, bridgeOK := activeBridges[bridgeID]
+		if !channelOK || !bridgeOK {
+			delete(h.callBridges, id)
+		}
+	}
+
+	// Originated legs are not in Stasis until answered, so check them against the full list
+	all := make(map[string]bool, len(channels))
+	for _, ch := range channels {
+		all[ch.ID] = true
+	}
+	for outboundID, inboundID := range h.pendingDials {
+		if !all[outboundID] {
+			delete(h.pendingDials, outboundID)
+			failedDials = append(failedDials, [2]string{outboundID, inboundID})
+		}
+	}
+
+	h.connStatus.LastResyncAt = &now
+	h.mu.Unlock()
+
+	for _, dial := range failedDials {
+		go h.onDialFailed(dial[0], dial[1])
+	}
+	h.resyncIVR(all)
+	h.resyncMonitors(all)
+	h.resyncVoicemail(all)
+	if h.acd != nil {
+		h.acd.resync(all, activeBridges)
+	}
+
+	log.Printf("ARI resync: %d channels, %d bridges", len(activeChannels), len(activeBridges))
+	return nil
+}
+
+// inApplication reports whether a channel is currently in our Stasis application
+func (h *CallHandler) inApplication(channel *Channel) bool {
+	if channel.Dialplan.AppName != "Stasis" {
+		return false
+	}
+	app, _, _ := strings.Cut(channel.Dialplan.AppData, ",")
+	return app == h.client.appName
+}
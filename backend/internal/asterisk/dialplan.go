@@ -0,0 +1,119 @@
+package asterisk
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Dialplan contexts generated from the DIDs table. Trunk contexts in
+// extensions.conf include DialplanDIDContext.
+const (
+	DialplanDIDContext      = "callcenter-dids"
+	dialplanUnroutedContext = "callcenter-unrouted"
+)
+
+// Asterisk module that loads extensions.conf and the files it includes
+const dialplanModule = "pbx_config.so"
+
+var (
+	dialplanNumberPattern = regexp.MustCompile(`^\+?[0-9]+$`)
+	dialplanTenantPattern = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+)
+
+// ReloadDialplan makes Asterisk re-read extensions.conf and the generated
+// DID dialplan it includes
+func (h *CallHandler) ReloadDialplan() error {
+	return h.client.ReloadModule(dialplanModule)
+}
+
+// DialplanSkipReason returns why a DID cannot be written into the dialplan,
+// or "" if it can. Numbers must be digits with an optional leading + and
+// tenant IDs must be usable in a context name.
+func DialplanSkipReason(did *DID) string {
+	if !dialplanNumberPattern.MatchString(did.Number) {
+		return "number is not a valid extension"
+	}
+	if !dialplanTenantPattern.MatchString(did.TenantID) {
+		return "tenant ID is not a valid context name"
+	}
+	return ""
+}
+
+// DialplanTenantContext returns the name of the context a tenant's DIDs are
+// generated into
+func DialplanTenantContext(tenantID string) string {
+	return "tenant-" + tenantID
+}
+
+// RenderDialplan renders the dialplan that sends calls to the DIDs into the
+// Stasis application app. Each tenant gets a context whose extensions tag the
+// call with the tenant and DID before handing it to the application, under
+// both the stored form of the number and the form with or without a leading
+// +. DIDs with a DialplanSkipReason are left out; numbers no DID matches
+// still reach the application, which rejects them.
+func RenderDialplan(app string, dids []DID) string {
+	sorted := make([]DID, 0, len(dids))
+	numbers := make(map[string]bool, len(dids))
+	for _, did := range dids {
+		if DialplanSkipReason(&did) != "" {
+			continue
+		}
+		sorted = append(sorted, did)
+		numbers[did.Number] = true
+	}
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if sorted[i].TenantID != sorted[j].TenantID {
+			return sorted[i].TenantID < sorted[j].TenantID
+		}
+		return sorted[i].Number < sorted[j].Number
+	})
+
+	var b strings.Builder
+	b.WriteString("; Generated by the call center API from the DIDs table.\n")
+	b.WriteString("; Do not edit: changes are overwritten when DIDs change.\n")
+
+	var contexts []string
+	for i, did := range sorted {
+		context := DialplanTenantContext(did.TenantID)
+		if i == 0 || did.TenantID != sorted[i-1].TenantID {
+			contexts = append(contexts, context)
+			fmt.Fprintf(&b, "\n[%s]\n", context)
+		}
+
+		fmt.Fprintf(&b, "exten => %s,1,NoOp(Inbound call for DID %d)\n", did.Number, did.ID)
+		fmt.Fprintf(&b, " same => n,Set(__%s=%s)\n", VarTenantID, did.TenantID)
+		fmt.Fprintf(&b, " same => n,Set(__%s=%d)\n", VarDIDID, did.ID)
+		fmt.Fprintf(&b, " same => n,Stasis(%s,incoming,%s)\n", app, did.Number)
+		b.WriteString(" same => n,Hangup()\n")
+
+		// Providers differ in whether they send the +, so the other form is
+		// routed to the same DID unless another DID is stored under it
+		alternate := "+" + did.Number
+		if strings.HasPrefix(did.Number, "+") {
+			alternate = strings.TrimPrefix(did.Number, "+")
+		}
+		if !numbers[alternate] {
+			numbers[alternate] = true
+			fmt.Fprintf(&b, "exten => %s,1,Goto(%s,1)\n", alternate, did.Number)
+		}
+	}
+
+	fmt.Fprintf(&b, "\n[%s]\n", DialplanDIDContext)
+	for _, context := range contexts {
+		fmt.Fprintf(&b, "include => %s\n", context)
+	}
+	fmt.Fprintf(&b, "include => %s\n", dialplanUnroutedContext)
+
+	// Kept in its own context so the patterns are only tried after every
+	// tenant's exact numbers
+	fmt.Fprintf(&b, "\n[%s]\n", dialplanUnroutedContext)
+	for _, pattern := range []string{"_X.", "_+X."} {
+		fmt.Fprintf(&b, "exten => %s,1,NoOp(Inbound call for unknown DID ${EXTEN})\n", pattern)
+		fmt.Fprintf(&b, " same => n,Stasis(%s,incoming,${EXTEN})\n", app)
+		b.WriteString(" same => n,Hangup()\n")
+	}
+
+	return b.String()
+}
@@ -0,0 +1,98 @@
+package asterisk
+
+import (
+	"time"
+
+	"github.com/psschand/callcenter/internal/core"
+)
+
+// IVR option actions
+const (
+	IVRActionQueue     = "queue"
+	IVRActionEndpoint  = "endpoint"
+	IVRActionSubmenu   = "submenu"
+	IVRActionVoicemail = "voicemail"
+	IVRActionExternal  = "external"
+	IVRActionWebhook   = "webhook"
+	IVRActionHangup    = "hangup"
+)
+
+// Special IVR option digits, taken when the caller exhausts max_attempts
+const (
+	IVRDigitTimeout = "t" // No input
+	IVRDigitInvalid = "i" // Invalid input
+)
+
+// IVRMenu represents an interactive voice response menu
+// @Description IVR menu with greeting and input settings
+type IVRMenu struct {
+	ID               int64     `gorm:"column:id;primaryKey;autoIncrement" json:"id" example:"1"`
+	TenantID         string    `gorm:"column:tenant_id;type:varchar(36);not null;index" json:"tenant_id" example:"acme-corp"`
+	Name             string    `gorm:"column:name;type:varchar(255);not null" json:"name" example:"main"`
+	Description      *string   `gorm:"column:description;type:text" json:"description,omitempty" example:"Main menu"`
+	GreetingAudioURL *string   `gorm:"column:greeting_audio_url;type:varchar(500)" json:"greeting_audio_url,omitempty" example:"sound:custom/main-menu"`
+	GreetingText     *string   `gorm:"column:greeting_text;type:text" json:"greeting_text,omitempty" example:"Press 1 for sales, 2 for support"`
+	Timeout          int       `gorm:"column:timeout;not null;default:5" json:"timeout" example:"5"`
+	MaxAttempts      int       `gorm:"column:max_attempts;not null;default:3" json:"max_attempts" example:"3"`
+	InvalidAudioURL  *string   `gorm:"column:invalid_audio_url;type:varchar(500)" json:"invalid_audio_url,omitempty" example:"sound:option-is-invalid"`
+	TimeoutAudioURL  *string   `gorm:"column:timeout_audio_url;type:varchar(500)" json:"timeout_audio_url,omitempty"`
+	IsActive         bool      `gorm:"column:is_active;not null;default:true;index" json:"is_active" example:"true"`
+	CreatedAt        time.Time `gorm:"column:created_at;autoCreateTime" json:"created_at"`
+	UpdatedAt        time.Time `gorm:"column:updated_at;autoUpdateTime" json:"updated_at"`
+
+	// Relations
+	Tenant  *core.Tenant `gorm:"foreignKey:TenantID" json:"tenant,omitempty"`
+	Options []IVROption  `gorm:"foreignKey:IVRMenuID" json:"options,omitempty"`
+}
+
+// TableName specifies the table name
+func (IVRMenu) TableName() string {
+	return "ivr_menus"
+}
+
+// FindOption returns the option for a digit sequence
+func (m *IVRMenu) FindOption(digits string) *IVROption {
+	for i := range m.Options {
+		if m.Options[i].Digit == digits {
+			return &m.Options[i]
+		}
+	}
+	return nil
+}
+
+// HasOptionPrefix checks if a longer option starts with the digits entered so far
+func (m *IVRMenu) HasOptionPrefix(digits string) bool {
+	for _, option := range m.Options {
+		if len(option.Digit) > len(digits) && option.Digit[:len(digits)] == digits {
+			return true
+		}
+	}
+	return false
+}
+
+// IVROption represents a choice within an IVR menu
+// @Description IVR menu option mapping a digit to an action
+type IVROption struct {
+	ID          int64     `gorm:"column:id;primaryKey;autoIncrement" json:"id" example:"1"`
+	IVRMenuID   int64     `gorm:"column:ivr_menu_id;not null;index" json:"ivr_menu_id" example:"1"`
+	Digit       string    `gorm:"column:digit;type:varchar(10);not null;index" json:"digit" example:"1"`
+	Action      string    `gorm:"column:action;type:varchar(50);not null" json:"action" example:"queue"`
+	ActionData  *string   `gorm:"column:action_data;type:varchar(500)" json:"action_data,omitempty" example:"sales"`
+	Description *string   `gorm:"column:description;type:varchar(255)" json:"description,omitempty" example:"Sales"`
+	SortOrder   int       `gorm:"column:sort_order;not null;default:0;index" json:"sort_order" example:"0"`
+	CreatedAt   time.Time `gorm:"column:created_at;autoCreateTime" json:"created_at"`
+	UpdatedAt   time.Time `gorm:"column:updated_at;autoUpdateTime" json:"updated_at"`
+}
+
+// TableName specifies the table name
+func (IVROption) TableName() string {
+	return "ivr_options"
+}
+
+// GetActionData returns the action data or an empty string
+func (o *IVROption) GetActionData() string {
+	if o.ActionData == nil {
+		return ""
+	}
+	return *o.ActionData
+}
@@ -0,0 +1,417 @@
+package asterisk
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/psschand/callcenter/internal/common"
+)
+
+// IVR defaults, used when a menu leaves them unset
+const (
+	ivrDefaultTimeout      = 5
+	ivrDefaultMaxAttempts  = 3
+	ivrDefaultInvalidSound = "sound:option-is-invalid"
+	ivrGoodbyeSound        = "goodbye"
+	ivrInterDigitTimeout   = 2 * time.Second
+)
+
+// IVRMenuLoader loads IVR menus with their options.
+// repository.IVRMenuRepository satisfies this interface.
+type IVRMenuLoader interface {
+	FindWithOptions(ctx context.Context, id int64) (*IVRMenu, error)
+	FindByName(ctx context.Context, tenantID, name string) (*IVRMenu, error)
+}
+
+// ivrSession tracks a caller navigating an IVR menu
+type ivrSession struct {
+	channel    *Channel
+	did        *DID
+	menu       *IVRMenu
+	attempts   int
+	digits     string
+	prompts    []string // Media still to play before collecting input
+	playbackID string
+	generation int // Bumped on every state change so stale timers are ignored
+	timer      *time.Timer
+}
+
+// SetIVRMenuLoader enables the IVR engine; DIDs routed to "ivr" run the menu named by their target
+func (h *CallHandler) SetIVRMenuLoader(loader IVRMenuLoader) {
+	h.ivr = loader
+	h.RegisterRouteHandler(common.RouteTypeIVR, h.routeToIVR)
+}
+
+// routeToIVR answers the channel and starts the IVR menu identified by target (ID or name)
+func (h *CallHandler) routeToIVR(channel *Channel, did *DID, target string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	menu, err := h.loadIVRMenu(ctx, did.TenantID, target)
+	if err != nil {
+		return err
+	}
+
+	if err := h.client.AnswerChannel(channel.ID); err != nil {
+		return err
+	}
+
+	h.startIVRMenu(channel, did, menu)
+	return nil
+}
+
+// loadIVRMenu loads an active menu of the tenant by ID or name
+func (h *CallHandler) loadIVRMenu(ctx context.Context, tenantID, target string) (*IVRMenu, error) {
+	if h.ivr == nil {
+		return nil, fmt.Errorf("IVR engine not configured")
+	}
+
+	id, err := strconv.ParseInt(target, 10, 64)
+	if err != nil {
+		menu, err := h.ivr.FindByName(ctx, tenantID, target)
+		if err != nil {
+			return nil, fmt.Errorf("IVR menu %q not found: %w", target, err)
+		}
+		id = menu.ID
+	}
+
+	menu, err := h.ivr.FindWithOptions(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("IVR menu %q not found: %w", target, err)
+	}
+	if menu.TenantID != tenantID {
+		return nil, fmt.Errorf("IVR menu %q not found", target)
+	}
+	if !menu.IsActive {
+		return nil, fmt.Errorf("IVR menu %q is inactive", target)
+	}
+
+	return menu, nil
+}
+
+// startIVRMenu enters a menu and plays its greeting
+func (h *CallHandler) startIVRMenu(channel *Channel, did *DID, menu *IVRMenu) {
+	log.Printf("Channel %s entering IVR menu %d (%s)", channel.ID, menu.ID, menu.Name)
+
+	stale := h.endIVRSession(channel.ID)
+	if stale != "" {
+		h.client.StopPlayback(stale)
+	}
+
+	h.mu.Lock()
+	h.ivrSessions[channel.ID] = &ivrSession{
+		channel: channel,
+		did:     did,
+		menu:    menu,
+	}
+	h.mu.Unlock()
+
+	h.promptIVR(channel.ID, greetingPrompts(menu))
+}
+
+// promptIVR plays prompts and then waits for input
+func (h *CallHandler) promptIVR(channelID string, prompts []string) {
+	h.mu.Lock()
+	session, ok := h.ivrSessions[channelID]
+	if ok {
+		session.digits = ""
+		session.prompts = prompts
+		stopIVRTimer(session)
+	}
+	h.mu.Unlock()
+
+	if ok {
+		h.playNextIVRPrompt(channelID)
+	}
+}
+
+// playNextIVRPrompt plays the next queued prompt, or starts the input timeout when there is none
+func (h *CallHandler) playNextIVRPrompt(channelID string) {
+	h.mu.Lock()
+	session, ok := h.ivrSessions[channelID]
+	if !ok {
+		h.mu.Unlock()
+		return
+	}
+	if len(session.prompts) == 0 {
+		h.armIVRTimer(channelID, session, time.Duration(menuTimeout(session.menu))*time.Second)
+		h.mu.Unlock()
+		return
+	}
+	media := session.prompts[0]
+	session.prompts = session.prompts[1:]
+	h.mu.Unlock()
+
+	playback, err := h.client.PlayMedia(channelID, media)
+	if err != nil {
+		log.Printf("Error playing IVR prompt %s on %s: %v", media, channelID, err)
+		h.playNextIVRPrompt(channelID)
+		return
+	}
+
+	h.mu.Lock()
+	if session, ok := h.ivrSessions[channelID]; ok {
+		session.playbackID = playback.ID
+		h.ivrPlaybacks[playback.ID] = channelID
+	}
+	h.mu.Unlock()
+}
+
+// onIVRPlaybackFinished continues the prompt sequence; it reports whether the playback belonged to an IVR
+func (h *CallHandler) onIVRPlaybackFinished(playbackID string) bool {
+	h.mu.Lock()
+	channelID, ok := h.ivrPlaybacks[playbackID]
+	delete(h.ivrPlaybacks, playbackID)
+	if ok {
+		if session, exists := h.ivrSessions[channelID]; exists && session.playbackID == playbackID {
+			session.playbackID = ""
+		}
+	}
+	h.mu.Unlock()
+
+	if ok {
+		h.playNextIVRPrompt(channelID)
+	}
+	return ok
+}
+
+// onIVRDigit collects a DTMF digit; it reports whether the channel is in an IVR
+func (h *CallHandler) onIVRDigit(channelID, digit string) bool {
+	h.mu.Lock()
+	session, ok := h.ivrSessions[channelID]
+	if !ok {
+		h.mu.Unlock()
+		return false
+	}
+
+	// Barge-in: input interrupts any prompt
+	playbackID := session.playbackID
+	session.playbackID = ""
+	session.prompts = nil
+	delete(h.ivrPlaybacks, playbackID)
+	stopIVRTimer(session)
+
+	session.digits += digit
+	digits := session.digits
+	option := session.menu.FindOption(digits)
+
+	// Wait for more digits while a longer option could still match
+	waiting := session.menu.HasOptionPrefix(digits)
+	if waiting {
+		h.armIVRTimer(channelID, session, ivrInterDigitTimeout)
+	}
+	h.mu.Unlock()
+
+	if playbackID != "" {
+		h.client.StopPlayback(playbackID)
+	}
+
+	log.Printf("IVR input on %s: %s", channelID, digits)
+
+	switch {
+	case waiting:
+	case option != nil:
+		h.executeIVROption(channelID, option)
+	default:
+		h.handleIVRFailure(channelID, IVRDigitInvalid)
+	}
+	return true
+}
+
+// onIVRTimeout fires when the caller stops entering digits
+func (h *CallHandler) onIVRTimeout(channelID string, generation int) {
+	h.mu.Lock()
+	session, ok := h.ivrSessions[channelID]
+	if !ok || session.generation != generation {
+		h.mu.Unlock()
+		return
+	}
+	digits := session.digits
+	option := session.menu.FindOption(digits)
+	h.mu.Unlock()
+
+	switch {
+	case digits == "":
+		h.handleIVRFailure(channelID, IVRDigitTimeout)
+	case option != nil:
+		h.executeIVROption(channelID, option)
+	default:
+		h.handleIVRFailure(channelID, IVRDigitInvalid)
+	}
+}
+
+// handleIVRFailure re-prompts after no or invalid input until max_attempts is reached,
+// then takes the menu's "t"/"i" option if it has one, or says goodbye
+func (h *CallHandler) handleIVRFailure(channelID, kind string) {
+	h.mu.Lock()
+	session, ok := h.ivrSessions[channelID]
+	if !ok {
+		h.mu.Unlock()
+		return
+	}
+	session.attempts++
+	menu := session.menu
+	exhausted := session.attempts >= menuMaxAttempts(menu)
+	h.mu.Unlock()
+
+	if exhausted {
+		log.Printf("IVR menu %d: caller %s exhausted %d attempts", menu.ID, channelID, menuMaxAttempts(menu))
+		if option := menu.FindOption(kind); option != nil {
+			h.executeIVROption(channelID, option)
+			return
+		}
+		h.endIVRSession(channelID)
+		if err := h.announceAndHangup(channelID, ivrGoodbyeSound); err != nil {
+			h.client.HangupChannel(channelID)
+		}
+		return
+	}
+
+	var prompts []string
+	if kind == IVRDigitInvalid {
+		if menu.InvalidAudioURL != nil && *menu.InvalidAudioURL != "" {
+			prompts = append(prompts, mediaURI(*menu.InvalidAudioURL))
+		} else {
+			prompts = append(prompts, ivrDefaultInvalidSound)
+		}
+	} else if menu.TimeoutAudioURL != nil && *menu.TimeoutAudioURL != "" {
+		prompts = append(prompts, mediaURI(*menu.TimeoutAudioURL))
+	}
+
+	h.promptIVR(channelID, append(prompts, greetingPrompts(menu)...))
+}
+
+// executeIVROption runs the action of a selected option
+func (h *CallHandler) executeIVROption(channelID string, option *IVROption) {
+	h.mu.RLock()
+	session, ok := h.ivrSessions[channelID]
+	h.mu.RUnlock()
+	if !ok {
+		return
+	}
+
+	data := option.GetActionData()
+	log.Printf("IVR menu %d on %s: option %s -> %s %s", session.menu.ID, channelID, option.Digit, option.Action, data)
+
+	if option.Action == IVRActionSubmenu {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		menu, err := h.loadIVRMenu(ctx, session.menu.TenantID, data)
+		if err != nil {
+			log.Printf("Error loading IVR submenu for %s: %v", channelID, err)
+			h.handleIVRFailure(channelID, IVRDigitInvalid)
+			return
+		}
+		h.startIVRMenu(session.channel, session.did, menu)
+		return
+	}
+
+	var err error
+	switch option.Action {
+	case IVRActionHangup:
+		h.endIVRSession(channelID)
+		err = h.client.HangupChannel(channelID)
+	case IVRActionQueue, IVRActionEndpoint, IVRActionVoicemail, IVRActionExternal, IVRActionWebhook:
+		h.endIVRSession(channelID)
+		err = h.RouteCall(session.channel, session.did, common.RouteType(option.Action), data)
+	default:
+		log.Printf("Unknown IVR action %q on menu %d", option.Action, session.menu.ID)
+		h.handleIVRFailure(channelID, IVRDigitInvalid)
+		return
+	}
+
+	if err != nil {
+		log.Printf("Error executing IVR option %s on %s: %v", option.Digit, channelID, err)
+		h.rejectCall(channelID, RejectTreatmentCongestion)
+	}
+}
+
+// endIVRSession removes a channel's IVR session and returns the playback still running, if any
+func (h *CallHandler) endIVRSession(channelID string) string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	session, ok := h.ivrSessions[channelID]
+	if !ok {
+		return ""
+	}
+	stopIVRTimer(session)
+	delete(h.ivrSessions, channelID)
+	delete(h.ivrPlaybacks, session.playbackID)
+	return session.playbackID
+}
+
+// resyncIVR ends the menus of callers who hung up while ARI was disconnected
+func (h *CallHandler) resyncIVR(channels map[string]bool) {
+	h.mu.Lock()
+	var gone []string
+	for channelID := range h.ivrSessions {
+		if !channels[channelID] {
+			gone = append(gone, channelID)
+		}
+	}
+	h.mu.Unlock()
+
+	for _, channelID := range gone {
+		h.endIVRSession(channelID)
+	}
+	if len(gone) > 0 {
+		log.Printf("IVR resync: ended %d menus", len(gone))
+	}
+}
+
+// armIVRTimer schedules the input timeout; the caller must hold h.mu
+func (h *CallHandler) armIVRTimer(channelID string, session *ivrSession, d time.Duration) {
+	stopIVRTimer(session)
+	generation := session.generation
+	session.timer = time.AfterFunc(d, func() {
+		h.onIVRTimeout(channelID, generation)
+	})
+}
+
+// stopIVRTimer cancels the input timeout and invalidates one that already fired
+func stopIVRTimer(session *ivrSession) {
+	session.generation++
+	if session.timer != nil {
+		session.timer.Stop()
+		session.timer = nil
+	}
+}
+
+// greetingPrompts returns the greeting media of a menu
+func greetingPrompts(menu *IVRMenu) []string {
+	if menu.GreetingAudioURL != nil && *menu.GreetingAudioURL != "" {
+		return []string{mediaURI(*menu.GreetingAudioURL)}
+	}
+	if menu.GreetingText != nil && *menu.GreetingText != "" {
+		log.Printf("IVR menu %d has greeting text but no audio; text-to-speech is not supported", menu.ID)
+	}
+	return nil
+}
+
+// mediaURI turns a configured prompt into an ARI media URI; bare names are sounds
+func mediaURI(prompt string) string {
+	if strings.Contains(prompt, ":") {
+		return prompt
+	}
+	return "sound:" + prompt
+}
+
+func menuTimeout(menu *IVRMenu) int {
+	if menu.Timeout > 0 {
+		return menu.Timeout
+	}
+	return ivrDefaultTimeout
+}
+
+func menuMaxAttempts(menu *IVRMenu) int {
+	if menu.MaxAttempts > 0 {
+		return menu.MaxAttempts
+	}
+	return ivrDefaultMaxAttempts
+}
@@ -0,0 +1,80 @@
+package asterisk
+
+import (
+	"time"
+
+	"github.com/psschand/callcenter/internal/core"
+)
+
+// Monitor modes
+const (
+	MonitorModeListen  = "listen"  // supervisor hears both parties
+	MonitorModeWhisper = "whisper" // supervisor also talks to the agent only
+	MonitorModeBarge   = "barge"   // supervisor talks to both parties
+)
+
+// Monitor session statuses
+const (
+	MonitorStatusRinging = "ringing"
+	MonitorStatusActive  = "active"
+	MonitorStatusEnded   = "ended"
+)
+
+// Monitor session events
+const (
+	MonitorEventStarted     = "started"
+	MonitorEventAnswered    = "answered"
+	MonitorEventModeChanged = "mode_changed"
+	MonitorEventEnded       = "ended"
+)
+
+// MonitorSession records a supervisor monitoring a live call
+// @Description Supervisor listen/whisper/barge session
+type MonitorSession struct {
+	ID                 int64      `gorm:"column:id;primaryKey;autoIncrement" json:"id" example:"1"`
+	TenantID           string     `gorm:"column:tenant_id;type:varchar(64);not null;index:idx_tenant_started" json:"tenant_id" example:"acme-corp"`
+	SupervisorID       int64      `gorm:"column:supervisor_id;not null;index" json:"supervisor_id" example:"3"`
+	SupervisorEndpoint string     `gorm:"column:supervisor_endpoint;type:varchar(128);not null" json:"supervisor_endpoint" example:"acme-supervisor1"`
+	TargetChannelID    string     `gorm:"column:target_channel_id;type:varchar(150);not null;index" json:"target_channel_id" example:"1634567890.123"`
+	TargetChannel      *string    `gorm:"column:target_channel;type:varchar(128)" json:"target_channel,omitempty" example:"PJSIP/acme-agent1-00000002"`
+	CallerNumber       *string    `gorm:"column:caller_number;type:varchar(80)" json:"caller_number,omitempty" example:"+15551234567"`
+	Mode               string     `gorm:"column:mode;type:enum('listen','whisper','barge');not null;default:listen" json:"mode" example:"listen"`
+	Status             string     `gorm:"column:status;type:enum('ringing','active','ended');not null;default:ringing;index" json:"status" example:"active"`
+	EndReason          *string    `gorm:"column:end_reason;type:varchar(64)" json:"end_reason,omitempty" example:"call_ended"`
+	StartedAt          time.Time  `gorm:"column:started_at;not null;index:idx_tenant_started" json:"started_at"`
+	AnsweredAt         *time.Time `gorm:"column:answered_at" json:"answered_at,omitempty"`
+	EndedAt            *time.Time `gorm:"column:ended_at" json:"ended_at,omitempty"`
+	CreatedAt          time.Time  `gorm:"column:created_at;autoCreateTime" json:"created_at"`
+	UpdatedAt          time.Time  `gorm:"column:updated_at;autoUpdateTime" json:"updated_at"`
+
+	// Relations
+	Tenant     *core.Tenant          `gorm:"foreignKey:TenantID" json:"tenant,omitempty"`
+	Supervisor *core.User            `gorm:"foreignKey:SupervisorID" json:"supervisor,omitempty"`
+	Events     []MonitorSessionEvent `gorm:"foreignKey:SessionID" json:"events,omitempty"`
+}
+
+// TableName specifies the table name
+func (MonitorSession) TableName() string {
+	return "monitor_sessions"
+}
+
+// IsEnded checks if the session is over
+func (s *MonitorSession) IsEnded() bool {
+	return s.Status == MonitorStatusEnded
+}
+
+// MonitorSessionEvent is an entry in a monitor session's audit trail
+// @Description Monitor session audit event
+type MonitorSessionEvent struct {
+	ID        int64     `gorm:"column:id;primaryKey;autoIncrement" json:"id" example:"1"`
+	SessionID int64     `gorm:"column:session_id;not null;index" json:"session_id" example:"1"`
+	Event     string    `gorm:"column:event;type:varchar(32);not null" json:"event" example:"mode_changed"`
+	Mode      *string   `gorm:"column:mode;type:enum('listen','whisper','barge')" json:"mode,omitempty" example:"whisper"`
+	UserID    *int64    `gorm:"column:user_id" json:"user_id,omitempty" example:"3"`
+	CreatedAt time.Time `gorm:"column:created_at;autoCreateTime" json:"created_at"`
+}
+
+// TableName specifies the table name
+func (MonitorSessionEvent) TableName() string {
+	return "monitor_session_events"
+}
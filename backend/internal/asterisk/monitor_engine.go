@@ -0,0 +1,411 @@
+package asterisk
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+)
+
+// Stasis application arguments marking the legs of a monitor session
+const (
+	appArgMonitor = "monitor" // supervisor's phone
+	appArgSnoop   = "snoop"   // snoop channel on the monitored agent
+)
+
+// Monitor session end reasons
+const (
+	MonitorEndCallEnded      = "call_ended"
+	MonitorEndSupervisorLeft = "supervisor_hangup"
+	MonitorEndNoAnswer       = "no_answer"
+	MonitorEndStopped        = "stopped"
+	MonitorEndFailed         = "failed"
+)
+
+// Monitor errors
+var (
+	ErrMonitorNotFound    = errors.New("monitor session not found")
+	ErrInvalidMonitorMode = errors.New("invalid monitor mode")
+)
+
+// MonitorSessionListener is notified when a monitor session is answered by the
+// supervisor or ends; reason is only set when it ends
+type MonitorSessionListener func(sessionID int64, status, reason string)
+
+// monitorSession connects a supervisor's phone to a snoop channel on the
+// agent leg of a call through a mixing bridge
+type monitorSession struct {
+	id           int64
+	targetID     string // agent leg being snooped on
+	supervisorID string
+	snoopID      string
+	bridgeID     string
+	mode         string
+	answered     bool
+}
+
+// SetMonitorListener sets the listener notified of monitor session state changes
+func (h *CallHandler) SetMonitorListener(listener MonitorSessionListener) {
+	h.monitorListener = listener
+}
+
+// IsValidMonitorMode checks if mode is a known monitor mode
+func IsValidMonitorMode(mode string) bool {
+	switch mode {
+	case MonitorModeListen, MonitorModeWhisper, MonitorModeBarge:
+		return true
+	}
+	return false
+}
+
+// snoopDirections returns the snoop spy and whisper directions for a monitor mode.
+// Whispering "out" is heard by the agent only, "both" also reaches the other party.
+func snoopDirections(mode string) (spy, whisper string) {
+	switch mode {
+	case MonitorModeWhisper:
+		return "both", "out"
+	case MonitorModeBarge:
+		return "both", "both"
+	default:
+		return "both", "none"
+	}
+}
+
+// MonitorTarget returns the agent leg a monitor session on channelID would snoop on
+func (h *CallHandler) MonitorTarget(channelID string) (*Channel, bool) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	if _, ok := h.activeChannels[channelID]; !ok {
+		return nil, false
+	}
+	target, ok := h.activeChannels[h.agentLegLocked(channelID)]
+	if !ok {
+		return nil, false
+	}
+	snapshot := *target
+	return &snapshot, true
+}
+
+// StartMonitor rings the supervisor's endpoint and, once answered, connects it
+// to the agent leg of the call targetChannelID belongs to
+func (h *CallHandler) StartMonitor(sessionID int64, targetChannelID, supervisorEndpoint, mode string) error {
+	if !IsValidMonitorMode(mode) {
+		return ErrInvalidMonitorMode
+	}
+
+	endpoint := supervisorEndpoint
+	if !strings.Contains(endpoint, "/") {
+		endpoint = "PJSIP/" + endpoint
+	}
+
+	h.mu.Lock()
+	caller, ok := h.activeChannels[targetChannelID]
+	if !ok {
+		h.mu.Unlock()
+		return ErrCallNotFound
+	}
+	targetID := h.agentLegLocked(targetChannelID)
+	if _, ok := h.activeChannels[targetID]; !ok {
+		h.mu.Unlock()
+		return ErrCallNotFound
+	}
+	session := &monitorSession{id: sessionID, targetID: targetID, mode: mode}
+	h.monitors[sessionID] = session
+	callerNumber := caller.Caller.Number
+	h.mu.Unlock()
+
+	// The supervisor's phone shows who the monitored call is with
+	supervisor, err := h.client.OriginateChannel(endpoint, callerNumber, h.routing.DialTimeout,
+		fmt.Sprintf("%s,%d", appArgMonitor, sessionID))
+	if err != nil {
+		h.mu.Lock()
+		delete(h.monitors, sessionID)
+		h.mu.Unlock()
+		return fmt.Errorf("failed to ring %s: %w", endpoint, err)
+	}
+
+	h.mu.Lock()
+	if _, ok := h.monitors[sessionID]; ok {
+		session.supervisorID = supervisor.ID
+		h.monitorLegs[supervisor.ID] = sessionID
+	}
+	h.mu.Unlock()
+
+	log.Printf("Monitor session %d: ringing %s to %s %s", sessionID, endpoint, mode, targetID)
+	return nil
+}
+
+// agentLegLocked returns the agent side of the call a channel belongs to, which
+// is where whispered audio must be injected; h.mu must be held
+func (h *CallHandler) agentLegLocked(channelID string) string {
+	if callID, ok := h.outboundLegs[channelID]; ok {
+		if call, ok := h.outboundCalls[callID]; ok && call.AgentChannelID != "" {
+			return call.AgentChannelID
+		}
+	}
+	if _, inbound := h.calls[channelID]; inbound {
+		if peerID, ok := h.peers[channelID]; ok {
+			return peerID
+		}
+	}
+	return channelID
+}
+
+// SetMonitorMode switches a monitor session between listen, whisper and barge.
+// A new snoop channel is created with the new directions and replaces the old one.
+func (h *CallHandler) SetMonitorMode(sessionID int64, mode string) error {
+	if !IsValidMonitorMode(mode) {
+		return ErrInvalidMonitorMode
+	}
+
+	h.mu.Lock()
+	session, ok := h.monitors[sessionID]
+	if !ok {
+		h.mu.Unlock()
+		return ErrMonitorNotFound
+	}
+	if session.mode == mode {
+		h.mu.Unlock()
+		return nil
+	}
+	if !session.answered {
+		// Applied when the supervisor answers
+		session.mode = mode
+		h.mu.Unlock()
+		return nil
+	}
+	targetID := session.targetID
+	h.mu.Unlock()
+
+	spy, whisper := snoopDirections(mode)
+	snoop, err := h.client.SnoopChannel(targetID, spy, whisper, fmt.Sprintf("%s,%d", appArgSnoop, sessionID))
+	if err != nil {
+		return fmt.Errorf("failed to switch monitor mode: %w", err)
+	}
+
+	h.mu.Lock()
+	if _, ok := h.monitors[sessionID]; !ok {
+		h.mu.Unlock()
+		h.client.HangupChannel(snoop.ID)
+		return ErrMonitorNotFound
+	}
+	oldSnoopID := session.snoopID
+	session.snoopID = snoop.ID
+	session.mode = mode
+	h.monitorLegs[snoop.ID] = sessionID
+	delete(h.monitorLegs, oldSnoopID)
+	h.mu.Unlock()
+
+	if oldSnoopID != "" {
+		h.client.HangupChannel(oldSnoopID)
+	}
+
+	log.Printf("Monitor session %d switched to %s", sessionID, mode)
+	return nil
+}
+
+// StopMonitor ends a monitor session and hangs up the supervisor's phone. The
+// listener is not notified: the caller records why the session was stopped.
+func (h *CallHandler) StopMonitor(sessionID int64) error {
+	session := h.takeMonitor(sessionID)
+	if session == nil {
+		return ErrMonitorNotFound
+	}
+
+	h.teardownMonitor(session, "")
+	return nil
+}
+
+// onMonitorAnswered bridges the supervisor with a snoop channel on the agent leg
+func (h *CallHandler) onMonitorAnswered(supervisor *Channel, args []string) {
+	sessionID := monitorSessionArg(args)
+
+	h.mu.Lock()
+	session, ok := h.monitors[sessionID]
+	if ok {
+		session.supervisorID = supervisor.ID
+		h.monitorLegs[supervisor.ID] = sessionID
+	}
+	h.mu.Unlock()
+
+	if !ok {
+		log.Printf("Supervisor leg %s answered an unknown monitor session %v", supervisor.ID, args)
+		h.client.HangupChannel(supervisor.ID)
+		return
+	}
+
+	if err := h.connectMonitor(session, supervisor.ID); err != nil {
+		log.Printf("Monitor session %d: %v", sessionID, err)
+		if session = h.takeMonitor(sessionID); session != nil {
+			h.teardownMonitor(session, "")
+			h.notifyMonitor(sessionID, MonitorStatusEnded, MonitorEndFailed)
+		}
+		return
+	}
+
+	h.notifyMonitor(sessionID, MonitorStatusActive, "")
+}
+
+// connectMonitor puts the supervisor in a bridge and snoops on the agent leg into it
+func (h *CallHandler) connectMonitor(session *monitorSession, supervisorID string) error {
+	bridge, err := h.client.CreateBridge("mixing")
+	if err != nil {
+		return fmt.Errorf("failed to create monitor bridge: %w", err)
+	}
+
+	h.mu.Lock()
+	session.bridgeID = bridge.ID
+	session.answered = true
+	mode := session.mode
+	targetID := session.targetID
+	h.mu.Unlock()
+
+	if err := h.client.AddChannelToBridge(bridge.ID, supervisorID); err != nil {
+		return fmt.Errorf("failed to bridge supervisor: %w", err)
+	}
+
+	spy, whisper := snoopDirections(mode)
+	snoop, err := h.client.SnoopChannel(targetID, spy, whisper, fmt.Sprintf("%s,%d", appArgSnoop, session.id))
+	if err != nil {
+		return err
+	}
+
+	h.mu.Lock()
+	switched := session.snoopID != ""
+	if !switched {
+		session.snoopID = snoop.ID
+		h.monitorLegs[snoop.ID] = session.id
+	}
+	h.mu.Unlock()
+
+	// The mode was switched while this snoop was being created
+	if switched {
+		h.client.HangupChannel(snoop.ID)
+	}
+
+	return nil
+}
+
+// onSnoopStarted adds a snoop channel to its monitor session's bridge
+func (h *CallHandler) onSnoopStarted(snoop *Channel, args []string) {
+	sessionID := monitorSessionArg(args)
+
+	h.mu.RLock()
+	session, ok := h.monitors[sessionID]
+	bridgeID := ""
+	if ok {
+		bridgeID = session.bridgeID
+	}
+	h.mu.RUnlock()
+
+	if !ok || bridgeID == "" {
+		h.client.HangupChannel(snoop.ID)
+		return
+	}
+
+	if err := h.client.AddChannelToBridge(bridgeID, snoop.ID); err != nil {
+		log.Printf("Monitor session %d: failed to bridge snoop %s: %v", sessionID, snoop.ID, err)
+		h.client.HangupChannel(snoop.ID)
+	}
+}
+
+// endMonitorLeg ends the monitor session a channel belongs to, whether it is
+// the supervisor, the snoop channel or the monitored agent leg
+func (h *CallHandler) endMonitorLeg(channelID string) {
+	h.mu.Lock()
+	var sessions []*monitorSession
+	if sessionID, ok := h.monitorLegs[channelID]; ok {
+		if session, ok := h.monitors[sessionID]; ok {
+			sessions = append(sessions, session)
+		}
+	}
+	for _, session := range h.monitors {
+		if session.targetID == channelID {
+			sessions = append(sessions, session)
+		}
+	}
+	h.mu.Unlock()
+
+	for _, s := range sessions {
+		session := h.takeMonitor(s.id)
+		if session == nil {
+			continue
+		}
+
+		reason := MonitorEndCallEnded
+		if channelID == session.supervisorID {
+			reason = MonitorEndSupervisorLeft
+			if !session.answered {
+				reason = MonitorEndNoAnswer
+			}
+		}
+
+		h.teardownMonitor(session, channelID)
+		h.notifyMonitor(session.id, MonitorStatusEnded, reason)
+	}
+}
+
+// resyncMonitors ends the monitor sessions whose supervisor, snoop or agent
+// leg disappeared while ARI was disconnected
+func (h *CallHandler) resyncMonitors(channels map[string]bool) {
+	h.mu.Lock()
+	var gone []string
+	for _, session := range h.monitors {
+		for _, channelID := range []string{session.targetID, session.supervisorID, session.snoopID} {
+			if channelID != "" && !channels[channelID] {
+				gone = append(gone, channelID)
+				break
+			}
+		}
+	}
+	h.mu.Unlock()
+
+	for _, channelID := range gone {
+		h.endMonitorLeg(channelID)
+	}
+}
+
+// takeMonitor removes a monitor session so that only one caller tears it down
+func (h *CallHandler) takeMonitor(sessionID int64) *monitorSession {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	session, ok := h.monitors[sessionID]
+	if !ok {
+		return nil
+	}
+	delete(h.monitors, sessionID)
+	delete(h.monitorLegs, session.supervisorID)
+	delete(h.monitorLegs, session.snoopID)
+	return session
+}
+
+// teardownMonitor hangs up a session's supervisor and snoop legs, except the one already gone
+func (h *CallHandler) teardownMonitor(session *monitorSession, goneID string) {
+	for _, id := range []string{session.snoopID, session.supervisorID} {
+		if id != "" && id != goneID {
+			h.client.HangupChannel(id)
+		}
+	}
+	if session.bridgeID != "" {
+		h.client.DestroyBridge(session.bridgeID)
+	}
+}
+
+// notifyMonitor passes a monitor session state change to the listener
+func (h *CallHandler) notifyMonitor(sessionID int64, status, reason string) {
+	if h.monitorListener != nil {
+		go h.monitorListener(sessionID, status, reason)
+	}
+}
+
+// monitorSessionArg parses the session ID from monitor or snoop application arguments
+func monitorSessionArg(args []string) int64 {
+	if len(args) < 2 {
+		return 0
+	}
+	id, _ := strconv.ParseInt(args[1], 10, 64)
+	return id
+}
@@ -0,0 +1,297 @@
+package asterisk
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/psschand/callcenter/internal/common"
+)
+
+// Stasis application argument marking the agent leg of a click-to-call
+const appArgOriginate = "originate"
+
+// Outbound call states
+const (
+	OutboundStateRingingAgent = "ringing_agent"
+	OutboundStateDialing      = "dialing"
+	OutboundStateAnswered     = "answered"
+	OutboundStateEnded        = "ended"
+)
+
+// OriginateRequest describes a click-to-call: the agent's endpoint is rung
+// first and the destination is dialed through the trunk once the agent answers
+type OriginateRequest struct {
+	TenantID      string
+	UserID        int64
+	AgentEndpoint string
+	Destination   string
+	CallerID      string
+	DIDID         int64
+	Timeout       int
+}
+
+// OutboundCall tracks a click-to-call
+type OutboundCall struct {
+	ID             string                 `json:"id"`
+	TenantID       string                 `json:"tenant_id"`
+	UserID         int64                  `json:"user_id"`
+	AgentEndpoint  string                 `json:"agent_endpoint"`
+	Destination    string                 `json:"destination"`
+	CallerID       string                 `json:"caller_id"`
+	DIDID          int64                  `json:"did_id"`
+	AgentChannelID string                 `json:"agent_channel_id"`
+	AgentChannel   string                 `json:"agent_channel"`
+	DestChannelID  string                 `json:"dest_channel_id,omitempty"`
+	DestChannel    string                 `json:"dest_channel,omitempty"`
+	DialString     string                 `json:"dial_string,omitempty"`
+	State          string                 `json:"state"`
+	Disposition    common.CallDisposition `json:"disposition,omitempty"`
+	CreatedAt      time.Time              `json:"created_at"`
+	AnsweredAt     *time.Time             `json:"answered_at,omitempty"`
+	EndedAt        *time.Time             `json:"ended_at,omitempty"`
+}
+
+// Number of outbound call state changes that can wait for the listener
+const outboundEventBuffer = 256
+
+// OutboundCallListener is notified each time an outbound call changes state
+type OutboundCallListener func(call OutboundCall)
+
+// SetOutboundCallListener sets the listener notified of outbound call state
+// changes. Changes are delivered one at a time, in the order they are made,
+// so that a call's end is never seen before its start.
+func (h *CallHandler) SetOutboundCallListener(listener OutboundCallListener) {
+	events := make(chan OutboundCall, outboundEventBuffer)
+	h.mu.Lock()
+	h.outboundEvents = events
+	h.mu.Unlock()
+
+	go func() {
+		for call := range events {
+			listener(call)
+		}
+	}()
+}
+
+// Originate starts a click-to-call by ringing the agent's endpoint
+func (h *CallHandler) Originate(req OriginateRequest) (*OutboundCall, error) {
+	if h.routing.TrunkEndpoint == "" && h.outboundRoutes == nil {
+		return nil, fmt.Errorf("no trunk configured for outbound calls")
+	}
+
+	endpoint := req.AgentEndpoint
+	if !strings.Contains(endpoint, "/") {
+		endpoint = "PJSIP/" + endpoint
+	}
+
+	call := &OutboundCall{
+		ID:            uuid.New().String(),
+		TenantID:      req.TenantID,
+		UserID:        req.UserID,
+		AgentEndpoint: req.AgentEndpoint,
+		Destination:   req.Destination,
+		CallerID:      req.CallerID,
+		DIDID:         req.DIDID,
+		State:         OutboundStateRingingAgent,
+		CreatedAt:     time.Now(),
+	}
+
+	h.mu.Lock()
+	h.outboundCalls[call.ID] = call
+	h.mu.Unlock()
+
+	timeout := req.Timeout
+	if timeout == 0 {
+		timeout = h.routing.DialTimeout
+	}
+
+	// The agent's phone shows the number being called
+	agent, err := h.client.OriginateChannel(endpoint, req.Destination, timeout,
+		fmt.Sprintf("%s,%s", appArgOriginate, call.ID))
+	if err != nil {
+		h.mu.Lock()
+		delete(h.outboundCalls, call.ID)
+		h.mu.Unlock()
+		return nil, fmt.Errorf("failed to ring %s: %w", endpoint, err)
+	}
+
+	h.mu.Lock()
+	call.AgentChannelID = agent.ID
+	call.AgentChannel = agent.Name
+	h.outboundLegs[agent.ID] = call.ID
+	snapshot := *call
+	h.notifyOutboundLocked(snapshot)
+	h.mu.Unlock()
+
+	log.Printf("Click-to-call %s: ringing %s for %s", call.ID, endpoint, req.Destination)
+	return &snapshot, nil
+}
+
+// GetOutboundCall returns an outbound call that is still in progress
+func (h *CallHandler) GetOutboundCall(callID string) (*OutboundCall, bool) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	call, ok := h.outboundCalls[callID]
+	if !ok {
+		return nil, false
+	}
+	snapshot := *call
+	return &snapshot, true
+}
+
+// onOriginateAgentAnswered dials the destination once the agent picks up
+func (h *CallHandler) onOriginateAgentAnswered(agent *Channel, args []string) {
+	if len(args) < 2 {
+		return
+	}
+
+	h.mu.Lock()
+	call, ok := h.outboundCalls[args[1]]
+	if ok {
+		call.State = OutboundStateDialing
+	}
+	h.mu.Unlock()
+
+	if !ok {
+		log.Printf("Agent leg %s answered an unknown click-to-call %s", agent.ID, args[1])
+		h.client.HangupChannel(agent.ID)
+		return
+	}
+
+	dialStrings, err := h.trunkDialStrings(call.TenantID, call.Destination)
+	var dest *Channel
+	var dialString string
+	if err == nil {
+		dest, dialString, err = h.dialTrunks(agent.ID, dialStrings, call.CallerID)
+	}
+	if err != nil {
+		log.Printf("Click-to-call %s: %v", call.ID, err)
+		h.mu.Lock()
+		call.Disposition = common.CallDispositionFailed
+		h.mu.Unlock()
+		h.client.HangupChannel(agent.ID)
+		return
+	}
+
+	h.mu.Lock()
+	call.DialString = dialString
+	if call.DestChannelID == "" {
+		call.DestChannelID = dest.ID
+		call.DestChannel = dest.Name
+	}
+	h.outboundLegs[dest.ID] = call.ID
+	h.notifyOutboundLocked(*call)
+	h.mu.Unlock()
+}
+
+// onOutboundDestAnswered marks a click-to-call answered when its destination leg enters Stasis
+func (h *CallHandler) onOutboundDestAnswered(dest *Channel, agentChannelID string) {
+	h.mu.Lock()
+	callID, ok := h.outboundLegs[agentChannelID]
+	call := h.outboundCalls[callID]
+	if !ok || call == nil {
+		h.mu.Unlock()
+		return
+	}
+
+	now := time.Now()
+	call.State = OutboundStateAnswered
+	call.AnsweredAt = &now
+	call.DestChannelID = dest.ID
+	call.DestChannel = dest.Name
+	h.outboundLegs[dest.ID] = call.ID
+	h.notifyOutboundLocked(*call)
+	h.mu.Unlock()
+}
+
+// onOutboundDestRedialed moves a click-to-call onto the destination leg
+// dialed through the next trunk after its previous leg failed
+func (h *CallHandler) onOutboundDestRedialed(agentChannelID, failedID string, dest *Channel, dialString string) {
+	h.mu.Lock()
+	callID, ok := h.outboundLegs[agentChannelID]
+	call := h.outboundCalls[callID]
+	if !ok || call == nil {
+		h.mu.Unlock()
+		return
+	}
+
+	delete(h.outboundLegs, failedID)
+	call.DestChannelID = dest.ID
+	call.DestChannel = dest.Name
+	call.DialString = dialString
+	call.Disposition = ""
+	h.outboundLegs[dest.ID] = call.ID
+	h.notifyOutboundLocked(*call)
+	h.mu.Unlock()
+}
+
+// onOutboundLegDestroyed records why a destination leg failed and ends the call with its agent leg
+func (h *CallHandler) onOutboundLegDestroyed(channelID string, cause int) {
+	h.mu.Lock()
+	callID, ok := h.outboundLegs[channelID]
+	call := h.outboundCalls[callID]
+	if !ok || call == nil {
+		h.mu.Unlock()
+		return
+	}
+	delete(h.outboundLegs, channelID)
+
+	if channelID != call.AgentChannelID {
+		if call.AnsweredAt == nil && call.Disposition == "" {
+			call.Disposition = dispositionFromCause(cause)
+		}
+		h.mu.Unlock()
+		return
+	}
+
+	// The agent leg carries the call: once it is gone the call is over
+	now := time.Now()
+	call.State = OutboundStateEnded
+	call.EndedAt = &now
+	switch {
+	case call.AnsweredAt != nil:
+		call.Disposition = common.CallDispositionAnswered
+	case call.Disposition == "":
+		call.Disposition = common.CallDispositionNoAnswer
+	}
+	delete(h.outboundCalls, call.ID)
+	if call.DestChannelID != "" {
+		delete(h.outboundLegs, call.DestChannelID)
+	}
+	h.notifyOutboundLocked(*call)
+	h.mu.Unlock()
+
+	log.Printf("Click-to-call %s ended (%s)", call.ID, call.Disposition)
+}
+
+// notifyOutboundLocked queues an outbound call state change for the
+// listener. It is called with h.mu held so that changes are queued in the
+// order they are made.
+func (h *CallHandler) notifyOutboundLocked(call OutboundCall) {
+	if h.outboundEvents == nil {
+		return
+	}
+	select {
+	case h.outboundEvents <- call:
+	default:
+		log.Printf("Outbound call listener is backed up, dropping %s state of click-to-call %s", call.State, call.ID)
+	}
+}
+
+// dispositionFromCause maps the Q.850 hangup cause of a leg that never answered to a CDR disposition
+func dispositionFromCause(cause int) common.CallDisposition {
+	switch cause {
+	case 17:
+		return common.CallDispositionBusy
+	case 16, 18, 19, 21:
+		return common.CallDispositionNoAnswer
+	case 34, 38, 41, 42:
+		return common.CallDispositionCongested
+	default:
+		return common.CallDispositionFailed
+	}
+}
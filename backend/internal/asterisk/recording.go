@@ -0,0 +1,158 @@
+package asterisk
+
+import (
+	"fmt"
+	"log"
+	"strings"
+)
+
+// Queue recording policies
+const (
+	QueueRecordingInherit = "inherit" // follow the tenant's call recording setting
+	QueueRecordingAlways  = "always"
+	QueueRecordingNever   = "never"
+)
+
+// Format calls are recorded in
+const recordingFormat = "wav"
+
+// RecordingPolicy decides whether a bridged call is recorded; queueName is
+// empty unless the call was answered from a queue
+type RecordingPolicy func(tenantID, queueName string) bool
+
+// RecordingEvent reports a call recording starting, finishing or failing
+type RecordingEvent struct {
+	Name      string
+	Format    string
+	TenantID  string
+	UniqueID  string // channel the call is identified by
+	QueueName string
+	State     string // RecordingStateRecording, RecordingStateDone or RecordingStateFailed
+	Duration  int
+	Cause     string
+}
+
+// RecordingListener is notified of call recording state changes
+type RecordingListener func(event RecordingEvent)
+
+// callRecording is a call recording in progress. A call that is bridged again
+// after a transfer appends to the same recording, so it is only over once all
+// of its bridge recordings have finished.
+type callRecording struct {
+	tenantID  string
+	uniqueID  string
+	queueName string
+	active    int
+}
+
+// SetRecordingPolicy sets the policy deciding which calls are recorded
+func (h *CallHandler) SetRecordingPolicy(policy RecordingPolicy) {
+	h.recordingPolicy = policy
+}
+
+// SetRecordingListener sets the listener notified of call recording state changes
+func (h *CallHandler) SetRecordingListener(listener RecordingListener) {
+	h.recordingListener = listener
+}
+
+// RecordingName returns the name a call's recording is stored under
+func RecordingName(tenantID, uniqueID string) string {
+	return fmt.Sprintf("%s-%s", tenantID, strings.ReplaceAll(uniqueID, ".", "-"))
+}
+
+// startCallRecording records a bridged call if the recording policy asks for it
+func (h *CallHandler) startCallRecording(channelID, peerID, bridgeID string) {
+	if h.recordingPolicy == nil {
+		return
+	}
+
+	h.mu.RLock()
+	tenantID, ok := h.channelTenantLocked(channelID)
+	queueName := ""
+	for _, id := range []string{channelID, peerID} {
+		if call, found := h.calls[id]; found && call.Queue != "" {
+			queueName = call.Queue
+		}
+	}
+	h.mu.RUnlock()
+
+	if !ok || !h.recordingPolicy(tenantID, queueName) {
+		return
+	}
+
+	event := RecordingEvent{
+		Name:      RecordingName(tenantID, channelID),
+		Format:    recordingFormat,
+		TenantID:  tenantID,
+		UniqueID:  channelID,
+		QueueName: queueName,
+		State:     RecordingStateRecording,
+	}
+
+	if _, err := h.client.RecordBridge(bridgeID, event.Name, event.Format); err != nil {
+		log.Printf("Error recording bridge %s for %s: %v", bridgeID, channelID, err)
+		event.State = RecordingStateFailed
+		event.Cause = err.Error()
+		h.notifyRecording(event)
+		return
+	}
+
+	h.mu.Lock()
+	rec, ok := h.recordings[event.Name]
+	if !ok {
+		rec = &callRecording{tenantID: tenantID, uniqueID: channelID, queueName: queueName}
+		h.recordings[event.Name] = rec
+	}
+	rec.active++
+	h.mu.Unlock()
+
+	log.Printf("Recording call %s as %s", channelID, event.Name)
+	h.notifyRecording(event)
+}
+
+// onRecordingEnded reports a call recording or voicemail once Asterisk has finished writing it
+func (h *CallHandler) onRecordingEnded(event ARIEvent) {
+	if event.Recording == nil || h.onVoicemailRecorded(event) {
+		return
+	}
+	recording := event.Recording
+
+	h.mu.Lock()
+	rec, ok := h.recordings[recording.Name]
+	if !ok {
+		// Not a call recording
+		h.mu.Unlock()
+		return
+	}
+	rec.active--
+	failed := event.Type == EventRecordingFailed
+	if rec.active > 0 && !failed {
+		h.mu.Unlock()
+		return
+	}
+	delete(h.recordings, recording.Name)
+	h.mu.Unlock()
+
+	state := RecordingStateDone
+	if failed {
+		state = RecordingStateFailed
+	}
+
+	h.notifyRecording(RecordingEvent{
+		Name:      recording.Name,
+		Format:    recording.Format,
+		TenantID:  rec.tenantID,
+		UniqueID:  rec.uniqueID,
+		QueueName: rec.queueName,
+		State:     state,
+		Duration:  recording.Duration,
+		Cause:     recording.Cause,
+	})
+}
+
+// notifyRecording passes a call recording state change to the listener
+func (h *CallHandler) notifyRecording(event RecordingEvent) {
+	if h.recordingListener != nil {
+		go h.recordingListener(event)
+	}
+}
@@ -0,0 +1,605 @@
+package asterisk
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/psschand/callcenter/internal/common"
+)
+
+// Stasis application argument marking an originated leg that should be bridged to its caller
+const appArgDialed = "dialed"
+
+// Channel variables set on routed inbound calls
+const (
+	VarTenantID = "CALLCENTER_TENANT_ID"
+	VarDIDID    = "CALLCENTER_DID_ID"
+	VarRoute    = "CALLCENTER_ROUTE"
+)
+
+// Rejection treatments for calls that cannot be routed
+const (
+	RejectTreatmentAnnounce   = "announce"
+	RejectTreatmentBusy       = "busy"
+	RejectTreatmentCongestion = "congestion"
+	RejectTreatmentHangup     = "hangup"
+)
+
+// DIDResolver looks up the DID a call was placed to.
+// repository.DIDRepository satisfies this interface.
+type DIDResolver interface {
+	FindByNumber(ctx context.Context, number string) (*DID, error)
+}
+
+// RouteHandler executes a route for an inbound channel
+type RouteHandler func(channel *Channel, did *DID, target string) error
+
+// RoutingConfig holds inbound call routing settings
+type RoutingConfig struct {
+	TrunkEndpoint        string
+	DialTimeout          int
+	UnknownDIDTreatment  string
+	InactiveDIDTreatment string
+	RejectSound          string
+}
+
+// DefaultRoutingConfig returns the routing settings used when none are configured
+func DefaultRoutingConfig() RoutingConfig {
+	return RoutingConfig{
+		TrunkEndpoint:        "twilio_trunk",
+		DialTimeout:          30,
+		UnknownDIDTreatment:  RejectTreatmentAnnounce,
+		InactiveDIDTreatment: RejectTreatmentAnnounce,
+		RejectSound:          "ss-noservice",
+	}
+}
+
+// Call holds the routing context of an inbound call
+type Call struct {
+	ChannelID    string
+	TenantID     string
+	DID          *DID
+	CallerNumber string
+	CallerName   string
+	Queue        string // queue the call was answered from
+	StartedAt    time.Time
+
+	SurveyOffered bool        // the caller has been asked to take a survey
+	Survey        *CallSurvey // survey the caller agreed to take once the agent hangs up
+}
+
+// SetDIDResolver sets the DID lookup used for inbound routing
+func (h *CallHandler) SetDIDResolver(resolver DIDResolver) {
+	h.dids = resolver
+}
+
+// SetRoutingConfig sets inbound routing settings
+func (h *CallHandler) SetRoutingConfig(cfg RoutingConfig) {
+	h.routing = cfg
+}
+
+// RegisterRouteHandler registers (or replaces) the handler for a route type
+func (h *CallHandler) RegisterRouteHandler(routeType common.RouteType, handler RouteHandler) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.routeHandlers[routeType] = handler
+}
+
+// GetCall returns the routing context of an inbound call
+func (h *CallHandler) GetCall(channelID string) (*Call, bool) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	call, ok := h.calls[channelID]
+	return call, ok
+}
+
+// registerDefaultRouteHandlers installs the built-in route handlers. Queue,
+// IVR and voicemail routes are registered by their engines once configured;
+// until then RouteCall refuses them and the call is rejected.
+func (h *CallHandler) registerDefaultRouteHandlers() {
+	h.routeHandlers[common.RouteTypeEndpoint] = h.routeToEndpoint
+	h.routeHandlers[common.RouteTypeExternal] = h.routeToExternal
+	h.routeHandlers[common.RouteTypeWebhook] = h.routeToWebhook
+}
+
+// routeInboundCall looks up the dialed DID and executes its route
+func (h *CallHandler) routeInboundCall(channel *Channel, args []string) {
+	number := dialedNumber(channel, args)
+	log.Printf("Routing inbound call %s from %s to %s", channel.ID, channel.Caller.Number, number)
+
+	if h.dids == nil {
+		log.Printf("No DID resolver configured, rejecting call %s", channel.ID)
+		h.rejectCall(channel.ID, h.routing.UnknownDIDTreatment)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	did := h.lookupDID(ctx, number)
+	if did == nil {
+		log.Printf("Unknown DID %s for call %s", number, channel.ID)
+		h.rejectCall(channel.ID, h.routing.UnknownDIDTreatment)
+		return
+	}
+
+	if !did.IsActive() {
+		log.Printf("DID %s is %s, rejecting call %s", did.Number, did.Status, channel.ID)
+		h.rejectCall(channel.ID, h.routing.InactiveDIDTreatment)
+		return
+	}
+
+	// Blocked callers sent to voicemail are otherwise handled like any call
+	blocked := h.screenCaller(channel, did)
+	if blocked != nil && blocked.Treatment != common.BlockTreatmentVoicemail {
+		h.blockCall(channel.ID, blocked)
+		return
+	}
+
+	h.mu.Lock()
+	call := &Call{
+		ChannelID:    channel.ID,
+		TenantID:     did.TenantID,
+		DID:          did,
+		CallerNumber: channel.Caller.Number,
+		CallerName:   channel.Caller.Name,
+		StartedAt:    time.Now(),
+	}
+	h.calls[channel.ID] = call
+	h.startCallDetailLocked(channel, call, channel.ID, 0)
+	h.mu.Unlock()
+	h.noteCallStarted(channel.ID)
+
+	h.client.SetChannelVariable(channel.ID, VarTenantID, did.TenantID)
+	h.client.SetChannelVariable(channel.ID, VarDIDID, strconv.FormatInt(did.ID, 10))
+
+	// Callers only reach an agent, and so a survey, on the regular route
+	routeType, target, surveyID := did.RouteType, did.RouteTarget, did.SurveyID
+	if did.AfterHoursRouteType != nil && *did.AfterHoursRouteType != "" && !h.scheduleOpen(did.TenantID, did.ScheduleID) {
+		routeType, target, surveyID = *did.AfterHoursRouteType, "", nil
+		if did.AfterHoursRouteTarget != nil {
+			target = *did.AfterHoursRouteTarget
+		}
+		log.Printf("DID %s is closed, sending call %s to its after-hours route", did.Number, channel.ID)
+	}
+	if blocked != nil {
+		routeType, target, surveyID = common.RouteTypeVoicemail, "", nil
+		if blocked.TreatmentTarget != nil {
+			target = *blocked.TreatmentTarget
+		}
+		log.Printf("Sending call %s matching blacklist entry %d to voicemail", channel.ID, blocked.ID)
+	}
+
+	route := func() error {
+		return h.RouteCall(channel, did, routeType, target)
+	}
+	if h.offerSurvey(channel, did.TenantID, surveyID, route) {
+		return
+	}
+	if err := route(); err != nil {
+		log.Printf("Error routing call %s (%s -> %s): %v", channel.ID, routeType, target, err)
+		h.rejectCall(channel.ID, RejectTreatmentCongestion)
+	}
+}
+
+// RouteCall executes a route for a channel
+func (h *CallHandler) RouteCall(channel *Channel, did *DID, routeType common.RouteType, target string) error {
+	h.mu.RLock()
+	handler, ok := h.routeHandlers[routeType]
+	h.mu.RUnlock()
+	if !ok {
+		return fmt.Errorf("unsupported route type: %s", routeType)
+	}
+
+	h.client.SetChannelVariable(channel.ID, VarRoute, fmt.Sprintf("%s:%s", routeType, target))
+	h.noteCallRoute(channel.ID, routeType, target)
+	log.Printf("Routing channel %s to %s %s", channel.ID, routeType, target)
+
+	return handler(channel, did, target)
+}
+
+// lookupDID finds the DID for a dialed number, tolerating a missing "+" prefix
+func (h *CallHandler) lookupDID(ctx context.Context, number string) *DID {
+	if number == "" {
+		return nil
+	}
+
+	candidates := []string{number}
+	if strings.HasPrefix(number, "+") {
+		candidates = append(candidates, strings.TrimPrefix(number, "+"))
+	} else {
+		candidates = append(candidates, "+"+number)
+	}
+
+	for _, candidate := range candidates {
+		if did, err := h.dids.FindByNumber(ctx, candidate); err == nil && did != nil {
+			return did
+		}
+	}
+	return nil
+}
+
+// dialedNumber extracts the dialed number from the Stasis arguments or the dialplan extension.
+// The dialplan passes it as Stasis(app,incoming,${EXTEN}).
+func dialedNumber(channel *Channel, args []string) string {
+	if len(args) >= 2 && args[0] == "incoming" && args[1] != "" {
+		return args[1]
+	}
+	return channel.Dialplan.Exten
+}
+
+// routeToEndpoint rings an internal endpoint (PJSIP/<target> unless a technology is given)
+func (h *CallHandler) routeToEndpoint(channel *Channel, did *DID, target string) error {
+	endpoint := target
+	if !strings.Contains(endpoint, "/") {
+		endpoint = "PJSIP/" + endpoint
+	}
+
+	callerID := channel.Caller.Number
+	if channel.Caller.Name != "" {
+		callerID = fmt.Sprintf("\"%s\" <%s>", channel.Caller.Name, channel.Caller.Number)
+	}
+
+	if h.mailboxes != nil && strings.HasPrefix(endpoint, "PJSIP/") {
+		h.mu.Lock()
+		h.voicemailFallbacks[channel.ID] = strings.TrimPrefix(endpoint, "PJSIP/")
+		h.mu.Unlock()
+	}
+
+	return h.DialAndBridge(channel.ID, endpoint, callerID)
+}
+
+// routeToExternal forwards the call to an external number through the
+// trunks of the tenant's matching outbound route
+func (h *CallHandler) routeToExternal(channel *Channel, did *DID, target string) error {
+	dialStrings, err := h.trunkDialStrings(did.TenantID, target)
+	if err != nil {
+		return err
+	}
+
+	_, _, err = h.dialTrunks(channel.ID, dialStrings, did.Number)
+	return err
+}
+
+// ErrWebhookAddressBlocked is returned for routing webhooks that point at a
+// loopback, private or link-local address
+var ErrWebhookAddressBlocked = errors.New("webhook URL must not point at a loopback, private or link-local address")
+
+// Address ranges routing webhooks may not reach beyond those netip classifies
+var webhookBlockedPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),
+	netip.MustParsePrefix("100.64.0.0/10"),
+	netip.MustParsePrefix("198.18.0.0/15"),
+}
+
+// webhookClient posts to routing webhooks. Every connection, including those
+// of redirects, is checked after DNS resolution so that a public hostname
+// cannot be pointed at the API's own network.
+var webhookClient = &http.Client{
+	Timeout: 5 * time.Second,
+	Transport: &http.Transport{
+		DialContext: (&net.Dialer{
+			Timeout: 5 * time.Second,
+			Control: webhookDialControl,
+		}).DialContext,
+		TLSHandshakeTimeout: 5 * time.Second,
+	},
+}
+
+// ValidateWebhookURL checks a routing webhook is an absolute http or https URL
+// that does not name a loopback, private or link-local host
+func ValidateWebhookURL(raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Hostname() == "" {
+		return errors.New("webhook URL must be an absolute http:// or https:// URL")
+	}
+
+	host := strings.ToLower(strings.TrimSuffix(u.Hostname(), "."))
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return ErrWebhookAddressBlocked
+	}
+	if addr, err := netip.ParseAddr(host); err == nil && !isPublicAddr(addr) {
+		return ErrWebhookAddressBlocked
+	}
+	return nil
+}
+
+// webhookDialControl refuses connections to addresses that are not public
+func webhookDialControl(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	addr, err := netip.ParseAddr(host)
+	if err != nil || !isPublicAddr(addr) {
+		return ErrWebhookAddressBlocked
+	}
+	return nil
+}
+
+// isPublicAddr reports whether addr is a globally routable unicast address
+func isPublicAddr(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	if !addr.IsGlobalUnicast() || addr.IsPrivate() {
+		return false
+	}
+	for _, prefix := range webhookBlockedPrefixes {
+		if prefix.Contains(addr) {
+			return false
+		}
+	}
+	return true
+}
+
+// webhookRouteRequest is posted to a DID's webhook to ask for a route
+type webhookRouteRequest struct {
+	ChannelID    string `json:"channel_id"`
+	TenantID     string `json:"tenant_id"`
+	DIDID        int64  `json:"did_id"`
+	DIDNumber    string `json:"did_number"`
+	CallerNumber string `json:"caller_number"`
+	CallerName   string `json:"caller_name"`
+}
+
+// webhookRouteResponse is the route returned by a DID's webhook
+type webhookRouteResponse struct {
+	RouteType   common.RouteType `json:"route_type"`
+	RouteTarget string           `json:"route_target"`
+}
+
+// routeToWebhook asks an external service where the call should go and executes that route
+func (h *CallHandler) routeToWebhook(channel *Channel, did *DID, target string) error {
+	body, err := json.Marshal(&webhookRouteRequest{
+		ChannelID:    channel.ID,
+		TenantID:     did.TenantID,
+		DIDID:        did.ID,
+		DIDNumber:    did.Number,
+		CallerNumber: channel.Caller.Number,
+		CallerName:   channel.Caller.Name,
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := ValidateWebhookURL(target); err != nil {
+		return err
+	}
+	resp, err := webhookClient.Post(target, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("routing webhook failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("routing webhook returned %s", resp.Status)
+	}
+
+	var route webhookRouteResponse
+	if err := json.NewDecoder(resp.Body).Decode(&route); err != nil {
+		return fmt.Errorf("invalid routing webhook response: %w", err)
+	}
+
+	if route.RouteType == common.RouteTypeWebhook || route.RouteType == "" {
+		return fmt.Errorf("routing webhook returned invalid route type %q", route.RouteType)
+	}
+
+	return h.RouteCall(channel, did, route.RouteType, route.RouteTarget)
+}
+
+// DialAndBridge rings the caller, originates a call to endpoint and bridges both legs once it answers
+func (h *CallHandler) DialAndBridge(channelID, endpoint, callerID string) error {
+	_, err := h.dialAndBridge(channelID, endpoint, callerID)
+	return err
+}
+
+// dialAndBridge is DialAndBridge returning the originated leg
+func (h *CallHandler) dialAndBridge(channelID, endpoint, callerID string) (*Channel, error) {
+	if err := h.client.RingChannel(channelID); err != nil {
+		log.Printf("Error indicating ringing on channel %s: %v", channelID, err)
+	}
+
+	outbound, err := h.client.OriginateChannel(endpoint, callerID, h.routing.DialTimeout,
+		fmt.Sprintf("%s,%s", appArgDialed, channelID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to dial %s: %w", endpoint, err)
+	}
+
+	h.mu.Lock()
+	h.pendingDials[outbound.ID] = channelID
+	h.mu.Unlock()
+
+	log.Printf("Dialing %s for channel %s (outbound %s)", endpoint, channelID, outbound.ID)
+	return outbound, nil
+}
+
+// onDialedAnswered bridges an answered outbound leg with the channel that dialed it
+func (h *CallHandler) onDialedAnswered(outbound *Channel, args []string) {
+	if len(args) < 2 {
+		return
+	}
+	inboundID := args[1]
+
+	h.mu.Lock()
+	delete(h.pendingDials, outbound.ID)
+	_, alive := h.activeChannels[inboundID]
+	h.mu.Unlock()
+
+	if !alive {
+		log.Printf("Caller %s hung up before %s answered", inboundID, outbound.ID)
+		h.client.HangupChannel(outbound.ID)
+		return
+	}
+
+	if err := h.client.AnswerChannel(inboundID); err != nil {
+		log.Printf("Error answering channel %s: %v", inboundID, err)
+	}
+
+	if err := h.bridgeChannels(inboundID, outbound.ID); err != nil {
+		log.Printf("Error bridging %s with %s: %v", inboundID, outbound.ID, err)
+		h.client.HangupChannel(outbound.ID)
+	}
+}
+
+// bridgeChannels joins two legs in a mixing bridge and links them so that
+// either hanging up releases the other
+func (h *CallHandler) bridgeChannels(channelID, peerID string) error {
+	bridge, err := h.client.CreateBridge("mixing")
+	if err != nil {
+		return fmt.Errorf("failed to create bridge: %w", err)
+	}
+
+	for _, id := range []string{channelID, peerID} {
+		if err := h.client.AddChannelToBridge(bridge.ID, id); err != nil {
+			h.client.DestroyBridge(bridge.ID)
+			return fmt.Errorf("failed to add channel %s to bridge %s: %w", id, bridge.ID, err)
+		}
+	}
+
+	h.mu.Lock()
+	h.peers[channelID] = peerID
+	h.peers[peerID] = channelID
+	h.callBridges[channelID] = bridge.ID
+	h.callBridges[peerID] = bridge.ID
+	h.mu.Unlock()
+
+	h.noteCallConnected(channelID, peerID)
+	log.Printf("Bridged %s with %s via bridge %s", channelID, peerID, bridge.ID)
+	go h.startCallRecording(channelID, peerID, bridge.ID)
+	return nil
+}
+
+// onDialFailed handles an outbound leg that was destroyed before it answered:
+// the caller leaves a message if the endpoint has a mailbox, or is hung up
+func (h *CallHandler) onDialFailed(outboundID, inboundID string) {
+	log.Printf("Outbound leg %s for %s was not answered", outboundID, inboundID)
+	if h.leaveVoicemailOnNoAnswer(inboundID) {
+		return
+	}
+	if err := h.client.HangupChannelWithReason(inboundID, "no_answer"); err != nil {
+		log.Printf("Error hanging up channel %s: %v", inboundID, err)
+	}
+}
+
+// releaseCall hangs up the peer of a channel that left Stasis, or starts
+// its survey, and cleans up its bridge
+func (h *CallHandler) releaseCall(channelID string) {
+	h.endCallDetail(channelID)
+	h.endIVRSession(channelID)
+	h.endVoicemailSession(channelID)
+	h.endSurveySession(channelID)
+	h.endTransferLeg(channelID)
+	h.endMonitorLeg(channelID)
+	if h.acd != nil {
+		h.acd.leave(channelID)
+	}
+
+	h.mu.Lock()
+	peerID := h.peers[channelID]
+	bridgeID := h.callBridges[channelID]
+	delete(h.peers, channelID)
+	delete(h.callBridges, channelID)
+	delete(h.calls, channelID)
+	delete(h.held, channelID)
+	if peerID != "" {
+		delete(h.peers, peerID)
+		delete(h.callBridges, peerID)
+	}
+
+	// Cancel any leg still ringing on behalf of this channel
+	var ringing []string
+	for outboundID, inboundID := range h.pendingDials {
+		if inboundID == channelID {
+			ringing = append(ringing, outboundID)
+			delete(h.pendingDials, outboundID)
+		}
+	}
+	h.mu.Unlock()
+
+	for _, id := range ringing {
+		h.client.HangupChannel(id)
+	}
+	// A caller who agreed to a survey stays on to take it
+	if peerID != "" && !h.surveyAfterCall(peerID, bridgeID) {
+		h.client.HangupChannel(peerID)
+	}
+	if bridgeID != "" {
+		h.client.DestroyBridge(bridgeID)
+	}
+}
+
+// rejectCall applies a rejection treatment to a call that cannot be routed
+func (h *CallHandler) rejectCall(channelID, treatment string) {
+	var err error
+	switch treatment {
+	case RejectTreatmentBusy:
+		h.noteCallDisposition(channelID, common.CallDispositionBusy)
+		err = h.client.HangupChannelWithReason(channelID, "busy")
+	case RejectTreatmentCongestion:
+		h.noteCallDisposition(channelID, common.CallDispositionCongested)
+		err = h.client.HangupChannelWithReason(channelID, "congestion")
+	case RejectTreatmentHangup:
+		h.noteCallDisposition(channelID, common.CallDispositionFailed)
+		err = h.client.HangupChannelWithReason(channelID, "normal")
+	default:
+		h.noteCallDisposition(channelID, common.CallDispositionFailed)
+		err = h.announceAndHangup(channelID, h.routing.RejectSound)
+	}
+
+	if err != nil {
+		log.Printf("Error rejecting channel %s: %v", channelID, err)
+		h.client.HangupChannel(channelID)
+	}
+}
+
+// announceAndHangup answers the channel, plays a sound and hangs up when playback finishes
+func (h *CallHandler) announceAndHangup(channelID, sound string) error {
+	if err := h.client.AnswerChannel(channelID); err != nil {
+		return err
+	}
+
+	playback, err := h.client.PlaySound(channelID, sound)
+	if err != nil {
+		return err
+	}
+
+	h.mu.Lock()
+	h.hangupAfterPlayback[playback.ID] = channelID
+	h.mu.Unlock()
+	return nil
+}
+
+// onPlaybackFinished hangs up channels whose closing announcement has finished
+// and advances IVR, survey, queue callback and voicemail prompts
+func (h *CallHandler) onPlaybackFinished(event ARIEvent) {
+	if event.Playback == nil {
+		return
+	}
+
+	h.mu.Lock()
+	channelID, ok := h.hangupAfterPlayback[event.Playback.ID]
+	delete(h.hangupAfterPlayback, event.Playback.ID)
+	h.mu.Unlock()
+
+	if ok {
+		h.client.HangupChannel(channelID)
+		return
+	}
+
+	if !h.onIVRPlaybackFinished(event.Playback.ID) &&
+		!h.onSurveyPlaybackFinished(event.Playback.ID) &&
+		!h.onQueueCallbackPlaybackFinished(event.Playback.ID) {
+		h.onVoicemailPlaybackFinished(event.Playback.ID)
+	}
+}
@@ -0,0 +1,53 @@
+package asterisk
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestValidateWebhookURL(t *testing.T) {
+	cases := []struct {
+		url     string
+		allowed bool
+	}{
+		{"https://hooks.example.com/route", true},
+		{"http://203.0.113.10:8080/route", true},
+		{"https://[2001:db8::1]/route", true},
+		{"ftp://hooks.example.com/route", false},
+		{"hooks.example.com/route", false},
+		{"https:///route", false},
+		{"http://localhost:8001/api", false},
+		{"http://api.localhost/", false},
+		{"http://127.0.0.1:8088/ari", false},
+		{"http://0.0.0.0:3306/", false},
+		{"http://10.0.0.5/", false},
+		{"http://172.20.0.3:8088/", false},
+		{"http://192.168.1.1/", false},
+		{"http://169.254.169.254/latest/meta-data/", false},
+		{"http://100.64.0.1/", false},
+		{"http://[::1]/", false},
+		{"http://[fd00::1]/", false},
+		{"http://[fe80::1]/", false},
+		{"http://[::ffff:127.0.0.1]/", false},
+	}
+	for _, tc := range cases {
+		err := ValidateWebhookURL(tc.url)
+		if tc.allowed && err != nil {
+			t.Errorf("ValidateWebhookURL(%q) = %v, want nil", tc.url, err)
+		}
+		if !tc.allowed && err == nil {
+			t.Errorf("ValidateWebhookURL(%q) = nil, want an error", tc.url)
+		}
+	}
+}
+
+func TestWebhookDialControl(t *testing.T) {
+	for _, address := range []string{"127.0.0.1:80", "10.1.2.3:443", "[::1]:80", "169.254.169.254:80"} {
+		if err := webhookDialControl("tcp", address, nil); !errors.Is(err, ErrWebhookAddressBlocked) {
+			t.Errorf("dialing %s: err = %v, want ErrWebhookAddressBlocked", address, err)
+		}
+	}
+	if err := webhookDialControl("tcp", "203.0.113.10:443", nil); err != nil {
+		t.Errorf("dialing a public address: %v", err)
+	}
+}
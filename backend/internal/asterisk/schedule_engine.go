@@ -0,0 +1,43 @@
+package asterisk
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/psschand/callcenter/internal/core"
+)
+
+// ScheduleLoader looks up business hours schedules.
+// repository.ScheduleRepository satisfies this interface.
+type ScheduleLoader interface {
+	FindByID(ctx context.Context, id int64) (*core.Schedule, error)
+}
+
+// SetScheduleLoader enables business hours routing; DIDs and queues with a
+// schedule send callers to their after-hours route while it is closed
+func (h *CallHandler) SetScheduleLoader(schedules ScheduleLoader) {
+	h.schedules = schedules
+}
+
+// scheduleOpen checks if a tenant's schedule is open now. Calls are let
+// through when there is no schedule or it cannot be used, so a missing or
+// inactive schedule never closes a line.
+func (h *CallHandler) scheduleOpen(tenantID string, scheduleID *int64) bool {
+	if scheduleID == nil || h.schedules == nil {
+		return true
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	schedule, err := h.schedules.FindByID(ctx, *scheduleID)
+	if err != nil {
+		log.Printf("Error loading schedule %d, treating it as open: %v", *scheduleID, err)
+		return true
+	}
+	if schedule.TenantID != tenantID || !schedule.IsActive {
+		return true
+	}
+	return schedule.IsOpen(time.Now())
+}
@@ -49,31 +49,59 @@ func (s *SMSMessage) IsFailed() bool {
 	return s.Status == common.SMSStatusFailed
 }
 
+// smsStatusOrder orders the delivery statuses of outbound messages
+var smsStatusOrder = map[common.SMSStatus]int{
+	common.SMSStatusPending:   0,
+	common.SMSStatusQueued:    1,
+	common.SMSStatusSent:      2,
+	common.SMSStatusDelivered: 3,
+	common.SMSStatusFailed:    3,
+}
+
+// CanTransitionTo checks if an outbound message may move to a delivery
+// status. Statuses only move forward, since providers may report them out
+// of order, and delivered and failed are final.
+func (s *SMSMessage) CanTransitionTo(status common.SMSStatus) bool {
+	if s.Direction != common.SMSDirectionOutbound {
+		return false
+	}
+	current, ok := smsStatusOrder[s.Status]
+	if !ok {
+		return false
+	}
+	next, ok := smsStatusOrder[status]
+	return ok && next > current
+}
+
 // Voicemail represents a voicemail message
 // @Description Voicemail message with audio file
 type Voicemail struct {
-	ID            int64          `gorm:"column:id;primaryKey;autoIncrement" json:"id" example:"1"`
-	TenantID      string         `gorm:"column:tenant_id;type:varchar(64);not null;index:idx_tenant" json:"tenant_id" example:"acme-corp"`
-	DIDID         *int64         `gorm:"column:did_id;index:idx_did" json:"did_id,omitempty" example:"1"`
-	UserID        *int64         `gorm:"column:user_id;index:idx_user" json:"user_id,omitempty" example:"1"`
-	EndpointID    *string        `gorm:"column:endpoint_id;type:varchar(128);index:idx_endpoint" json:"endpoint_id,omitempty" example:"acme-agent1"`
-	CallerID      string         `gorm:"column:callerid;type:varchar(80);not null;index:idx_caller" json:"callerid" example:"+15551234567"`
-	CallerName    *string        `gorm:"column:callername;type:varchar(80)" json:"callername,omitempty" example:"John Doe"`
-	Duration      int            `gorm:"column:duration;default:0" json:"duration" example:"45"`
-	FilePath      string         `gorm:"column:file_path;type:varchar(1024);not null" json:"file_path" example:"/var/spool/asterisk/voicemail/acme-corp/101/INBOX/msg0001.wav"`
-	FileSize      int64          `gorm:"column:file_size;default:0" json:"file_size" example:"180224"`
-	Format        string         `gorm:"column:format;type:varchar(16);default:wav" json:"format" example:"wav"`
-	IsRead        bool           `gorm:"column:is_read;default:false;index:idx_read" json:"is_read" example:"false"`
-	IsDeleted     bool           `gorm:"column:is_deleted;default:false;index:idx_deleted" json:"is_deleted" example:"false"`
-	Transcription *string        `gorm:"column:transcription;type:text" json:"transcription,omitempty"`
-	Metadata      common.JSONMap `gorm:"column:metadata;type:json" json:"metadata,omitempty"`
-	CreatedAt     time.Time      `gorm:"column:created_at;autoCreateTime;index:idx_created" json:"created_at"`
-	ReadAt        *time.Time     `gorm:"column:read_at" json:"read_at,omitempty"`
+	ID             int64          `gorm:"column:id;primaryKey;autoIncrement" json:"id" example:"1"`
+	TenantID       string         `gorm:"column:tenant_id;type:varchar(64);not null;index:idx_tenant" json:"tenant_id" example:"acme-corp"`
+	MailboxID      *int64         `gorm:"column:mailbox_id;index:idx_mailbox" json:"mailbox_id,omitempty" example:"1"`
+	DIDID          *int64         `gorm:"column:did_id;index:idx_did" json:"did_id,omitempty" example:"1"`
+	UserID         *int64         `gorm:"column:user_id;index:idx_user" json:"user_id,omitempty" example:"1"`
+	EndpointID     *string        `gorm:"column:endpoint_id;type:varchar(128);index:idx_endpoint" json:"endpoint_id,omitempty" example:"acme-agent1"`
+	CallerID       string         `gorm:"column:callerid;type:varchar(80);not null;index:idx_caller" json:"callerid" example:"+15551234567"`
+	CallerName     *string        `gorm:"column:callername;type:varchar(80)" json:"callername,omitempty" example:"John Doe"`
+	Duration       int            `gorm:"column:duration;default:0" json:"duration" example:"45"`
+	FilePath       string         `gorm:"column:file_path;type:varchar(1024);not null" json:"file_path" example:"/var/spool/asterisk/voicemail/acme-corp/101/INBOX/msg0001.wav"`
+	StorageBackend *string        `gorm:"column:storage_backend;type:varchar(16)" json:"storage_backend,omitempty" example:"s3"`
+	StorageKey     *string        `gorm:"column:storage_key;type:varchar(1024)" json:"storage_key,omitempty" example:"voicemails/acme-corp/2023/10/25/1.wav"`
+	FileSize       int64          `gorm:"column:file_size;default:0" json:"file_size" example:"180224"`
+	Format         string         `gorm:"column:format;type:varchar(16);default:wav" json:"format" example:"wav"`
+	IsRead         bool           `gorm:"column:is_read;default:false;index:idx_read" json:"is_read" example:"false"`
+	IsDeleted      bool           `gorm:"column:is_deleted;default:false;index:idx_deleted" json:"is_deleted" example:"false"`
+	Transcription  *string        `gorm:"column:transcription;type:text" json:"transcription,omitempty"`
+	Metadata       common.JSONMap `gorm:"column:metadata;type:json" json:"metadata,omitempty"`
+	CreatedAt      time.Time      `gorm:"column:created_at;autoCreateTime;index:idx_created" json:"created_at"`
+	ReadAt         *time.Time     `gorm:"column:read_at" json:"read_at,omitempty"`
 
 	// Relations
-	Tenant *core.Tenant `gorm:"foreignKey:TenantID" json:"tenant,omitempty"`
-	DID    *DID         `gorm:"foreignKey:DIDID" json:"did,omitempty"`
-	User   *core.User   `gorm:"foreignKey:UserID" json:"user,omitempty"`
+	Tenant  *core.Tenant      `gorm:"foreignKey:TenantID" json:"tenant,omitempty"`
+	Mailbox *VoicemailMailbox `gorm:"foreignKey:MailboxID" json:"mailbox,omitempty"`
+	DID     *DID              `gorm:"foreignKey:DIDID" json:"did,omitempty"`
+	User    *core.User        `gorm:"foreignKey:UserID" json:"user,omitempty"`
 }
 
 // TableName specifies the table name
@@ -98,6 +126,54 @@ func (v *Voicemail) GetFileSizeMB() float64 {
 	return float64(v.FileSize) / (1024 * 1024)
 }
 
+// Voicemail mailbox defaults
+const (
+	VoicemailDefaultMaxLength = 180 // seconds
+	VoicemailDefaultGreeting  = "vm-intro"
+)
+
+// VoicemailMailbox is where callers leave messages for a user or a DID. A
+// user's mailbox also takes the calls to their endpoint that go unanswered.
+// @Description Voicemail mailbox with its greeting
+type VoicemailMailbox struct {
+	ID        int64     `gorm:"column:id;primaryKey;autoIncrement" json:"id" example:"1"`
+	TenantID  string    `gorm:"column:tenant_id;type:varchar(64);not null;index:idx_tenant" json:"tenant_id" example:"acme-corp"`
+	Name      string    `gorm:"column:name;type:varchar(100);not null" json:"name" example:"Sales"`
+	UserID    *int64    `gorm:"column:user_id;uniqueIndex:unique_tenant_user" json:"user_id,omitempty" example:"1"`
+	DIDID     *int64    `gorm:"column:did_id;uniqueIndex:unique_did" json:"did_id,omitempty" example:"1"`
+	Greeting  *string   `gorm:"column:greeting;type:varchar(512)" json:"greeting,omitempty" example:"recording:greeting-acme-corp-1"` // media URI or sound name
+	MaxLength int       `gorm:"column:max_length;not null;default:180" json:"max_length" example:"180"`                               // seconds
+	IsActive  bool      `gorm:"column:is_active;default:true" json:"is_active" example:"true"`
+	CreatedAt time.Time `gorm:"column:created_at;autoCreateTime" json:"created_at"`
+	UpdatedAt time.Time `gorm:"column:updated_at;autoUpdateTime" json:"updated_at"`
+
+	// Relations
+	Tenant *core.Tenant `gorm:"foreignKey:TenantID" json:"tenant,omitempty"`
+	User   *core.User   `gorm:"foreignKey:UserID" json:"user,omitempty"`
+	DID    *DID         `gorm:"foreignKey:DIDID" json:"did,omitempty"`
+}
+
+// TableName specifies the table name
+func (VoicemailMailbox) TableName() string {
+	return "voicemail_mailboxes"
+}
+
+// GreetingMedia returns the media played before the beep
+func (m *VoicemailMailbox) GreetingMedia() string {
+	if m.Greeting != nil && *m.Greeting != "" {
+		return mediaURI(*m.Greeting)
+	}
+	return mediaURI(VoicemailDefaultGreeting)
+}
+
+// MessageLength returns the longest message the mailbox takes, in seconds
+func (m *VoicemailMailbox) MessageLength() int {
+	if m.MaxLength > 0 {
+		return m.MaxLength
+	}
+	return VoicemailDefaultMaxLength
+}
+
 // PsEndpoint represents an Asterisk PJSIP endpoint (ps_endpoints table)
 // This is an ARA (Asterisk Realtime Architecture) table
 // @Description PJSIP endpoint configuration (ARA)
@@ -120,6 +196,10 @@ type PsEndpoint struct {
 	Callerid        *string   `gorm:"column:callerid;type:varchar(128)" json:"callerid,omitempty" example:"Agent 1 <101>"`
 	MediaEncryption *string   `gorm:"column:media_encryption;type:varchar(20)" json:"media_encryption,omitempty" example:"dtls"`
 	Webrtc          *string   `gorm:"column:webrtc;type:varchar(10)" json:"webrtc,omitempty" example:"yes"`
+	OutboundAuth    *string   `gorm:"column:outbound_auth;type:varchar(128)" json:"outbound_auth,omitempty" example:"acme-corp-trunk-1-auth"`
+	FromUser        *string   `gorm:"column:from_user;type:varchar(128)" json:"from_user,omitempty" example:"15551234567"`
+	FromDomain      *string   `gorm:"column:from_domain;type:varchar(128)" json:"from_domain,omitempty" example:"sip.example.com"`
+	IdentifyBy      *string   `gorm:"column:identify_by;type:varchar(128)" json:"identify_by,omitempty" example:"username,ip"`
 	CreatedAt       time.Time `gorm:"column:created_at;autoCreateTime" json:"created_at"`
 	UpdatedAt       time.Time `gorm:"column:updated_at;autoUpdateTime" json:"updated_at"`
 
@@ -214,3 +294,43 @@ func (pc *PsContact) IsExpired() bool {
 	}
 	return time.Now().Unix() > *pc.ExpirationTime
 }
+
+// PsRegistration represents an outbound registration (ps_registrations table)
+// This is an ARA table
+// @Description PJSIP outbound registration to a SIP provider (ARA)
+type PsRegistration struct {
+	ID                     string  `gorm:"column:id;primaryKey;type:varchar(128)" json:"id" example:"acme-corp-trunk-1"`
+	TenantID               string  `gorm:"column:tenant_id;type:varchar(64);not null;index:idx_tenant_registration" json:"tenant_id" example:"acme-corp"`
+	ServerURI              *string `gorm:"column:server_uri;type:varchar(255)" json:"server_uri,omitempty" example:"sip:sip.example.com:5060"`
+	ClientURI              *string `gorm:"column:client_uri;type:varchar(255)" json:"client_uri,omitempty" example:"sip:acme@sip.example.com:5060"`
+	ContactUser            *string `gorm:"column:contact_user;type:varchar(40)" json:"contact_user,omitempty" example:"acme"`
+	Endpoint               *string `gorm:"column:endpoint;type:varchar(128)" json:"endpoint,omitempty" example:"acme-corp-trunk-1"`
+	OutboundAuth           *string `gorm:"column:outbound_auth;type:varchar(128)" json:"outbound_auth,omitempty" example:"acme-corp-trunk-1-auth"`
+	Transport              *string `gorm:"column:transport;type:varchar(40)" json:"transport,omitempty" example:"transport-udp"`
+	Line                   *string `gorm:"column:line;type:varchar(10)" json:"line,omitempty" example:"yes"`
+	Expiration             *int    `gorm:"column:expiration" json:"expiration,omitempty" example:"3600"`
+	RetryInterval          *int    `gorm:"column:retry_interval" json:"retry_interval,omitempty" example:"60"`
+	ForbiddenRetryInterval *int    `gorm:"column:forbidden_retry_interval" json:"forbidden_retry_interval,omitempty" example:"300"`
+	MaxRetries             *int    `gorm:"column:max_retries" json:"max_retries,omitempty" example:"10000"`
+	AuthRejectionPermanent *string `gorm:"column:auth_rejection_permanent;type:varchar(10)" json:"auth_rejection_permanent,omitempty" example:"no"`
+}
+
+// TableName specifies the table name
+func (PsRegistration) TableName() string {
+	return "ps_registrations"
+}
+
+// PsEndpointIdentify matches requests from an IP address or network to an
+// endpoint (ps_endpoint_id_ips table)
+// This is an ARA table
+// @Description PJSIP identify-by-IP rule (ARA)
+type PsEndpointIdentify struct {
+	ID       string `gorm:"column:id;primaryKey;type:varchar(128)" json:"id" example:"acme-corp-trunk-1-identify"`
+	Endpoint string `gorm:"column:endpoint;type:varchar(128);not null;index:endpoint_idx" json:"endpoint" example:"acme-corp-trunk-1"`
+	Match    string `gorm:"column:match;type:varchar(255);not null" json:"match" example:"203.0.113.0/24,198.51.100.10"`
+}
+
+// TableName specifies the table name
+func (PsEndpointIdentify) TableName() string {
+	return "ps_endpoint_id_ips"
+}
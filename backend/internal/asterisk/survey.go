@@ -0,0 +1,245 @@
+package asterisk
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/psschand/callcenter/internal/common"
+	"github.com/psschand/callcenter/internal/core"
+)
+
+// SurveyTriggerCallEnd runs a survey once the agent hangs up, the only
+// trigger supported
+const SurveyTriggerCallEnd = "call_end"
+
+// surveyMaxQuestions keeps surveys short enough that callers finish them
+const surveyMaxQuestions = 10
+
+// CallSurvey is a post-call survey callers are offered when their call
+// reaches a DID or queue using it. Callers who press 1 at the opt-in prompt
+// answer its questions by DTMF after the agent hangs up.
+// @Description Post-call IVR survey
+type CallSurvey struct {
+	ID             int64           `gorm:"column:id;primaryKey;autoIncrement" json:"id" example:"1"`
+	TenantID       string          `gorm:"column:tenant_id;type:varchar(36);not null;index" json:"tenant_id" example:"acme-corp"`
+	Name           string          `gorm:"column:name;type:varchar(255);not null" json:"name" example:"Support CSAT"`
+	Description    *string         `gorm:"column:description;type:text" json:"description,omitempty" example:"Asked after support calls"`
+	Questions      SurveyQuestions `gorm:"column:questions;type:json;not null" json:"questions"`
+	TriggerOn      string          `gorm:"column:trigger_on;type:varchar(50);not null;default:call_end" json:"trigger_on" example:"call_end"`
+	OptInPrompt    string          `gorm:"column:opt_in_prompt;type:varchar(255);not null" json:"opt_in_prompt" example:"custom/survey-optin"`
+	IntroPrompt    *string         `gorm:"column:intro_prompt;type:varchar(255)" json:"intro_prompt,omitempty" example:"custom/survey-intro"`
+	ThankYouPrompt *string         `gorm:"column:thank_you_prompt;type:varchar(255)" json:"thank_you_prompt,omitempty" example:"custom/survey-thanks"`
+	IsActive       bool            `gorm:"column:is_active;not null;default:true;index" json:"is_active" example:"true"`
+	CreatedAt      time.Time       `gorm:"column:created_at;autoCreateTime" json:"created_at"`
+	UpdatedAt      time.Time       `gorm:"column:updated_at;autoUpdateTime" json:"updated_at"`
+
+	// Relations
+	Tenant *core.Tenant `gorm:"foreignKey:TenantID" json:"tenant,omitempty"`
+}
+
+// TableName specifies the table name
+func (CallSurvey) TableName() string {
+	return "call_surveys"
+}
+
+// SurveyQuestion is one question of a survey, asked in order. Rating
+// questions take a number from Min to Max, ended early with #; yes/no
+// questions take 1 for yes and 2 for no. Metric marks the rating that
+// feeds the CSAT or NPS score.
+type SurveyQuestion struct {
+	Label  string                    `json:"label" example:"Overall satisfaction"`
+	Prompt string                    `json:"prompt" example:"custom/survey-q1"`
+	Type   common.SurveyQuestionType `json:"type" example:"rating"`
+	Min    int                       `json:"min,omitempty" example:"1"`
+	Max    int                       `json:"max,omitempty" example:"5"`
+	Metric common.SurveyMetric       `json:"metric,omitempty" example:"csat"`
+}
+
+// SurveyQuestions is the ordered question list of a survey
+type SurveyQuestions []SurveyQuestion
+
+// Value implements driver.Valuer interface
+func (q SurveyQuestions) Value() (driver.Value, error) {
+	return json.Marshal(q)
+}
+
+// Scan implements sql.Scanner interface
+func (q *SurveyQuestions) Scan(value interface{}) error {
+	if value == nil {
+		return nil
+	}
+	bytes, ok := value.([]byte)
+	if !ok {
+		return errors.New("type assertion to []byte failed")
+	}
+	return json.Unmarshal(bytes, q)
+}
+
+// Validate checks the questions can be asked and scored. Ratings go up to
+// 10 at most so they can be keyed in; an NPS question rates 0 to 10.
+func (q SurveyQuestions) Validate() error {
+	if len(q) == 0 {
+		return errors.New("a survey needs at least one question")
+	}
+	if len(q) > surveyMaxQuestions {
+		return fmt.Errorf("a survey has at most %d questions", surveyMaxQuestions)
+	}
+
+	metrics := map[common.SurveyMetric]bool{}
+	for i, question := range q {
+		if strings.TrimSpace(question.Label) == "" {
+			return fmt.Errorf("question %d: label is required", i+1)
+		}
+		if strings.TrimSpace(question.Prompt) == "" {
+			return fmt.Errorf("question %d: prompt is required", i+1)
+		}
+		switch question.Type {
+		case common.SurveyQuestionRating:
+			if question.Min < 0 || question.Max > 10 || question.Min >= question.Max {
+				return fmt.Errorf("question %d: rating must run from min to a higher max between 0 and 10", i+1)
+			}
+		case common.SurveyQuestionYesNo:
+			if question.Metric != "" {
+				return fmt.Errorf("question %d: a yes/no question cannot have a metric", i+1)
+			}
+		default:
+			return fmt.Errorf("question %d: type must be rating or yes_no", i+1)
+		}
+
+		switch question.Metric {
+		case "":
+			continue
+		case common.SurveyMetricCSAT:
+		case common.SurveyMetricNPS:
+			if question.Min != 0 || question.Max != 10 {
+				return fmt.Errorf("question %d: an NPS question rates 0 to 10", i+1)
+			}
+		default:
+			return fmt.Errorf("question %d: metric must be csat or nps", i+1)
+		}
+		if metrics[question.Metric] {
+			return fmt.Errorf("question %d: only one question can feed %s", i+1, question.Metric)
+		}
+		metrics[question.Metric] = true
+	}
+	return nil
+}
+
+// Answer parses the digits a caller keyed in for the question
+func (q SurveyQuestion) Answer(digits string) (int, bool) {
+	if q.Type == common.SurveyQuestionYesNo {
+		switch digits {
+		case "1":
+			return 1, true
+		case "2":
+			return 0, true
+		}
+		return 0, false
+	}
+
+	value, err := strconv.Atoi(digits)
+	if err != nil || value < q.Min || value > q.Max {
+		return 0, false
+	}
+	return value, true
+}
+
+// AwaitsDigit checks if a caller who keyed in digits could still be
+// entering a longer rating, as 1 may be the start of 10
+func (q SurveyQuestion) AwaitsDigit(digits string) bool {
+	if q.Type != common.SurveyQuestionRating {
+		return false
+	}
+	value, err := strconv.Atoi(digits)
+	return err == nil && value > 0 && value*10 <= q.Max
+}
+
+// SurveyAnswer is a caller's answer to one survey question; yes/no answers
+// are 1 for yes and 0 for no
+type SurveyAnswer struct {
+	Question int                       `json:"question" example:"1"`
+	Label    string                    `json:"label" example:"Overall satisfaction"`
+	Type     common.SurveyQuestionType `json:"type" example:"rating"`
+	Metric   common.SurveyMetric       `json:"metric,omitempty" example:"csat"`
+	Max      int                       `json:"max,omitempty" example:"5"`
+	Value    int                       `json:"value" example:"4"`
+}
+
+// SurveyAnswers is the answer list of a survey response
+type SurveyAnswers []SurveyAnswer
+
+// Value implements driver.Valuer interface
+func (a SurveyAnswers) Value() (driver.Value, error) {
+	return json.Marshal(a)
+}
+
+// Scan implements sql.Scanner interface
+func (a *SurveyAnswers) Scan(value interface{}) error {
+	if value == nil {
+		return nil
+	}
+	bytes, ok := value.([]byte)
+	if !ok {
+		return errors.New("type assertion to []byte failed")
+	}
+	return json.Unmarshal(bytes, a)
+}
+
+// SurveyResponse is a caller's answers to a post-call survey, linked to the
+// CDR of the call and the agent who took it
+// @Description Post-call survey response
+type SurveyResponse struct {
+	ID            int64         `gorm:"column:id;primaryKey;autoIncrement" json:"id" example:"1"`
+	TenantID      string        `gorm:"column:tenant_id;type:varchar(36);not null;index" json:"tenant_id" example:"acme-corp"`
+	SurveyID      int64         `gorm:"column:survey_id;not null;index" json:"survey_id" example:"1"`
+	CDRID         *int64        `gorm:"column:cdr_id;index" json:"cdr_id,omitempty" example:"1"`
+	UniqueID      string        `gorm:"column:uniqueid;type:varchar(150);not null;index" json:"uniqueid" example:"1234567890.1"`
+	LinkedID      string        `gorm:"column:linkedid;type:varchar(150)" json:"linkedid" example:"1234567890.1"`
+	CallerNumber  string        `gorm:"column:caller_number;type:varchar(80)" json:"caller_number" example:"+15551234567"`
+	DIDID         *int64        `gorm:"column:did_id" json:"did_id,omitempty" example:"1"`
+	QueueName     *string       `gorm:"column:queue_name;type:varchar(128);index" json:"queue_name,omitempty" example:"support"`
+	AgentEndpoint *string       `gorm:"column:agent_endpoint;type:varchar(128)" json:"agent_endpoint,omitempty" example:"acme-agent1"`
+	UserID        *int64        `gorm:"column:user_id;index" json:"user_id,omitempty" example:"5"`
+	Responses     SurveyAnswers `gorm:"column:responses;type:json;not null" json:"responses"`
+	OverallRating *int          `gorm:"column:overall_rating" json:"overall_rating,omitempty" example:"4"`
+	CSATScore     *int          `gorm:"column:csat_score" json:"csat_score,omitempty" example:"4"`
+	CSATSatisfied *bool         `gorm:"column:csat_satisfied" json:"csat_satisfied,omitempty" example:"true"`
+	NPSScore      *int          `gorm:"column:nps_score" json:"nps_score,omitempty" example:"9"`
+	Completed     bool          `gorm:"column:completed;not null;default:true" json:"completed" example:"true"`
+	CompletedAt   time.Time     `gorm:"column:completed_at;autoCreateTime;index" json:"completed_at"`
+}
+
+// TableName specifies the table name
+func (SurveyResponse) TableName() string {
+	return "survey_responses"
+}
+
+// SetScores fills in the scores from the answers. A CSAT rating in the top
+// two of its scale counts as satisfied. The overall rating is the CSAT
+// rating, or the first rating answered if the survey has no CSAT question.
+func (r *SurveyResponse) SetScores() {
+	r.OverallRating, r.CSATScore, r.CSATSatisfied, r.NPSScore = nil, nil, nil, nil
+	for _, answer := range r.Responses {
+		if answer.Type != common.SurveyQuestionRating {
+			continue
+		}
+		value := answer.Value
+		switch answer.Metric {
+		case common.SurveyMetricCSAT:
+			satisfied := value >= answer.Max-1
+			r.CSATScore = &value
+			r.CSATSatisfied = &satisfied
+			r.OverallRating = &value
+		case common.SurveyMetricNPS:
+			r.NPSScore = &value
+		}
+		if r.OverallRating == nil {
+			r.OverallRating = &value
+		}
+	}
+}
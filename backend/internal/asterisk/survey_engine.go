@@ -0,0 +1,466 @@
+package asterisk
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// Survey input settings
+const (
+	surveyOptInDigit        = "1"
+	surveyInputTimeout      = 5 * time.Second
+	surveyInterDigitTimeout = 2 * time.Second
+	surveyMaxAttempts       = 2
+	surveyThankYouSound     = "auth-thankyou"
+)
+
+// SurveyLoader looks up post-call surveys.
+// repository.SurveyRepository satisfies this interface.
+type SurveyLoader interface {
+	FindByID(ctx context.Context, id int64) (*CallSurvey, error)
+}
+
+// SurveyResult is a caller's answers to a post-call survey. Completed is
+// false when the caller hung up part way; questions they skipped or failed
+// to answer have no answer.
+type SurveyResult struct {
+	TenantID      string
+	SurveyID      int64
+	UniqueID      string // caller channel
+	LinkedID      string
+	CallerNumber  string
+	DIDID         *int64
+	QueueName     string
+	AgentEndpoint string // endpoint of the agent the caller spoke to
+	Answers       []SurveyAnswer
+	Completed     bool
+}
+
+// SurveyListener is notified of each survey a caller answered
+type SurveyListener func(result SurveyResult)
+
+// surveySession tracks a caller being offered a survey or answering one
+type surveySession struct {
+	channel    *Channel
+	survey     *CallSurvey
+	next       func() error  // continues routing once the caller answers the opt-in prompt
+	result     *SurveyResult // nil while the caller is being offered the survey
+	question   int
+	attempts   int
+	digits     string
+	prompts    []string // Media still to play before collecting input
+	playbackID string
+	generation int // Bumped on every state change so stale timers are ignored
+	timer      *time.Timer
+}
+
+// SetSurveyLoader enables post-call surveys. Callers reaching a DID or queue
+// with a survey are asked whether they will take it, and those who press 1
+// answer it after the agent hangs up.
+func (h *CallHandler) SetSurveyLoader(loader SurveyLoader) {
+	h.surveys = loader
+}
+
+// SetSurveyListener sets the listener notified of survey answers
+func (h *CallHandler) SetSurveyListener(listener SurveyListener) {
+	h.surveyListener = listener
+}
+
+// offerSurvey asks a caller whether they will take a survey after the
+// call, then runs next to carry on routing. A caller is only asked once,
+// by the first DID or queue with a survey they reach. It reports whether
+// the caller is being asked; if not, routing should carry on at once.
+func (h *CallHandler) offerSurvey(channel *Channel, tenantID string, surveyID *int64, next func() error) bool {
+	if surveyID == nil || h.surveys == nil {
+		return false
+	}
+
+	h.mu.Lock()
+	call, ok := h.calls[channel.ID]
+	if !ok || call.SurveyOffered {
+		h.mu.Unlock()
+		return false
+	}
+	call.SurveyOffered = true
+	h.mu.Unlock()
+
+	survey := h.loadSurvey(tenantID, *surveyID)
+	if survey == nil {
+		return false
+	}
+
+	if err := h.client.AnswerChannel(channel.ID); err != nil {
+		log.Printf("Error answering %s to offer survey %d: %v", channel.ID, survey.ID, err)
+		return false
+	}
+
+	log.Printf("Offering survey %d (%s) to %s", survey.ID, survey.Name, channel.ID)
+	h.mu.Lock()
+	h.surveySessions[channel.ID] = &surveySession{
+		channel: channel,
+		survey:  survey,
+		next:    next,
+	}
+	h.mu.Unlock()
+
+	h.promptSurvey(channel.ID, []string{mediaURI(survey.OptInPrompt)})
+	return true
+}
+
+// loadSurvey loads an active survey of the tenant, or nil if there is none
+func (h *CallHandler) loadSurvey(tenantID string, id int64) *CallSurvey {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	survey, err := h.surveys.FindByID(ctx, id)
+	if err != nil {
+		log.Printf("Error loading survey %d: %v", id, err)
+		return nil
+	}
+	if survey.TenantID != tenantID || !survey.IsActive || len(survey.Questions) == 0 {
+		return nil
+	}
+	return survey
+}
+
+// finishSurveyOptIn records whether the caller agreed to take the survey
+// and carries on routing the call
+func (h *CallHandler) finishSurveyOptIn(channelID string, accepted bool) {
+	h.mu.Lock()
+	session, ok := h.surveySessions[channelID]
+	if !ok || session.result != nil {
+		h.mu.Unlock()
+		return
+	}
+	stopSurveyTimer(session)
+	delete(h.surveySessions, channelID)
+	delete(h.surveyPlaybacks, session.playbackID)
+	if call, known := h.calls[channelID]; known && accepted {
+		call.Survey = session.survey
+	}
+	h.mu.Unlock()
+
+	if session.playbackID != "" {
+		h.client.StopPlayback(session.playbackID)
+	}
+	if accepted {
+		log.Printf("Caller %s accepted survey %d", channelID, session.survey.ID)
+	} else {
+		log.Printf("Caller %s declined survey %d", channelID, session.survey.ID)
+	}
+
+	if err := session.next(); err != nil {
+		log.Printf("Error routing call %s after survey offer: %v", channelID, err)
+		h.rejectCall(channelID, RejectTreatmentCongestion)
+	}
+}
+
+// surveyAfterCall starts the survey a caller agreed to take once the agent
+// hangs up, taking the caller out of the call's bridge. It reports whether
+// the survey started; if not, the caller should be hung up.
+func (h *CallHandler) surveyAfterCall(callerID, bridgeID string) bool {
+	h.mu.Lock()
+	call, ok := h.calls[callerID]
+	_, alive := h.activeChannels[callerID]
+	_, busy := h.surveySessions[callerID]
+	if !ok || !alive || busy || call.Survey == nil || call.Survey.TriggerOn != SurveyTriggerCallEnd {
+		h.mu.Unlock()
+		return false
+	}
+	survey := call.Survey
+	call.Survey = nil
+
+	result := &SurveyResult{
+		TenantID:     call.TenantID,
+		SurveyID:     survey.ID,
+		UniqueID:     callerID,
+		LinkedID:     callerID,
+		CallerNumber: call.CallerNumber,
+		QueueName:    call.Queue,
+	}
+	if call.DID != nil && call.DID.ID != 0 {
+		didID := call.DID.ID
+		result.DIDID = &didID
+	}
+	if detail, ok := h.callDetails[callerID]; ok {
+		result.LinkedID = detail.LinkedID
+		result.AgentEndpoint = detail.AgentEndpoint
+	}
+	h.surveySessions[callerID] = &surveySession{
+		channel: h.activeChannels[callerID],
+		survey:  survey,
+		result:  result,
+	}
+	h.mu.Unlock()
+
+	// The call itself is over; the survey is not part of its CDR
+	h.endCallDetail(callerID)
+	if bridgeID != "" {
+		h.client.RemoveChannelFromBridge(bridgeID, callerID)
+	}
+
+	log.Printf("Caller %s taking survey %d (%s) after talking to %s", callerID, survey.ID, survey.Name, result.AgentEndpoint)
+
+	var prompts []string
+	if survey.IntroPrompt != nil && *survey.IntroPrompt != "" {
+		prompts = append(prompts, mediaURI(*survey.IntroPrompt))
+	}
+	h.promptSurvey(callerID, append(prompts, mediaURI(survey.Questions[0].Prompt)))
+	return true
+}
+
+// promptSurvey plays prompts and then waits for input
+func (h *CallHandler) promptSurvey(channelID string, prompts []string) {
+	h.mu.Lock()
+	session, ok := h.surveySessions[channelID]
+	if ok {
+		session.digits = ""
+		session.prompts = prompts
+		stopSurveyTimer(session)
+	}
+	h.mu.Unlock()
+
+	if ok {
+		h.playNextSurveyPrompt(channelID)
+	}
+}
+
+// playNextSurveyPrompt plays the next queued prompt, or starts the input timeout when there is none
+func (h *CallHandler) playNextSurveyPrompt(channelID string) {
+	h.mu.Lock()
+	session, ok := h.surveySessions[channelID]
+	if !ok {
+		h.mu.Unlock()
+		return
+	}
+	if len(session.prompts) == 0 {
+		h.armSurveyTimer(channelID, session, surveyInputTimeout)
+		h.mu.Unlock()
+		return
+	}
+	media := session.prompts[0]
+	session.prompts = session.prompts[1:]
+	h.mu.Unlock()
+
+	playback, err := h.client.PlayMedia(channelID, media)
+	if err != nil {
+		log.Printf("Error playing survey prompt %s on %s: %v", media, channelID, err)
+		h.playNextSurveyPrompt(channelID)
+		return
+	}
+
+	h.mu.Lock()
+	if session, ok := h.surveySessions[channelID]; ok {
+		session.playbackID = playback.ID
+		h.surveyPlaybacks[playback.ID] = channelID
+	}
+	h.mu.Unlock()
+}
+
+// onSurveyPlaybackFinished continues the prompt sequence; it reports whether the playback belonged to a survey
+func (h *CallHandler) onSurveyPlaybackFinished(playbackID string) bool {
+	h.mu.Lock()
+	channelID, ok := h.surveyPlaybacks[playbackID]
+	delete(h.surveyPlaybacks, playbackID)
+	if ok {
+		if session, exists := h.surveySessions[channelID]; exists && session.playbackID == playbackID {
+			session.playbackID = ""
+		}
+	}
+	h.mu.Unlock()
+
+	if ok {
+		h.playNextSurveyPrompt(channelID)
+	}
+	return ok
+}
+
+// onSurveyDigit collects a DTMF digit; it reports whether the channel is
+// being offered or taking a survey
+func (h *CallHandler) onSurveyDigit(channelID, digit string) bool {
+	h.mu.Lock()
+	session, ok := h.surveySessions[channelID]
+	if !ok {
+		h.mu.Unlock()
+		return false
+	}
+
+	if session.result == nil {
+		h.mu.Unlock()
+		h.finishSurveyOptIn(channelID, digit == surveyOptInDigit)
+		return true
+	}
+
+	// Barge-in: input interrupts any prompt
+	playbackID := session.playbackID
+	session.playbackID = ""
+	session.prompts = nil
+	delete(h.surveyPlaybacks, playbackID)
+	stopSurveyTimer(session)
+
+	// # ends a rating early
+	if digit != "#" {
+		session.digits += digit
+	}
+	digits := session.digits
+	waiting := digit != "#" && session.survey.Questions[session.question].AwaitsDigit(digits)
+	if waiting {
+		h.armSurveyTimer(channelID, session, surveyInterDigitTimeout)
+	}
+	h.mu.Unlock()
+
+	if playbackID != "" {
+		h.client.StopPlayback(playbackID)
+	}
+
+	if !waiting {
+		h.answerSurveyQuestion(channelID)
+	}
+	return true
+}
+
+// onSurveyTimeout fires when the caller stops entering digits
+func (h *CallHandler) onSurveyTimeout(channelID string, generation int) {
+	h.mu.Lock()
+	session, ok := h.surveySessions[channelID]
+	if !ok || session.generation != generation {
+		h.mu.Unlock()
+		return
+	}
+	offering := session.result == nil
+	h.mu.Unlock()
+
+	if offering {
+		h.finishSurveyOptIn(channelID, false)
+		return
+	}
+	h.answerSurveyQuestion(channelID)
+}
+
+// answerSurveyQuestion records the digits entered for the current question
+// and moves on; input that does not answer it is asked again, and a
+// question still unanswered after the last attempt is skipped
+func (h *CallHandler) answerSurveyQuestion(channelID string) {
+	h.mu.Lock()
+	session, ok := h.surveySessions[channelID]
+	if !ok || session.result == nil {
+		h.mu.Unlock()
+		return
+	}
+	question := session.survey.Questions[session.question]
+	value, valid := question.Answer(session.digits)
+	log.Printf("Survey %d on %s: question %d answered %q", session.survey.ID, channelID, session.question+1, session.digits)
+
+	if !valid {
+		session.attempts++
+		if session.attempts < surveyMaxAttempts {
+			noInput := session.digits == ""
+			h.mu.Unlock()
+
+			prompts := []string{mediaURI(question.Prompt)}
+			if !noInput {
+				prompts = append([]string{ivrDefaultInvalidSound}, prompts...)
+			}
+			h.promptSurvey(channelID, prompts)
+			return
+		}
+	} else {
+		session.result.Answers = append(session.result.Answers, SurveyAnswer{
+			Question: session.question + 1,
+			Label:    question.Label,
+			Type:     question.Type,
+			Metric:   question.Metric,
+			Max:      question.Max,
+			Value:    value,
+		})
+	}
+
+	session.question++
+	session.attempts = 0
+	done := session.question >= len(session.survey.Questions)
+	var prompt string
+	if !done {
+		prompt = mediaURI(session.survey.Questions[session.question].Prompt)
+	}
+	h.mu.Unlock()
+
+	if done {
+		h.finishSurvey(channelID)
+		return
+	}
+	h.promptSurvey(channelID, []string{prompt})
+}
+
+// finishSurvey reports a completed survey, thanks the caller and hangs up
+func (h *CallHandler) finishSurvey(channelID string) {
+	h.mu.Lock()
+	session, ok := h.surveySessions[channelID]
+	if !ok {
+		h.mu.Unlock()
+		return
+	}
+	stopSurveyTimer(session)
+	delete(h.surveySessions, channelID)
+	delete(h.surveyPlaybacks, session.playbackID)
+	h.mu.Unlock()
+
+	session.result.Completed = true
+	h.notifySurvey(*session.result)
+
+	media := "sound:" + surveyThankYouSound
+	if session.survey.ThankYouPrompt != nil && *session.survey.ThankYouPrompt != "" {
+		media = mediaURI(*session.survey.ThankYouPrompt)
+	}
+	playback, err := h.client.PlayMedia(channelID, media)
+	if err != nil {
+		h.client.HangupChannel(channelID)
+		return
+	}
+	h.mu.Lock()
+	h.hangupAfterPlayback[playback.ID] = channelID
+	h.mu.Unlock()
+}
+
+// endSurveySession removes a channel's survey session when it leaves the
+// application, reporting the answers of a caller who hung up part way
+func (h *CallHandler) endSurveySession(channelID string) {
+	h.mu.Lock()
+	session, ok := h.surveySessions[channelID]
+	if ok {
+		stopSurveyTimer(session)
+		delete(h.surveySessions, channelID)
+		delete(h.surveyPlaybacks, session.playbackID)
+	}
+	h.mu.Unlock()
+
+	if ok && session.result != nil && len(session.result.Answers) > 0 {
+		h.notifySurvey(*session.result)
+	}
+}
+
+// notifySurvey passes a caller's survey answers to the listener
+func (h *CallHandler) notifySurvey(result SurveyResult) {
+	log.Printf("Survey %d on %s: %d answers (completed: %t)", result.SurveyID, result.UniqueID, len(result.Answers), result.Completed)
+	if h.surveyListener != nil {
+		go h.surveyListener(result)
+	}
+}
+
+// armSurveyTimer schedules the input timeout; the caller must hold h.mu
+func (h *CallHandler) armSurveyTimer(channelID string, session *surveySession, d time.Duration) {
+	stopSurveyTimer(session)
+	generation := session.generation
+	session.timer = time.AfterFunc(d, func() {
+		h.onSurveyTimeout(channelID, generation)
+	})
+}
+
+// stopSurveyTimer cancels the input timeout and invalidates one that already fired
+func stopSurveyTimer(session *surveySession) {
+	session.generation++
+	if session.timer != nil {
+		session.timer.Stop()
+		session.timer = nil
+	}
+}
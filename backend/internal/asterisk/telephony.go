@@ -10,19 +10,23 @@ import (
 // DID represents a phone number (Direct Inward Dialing) assigned to a tenant
 // @Description Phone number with routing configuration
 type DID struct {
-	ID            int64            `gorm:"column:id;primaryKey;autoIncrement" json:"id" example:"1"`
-	TenantID      string           `gorm:"column:tenant_id;type:varchar(64);not null;index" json:"tenant_id" example:"acme-corp"`
-	Number        string           `gorm:"column:number;type:varchar(32);not null;uniqueIndex" json:"number" example:"+15551234567"`
-	CountryCode   *string          `gorm:"column:country_code;type:varchar(8)" json:"country_code,omitempty" example:"+1"`
-	FriendlyName  *string          `gorm:"column:friendly_name;type:varchar(255)" json:"friendly_name,omitempty" example:"Main Sales Line"`
-	RouteType     common.RouteType `gorm:"column:route_type;type:enum('queue','endpoint','ivr','webhook','external','voicemail');not null;default:queue;index" json:"route_type" example:"queue"`
-	RouteTarget   string           `gorm:"column:route_target;type:varchar(255);not null" json:"route_target" example:"sales"`
-	SMSEnabled    bool             `gorm:"column:sms_enabled;default:false" json:"sms_enabled" example:"true"`
-	SMSWebhookURL *string          `gorm:"column:sms_webhook_url;type:varchar(512)" json:"sms_webhook_url,omitempty"`
-	Status        common.DIDStatus `gorm:"column:status;type:enum('active','inactive','pending');default:active;index" json:"status" example:"active"`
-	Metadata      common.JSONMap   `gorm:"column:metadata;type:json" json:"metadata,omitempty"`
-	CreatedAt     time.Time        `gorm:"column:created_at;autoCreateTime" json:"created_at"`
-	UpdatedAt     time.Time        `gorm:"column:updated_at;autoUpdateTime" json:"updated_at"`
+	ID                    int64             `gorm:"column:id;primaryKey;autoIncrement" json:"id" example:"1"`
+	TenantID              string            `gorm:"column:tenant_id;type:varchar(64);not null;index" json:"tenant_id" example:"acme-corp"`
+	Number                string            `gorm:"column:number;type:varchar(32);not null;uniqueIndex" json:"number" example:"+15551234567"`
+	CountryCode           *string           `gorm:"column:country_code;type:varchar(8)" json:"country_code,omitempty" example:"+1"`
+	FriendlyName          *string           `gorm:"column:friendly_name;type:varchar(255)" json:"friendly_name,omitempty" example:"Main Sales Line"`
+	RouteType             common.RouteType  `gorm:"column:route_type;type:enum('queue','endpoint','ivr','webhook','external','voicemail');not null;default:queue;index" json:"route_type" example:"queue"`
+	RouteTarget           string            `gorm:"column:route_target;type:varchar(255);not null" json:"route_target" example:"sales"`
+	ScheduleID            *int64            `gorm:"column:schedule_id;index" json:"schedule_id,omitempty" example:"1"`
+	AfterHoursRouteType   *common.RouteType `gorm:"column:after_hours_route_type;type:varchar(20)" json:"after_hours_route_type,omitempty" example:"voicemail"`
+	AfterHoursRouteTarget *string           `gorm:"column:after_hours_route_target;type:varchar(255)" json:"after_hours_route_target,omitempty" example:"1000"`
+	SurveyID              *int64            `gorm:"column:survey_id;index" json:"survey_id,omitempty" example:"1"`
+	SMSEnabled            bool              `gorm:"column:sms_enabled;default:false" json:"sms_enabled" example:"true"`
+	SMSWebhookURL         *string           `gorm:"column:sms_webhook_url;type:varchar(512)" json:"sms_webhook_url,omitempty"`
+	Status                common.DIDStatus  `gorm:"column:status;type:enum('active','inactive','pending');default:active;index" json:"status" example:"active"`
+	Metadata              common.JSONMap    `gorm:"column:metadata;type:json" json:"metadata,omitempty"`
+	CreatedAt             time.Time         `gorm:"column:created_at;autoCreateTime" json:"created_at"`
+	UpdatedAt             time.Time         `gorm:"column:updated_at;autoUpdateTime" json:"updated_at"`
 
 	// Relations
 	Tenant *core.Tenant `gorm:"foreignKey:TenantID" json:"tenant,omitempty"`
@@ -41,22 +45,33 @@ func (d *DID) IsActive() bool {
 // Queue represents a call queue configuration
 // @Description Call queue with strategy and timeout settings
 type Queue struct {
-	ID                int64          `gorm:"column:id;primaryKey;autoIncrement" json:"id" example:"1"`
-	TenantID          string         `gorm:"column:tenant_id;type:varchar(64);not null;index:idx_tenant_queue" json:"tenant_id" example:"acme-corp"`
-	Name              string         `gorm:"column:name;type:varchar(128);not null;index:idx_tenant_queue" json:"name" example:"sales"`
-	DisplayName       string         `gorm:"column:display_name;type:varchar(255);not null" json:"display_name" example:"Sales Queue"`
-	Strategy          string         `gorm:"column:strategy;type:enum('ringall','leastrecent','fewestcalls','random','rrmemory','rrordered','linear','wrandom');default:ringall" json:"strategy" example:"leastrecent"`
-	Timeout           int            `gorm:"column:timeout;default:30" json:"timeout" example:"30"`
-	Retry             int            `gorm:"column:retry;default:5" json:"retry" example:"5"`
-	MaxWaitTime       int            `gorm:"column:max_wait_time;default:300" json:"max_wait_time" example:"300"`
-	MaxLen            int            `gorm:"column:max_len;default:0" json:"max_len" example:"0"`
-	AnnounceFrequency int            `gorm:"column:announce_frequency;default:60" json:"announce_frequency" example:"60"`
-	AnnounceHoldTime  bool           `gorm:"column:announce_hold_time;default:true" json:"announce_hold_time" example:"true"`
-	MusicOnHold       string         `gorm:"column:music_on_hold;type:varchar(128);default:default" json:"music_on_hold" example:"default"`
-	Status            string         `gorm:"column:status;type:enum('active','inactive');default:active;index" json:"status" example:"active"`
-	Metadata          common.JSONMap `gorm:"column:metadata;type:json" json:"metadata,omitempty"`
-	CreatedAt         time.Time      `gorm:"column:created_at;autoCreateTime" json:"created_at"`
-	UpdatedAt         time.Time      `gorm:"column:updated_at;autoUpdateTime" json:"updated_at"`
+	ID                  int64             `gorm:"column:id;primaryKey;autoIncrement" json:"id" example:"1"`
+	TenantID            string            `gorm:"column:tenant_id;type:varchar(64);not null;index:idx_tenant_queue" json:"tenant_id" example:"acme-corp"`
+	Name                string            `gorm:"column:name;type:varchar(128);not null;index:idx_tenant_queue" json:"name" example:"sales"`
+	DisplayName         string            `gorm:"column:display_name;type:varchar(255);not null" json:"display_name" example:"Sales Queue"`
+	Strategy            string            `gorm:"column:strategy;type:enum('ringall','leastrecent','fewestcalls','random','rrmemory','rrordered','linear','wrandom');default:ringall" json:"strategy" example:"leastrecent"`
+	Timeout             int               `gorm:"column:timeout;default:30" json:"timeout" example:"30"`
+	Retry               int               `gorm:"column:retry;default:5" json:"retry" example:"5"`
+	WrapupTime          int               `gorm:"column:wrapup_time;default:0" json:"wrapup_time" example:"30"`
+	MaxWaitTime         int               `gorm:"column:max_wait_time;default:300" json:"max_wait_time" example:"300"`
+	MaxLen              int               `gorm:"column:max_len;default:0" json:"max_len" example:"0"`
+	AnnounceFrequency   int               `gorm:"column:announce_frequency;default:60" json:"announce_frequency" example:"60"`
+	AnnounceHoldTime    bool              `gorm:"column:announce_hold_time;default:true" json:"announce_hold_time" example:"true"`
+	MusicOnHold         string            `gorm:"column:music_on_hold;type:varchar(128);default:default" json:"music_on_hold" example:"default"`
+	FallbackRouteType   *common.RouteType `gorm:"column:fallback_route_type;type:varchar(20)" json:"fallback_route_type,omitempty" example:"voicemail"`
+	FallbackRouteTarget *string           `gorm:"column:fallback_route_target;type:varchar(255)" json:"fallback_route_target,omitempty" example:"1000"`
+	ScheduleID          *int64            `gorm:"column:schedule_id;index" json:"schedule_id,omitempty" example:"1"`
+	SurveyID            *int64            `gorm:"column:survey_id;index" json:"survey_id,omitempty" example:"1"`
+	CallbackEnabled     bool              `gorm:"column:callback_enabled;default:false" json:"callback_enabled" example:"true"`
+	CallbackOfferAfter  int               `gorm:"column:callback_offer_after;default:60" json:"callback_offer_after" example:"60"`
+	CallbackPrompt      *string           `gorm:"column:callback_prompt;type:varchar(255)" json:"callback_prompt,omitempty" example:"custom/press-1-for-callback"`
+	CallbackMaxAttempts int               `gorm:"column:callback_max_attempts;default:3" json:"callback_max_attempts" example:"3"`
+	CallbackRetry       int               `gorm:"column:callback_retry;default:300" json:"callback_retry" example:"300"`
+	RecordingPolicy     string            `gorm:"column:recording_policy;type:enum('inherit','always','never');default:inherit" json:"recording_policy" example:"inherit"`
+	Status              string            `gorm:"column:status;type:enum('active','inactive');default:active;index" json:"status" example:"active"`
+	Metadata            common.JSONMap    `gorm:"column:metadata;type:json" json:"metadata,omitempty"`
+	CreatedAt           time.Time         `gorm:"column:created_at;autoCreateTime" json:"created_at"`
+	UpdatedAt           time.Time         `gorm:"column:updated_at;autoUpdateTime" json:"updated_at"`
 
 	// Relations
 	Tenant  *core.Tenant  `gorm:"foreignKey:TenantID" json:"tenant,omitempty"`
@@ -73,6 +88,11 @@ func (q *Queue) IsActive() bool {
 	return q.Status == "active"
 }
 
+// OffersCallbacks checks if callers holding in the queue are offered a callback
+func (q *Queue) OffersCallbacks() bool {
+	return q.CallbackEnabled && q.CallbackPrompt != nil && *q.CallbackPrompt != ""
+}
+
 // QueueMember represents a member (agent) in a queue
 // @Description Queue member assignment with penalty and state
 type QueueMember struct {
@@ -120,12 +140,15 @@ type CDR struct {
 	AMAFlags      int                    `gorm:"column:amaflags;not null;default:0" json:"amaflags" example:"3"`
 	AccountCode   string                 `gorm:"column:accountcode;type:varchar(20);not null;index:idx_accountcode" json:"accountcode" example:"acme-corp"`
 	UniqueID      string                 `gorm:"column:uniqueid;type:varchar(150);not null;index:idx_uniqueid" json:"uniqueid" example:"1634567890.123"`
+	LinkedID      string                 `gorm:"column:linkedid;type:varchar(150);not null;default:'';index:idx_linkedid" json:"linkedid" example:"1634567890.123"`
+	Sequence      int                    `gorm:"column:sequence;not null;default:0" json:"sequence" example:"0"`
 	UserField     string                 `gorm:"column:userfield;type:varchar(255);not null" json:"userfield,omitempty"`
 	RecordingFile *string                `gorm:"column:recordingfile;type:varchar(512)" json:"recordingfile,omitempty"`
 	DIDID         *int64                 `gorm:"column:did_id" json:"did_id,omitempty" example:"1"`
 	UserID        *int64                 `gorm:"column:user_id;index:idx_user" json:"user_id,omitempty" example:"1"`
 	QueueName     *string                `gorm:"column:queue_name;type:varchar(128);index:idx_queue" json:"queue_name,omitempty" example:"sales"`
 	QueueWaitTime int                    `gorm:"column:queue_wait_time;default:0" json:"queue_wait_time" example:"15"`
+	WrapupTime    int                    `gorm:"column:wrapup_time;default:0" json:"wrapup_time" example:"20"`
 	Metadata      common.JSONMap         `gorm:"column:metadata;type:json" json:"metadata,omitempty"`
 
 	// Relations
@@ -153,17 +176,19 @@ func (c *CDR) GetDurationMinutes() float64 {
 // CallRecording represents a stored call recording
 // @Description Call recording file information
 type CallRecording struct {
-	ID        int64                  `gorm:"column:id;primaryKey;autoIncrement" json:"id" example:"1"`
-	TenantID  string                 `gorm:"column:tenant_id;type:varchar(64);not null;index:idx_tenant" json:"tenant_id" example:"acme-corp"`
-	CDRID     *int64                 `gorm:"column:cdr_id;index:idx_cdr" json:"cdr_id,omitempty" example:"1"`
-	UniqueID  string                 `gorm:"column:uniqueid;type:varchar(150);not null;index:idx_uniqueid" json:"uniqueid" example:"1634567890.123"`
-	Filename  string                 `gorm:"column:filename;type:varchar(512);not null" json:"filename" example:"acme-corp-1634567890-123.wav"`
-	FilePath  string                 `gorm:"column:file_path;type:varchar(1024);not null" json:"file_path" example:"/var/spool/asterisk/monitor/2023/10/25/acme-corp-1634567890-123.wav"`
-	FileSize  int64                  `gorm:"column:file_size;default:0" json:"file_size" example:"524288"`
-	Duration  int                    `gorm:"column:duration;default:0" json:"duration" example:"120"`
-	Format    string                 `gorm:"column:format;type:varchar(16);default:wav" json:"format" example:"wav"`
-	Status    common.RecordingStatus `gorm:"column:status;type:enum('recording','completed','failed','deleted');default:recording" json:"status" example:"completed"`
-	CreatedAt time.Time              `gorm:"column:created_at;autoCreateTime;index:idx_created" json:"created_at"`
+	ID             int64                  `gorm:"column:id;primaryKey;autoIncrement" json:"id" example:"1"`
+	TenantID       string                 `gorm:"column:tenant_id;type:varchar(64);not null;index:idx_tenant" json:"tenant_id" example:"acme-corp"`
+	CDRID          *int64                 `gorm:"column:cdr_id;index:idx_cdr" json:"cdr_id,omitempty" example:"1"`
+	UniqueID       string                 `gorm:"column:uniqueid;type:varchar(150);not null;index:idx_uniqueid" json:"uniqueid" example:"1634567890.123"`
+	Filename       string                 `gorm:"column:filename;type:varchar(512);not null" json:"filename" example:"acme-corp-1634567890-123.wav"`
+	FilePath       string                 `gorm:"column:file_path;type:varchar(1024);not null" json:"file_path" example:"/var/spool/asterisk/monitor/2023/10/25/acme-corp-1634567890-123.wav"`
+	StorageBackend *string                `gorm:"column:storage_backend;type:varchar(16)" json:"storage_backend,omitempty" example:"s3"`
+	StorageKey     *string                `gorm:"column:storage_key;type:varchar(1024)" json:"storage_key,omitempty" example:"recordings/acme-corp/2023/10/25/acme-corp-1634567890-123.wav"`
+	FileSize       int64                  `gorm:"column:file_size;default:0" json:"file_size" example:"524288"`
+	Duration       int                    `gorm:"column:duration;default:0" json:"duration" example:"120"`
+	Format         string                 `gorm:"column:format;type:varchar(16);default:wav" json:"format" example:"wav"`
+	Status         common.RecordingStatus `gorm:"column:status;type:enum('recording','completed','failed','deleted');default:recording" json:"status" example:"completed"`
+	CreatedAt      time.Time              `gorm:"column:created_at;autoCreateTime;index:idx_created" json:"created_at"`
 
 	// Relations
 	Tenant *core.Tenant `gorm:"foreignKey:TenantID" json:"tenant,omitempty"`
@@ -180,6 +205,11 @@ func (cr *CallRecording) IsCompleted() bool {
 	return cr.Status == common.RecordingStatusCompleted
 }
 
+// IsArchived checks if the recording has been moved out of the Asterisk spool into storage
+func (cr *CallRecording) IsArchived() bool {
+	return cr.StorageKey != nil
+}
+
 // GetFileSizeMB returns file size in megabytes
 func (cr *CallRecording) GetFileSizeMB() float64 {
 	return float64(cr.FileSize) / (1024 * 1024)
@@ -192,11 +222,17 @@ type AgentState struct {
 	TenantID      string             `gorm:"column:tenant_id;type:varchar(64);not null;index:idx_tenant" json:"tenant_id" example:"acme-corp"`
 	UserID        int64              `gorm:"column:user_id;not null;uniqueIndex:unique_tenant_user" json:"user_id" example:"1"`
 	EndpointID    string             `gorm:"column:endpoint_id;type:varchar(128);not null;index:idx_endpoint" json:"endpoint_id" example:"acme-agent1"`
-	State         common.AgentStatus `gorm:"column:state;type:enum('available','busy','away','break','offline','dnd');default:offline;index:idx_state" json:"state" example:"available"`
+	State         common.AgentStatus `gorm:"column:state;type:enum('available','busy','wrapup','away','break','offline','dnd');default:offline;index:idx_state" json:"state" example:"available"`
+	ReasonCode    *string            `gorm:"column:reason_code;type:varchar(64)" json:"reason_code,omitempty" example:"lunch"`
 	Reason        *string            `gorm:"column:reason;type:varchar(255)" json:"reason,omitempty" example:"Lunch break"`
 	CurrentCallID *string            `gorm:"column:current_call_id;type:varchar(150)" json:"current_call_id,omitempty" example:"1634567890.123"`
 	ChangedAt     time.Time          `gorm:"column:changed_at;autoUpdateTime" json:"changed_at"`
 
+	// Wrap-up after a call (CurrentCallID) or chat (WrapupChatID)
+	WrapupStartedAt *time.Time `gorm:"column:wrapup_started_at" json:"wrapup_started_at,omitempty"`
+	WrapupEndsAt    *time.Time `gorm:"column:wrapup_ends_at" json:"wrapup_ends_at,omitempty"`
+	WrapupChatID    *int64     `gorm:"column:wrapup_chat_id" json:"wrapup_chat_id,omitempty" example:"1"`
+
 	// Relations
 	Tenant *core.Tenant `gorm:"foreignKey:TenantID" json:"tenant,omitempty"`
 	User   *core.User   `gorm:"foreignKey:UserID" json:"user,omitempty"`
@@ -217,6 +253,11 @@ func (as *AgentState) IsOnCall() bool {
 	return as.State == common.AgentStatusBusy && as.CurrentCallID != nil
 }
 
+// IsInWrapup checks if agent is wrapping up a call or chat
+func (as *AgentState) IsInWrapup() bool {
+	return as.State == common.AgentStatusWrapup && as.WrapupEndsAt != nil
+}
+
 // WebSocketSession represents a real-time WebSocket connection
 // @Description WebSocket session for real-time agent communication
 type WebSocketSession struct {
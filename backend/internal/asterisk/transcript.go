@@ -0,0 +1,75 @@
+package asterisk
+
+import (
+	"time"
+
+	"github.com/psschand/callcenter/internal/common"
+	"github.com/psschand/callcenter/internal/core"
+)
+
+// TranscriptionJob queues a call recording or voicemail for speech-to-text.
+// Each source has one job, which is requeued to transcribe it again.
+// @Description Speech-to-text job
+type TranscriptionJob struct {
+	ID            int64                      `gorm:"column:id;primaryKey;autoIncrement" json:"id" example:"1"`
+	TenantID      string                     `gorm:"column:tenant_id;type:varchar(64);not null;index:idx_tenant" json:"tenant_id" example:"acme-corp"`
+	SourceType    common.TranscriptSource    `gorm:"column:source_type;type:enum('recording','voicemail');not null;uniqueIndex:unique_source" json:"source_type" example:"recording"`
+	SourceID      int64                      `gorm:"column:source_id;not null;uniqueIndex:unique_source" json:"source_id" example:"1"`
+	Status        common.TranscriptionStatus `gorm:"column:status;type:enum('pending','processing','completed','failed');default:pending;index:idx_status_next" json:"status" example:"pending"`
+	Attempts      int                        `gorm:"column:attempts;not null;default:0" json:"attempts" example:"0"`
+	Error         *string                    `gorm:"column:error;type:text" json:"error,omitempty"`
+	NextAttemptAt time.Time                  `gorm:"column:next_attempt_at;not null;index:idx_status_next" json:"next_attempt_at"`
+	StartedAt     *time.Time                 `gorm:"column:started_at" json:"started_at,omitempty"`
+	CompletedAt   *time.Time                 `gorm:"column:completed_at" json:"completed_at,omitempty"`
+	CreatedAt     time.Time                  `gorm:"column:created_at;autoCreateTime" json:"created_at"`
+	UpdatedAt     time.Time                  `gorm:"column:updated_at;autoUpdateTime" json:"updated_at"`
+
+	// Relations
+	Tenant *core.Tenant `gorm:"foreignKey:TenantID" json:"tenant,omitempty"`
+}
+
+// TableName specifies the table name
+func (TranscriptionJob) TableName() string {
+	return "transcription_jobs"
+}
+
+// Transcript is the text of a call recording or voicemail
+// @Description Transcript with its timed segments
+type Transcript struct {
+	ID         int64                   `gorm:"column:id;primaryKey;autoIncrement" json:"id" example:"1"`
+	TenantID   string                  `gorm:"column:tenant_id;type:varchar(64);not null;index:idx_tenant" json:"tenant_id" example:"acme-corp"`
+	SourceType common.TranscriptSource `gorm:"column:source_type;type:enum('recording','voicemail');not null;uniqueIndex:unique_source" json:"source_type" example:"voicemail"`
+	SourceID   int64                   `gorm:"column:source_id;not null;uniqueIndex:unique_source" json:"source_id" example:"1"`
+	UserID     *int64                  `gorm:"column:user_id;index:idx_user" json:"user_id,omitempty" example:"1"` // owner of a voicemail's mailbox
+	Provider   string                  `gorm:"column:provider;type:varchar(32);not null" json:"provider" example:"whisper"`
+	Language   *string                 `gorm:"column:language;type:varchar(32)" json:"language,omitempty" example:"en"`
+	Text       string                  `gorm:"column:text;type:mediumtext;not null" json:"text" example:"Hi, this is John calling about my order."`
+	Duration   int                     `gorm:"column:duration;default:0" json:"duration" example:"45"` // seconds
+	CreatedAt  time.Time               `gorm:"column:created_at;autoCreateTime;index:idx_created" json:"created_at"`
+
+	// Relations
+	Tenant   *core.Tenant        `gorm:"foreignKey:TenantID" json:"tenant,omitempty"`
+	Segments []TranscriptSegment `gorm:"foreignKey:TranscriptID" json:"segments,omitempty"`
+}
+
+// TableName specifies the table name
+func (Transcript) TableName() string {
+	return "transcripts"
+}
+
+// TranscriptSegment is a stretch of a transcript spoken by one speaker
+// @Description Timed transcript segment
+type TranscriptSegment struct {
+	ID           int64   `gorm:"column:id;primaryKey;autoIncrement" json:"id" example:"1"`
+	TranscriptID int64   `gorm:"column:transcript_id;not null;index:idx_transcript_sequence" json:"transcript_id" example:"1"`
+	Sequence     int     `gorm:"column:sequence;not null;index:idx_transcript_sequence" json:"sequence" example:"0"`
+	Speaker      *string `gorm:"column:speaker;type:varchar(64)" json:"speaker,omitempty" example:"speaker_1"`
+	StartMs      int64   `gorm:"column:start_ms;not null" json:"start_ms" example:"0"`
+	EndMs        int64   `gorm:"column:end_ms;not null" json:"end_ms" example:"4200"`
+	Text         string  `gorm:"column:text;type:text;not null" json:"text" example:"Hi, this is John calling about my order."`
+}
+
+// TableName specifies the table name
+func (TranscriptSegment) TableName() string {
+	return "transcript_segments"
+}
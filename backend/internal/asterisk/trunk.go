@@ -0,0 +1,138 @@
+package asterisk
+
+import (
+	"strings"
+	"time"
+
+	"github.com/psschand/callcenter/internal/common"
+	"github.com/psschand/callcenter/internal/core"
+)
+
+// Trunk defaults
+const (
+	TrunkDefaultPort      = 5060
+	TrunkDefaultTransport = "transport-udp"
+	TrunkDefaultContext   = "from-trunk"
+	TrunkDefaultCodecs    = "ulaw,alaw"
+)
+
+// TrunkAuthID returns the ID of the ARA auth a trunk endpoint registers and
+// places calls with
+func TrunkAuthID(endpointID string) string {
+	return endpointID + "-auth"
+}
+
+// SIPTrunk is a SIP provider calls are placed through and received from.
+// Trunks without a tenant are platform trunks that every tenant may route
+// calls through. Each trunk is provisioned as a PJSIP endpoint in the
+// Asterisk realtime (ARA) tables under EndpointID. The trunk's password is
+// only stored in its ARA auth, where Asterisk reads it.
+// @Description SIP trunk to a provider
+type SIPTrunk struct {
+	ID            int64              `gorm:"column:id;primaryKey;autoIncrement" json:"id" example:"1"`
+	TenantID      *string            `gorm:"column:tenant_id;type:varchar(64);index" json:"tenant_id,omitempty" example:"acme-corp"`
+	Name          string             `gorm:"column:name;type:varchar(128);not null" json:"name" example:"Primary carrier"`
+	EndpointID    string             `gorm:"column:endpoint_id;type:varchar(128);not null;uniqueIndex" json:"endpoint_id" example:"acme-corp-trunk-1"`
+	Host          string             `gorm:"column:host;type:varchar(255);not null" json:"host" example:"sip.example.com"`
+	Port          int                `gorm:"column:port;not null;default:5060" json:"port" example:"5060"`
+	Transport     string             `gorm:"column:transport;type:varchar(40);not null;default:transport-udp" json:"transport" example:"transport-udp"`
+	Username      *string            `gorm:"column:username;type:varchar(128)" json:"username,omitempty" example:"acme"`
+	Password      *string            `gorm:"-" json:"-"`
+	Register      bool               `gorm:"column:register;not null;default:false" json:"register" example:"true"`
+	FromUser      *string            `gorm:"column:from_user;type:varchar(128)" json:"from_user,omitempty" example:"15551234567"`
+	FromDomain    *string            `gorm:"column:from_domain;type:varchar(128)" json:"from_domain,omitempty" example:"sip.example.com"`
+	Codecs        string             `gorm:"column:codecs;type:varchar(256);not null;default:'ulaw,alaw'" json:"codecs" example:"ulaw,alaw"`
+	Context       string             `gorm:"column:context;type:varchar(128);not null;default:from-trunk" json:"context" example:"from-trunk"`
+	IdentifyMatch *string            `gorm:"column:identify_match;type:varchar(255)" json:"identify_match,omitempty" example:"203.0.113.0/24,198.51.100.10"`
+	MaxChannels   int                `gorm:"column:max_channels;not null;default:0" json:"max_channels" example:"30"`
+	Status        common.TrunkStatus `gorm:"column:status;type:enum('active','disabled');default:active;index" json:"status" example:"active"`
+	CreatedAt     time.Time          `gorm:"column:created_at;autoCreateTime" json:"created_at"`
+	UpdatedAt     time.Time          `gorm:"column:updated_at;autoUpdateTime" json:"updated_at"`
+
+	// Relations
+	Tenant *core.Tenant `gorm:"foreignKey:TenantID" json:"tenant,omitempty"`
+}
+
+// TableName specifies the table name
+func (SIPTrunk) TableName() string {
+	return "sip_trunks"
+}
+
+// IsActive checks if the trunk carries calls
+func (t *SIPTrunk) IsActive() bool {
+	return t.Status == common.TrunkStatusActive
+}
+
+// IsPlatform checks if the trunk is shared by all tenants
+func (t *SIPTrunk) IsPlatform() bool {
+	return t.TenantID == nil
+}
+
+// IdentifyMatches returns the addresses and networks inbound requests from
+// the provider are matched by
+func (t *SIPTrunk) IdentifyMatches() []string {
+	matches := []string{}
+	if t.IdentifyMatch == nil {
+		return matches
+	}
+	for _, match := range strings.Split(*t.IdentifyMatch, ",") {
+		if match = strings.TrimSpace(match); match != "" {
+			matches = append(matches, match)
+		}
+	}
+	return matches
+}
+
+// HasCapacity checks if the trunk may take another call while it has the
+// given number of channels up. A MaxChannels of 0 means no limit.
+func (t *SIPTrunk) HasCapacity(channels int) bool {
+	return t.MaxChannels <= 0 || channels < t.MaxChannels
+}
+
+// OutboundRoute sends calls to numbers starting with Prefix through a trunk,
+// falling back to a second trunk when the first is down, full or fails the
+// call. Routes without a tenant are platform routes, used when none of a
+// tenant's own routes match.
+// @Description Outbound route selecting a trunk by dialed prefix
+type OutboundRoute struct {
+	ID              int64     `gorm:"column:id;primaryKey;autoIncrement" json:"id" example:"1"`
+	TenantID        *string   `gorm:"column:tenant_id;type:varchar(64);index:idx_tenant_prefix" json:"tenant_id,omitempty" example:"acme-corp"`
+	Name            string    `gorm:"column:name;type:varchar(128);not null" json:"name" example:"UK mobiles"`
+	Prefix          string    `gorm:"column:prefix;type:varchar(32);not null;default:'';index:idx_tenant_prefix" json:"prefix" example:"+447"`
+	Priority        int       `gorm:"column:priority;not null;default:0" json:"priority" example:"0"`
+	TrunkID         int64     `gorm:"column:trunk_id;not null;index" json:"trunk_id" example:"1"`
+	FailoverTrunkID *int64    `gorm:"column:failover_trunk_id;index" json:"failover_trunk_id,omitempty" example:"2"`
+	StripDigits     int       `gorm:"column:strip_digits;not null;default:0" json:"strip_digits" example:"0"`
+	AddPrefix       string    `gorm:"column:add_prefix;type:varchar(32);not null;default:''" json:"add_prefix" example:""`
+	IsActive        bool      `gorm:"column:is_active;not null;default:true;index" json:"is_active" example:"true"`
+	CreatedAt       time.Time `gorm:"column:created_at;autoCreateTime" json:"created_at"`
+	UpdatedAt       time.Time `gorm:"column:updated_at;autoUpdateTime" json:"updated_at"`
+
+	// Relations
+	Tenant        *core.Tenant `gorm:"foreignKey:TenantID" json:"tenant,omitempty"`
+	Trunk         *SIPTrunk    `gorm:"foreignKey:TrunkID" json:"trunk,omitempty"`
+	FailoverTrunk *SIPTrunk    `gorm:"foreignKey:FailoverTrunkID" json:"failover_trunk,omitempty"`
+}
+
+// TableName specifies the table name
+func (OutboundRoute) TableName() string {
+	return "outbound_routes"
+}
+
+// Matches checks if the route takes calls to a number. An empty prefix
+// matches every number.
+func (r *OutboundRoute) Matches(number string) bool {
+	return strings.HasPrefix(number, r.Prefix)
+}
+
+// DialNumber rewrites a number the way the route's trunks expect it
+func (r *OutboundRoute) DialNumber(number string) string {
+	if r.StripDigits > 0 {
+		if r.StripDigits >= len(number) {
+			number = ""
+		} else {
+			number = number[r.StripDigits:]
+		}
+	}
+	return r.AddPrefix + number
+}
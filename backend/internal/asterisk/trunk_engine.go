@@ -0,0 +1,226 @@
+package asterisk
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+)
+
+// Asterisk module that reads outbound registrations, which only picks up
+// realtime changes when reloaded
+const registrationModule = "res_pjsip_outbound_registration.so"
+
+// Endpoint states reported by ARI
+const (
+	EndpointStateOnline  = "online"
+	EndpointStateOffline = "offline"
+	EndpointStateUnknown = "unknown"
+)
+
+// OutboundRouteLoader loads the active outbound routes a tenant's calls may
+// take: the tenant's own and the platform's, with their trunks.
+// repository.OutboundRouteRepository satisfies this interface.
+type OutboundRouteLoader interface {
+	FindForDialing(ctx context.Context, tenantID string) ([]OutboundRoute, error)
+}
+
+// trunkFailover holds the trunks left to try if a dialed trunk leg fails
+type trunkFailover struct {
+	channelID   string   // channel that dialed the leg
+	callerID    string   // caller ID the leg was dialed with
+	dialStrings []string // remaining trunks, in order
+}
+
+// SetOutboundRouteLoader sets the outbound route lookup used to pick trunks.
+// Without one, or when no route matches, external calls go through
+// RoutingConfig.TrunkEndpoint.
+func (h *CallHandler) SetOutboundRouteLoader(loader OutboundRouteLoader) {
+	h.outboundRoutes = loader
+}
+
+// TrunkState gets a trunk endpoint's state and the channels it has up
+func (h *CallHandler) TrunkState(endpointID string) (*Endpoint, error) {
+	return h.client.GetEndpoint("PJSIP", endpointID)
+}
+
+// ReloadTrunkRegistrations makes Asterisk pick up added, changed and removed
+// outbound registrations
+func (h *CallHandler) ReloadTrunkRegistrations() error {
+	return h.client.ReloadModule(registrationModule)
+}
+
+// trunkDialStrings returns the dial strings to try, in order, to reach an
+// external number for a tenant
+func (h *CallHandler) trunkDialStrings(tenantID, number string) ([]string, error) {
+	if h.outboundRoutes != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		routes, err := h.outboundRoutes.FindForDialing(ctx, tenantID)
+		cancel()
+		if err != nil {
+			return nil, fmt.Errorf("failed to load outbound routes: %w", err)
+		}
+
+		if route := selectOutboundRoute(routes, number); route != nil {
+			dialStrings := h.routeDialStrings(route, number)
+			if len(dialStrings) == 0 {
+				return nil, fmt.Errorf("no trunk of outbound route %q is available", route.Name)
+			}
+			return dialStrings, nil
+		}
+	}
+
+	if h.routing.TrunkEndpoint == "" {
+		return nil, fmt.Errorf("no outbound route matches %s", number)
+	}
+	return []string{fmt.Sprintf("PJSIP/%s@%s", number, h.routing.TrunkEndpoint)}, nil
+}
+
+// selectOutboundRoute picks the route a number is dialed by: the tenant's own
+// routes before platform routes, then the longest matching prefix, then the
+// lowest priority
+func selectOutboundRoute(routes []OutboundRoute, number string) *OutboundRoute {
+	var best *OutboundRoute
+	for i := range routes {
+		route := &routes[i]
+		if !route.IsActive || !route.Matches(number) {
+			continue
+		}
+		if best == nil || outboundRouteBefore(route, best) {
+			best = route
+		}
+	}
+	return best
+}
+
+// outboundRouteBefore checks if route a is preferred over route b
+func outboundRouteBefore(a, b *OutboundRoute) bool {
+	if (a.TenantID != nil) != (b.TenantID != nil) {
+		return a.TenantID != nil
+	}
+	if len(a.Prefix) != len(b.Prefix) {
+		return len(a.Prefix) > len(b.Prefix)
+	}
+	return a.Priority < b.Priority
+}
+
+// routeDialStrings returns the dial strings of a route's trunks that can take
+// a call now, the primary trunk first. Trunks that are disabled, that Asterisk
+// reports offline or that are at their channel limit are skipped.
+func (h *CallHandler) routeDialStrings(route *OutboundRoute, number string) []string {
+	number = route.DialNumber(number)
+
+	var dialStrings []string
+	for _, trunk := range []*SIPTrunk{route.Trunk, route.FailoverTrunk} {
+		if trunk == nil || !trunk.IsActive() {
+			continue
+		}
+
+		// A trunk whose state cannot be read is still tried
+		if state, err := h.TrunkState(trunk.EndpointID); err != nil {
+			log.Printf("Error getting state of trunk %s: %v", trunk.EndpointID, err)
+		} else if state.State == EndpointStateOffline {
+			log.Printf("Skipping trunk %s for %s: offline", trunk.EndpointID, number)
+			continue
+		} else if !trunk.HasCapacity(len(state.ChannelIDs)) {
+			log.Printf("Skipping trunk %s for %s: all %d channels in use", trunk.EndpointID, number, trunk.MaxChannels)
+			continue
+		}
+
+		dialStrings = append(dialStrings, fmt.Sprintf("PJSIP/%s@%s", number, trunk.EndpointID))
+	}
+	return dialStrings
+}
+
+// dialTrunks dials through the first trunk that accepts the call and bridges
+// the answered leg with channelID. If the leg later fails on the trunk, the
+// remaining trunks are tried in turn.
+func (h *CallHandler) dialTrunks(channelID string, dialStrings []string, callerID string) (*Channel, string, error) {
+	var lastErr error
+	for i, dialString := range dialStrings {
+		outbound, err := h.dialAndBridge(channelID, dialString, callerID)
+		if err != nil {
+			log.Printf("Error dialing %s for channel %s: %v", dialString, channelID, err)
+			lastErr = err
+			continue
+		}
+
+		if remaining := dialStrings[i+1:]; len(remaining) > 0 {
+			h.mu.Lock()
+			h.trunkFailovers[outbound.ID] = trunkFailover{
+				channelID:   channelID,
+				callerID:    callerID,
+				dialStrings: remaining,
+			}
+			h.mu.Unlock()
+		}
+		return outbound, dialString, nil
+	}
+
+	if lastErr == nil {
+		lastErr = fmt.Errorf("no trunk to dial")
+	}
+	return nil, "", lastErr
+}
+
+// originateFirst originates a channel to the first of the dial strings that
+// accepts it
+func (h *CallHandler) originateFirst(dialStrings []string, callerID, appArgs string) (*Channel, string, error) {
+	var lastErr error
+	for _, dialString := range dialStrings {
+		channel, err := h.client.OriginateChannel(dialString, callerID, h.routing.DialTimeout, appArgs)
+		if err != nil {
+			log.Printf("Error dialing %s: %v", dialString, err)
+			lastErr = fmt.Errorf("failed to dial %s: %w", dialString, err)
+			continue
+		}
+		return channel, dialString, nil
+	}
+
+	if lastErr == nil {
+		lastErr = fmt.Errorf("no trunk to dial")
+	}
+	return nil, "", lastErr
+}
+
+// takeTrunkFailover removes the failover of a destroyed leg, returning it when
+// the leg failed on its trunk and there is another trunk to try
+func (h *CallHandler) takeTrunkFailover(channelID string, cause int) (trunkFailover, bool) {
+	h.mu.Lock()
+	failover, ok := h.trunkFailovers[channelID]
+	delete(h.trunkFailovers, channelID)
+	h.mu.Unlock()
+
+	return failover, ok && trunkFailed(cause)
+}
+
+// failOverTrunk redials a call whose trunk leg failed through the next trunk
+func (h *CallHandler) failOverTrunk(failedID string, failover trunkFailover, cause int) {
+	h.mu.RLock()
+	_, alive := h.activeChannels[failover.channelID]
+	h.mu.RUnlock()
+	if !alive {
+		return
+	}
+
+	log.Printf("Trunk leg %s for %s failed (cause %d), trying the next trunk", failedID, failover.channelID, cause)
+	outbound, dialString, err := h.dialTrunks(failover.channelID, failover.dialStrings, failover.callerID)
+	if err != nil {
+		h.noteCallDisposition(failover.channelID, dispositionFromCause(cause))
+		h.onDialFailed(failedID, failover.channelID)
+		return
+	}
+
+	h.onOutboundDestRedialed(failover.channelID, failedID, outbound, dialString)
+}
+
+// trunkFailed checks if the Q.850 hangup cause of an unanswered leg means the
+// trunk could not place the call, rather than the called party not taking it
+func trunkFailed(cause int) bool {
+	switch cause {
+	case 3, 27, 34, 38, 41, 42, 47, 58:
+		return true
+	default:
+		return false
+	}
+}
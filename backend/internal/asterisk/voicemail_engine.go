@@ -0,0 +1,366 @@
+package asterisk
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/psschand/callcenter/internal/common"
+)
+
+// Voicemail recording settings
+const (
+	voicemailFormat     = "wav"
+	voicemailMaxSilence = 10 // seconds of silence that end a message
+	voicemailMinLength  = 1  // seconds; shorter messages are discarded
+	voicemailSavedSound = "vm-msgsaved"
+)
+
+// MailboxLoader loads voicemail mailboxes.
+// repository.VoicemailMailboxRepository satisfies this interface.
+type MailboxLoader interface {
+	FindByID(ctx context.Context, id int64) (*VoicemailMailbox, error)
+	FindByDID(ctx context.Context, didID int64) (*VoicemailMailbox, error)
+	FindByEndpoint(ctx context.Context, tenantID, endpoint string) (*VoicemailMailbox, error)
+}
+
+// VoicemailMessage is a message a caller left in a mailbox; the recording is
+// in the Asterisk recording directory under Name
+type VoicemailMessage struct {
+	TenantID     string
+	MailboxID    int64
+	ChannelID    string
+	CallerNumber string
+	CallerName   string
+	Name         string
+	Format       string
+	Duration     int
+}
+
+// VoicemailListener is notified of each message left
+type VoicemailListener func(message VoicemailMessage)
+
+// voicemailSession tracks a caller leaving a message
+type voicemailSession struct {
+	channel    *Channel
+	mailbox    *VoicemailMailbox
+	prompts    []string // Media still to play before the recording starts
+	playbackID string
+	greeting   bool // the greeting is playing and a digit skips it
+}
+
+// SetMailboxLoader enables the voicemail engine. DIDs routed to "voicemail"
+// take a message for the mailbox named by their target: a mailbox ID, an
+// endpoint whose owner's mailbox to use, or nothing for the DID's own mailbox.
+// Calls to an endpoint that go unanswered fall back to its owner's mailbox.
+func (h *CallHandler) SetMailboxLoader(loader MailboxLoader) {
+	h.mailboxes = loader
+	h.RegisterRouteHandler(common.RouteTypeVoicemail, h.routeToVoicemail)
+}
+
+// SetVoicemailListener sets the listener notified of new messages
+func (h *CallHandler) SetVoicemailListener(listener VoicemailListener) {
+	h.voicemailListener = listener
+}
+
+// routeToVoicemail answers the channel and takes a message for the mailbox identified by target
+func (h *CallHandler) routeToVoicemail(channel *Channel, did *DID, target string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	mailbox, err := h.loadMailbox(ctx, did, target)
+	if err != nil {
+		return err
+	}
+
+	if err := h.client.AnswerChannel(channel.ID); err != nil {
+		return err
+	}
+
+	h.startVoicemail(channel, mailbox)
+	return nil
+}
+
+// loadMailbox loads an active mailbox of the DID's tenant by ID or endpoint,
+// or the DID's own mailbox when target is empty
+func (h *CallHandler) loadMailbox(ctx context.Context, did *DID, target string) (*VoicemailMailbox, error) {
+	if h.mailboxes == nil {
+		return nil, fmt.Errorf("voicemail engine not configured")
+	}
+
+	var mailbox *VoicemailMailbox
+	var err error
+	if target == "" {
+		mailbox, err = h.mailboxes.FindByDID(ctx, did.ID)
+	} else if id, parseErr := strconv.ParseInt(target, 10, 64); parseErr == nil {
+		mailbox, err = h.mailboxes.FindByID(ctx, id)
+	} else {
+		mailbox, err = h.mailboxes.FindByEndpoint(ctx, did.TenantID, target)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("mailbox %q not found: %w", target, err)
+	}
+	if mailbox.TenantID != did.TenantID {
+		return nil, fmt.Errorf("mailbox %q not found", target)
+	}
+	if !mailbox.IsActive {
+		return nil, fmt.Errorf("mailbox %q is inactive", target)
+	}
+
+	return mailbox, nil
+}
+
+// startVoicemail plays a mailbox's greeting to a caller, then records their message
+func (h *CallHandler) startVoicemail(channel *Channel, mailbox *VoicemailMailbox) {
+	log.Printf("Channel %s leaving a message in mailbox %d (%s)", channel.ID, mailbox.ID, mailbox.Name)
+
+	h.mu.Lock()
+	h.voicemailSessions[channel.ID] = &voicemailSession{
+		channel:  channel,
+		mailbox:  mailbox,
+		prompts:  []string{mailbox.GreetingMedia()},
+		greeting: true,
+	}
+	h.mu.Unlock()
+
+	h.playNextVoicemailPrompt(channel.ID)
+}
+
+// playNextVoicemailPrompt plays the next queued prompt, or starts recording when there is none
+func (h *CallHandler) playNextVoicemailPrompt(channelID string) {
+	h.mu.Lock()
+	session, ok := h.voicemailSessions[channelID]
+	if !ok {
+		h.mu.Unlock()
+		return
+	}
+	if len(session.prompts) == 0 {
+		h.mu.Unlock()
+		h.recordVoicemail(channelID)
+		return
+	}
+	media := session.prompts[0]
+	session.prompts = session.prompts[1:]
+	h.mu.Unlock()
+
+	playback, err := h.client.PlayMedia(channelID, media)
+	if err != nil {
+		log.Printf("Error playing voicemail prompt %s on %s: %v", media, channelID, err)
+		h.playNextVoicemailPrompt(channelID)
+		return
+	}
+
+	h.mu.Lock()
+	if session, ok := h.voicemailSessions[channelID]; ok {
+		session.playbackID = playback.ID
+		h.voicemailPlaybacks[playback.ID] = channelID
+	}
+	h.mu.Unlock()
+}
+
+// onVoicemailPlaybackFinished continues to the recording; it reports whether the playback belonged to a mailbox
+func (h *CallHandler) onVoicemailPlaybackFinished(playbackID string) bool {
+	h.mu.Lock()
+	channelID, ok := h.voicemailPlaybacks[playbackID]
+	delete(h.voicemailPlaybacks, playbackID)
+	if ok {
+		if session, exists := h.voicemailSessions[channelID]; exists && session.playbackID == playbackID {
+			session.playbackID = ""
+		}
+	}
+	h.mu.Unlock()
+
+	if ok {
+		h.playNextVoicemailPrompt(channelID)
+	}
+	return ok
+}
+
+// onVoicemailDigit lets the caller skip the greeting; it reports whether the channel is leaving a message
+func (h *CallHandler) onVoicemailDigit(channelID string) bool {
+	h.mu.Lock()
+	session, ok := h.voicemailSessions[channelID]
+	if !ok {
+		h.mu.Unlock()
+		return false
+	}
+	playbackID := ""
+	if session.greeting {
+		// Stopping the greeting moves on to the recording
+		playbackID = session.playbackID
+		session.prompts = nil
+		session.greeting = false
+	}
+	h.mu.Unlock()
+
+	if playbackID != "" {
+		h.client.StopPlayback(playbackID)
+	}
+	return true
+}
+
+// recordVoicemail records the caller's message into the session's mailbox
+func (h *CallHandler) recordVoicemail(channelID string) {
+	h.mu.Lock()
+	session, ok := h.voicemailSessions[channelID]
+	if !ok {
+		h.mu.Unlock()
+		return
+	}
+	session.greeting = false
+	mailbox := session.mailbox
+	message := VoicemailMessage{
+		TenantID:     mailbox.TenantID,
+		MailboxID:    mailbox.ID,
+		ChannelID:    channelID,
+		CallerNumber: session.channel.Caller.Number,
+		CallerName:   session.channel.Caller.Name,
+		Name:         VoicemailName(mailbox.TenantID, mailbox.ID, channelID),
+		Format:       voicemailFormat,
+	}
+	h.voicemailRecordings[message.Name] = message
+	h.mu.Unlock()
+
+	if _, err := h.client.RecordMessage(channelID, message.Name, message.Format, mailbox.MessageLength(), voicemailMaxSilence); err != nil {
+		log.Printf("Error recording voicemail on %s: %v", channelID, err)
+		h.mu.Lock()
+		delete(h.voicemailRecordings, message.Name)
+		h.mu.Unlock()
+		h.endVoicemailSession(channelID)
+		h.client.HangupChannel(channelID)
+		return
+	}
+
+	log.Printf("Recording voicemail for mailbox %d from %s as %s", message.MailboxID, channelID, message.Name)
+}
+
+// onVoicemailRecorded passes a finished message to the listener and thanks a
+// caller who is still on the line; it reports whether the recording was a message
+func (h *CallHandler) onVoicemailRecorded(event ARIEvent) bool {
+	recording := event.Recording
+
+	h.mu.Lock()
+	message, ok := h.voicemailRecordings[recording.Name]
+	delete(h.voicemailRecordings, recording.Name)
+	h.mu.Unlock()
+	if !ok {
+		return false
+	}
+
+	message.Duration = recording.Duration
+	if recording.Format != "" {
+		message.Format = recording.Format
+	}
+
+	switch {
+	case event.Type == EventRecordingFailed:
+		log.Printf("Voicemail %s failed: %s", recording.Name, recording.Cause)
+	case message.Duration < voicemailMinLength:
+		log.Printf("Voicemail %s discarded: too short", recording.Name)
+	default:
+		log.Printf("Voicemail %s left in mailbox %d (%ds)", recording.Name, message.MailboxID, message.Duration)
+		if h.voicemailListener != nil {
+			go h.voicemailListener(message)
+		}
+	}
+
+	// The caller pressed # or fell silent rather than hanging up
+	if h.endVoicemailSession(message.ChannelID) {
+		if err := h.announceAndHangup(message.ChannelID, voicemailSavedSound); err != nil {
+			h.client.HangupChannel(message.ChannelID)
+		}
+	}
+	return true
+}
+
+// leaveVoicemailOnNoAnswer sends a caller whose call to an endpoint went
+// unanswered to the mailbox of the endpoint's owner; it reports whether it did
+func (h *CallHandler) leaveVoicemailOnNoAnswer(channelID string) bool {
+	h.mu.Lock()
+	endpoint, ok := h.voicemailFallbacks[channelID]
+	delete(h.voicemailFallbacks, channelID)
+	channel, active := h.activeChannels[channelID]
+	call, known := h.calls[channelID]
+	h.mu.Unlock()
+	if !ok || !active || !known || h.mailboxes == nil {
+		return false
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	did := call.DID
+	if did == nil {
+		did = &DID{TenantID: call.TenantID}
+	}
+	mailbox, err := h.loadMailbox(ctx, did, endpoint)
+	if err != nil {
+		return false
+	}
+
+	h.client.SetChannelVariable(channelID, VarRoute, fmt.Sprintf("%s:%s", common.RouteTypeVoicemail, endpoint))
+	h.noteCallRoute(channelID, common.RouteTypeVoicemail, endpoint)
+	if err := h.client.AnswerChannel(channelID); err != nil {
+		log.Printf("Error answering channel %s for voicemail: %v", channelID, err)
+		return false
+	}
+	h.startVoicemail(channel, mailbox)
+	return true
+}
+
+// endVoicemailSession removes a channel's voicemail session, stopping any
+// prompt, and reports whether it had one. A message being recorded is still
+// reported once Asterisk has finished writing it.
+func (h *CallHandler) endVoicemailSession(channelID string) bool {
+	h.mu.Lock()
+	delete(h.voicemailFallbacks, channelID)
+	session, ok := h.voicemailSessions[channelID]
+	if ok {
+		delete(h.voicemailSessions, channelID)
+		delete(h.voicemailPlaybacks, session.playbackID)
+	}
+	h.mu.Unlock()
+	return ok
+}
+
+// resyncVoicemail ends the voicemail sessions of callers who hung up while
+// ARI was disconnected. Messages they were recording are given up: the event
+// reporting how long they ran was missed.
+func (h *CallHandler) resyncVoicemail(channels map[string]bool) {
+	h.mu.Lock()
+	var gone []string
+	for channelID := range h.voicemailSessions {
+		if !channels[channelID] {
+			gone = append(gone, channelID)
+		}
+	}
+	for channelID := range h.voicemailFallbacks {
+		if !channels[channelID] {
+			delete(h.voicemailFallbacks, channelID)
+		}
+	}
+	for name, message := range h.voicemailRecordings {
+		if !channels[message.ChannelID] {
+			delete(h.voicemailRecordings, name)
+			log.Printf("Voicemail %s lost: the call ended while ARI was disconnected", name)
+		}
+	}
+	h.mu.Unlock()
+
+	for _, channelID := range gone {
+		h.endVoicemailSession(channelID)
+	}
+}
+
+// VoicemailName returns the name a message is recorded under
+func VoicemailName(tenantID string, mailboxID int64, uniqueID string) string {
+	return fmt.Sprintf("vm-%s-%d-%s", tenantID, mailboxID, strings.ReplaceAll(uniqueID, ".", "-"))
+}
+
+// GreetingName returns the name a mailbox's uploaded greeting is stored under
+func GreetingName(tenantID string, mailboxID int64) string {
+	return fmt.Sprintf("greeting-%s-%d", tenantID, mailboxID)
+}
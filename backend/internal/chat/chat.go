@@ -40,6 +40,7 @@ type ChatWidget struct {
 	// Business hours
 	BusinessHoursEnabled bool    `gorm:"column:business_hours_enabled;default:false" json:"business_hours_enabled" example:"true"`
 	BusinessHours        *string `gorm:"column:business_hours;type:json" json:"business_hours,omitempty"`
+	ScheduleID           *int64  `gorm:"column:schedule_id" json:"schedule_id,omitempty" example:"1"`
 	OfflineMessage       *string `gorm:"column:offline_message;type:text" json:"offline_message,omitempty" example:"We're currently offline. Leave a message and we'll get back to you!"`
 
 	// Security
@@ -77,9 +78,11 @@ func (cw *ChatWidget) GetEmbedCode() string {
 type ChatSession struct {
 	ID         int64                    `gorm:"column:id;primaryKey;autoIncrement" json:"id" example:"1"`
 	TenantID   string                   `gorm:"column:tenant_id;type:varchar(64);not null;index:idx_tenant" json:"tenant_id" example:"acme-corp"`
-	WidgetID   int64                    `gorm:"column:widget_id;not null;index:idx_widget" json:"widget_id" example:"1"`
+	WidgetID   *int64                   `gorm:"column:widget_id;index:idx_widget" json:"widget_id,omitempty" example:"1"` // nil for SMS sessions
 	SessionKey string                   `gorm:"column:session_key;type:varchar(64);not null;uniqueIndex" json:"session_key" example:"sess_xyz789"`
 	Status     common.ChatSessionStatus `gorm:"column:status;type:enum('active','queued','ended','abandoned');default:active;index:idx_status" json:"status" example:"active"`
+	Channel    common.ChatChannel       `gorm:"column:channel;type:enum('web','sms');default:web;index:idx_channel" json:"channel" example:"web"`
+	DIDID      *int64                   `gorm:"column:did_id;index:idx_did" json:"did_id,omitempty" example:"1"` // number an SMS session is held on
 
 	// Visitor info
 	VisitorName  *string `gorm:"column:visitor_name;type:varchar(255)" json:"visitor_name,omitempty" example:"Jane Visitor"`
@@ -103,6 +106,8 @@ type ChatSession struct {
 	EndedAt   *time.Time `gorm:"column:ended_at" json:"ended_at,omitempty"`
 	Duration  *int       `gorm:"column:duration" json:"duration,omitempty" example:"180"` // seconds
 
+	WrapupTime *int `gorm:"column:wrapup_time" json:"wrapup_time,omitempty" example:"30"` // seconds the agent spent in wrap-up
+
 	// Ratings
 	Rating        *int    `gorm:"column:rating" json:"rating,omitempty" example:"5"`
 	RatingComment *string `gorm:"column:rating_comment;type:text" json:"rating_comment,omitempty"`
@@ -129,6 +134,11 @@ func (cs *ChatSession) IsActive() bool {
 	return cs.Status == common.ChatSessionStatusActive
 }
 
+// IsSMS checks if the session is an SMS conversation, whose replies go out as SMS
+func (cs *ChatSession) IsSMS() bool {
+	return cs.Channel == common.ChatChannelSMS
+}
+
 // IsEnded checks if session has ended
 func (cs *ChatSession) IsEnded() bool {
 	return cs.Status == common.ChatSessionStatusEnded
@@ -169,6 +179,8 @@ type ChatMessage struct {
 	AttachmentType *string                `gorm:"column:attachment_type;type:varchar(100)" json:"attachment_type,omitempty" example:"image/png"`
 	IsRead         bool                   `gorm:"column:is_read;default:false;index:idx_read" json:"is_read" example:"true"`
 	ReadAt         *time.Time             `gorm:"column:read_at" json:"read_at,omitempty"`
+	SMSMessageID   *int64                 `gorm:"column:sms_message_id;index:idx_sms_message" json:"sms_message_id,omitempty" example:"1"`
+	DeliveryStatus *common.SMSStatus      `gorm:"column:delivery_status;type:enum('pending','queued','sent','delivered','failed','received')" json:"delivery_status,omitempty" example:"delivered"`
 	Metadata       common.JSONMap         `gorm:"column:metadata;type:json" json:"metadata,omitempty"`
 	CreatedAt      time.Time              `gorm:"column:created_at;autoCreateTime;index:idx_created" json:"created_at"`
 
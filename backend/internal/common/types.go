@@ -46,6 +46,14 @@ const (
 	DIDStatusPending  DIDStatus = "pending"
 )
 
+// TrunkStatus represents whether a SIP trunk carries calls
+type TrunkStatus string
+
+const (
+	TrunkStatusActive   TrunkStatus = "active"
+	TrunkStatusDisabled TrunkStatus = "disabled"
+)
+
 // RouteType represents how incoming calls should be routed
 type RouteType string
 
@@ -58,12 +66,39 @@ const (
 	RouteTypeVoicemail RouteType = "voicemail"
 )
 
+// ScheduleType represents how a schedule decides when it is open
+type ScheduleType string
+
+const (
+	ScheduleTypeBusinessHours ScheduleType = "business_hours" // open during its weekly hours
+	ScheduleTypeHolidays      ScheduleType = "holidays"       // open except on its exception dates
+)
+
+// BlockTreatment represents what happens to a call from a blocked number
+type BlockTreatment string
+
+const (
+	BlockTreatmentReject    BlockTreatment = "reject"
+	BlockTreatmentBusy      BlockTreatment = "busy"
+	BlockTreatmentMessage   BlockTreatment = "message"
+	BlockTreatmentVoicemail BlockTreatment = "voicemail"
+)
+
+// BlockedSource represents whether a blocked attempt was a call or an SMS
+type BlockedSource string
+
+const (
+	BlockedSourceCall BlockedSource = "call"
+	BlockedSourceSMS  BlockedSource = "sms"
+)
+
 // AgentStatus represents the status of an agent
 type AgentStatus string
 
 const (
 	AgentStatusAvailable AgentStatus = "available"
 	AgentStatusBusy      AgentStatus = "busy"
+	AgentStatusWrapup    AgentStatus = "wrapup"
 	AgentStatusAway      AgentStatus = "away"
 	AgentStatusBreak     AgentStatus = "break"
 	AgentStatusOffline   AgentStatus = "offline"
@@ -77,6 +112,7 @@ type AgentState = AgentStatus
 const (
 	AgentStateAvailable = AgentStatusAvailable
 	AgentStateBusy      = AgentStatusBusy
+	AgentStateWrapup    = AgentStatusWrapup
 	AgentStateAway      = AgentStatusAway
 	AgentStateBreak     = AgentStatusBreak
 	AgentStateOffline   = AgentStatusOffline
@@ -132,6 +168,40 @@ const (
 	RecordingStatusDeleted   RecordingStatus = "deleted"
 )
 
+// SurveyQuestionType represents how a caller answers a survey question
+type SurveyQuestionType string
+
+const (
+	SurveyQuestionRating SurveyQuestionType = "rating" // a number keyed in, from min to max
+	SurveyQuestionYesNo  SurveyQuestionType = "yes_no" // 1 for yes, 2 for no
+)
+
+// SurveyMetric represents the score a survey question feeds
+type SurveyMetric string
+
+const (
+	SurveyMetricCSAT SurveyMetric = "csat"
+	SurveyMetricNPS  SurveyMetric = "nps"
+)
+
+// TranscriptSource represents what a transcript was made from
+type TranscriptSource string
+
+const (
+	TranscriptSourceRecording TranscriptSource = "recording"
+	TranscriptSourceVoicemail TranscriptSource = "voicemail"
+)
+
+// TranscriptionStatus represents the status of a transcription job
+type TranscriptionStatus string
+
+const (
+	TranscriptionStatusPending    TranscriptionStatus = "pending"
+	TranscriptionStatusProcessing TranscriptionStatus = "processing"
+	TranscriptionStatusCompleted  TranscriptionStatus = "completed"
+	TranscriptionStatusFailed     TranscriptionStatus = "failed"
+)
+
 // TicketStatus represents the status of a helpdesk ticket
 type TicketStatus string
 
@@ -174,6 +244,14 @@ const (
 	ChatSessionStatusAbandoned ChatSessionStatus = "abandoned"
 )
 
+// ChatChannel represents the channel a chat session came in on
+type ChatChannel string
+
+const (
+	ChatChannelWeb ChatChannel = "web"
+	ChatChannelSMS ChatChannel = "sms"
+)
+
 // JSONMap is a helper type for JSON metadata fields
 type JSONMap map[string]interface{}
 
@@ -274,6 +352,16 @@ type TenantSettings struct {
 	ChatEnabled     bool   `json:"chat_enabled"`
 	HelpdeskEnabled bool   `json:"helpdesk_enabled"`
 	BusinessHours   string `json:"business_hours"`
+
+	// Days recordings and voicemails are kept before being deleted; 0 keeps them forever
+	RecordingRetentionDays int `json:"recording_retention_days"`
+	VoicemailRetentionDays int `json:"voicemail_retention_days"`
+
+	// Seconds of wrap-up after calls outside queues and after chats; 0 makes agents available straight away
+	WrapupTime int `json:"wrapup_time"`
+
+	// Transcribe call recordings and voicemails with the configured speech-to-text provider
+	Transcription bool `json:"transcription"`
 }
 
 // Value implements driver.Valuer interface
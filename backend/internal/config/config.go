@@ -1,7 +1,11 @@
 package config
 
 import (
+	"crypto/hkdf"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
+	"log"
 	"os"
 	"strconv"
 	"strings"
@@ -10,6 +14,9 @@ import (
 	"github.com/joho/godotenv"
 )
 
+// storageSigningKeyInfo labels the storage signing key derived from the JWT secret
+const storageSigningKeyInfo = "callcenter storage download url signing"
+
 // Config holds all application configuration
 type Config struct {
 	Server    ServerConfig
@@ -22,6 +29,11 @@ type Config struct {
 	RateLimit RateLimitConfig
 	Logging   LoggingConfig
 	Redis     RedisConfig
+	Storage   StorageConfig
+
+	Transcription TranscriptionConfig
+	SMS           SMSConfig
+	Softphone     SoftphoneConfig
 }
 
 // ServerConfig holds server configuration
@@ -63,6 +75,19 @@ type AsteriskConfig struct {
 	Username string
 	Password string
 	AppName  string
+
+	// Inbound call routing
+	TrunkEndpoint        string
+	DialTimeout          int
+	UnknownDIDTreatment  string
+	InactiveDIDTreatment string
+	RejectSound          string
+
+	// Directory Asterisk writes call recordings to, shared with the API
+	RecordingPath string
+
+	// File the DID dialplan is generated into, included by extensions.conf
+	DialplanPath string
 }
 
 // WebSocketConfig holds WebSocket configuration
@@ -102,6 +127,75 @@ type RedisConfig struct {
 	DB       int
 }
 
+// StorageConfig holds recording and voicemail storage configuration
+type StorageConfig struct {
+	Backend   string // local or s3
+	LocalPath string
+
+	// S3-compatible object store
+	S3Endpoint  string
+	S3Region    string
+	S3Bucket    string
+	S3AccessKey string
+	S3SecretKey string
+	S3PathStyle bool
+
+	// Signed download URLs
+	SigningKey      string
+	PublicURL       string
+	SignedURLExpiry time.Duration
+
+	// Background archiving
+	UploadInterval    time.Duration
+	PurgeSpool        bool
+	RetentionInterval time.Duration
+}
+
+// TranscriptionConfig holds speech-to-text configuration
+type TranscriptionConfig struct {
+	Provider string // none, local or whisper
+	Language string // used for tenants without a language setting; empty lets the provider detect it
+
+	// OpenAI-compatible transcription API (whisper provider)
+	URL     string
+	APIKey  string
+	Model   string
+	Timeout time.Duration
+
+	// Background job queue
+	Workers      int
+	PollInterval time.Duration
+	MaxAttempts  int
+}
+
+// SMSConfig holds SMS provider configuration
+type SMSConfig struct {
+	Provider    string  // none, fake or twilio
+	CallbackURL string  // public URL of the SMS webhooks, e.g. https://cc.example.com/api/v1/sms/webhooks
+	SegmentCost float64 // charged per message segment
+
+	// Twilio-compatible messaging API
+	APIURL     string
+	AccountSID string
+	AuthToken  string
+}
+
+// SoftphoneConfig holds the settings of the PJSIP endpoints provisioned for
+// agents' WebRTC softphones
+type SoftphoneConfig struct {
+	WSSURL      string // WebSocket URL softphones register over, e.g. wss://pbx.example.com:8089/ws
+	Domain      string // SIP domain of softphone URIs
+	Transport   string
+	Context     string
+	Codecs      string
+	MaxContacts int
+
+	// ICE servers given to softphones; turn: and turns: URLs use the TURN credentials
+	ICEServers     []string
+	TURNUsername   string
+	TURNCredential string
+}
+
 // Load loads configuration from environment variables
 func Load() (*Config, error) {
 	// Load .env file if it exists (ignore error if not found)
@@ -138,6 +232,15 @@ func Load() (*Config, error) {
 			Username: getEnv("ASTERISK_ARI_USERNAME", "asterisk"),
 			Password: getEnv("ASTERISK_ARI_PASSWORD", "asterisk"),
 			AppName:  getEnv("ASTERISK_ARI_APP", "callcenter"),
+
+			TrunkEndpoint:        getEnv("ASTERISK_TRUNK_ENDPOINT", "twilio_trunk"),
+			DialTimeout:          getEnvAsInt("ASTERISK_DIAL_TIMEOUT", 30),
+			UnknownDIDTreatment:  getEnv("ASTERISK_UNKNOWN_DID_TREATMENT", "announce"),
+			InactiveDIDTreatment: getEnv("ASTERISK_INACTIVE_DID_TREATMENT", "announce"),
+			RejectSound:          getEnv("ASTERISK_REJECT_SOUND", "ss-noservice"),
+
+			RecordingPath: getEnv("ASTERISK_RECORDING_PATH", "/var/spool/asterisk/recording"),
+			DialplanPath:  getEnv("ASTERISK_DIALPLAN_PATH", "/etc/asterisk/generated/extensions_callcenter.conf"),
 		},
 		WebSocket: WebSocketConfig{
 			ReadBufferSize:  getEnvAsInt("WS_READ_BUFFER_SIZE", 1024),
@@ -166,6 +269,59 @@ func Load() (*Config, error) {
 			Password: getEnv("REDIS_PASSWORD", ""),
 			DB:       getEnvAsInt("REDIS_DB", 0),
 		},
+		Storage: StorageConfig{
+			Backend:   getEnv("STORAGE_BACKEND", "local"),
+			LocalPath: getEnv("STORAGE_LOCAL_PATH", "./storage"),
+
+			S3Endpoint:  getEnv("STORAGE_S3_ENDPOINT", ""),
+			S3Region:    getEnv("STORAGE_S3_REGION", "us-east-1"),
+			S3Bucket:    getEnv("STORAGE_S3_BUCKET", ""),
+			S3AccessKey: getEnv("STORAGE_S3_ACCESS_KEY", ""),
+			S3SecretKey: getEnv("STORAGE_S3_SECRET_KEY", ""),
+			S3PathStyle: getEnvAsBool("STORAGE_S3_PATH_STYLE", true),
+
+			SigningKey:      getEnv("STORAGE_SIGNING_KEY", ""),
+			PublicURL:       getEnv("STORAGE_PUBLIC_URL", ""),
+			SignedURLExpiry: getEnvAsDuration("STORAGE_SIGNED_URL_EXPIRY", 15*time.Minute),
+
+			UploadInterval:    getEnvAsDuration("STORAGE_UPLOAD_INTERVAL", 1*time.Minute),
+			PurgeSpool:        getEnvAsBool("STORAGE_PURGE_SPOOL", true),
+			RetentionInterval: getEnvAsDuration("STORAGE_RETENTION_INTERVAL", 1*time.Hour),
+		},
+		Transcription: TranscriptionConfig{
+			Provider: getEnv("TRANSCRIPTION_PROVIDER", "none"),
+			Language: getEnv("TRANSCRIPTION_LANGUAGE", ""),
+
+			URL:     getEnv("TRANSCRIPTION_URL", "https://api.openai.com/v1"),
+			APIKey:  getEnv("TRANSCRIPTION_API_KEY", ""),
+			Model:   getEnv("TRANSCRIPTION_MODEL", "whisper-1"),
+			Timeout: getEnvAsDuration("TRANSCRIPTION_TIMEOUT", 10*time.Minute),
+
+			Workers:      getEnvAsInt("TRANSCRIPTION_WORKERS", 2),
+			PollInterval: getEnvAsDuration("TRANSCRIPTION_POLL_INTERVAL", 15*time.Second),
+			MaxAttempts:  getEnvAsInt("TRANSCRIPTION_MAX_ATTEMPTS", 3),
+		},
+		SMS: SMSConfig{
+			Provider:    getEnv("SMS_PROVIDER", "none"),
+			CallbackURL: getEnv("SMS_CALLBACK_URL", ""),
+			SegmentCost: getEnvAsFloat("SMS_SEGMENT_COST", 0),
+
+			APIURL:     getEnv("SMS_API_URL", "https://api.twilio.com"),
+			AccountSID: getEnv("SMS_ACCOUNT_SID", ""),
+			AuthToken:  getEnv("SMS_AUTH_TOKEN", ""),
+		},
+		Softphone: SoftphoneConfig{
+			WSSURL:      getEnv("SOFTPHONE_WSS_URL", "wss://localhost:8089/ws"),
+			Domain:      getEnv("SOFTPHONE_SIP_DOMAIN", "localhost"),
+			Transport:   getEnv("SOFTPHONE_TRANSPORT", "transport-wss"),
+			Context:     getEnv("SOFTPHONE_CONTEXT", "agents"),
+			Codecs:      getEnv("SOFTPHONE_CODECS", "opus,ulaw,alaw"),
+			MaxContacts: getEnvAsInt("SOFTPHONE_MAX_CONTACTS", 2),
+
+			ICEServers:     getEnvAsSlice("SOFTPHONE_ICE_SERVERS", []string{"stun:stun.l.google.com:19302"}),
+			TURNUsername:   getEnv("SOFTPHONE_TURN_USERNAME", ""),
+			TURNCredential: getEnv("SOFTPHONE_TURN_CREDENTIAL", ""),
+		},
 	}
 
 	// Validate required fields
@@ -173,6 +329,17 @@ func Load() (*Config, error) {
 		return nil, fmt.Errorf("JWT_SECRET is required")
 	}
 
+	// Signed download URLs must not share a key with JWTs; without a key of
+	// their own they get one derived from the JWT secret
+	if cfg.Storage.SigningKey == "" {
+		key, err := hkdf.Key(sha256.New, []byte(cfg.JWT.Secret), nil, storageSigningKeyInfo, 32)
+		if err != nil {
+			return nil, fmt.Errorf("failed to derive storage signing key: %w", err)
+		}
+		cfg.Storage.SigningKey = hex.EncodeToString(key)
+		log.Printf("Warning: STORAGE_SIGNING_KEY is not set, deriving the download URL signing key from JWT_SECRET")
+	}
+
 	if cfg.Database.Password == "" && cfg.Server.Env == "production" {
 		return nil, fmt.Errorf("DB_PASSWORD is required in production")
 	}
@@ -236,6 +403,14 @@ func getEnvAsInt64(key string, defaultValue int64) int64 {
 	return defaultValue
 }
 
+func getEnvAsFloat(key string, defaultValue float64) float64 {
+	valueStr := getEnv(key, "")
+	if value, err := strconv.ParseFloat(valueStr, 64); err == nil {
+		return value
+	}
+	return defaultValue
+}
+
 func getEnvAsBool(key string, defaultValue bool) bool {
 	valueStr := getEnv(key, "")
 	if value, err := strconv.ParseBool(valueStr); err == nil {
@@ -0,0 +1,312 @@
+package core
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/psschand/callcenter/internal/common"
+)
+
+// scheduleSearchDays is how far ahead NextOpen and NextClose look
+const scheduleSearchDays = 400
+
+// Schedule is a tenant's opening hours in a timezone. Business hours
+// schedules are open during their weekly hours; holiday schedules are open
+// all day every day. On an exception date either kind follows the
+// exception instead.
+// @Description Business hours or holiday schedule
+type Schedule struct {
+	ID          int64               `gorm:"column:id;primaryKey;autoIncrement" json:"id" example:"1"`
+	TenantID    string              `gorm:"column:tenant_id;type:varchar(36);not null;index" json:"tenant_id" example:"acme-corp"`
+	Name        string              `gorm:"column:name;type:varchar(255);not null" json:"name" example:"Support hours"`
+	Description *string             `gorm:"column:description;type:text" json:"description,omitempty" example:"Weekdays 9 to 5"`
+	Timezone    string              `gorm:"column:timezone;type:varchar(100);not null;default:UTC" json:"timezone" example:"America/New_York"`
+	Type        common.ScheduleType `gorm:"column:schedule_type;type:varchar(50);not null;default:business_hours;index" json:"schedule_type" example:"business_hours"`
+	Rules       ScheduleRules       `gorm:"column:rules;type:json;not null" json:"rules"`
+	IsActive    bool                `gorm:"column:is_active;not null;default:true;index" json:"is_active" example:"true"`
+	CreatedAt   time.Time           `gorm:"column:created_at;autoCreateTime" json:"created_at"`
+	UpdatedAt   time.Time           `gorm:"column:updated_at;autoUpdateTime" json:"updated_at"`
+
+	// Relations
+	Tenant *Tenant `gorm:"foreignKey:TenantID" json:"tenant,omitempty"`
+}
+
+// TableName specifies the table name
+func (Schedule) TableName() string {
+	return "schedules"
+}
+
+// ScheduleRules holds a schedule's weekly hours and its exception dates
+type ScheduleRules struct {
+	Weekly     []ScheduleHours     `json:"weekly"`
+	Exceptions []ScheduleException `json:"exceptions"`
+}
+
+// ScheduleHours is a span of opening hours in "15:04" form. Close may be
+// "24:00" for hours running to midnight; Day is a lowercase weekday name and
+// is only used in weekly hours.
+type ScheduleHours struct {
+	Day   string `json:"day,omitempty" example:"monday"`
+	Open  string `json:"open" example:"09:00"`
+	Close string `json:"close" example:"17:00"`
+}
+
+// ScheduleException overrides a schedule on one date, "2006-01-02", or
+// every year on a date, "01-02". A closed exception is closed all day; one
+// with hours is open only then; any other is open all day.
+type ScheduleException struct {
+	Date   string          `json:"date" example:"12-25"`
+	Name   string          `json:"name,omitempty" example:"Christmas Day"`
+	Closed bool            `json:"closed" example:"true"`
+	Hours  []ScheduleHours `json:"hours,omitempty"`
+}
+
+// Value implements driver.Valuer interface
+func (r ScheduleRules) Value() (driver.Value, error) {
+	return json.Marshal(r)
+}
+
+// Scan implements sql.Scanner interface
+func (r *ScheduleRules) Scan(value interface{}) error {
+	if value == nil {
+		return nil
+	}
+	bytes, ok := value.([]byte)
+	if !ok {
+		return errors.New("type assertion to []byte failed")
+	}
+	return json.Unmarshal(bytes, r)
+}
+
+// Validate checks the rules' days, dates and hours
+func (r *ScheduleRules) Validate() error {
+	for i, hours := range r.Weekly {
+		if _, ok := weekdays[strings.ToLower(hours.Day)]; !ok {
+			return fmt.Errorf("weekly hours %d: unknown day %q", i+1, hours.Day)
+		}
+		if _, err := hours.span(); err != nil {
+			return fmt.Errorf("weekly hours %d: %w", i+1, err)
+		}
+	}
+
+	seen := map[string]bool{}
+	for i, exception := range r.Exceptions {
+		if !validExceptionDate(exception.Date) {
+			return fmt.Errorf("exception %d: date %q must be YYYY-MM-DD or MM-DD", i+1, exception.Date)
+		}
+		if seen[exception.Date] {
+			return fmt.Errorf("exception %d: date %s is listed twice", i+1, exception.Date)
+		}
+		seen[exception.Date] = true
+		if exception.Closed && len(exception.Hours) > 0 {
+			return fmt.Errorf("exception %d: a closed exception cannot have hours", i+1)
+		}
+		for _, hours := range exception.Hours {
+			if _, err := hours.span(); err != nil {
+				return fmt.Errorf("exception %d: %w", i+1, err)
+			}
+		}
+	}
+	return nil
+}
+
+// Location returns the schedule's timezone, or UTC if it is not known
+func (s *Schedule) Location() *time.Location {
+	loc, err := time.LoadLocation(s.Timezone)
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}
+
+// IsOpen checks if the schedule is open at a moment
+func (s *Schedule) IsOpen(at time.Time) bool {
+	local := at.In(s.Location())
+	minute := local.Hour()*60 + local.Minute()
+	for _, span := range s.spansOn(local) {
+		if minute >= span.open && minute < span.close {
+			return true
+		}
+	}
+	return false
+}
+
+// NextOpen returns when the schedule is next open from a moment on: the
+// moment itself if it is open then. It returns false if the schedule does
+// not open within the next year or so.
+func (s *Schedule) NextOpen(at time.Time) (time.Time, bool) {
+	if s.IsOpen(at) {
+		return at, true
+	}
+
+	loc := s.Location()
+	local := at.In(loc)
+	for day := 0; day < scheduleSearchDays; day++ {
+		date := time.Date(local.Year(), local.Month(), local.Day()+day, 0, 0, 0, 0, loc)
+		for _, span := range s.spansOn(date) {
+			if t := clockTime(date, span.open); t.After(at) {
+				return t, true
+			}
+		}
+	}
+	return time.Time{}, false
+}
+
+// NextClose returns when the schedule next closes after a moment it is
+// open. It returns false if the schedule is closed then or stays open for
+// the next year or so.
+func (s *Schedule) NextClose(at time.Time) (time.Time, bool) {
+	if !s.IsOpen(at) {
+		return time.Time{}, false
+	}
+
+	loc := s.Location()
+	local := at.In(loc)
+	minute := local.Hour()*60 + local.Minute()
+	for day := 0; day < scheduleSearchDays; day++ {
+		date := time.Date(local.Year(), local.Month(), local.Day()+day, 0, 0, 0, 0, loc)
+		open := false
+		for _, span := range s.spansOn(date) {
+			if minute >= span.open && minute < span.close {
+				if span.close < 24*60 {
+					return clockTime(date, span.close), true
+				}
+				open = true
+			}
+		}
+		// Hours running to midnight carry on into the next day's
+		if !open {
+			return date, true
+		}
+		minute = 0
+	}
+	return time.Time{}, false
+}
+
+// clockSpan is a span of minutes after midnight, close exclusive
+type clockSpan struct {
+	open, close int
+}
+
+// spansOn returns the opening hours on a local date, in order
+func (s *Schedule) spansOn(date time.Time) []clockSpan {
+	var spans []clockSpan
+	if exception := s.exceptionOn(date); exception != nil {
+		if exception.Closed {
+			return nil
+		}
+		if len(exception.Hours) == 0 {
+			return []clockSpan{{0, 24 * 60}}
+		}
+		for _, hours := range exception.Hours {
+			if span, err := hours.span(); err == nil {
+				spans = append(spans, span)
+			}
+		}
+	} else if s.Type == common.ScheduleTypeHolidays {
+		return []clockSpan{{0, 24 * 60}}
+	} else {
+		day := strings.ToLower(date.Weekday().String())
+		for _, hours := range s.Rules.Weekly {
+			if strings.ToLower(hours.Day) != day {
+				continue
+			}
+			if span, err := hours.span(); err == nil {
+				spans = append(spans, span)
+			}
+		}
+	}
+
+	// Overlapping and touching hours merge, so 09:00-12:00 and 12:00-17:00
+	// close at 17:00
+	sort.Slice(spans, func(i, j int) bool { return spans[i].open < spans[j].open })
+	merged := spans[:0]
+	for _, span := range spans {
+		if n := len(merged); n > 0 && span.open <= merged[n-1].close {
+			if span.close > merged[n-1].close {
+				merged[n-1].close = span.close
+			}
+			continue
+		}
+		merged = append(merged, span)
+	}
+	return merged
+}
+
+// exceptionOn returns the exception for a local date; one for the exact
+// date wins over a yearly one
+func (s *Schedule) exceptionOn(date time.Time) *ScheduleException {
+	exact := date.Format("2006-01-02")
+	yearly := date.Format("01-02")
+
+	var found *ScheduleException
+	for i := range s.Rules.Exceptions {
+		exception := &s.Rules.Exceptions[i]
+		if exception.Date == exact {
+			return exception
+		}
+		if exception.Date == yearly {
+			found = exception
+		}
+	}
+	return found
+}
+
+// span parses the hours into minutes after midnight
+func (h ScheduleHours) span() (clockSpan, error) {
+	open, ok := parseClock(h.Open)
+	if !ok || open >= 24*60 {
+		return clockSpan{}, fmt.Errorf("open %q must be HH:MM", h.Open)
+	}
+	closing, ok := parseClock(h.Close)
+	if !ok {
+		return clockSpan{}, fmt.Errorf("close %q must be HH:MM", h.Close)
+	}
+	if closing <= open {
+		return clockSpan{}, fmt.Errorf("close %s must be after open %s", h.Close, h.Open)
+	}
+	return clockSpan{open, closing}, nil
+}
+
+// parseClock parses "15:04" into minutes after midnight, allowing "24:00"
+func parseClock(value string) (int, bool) {
+	if value == "24:00" {
+		return 24 * 60, true
+	}
+	t, err := time.Parse("15:04", value)
+	if err != nil {
+		return 0, false
+	}
+	return t.Hour()*60 + t.Minute(), true
+}
+
+// clockTime returns the moment a number of minutes after a local midnight,
+// going by the wall clock across daylight saving changes
+func clockTime(date time.Time, minute int) time.Time {
+	return time.Date(date.Year(), date.Month(), date.Day(), minute/60, minute%60, 0, 0, date.Location())
+}
+
+// validExceptionDate checks an exception date is YYYY-MM-DD or MM-DD
+func validExceptionDate(date string) bool {
+	if _, err := time.Parse("2006-01-02", date); err == nil {
+		return true
+	}
+	// Parsed within a leap year so 02-29 is allowed
+	_, err := time.Parse("2006-01-02", "2024-"+date)
+	return err == nil && len(date) == 5
+}
+
+var weekdays = map[string]time.Weekday{
+	"sunday":    time.Sunday,
+	"monday":    time.Monday,
+	"tuesday":   time.Tuesday,
+	"wednesday": time.Wednesday,
+	"thursday":  time.Thursday,
+	"friday":    time.Friday,
+	"saturday":  time.Saturday,
+}
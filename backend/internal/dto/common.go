@@ -235,6 +235,81 @@ type ImportContactsResponse struct {
 	Errors        []string `json:"errors,omitempty"`
 }
 
+// ===================================
+// BUSINESS HOURS SCHEDULES
+// ===================================
+
+// ScheduleHours represents a span of opening hours
+// @Description Opening hours in 24-hour HH:MM, close may be 24:00
+type ScheduleHours struct {
+	Day   string `json:"day,omitempty" binding:"omitempty,oneof=monday tuesday wednesday thursday friday saturday sunday" example:"monday"`
+	Open  string `json:"open" binding:"required" example:"09:00"`
+	Close string `json:"close" binding:"required" example:"17:00"`
+}
+
+// ScheduleException represents a date that overrides a schedule
+// @Description Holiday or special hours on a date (YYYY-MM-DD) or every year (MM-DD)
+type ScheduleException struct {
+	Date   string          `json:"date" binding:"required" example:"12-25"`
+	Name   string          `json:"name,omitempty" example:"Christmas Day"`
+	Closed bool            `json:"closed" example:"true"`
+	Hours  []ScheduleHours `json:"hours,omitempty" binding:"omitempty,dive"`
+}
+
+// ScheduleRules represents a schedule's weekly hours and exception dates
+// @Description Weekly hours and exception dates
+type ScheduleRules struct {
+	Weekly     []ScheduleHours     `json:"weekly" binding:"omitempty,dive"`
+	Exceptions []ScheduleException `json:"exceptions" binding:"omitempty,dive"`
+}
+
+// ScheduleResponse represents schedule data
+// @Description Business hours or holiday schedule
+type ScheduleResponse struct {
+	ID           int64               `json:"id" example:"1"`
+	TenantID     string              `json:"tenant_id" example:"acme-corp"`
+	Name         string              `json:"name" example:"Support hours"`
+	Description  *string             `json:"description,omitempty" example:"Weekdays 9 to 5"`
+	Timezone     string              `json:"timezone" example:"America/New_York"`
+	ScheduleType common.ScheduleType `json:"schedule_type" example:"business_hours"`
+	Rules        ScheduleRules       `json:"rules"`
+	IsActive     bool                `json:"is_active" example:"true"`
+	CreatedAt    time.Time           `json:"created_at"`
+	UpdatedAt    time.Time           `json:"updated_at"`
+}
+
+// CreateScheduleRequest represents schedule creation data
+// @Description Create new schedule
+type CreateScheduleRequest struct {
+	Name         string              `json:"name" binding:"required,max=255" example:"Support hours"`
+	Description  *string             `json:"description,omitempty" example:"Weekdays 9 to 5"`
+	Timezone     string              `json:"timezone" example:"America/New_York"`
+	ScheduleType common.ScheduleType `json:"schedule_type" binding:"omitempty,oneof=business_hours holidays" example:"business_hours"`
+	Rules        ScheduleRules       `json:"rules"`
+	IsActive     *bool               `json:"is_active,omitempty" example:"true"`
+}
+
+// UpdateScheduleRequest represents schedule update data
+// @Description Update schedule; rules replace the existing rules in full
+type UpdateScheduleRequest struct {
+	Name         *string              `json:"name,omitempty" binding:"omitempty,max=255" example:"Support hours"`
+	Description  *string              `json:"description,omitempty" example:"Weekdays 9 to 5"`
+	Timezone     *string              `json:"timezone,omitempty" example:"America/New_York"`
+	ScheduleType *common.ScheduleType `json:"schedule_type,omitempty" binding:"omitempty,oneof=business_hours holidays" example:"business_hours"`
+	Rules        *ScheduleRules       `json:"rules,omitempty"`
+	IsActive     *bool                `json:"is_active,omitempty" example:"true"`
+}
+
+// ScheduleStatusResponse represents whether a schedule is open at a moment
+// @Description Schedule open/closed state
+type ScheduleStatusResponse struct {
+	ScheduleID int64      `json:"schedule_id" example:"1"`
+	At         time.Time  `json:"at"`
+	IsOpen     bool       `json:"is_open" example:"true"`
+	NextOpen   *time.Time `json:"next_open,omitempty"`
+	NextClose  *time.Time `json:"next_close,omitempty"`
+}
+
 // ===================================
 // PAGINATION & FILTERING
 // ===================================
@@ -156,6 +156,10 @@ type ChatWidgetResponse struct {
 	RequireEmail         bool                   `json:"require_email" example:"false"`
 	RequireName          bool                   `json:"require_name" example:"true"`
 	BusinessHoursEnabled bool                   `json:"business_hours_enabled" example:"true"`
+	ScheduleID           *int64                 `json:"schedule_id,omitempty" example:"1"`
+	IsOnline             bool                   `json:"is_online" example:"true"` // Enabled and within business hours
+	OfflineMessage       *string                `json:"offline_message,omitempty" example:"We're currently offline"`
+	NextOpenAt           *time.Time             `json:"next_open_at,omitempty"` // Set while offline outside business hours
 	EmbedCode            string                 `json:"embed_code" example:"<script>...</script>"`
 	Settings             map[string]interface{} `json:"settings,omitempty"` // Extended configuration
 	CreatedAt            time.Time              `json:"created_at"`
@@ -178,6 +182,8 @@ type CreateChatWidgetRequest struct {
 	DefaultTeam          *string `json:"default_team,omitempty" example:"Support Team"`
 	BusinessHoursEnabled bool    `json:"business_hours_enabled" example:"true"`
 	BusinessHours        *string `json:"business_hours,omitempty"`
+	ScheduleID           *int64  `json:"schedule_id,omitempty" example:"1"`
+	OfflineMessage       *string `json:"offline_message,omitempty" example:"We're currently offline"`
 }
 
 // UpdateChatWidgetRequest represents chat widget update data
@@ -206,6 +212,7 @@ type UpdateChatWidgetRequest struct {
 	OfflineMessage       *string `json:"offline_message,omitempty" example:"We're currently offline"`
 	BusinessHoursEnabled *bool   `json:"business_hours_enabled,omitempty" example:"true"`
 	BusinessHours        *string `json:"business_hours,omitempty"`
+	ScheduleID           *int64  `json:"schedule_id,omitempty" example:"1"` // 0 removes the schedule
 
 	// Pre-chat form
 	EnablePreChatForm *bool   `json:"enable_pre_chat_form,omitempty" example:"false"`
@@ -256,17 +263,21 @@ type UpdateChatWidgetRequest struct {
 type ChatSessionResponse struct {
 	ID                int64                    `json:"id" example:"1"`
 	TenantID          string                   `json:"tenant_id" example:"acme-corp"`
-	WidgetID          int64                    `json:"widget_id" example:"1"`
+	WidgetID          *int64                   `json:"widget_id,omitempty" example:"1"`
 	SessionKey        string                   `json:"session_key" example:"sess_xyz789"`
 	Status            common.ChatSessionStatus `json:"status" example:"active"`
+	Channel           common.ChatChannel       `json:"channel" example:"web"`
+	DIDID             *int64                   `json:"did_id,omitempty" example:"1"`
 	VisitorName       *string                  `json:"visitor_name,omitempty" example:"Jane Visitor"`
 	VisitorEmail      *string                  `json:"visitor_email,omitempty" example:"jane@example.com"`
+	VisitorPhone      *string                  `json:"visitor_phone,omitempty" example:"+15559876543"`
 	AssignedToID      *int64                   `json:"assigned_to_id,omitempty" example:"1"`
 	AssignedToName    *string                  `json:"assigned_to_name,omitempty" example:"Agent John"`
 	AssignedTeam      *string                  `json:"assigned_team,omitempty" example:"Support Team"`
 	MessageCount      int                      `json:"message_count" example:"15"`
 	FirstResponseTime *int                     `json:"first_response_time,omitempty" example:"45"` // seconds
 	Duration          *int                     `json:"duration,omitempty" example:"180"`           // seconds
+	WrapupTime        *int                     `json:"wrapup_time,omitempty" example:"30"`         // seconds the agent spent in wrap-up
 	Rating            *int                     `json:"rating,omitempty" example:"5"`
 	RatingComment     *string                  `json:"rating_comment,omitempty"`
 	StartedAt         *time.Time               `json:"started_at,omitempty"`
@@ -309,6 +320,7 @@ type ChatMessageResponse struct {
 	AttachmentURL  *string                `json:"attachment_url,omitempty"`
 	AttachmentName *string                `json:"attachment_name,omitempty"`
 	IsRead         bool                   `json:"is_read" example:"true"`
+	DeliveryStatus *common.SMSStatus      `json:"delivery_status,omitempty" example:"delivered"` // SMS sessions only
 	CreatedAt      time.Time              `json:"created_at"`
 }
 
@@ -13,44 +13,56 @@ import (
 // DIDResponse represents DID/phone number data
 // @Description Phone number information
 type DIDResponse struct {
-	ID            int64            `json:"id" example:"1"`
-	TenantID      string           `json:"tenant_id" example:"acme-corp"`
-	Number        string           `json:"number" example:"+15551234567"`
-	CountryCode   *string          `json:"country_code,omitempty" example:"+1"`
-	FriendlyName  *string          `json:"friendly_name,omitempty" example:"Main Sales Line"`
-	RouteType     common.RouteType `json:"route_type" example:"queue"`
-	RouteTarget   string           `json:"route_target" example:"sales"`
-	SMSEnabled    bool             `json:"sms_enabled" example:"true"`
-	SMSWebhookURL *string          `json:"sms_webhook_url,omitempty"`
-	Status        common.DIDStatus `json:"status" example:"active"`
-	Metadata      common.JSONMap   `json:"metadata,omitempty"`
-	CreatedAt     time.Time        `json:"created_at"`
-	UpdatedAt     time.Time        `json:"updated_at"`
+	ID                    int64             `json:"id" example:"1"`
+	TenantID              string            `json:"tenant_id" example:"acme-corp"`
+	Number                string            `json:"number" example:"+15551234567"`
+	CountryCode           *string           `json:"country_code,omitempty" example:"+1"`
+	FriendlyName          *string           `json:"friendly_name,omitempty" example:"Main Sales Line"`
+	RouteType             common.RouteType  `json:"route_type" example:"queue"`
+	RouteTarget           string            `json:"route_target" example:"sales"`
+	ScheduleID            *int64            `json:"schedule_id,omitempty" example:"1"`
+	AfterHoursRouteType   *common.RouteType `json:"after_hours_route_type,omitempty" example:"voicemail"`
+	AfterHoursRouteTarget *string           `json:"after_hours_route_target,omitempty" example:"1000"`
+	SurveyID              *int64            `json:"survey_id,omitempty" example:"1"`
+	SMSEnabled            bool              `json:"sms_enabled" example:"true"`
+	SMSWebhookURL         *string           `json:"sms_webhook_url,omitempty"`
+	Status                common.DIDStatus  `json:"status" example:"active"`
+	Metadata              common.JSONMap    `json:"metadata,omitempty"`
+	CreatedAt             time.Time         `json:"created_at"`
+	UpdatedAt             time.Time         `json:"updated_at"`
 }
 
 // CreateDIDRequest represents DID creation data
 // @Description Create new phone number
 type CreateDIDRequest struct {
-	Number        string           `json:"number" binding:"required" example:"+15551234567"`
-	CountryCode   *string          `json:"country_code,omitempty" example:"+1"`
-	FriendlyName  *string          `json:"friendly_name,omitempty" example:"Main Sales Line"`
-	RouteType     common.RouteType `json:"route_type" binding:"required" example:"queue"`
-	RouteTarget   string           `json:"route_target" binding:"required" example:"sales"`
-	SMSEnabled    bool             `json:"sms_enabled" example:"true"`
-	SMSWebhookURL *string          `json:"sms_webhook_url,omitempty"`
-	Metadata      common.JSONMap   `json:"metadata,omitempty"`
+	Number                string            `json:"number" binding:"required" example:"+15551234567"`
+	CountryCode           *string           `json:"country_code,omitempty" example:"+1"`
+	FriendlyName          *string           `json:"friendly_name,omitempty" example:"Main Sales Line"`
+	RouteType             common.RouteType  `json:"route_type" binding:"required" example:"queue"`
+	RouteTarget           string            `json:"route_target" binding:"required" example:"sales"`
+	ScheduleID            *int64            `json:"schedule_id,omitempty" example:"1"`
+	AfterHoursRouteType   *common.RouteType `json:"after_hours_route_type,omitempty" example:"voicemail"`
+	AfterHoursRouteTarget *string           `json:"after_hours_route_target,omitempty" example:"1000"`
+	SurveyID              *int64            `json:"survey_id,omitempty" example:"1"`
+	SMSEnabled            bool              `json:"sms_enabled" example:"true"`
+	SMSWebhookURL         *string           `json:"sms_webhook_url,omitempty"`
+	Metadata              common.JSONMap    `json:"metadata,omitempty"`
 }
 
 // UpdateDIDRequest represents DID update data
 // @Description Update phone number configuration
 type UpdateDIDRequest struct {
-	FriendlyName  *string           `json:"friendly_name,omitempty" example:"Main Sales Line"`
-	RouteType     *common.RouteType `json:"route_type,omitempty" example:"queue"`
-	RouteTarget   *string           `json:"route_target,omitempty" example:"sales"`
-	SMSEnabled    *bool             `json:"sms_enabled,omitempty" example:"true"`
-	SMSWebhookURL *string           `json:"sms_webhook_url,omitempty"`
-	Status        *common.DIDStatus `json:"status,omitempty" example:"active"`
-	Metadata      common.JSONMap    `json:"metadata,omitempty"`
+	FriendlyName          *string           `json:"friendly_name,omitempty" example:"Main Sales Line"`
+	RouteType             *common.RouteType `json:"route_type,omitempty" example:"queue"`
+	RouteTarget           *string           `json:"route_target,omitempty" example:"sales"`
+	ScheduleID            *int64            `json:"schedule_id,omitempty" example:"1"` // 0 removes the schedule
+	AfterHoursRouteType   *common.RouteType `json:"after_hours_route_type,omitempty" example:"voicemail"`
+	AfterHoursRouteTarget *string           `json:"after_hours_route_target,omitempty" example:"1000"`
+	SurveyID              *int64            `json:"survey_id,omitempty" example:"1"` // 0 removes the survey
+	SMSEnabled            *bool             `json:"sms_enabled,omitempty" example:"true"`
+	SMSWebhookURL         *string           `json:"sms_webhook_url,omitempty"`
+	Status                *common.DIDStatus `json:"status,omitempty" example:"active"`
+	Metadata              common.JSONMap    `json:"metadata,omitempty"`
 }
 
 // UpdateDIDRoutingRequest represents DID routing update
@@ -67,55 +79,95 @@ type UpdateDIDRoutingRequest struct {
 // QueueResponse represents queue data
 // @Description Call queue configuration
 type QueueResponse struct {
-	ID                int64          `json:"id" example:"1"`
-	TenantID          string         `json:"tenant_id" example:"acme-corp"`
-	Name              string         `json:"name" example:"sales"`
-	DisplayName       string         `json:"display_name" example:"Sales Queue"`
-	Strategy          string         `json:"strategy" example:"leastrecent"`
-	Timeout           int            `json:"timeout" example:"30"`
-	Retry             int            `json:"retry" example:"5"`
-	MaxWaitTime       int            `json:"max_wait_time" example:"300"`
-	MaxLen            int            `json:"max_len" example:"0"`
-	AnnounceFrequency int            `json:"announce_frequency" example:"60"`
-	AnnounceHoldTime  bool           `json:"announce_hold_time" example:"true"`
-	MusicOnHold       string         `json:"music_on_hold" example:"default"`
-	Status            string         `json:"status" example:"active"`
-	MemberCount       int            `json:"member_count" example:"5"`
-	Metadata          common.JSONMap `json:"metadata,omitempty"`
-	CreatedAt         time.Time      `json:"created_at"`
-	UpdatedAt         time.Time      `json:"updated_at"`
+	ID                  int64             `json:"id" example:"1"`
+	TenantID            string            `json:"tenant_id" example:"acme-corp"`
+	Name                string            `json:"name" example:"sales"`
+	DisplayName         string            `json:"display_name" example:"Sales Queue"`
+	Strategy            string            `json:"strategy" example:"leastrecent"`
+	Timeout             int               `json:"timeout" example:"30"`
+	Retry               int               `json:"retry" example:"5"`
+	WrapupTime          int               `json:"wrapup_time" example:"30"`
+	MaxWaitTime         int               `json:"max_wait_time" example:"300"`
+	MaxLen              int               `json:"max_len" example:"0"`
+	AnnounceFrequency   int               `json:"announce_frequency" example:"60"`
+	AnnounceHoldTime    bool              `json:"announce_hold_time" example:"true"`
+	MusicOnHold         string            `json:"music_on_hold" example:"default"`
+	FallbackRouteType   *common.RouteType `json:"fallback_route_type,omitempty" example:"voicemail"`
+	FallbackRouteTarget *string           `json:"fallback_route_target,omitempty" example:"1000"`
+	ScheduleID          *int64            `json:"schedule_id,omitempty" example:"1"`
+	SurveyID            *int64            `json:"survey_id,omitempty" example:"1"`
+	CallbackEnabled     bool              `json:"callback_enabled" example:"true"`
+	CallbackOfferAfter  int               `json:"callback_offer_after" example:"60"`
+	CallbackPrompt      *string           `json:"callback_prompt,omitempty" example:"custom/press-1-for-callback"`
+	CallbackMaxAttempts int               `json:"callback_max_attempts" example:"3"`
+	CallbackRetry       int               `json:"callback_retry" example:"300"`
+	RecordingPolicy     string            `json:"recording_policy" example:"inherit"`
+	Status              string            `json:"status" example:"active"`
+	MemberCount         int               `json:"member_count" example:"5"`
+	Metadata            common.JSONMap    `json:"metadata,omitempty"`
+	CreatedAt           time.Time         `json:"created_at"`
+	UpdatedAt           time.Time         `json:"updated_at"`
 }
 
 // CreateQueueRequest represents queue creation data
-// @Description Create new call queue
+// @Description Create new call queue. recording_policy overrides the tenant's call recording
+// setting for calls answered from the queue (inherit, always or never). wrapup_time is the
+// seconds of wrap-up agents get after a call from the queue, unless their membership sets its own.
+// While the schedule_id schedule is closed callers go straight to the fallback route.
+// Callers reaching the queue are offered the survey_id survey unless they were already offered one.
+// With callback_enabled, callers still holding after callback_offer_after seconds hear callback_prompt
+// and can press 1 to be called back instead; each callback is dialed up to callback_max_attempts
+// times, callback_retry seconds apart
 type CreateQueueRequest struct {
-	Name              string         `json:"name" binding:"required" example:"sales"`
-	DisplayName       string         `json:"display_name" binding:"required" example:"Sales Queue"`
-	Strategy          string         `json:"strategy" example:"leastrecent"`
-	Timeout           int            `json:"timeout" example:"30"`
-	Retry             int            `json:"retry" example:"5"`
-	MaxWaitTime       int            `json:"max_wait_time" example:"300"`
-	MaxLen            int            `json:"max_len" example:"0"`
-	AnnounceFrequency int            `json:"announce_frequency" example:"60"`
-	AnnounceHoldTime  bool           `json:"announce_hold_time" example:"true"`
-	MusicOnHold       string         `json:"music_on_hold" example:"default"`
-	Metadata          common.JSONMap `json:"metadata,omitempty"`
+	Name                string            `json:"name" binding:"required" example:"sales"`
+	DisplayName         string            `json:"display_name" binding:"required" example:"Sales Queue"`
+	Strategy            string            `json:"strategy" example:"leastrecent"`
+	Timeout             int               `json:"timeout" example:"30"`
+	Retry               int               `json:"retry" example:"5"`
+	WrapupTime          int               `json:"wrapup_time" binding:"min=0,max=3600" example:"30"`
+	MaxWaitTime         int               `json:"max_wait_time" example:"300"`
+	MaxLen              int               `json:"max_len" example:"0"`
+	AnnounceFrequency   int               `json:"announce_frequency" example:"60"`
+	AnnounceHoldTime    bool              `json:"announce_hold_time" example:"true"`
+	MusicOnHold         string            `json:"music_on_hold" example:"default"`
+	FallbackRouteType   *common.RouteType `json:"fallback_route_type,omitempty" binding:"omitempty,oneof=queue endpoint ivr webhook external voicemail" example:"voicemail"`
+	FallbackRouteTarget *string           `json:"fallback_route_target,omitempty" example:"1000"`
+	ScheduleID          *int64            `json:"schedule_id,omitempty" example:"1"`
+	SurveyID            *int64            `json:"survey_id,omitempty" example:"1"`
+	CallbackEnabled     bool              `json:"callback_enabled" example:"true"`
+	CallbackOfferAfter  int               `json:"callback_offer_after" binding:"min=0,max=3600" example:"60"`
+	CallbackPrompt      *string           `json:"callback_prompt,omitempty" example:"custom/press-1-for-callback"`
+	CallbackMaxAttempts int               `json:"callback_max_attempts" binding:"min=0,max=10" example:"3"`
+	CallbackRetry       int               `json:"callback_retry" binding:"min=0,max=86400" example:"300"`
+	RecordingPolicy     string            `json:"recording_policy,omitempty" binding:"omitempty,oneof=inherit always never" example:"inherit"`
+	Metadata            common.JSONMap    `json:"metadata,omitempty"`
 }
 
 // UpdateQueueRequest represents queue update data
 // @Description Update call queue configuration
 type UpdateQueueRequest struct {
-	DisplayName       *string        `json:"display_name,omitempty" example:"Sales Queue"`
-	Strategy          *string        `json:"strategy,omitempty" example:"leastrecent"`
-	Timeout           *int           `json:"timeout,omitempty" example:"30"`
-	Retry             *int           `json:"retry,omitempty" example:"5"`
-	MaxWaitTime       *int           `json:"max_wait_time,omitempty" example:"300"`
-	MaxLen            *int           `json:"max_len,omitempty" example:"0"`
-	AnnounceFrequency *int           `json:"announce_frequency,omitempty" example:"60"`
-	AnnounceHoldTime  *bool          `json:"announce_hold_time,omitempty" example:"true"`
-	MusicOnHold       *string        `json:"music_on_hold,omitempty" example:"default"`
-	Status            *string        `json:"status,omitempty" example:"active"`
-	Metadata          common.JSONMap `json:"metadata,omitempty"`
+	DisplayName         *string           `json:"display_name,omitempty" example:"Sales Queue"`
+	Strategy            *string           `json:"strategy,omitempty" example:"leastrecent"`
+	Timeout             *int              `json:"timeout,omitempty" example:"30"`
+	Retry               *int              `json:"retry,omitempty" example:"5"`
+	WrapupTime          *int              `json:"wrapup_time,omitempty" binding:"omitempty,min=0,max=3600" example:"30"`
+	MaxWaitTime         *int              `json:"max_wait_time,omitempty" example:"300"`
+	MaxLen              *int              `json:"max_len,omitempty" example:"0"`
+	AnnounceFrequency   *int              `json:"announce_frequency,omitempty" example:"60"`
+	AnnounceHoldTime    *bool             `json:"announce_hold_time,omitempty" example:"true"`
+	MusicOnHold         *string           `json:"music_on_hold,omitempty" example:"default"`
+	FallbackRouteType   *common.RouteType `json:"fallback_route_type,omitempty" example:"voicemail"`
+	FallbackRouteTarget *string           `json:"fallback_route_target,omitempty" example:"1000"`
+	ScheduleID          *int64            `json:"schedule_id,omitempty" example:"1"` // 0 removes the schedule
+	SurveyID            *int64            `json:"survey_id,omitempty" example:"1"`   // 0 removes the survey
+	CallbackEnabled     *bool             `json:"callback_enabled,omitempty" example:"true"`
+	CallbackOfferAfter  *int              `json:"callback_offer_after,omitempty" binding:"omitempty,min=1,max=3600" example:"60"`
+	CallbackPrompt      *string           `json:"callback_prompt,omitempty" example:"custom/press-1-for-callback"`
+	CallbackMaxAttempts *int              `json:"callback_max_attempts,omitempty" binding:"omitempty,min=1,max=10" example:"3"`
+	CallbackRetry       *int              `json:"callback_retry,omitempty" binding:"omitempty,min=1,max=86400" example:"300"`
+	RecordingPolicy     *string           `json:"recording_policy,omitempty" binding:"omitempty,oneof=inherit always never" example:"always"`
+	Status              *string           `json:"status,omitempty" example:"active"`
+	Metadata            common.JSONMap    `json:"metadata,omitempty"`
 }
 
 // QueueMemberResponse represents queue member data
@@ -170,7 +222,10 @@ type CDRResponse struct {
 	RecordingFile *string                `json:"recordingfile,omitempty"`
 	QueueName     *string                `json:"queue_name,omitempty" example:"sales"`
 	QueueWaitTime int                    `json:"queue_wait_time" example:"15"`
+	WrapupTime    int                    `json:"wrapup_time" example:"20"`
 	AgentName     *string                `json:"agent_name,omitempty" example:"John Doe"`
+	LinkedID      string                 `json:"linkedid" example:"1634567890.123"`
+	Sequence      int                    `json:"sequence" example:"0"`
 	Metadata      common.JSONMap         `json:"metadata,omitempty"`
 }
 
@@ -197,6 +252,7 @@ type CDRStatsResponse struct {
 	BusyCalls       int     `json:"busy_calls" example:"5"`
 	AverageDuration float64 `json:"average_duration" example:"125.5"`
 	AverageWaitTime float64 `json:"average_wait_time" example:"15.3"`
+	AverageWrapup   float64 `json:"average_wrapup_time" example:"22.5"`
 	TotalDuration   int     `json:"total_duration" example:"16875"`
 	AnswerRate      float64 `json:"answer_rate" example:"90.0"`
 }
@@ -211,6 +267,33 @@ type CallVolumeResponse struct {
 	AverageDuration float64 `json:"average_duration" example:"125.5"`
 }
 
+// ===================================
+// CALL RECORDINGS
+// ===================================
+
+// CallRecordingResponse represents call recording data
+// @Description Call recording file information
+type CallRecordingResponse struct {
+	ID        int64                  `json:"id" example:"1"`
+	CDRID     *int64                 `json:"cdr_id,omitempty" example:"1"`
+	UniqueID  string                 `json:"uniqueid" example:"1634567890.123"`
+	Filename  string                 `json:"filename" example:"acme-corp-1634567890-123.wav"`
+	FileSize  int64                  `json:"file_size" example:"524288"`
+	Duration  int                    `json:"duration" example:"120"`
+	Format    string                 `json:"format" example:"wav"`
+	Status    common.RecordingStatus `json:"status" example:"completed"`
+	StreamURL string                 `json:"stream_url,omitempty" example:"/api/v1/recordings/1/stream"`
+	Storage   *string                `json:"storage,omitempty" example:"s3"`
+	CreatedAt time.Time              `json:"created_at"`
+}
+
+// SignedURLResponse represents a time-limited download URL
+// @Description Download URL that needs no authentication until it expires
+type SignedURLResponse struct {
+	URL       string    `json:"url" example:"/api/v1/files/recordings/acme-corp/2023/10/25/acme-corp-1634567890-123.wav?expires=1698242400&signature=9f2c"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
 // ===================================
 // AGENT STATE & STATUS
 // ===================================
@@ -223,17 +306,118 @@ type AgentStateResponse struct {
 	UserID        int64              `json:"user_id" example:"1"`
 	EndpointID    string             `json:"endpoint_id" example:"acme-agent1"`
 	State         common.AgentStatus `json:"state" example:"available"`
+	ReasonCode    *string            `json:"reason_code,omitempty" example:"lunch"`
 	Reason        *string            `json:"reason,omitempty" example:"Lunch break"`
 	CurrentCallID *string            `json:"current_call_id,omitempty" example:"1634567890.123"`
 	UserName      *string            `json:"user_name,omitempty" example:"John Doe"`
 	ChangedAt     time.Time          `json:"changed_at"`
+
+	// Set while the agent is in wrap-up
+	WrapupStartedAt *time.Time `json:"wrapup_started_at,omitempty"`
+	WrapupEndsAt    *time.Time `json:"wrapup_ends_at,omitempty"`
+	WrapupRemaining *int       `json:"wrapup_remaining,omitempty" example:"25"` // seconds
+	WrapupChatID    *int64     `json:"wrapup_chat_id,omitempty" example:"1"`
 }
 
 // UpdateAgentStateRequest represents agent state update
 // @Description Update agent status
 type UpdateAgentStateRequest struct {
-	State  common.AgentStatus `json:"state" binding:"required,oneof=available busy away break offline dnd" example:"available"`
-	Reason *string            `json:"reason,omitempty" example:"Lunch break"`
+	State      common.AgentStatus `json:"state" binding:"required,oneof=available busy away break offline dnd" example:"available"`
+	ReasonCode *string            `json:"reason_code,omitempty" example:"lunch"`
+	Reason     *string            `json:"reason,omitempty" example:"Lunch break"`
+}
+
+// AgentStateHistoryResponse represents a period an agent spent in a state
+// @Description Agent state history entry
+type AgentStateHistoryResponse struct {
+	ID         int64              `json:"id" example:"1"`
+	UserID     int64              `json:"user_id" example:"1"`
+	State      common.AgentStatus `json:"state" example:"break"`
+	ReasonCode *string            `json:"reason_code,omitempty" example:"lunch"`
+	Reason     *string            `json:"reason,omitempty" example:"Lunch break"`
+	CallID     *string            `json:"call_id,omitempty" example:"1634567890.123"`
+	StartedAt  time.Time          `json:"started_at"`
+	EndedAt    *time.Time         `json:"ended_at,omitempty"`
+	Duration   int                `json:"duration" example:"1800"` // seconds, so far for the current state
+}
+
+// AgentBreakReasonResponse represents a break reason code
+// @Description Break reason code
+type AgentBreakReasonResponse struct {
+	ID          int64     `json:"id" example:"1"`
+	TenantID    string    `json:"tenant_id" example:"acme-corp"`
+	Code        string    `json:"code" example:"lunch"`
+	Name        string    `json:"name" example:"Lunch break"`
+	MaxDuration int       `json:"max_duration" example:"1800"` // seconds, 0 for no limit
+	IsActive    bool      `json:"is_active" example:"true"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// CreateAgentBreakReasonRequest represents break reason creation
+// @Description Create a break reason code
+type CreateAgentBreakReasonRequest struct {
+	Code        string `json:"code" binding:"required,max=64" example:"lunch"`
+	Name        string `json:"name" binding:"required,max=100" example:"Lunch break"`
+	MaxDuration int    `json:"max_duration" binding:"min=0" example:"1800"`
+	IsActive    *bool  `json:"is_active,omitempty" example:"true"`
+}
+
+// UpdateAgentBreakReasonRequest represents break reason update
+// @Description Update a break reason code
+type UpdateAgentBreakReasonRequest struct {
+	Name        *string `json:"name,omitempty" binding:"omitempty,max=100" example:"Lunch break"`
+	MaxDuration *int    `json:"max_duration,omitempty" binding:"omitempty,min=0" example:"2700"`
+	IsActive    *bool   `json:"is_active,omitempty" example:"false"`
+}
+
+// AgentTimeReportRequest represents time-in-state report parameters
+// @Description Filter parameters for the agent time-in-state report
+type AgentTimeReportRequest struct {
+	StartDate time.Time
+	EndDate   time.Time
+	UserID    int64  // one agent, or 0 for all
+	Team      string // agents of one team, or empty for all
+}
+
+// AgentTimeReportResponse represents the time agents spent in each state
+// @Description Agent time-in-state report
+type AgentTimeReportResponse struct {
+	StartDate time.Time         `json:"start_date"`
+	EndDate   time.Time         `json:"end_date"`
+	Team      string            `json:"team,omitempty" example:"Support Team"`
+	Agents    []AgentTimeReport `json:"agents"`
+	Totals    AgentTimeReport   `json:"totals"`
+}
+
+// AgentTimeReport represents one agent's, or all agents', time in each state. Times are in seconds.
+// @Description Agent time-in-state totals
+type AgentTimeReport struct {
+	UserID         int64              `json:"user_id,omitempty" example:"1"`
+	UserName       string             `json:"user_name,omitempty" example:"John Doe"`
+	LoginTime      int64              `json:"login_time" example:"28800"`
+	AvailableTime  int64              `json:"available_time" example:"9000"`
+	BusyTime       int64              `json:"busy_time" example:"14400"`
+	WrapupTime     int64              `json:"wrapup_time" example:"1800"`
+	AwayTime       int64              `json:"away_time" example:"600"`
+	BreakTime      int64              `json:"break_time" example:"3000"`
+	DNDTime        int64              `json:"dnd_time" example:"0"`
+	Occupancy      float64            `json:"occupancy" example:"64.0"` // percent of available, busy and wrap-up time spent busy or in wrap-up
+	Breaks         int                `json:"breaks" example:"3"`
+	BreaksOverMax  int                `json:"breaks_over_max" example:"1"`
+	BreakAdherence float64            `json:"break_adherence" example:"66.7"` // percent of breaks within their reason's maximum
+	BreakReasons   []AgentBreakReport `json:"break_reasons"`
+}
+
+// AgentBreakReport represents the breaks taken for one reason code
+// @Description Break totals per reason code
+type AgentBreakReport struct {
+	ReasonCode  string `json:"reason_code" example:"lunch"` // empty for breaks without a code
+	Name        string `json:"name,omitempty" example:"Lunch break"`
+	MaxDuration int    `json:"max_duration" example:"1800"`
+	Breaks      int    `json:"breaks" example:"1"`
+	OverMax     int    `json:"over_max" example:"0"`
+	TotalTime   int64  `json:"total_time" example:"1750"`
 }
 
 // ===================================
@@ -245,6 +429,8 @@ type UpdateAgentStateRequest struct {
 type SMSResponse struct {
 	ID                int64               `json:"id" example:"1"`
 	TenantID          string              `json:"tenant_id" example:"acme-corp"`
+	DIDID             *int64              `json:"did_id,omitempty" example:"1"`
+	UserID            *int64              `json:"user_id,omitempty" example:"1"`
 	Direction         common.SMSDirection `json:"direction" example:"outbound"`
 	Sender            string              `json:"sender" example:"+15551234567"`
 	Recipient         string              `json:"recipient" example:"+15559876543"`
@@ -276,6 +462,9 @@ type SendSMSRequest struct {
 type VoicemailResponse struct {
 	ID            int64      `json:"id" example:"1"`
 	TenantID      string     `json:"tenant_id" example:"acme-corp"`
+	MailboxID     *int64     `json:"mailbox_id,omitempty" example:"1"`
+	DIDID         *int64     `json:"did_id,omitempty" example:"1"`
+	UserID        *int64     `json:"user_id,omitempty" example:"1"`
 	CallerID      string     `json:"callerid" example:"+15551234567"`
 	CallerName    *string    `json:"callername,omitempty" example:"John Doe"`
 	Duration      int        `json:"duration" example:"45"`
@@ -283,10 +472,88 @@ type VoicemailResponse struct {
 	Format        string     `json:"format" example:"wav"`
 	IsRead        bool       `json:"is_read" example:"false"`
 	Transcription *string    `json:"transcription,omitempty"`
+	StreamURL     string     `json:"stream_url" example:"/api/v1/voicemails/1/stream"`
 	CreatedAt     time.Time  `json:"created_at"`
 	ReadAt        *time.Time `json:"read_at,omitempty"`
 }
 
+// VoicemailMailboxResponse represents voicemail mailbox data
+// @Description Voicemail mailbox information
+type VoicemailMailboxResponse struct {
+	ID          int64     `json:"id" example:"1"`
+	TenantID    string    `json:"tenant_id" example:"acme-corp"`
+	Name        string    `json:"name" example:"Sales"`
+	UserID      *int64    `json:"user_id,omitempty" example:"1"`
+	DIDID       *int64    `json:"did_id,omitempty" example:"1"`
+	Greeting    *string   `json:"greeting,omitempty" example:"recording:greeting-acme-corp-1"`
+	MaxLength   int       `json:"max_length" example:"180"`
+	IsActive    bool      `json:"is_active" example:"true"`
+	UnreadCount int64     `json:"unread_count" example:"3"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// CreateVoicemailMailboxRequest represents voicemail mailbox creation data.
+// A mailbox belongs to a user or a DID, not both.
+// @Description Create voicemail mailbox
+type CreateVoicemailMailboxRequest struct {
+	Name      string  `json:"name" binding:"required,max=100" example:"Sales"`
+	UserID    *int64  `json:"user_id,omitempty" example:"1"`
+	DIDID     *int64  `json:"did_id,omitempty" example:"1"`
+	Greeting  *string `json:"greeting,omitempty" binding:"omitempty,max=512" example:"vm-intro"`
+	MaxLength int     `json:"max_length,omitempty" binding:"omitempty,min=10,max=3600" example:"180"`
+}
+
+// UpdateVoicemailMailboxRequest represents voicemail mailbox update data
+// @Description Update voicemail mailbox
+type UpdateVoicemailMailboxRequest struct {
+	Name      *string `json:"name,omitempty" binding:"omitempty,max=100" example:"Sales"`
+	Greeting  *string `json:"greeting,omitempty" binding:"omitempty,max=512" example:"vm-intro"`
+	MaxLength *int    `json:"max_length,omitempty" binding:"omitempty,min=10,max=3600" example:"180"`
+	IsActive  *bool   `json:"is_active,omitempty" example:"true"`
+}
+
+// ===================================
+// TRANSCRIPTION
+// ===================================
+
+// TranscriptSegmentResponse represents a timed stretch of a transcript
+// @Description Transcript segment spoken by one speaker
+type TranscriptSegmentResponse struct {
+	Speaker *string `json:"speaker,omitempty" example:"speaker_1"`
+	StartMs int64   `json:"start_ms" example:"0"`
+	EndMs   int64   `json:"end_ms" example:"4200"`
+	Text    string  `json:"text" example:"Hi, this is John calling about my order."`
+}
+
+// TranscriptResponse represents the transcript of a recording or voicemail
+// with the state of its transcription job. Text and segments are empty
+// until the first transcription completes.
+// @Description Recording or voicemail transcript
+type TranscriptResponse struct {
+	SourceType  common.TranscriptSource     `json:"source_type" example:"voicemail"`
+	SourceID    int64                       `json:"source_id" example:"1"`
+	Status      common.TranscriptionStatus  `json:"status" example:"completed"`
+	Error       *string                     `json:"error,omitempty"`
+	Provider    string                      `json:"provider,omitempty" example:"whisper"`
+	Language    *string                     `json:"language,omitempty" example:"en"`
+	Text        string                      `json:"text" example:"Hi, this is John calling about my order."`
+	Duration    int                         `json:"duration" example:"45"`
+	Segments    []TranscriptSegmentResponse `json:"segments"`
+	CompletedAt *time.Time                  `json:"completed_at,omitempty"`
+}
+
+// TranscriptSearchResult represents a transcript matching a search
+// @Description Transcript search hit with its matching segments
+type TranscriptSearchResult struct {
+	SourceType common.TranscriptSource     `json:"source_type" example:"recording"`
+	SourceID   int64                       `json:"source_id" example:"1"`
+	Language   *string                     `json:"language,omitempty" example:"en"`
+	Duration   int                         `json:"duration" example:"120"`
+	Matches    []TranscriptSegmentResponse `json:"matches"`
+	CreatedAt  time.Time                   `json:"created_at"`
+}
+
 // ===================================
 // ENDPOINT (SIP) MANAGEMENT
 // ===================================
@@ -335,35 +602,629 @@ type UpdateEndpointRequest struct {
 // ===================================
 
 // OriginateCallRequest represents call initiation data
-// @Description Originate outbound call
+// @Description Click-to-call: rings the agent's own endpoint, then dials to_number through the trunk.
+// caller_id must be one of the tenant's active DIDs; the first active DID is used when omitted
 type OriginateCallRequest struct {
-	Endpoint  string            `json:"endpoint" binding:"required" example:"PJSIP/acme-agent1"`
-	ToNumber  string            `json:"to_number" binding:"required" example:"+15559876543"`
-	CallerID  *string           `json:"caller_id,omitempty" example:"+15551234567"`
-	Context   *string           `json:"context,omitempty" example:"from-internal"`
-	Variables map[string]string `json:"variables,omitempty"`
-	Timeout   int               `json:"timeout,omitempty" example:"30"`
+	ToNumber string  `json:"to_number" binding:"required" example:"+15559876543"`
+	CallerID *string `json:"caller_id,omitempty" example:"+15551234567"`
+	Timeout  int     `json:"timeout,omitempty" binding:"omitempty,min=5,max=120" example:"30"`
 }
 
 // OriginateCallResponse represents call initiation result
 // @Description Call origination result
 type OriginateCallResponse struct {
-	ChannelID string `json:"channel_id" example:"1634567890.123"`
-	CallID    string `json:"call_id" example:"call-abc123"`
-	Status    string `json:"status" example:"initiated"`
+	ChannelID string    `json:"channel_id" example:"1634567890.123"`
+	CallID    string    `json:"call_id" example:"call-abc123"`
+	Status    string    `json:"status" example:"ringing_agent"`
+	Endpoint  string    `json:"endpoint" example:"acme-agent1"`
+	ToNumber  string    `json:"to_number" example:"+15559876543"`
+	CallerID  string    `json:"caller_id" example:"+15551234567"`
+	DIDID     int64     `json:"did_id" example:"1"`
+	CreatedAt time.Time `json:"created_at"`
 }
 
 // HangupCallRequest represents call termination data
 // @Description Hangup active call
 type HangupCallRequest struct {
-	ChannelID string  `json:"channel_id" binding:"required" example:"1634567890.123"`
-	Reason    *string `json:"reason,omitempty" example:"normal"`
+	Reason *string `json:"reason,omitempty" binding:"omitempty,oneof=normal busy congestion no_answer answered_elsewhere" example:"normal"`
+}
+
+// HoldCallRequest represents call hold data
+// @Description Put a call on hold with music on hold
+type HoldCallRequest struct {
+	MusicOnHold *string `json:"music_on_hold,omitempty" example:"default"`
+}
+
+// MuteCallRequest represents call mute data
+// @Description Mute or unmute a call. Direction "in" stops the party being heard,
+// "out" stops the party hearing the call
+type MuteCallRequest struct {
+	Direction string `json:"direction" binding:"omitempty,oneof=both in out" example:"both"`
 }
 
 // TransferCallRequest represents call transfer data
-// @Description Transfer call to another destination
+// @Description Transfer call to a user, queue or external number. Blind transfers
+// drop the transferring party immediately; attended transfers put the call on hold
+// while the transferring party consults the target, then complete or cancel
 type TransferCallRequest struct {
-	ChannelID       string `json:"channel_id" binding:"required" example:"1634567890.123"`
-	TargetExtension string `json:"target_extension" binding:"required" example:"102"`
-	Type            string `json:"type" example:"blind"` // blind or attended
+	Type       string `json:"type" binding:"omitempty,oneof=blind attended" example:"blind"`
+	TargetType string `json:"target_type" binding:"required,oneof=user queue number" example:"user"`
+	Target     string `json:"target" binding:"required" example:"12"`
+}
+
+// CallControlResponse represents the result of a call control action
+// @Description Call control result
+type CallControlResponse struct {
+	ChannelID string `json:"channel_id" example:"1634567890.123"`
+	Action    string `json:"action" example:"hold"`
+	Status    string `json:"status" example:"ok"`
+}
+
+// ===================================
+// CALL MONITORING
+// ===================================
+
+// StartMonitorRequest represents a request to monitor a live call
+// @Description Rings the supervisor's own endpoint and connects it to the agent side of the call.
+// listen requires can_listen_calls; whisper and barge require can_coach_agents. Defaults to listen
+type StartMonitorRequest struct {
+	ChannelID string `json:"channel_id" binding:"required" example:"1634567890.123"`
+	Mode      string `json:"mode" binding:"omitempty,oneof=listen whisper barge" example:"listen"`
+}
+
+// UpdateMonitorModeRequest represents a monitor mode switch
+// @Description Switch a monitor session between listen, whisper and barge
+type UpdateMonitorModeRequest struct {
+	Mode string `json:"mode" binding:"required,oneof=listen whisper barge" example:"whisper"`
+}
+
+// MonitorSessionEventResponse represents an entry in a monitor session's audit trail
+// @Description Monitor session audit event
+type MonitorSessionEventResponse struct {
+	Event     string    `json:"event" example:"mode_changed"`
+	Mode      *string   `json:"mode,omitempty" example:"whisper"`
+	UserID    *int64    `json:"user_id,omitempty" example:"3"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// MonitorSessionResponse represents a supervisor monitor session
+// @Description Supervisor listen/whisper/barge session
+type MonitorSessionResponse struct {
+	ID                 int64                         `json:"id" example:"1"`
+	SupervisorID       int64                         `json:"supervisor_id" example:"3"`
+	SupervisorName     string                        `json:"supervisor_name,omitempty" example:"Jane Smith"`
+	SupervisorEndpoint string                        `json:"supervisor_endpoint" example:"acme-supervisor1"`
+	TargetChannelID    string                        `json:"target_channel_id" example:"1634567890.123"`
+	TargetChannel      *string                       `json:"target_channel,omitempty" example:"PJSIP/acme-agent1-00000002"`
+	CallerNumber       *string                       `json:"caller_number,omitempty" example:"+15551234567"`
+	Mode               string                        `json:"mode" example:"listen"`
+	Status             string                        `json:"status" example:"active"`
+	EndReason          *string                       `json:"end_reason,omitempty" example:"call_ended"`
+	StartedAt          time.Time                     `json:"started_at"`
+	AnsweredAt         *time.Time                    `json:"answered_at,omitempty"`
+	EndedAt            *time.Time                    `json:"ended_at,omitempty"`
+	Events             []MonitorSessionEventResponse `json:"events,omitempty"`
+}
+
+// ===================================
+// IVR MENUS
+// ===================================
+
+// IVRMenuResponse represents IVR menu data
+// @Description IVR menu configuration with options
+type IVRMenuResponse struct {
+	ID               int64               `json:"id" example:"1"`
+	TenantID         string              `json:"tenant_id" example:"acme-corp"`
+	Name             string              `json:"name" example:"main"`
+	Description      *string             `json:"description,omitempty" example:"Main menu"`
+	GreetingAudioURL *string             `json:"greeting_audio_url,omitempty" example:"sound:custom/main-menu"`
+	GreetingText     *string             `json:"greeting_text,omitempty" example:"Press 1 for sales, 2 for support"`
+	Timeout          int                 `json:"timeout" example:"5"`
+	MaxAttempts      int                 `json:"max_attempts" example:"3"`
+	InvalidAudioURL  *string             `json:"invalid_audio_url,omitempty" example:"sound:option-is-invalid"`
+	TimeoutAudioURL  *string             `json:"timeout_audio_url,omitempty"`
+	IsActive         bool                `json:"is_active" example:"true"`
+	Options          []IVROptionResponse `json:"options,omitempty"`
+	CreatedAt        time.Time           `json:"created_at"`
+	UpdatedAt        time.Time           `json:"updated_at"`
+}
+
+// CreateIVRMenuRequest represents IVR menu creation data
+// @Description Create new IVR menu
+type CreateIVRMenuRequest struct {
+	Name             string                   `json:"name" binding:"required" example:"main"`
+	Description      *string                  `json:"description,omitempty" example:"Main menu"`
+	GreetingAudioURL *string                  `json:"greeting_audio_url,omitempty" example:"sound:custom/main-menu"`
+	GreetingText     *string                  `json:"greeting_text,omitempty" example:"Press 1 for sales, 2 for support"`
+	Timeout          int                      `json:"timeout" binding:"omitempty,min=1,max=60" example:"5"`
+	MaxAttempts      int                      `json:"max_attempts" binding:"omitempty,min=1,max=10" example:"3"`
+	InvalidAudioURL  *string                  `json:"invalid_audio_url,omitempty" example:"sound:option-is-invalid"`
+	TimeoutAudioURL  *string                  `json:"timeout_audio_url,omitempty"`
+	Options          []CreateIVROptionRequest `json:"options,omitempty" binding:"omitempty,dive"`
+}
+
+// UpdateIVRMenuRequest represents IVR menu update data
+// @Description Update IVR menu configuration
+type UpdateIVRMenuRequest struct {
+	Name             *string `json:"name,omitempty" example:"main"`
+	Description      *string `json:"description,omitempty" example:"Main menu"`
+	GreetingAudioURL *string `json:"greeting_audio_url,omitempty" example:"sound:custom/main-menu"`
+	GreetingText     *string `json:"greeting_text,omitempty"`
+	Timeout          *int    `json:"timeout,omitempty" binding:"omitempty,min=1,max=60" example:"5"`
+	MaxAttempts      *int    `json:"max_attempts,omitempty" binding:"omitempty,min=1,max=10" example:"3"`
+	InvalidAudioURL  *string `json:"invalid_audio_url,omitempty"`
+	TimeoutAudioURL  *string `json:"timeout_audio_url,omitempty"`
+	IsActive         *bool   `json:"is_active,omitempty" example:"true"`
+}
+
+// IVROptionResponse represents IVR option data
+// @Description IVR menu option
+type IVROptionResponse struct {
+	ID          int64     `json:"id" example:"1"`
+	IVRMenuID   int64     `json:"ivr_menu_id" example:"1"`
+	Digit       string    `json:"digit" example:"1"`
+	Action      string    `json:"action" example:"queue"`
+	ActionData  *string   `json:"action_data,omitempty" example:"sales"`
+	Description *string   `json:"description,omitempty" example:"Sales"`
+	SortOrder   int       `json:"sort_order" example:"0"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// CreateIVROptionRequest represents IVR option creation data
+// @Description Add option to IVR menu. Digit is a DTMF sequence, or "t"/"i" for the
+// timeout/invalid fallback taken after max_attempts
+type CreateIVROptionRequest struct {
+	Digit       string  `json:"digit" binding:"required,max=10" example:"1"`
+	Action      string  `json:"action" binding:"required,oneof=queue endpoint submenu voicemail external webhook hangup" example:"queue"`
+	ActionData  *string `json:"action_data,omitempty" example:"sales"`
+	Description *string `json:"description,omitempty" example:"Sales"`
+	SortOrder   int     `json:"sort_order" example:"0"`
+}
+
+// UpdateIVROptionRequest represents IVR option update data
+// @Description Update IVR menu option
+type UpdateIVROptionRequest struct {
+	Digit       *string `json:"digit,omitempty" binding:"omitempty,max=10" example:"1"`
+	Action      *string `json:"action,omitempty" binding:"omitempty,oneof=queue endpoint submenu voicemail external webhook hangup" example:"queue"`
+	ActionData  *string `json:"action_data,omitempty" example:"sales"`
+	Description *string `json:"description,omitempty" example:"Sales"`
+	SortOrder   *int    `json:"sort_order,omitempty" example:"0"`
+}
+
+// ===================================
+// SOFTPHONES
+// ===================================
+
+// SoftphoneConfigResponse represents what an agent's WebRTC softphone needs to register
+// @Description Softphone registration settings of an agent's PJSIP endpoint
+type SoftphoneConfigResponse struct {
+	EndpointID  string              `json:"endpoint_id" example:"acme-agent42"`
+	Username    string              `json:"username" example:"acme-agent42"`
+	Password    string              `json:"password" example:"kX9v2mQ7pL4tR8wZ1cB6nH3s"`
+	Domain      string              `json:"domain" example:"pbx.example.com"`
+	URI         string              `json:"uri" example:"sip:acme-agent42@pbx.example.com"`
+	DisplayName *string             `json:"display_name,omitempty" example:"John Doe"`
+	WSSURL      string              `json:"wss_url" example:"wss://pbx.example.com:8089/ws"`
+	ICEServers  []ICEServerResponse `json:"ice_servers"`
+}
+
+// ICEServerResponse represents a STUN or TURN server, in RTCIceServer form
+// @Description STUN/TURN server for WebRTC
+type ICEServerResponse struct {
+	URLs       []string `json:"urls" example:"stun:stun.l.google.com:19302"`
+	Username   string   `json:"username,omitempty"`
+	Credential string   `json:"credential,omitempty"`
+}
+
+// ===================================
+// SIP TRUNKS
+// ===================================
+
+// TrunkResponse represents SIP trunk data
+// @Description SIP trunk to a provider
+type TrunkResponse struct {
+	ID            int64     `json:"id" example:"1"`
+	TenantID      *string   `json:"tenant_id,omitempty" example:"acme-corp"`
+	Platform      bool      `json:"platform" example:"false"`
+	Name          string    `json:"name" example:"Primary carrier"`
+	EndpointID    string    `json:"endpoint_id" example:"acme-corp-trunk-primary-carrier"`
+	Host          string    `json:"host" example:"sip.example.com"`
+	Port          int       `json:"port" example:"5060"`
+	Transport     string    `json:"transport" example:"transport-udp"`
+	Username      *string   `json:"username,omitempty" example:"acme"`
+	HasPassword   bool      `json:"has_password" example:"true"`
+	Register      bool      `json:"register" example:"true"`
+	FromUser      *string   `json:"from_user,omitempty" example:"15551234567"`
+	FromDomain    *string   `json:"from_domain,omitempty" example:"sip.example.com"`
+	Codecs        string    `json:"codecs" example:"ulaw,alaw"`
+	Context       string    `json:"context" example:"from-trunk"`
+	IdentifyMatch []string  `json:"identify_match" example:"203.0.113.0/24"`
+	MaxChannels   int       `json:"max_channels" example:"30"`
+	Status        string    `json:"status" example:"active"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
+
+// CreateTrunkRequest represents SIP trunk creation data. Platform trunks,
+// shared by all tenants, can only be created by superadmins, as can trunks
+// with a context other than from-trunk.
+// @Description Create SIP trunk
+type CreateTrunkRequest struct {
+	Name          string   `json:"name" binding:"required,max=100" example:"Primary carrier"`
+	Platform      bool     `json:"platform" example:"false"`
+	Host          string   `json:"host" binding:"required,max=255" example:"sip.example.com"`
+	Port          int      `json:"port,omitempty" binding:"omitempty,min=1,max=65535" example:"5060"`
+	Transport     string   `json:"transport,omitempty" binding:"omitempty,max=40" example:"transport-udp"`
+	Username      *string  `json:"username,omitempty" binding:"omitempty,max=128" example:"acme"`
+	Password      *string  `json:"password,omitempty" binding:"omitempty,max=256" example:"s3cret"`
+	Register      bool     `json:"register" example:"true"`
+	FromUser      *string  `json:"from_user,omitempty" binding:"omitempty,max=128" example:"15551234567"`
+	FromDomain    *string  `json:"from_domain,omitempty" binding:"omitempty,max=128" example:"sip.example.com"`
+	Codecs        string   `json:"codecs,omitempty" binding:"omitempty,max=256" example:"ulaw,alaw"`
+	Context       string   `json:"context,omitempty" binding:"omitempty,max=128" example:"from-trunk"`
+	IdentifyMatch []string `json:"identify_match,omitempty" example:"203.0.113.0/24"`
+	MaxChannels   int      `json:"max_channels,omitempty" binding:"omitempty,min=0" example:"30"`
+}
+
+// UpdateTrunkRequest represents SIP trunk update data. Without a password the
+// trunk keeps its current one; an empty password removes its credentials.
+// Only superadmins may change the context.
+// @Description Update SIP trunk
+type UpdateTrunkRequest struct {
+	Name          *string  `json:"name,omitempty" binding:"omitempty,max=100" example:"Primary carrier"`
+	Host          *string  `json:"host,omitempty" binding:"omitempty,max=255" example:"sip.example.com"`
+	Port          *int     `json:"port,omitempty" binding:"omitempty,min=1,max=65535" example:"5060"`
+	Transport     *string  `json:"transport,omitempty" binding:"omitempty,max=40" example:"transport-udp"`
+	Username      *string  `json:"username,omitempty" binding:"omitempty,max=128" example:"acme"`
+	Password      *string  `json:"password,omitempty" binding:"omitempty,max=256" example:"s3cret"`
+	Register      *bool    `json:"register,omitempty" example:"true"`
+	FromUser      *string  `json:"from_user,omitempty" binding:"omitempty,max=128" example:"15551234567"`
+	FromDomain    *string  `json:"from_domain,omitempty" binding:"omitempty,max=128" example:"sip.example.com"`
+	Codecs        *string  `json:"codecs,omitempty" binding:"omitempty,max=256" example:"ulaw,alaw"`
+	Context       *string  `json:"context,omitempty" binding:"omitempty,max=128" example:"from-trunk"`
+	IdentifyMatch []string `json:"identify_match,omitempty" example:"203.0.113.0/24"`
+	MaxChannels   *int     `json:"max_channels,omitempty" binding:"omitempty,min=0" example:"30"`
+	Status        *string  `json:"status,omitempty" binding:"omitempty,oneof=active disabled" example:"active"`
+}
+
+// TrunkStatusResponse represents a trunk's health as Asterisk reports it
+// @Description SIP trunk health and channel usage
+type TrunkStatusResponse struct {
+	TrunkID        int64  `json:"trunk_id" example:"1"`
+	EndpointID     string `json:"endpoint_id" example:"acme-corp-trunk-primary-carrier"`
+	Status         string `json:"status" example:"active"`
+	State          string `json:"state" example:"online"`
+	ActiveChannels int    `json:"active_channels" example:"12"`
+	MaxChannels    int    `json:"max_channels" example:"30"`
+	Available      bool   `json:"available" example:"true"`
+}
+
+// OutboundRouteResponse represents outbound route data
+// @Description Outbound route selecting a trunk by dialed prefix
+type OutboundRouteResponse struct {
+	ID                int64     `json:"id" example:"1"`
+	TenantID          *string   `json:"tenant_id,omitempty" example:"acme-corp"`
+	Platform          bool      `json:"platform" example:"false"`
+	Name              string    `json:"name" example:"UK mobiles"`
+	Prefix            string    `json:"prefix" example:"+447"`
+	Priority          int       `json:"priority" example:"0"`
+	TrunkID           int64     `json:"trunk_id" example:"1"`
+	TrunkName         string    `json:"trunk_name,omitempty" example:"Primary carrier"`
+	FailoverTrunkID   *int64    `json:"failover_trunk_id,omitempty" example:"2"`
+	FailoverTrunkName string    `json:"failover_trunk_name,omitempty" example:"Backup carrier"`
+	StripDigits       int       `json:"strip_digits" example:"0"`
+	AddPrefix         string    `json:"add_prefix" example:""`
+	IsActive          bool      `json:"is_active" example:"true"`
+	CreatedAt         time.Time `json:"created_at"`
+	UpdatedAt         time.Time `json:"updated_at"`
+}
+
+// CreateOutboundRouteRequest represents outbound route creation data. An
+// empty prefix matches every number. Platform routes can only be created by
+// superadmins.
+// @Description Create outbound route
+type CreateOutboundRouteRequest struct {
+	Name            string `json:"name" binding:"required,max=100" example:"UK mobiles"`
+	Platform        bool   `json:"platform" example:"false"`
+	Prefix          string `json:"prefix" binding:"max=32" example:"+447"`
+	Priority        int    `json:"priority,omitempty" example:"0"`
+	TrunkID         int64  `json:"trunk_id" binding:"required" example:"1"`
+	FailoverTrunkID *int64 `json:"failover_trunk_id,omitempty" example:"2"`
+	StripDigits     int    `json:"strip_digits,omitempty" binding:"omitempty,min=0,max=32" example:"0"`
+	AddPrefix       string `json:"add_prefix,omitempty" binding:"omitempty,max=32" example:""`
+}
+
+// UpdateOutboundRouteRequest represents outbound route update data. A
+// failover trunk ID of 0 removes the failover trunk.
+// @Description Update outbound route
+type UpdateOutboundRouteRequest struct {
+	Name            *string `json:"name,omitempty" binding:"omitempty,max=100" example:"UK mobiles"`
+	Prefix          *string `json:"prefix,omitempty" binding:"omitempty,max=32" example:"+447"`
+	Priority        *int    `json:"priority,omitempty" example:"0"`
+	TrunkID         *int64  `json:"trunk_id,omitempty" example:"1"`
+	FailoverTrunkID *int64  `json:"failover_trunk_id,omitempty" example:"2"`
+	StripDigits     *int    `json:"strip_digits,omitempty" binding:"omitempty,min=0,max=32" example:"0"`
+	AddPrefix       *string `json:"add_prefix,omitempty" binding:"omitempty,max=32" example:""`
+	IsActive        *bool   `json:"is_active,omitempty" example:"true"`
+}
+
+// ===================================
+// DIALPLAN GENERATION
+// ===================================
+
+// DialplanResponse represents the DID dialplan generated from the database
+// compared with the one Asterisk has
+// @Description Generated DID dialplan and its changes
+type DialplanResponse struct {
+	Path    string               `json:"path" example:"/etc/asterisk/generated/extensions_callcenter.conf"`
+	Changed bool                 `json:"changed" example:"true"`
+	Applied bool                 `json:"applied" example:"false"`
+	Diff    string               `json:"diff,omitempty"`
+	Content string               `json:"content,omitempty"`
+	Skipped []DialplanSkippedDID `json:"skipped,omitempty"`
+}
+
+// DialplanSkippedDID represents a DID left out of the dialplan
+// @Description DID that could not be written into the dialplan
+type DialplanSkippedDID struct {
+	ID       int64  `json:"id" example:"1"`
+	TenantID string `json:"tenant_id" example:"acme-corp"`
+	Number   string `json:"number" example:"+1 555 123"`
+	Reason   string `json:"reason" example:"number is not a valid extension"`
+}
+
+// ===================================
+// CALLER BLACKLIST
+// ===================================
+
+// BlacklistEntryResponse represents blacklist entry data
+// @Description Blocked caller number or pattern
+type BlacklistEntryResponse struct {
+	ID              int64                 `json:"id" example:"1"`
+	TenantID        string                `json:"tenant_id" example:"acme-corp"`
+	PhoneNumber     string                `json:"phone_number" example:"+1900*"`
+	Reason          *string               `json:"reason,omitempty" example:"Robocaller"`
+	BlockCalls      bool                  `json:"block_calls" example:"true"`
+	BlockSMS        bool                  `json:"block_sms" example:"true"`
+	Treatment       common.BlockTreatment `json:"treatment" example:"reject"`
+	TreatmentTarget *string               `json:"treatment_target,omitempty" example:"custom/blocked"`
+	AddedBy         *int64                `json:"added_by,omitempty" example:"1"`
+	ExpiresAt       *time.Time            `json:"expires_at,omitempty"`
+	Expired         bool                  `json:"expired" example:"false"`
+	CreatedAt       time.Time             `json:"created_at"`
+	UpdatedAt       time.Time             `json:"updated_at"`
+}
+
+// CreateBlacklistEntryRequest represents blacklist entry creation data.
+// PhoneNumber is a number, a pattern with ? for any digit and a trailing *
+// for any remaining digits, or "anonymous" for callers withholding their
+// number. TreatmentTarget is the sound played for the message treatment and
+// the mailbox for the voicemail treatment.
+// @Description Block a caller number or pattern
+type CreateBlacklistEntryRequest struct {
+	PhoneNumber     string                `json:"phone_number" binding:"required,max=50" example:"+1900*"`
+	Reason          *string               `json:"reason,omitempty" example:"Robocaller"`
+	BlockCalls      *bool                 `json:"block_calls,omitempty" example:"true"`
+	BlockSMS        *bool                 `json:"block_sms,omitempty" example:"true"`
+	Treatment       common.BlockTreatment `json:"treatment,omitempty" binding:"omitempty,oneof=reject busy message voicemail" example:"reject"`
+	TreatmentTarget *string               `json:"treatment_target,omitempty" binding:"omitempty,max=255" example:"custom/blocked"`
+	ExpiresAt       *time.Time            `json:"expires_at,omitempty"`
+}
+
+// UpdateBlacklistEntryRequest represents blacklist entry update data
+// @Description Update a blocked caller number or pattern
+type UpdateBlacklistEntryRequest struct {
+	PhoneNumber     *string                `json:"phone_number,omitempty" binding:"omitempty,max=50" example:"+1900*"`
+	Reason          *string                `json:"reason,omitempty" example:"Robocaller"`
+	BlockCalls      *bool                  `json:"block_calls,omitempty" example:"true"`
+	BlockSMS        *bool                  `json:"block_sms,omitempty" example:"true"`
+	Treatment       *common.BlockTreatment `json:"treatment,omitempty" binding:"omitempty,oneof=reject busy message voicemail" example:"busy"`
+	TreatmentTarget *string                `json:"treatment_target,omitempty" binding:"omitempty,max=255" example:"custom/blocked"`
+	ExpiresAt       *time.Time             `json:"expires_at,omitempty"`
+	NeverExpires    bool                   `json:"never_expires,omitempty" example:"false"`
+}
+
+// ImportBlacklistRequest represents bulk blacklist import data. Entries for
+// numbers already on the blacklist replace them.
+// @Description Block caller numbers in bulk
+type ImportBlacklistRequest struct {
+	Entries []CreateBlacklistEntryRequest `json:"entries" binding:"required,min=1,max=1000,dive"`
+}
+
+// ImportBlacklistResponse represents blacklist import result
+// @Description Bulk blacklist import result
+type ImportBlacklistResponse struct {
+	TotalImported int      `json:"total_imported" example:"45"`
+	TotalUpdated  int      `json:"total_updated" example:"3"`
+	TotalFailed   int      `json:"total_failed" example:"2"`
+	Errors        []string `json:"errors,omitempty"`
+}
+
+// BlockedAttemptResponse represents a call or SMS refused from a blocked number
+// @Description Blocked call or SMS
+type BlockedAttemptResponse struct {
+	ID           int64                  `json:"id" example:"1"`
+	BlacklistID  *int64                 `json:"blacklist_id,omitempty" example:"1"`
+	Pattern      string                 `json:"pattern" example:"+1900*"`
+	Source       common.BlockedSource   `json:"source" example:"call"`
+	CallerNumber string                 `json:"caller_number" example:"+19005551234"`
+	DIDID        *int64                 `json:"did_id,omitempty" example:"1"`
+	DIDNumber    string                 `json:"did_number" example:"+15551234567"`
+	Treatment    *common.BlockTreatment `json:"treatment,omitempty" example:"reject"`
+	CreatedAt    time.Time              `json:"created_at"`
+}
+
+// ===================================
+// POST-CALL SURVEYS
+// ===================================
+
+// SurveyQuestion represents one question of a post-call survey. Rating
+// questions take a number from min to max keyed in, ended early with #;
+// yes_no questions take 1 for yes and 2 for no. Metric marks the rating
+// that feeds the CSAT score (csat) or, rated 0 to 10, the NPS score (nps).
+// @Description Post-call survey question
+type SurveyQuestion struct {
+	Label  string                    `json:"label" binding:"required,max=100" example:"Overall satisfaction"`
+	Prompt string                    `json:"prompt" binding:"required,max=255" example:"custom/survey-q1"`
+	Type   common.SurveyQuestionType `json:"type" binding:"required,oneof=rating yes_no" example:"rating"`
+	Min    int                       `json:"min,omitempty" example:"1"`
+	Max    int                       `json:"max,omitempty" example:"5"`
+	Metric common.SurveyMetric       `json:"metric,omitempty" binding:"omitempty,oneof=csat nps" example:"csat"`
+}
+
+// SurveyResponse represents post-call survey data
+// @Description Post-call IVR survey
+type SurveyResponse struct {
+	ID             int64            `json:"id" example:"1"`
+	TenantID       string           `json:"tenant_id" example:"acme-corp"`
+	Name           string           `json:"name" example:"Support CSAT"`
+	Description    *string          `json:"description,omitempty" example:"Asked after support calls"`
+	Questions      []SurveyQuestion `json:"questions"`
+	TriggerOn      string           `json:"trigger_on" example:"call_end"`
+	OptInPrompt    string           `json:"opt_in_prompt" example:"custom/survey-optin"`
+	IntroPrompt    *string          `json:"intro_prompt,omitempty" example:"custom/survey-intro"`
+	ThankYouPrompt *string          `json:"thank_you_prompt,omitempty" example:"custom/survey-thanks"`
+	IsActive       bool             `json:"is_active" example:"true"`
+	CreatedAt      time.Time        `json:"created_at"`
+	UpdatedAt      time.Time        `json:"updated_at"`
+}
+
+// CreateSurveyRequest represents survey creation data. Callers reaching a
+// DID or queue with the survey hear opt_in_prompt and take the survey
+// after the agent hangs up if they press 1. Prompts are sound names or
+// media URIs.
+// @Description Create a post-call survey
+type CreateSurveyRequest struct {
+	Name           string           `json:"name" binding:"required,max=255" example:"Support CSAT"`
+	Description    *string          `json:"description,omitempty" example:"Asked after support calls"`
+	Questions      []SurveyQuestion `json:"questions" binding:"required,min=1,dive"`
+	OptInPrompt    string           `json:"opt_in_prompt" binding:"required,max=255" example:"custom/survey-optin"`
+	IntroPrompt    *string          `json:"intro_prompt,omitempty" binding:"omitempty,max=255" example:"custom/survey-intro"`
+	ThankYouPrompt *string          `json:"thank_you_prompt,omitempty" binding:"omitempty,max=255" example:"custom/survey-thanks"`
+	IsActive       *bool            `json:"is_active,omitempty" example:"true"`
+}
+
+// UpdateSurveyRequest represents survey update data; an empty intro or
+// thank-you prompt removes it
+// @Description Update a post-call survey
+type UpdateSurveyRequest struct {
+	Name           *string          `json:"name,omitempty" binding:"omitempty,max=255" example:"Support CSAT"`
+	Description    *string          `json:"description,omitempty" example:"Asked after support calls"`
+	Questions      []SurveyQuestion `json:"questions,omitempty" binding:"omitempty,dive"`
+	OptInPrompt    *string          `json:"opt_in_prompt,omitempty" binding:"omitempty,max=255" example:"custom/survey-optin"`
+	IntroPrompt    *string          `json:"intro_prompt,omitempty" binding:"omitempty,max=255" example:"custom/survey-intro"`
+	ThankYouPrompt *string          `json:"thank_you_prompt,omitempty" binding:"omitempty,max=255" example:"custom/survey-thanks"`
+	IsActive       *bool            `json:"is_active,omitempty" example:"true"`
+}
+
+// SurveyAnswer represents a caller's answer to one survey question; yes/no
+// answers are 1 for yes and 0 for no
+// @Description Answer to a survey question
+type SurveyAnswer struct {
+	Question int                       `json:"question" example:"1"`
+	Label    string                    `json:"label" example:"Overall satisfaction"`
+	Type     common.SurveyQuestionType `json:"type" example:"rating"`
+	Metric   common.SurveyMetric       `json:"metric,omitempty" example:"csat"`
+	Value    int                       `json:"value" example:"4"`
+}
+
+// SurveyAnswersResponse represents a caller's answers to a survey
+// @Description Post-call survey response
+type SurveyAnswersResponse struct {
+	ID            int64          `json:"id" example:"1"`
+	SurveyID      int64          `json:"survey_id" example:"1"`
+	CDRID         *int64         `json:"cdr_id,omitempty" example:"1"`
+	UniqueID      string         `json:"uniqueid" example:"1234567890.1"`
+	CallerNumber  string         `json:"caller_number" example:"+15551234567"`
+	DIDID         *int64         `json:"did_id,omitempty" example:"1"`
+	QueueName     *string        `json:"queue_name,omitempty" example:"support"`
+	AgentEndpoint *string        `json:"agent_endpoint,omitempty" example:"acme-agent1"`
+	UserID        *int64         `json:"user_id,omitempty" example:"5"`
+	Answers       []SurveyAnswer `json:"answers"`
+	OverallRating *int           `json:"overall_rating,omitempty" example:"4"`
+	CSATScore     *int           `json:"csat_score,omitempty" example:"4"`
+	NPSScore      *int           `json:"nps_score,omitempty" example:"9"`
+	Completed     bool           `json:"completed" example:"true"`
+	CompletedAt   time.Time      `json:"completed_at"`
+}
+
+// SurveyResponseQuery represents the survey responses a listing or report
+// covers
+type SurveyResponseQuery struct {
+	StartDate time.Time
+	EndDate   time.Time
+	SurveyID  int64  // one survey, or 0 for all
+	UserID    int64  // one agent, or 0 for all
+	QueueName string // one queue, or empty for all
+}
+
+// SurveyScores represents the survey scores of an agent, a queue or all
+// calls. CSAT is the percentage of CSAT ratings in the top two of their
+// scale; NPS is the percentage of promoters (9-10) less that of detractors
+// (0-6). Either is left out without any ratings for it.
+// @Description CSAT and NPS scores
+type SurveyScores struct {
+	UserID        *int64   `json:"user_id,omitempty" example:"5"`
+	AgentName     string   `json:"agent_name,omitempty" example:"John Doe"`
+	QueueName     *string  `json:"queue_name,omitempty" example:"support"`
+	Responses     int64    `json:"responses" example:"120"`
+	CSATResponses int64    `json:"csat_responses" example:"118"`
+	CSATAverage   *float64 `json:"csat_average,omitempty" example:"4.3"`
+	CSAT          *float64 `json:"csat,omitempty" example:"86.4"`
+	NPSResponses  int64    `json:"nps_responses" example:"110"`
+	Promoters     int64    `json:"promoters" example:"70"`
+	Passives      int64    `json:"passives" example:"25"`
+	Detractors    int64    `json:"detractors" example:"15"`
+	NPS           *float64 `json:"nps,omitempty" example:"50"`
+}
+
+// SurveyReportResponse represents survey scores per agent and per queue
+// over a date range. Responses from calls no agent or queue took are
+// grouped without a user_id or queue_name.
+// @Description Survey score report
+type SurveyReportResponse struct {
+	StartDate time.Time      `json:"start_date"`
+	EndDate   time.Time      `json:"end_date"`
+	SurveyID  *int64         `json:"survey_id,omitempty" example:"1"`
+	Agents    []SurveyScores `json:"agents"`
+	Queues    []SurveyScores `json:"queues"`
+	Totals    SurveyScores   `json:"totals"`
+}
+
+// ===================================
+// QUEUE CALLBACKS
+// ===================================
+
+// QueueCallbackResponse represents a callback a caller requested instead of
+// waiting on hold
+// @Description Queue callback
+type QueueCallbackResponse struct {
+	ID             int64      `json:"id" example:"1"`
+	QueueName      string     `json:"queue_name" example:"support"`
+	UniqueID       string     `json:"uniqueid" example:"1634567890.123"`
+	DIDID          *int64     `json:"did_id,omitempty" example:"1"`
+	CallerNumber   string     `json:"caller_number" example:"+15551234567"`
+	CallerName     string     `json:"caller_name" example:"John Doe"`
+	CallbackNumber string     `json:"callback_number" example:"+15551234567"`
+	Status         string     `json:"status" example:"pending"`
+	Attempts       int        `json:"attempts" example:"1"`
+	MaxAttempts    int        `json:"max_attempts" example:"3"`
+	WaitTime       int        `json:"wait_time" example:"95"`
+	AgentEndpoint  *string    `json:"agent_endpoint,omitempty" example:"acme-agent1"`
+	LastError      *string    `json:"last_error,omitempty" example:"BUSY"`
+	RequestedAt    time.Time  `json:"requested_at"`
+	LastAttemptAt  *time.Time `json:"last_attempt_at,omitempty"`
+	NextAttemptAt  *time.Time `json:"next_attempt_at,omitempty"`
+	ConnectedAt    *time.Time `json:"connected_at,omitempty"`
+	EndedAt        *time.Time `json:"ended_at,omitempty"`
+}
+
+// QueueCallbackQuery represents the callbacks a listing covers; zero dates
+// leave the requested time open
+type QueueCallbackQuery struct {
+	StartDate time.Time
+	EndDate   time.Time
+	QueueName string // one queue, or empty for all
+	Status    string // one status, or empty for all
 }
@@ -0,0 +1,116 @@
+package handler
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/psschand/callcenter/internal/dto"
+	"github.com/psschand/callcenter/internal/service"
+	"github.com/psschand/callcenter/pkg/response"
+)
+
+// AgentReportHandler handles agent state history and time-in-state report requests
+type AgentReportHandler struct {
+	agentReportService service.AgentReportService
+}
+
+// NewAgentReportHandler creates a new agent report handler
+func NewAgentReportHandler(agentReportService service.AgentReportService) *AgentReportHandler {
+	return &AgentReportHandler{
+		agentReportService: agentReportService,
+	}
+}
+
+// GetHistory lists an agent's state changes between start_date and end_date (today by default)
+func (h *AgentReportHandler) GetHistory(c *gin.Context) {
+	tenantID := c.GetString("tenant_id")
+	requesterID := c.GetInt64("user_id")
+	userID, err := strconv.ParseInt(c.Param("userId"), 10, 64)
+	if err != nil {
+		response.ValidationError(c, map[string]string{"userId": "invalid user ID"})
+		return
+	}
+	h.history(c, tenantID, requesterID, userID)
+}
+
+// GetMyHistory lists the current user's state changes
+func (h *AgentReportHandler) GetMyHistory(c *gin.Context) {
+	userID := c.GetInt64("user_id")
+	h.history(c, c.GetString("tenant_id"), userID, userID)
+}
+
+func (h *AgentReportHandler) history(c *gin.Context, tenantID string, requesterID, userID int64) {
+	start, end, ok := reportDateRange(c)
+	if !ok {
+		return
+	}
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+
+	entries, total, err := h.agentReportService.GetHistory(c.Request.Context(), tenantID, requesterID, userID, start, end, page, pageSize)
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	meta := response.NewMeta(page, pageSize, int(total))
+	response.SuccessWithMeta(c, entries, meta)
+}
+
+// GetTimeInState reports agents' login time, time in each state, occupancy
+// and break adherence between start_date and end_date (today by default),
+// optionally for one agent (user_id) or team
+func (h *AgentReportHandler) GetTimeInState(c *gin.Context) {
+	tenantID := c.GetString("tenant_id")
+	requesterID := c.GetInt64("user_id")
+
+	start, end, ok := reportDateRange(c)
+	if !ok {
+		return
+	}
+	req := dto.AgentTimeReportRequest{
+		StartDate: start,
+		EndDate:   end,
+		Team:      c.Query("team"),
+	}
+	if userIDStr := c.Query("user_id"); userIDStr != "" {
+		userID, err := strconv.ParseInt(userIDStr, 10, 64)
+		if err != nil {
+			response.ValidationError(c, map[string]string{"user_id": "invalid user ID"})
+			return
+		}
+		req.UserID = userID
+	}
+
+	result, err := h.agentReportService.GetTimeInState(c.Request.Context(), tenantID, requesterID, &req)
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.Success(c, result)
+}
+
+// reportDateRange parses the start_date and end_date query parameters into
+// the start of the first day and the end of the last. Either defaults to today.
+func reportDateRange(c *gin.Context) (time.Time, time.Time, bool) {
+	today := time.Now().Format("2006-01-02")
+
+	start, err := time.Parse("2006-01-02", c.DefaultQuery("start_date", today))
+	if err != nil {
+		response.ValidationError(c, map[string]string{"start_date": "invalid date format, use YYYY-MM-DD"})
+		return time.Time{}, time.Time{}, false
+	}
+
+	end, err := time.Parse("2006-01-02", c.DefaultQuery("end_date", today))
+	if err != nil {
+		response.ValidationError(c, map[string]string{"end_date": "invalid date format, use YYYY-MM-DD"})
+		return time.Time{}, time.Time{}, false
+	}
+
+	// Set end date to end of day
+	end = end.Add(24*time.Hour - time.Second)
+
+	return start, end, true
+}
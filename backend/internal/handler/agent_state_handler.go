@@ -5,6 +5,7 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/psschand/callcenter/internal/common"
+	"github.com/psschand/callcenter/internal/dto"
 	"github.com/psschand/callcenter/internal/service"
 	"github.com/psschand/callcenter/pkg/response"
 )
@@ -59,15 +60,16 @@ func (h *AgentStateHandler) UpdateState(c *gin.Context) {
 	userID := c.GetInt64("user_id")
 
 	var req struct {
-		State  common.AgentState `json:"state" binding:"required"`
-		Reason string            `json:"reason"`
+		State      common.AgentState `json:"state" binding:"required"`
+		ReasonCode string            `json:"reason_code"`
+		Reason     string            `json:"reason"`
 	}
 	if err := c.ShouldBindJSON(&req); err != nil {
 		response.ValidationError(c, err)
 		return
 	}
 
-	if err := h.agentStateService.UpdateState(c.Request.Context(), tenantID, userID, req.State, req.Reason); err != nil {
+	if err := h.agentStateService.UpdateState(c.Request.Context(), tenantID, userID, req.State, req.ReasonCode, req.Reason); err != nil {
 		response.Error(c, err)
 		return
 	}
@@ -121,11 +123,12 @@ func (h *AgentStateHandler) StartBreak(c *gin.Context) {
 	userID := c.GetInt64("user_id")
 
 	var req struct {
-		Reason string `json:"reason"`
+		ReasonCode string `json:"reason_code"`
+		Reason     string `json:"reason"`
 	}
 	c.ShouldBindJSON(&req)
 
-	if err := h.agentStateService.StartBreak(c.Request.Context(), tenantID, userID, req.Reason); err != nil {
+	if err := h.agentStateService.StartBreak(c.Request.Context(), tenantID, userID, req.ReasonCode, req.Reason); err != nil {
 		response.Error(c, err)
 		return
 	}
@@ -146,6 +149,41 @@ func (h *AgentStateHandler) EndBreak(c *gin.Context) {
 	response.Success(c, nil)
 }
 
+// ExtendWrapup gives the current user more time in wrap-up
+func (h *AgentStateHandler) ExtendWrapup(c *gin.Context) {
+	tenantID := c.GetString("tenant_id")
+	userID := c.GetInt64("user_id")
+
+	var req struct {
+		Seconds int `json:"seconds" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.ValidationError(c, err)
+		return
+	}
+
+	result, err := h.agentStateService.ExtendWrapup(c.Request.Context(), tenantID, userID, req.Seconds)
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.Success(c, result)
+}
+
+// FinishWrapup ends the current user's wrap-up early
+func (h *AgentStateHandler) FinishWrapup(c *gin.Context) {
+	tenantID := c.GetString("tenant_id")
+	userID := c.GetInt64("user_id")
+
+	if err := h.agentStateService.FinishWrapup(c.Request.Context(), tenantID, userID); err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.Success(c, nil)
+}
+
 // SetAway sets agent as away
 func (h *AgentStateHandler) SetAway(c *gin.Context) {
 	tenantID := c.GetString("tenant_id")
@@ -176,3 +214,80 @@ func (h *AgentStateHandler) SetAvailable(c *gin.Context) {
 
 	response.Success(c, nil)
 }
+
+// ListBreakReasons lists the tenant's break reasons; ?active=true lists only those agents can pick
+func (h *AgentStateHandler) ListBreakReasons(c *gin.Context) {
+	tenantID := c.GetString("tenant_id")
+	activeOnly := c.Query("active") == "true"
+
+	reasons, err := h.agentStateService.ListBreakReasons(c.Request.Context(), tenantID, activeOnly)
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.Success(c, reasons)
+}
+
+// CreateBreakReason adds a break reason code
+func (h *AgentStateHandler) CreateBreakReason(c *gin.Context) {
+	tenantID := c.GetString("tenant_id")
+	userID := c.GetInt64("user_id")
+
+	var req dto.CreateAgentBreakReasonRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.ValidationError(c, err)
+		return
+	}
+
+	result, err := h.agentStateService.CreateBreakReason(c.Request.Context(), tenantID, userID, &req)
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.Created(c, result)
+}
+
+// UpdateBreakReason updates a break reason
+func (h *AgentStateHandler) UpdateBreakReason(c *gin.Context) {
+	tenantID := c.GetString("tenant_id")
+	userID := c.GetInt64("user_id")
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		response.ValidationError(c, map[string]string{"id": "invalid break reason ID"})
+		return
+	}
+
+	var req dto.UpdateAgentBreakReasonRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.ValidationError(c, err)
+		return
+	}
+
+	result, err := h.agentStateService.UpdateBreakReason(c.Request.Context(), tenantID, userID, id, &req)
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.Success(c, result)
+}
+
+// DeleteBreakReason removes a break reason code
+func (h *AgentStateHandler) DeleteBreakReason(c *gin.Context) {
+	tenantID := c.GetString("tenant_id")
+	userID := c.GetInt64("user_id")
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		response.ValidationError(c, map[string]string{"id": "invalid break reason ID"})
+		return
+	}
+
+	if err := h.agentStateService.DeleteBreakReason(c.Request.Context(), tenantID, userID, id); err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.Success(c, nil)
+}
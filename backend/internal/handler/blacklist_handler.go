@@ -0,0 +1,160 @@
+package handler
+
+import (
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/psschand/callcenter/internal/dto"
+	"github.com/psschand/callcenter/internal/service"
+	"github.com/psschand/callcenter/pkg/response"
+)
+
+// BlacklistHandler handles blocked caller numbers and the attempts they made
+type BlacklistHandler struct {
+	blacklistService service.BlacklistService
+}
+
+// NewBlacklistHandler creates a new blacklist handler
+func NewBlacklistHandler(blacklistService service.BlacklistService) *BlacklistHandler {
+	return &BlacklistHandler{
+		blacklistService: blacklistService,
+	}
+}
+
+// List lists the tenant's blacklist; expired entries are included with
+// include_expired=true
+func (h *BlacklistHandler) List(c *gin.Context) {
+	tenantID := c.GetString("tenant_id")
+	userID := c.GetInt64("user_id")
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+	includeExpired := c.Query("include_expired") == "true"
+
+	entries, total, err := h.blacklistService.List(c.Request.Context(), tenantID, userID, includeExpired, page, pageSize)
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	meta := response.NewMeta(page, pageSize, int(total))
+	response.SuccessWithMeta(c, entries, meta)
+}
+
+// Get gets a blacklist entry
+func (h *BlacklistHandler) Get(c *gin.Context) {
+	tenantID := c.GetString("tenant_id")
+	userID := c.GetInt64("user_id")
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		response.ValidationError(c, map[string]string{"id": "invalid blacklist entry ID"})
+		return
+	}
+
+	result, err := h.blacklistService.Get(c.Request.Context(), tenantID, userID, id)
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.Success(c, result)
+}
+
+// Create blocks a number or pattern
+func (h *BlacklistHandler) Create(c *gin.Context) {
+	tenantID := c.GetString("tenant_id")
+	userID := c.GetInt64("user_id")
+
+	var req dto.CreateBlacklistEntryRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.ValidationError(c, err)
+		return
+	}
+
+	result, err := h.blacklistService.Create(c.Request.Context(), tenantID, userID, &req)
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.Created(c, result)
+}
+
+// Update updates a blacklist entry
+func (h *BlacklistHandler) Update(c *gin.Context) {
+	tenantID := c.GetString("tenant_id")
+	userID := c.GetInt64("user_id")
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		response.ValidationError(c, map[string]string{"id": "invalid blacklist entry ID"})
+		return
+	}
+
+	var req dto.UpdateBlacklistEntryRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.ValidationError(c, err)
+		return
+	}
+
+	result, err := h.blacklistService.Update(c.Request.Context(), tenantID, userID, id, &req)
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.Success(c, result)
+}
+
+// Delete unblocks a blacklist entry
+func (h *BlacklistHandler) Delete(c *gin.Context) {
+	tenantID := c.GetString("tenant_id")
+	userID := c.GetInt64("user_id")
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		response.ValidationError(c, map[string]string{"id": "invalid blacklist entry ID"})
+		return
+	}
+
+	if err := h.blacklistService.Delete(c.Request.Context(), tenantID, userID, id); err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.Success(c, nil)
+}
+
+// Import blocks numbers in bulk
+func (h *BlacklistHandler) Import(c *gin.Context) {
+	tenantID := c.GetString("tenant_id")
+	userID := c.GetInt64("user_id")
+
+	var req dto.ImportBlacklistRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.ValidationError(c, err)
+		return
+	}
+
+	result, err := h.blacklistService.Import(c.Request.Context(), tenantID, userID, &req)
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.Success(c, result)
+}
+
+// ListAttempts lists the calls and SMS the blacklist refused
+func (h *BlacklistHandler) ListAttempts(c *gin.Context) {
+	tenantID := c.GetString("tenant_id")
+	userID := c.GetInt64("user_id")
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+
+	attempts, total, err := h.blacklistService.ListAttempts(c.Request.Context(), tenantID, userID, page, pageSize)
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	meta := response.NewMeta(page, pageSize, int(total))
+	response.SuccessWithMeta(c, attempts, meta)
+}
@@ -0,0 +1,166 @@
+package handler
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/psschand/callcenter/internal/dto"
+	"github.com/psschand/callcenter/internal/service"
+	"github.com/psschand/callcenter/pkg/response"
+)
+
+// CallHandler handles live call requests
+type CallHandler struct {
+	callService service.CallService
+}
+
+// NewCallHandler creates a new call handler
+func NewCallHandler(callService service.CallService) *CallHandler {
+	return &CallHandler{
+		callService: callService,
+	}
+}
+
+// Originate places a click-to-call from the current user's phone
+func (h *CallHandler) Originate(c *gin.Context) {
+	tenantID := c.GetString("tenant_id")
+	userID := c.GetInt64("user_id")
+
+	var req dto.OriginateCallRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.ValidationError(c, err)
+		return
+	}
+
+	result, err := h.callService.Originate(c.Request.Context(), tenantID, userID, &req)
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.Created(c, result)
+}
+
+// Hold puts a call on hold
+func (h *CallHandler) Hold(c *gin.Context) {
+	var req dto.HoldCallRequest
+	if !bindOptionalJSON(c, &req) {
+		return
+	}
+
+	result, err := h.callService.Hold(c.Request.Context(), c.GetString("tenant_id"), c.GetInt64("user_id"), c.Param("id"), &req)
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.Success(c, result)
+}
+
+// Unhold resumes a held call
+func (h *CallHandler) Unhold(c *gin.Context) {
+	result, err := h.callService.Unhold(c.Request.Context(), c.GetString("tenant_id"), c.GetInt64("user_id"), c.Param("id"))
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.Success(c, result)
+}
+
+// Mute mutes a call
+func (h *CallHandler) Mute(c *gin.Context) {
+	var req dto.MuteCallRequest
+	if !bindOptionalJSON(c, &req) {
+		return
+	}
+
+	result, err := h.callService.Mute(c.Request.Context(), c.GetString("tenant_id"), c.GetInt64("user_id"), c.Param("id"), &req)
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.Success(c, result)
+}
+
+// Unmute unmutes a call
+func (h *CallHandler) Unmute(c *gin.Context) {
+	var req dto.MuteCallRequest
+	if !bindOptionalJSON(c, &req) {
+		return
+	}
+
+	result, err := h.callService.Unmute(c.Request.Context(), c.GetString("tenant_id"), c.GetInt64("user_id"), c.Param("id"), &req)
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.Success(c, result)
+}
+
+// Transfer transfers a call to a user, queue or number
+func (h *CallHandler) Transfer(c *gin.Context) {
+	var req dto.TransferCallRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.ValidationError(c, err)
+		return
+	}
+
+	result, err := h.callService.Transfer(c.Request.Context(), c.GetString("tenant_id"), c.GetInt64("user_id"), c.Param("id"), &req)
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.Success(c, result)
+}
+
+// CompleteTransfer completes an attended transfer
+func (h *CallHandler) CompleteTransfer(c *gin.Context) {
+	result, err := h.callService.CompleteTransfer(c.Request.Context(), c.GetString("tenant_id"), c.GetInt64("user_id"), c.Param("id"))
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.Success(c, result)
+}
+
+// CancelTransfer cancels an attended transfer
+func (h *CallHandler) CancelTransfer(c *gin.Context) {
+	result, err := h.callService.CancelTransfer(c.Request.Context(), c.GetString("tenant_id"), c.GetInt64("user_id"), c.Param("id"))
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.Success(c, result)
+}
+
+// Hangup hangs up a call
+func (h *CallHandler) Hangup(c *gin.Context) {
+	var req dto.HangupCallRequest
+	if !bindOptionalJSON(c, &req) {
+		return
+	}
+
+	result, err := h.callService.Hangup(c.Request.Context(), c.GetString("tenant_id"), c.GetInt64("user_id"), c.Param("id"), &req)
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.Success(c, result)
+}
+
+// bindOptionalJSON binds a request body that may be omitted; it reports false after writing a validation error
+func bindOptionalJSON(c *gin.Context, req interface{}) bool {
+	if c.Request.ContentLength == 0 {
+		return true
+	}
+	if err := c.ShouldBindJSON(req); err != nil {
+		response.ValidationError(c, err)
+		return false
+	}
+	return true
+}
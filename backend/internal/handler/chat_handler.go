@@ -466,6 +466,10 @@ func (h *ChatHandler) flattenWidgetResponse(widget *dto.ChatWidgetResponse) map[
 		"require_email":          widget.RequireEmail,
 		"require_name":           widget.RequireName,
 		"business_hours_enabled": widget.BusinessHoursEnabled,
+		"schedule_id":            widget.ScheduleID,
+		"is_online":              widget.IsOnline,
+		"offline_message":        widget.OfflineMessage,
+		"next_open_at":           widget.NextOpenAt,
 		"embed_code":             widget.EmbedCode,
 		"created_at":             widget.CreatedAt,
 		"updated_at":             widget.UpdatedAt,
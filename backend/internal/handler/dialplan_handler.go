@@ -0,0 +1,48 @@
+package handler
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/psschand/callcenter/internal/service"
+	"github.com/psschand/callcenter/pkg/response"
+)
+
+// DialplanHandler handles the DID dialplan generated for Asterisk
+type DialplanHandler struct {
+	dialplanService service.DialplanService
+}
+
+// NewDialplanHandler creates a new dialplan handler
+func NewDialplanHandler(dialplanService service.DialplanService) *DialplanHandler {
+	return &DialplanHandler{
+		dialplanService: dialplanService,
+	}
+}
+
+// Preview shows the dialplan the DIDs would generate and how it differs from
+// the one Asterisk has
+func (h *DialplanHandler) Preview(c *gin.Context) {
+	tenantID := c.GetString("tenant_id")
+	userID := c.GetInt64("user_id")
+
+	result, err := h.dialplanService.Preview(c.Request.Context(), tenantID, userID)
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.Success(c, result)
+}
+
+// Apply writes the generated dialplan and reloads it in Asterisk
+func (h *DialplanHandler) Apply(c *gin.Context) {
+	tenantID := c.GetString("tenant_id")
+	userID := c.GetInt64("user_id")
+
+	result, err := h.dialplanService.Apply(c.Request.Context(), tenantID, userID)
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.Success(c, result)
+}
@@ -0,0 +1,55 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"path"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/psschand/callcenter/internal/storage"
+	"github.com/psschand/callcenter/pkg/response"
+)
+
+// FileHandler serves stored files through signed, time-limited URLs
+type FileHandler struct {
+	store  storage.Storage
+	signer *storage.URLSigner
+}
+
+// NewFileHandler creates a new file handler
+func NewFileHandler(store storage.Storage, signer *storage.URLSigner) *FileHandler {
+	return &FileHandler{
+		store:  store,
+		signer: signer,
+	}
+}
+
+// Download serves a stored file. It needs no authentication: the URL's
+// signature grants access to the one file until the URL expires.
+func (h *FileHandler) Download(c *gin.Context) {
+	key := strings.TrimPrefix(c.Param("key"), "/")
+
+	if err := h.signer.Verify(key, c.Query("expires"), c.Query("signature")); err != nil {
+		response.Forbidden(c, "invalid or expired download link")
+		return
+	}
+
+	object, err := h.store.Open(c.Request.Context(), key)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) || errors.Is(err, storage.ErrInvalidKey) {
+			response.NotFound(c, "file not found")
+			return
+		}
+		log.Printf("Error opening stored file %s: %v", key, err)
+		response.InternalError(c, "failed to open file")
+		return
+	}
+	defer object.Content.Close()
+
+	name := path.Base(key)
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", name))
+	http.ServeContent(c.Writer, c.Request, name, object.ModTime, object.Content)
+}
@@ -0,0 +1,193 @@
+package handler
+
+import (
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/psschand/callcenter/internal/dto"
+	"github.com/psschand/callcenter/internal/service"
+	"github.com/psschand/callcenter/pkg/response"
+)
+
+// IVRHandler handles IVR menu requests
+type IVRHandler struct {
+	ivrService service.IVRService
+}
+
+// NewIVRHandler creates a new IVR handler
+func NewIVRHandler(ivrService service.IVRService) *IVRHandler {
+	return &IVRHandler{
+		ivrService: ivrService,
+	}
+}
+
+// Create creates a new IVR menu
+func (h *IVRHandler) Create(c *gin.Context) {
+	tenantID := c.GetString("tenant_id")
+
+	var req dto.CreateIVRMenuRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.ValidationError(c, err)
+		return
+	}
+
+	result, err := h.ivrService.Create(c.Request.Context(), tenantID, &req)
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.Created(c, result)
+}
+
+// Get gets an IVR menu with its options
+func (h *IVRHandler) Get(c *gin.Context) {
+	tenantID := c.GetString("tenant_id")
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		response.ValidationError(c, map[string]string{"id": "invalid IVR menu ID"})
+		return
+	}
+
+	result, err := h.ivrService.GetByID(c.Request.Context(), tenantID, id)
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.Success(c, result)
+}
+
+// List lists all IVR menus for the current tenant
+func (h *IVRHandler) List(c *gin.Context) {
+	tenantID := c.GetString("tenant_id")
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+
+	menus, total, err := h.ivrService.GetByTenant(c.Request.Context(), tenantID, page, pageSize)
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	meta := response.NewMeta(page, pageSize, int(total))
+	response.SuccessWithMeta(c, menus, meta)
+}
+
+// Update updates an IVR menu
+func (h *IVRHandler) Update(c *gin.Context) {
+	tenantID := c.GetString("tenant_id")
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		response.ValidationError(c, map[string]string{"id": "invalid IVR menu ID"})
+		return
+	}
+
+	var req dto.UpdateIVRMenuRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.ValidationError(c, err)
+		return
+	}
+
+	result, err := h.ivrService.Update(c.Request.Context(), tenantID, id, &req)
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.Success(c, result)
+}
+
+// Delete deletes an IVR menu
+func (h *IVRHandler) Delete(c *gin.Context) {
+	tenantID := c.GetString("tenant_id")
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		response.ValidationError(c, map[string]string{"id": "invalid IVR menu ID"})
+		return
+	}
+
+	if err := h.ivrService.Delete(c.Request.Context(), tenantID, id); err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.Success(c, nil)
+}
+
+// AddOption adds an option to an IVR menu
+func (h *IVRHandler) AddOption(c *gin.Context) {
+	tenantID := c.GetString("tenant_id")
+	menuID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		response.ValidationError(c, map[string]string{"id": "invalid IVR menu ID"})
+		return
+	}
+
+	var req dto.CreateIVROptionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.ValidationError(c, err)
+		return
+	}
+
+	result, err := h.ivrService.AddOption(c.Request.Context(), tenantID, menuID, &req)
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.Created(c, result)
+}
+
+// UpdateOption updates an IVR menu option
+func (h *IVRHandler) UpdateOption(c *gin.Context) {
+	tenantID := c.GetString("tenant_id")
+	menuID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		response.ValidationError(c, map[string]string{"id": "invalid IVR menu ID"})
+		return
+	}
+
+	optionID, err := strconv.ParseInt(c.Param("optionId"), 10, 64)
+	if err != nil {
+		response.ValidationError(c, map[string]string{"optionId": "invalid option ID"})
+		return
+	}
+
+	var req dto.UpdateIVROptionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.ValidationError(c, err)
+		return
+	}
+
+	result, err := h.ivrService.UpdateOption(c.Request.Context(), tenantID, menuID, optionID, &req)
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.Success(c, result)
+}
+
+// DeleteOption deletes an IVR menu option
+func (h *IVRHandler) DeleteOption(c *gin.Context) {
+	tenantID := c.GetString("tenant_id")
+	menuID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		response.ValidationError(c, map[string]string{"id": "invalid IVR menu ID"})
+		return
+	}
+
+	optionID, err := strconv.ParseInt(c.Param("optionId"), 10, 64)
+	if err != nil {
+		response.ValidationError(c, map[string]string{"optionId": "invalid option ID"})
+		return
+	}
+
+	if err := h.ivrService.DeleteOption(c.Request.Context(), tenantID, menuID, optionID); err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.Success(c, nil)
+}
@@ -0,0 +1,121 @@
+package handler
+
+import (
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/psschand/callcenter/internal/dto"
+	"github.com/psschand/callcenter/internal/service"
+	"github.com/psschand/callcenter/pkg/response"
+)
+
+// MonitorHandler handles supervisor call monitoring requests
+type MonitorHandler struct {
+	monitorService service.MonitorService
+}
+
+// NewMonitorHandler creates a new monitor handler
+func NewMonitorHandler(monitorService service.MonitorService) *MonitorHandler {
+	return &MonitorHandler{
+		monitorService: monitorService,
+	}
+}
+
+// Start starts listening to, whispering on or barging into a live call
+func (h *MonitorHandler) Start(c *gin.Context) {
+	tenantID := c.GetString("tenant_id")
+	userID := c.GetInt64("user_id")
+
+	var req dto.StartMonitorRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.ValidationError(c, err)
+		return
+	}
+
+	result, err := h.monitorService.Start(c.Request.Context(), tenantID, userID, &req)
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.Created(c, result)
+}
+
+// List lists monitor sessions, optionally filtered by supervisor_id
+func (h *MonitorHandler) List(c *gin.Context) {
+	tenantID := c.GetString("tenant_id")
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+	supervisorID, _ := strconv.ParseInt(c.Query("supervisor_id"), 10, 64)
+
+	sessions, total, err := h.monitorService.GetByTenant(c.Request.Context(), tenantID, supervisorID, page, pageSize)
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	meta := response.NewMeta(page, pageSize, int(total))
+	response.SuccessWithMeta(c, sessions, meta)
+}
+
+// GetByID gets a monitor session with its audit trail
+func (h *MonitorHandler) GetByID(c *gin.Context) {
+	tenantID := c.GetString("tenant_id")
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		response.ValidationError(c, map[string]string{"id": "invalid monitor session ID"})
+		return
+	}
+
+	result, err := h.monitorService.GetByID(c.Request.Context(), tenantID, id)
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.Success(c, result)
+}
+
+// SetMode switches a monitor session between listen, whisper and barge
+func (h *MonitorHandler) SetMode(c *gin.Context) {
+	tenantID := c.GetString("tenant_id")
+	userID := c.GetInt64("user_id")
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		response.ValidationError(c, map[string]string{"id": "invalid monitor session ID"})
+		return
+	}
+
+	var req dto.UpdateMonitorModeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.ValidationError(c, err)
+		return
+	}
+
+	result, err := h.monitorService.SetMode(c.Request.Context(), tenantID, userID, id, &req)
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.Success(c, result)
+}
+
+// Stop ends a monitor session
+func (h *MonitorHandler) Stop(c *gin.Context) {
+	tenantID := c.GetString("tenant_id")
+	userID := c.GetInt64("user_id")
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		response.ValidationError(c, map[string]string{"id": "invalid monitor session ID"})
+		return
+	}
+
+	result, err := h.monitorService.Stop(c.Request.Context(), tenantID, userID, id)
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.Success(c, result)
+}
@@ -349,6 +349,23 @@ func (h *PublicChatHandler) GetSessionStatus(c *gin.Context) {
 	})
 }
 
+// GetWidgetStatus reports whether a widget is online, so it can show its
+// offline message outside business hours
+func (h *PublicChatHandler) GetWidgetStatus(c *gin.Context) {
+	widget, err := h.chatService.GetWidgetByKey(c.Request.Context(), c.Param("widget_key"))
+	if err != nil {
+		response.NotFound(c, "Widget not found")
+		return
+	}
+
+	response.Success(c, gin.H{
+		"widget_key":      widget.WidgetKey,
+		"is_online":       widget.IsOnline,
+		"offline_message": widget.OfflineMessage,
+		"next_open_at":    widget.NextOpenAt,
+	})
+}
+
 // EndSession ends a chat session
 func (h *PublicChatHandler) EndSession(c *gin.Context) {
 	var req PublicEndSessionRequest
@@ -0,0 +1,92 @@
+package handler
+
+import (
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/psschand/callcenter/internal/dto"
+	"github.com/psschand/callcenter/internal/service"
+	"github.com/psschand/callcenter/pkg/response"
+)
+
+// QueueCallbackHandler handles the callbacks callers request instead of waiting on hold
+type QueueCallbackHandler struct {
+	callbackService service.QueueCallbackService
+}
+
+// NewQueueCallbackHandler creates a new queue callback handler
+func NewQueueCallbackHandler(callbackService service.QueueCallbackService) *QueueCallbackHandler {
+	return &QueueCallbackHandler{
+		callbackService: callbackService,
+	}
+}
+
+// List lists the tenant's callbacks, optionally for one queue or status.
+// Giving start_date or end_date limits them to those requested in the
+// range, which runs from and to today when either is left out.
+func (h *QueueCallbackHandler) List(c *gin.Context) {
+	tenantID := c.GetString("tenant_id")
+	userID := c.GetInt64("user_id")
+
+	query := &dto.QueueCallbackQuery{
+		QueueName: c.Query("queue"),
+		Status:    c.Query("status"),
+	}
+	if c.Query("start_date") != "" || c.Query("end_date") != "" {
+		start, end, ok := reportDateRange(c)
+		if !ok {
+			return
+		}
+		query.StartDate = start
+		query.EndDate = end
+	}
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+
+	result, total, err := h.callbackService.List(c.Request.Context(), tenantID, userID, query, page, pageSize)
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	meta := response.NewMeta(page, pageSize, int(total))
+	response.SuccessWithMeta(c, result, meta)
+}
+
+// Get gets a callback
+func (h *QueueCallbackHandler) Get(c *gin.Context) {
+	tenantID := c.GetString("tenant_id")
+	userID := c.GetInt64("user_id")
+	id, err := strconv.ParseInt(c.Param("callbackId"), 10, 64)
+	if err != nil {
+		response.ValidationError(c, map[string]string{"callbackId": "invalid callback ID"})
+		return
+	}
+
+	result, err := h.callbackService.Get(c.Request.Context(), tenantID, userID, id)
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.Success(c, result)
+}
+
+// Cancel takes a callback out of its queue before it is dialed
+func (h *QueueCallbackHandler) Cancel(c *gin.Context) {
+	tenantID := c.GetString("tenant_id")
+	userID := c.GetInt64("user_id")
+	id, err := strconv.ParseInt(c.Param("callbackId"), 10, 64)
+	if err != nil {
+		response.ValidationError(c, map[string]string{"callbackId": "invalid callback ID"})
+		return
+	}
+
+	result, err := h.callbackService.Cancel(c.Request.Context(), tenantID, userID, id)
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.Success(c, result)
+}
@@ -0,0 +1,122 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/psschand/callcenter/internal/service"
+	"github.com/psschand/callcenter/pkg/response"
+)
+
+// RecordingHandler handles call recording requests
+type RecordingHandler struct {
+	recordingService service.RecordingService
+}
+
+// NewRecordingHandler creates a new recording handler
+func NewRecordingHandler(recordingService service.RecordingService) *RecordingHandler {
+	return &RecordingHandler{
+		recordingService: recordingService,
+	}
+}
+
+// List lists the tenant's call recordings
+func (h *RecordingHandler) List(c *gin.Context) {
+	tenantID := c.GetString("tenant_id")
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+
+	recordings, total, err := h.recordingService.GetByTenant(c.Request.Context(), tenantID, page, pageSize)
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	meta := response.NewMeta(page, pageSize, int(total))
+	response.SuccessWithMeta(c, recordings, meta)
+}
+
+// Get gets a call recording
+func (h *RecordingHandler) Get(c *gin.Context) {
+	tenantID := c.GetString("tenant_id")
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		response.ValidationError(c, map[string]string{"id": "invalid recording ID"})
+		return
+	}
+
+	result, err := h.recordingService.GetByID(c.Request.Context(), tenantID, id)
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.Success(c, result)
+}
+
+// Stream streams a call recording's audio, honouring Range requests so players can seek
+func (h *RecordingHandler) Stream(c *gin.Context) {
+	tenantID := c.GetString("tenant_id")
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		response.ValidationError(c, map[string]string{"id": "invalid recording ID"})
+		return
+	}
+
+	recording, err := h.recordingService.Open(c.Request.Context(), tenantID, id)
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+	defer recording.Content.Close()
+
+	c.Header("Content-Type", recording.ContentType)
+	c.Header("Content-Disposition", fmt.Sprintf("inline; filename=%q", recording.Filename))
+	http.ServeContent(c.Writer, c.Request, recording.Filename, recording.ModTime, recording.Content)
+}
+
+// SignedURL creates a time-limited download URL for a call recording.
+// expires_in optionally sets its lifetime in seconds.
+func (h *RecordingHandler) SignedURL(c *gin.Context) {
+	tenantID := c.GetString("tenant_id")
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		response.ValidationError(c, map[string]string{"id": "invalid recording ID"})
+		return
+	}
+
+	expiresIn, err := strconv.Atoi(c.DefaultQuery("expires_in", "0"))
+	if err != nil {
+		response.ValidationError(c, map[string]string{"expires_in": "must be a number of seconds"})
+		return
+	}
+
+	result, err := h.recordingService.SignedURL(c.Request.Context(), tenantID, id, time.Duration(expiresIn)*time.Second)
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.Success(c, result)
+}
+
+// Delete deletes a call recording
+func (h *RecordingHandler) Delete(c *gin.Context) {
+	tenantID := c.GetString("tenant_id")
+	userID := c.GetInt64("user_id")
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		response.ValidationError(c, map[string]string{"id": "invalid recording ID"})
+		return
+	}
+
+	if err := h.recordingService.Delete(c.Request.Context(), tenantID, userID, id); err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.Success(c, nil)
+}
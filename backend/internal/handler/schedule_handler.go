@@ -0,0 +1,147 @@
+package handler
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/psschand/callcenter/internal/dto"
+	"github.com/psschand/callcenter/internal/service"
+	"github.com/psschand/callcenter/pkg/response"
+)
+
+// ScheduleHandler handles business hours and holiday schedules
+type ScheduleHandler struct {
+	scheduleService service.ScheduleService
+}
+
+// NewScheduleHandler creates a new schedule handler
+func NewScheduleHandler(scheduleService service.ScheduleService) *ScheduleHandler {
+	return &ScheduleHandler{
+		scheduleService: scheduleService,
+	}
+}
+
+// List lists the tenant's schedules
+func (h *ScheduleHandler) List(c *gin.Context) {
+	tenantID := c.GetString("tenant_id")
+	userID := c.GetInt64("user_id")
+
+	result, err := h.scheduleService.List(c.Request.Context(), tenantID, userID)
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.Success(c, result)
+}
+
+// Get gets a schedule
+func (h *ScheduleHandler) Get(c *gin.Context) {
+	tenantID := c.GetString("tenant_id")
+	userID := c.GetInt64("user_id")
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		response.ValidationError(c, map[string]string{"id": "invalid schedule ID"})
+		return
+	}
+
+	result, err := h.scheduleService.Get(c.Request.Context(), tenantID, userID, id)
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.Success(c, result)
+}
+
+// Create creates a schedule
+func (h *ScheduleHandler) Create(c *gin.Context) {
+	tenantID := c.GetString("tenant_id")
+	userID := c.GetInt64("user_id")
+
+	var req dto.CreateScheduleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.ValidationError(c, err)
+		return
+	}
+
+	result, err := h.scheduleService.Create(c.Request.Context(), tenantID, userID, &req)
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.Created(c, result)
+}
+
+// Update updates a schedule
+func (h *ScheduleHandler) Update(c *gin.Context) {
+	tenantID := c.GetString("tenant_id")
+	userID := c.GetInt64("user_id")
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		response.ValidationError(c, map[string]string{"id": "invalid schedule ID"})
+		return
+	}
+
+	var req dto.UpdateScheduleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.ValidationError(c, err)
+		return
+	}
+
+	result, err := h.scheduleService.Update(c.Request.Context(), tenantID, userID, id, &req)
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.Success(c, result)
+}
+
+// Delete deletes a schedule
+func (h *ScheduleHandler) Delete(c *gin.Context) {
+	tenantID := c.GetString("tenant_id")
+	userID := c.GetInt64("user_id")
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		response.ValidationError(c, map[string]string{"id": "invalid schedule ID"})
+		return
+	}
+
+	if err := h.scheduleService.Delete(c.Request.Context(), tenantID, userID, id); err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.Success(c, nil)
+}
+
+// Status reports whether a schedule is open now, or at the RFC 3339 time
+// given as "at"
+func (h *ScheduleHandler) Status(c *gin.Context) {
+	tenantID := c.GetString("tenant_id")
+	userID := c.GetInt64("user_id")
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		response.ValidationError(c, map[string]string{"id": "invalid schedule ID"})
+		return
+	}
+
+	at := time.Now()
+	if atStr := c.Query("at"); atStr != "" {
+		if at, err = time.Parse(time.RFC3339, atStr); err != nil {
+			response.ValidationError(c, map[string]string{"at": "must be an RFC 3339 time"})
+			return
+		}
+	}
+
+	result, err := h.scheduleService.Status(c.Request.Context(), tenantID, userID, id, at)
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.Success(c, result)
+}
@@ -0,0 +1,137 @@
+package handler
+
+import (
+	stderrors "errors"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/psschand/callcenter/internal/common"
+	"github.com/psschand/callcenter/internal/dto"
+	"github.com/psschand/callcenter/internal/service"
+	"github.com/psschand/callcenter/internal/sms"
+	"github.com/psschand/callcenter/pkg/response"
+)
+
+// SMSHandler handles SMS messages and the SMS provider's callbacks
+type SMSHandler struct {
+	smsService service.SMSService
+	provider   sms.Provider
+}
+
+// NewSMSHandler creates a new SMS handler. provider is nil when SMS is turned off.
+func NewSMSHandler(smsService service.SMSService, provider sms.Provider) *SMSHandler {
+	return &SMSHandler{
+		smsService: smsService,
+		provider:   provider,
+	}
+}
+
+// Send sends an SMS message from one of the tenant's numbers
+func (h *SMSHandler) Send(c *gin.Context) {
+	tenantID := c.GetString("tenant_id")
+	userID := c.GetInt64("user_id")
+
+	var req dto.SendSMSRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.ValidationError(c, err)
+		return
+	}
+
+	result, err := h.smsService.Send(c.Request.Context(), tenantID, userID, &req)
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.Created(c, result)
+}
+
+// List lists the tenant's SMS messages. direction, status and number
+// (sender or recipient) narrow the list.
+func (h *SMSHandler) List(c *gin.Context) {
+	tenantID := c.GetString("tenant_id")
+	userID := c.GetInt64("user_id")
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+	direction := common.SMSDirection(c.Query("direction"))
+	status := common.SMSStatus(c.Query("status"))
+
+	messages, total, err := h.smsService.List(c.Request.Context(), tenantID, userID, direction, status, c.Query("number"), page, pageSize)
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	meta := response.NewMeta(page, pageSize, int(total))
+	response.SuccessWithMeta(c, messages, meta)
+}
+
+// Get gets an SMS message
+func (h *SMSHandler) Get(c *gin.Context) {
+	tenantID := c.GetString("tenant_id")
+	userID := c.GetInt64("user_id")
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		response.ValidationError(c, map[string]string{"id": "invalid SMS message ID"})
+		return
+	}
+
+	result, err := h.smsService.GetByID(c.Request.Context(), tenantID, userID, id)
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.Success(c, result)
+}
+
+// Inbound receives the provider's callback for a message sent to one of the tenants' numbers
+func (h *SMSHandler) Inbound(c *gin.Context) {
+	if h.provider == nil {
+		response.NotFound(c, "SMS is not configured")
+		return
+	}
+
+	msg, err := h.provider.ParseInbound(c.Request)
+	if err != nil {
+		h.callbackError(c, err)
+		return
+	}
+
+	if err := h.smsService.ReceiveInbound(c.Request.Context(), msg); err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.NoContent(c)
+}
+
+// Status receives the provider's delivery status callback for a message sent
+func (h *SMSHandler) Status(c *gin.Context) {
+	if h.provider == nil {
+		response.NotFound(c, "SMS is not configured")
+		return
+	}
+
+	update, err := h.provider.ParseStatus(c.Request)
+	if err != nil {
+		h.callbackError(c, err)
+		return
+	}
+
+	if err := h.smsService.UpdateStatus(c.Request.Context(), update); err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.NoContent(c)
+}
+
+// callbackError responds to a callback that could not be parsed
+func (h *SMSHandler) callbackError(c *gin.Context, err error) {
+	if stderrors.Is(err, sms.ErrInvalidSignature) {
+		response.Forbidden(c, "invalid signature")
+		return
+	}
+	response.BadRequest(c, err.Error())
+}
@@ -0,0 +1,62 @@
+package handler
+
+import (
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/psschand/callcenter/internal/service"
+	"github.com/psschand/callcenter/pkg/response"
+)
+
+// SoftphoneHandler handles agents' softphone settings
+type SoftphoneHandler struct {
+	softphoneService service.SoftphoneService
+}
+
+// NewSoftphoneHandler creates a new softphone handler
+func NewSoftphoneHandler(softphoneService service.SoftphoneService) *SoftphoneHandler {
+	return &SoftphoneHandler{
+		softphoneService: softphoneService,
+	}
+}
+
+// GetConfig gets the current user's softphone settings
+func (h *SoftphoneHandler) GetConfig(c *gin.Context) {
+	result, err := h.softphoneService.GetConfig(c.Request.Context(), c.GetString("tenant_id"), c.GetInt64("user_id"))
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.Success(c, result)
+}
+
+// RotatePassword gives the current user's softphone a new SIP password
+func (h *SoftphoneHandler) RotatePassword(c *gin.Context) {
+	userID := c.GetInt64("user_id")
+
+	result, err := h.softphoneService.RotatePassword(c.Request.Context(), c.GetString("tenant_id"), userID, userID)
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.Success(c, result)
+}
+
+// RotateUserPassword gives a user's softphone a new SIP password
+func (h *SoftphoneHandler) RotateUserPassword(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		response.ValidationError(c, map[string]string{"id": "invalid user ID"})
+		return
+	}
+
+	result, err := h.softphoneService.RotatePassword(c.Request.Context(), c.GetString("tenant_id"), c.GetInt64("user_id"), id)
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.Success(c, result)
+}
@@ -0,0 +1,194 @@
+package handler
+
+import (
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/psschand/callcenter/internal/dto"
+	"github.com/psschand/callcenter/internal/service"
+	"github.com/psschand/callcenter/pkg/response"
+)
+
+// SurveyHandler handles post-call surveys, their responses and score reports
+type SurveyHandler struct {
+	surveyService service.SurveyService
+}
+
+// NewSurveyHandler creates a new survey handler
+func NewSurveyHandler(surveyService service.SurveyService) *SurveyHandler {
+	return &SurveyHandler{
+		surveyService: surveyService,
+	}
+}
+
+// List lists the tenant's surveys
+func (h *SurveyHandler) List(c *gin.Context) {
+	tenantID := c.GetString("tenant_id")
+	userID := c.GetInt64("user_id")
+
+	result, err := h.surveyService.List(c.Request.Context(), tenantID, userID)
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.Success(c, result)
+}
+
+// Get gets a survey
+func (h *SurveyHandler) Get(c *gin.Context) {
+	tenantID := c.GetString("tenant_id")
+	userID := c.GetInt64("user_id")
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		response.ValidationError(c, map[string]string{"id": "invalid survey ID"})
+		return
+	}
+
+	result, err := h.surveyService.Get(c.Request.Context(), tenantID, userID, id)
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.Success(c, result)
+}
+
+// Create creates a survey
+func (h *SurveyHandler) Create(c *gin.Context) {
+	tenantID := c.GetString("tenant_id")
+	userID := c.GetInt64("user_id")
+
+	var req dto.CreateSurveyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.ValidationError(c, err)
+		return
+	}
+
+	result, err := h.surveyService.Create(c.Request.Context(), tenantID, userID, &req)
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.Created(c, result)
+}
+
+// Update updates a survey
+func (h *SurveyHandler) Update(c *gin.Context) {
+	tenantID := c.GetString("tenant_id")
+	userID := c.GetInt64("user_id")
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		response.ValidationError(c, map[string]string{"id": "invalid survey ID"})
+		return
+	}
+
+	var req dto.UpdateSurveyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.ValidationError(c, err)
+		return
+	}
+
+	result, err := h.surveyService.Update(c.Request.Context(), tenantID, userID, id, &req)
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.Success(c, result)
+}
+
+// Delete deletes a survey with its responses
+func (h *SurveyHandler) Delete(c *gin.Context) {
+	tenantID := c.GetString("tenant_id")
+	userID := c.GetInt64("user_id")
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		response.ValidationError(c, map[string]string{"id": "invalid survey ID"})
+		return
+	}
+
+	if err := h.surveyService.Delete(c.Request.Context(), tenantID, userID, id); err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.Success(c, nil)
+}
+
+// ListResponses lists callers' survey answers between start_date and
+// end_date (today by default), optionally for one survey, agent (user_id)
+// or queue
+func (h *SurveyHandler) ListResponses(c *gin.Context) {
+	tenantID := c.GetString("tenant_id")
+	userID := c.GetInt64("user_id")
+
+	query, ok := surveyResponseQuery(c)
+	if !ok {
+		return
+	}
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+
+	result, total, err := h.surveyService.ListResponses(c.Request.Context(), tenantID, userID, query, page, pageSize)
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	meta := response.NewMeta(page, pageSize, int(total))
+	response.SuccessWithMeta(c, result, meta)
+}
+
+// Report reports CSAT and NPS scores per agent and per queue between
+// start_date and end_date (today by default), with the same filters as
+// ListResponses
+func (h *SurveyHandler) Report(c *gin.Context) {
+	tenantID := c.GetString("tenant_id")
+	userID := c.GetInt64("user_id")
+
+	query, ok := surveyResponseQuery(c)
+	if !ok {
+		return
+	}
+
+	result, err := h.surveyService.Report(c.Request.Context(), tenantID, userID, query)
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.Success(c, result)
+}
+
+// surveyResponseQuery parses the date range and filters of a survey
+// response listing or report
+func surveyResponseQuery(c *gin.Context) (*dto.SurveyResponseQuery, bool) {
+	start, end, ok := reportDateRange(c)
+	if !ok {
+		return nil, false
+	}
+	query := &dto.SurveyResponseQuery{
+		StartDate: start,
+		EndDate:   end,
+		QueueName: c.Query("queue"),
+	}
+	if surveyIDStr := c.Query("survey_id"); surveyIDStr != "" {
+		surveyID, err := strconv.ParseInt(surveyIDStr, 10, 64)
+		if err != nil {
+			response.ValidationError(c, map[string]string{"survey_id": "invalid survey ID"})
+			return nil, false
+		}
+		query.SurveyID = surveyID
+	}
+	if userIDStr := c.Query("user_id"); userIDStr != "" {
+		userID, err := strconv.ParseInt(userIDStr, 10, 64)
+		if err != nil {
+			response.ValidationError(c, map[string]string{"user_id": "invalid user ID"})
+			return nil, false
+		}
+		query.UserID = userID
+	}
+	return query, true
+}
@@ -0,0 +1,143 @@
+package handler
+
+import (
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/psschand/callcenter/internal/common"
+	"github.com/psschand/callcenter/internal/service"
+	"github.com/psschand/callcenter/pkg/response"
+)
+
+// TranscriptHandler handles transcripts of call recordings and voicemails
+type TranscriptHandler struct {
+	transcriptionService service.TranscriptionService
+	recordingService     service.RecordingService
+	voicemailService     service.VoicemailService
+}
+
+// NewTranscriptHandler creates a new transcript handler
+func NewTranscriptHandler(
+	transcriptionService service.TranscriptionService,
+	recordingService service.RecordingService,
+	voicemailService service.VoicemailService,
+) *TranscriptHandler {
+	return &TranscriptHandler{
+		transcriptionService: transcriptionService,
+		recordingService:     recordingService,
+		voicemailService:     voicemailService,
+	}
+}
+
+// GetRecordingTranscript gets a call recording's transcript
+func (h *TranscriptHandler) GetRecordingTranscript(c *gin.Context) {
+	id, ok := h.recordingID(c)
+	if !ok {
+		return
+	}
+
+	result, err := h.transcriptionService.GetTranscript(c.Request.Context(), c.GetString("tenant_id"), common.TranscriptSourceRecording, id)
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.Success(c, result)
+}
+
+// TranscribeRecording queues a call recording for transcription
+func (h *TranscriptHandler) TranscribeRecording(c *gin.Context) {
+	id, ok := h.recordingID(c)
+	if !ok {
+		return
+	}
+
+	result, err := h.transcriptionService.Transcribe(c.Request.Context(), c.GetString("tenant_id"), common.TranscriptSourceRecording, id)
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.Success(c, result)
+}
+
+// GetVoicemailTranscript gets a voicemail's transcript
+func (h *TranscriptHandler) GetVoicemailTranscript(c *gin.Context) {
+	id, ok := h.voicemailID(c)
+	if !ok {
+		return
+	}
+
+	result, err := h.transcriptionService.GetTranscript(c.Request.Context(), c.GetString("tenant_id"), common.TranscriptSourceVoicemail, id)
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.Success(c, result)
+}
+
+// TranscribeVoicemail queues a voicemail for transcription
+func (h *TranscriptHandler) TranscribeVoicemail(c *gin.Context) {
+	id, ok := h.voicemailID(c)
+	if !ok {
+		return
+	}
+
+	result, err := h.transcriptionService.Transcribe(c.Request.Context(), c.GetString("tenant_id"), common.TranscriptSourceVoicemail, id)
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.Success(c, result)
+}
+
+// Search searches transcripts for the words in q. source=recording or
+// source=voicemail limits it to one kind.
+func (h *TranscriptHandler) Search(c *gin.Context) {
+	tenantID := c.GetString("tenant_id")
+	userID := c.GetInt64("user_id")
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+	source := common.TranscriptSource(c.Query("source"))
+
+	results, total, err := h.transcriptionService.Search(c.Request.Context(), tenantID, userID, c.Query("q"), source, page, pageSize)
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	meta := response.NewMeta(page, pageSize, int(total))
+	response.SuccessWithMeta(c, results, meta)
+}
+
+// recordingID parses the recording ID and checks the recording is the tenant's
+func (h *TranscriptHandler) recordingID(c *gin.Context) (int64, bool) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		response.ValidationError(c, map[string]string{"id": "invalid recording ID"})
+		return 0, false
+	}
+
+	if _, err := h.recordingService.GetByID(c.Request.Context(), c.GetString("tenant_id"), id); err != nil {
+		response.Error(c, err)
+		return 0, false
+	}
+	return id, true
+}
+
+// voicemailID parses the voicemail ID and checks the user may access the voicemail
+func (h *TranscriptHandler) voicemailID(c *gin.Context) (int64, bool) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		response.ValidationError(c, map[string]string{"id": "invalid voicemail ID"})
+		return 0, false
+	}
+
+	if _, err := h.voicemailService.GetByID(c.Request.Context(), c.GetString("tenant_id"), c.GetInt64("user_id"), id); err != nil {
+		response.Error(c, err)
+		return 0, false
+	}
+	return id, true
+}
@@ -0,0 +1,233 @@
+package handler
+
+import (
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/psschand/callcenter/internal/dto"
+	"github.com/psschand/callcenter/internal/service"
+	"github.com/psschand/callcenter/pkg/response"
+)
+
+// TrunkHandler handles SIP trunks and outbound routes
+type TrunkHandler struct {
+	trunkService service.TrunkService
+}
+
+// NewTrunkHandler creates a new trunk handler
+func NewTrunkHandler(trunkService service.TrunkService) *TrunkHandler {
+	return &TrunkHandler{
+		trunkService: trunkService,
+	}
+}
+
+// ListTrunks lists the tenant's and the platform's trunks
+func (h *TrunkHandler) ListTrunks(c *gin.Context) {
+	tenantID := c.GetString("tenant_id")
+	userID := c.GetInt64("user_id")
+
+	result, err := h.trunkService.ListTrunks(c.Request.Context(), tenantID, userID)
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.Success(c, result)
+}
+
+// GetTrunk gets a trunk
+func (h *TrunkHandler) GetTrunk(c *gin.Context) {
+	tenantID := c.GetString("tenant_id")
+	userID := c.GetInt64("user_id")
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		response.ValidationError(c, map[string]string{"id": "invalid trunk ID"})
+		return
+	}
+
+	result, err := h.trunkService.GetTrunk(c.Request.Context(), tenantID, userID, id)
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.Success(c, result)
+}
+
+// CreateTrunk creates a trunk
+func (h *TrunkHandler) CreateTrunk(c *gin.Context) {
+	tenantID := c.GetString("tenant_id")
+	userID := c.GetInt64("user_id")
+
+	var req dto.CreateTrunkRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.ValidationError(c, err)
+		return
+	}
+
+	result, err := h.trunkService.CreateTrunk(c.Request.Context(), tenantID, userID, &req)
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.Created(c, result)
+}
+
+// UpdateTrunk updates a trunk
+func (h *TrunkHandler) UpdateTrunk(c *gin.Context) {
+	tenantID := c.GetString("tenant_id")
+	userID := c.GetInt64("user_id")
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		response.ValidationError(c, map[string]string{"id": "invalid trunk ID"})
+		return
+	}
+
+	var req dto.UpdateTrunkRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.ValidationError(c, err)
+		return
+	}
+
+	result, err := h.trunkService.UpdateTrunk(c.Request.Context(), tenantID, userID, id, &req)
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.Success(c, result)
+}
+
+// DeleteTrunk deletes a trunk
+func (h *TrunkHandler) DeleteTrunk(c *gin.Context) {
+	tenantID := c.GetString("tenant_id")
+	userID := c.GetInt64("user_id")
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		response.ValidationError(c, map[string]string{"id": "invalid trunk ID"})
+		return
+	}
+
+	if err := h.trunkService.DeleteTrunk(c.Request.Context(), tenantID, userID, id); err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.Success(c, nil)
+}
+
+// GetTrunkStatus gets a trunk's state and channel usage
+func (h *TrunkHandler) GetTrunkStatus(c *gin.Context) {
+	tenantID := c.GetString("tenant_id")
+	userID := c.GetInt64("user_id")
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		response.ValidationError(c, map[string]string{"id": "invalid trunk ID"})
+		return
+	}
+
+	result, err := h.trunkService.GetTrunkStatus(c.Request.Context(), tenantID, userID, id)
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.Success(c, result)
+}
+
+// ListRoutes lists the outbound routes that apply to the tenant
+func (h *TrunkHandler) ListRoutes(c *gin.Context) {
+	tenantID := c.GetString("tenant_id")
+	userID := c.GetInt64("user_id")
+
+	result, err := h.trunkService.ListRoutes(c.Request.Context(), tenantID, userID)
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.Success(c, result)
+}
+
+// GetRoute gets an outbound route
+func (h *TrunkHandler) GetRoute(c *gin.Context) {
+	tenantID := c.GetString("tenant_id")
+	userID := c.GetInt64("user_id")
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		response.ValidationError(c, map[string]string{"id": "invalid outbound route ID"})
+		return
+	}
+
+	result, err := h.trunkService.GetRoute(c.Request.Context(), tenantID, userID, id)
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.Success(c, result)
+}
+
+// CreateRoute creates an outbound route
+func (h *TrunkHandler) CreateRoute(c *gin.Context) {
+	tenantID := c.GetString("tenant_id")
+	userID := c.GetInt64("user_id")
+
+	var req dto.CreateOutboundRouteRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.ValidationError(c, err)
+		return
+	}
+
+	result, err := h.trunkService.CreateRoute(c.Request.Context(), tenantID, userID, &req)
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.Created(c, result)
+}
+
+// UpdateRoute updates an outbound route
+func (h *TrunkHandler) UpdateRoute(c *gin.Context) {
+	tenantID := c.GetString("tenant_id")
+	userID := c.GetInt64("user_id")
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		response.ValidationError(c, map[string]string{"id": "invalid outbound route ID"})
+		return
+	}
+
+	var req dto.UpdateOutboundRouteRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.ValidationError(c, err)
+		return
+	}
+
+	result, err := h.trunkService.UpdateRoute(c.Request.Context(), tenantID, userID, id, &req)
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.Success(c, result)
+}
+
+// DeleteRoute deletes an outbound route
+func (h *TrunkHandler) DeleteRoute(c *gin.Context) {
+	tenantID := c.GetString("tenant_id")
+	userID := c.GetInt64("user_id")
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		response.ValidationError(c, map[string]string{"id": "invalid outbound route ID"})
+		return
+	}
+
+	if err := h.trunkService.DeleteRoute(c.Request.Context(), tenantID, userID, id); err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.Success(c, nil)
+}
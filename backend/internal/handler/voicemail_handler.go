@@ -0,0 +1,286 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/psschand/callcenter/internal/dto"
+	"github.com/psschand/callcenter/internal/service"
+	"github.com/psschand/callcenter/pkg/response"
+)
+
+// VoicemailHandler handles voicemail and mailbox requests
+type VoicemailHandler struct {
+	voicemailService service.VoicemailService
+}
+
+// NewVoicemailHandler creates a new voicemail handler
+func NewVoicemailHandler(voicemailService service.VoicemailService) *VoicemailHandler {
+	return &VoicemailHandler{
+		voicemailService: voicemailService,
+	}
+}
+
+// List lists the voicemails of the mailboxes the user can access.
+// mailbox_id limits it to one mailbox and unread=true to unread messages.
+func (h *VoicemailHandler) List(c *gin.Context) {
+	tenantID := c.GetString("tenant_id")
+	userID := c.GetInt64("user_id")
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+	unreadOnly := c.Query("unread") == "true"
+
+	var mailboxID int64
+	if raw := c.Query("mailbox_id"); raw != "" {
+		id, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			response.ValidationError(c, map[string]string{"mailbox_id": "invalid mailbox ID"})
+			return
+		}
+		mailboxID = id
+	}
+
+	voicemails, total, err := h.voicemailService.List(c.Request.Context(), tenantID, userID, mailboxID, unreadOnly, page, pageSize)
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	meta := response.NewMeta(page, pageSize, int(total))
+	response.SuccessWithMeta(c, voicemails, meta)
+}
+
+// Get gets a voicemail
+func (h *VoicemailHandler) Get(c *gin.Context) {
+	tenantID := c.GetString("tenant_id")
+	userID := c.GetInt64("user_id")
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		response.ValidationError(c, map[string]string{"id": "invalid voicemail ID"})
+		return
+	}
+
+	result, err := h.voicemailService.GetByID(c.Request.Context(), tenantID, userID, id)
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.Success(c, result)
+}
+
+// Stream streams a voicemail's audio, honouring Range requests so players can seek
+func (h *VoicemailHandler) Stream(c *gin.Context) {
+	tenantID := c.GetString("tenant_id")
+	userID := c.GetInt64("user_id")
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		response.ValidationError(c, map[string]string{"id": "invalid voicemail ID"})
+		return
+	}
+
+	voicemail, err := h.voicemailService.Open(c.Request.Context(), tenantID, userID, id)
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+	defer voicemail.Content.Close()
+
+	c.Header("Content-Type", voicemail.ContentType)
+	c.Header("Content-Disposition", fmt.Sprintf("inline; filename=%q", voicemail.Filename))
+	http.ServeContent(c.Writer, c.Request, voicemail.Filename, voicemail.ModTime, voicemail.Content)
+}
+
+// MarkRead marks a voicemail as read
+func (h *VoicemailHandler) MarkRead(c *gin.Context) {
+	h.setRead(c, true)
+}
+
+// MarkUnread marks a voicemail as unread
+func (h *VoicemailHandler) MarkUnread(c *gin.Context) {
+	h.setRead(c, false)
+}
+
+func (h *VoicemailHandler) setRead(c *gin.Context, read bool) {
+	tenantID := c.GetString("tenant_id")
+	userID := c.GetInt64("user_id")
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		response.ValidationError(c, map[string]string{"id": "invalid voicemail ID"})
+		return
+	}
+
+	result, err := h.voicemailService.MarkRead(c.Request.Context(), tenantID, userID, id, read)
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.Success(c, result)
+}
+
+// Delete deletes a voicemail
+func (h *VoicemailHandler) Delete(c *gin.Context) {
+	tenantID := c.GetString("tenant_id")
+	userID := c.GetInt64("user_id")
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		response.ValidationError(c, map[string]string{"id": "invalid voicemail ID"})
+		return
+	}
+
+	if err := h.voicemailService.Delete(c.Request.Context(), tenantID, userID, id); err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.Success(c, nil)
+}
+
+// ListMailboxes lists the mailboxes the user can access
+func (h *VoicemailHandler) ListMailboxes(c *gin.Context) {
+	tenantID := c.GetString("tenant_id")
+	userID := c.GetInt64("user_id")
+
+	mailboxes, err := h.voicemailService.ListMailboxes(c.Request.Context(), tenantID, userID)
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.Success(c, mailboxes)
+}
+
+// GetMailbox gets a mailbox
+func (h *VoicemailHandler) GetMailbox(c *gin.Context) {
+	tenantID := c.GetString("tenant_id")
+	userID := c.GetInt64("user_id")
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		response.ValidationError(c, map[string]string{"id": "invalid mailbox ID"})
+		return
+	}
+
+	result, err := h.voicemailService.GetMailbox(c.Request.Context(), tenantID, userID, id)
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.Success(c, result)
+}
+
+// CreateMailbox creates a mailbox for a user or a DID
+func (h *VoicemailHandler) CreateMailbox(c *gin.Context) {
+	tenantID := c.GetString("tenant_id")
+	userID := c.GetInt64("user_id")
+
+	var req dto.CreateVoicemailMailboxRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.ValidationError(c, err)
+		return
+	}
+
+	result, err := h.voicemailService.CreateMailbox(c.Request.Context(), tenantID, userID, &req)
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.Created(c, result)
+}
+
+// UpdateMailbox updates a mailbox
+func (h *VoicemailHandler) UpdateMailbox(c *gin.Context) {
+	tenantID := c.GetString("tenant_id")
+	userID := c.GetInt64("user_id")
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		response.ValidationError(c, map[string]string{"id": "invalid mailbox ID"})
+		return
+	}
+
+	var req dto.UpdateVoicemailMailboxRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.ValidationError(c, err)
+		return
+	}
+
+	result, err := h.voicemailService.UpdateMailbox(c.Request.Context(), tenantID, userID, id, &req)
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.Success(c, result)
+}
+
+// DeleteMailbox deletes a mailbox
+func (h *VoicemailHandler) DeleteMailbox(c *gin.Context) {
+	tenantID := c.GetString("tenant_id")
+	userID := c.GetInt64("user_id")
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		response.ValidationError(c, map[string]string{"id": "invalid mailbox ID"})
+		return
+	}
+
+	if err := h.voicemailService.DeleteMailbox(c.Request.Context(), tenantID, userID, id); err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.Success(c, nil)
+}
+
+// UploadGreeting sets a mailbox's greeting from an uploaded WAV file (form field "file")
+func (h *VoicemailHandler) UploadGreeting(c *gin.Context) {
+	tenantID := c.GetString("tenant_id")
+	userID := c.GetInt64("user_id")
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		response.ValidationError(c, map[string]string{"id": "invalid mailbox ID"})
+		return
+	}
+
+	file, err := c.FormFile("file")
+	if err != nil {
+		response.ValidationError(c, map[string]string{"file": "file is required"})
+		return
+	}
+	content, err := file.Open()
+	if err != nil {
+		response.ValidationError(c, map[string]string{"file": "could not read file"})
+		return
+	}
+	defer content.Close()
+
+	result, err := h.voicemailService.UploadGreeting(c.Request.Context(), tenantID, userID, id, content)
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.Success(c, result)
+}
+
+// DeleteGreeting puts a mailbox back on the default greeting
+func (h *VoicemailHandler) DeleteGreeting(c *gin.Context) {
+	tenantID := c.GetString("tenant_id")
+	userID := c.GetInt64("user_id")
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		response.ValidationError(c, map[string]string{"id": "invalid mailbox ID"})
+		return
+	}
+
+	result, err := h.voicemailService.DeleteGreeting(c.Request.Context(), tenantID, userID, id)
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.Success(c, result)
+}
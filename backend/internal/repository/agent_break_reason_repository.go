@@ -0,0 +1,76 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/psschand/callcenter/internal/asterisk"
+	"gorm.io/gorm"
+)
+
+// AgentBreakReasonRepository defines the interface for break reason code data access
+type AgentBreakReasonRepository interface {
+	Create(ctx context.Context, reason *asterisk.AgentBreakReason) error
+	FindByID(ctx context.Context, id int64) (*asterisk.AgentBreakReason, error)
+	FindByCode(ctx context.Context, tenantID, code string) (*asterisk.AgentBreakReason, error)
+	FindByTenant(ctx context.Context, tenantID string, activeOnly bool) ([]asterisk.AgentBreakReason, error)
+	Update(ctx context.Context, reason *asterisk.AgentBreakReason) error
+	Delete(ctx context.Context, id int64) error
+}
+
+// agentBreakReasonRepository implements AgentBreakReasonRepository
+type agentBreakReasonRepository struct {
+	db *gorm.DB
+}
+
+// NewAgentBreakReasonRepository creates a new break reason repository
+func NewAgentBreakReasonRepository(db *gorm.DB) AgentBreakReasonRepository {
+	return &agentBreakReasonRepository{db: db}
+}
+
+// Create creates a new break reason
+func (r *agentBreakReasonRepository) Create(ctx context.Context, reason *asterisk.AgentBreakReason) error {
+	return r.db.WithContext(ctx).Create(reason).Error
+}
+
+// FindByID finds a break reason by ID
+func (r *agentBreakReasonRepository) FindByID(ctx context.Context, id int64) (*asterisk.AgentBreakReason, error) {
+	var reason asterisk.AgentBreakReason
+	err := r.db.WithContext(ctx).Where("id = ?", id).First(&reason).Error
+	if err != nil {
+		return nil, err
+	}
+	return &reason, nil
+}
+
+// FindByCode finds a tenant's break reason by code
+func (r *agentBreakReasonRepository) FindByCode(ctx context.Context, tenantID, code string) (*asterisk.AgentBreakReason, error) {
+	var reason asterisk.AgentBreakReason
+	err := r.db.WithContext(ctx).
+		Where("tenant_id = ? AND code = ?", tenantID, code).
+		First(&reason).Error
+	if err != nil {
+		return nil, err
+	}
+	return &reason, nil
+}
+
+// FindByTenant finds a tenant's break reasons, optionally only the active ones
+func (r *agentBreakReasonRepository) FindByTenant(ctx context.Context, tenantID string, activeOnly bool) ([]asterisk.AgentBreakReason, error) {
+	var reasons []asterisk.AgentBreakReason
+	query := r.db.WithContext(ctx).Where("tenant_id = ?", tenantID)
+	if activeOnly {
+		query = query.Where("is_active = ?", true)
+	}
+	err := query.Order("name ASC").Find(&reasons).Error
+	return reasons, err
+}
+
+// Update updates a break reason
+func (r *agentBreakReasonRepository) Update(ctx context.Context, reason *asterisk.AgentBreakReason) error {
+	return r.db.WithContext(ctx).Save(reason).Error
+}
+
+// Delete deletes a break reason. Past breaks keep its code.
+func (r *agentBreakReasonRepository) Delete(ctx context.Context, id int64) error {
+	return r.db.WithContext(ctx).Delete(&asterisk.AgentBreakReason{}, id).Error
+}
@@ -0,0 +1,118 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/psschand/callcenter/internal/asterisk"
+	"github.com/psschand/callcenter/internal/common"
+	"gorm.io/gorm"
+)
+
+// AgentStateTotal is the time agents spent in a state with one reason code
+type AgentStateTotal struct {
+	UserID     int64
+	State      common.AgentStatus
+	ReasonCode string
+	Periods    int64
+	Seconds    int64
+}
+
+// AgentStateHistoryRepository defines the interface for agent state history data access
+type AgentStateHistoryRepository interface {
+	Record(ctx context.Context, entry *asterisk.AgentStateHistory) error
+	FindByUser(ctx context.Context, tenantID string, userID int64, start, end time.Time, page, pageSize int) ([]asterisk.AgentStateHistory, int64, error)
+	SumByState(ctx context.Context, tenantID string, userIDs []int64, start, end time.Time) ([]AgentStateTotal, error)
+	FindByState(ctx context.Context, tenantID string, userIDs []int64, state common.AgentStatus, start, end time.Time) ([]asterisk.AgentStateHistory, error)
+}
+
+// agentStateHistoryRepository implements AgentStateHistoryRepository
+type agentStateHistoryRepository struct {
+	db *gorm.DB
+}
+
+// NewAgentStateHistoryRepository creates a new agent state history repository
+func NewAgentStateHistoryRepository(db *gorm.DB) AgentStateHistoryRepository {
+	return &agentStateHistoryRepository{db: db}
+}
+
+// Record closes the agent's open period and opens the one for its new state
+func (r *agentStateHistoryRepository) Record(ctx context.Context, entry *asterisk.AgentStateHistory) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&asterisk.AgentStateHistory{}).
+			Where("tenant_id = ? AND user_id = ? AND ended_at IS NULL", entry.TenantID, entry.UserID).
+			Updates(map[string]interface{}{
+				"ended_at": entry.StartedAt,
+				"duration": gorm.Expr("GREATEST(TIMESTAMPDIFF(SECOND, started_at, ?), 0)", entry.StartedAt),
+			}).Error
+		if err != nil {
+			return err
+		}
+		return tx.Create(entry).Error
+	})
+}
+
+// FindByUser finds the periods of an agent overlapping a time range, latest first, with pagination
+func (r *agentStateHistoryRepository) FindByUser(ctx context.Context, tenantID string, userID int64, start, end time.Time, page, pageSize int) ([]asterisk.AgentStateHistory, int64, error) {
+	var entries []asterisk.AgentStateHistory
+	var total int64
+
+	query := r.overlapping(ctx, tenantID, []int64{userID}, start, end)
+
+	// Count total
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	// Get paginated results
+	offset := (page - 1) * pageSize
+	err := query.
+		Offset(offset).
+		Limit(pageSize).
+		Order("started_at DESC, id DESC").
+		Find(&entries).Error
+
+	return entries, total, err
+}
+
+// SumByState totals the time agents spent in each state and reason code
+// within a time range. Periods running over the range's edges, or still
+// open, count only for the part inside it. No userIDs means all agents.
+func (r *agentStateHistoryRepository) SumByState(ctx context.Context, tenantID string, userIDs []int64, start, end time.Time) ([]AgentStateTotal, error) {
+	var totals []AgentStateTotal
+	now := time.Now()
+	if end.After(now) {
+		end = now
+	}
+
+	err := r.overlapping(ctx, tenantID, userIDs, start, end).
+		Select(`user_id, state, COALESCE(reason_code, '') AS reason_code, COUNT(*) AS periods,
+			SUM(GREATEST(TIMESTAMPDIFF(SECOND, GREATEST(started_at, ?), LEAST(COALESCE(ended_at, ?), ?)), 0)) AS seconds`,
+			start, now, end).
+		Group("user_id, state, reason_code").
+		Scan(&totals).Error
+	return totals, err
+}
+
+// FindByState finds the periods agents spent in a state starting within a time range
+func (r *agentStateHistoryRepository) FindByState(ctx context.Context, tenantID string, userIDs []int64, state common.AgentStatus, start, end time.Time) ([]asterisk.AgentStateHistory, error) {
+	var entries []asterisk.AgentStateHistory
+	query := r.db.WithContext(ctx).
+		Where("tenant_id = ? AND state = ? AND started_at BETWEEN ? AND ?", tenantID, state, start, end)
+	if len(userIDs) > 0 {
+		query = query.Where("user_id IN ?", userIDs)
+	}
+	err := query.Order("started_at ASC").Find(&entries).Error
+	return entries, err
+}
+
+// overlapping selects the periods of agents overlapping a time range; no userIDs means all agents
+func (r *agentStateHistoryRepository) overlapping(ctx context.Context, tenantID string, userIDs []int64, start, end time.Time) *gorm.DB {
+	query := r.db.WithContext(ctx).
+		Model(&asterisk.AgentStateHistory{}).
+		Where("tenant_id = ? AND started_at < ? AND (ended_at IS NULL OR ended_at > ?)", tenantID, end, start)
+	if len(userIDs) > 0 {
+		query = query.Where("user_id IN ?", userIDs)
+	}
+	return query
+}
@@ -2,6 +2,7 @@ package repository
 
 import (
 	"context"
+	"time"
 
 	"github.com/psschand/callcenter/internal/asterisk"
 	"github.com/psschand/callcenter/internal/common"
@@ -15,7 +16,12 @@ type AgentStateRepository interface {
 	FindByUser(ctx context.Context, tenantID string, userID int64) (*asterisk.AgentState, error)
 	FindByTenant(ctx context.Context, tenantID string) ([]asterisk.AgentState, error)
 	Update(ctx context.Context, state *asterisk.AgentState) error
-	UpdateState(ctx context.Context, id int64, state common.AgentStatus, reason *string) error
+	UpdateState(ctx context.Context, id int64, state common.AgentStatus, reasonCode, reason *string) error
+	UpdateCallState(ctx context.Context, id int64, state common.AgentStatus, reason string, callID *string) error
+	StartWrapup(ctx context.Context, id int64, callID *string, chatID *int64, endsAt time.Time) error
+	SetWrapupEnd(ctx context.Context, id int64, endsAt time.Time) error
+	EndWrapup(ctx context.Context, id int64, state common.AgentStatus, reasonCode *string, reason string) (bool, error)
+	FindInWrapup(ctx context.Context) ([]asterisk.AgentState, error)
 	FindByState(ctx context.Context, tenantID string, state common.AgentStatus) ([]asterisk.AgentState, error)
 	FindAvailableAgents(ctx context.Context, tenantID string) ([]asterisk.AgentState, error)
 }
@@ -72,10 +78,12 @@ func (r *agentStateRepository) Update(ctx context.Context, state *asterisk.Agent
 	return r.db.WithContext(ctx).Save(state).Error
 }
 
-// UpdateState updates only the state and reason
-func (r *agentStateRepository) UpdateState(ctx context.Context, id int64, state common.AgentStatus, reason *string) error {
+// UpdateState updates only the state, reason code and reason; a nil
+// reasonCode clears the reason code
+func (r *agentStateRepository) UpdateState(ctx context.Context, id int64, state common.AgentStatus, reasonCode, reason *string) error {
 	updates := map[string]interface{}{
-		"state": state,
+		"state":       state,
+		"reason_code": reasonCode,
 	}
 	if reason != nil {
 		updates["reason"] = *reason
@@ -86,6 +94,71 @@ func (r *agentStateRepository) UpdateState(ctx context.Context, id int64, state
 		Updates(updates).Error
 }
 
+// UpdateCallState updates the state, reason and current call together; a nil
+// callID clears the current call
+func (r *agentStateRepository) UpdateCallState(ctx context.Context, id int64, state common.AgentStatus, reason string, callID *string) error {
+	return r.db.WithContext(ctx).
+		Model(&asterisk.AgentState{}).
+		Where("id = ?", id).
+		Updates(map[string]interface{}{
+			"state":           state,
+			"reason_code":     nil,
+			"reason":          reason,
+			"current_call_id": callID,
+		}).Error
+}
+
+// StartWrapup puts an agent in wrap-up after a call or chat until endsAt
+func (r *agentStateRepository) StartWrapup(ctx context.Context, id int64, callID *string, chatID *int64, endsAt time.Time) error {
+	return r.db.WithContext(ctx).
+		Model(&asterisk.AgentState{}).
+		Where("id = ?", id).
+		Updates(map[string]interface{}{
+			"state":             common.AgentStatusWrapup,
+			"reason_code":       nil,
+			"reason":            "Wrap-up",
+			"current_call_id":   callID,
+			"wrapup_started_at": time.Now(),
+			"wrapup_ends_at":    endsAt,
+			"wrapup_chat_id":    chatID,
+		}).Error
+}
+
+// SetWrapupEnd moves the end of an agent's wrap-up
+func (r *agentStateRepository) SetWrapupEnd(ctx context.Context, id int64, endsAt time.Time) error {
+	return r.db.WithContext(ctx).
+		Model(&asterisk.AgentState{}).
+		Where("id = ? AND state = ?", id, common.AgentStatusWrapup).
+		Update("wrapup_ends_at", endsAt).Error
+}
+
+// EndWrapup moves an agent out of wrap-up and clears it. It reports false if
+// the agent was no longer in wrap-up.
+func (r *agentStateRepository) EndWrapup(ctx context.Context, id int64, state common.AgentStatus, reasonCode *string, reason string) (bool, error) {
+	result := r.db.WithContext(ctx).
+		Model(&asterisk.AgentState{}).
+		Where("id = ? AND state = ?", id, common.AgentStatusWrapup).
+		Updates(map[string]interface{}{
+			"state":             state,
+			"reason_code":       reasonCode,
+			"reason":            reason,
+			"current_call_id":   nil,
+			"wrapup_started_at": nil,
+			"wrapup_ends_at":    nil,
+			"wrapup_chat_id":    nil,
+		})
+	return result.RowsAffected > 0, result.Error
+}
+
+// FindInWrapup finds the agents of all tenants who are in wrap-up
+func (r *agentStateRepository) FindInWrapup(ctx context.Context) ([]asterisk.AgentState, error) {
+	var states []asterisk.AgentState
+	err := r.db.WithContext(ctx).
+		Where("state = ?", common.AgentStatusWrapup).
+		Find(&states).Error
+	return states, err
+}
+
 // FindByState finds all agents with a specific state
 func (r *agentStateRepository) FindByState(ctx context.Context, tenantID string, state common.AgentStatus) ([]asterisk.AgentState, error) {
 	var states []asterisk.AgentState
@@ -0,0 +1,134 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/psschand/callcenter/internal/asterisk"
+	"gorm.io/gorm"
+)
+
+// BlacklistRepository defines the interface for blacklist data access
+type BlacklistRepository interface {
+	Create(ctx context.Context, entry *asterisk.BlacklistEntry) error
+	FindByID(ctx context.Context, id int64) (*asterisk.BlacklistEntry, error)
+	FindByPhoneNumber(ctx context.Context, tenantID, phoneNumber string) (*asterisk.BlacklistEntry, error)
+	FindByTenant(ctx context.Context, tenantID string, includeExpired bool, page, pageSize int) ([]asterisk.BlacklistEntry, int64, error)
+	FindActive(ctx context.Context, tenantID string, now time.Time) ([]asterisk.BlacklistEntry, error)
+	Update(ctx context.Context, entry *asterisk.BlacklistEntry) error
+	Delete(ctx context.Context, id int64) error
+
+	CreateAttempt(ctx context.Context, attempt *asterisk.BlockedAttempt) error
+	FindAttempts(ctx context.Context, tenantID string, page, pageSize int) ([]asterisk.BlockedAttempt, int64, error)
+}
+
+// blacklistRepository implements BlacklistRepository
+type blacklistRepository struct {
+	db *gorm.DB
+}
+
+// NewBlacklistRepository creates a new blacklist repository
+func NewBlacklistRepository(db *gorm.DB) BlacklistRepository {
+	return &blacklistRepository{db: db}
+}
+
+// Create creates a new blacklist entry
+func (r *blacklistRepository) Create(ctx context.Context, entry *asterisk.BlacklistEntry) error {
+	return r.db.WithContext(ctx).Omit("Tenant").Create(entry).Error
+}
+
+// FindByID finds a blacklist entry by ID
+func (r *blacklistRepository) FindByID(ctx context.Context, id int64) (*asterisk.BlacklistEntry, error) {
+	var entry asterisk.BlacklistEntry
+	err := r.db.WithContext(ctx).Where("id = ?", id).First(&entry).Error
+	if err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
+
+// FindByPhoneNumber finds a tenant's entry for a number or pattern
+func (r *blacklistRepository) FindByPhoneNumber(ctx context.Context, tenantID, phoneNumber string) (*asterisk.BlacklistEntry, error) {
+	var entry asterisk.BlacklistEntry
+	err := r.db.WithContext(ctx).
+		Where("tenant_id = ? AND phone_number = ?", tenantID, phoneNumber).
+		First(&entry).Error
+	if err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
+
+// FindByTenant finds a tenant's entries with pagination, newest first
+func (r *blacklistRepository) FindByTenant(ctx context.Context, tenantID string, includeExpired bool, page, pageSize int) ([]asterisk.BlacklistEntry, int64, error) {
+	var entries []asterisk.BlacklistEntry
+	var total int64
+
+	scope := func(db *gorm.DB) *gorm.DB {
+		db = db.Where("tenant_id = ?", tenantID)
+		if !includeExpired {
+			db = db.Where("expires_at IS NULL OR expires_at > ?", time.Now())
+		}
+		return db
+	}
+
+	// Count total
+	if err := r.db.WithContext(ctx).Model(&asterisk.BlacklistEntry{}).Scopes(scope).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	// Get paginated results
+	offset := (page - 1) * pageSize
+	err := r.db.WithContext(ctx).
+		Scopes(scope).
+		Offset(offset).
+		Limit(pageSize).
+		Order("created_at DESC").
+		Find(&entries).Error
+
+	return entries, total, err
+}
+
+// FindActive finds a tenant's entries that have not expired
+func (r *blacklistRepository) FindActive(ctx context.Context, tenantID string, now time.Time) ([]asterisk.BlacklistEntry, error) {
+	var entries []asterisk.BlacklistEntry
+	err := r.db.WithContext(ctx).
+		Where("tenant_id = ? AND (expires_at IS NULL OR expires_at > ?)", tenantID, now).
+		Find(&entries).Error
+	return entries, err
+}
+
+// Update updates a blacklist entry
+func (r *blacklistRepository) Update(ctx context.Context, entry *asterisk.BlacklistEntry) error {
+	return r.db.WithContext(ctx).Omit("Tenant").Save(entry).Error
+}
+
+// Delete deletes a blacklist entry
+func (r *blacklistRepository) Delete(ctx context.Context, id int64) error {
+	return r.db.WithContext(ctx).Where("id = ?", id).Delete(&asterisk.BlacklistEntry{}).Error
+}
+
+// CreateAttempt records a blocked call or SMS
+func (r *blacklistRepository) CreateAttempt(ctx context.Context, attempt *asterisk.BlockedAttempt) error {
+	return r.db.WithContext(ctx).Create(attempt).Error
+}
+
+// FindAttempts finds a tenant's blocked calls and SMS with pagination, newest first
+func (r *blacklistRepository) FindAttempts(ctx context.Context, tenantID string, page, pageSize int) ([]asterisk.BlockedAttempt, int64, error) {
+	var attempts []asterisk.BlockedAttempt
+	var total int64
+
+	if err := r.db.WithContext(ctx).Model(&asterisk.BlockedAttempt{}).Where("tenant_id = ?", tenantID).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	offset := (page - 1) * pageSize
+	err := r.db.WithContext(ctx).
+		Where("tenant_id = ?", tenantID).
+		Offset(offset).
+		Limit(pageSize).
+		Order("created_at DESC").
+		Find(&attempts).Error
+
+	return attempts, total, err
+}
@@ -0,0 +1,138 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/psschand/callcenter/internal/asterisk"
+	"github.com/psschand/callcenter/internal/common"
+	"gorm.io/gorm"
+)
+
+// CallRecordingRepository defines the interface for call recording data access
+type CallRecordingRepository interface {
+	Create(ctx context.Context, recording *asterisk.CallRecording) error
+	FindByID(ctx context.Context, id int64) (*asterisk.CallRecording, error)
+	FindByFilename(ctx context.Context, tenantID, filename string) (*asterisk.CallRecording, error)
+	FindByUniqueID(ctx context.Context, tenantID, uniqueID string) (*asterisk.CallRecording, error)
+	FindByTenant(ctx context.Context, tenantID string, page, pageSize int) ([]asterisk.CallRecording, int64, error)
+	FindUnarchived(ctx context.Context, afterID int64, limit int) ([]asterisk.CallRecording, error)
+	FindExpired(ctx context.Context, tenantID string, before time.Time, limit int) ([]asterisk.CallRecording, error)
+	Update(ctx context.Context, recording *asterisk.CallRecording) error
+	SetStorage(ctx context.Context, id int64, backend, key string) error
+}
+
+// callRecordingRepository implements CallRecordingRepository
+type callRecordingRepository struct {
+	db *gorm.DB
+}
+
+// NewCallRecordingRepository creates a new call recording repository
+func NewCallRecordingRepository(db *gorm.DB) CallRecordingRepository {
+	return &callRecordingRepository{db: db}
+}
+
+// Create creates a new call recording
+func (r *callRecordingRepository) Create(ctx context.Context, recording *asterisk.CallRecording) error {
+	return r.db.WithContext(ctx).Create(recording).Error
+}
+
+// FindByID finds a call recording by ID
+func (r *callRecordingRepository) FindByID(ctx context.Context, id int64) (*asterisk.CallRecording, error) {
+	var recording asterisk.CallRecording
+	err := r.db.WithContext(ctx).Where("id = ?", id).First(&recording).Error
+	if err != nil {
+		return nil, err
+	}
+	return &recording, nil
+}
+
+// FindByFilename finds a tenant's call recording by file name
+func (r *callRecordingRepository) FindByFilename(ctx context.Context, tenantID, filename string) (*asterisk.CallRecording, error) {
+	var recording asterisk.CallRecording
+	err := r.db.WithContext(ctx).
+		Where("tenant_id = ? AND filename = ?", tenantID, filename).
+		Order("id DESC").
+		First(&recording).Error
+	if err != nil {
+		return nil, err
+	}
+	return &recording, nil
+}
+
+// FindByUniqueID finds the latest recording of a call that has not been deleted
+func (r *callRecordingRepository) FindByUniqueID(ctx context.Context, tenantID, uniqueID string) (*asterisk.CallRecording, error) {
+	var recording asterisk.CallRecording
+	err := r.db.WithContext(ctx).
+		Where("tenant_id = ? AND uniqueid = ? AND status <> ?", tenantID, uniqueID, common.RecordingStatusDeleted).
+		Order("id DESC").
+		First(&recording).Error
+	if err != nil {
+		return nil, err
+	}
+	return &recording, nil
+}
+
+// FindByTenant finds a tenant's recordings that have not been deleted, with pagination
+func (r *callRecordingRepository) FindByTenant(ctx context.Context, tenantID string, page, pageSize int) ([]asterisk.CallRecording, int64, error) {
+	var recordings []asterisk.CallRecording
+	var total int64
+
+	query := r.db.WithContext(ctx).
+		Model(&asterisk.CallRecording{}).
+		Where("tenant_id = ? AND status <> ?", tenantID, common.RecordingStatusDeleted)
+
+	// Count total
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	// Get paginated results
+	offset := (page - 1) * pageSize
+	err := query.
+		Offset(offset).
+		Limit(pageSize).
+		Order("created_at DESC").
+		Find(&recordings).Error
+
+	return recordings, total, err
+}
+
+// FindUnarchived finds completed recordings still in the Asterisk spool with an ID above afterID, oldest first
+func (r *callRecordingRepository) FindUnarchived(ctx context.Context, afterID int64, limit int) ([]asterisk.CallRecording, error) {
+	var recordings []asterisk.CallRecording
+	err := r.db.WithContext(ctx).
+		Where("id > ? AND status = ? AND storage_key IS NULL", afterID, common.RecordingStatusCompleted).
+		Order("id ASC").
+		Limit(limit).
+		Find(&recordings).Error
+	return recordings, err
+}
+
+// FindExpired finds a tenant's finished recordings created before the given time
+func (r *callRecordingRepository) FindExpired(ctx context.Context, tenantID string, before time.Time, limit int) ([]asterisk.CallRecording, error) {
+	var recordings []asterisk.CallRecording
+	err := r.db.WithContext(ctx).
+		Where("tenant_id = ? AND created_at < ? AND status IN ?", tenantID, before,
+			[]common.RecordingStatus{common.RecordingStatusCompleted, common.RecordingStatusFailed}).
+		Order("id ASC").
+		Limit(limit).
+		Find(&recordings).Error
+	return recordings, err
+}
+
+// Update updates a call recording
+func (r *callRecordingRepository) Update(ctx context.Context, recording *asterisk.CallRecording) error {
+	return r.db.WithContext(ctx).Omit("Tenant", "CDR").Save(recording).Error
+}
+
+// SetStorage records where a call recording has been archived to
+func (r *callRecordingRepository) SetStorage(ctx context.Context, id int64, backend, key string) error {
+	return r.db.WithContext(ctx).
+		Model(&asterisk.CallRecording{}).
+		Where("id = ?", id).
+		Updates(map[string]interface{}{
+			"storage_backend": backend,
+			"storage_key":     key,
+		}).Error
+}
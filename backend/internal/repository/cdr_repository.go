@@ -13,6 +13,9 @@ import (
 type CDRRepository interface {
 	Create(ctx context.Context, cdr *asterisk.CDR) error
 	FindByID(ctx context.Context, id int64) (*asterisk.CDR, error)
+	FindByUniqueID(ctx context.Context, tenantID, uniqueID string) (*asterisk.CDR, error)
+	SetRecordingFile(ctx context.Context, id int64, recordingFile *string) error
+	SetWrapupTime(ctx context.Context, tenantID, callID string, userID int64, seconds int) error
 	FindByTenant(ctx context.Context, tenantID string, page, pageSize int) ([]asterisk.CDR, int64, error)
 	FindByDateRange(ctx context.Context, tenantID string, start, end time.Time, page, pageSize int) ([]asterisk.CDR, int64, error)
 	FindByUser(ctx context.Context, userID int64, page, pageSize int) ([]asterisk.CDR, int64, error)
@@ -46,6 +49,37 @@ func (r *cdrRepository) FindByID(ctx context.Context, id int64) (*asterisk.CDR,
 	return &cdr, nil
 }
 
+// FindByUniqueID finds a tenant's CDR by the unique ID of its channel
+func (r *cdrRepository) FindByUniqueID(ctx context.Context, tenantID, uniqueID string) (*asterisk.CDR, error) {
+	var cdr asterisk.CDR
+	err := r.db.WithContext(ctx).
+		Where("tenant_id = ? AND uniqueid = ?", tenantID, uniqueID).
+		Order("id DESC").
+		First(&cdr).Error
+	if err != nil {
+		return nil, err
+	}
+	return &cdr, nil
+}
+
+// SetRecordingFile links a CDR to its recording, or unlinks it when recordingFile is nil
+func (r *cdrRepository) SetRecordingFile(ctx context.Context, id int64, recordingFile *string) error {
+	return r.db.WithContext(ctx).
+		Model(&asterisk.CDR{}).
+		Where("id = ?", id).
+		Update("recordingfile", recordingFile).Error
+}
+
+// SetWrapupTime records the wrap-up an agent spent after a call on the agent's
+// CDR of it. callID is the caller channel of inbound calls and the call ID of
+// click-to-calls.
+func (r *cdrRepository) SetWrapupTime(ctx context.Context, tenantID, callID string, userID int64, seconds int) error {
+	return r.db.WithContext(ctx).
+		Model(&asterisk.CDR{}).
+		Where("tenant_id = ? AND user_id = ? AND (uniqueid = ? OR linkedid = ?)", tenantID, userID, callID, callID).
+		Update("wrapup_time", seconds).Error
+}
+
 // FindByTenant finds all CDRs for a tenant with pagination
 func (r *cdrRepository) FindByTenant(ctx context.Context, tenantID string, page, pageSize int) ([]asterisk.CDR, int64, error) {
 	var cdrs []asterisk.CDR
@@ -186,6 +220,17 @@ func (r *cdrRepository) GetStats(ctx context.Context, tenantID string, start, en
 	}
 	stats["total_talk_time"] = totalTalkTime
 
+	// Average wrap-up after answered calls
+	var avgWrapup float64
+	if err := r.db.WithContext(ctx).
+		Model(&asterisk.CDR{}).
+		Where("tenant_id = ? AND calldate BETWEEN ? AND ? AND disposition = ? AND user_id IS NOT NULL", tenantID, start, end, common.CallDispositionAnswered).
+		Select("COALESCE(AVG(wrapup_time), 0)").
+		Scan(&avgWrapup).Error; err != nil {
+		return nil, err
+	}
+	stats["avg_wrapup_time"] = avgWrapup
+
 	// Answer rate
 	if totalCalls > 0 {
 		stats["answer_rate"] = float64(answeredCalls) / float64(totalCalls) * 100
@@ -4,6 +4,7 @@ import (
 	"context"
 
 	"github.com/psschand/callcenter/internal/chat"
+	"github.com/psschand/callcenter/internal/common"
 	"gorm.io/gorm"
 )
 
@@ -12,7 +13,9 @@ type ChatMessageRepository interface {
 	Create(ctx context.Context, message *chat.ChatMessage) error
 	FindByID(ctx context.Context, id int64) (*chat.ChatMessage, error)
 	FindBySession(ctx context.Context, sessionID int64, page, pageSize int) ([]chat.ChatMessage, int64, error)
+	FindBySMSMessage(ctx context.Context, smsMessageID int64) (*chat.ChatMessage, error)
 	Update(ctx context.Context, message *chat.ChatMessage) error
+	SetDeliveryStatus(ctx context.Context, id int64, status common.SMSStatus) error
 	Delete(ctx context.Context, id int64) error
 	MarkAsRead(ctx context.Context, messageID int64) error
 	CountUnreadBySession(ctx context.Context, sessionID int64) (int64, error)
@@ -46,6 +49,16 @@ func (r *chatMessageRepository) FindByID(ctx context.Context, id int64) (*chat.C
 	return &message, nil
 }
 
+// FindBySMSMessage finds the chat message an SMS message was sent or received for
+func (r *chatMessageRepository) FindBySMSMessage(ctx context.Context, smsMessageID int64) (*chat.ChatMessage, error) {
+	var message chat.ChatMessage
+	err := r.db.WithContext(ctx).Where("sms_message_id = ?", smsMessageID).First(&message).Error
+	if err != nil {
+		return nil, err
+	}
+	return &message, nil
+}
+
 // FindBySession finds all messages for a chat session with pagination
 func (r *chatMessageRepository) FindBySession(ctx context.Context, sessionID int64, page, pageSize int) ([]chat.ChatMessage, int64, error) {
 	var messages []chat.ChatMessage
@@ -74,6 +87,14 @@ func (r *chatMessageRepository) Update(ctx context.Context, message *chat.ChatMe
 	return r.db.WithContext(ctx).Save(message).Error
 }
 
+// SetDeliveryStatus sets the delivery status of a chat message sent as SMS
+func (r *chatMessageRepository) SetDeliveryStatus(ctx context.Context, id int64, status common.SMSStatus) error {
+	return r.db.WithContext(ctx).
+		Model(&chat.ChatMessage{}).
+		Where("id = ?", id).
+		Update("delivery_status", status).Error
+}
+
 // Delete deletes a chat message
 func (r *chatMessageRepository) Delete(ctx context.Context, id int64) error {
 	return r.db.WithContext(ctx).Where("id = ?", id).Delete(&chat.ChatMessage{}).Error
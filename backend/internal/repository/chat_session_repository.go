@@ -18,7 +18,9 @@ type ChatSessionRepository interface {
 	FindByStatus(ctx context.Context, tenantID string, status common.ChatSessionStatus) ([]chat.ChatSession, error)
 	FindByAssignee(ctx context.Context, assigneeID int64) ([]chat.ChatSession, error)
 	FindActiveByTenant(ctx context.Context, tenantID string) ([]chat.ChatSession, error)
+	FindOpenSMS(ctx context.Context, tenantID string, didID int64, phone string) (*chat.ChatSession, error)
 	Update(ctx context.Context, session *chat.ChatSession) error
+	SetWrapupTime(ctx context.Context, id int64, seconds int) error
 	Delete(ctx context.Context, id int64) error
 	FindWithMessages(ctx context.Context, id int64) (*chat.ChatSession, error)
 	GetStats(ctx context.Context, tenantID string, start, end time.Time) (map[string]interface{}, error)
@@ -125,11 +127,33 @@ func (r *chatSessionRepository) FindActiveByTenant(ctx context.Context, tenantID
 	return sessions, err
 }
 
+// FindOpenSMS finds the latest SMS session with a customer number on a DID that has not ended
+func (r *chatSessionRepository) FindOpenSMS(ctx context.Context, tenantID string, didID int64, phone string) (*chat.ChatSession, error) {
+	var session chat.ChatSession
+	err := r.db.WithContext(ctx).
+		Where("tenant_id = ? AND channel = ? AND did_id = ? AND visitor_phone = ?", tenantID, common.ChatChannelSMS, didID, phone).
+		Where("status IN ?", []common.ChatSessionStatus{common.ChatSessionStatusActive, common.ChatSessionStatusQueued}).
+		Order("created_at DESC").
+		First(&session).Error
+	if err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
 // Update updates a chat session
 func (r *chatSessionRepository) Update(ctx context.Context, session *chat.ChatSession) error {
 	return r.db.WithContext(ctx).Save(session).Error
 }
 
+// SetWrapupTime records the wrap-up the agent spent after a chat
+func (r *chatSessionRepository) SetWrapupTime(ctx context.Context, id int64, seconds int) error {
+	return r.db.WithContext(ctx).
+		Model(&chat.ChatSession{}).
+		Where("id = ?", id).
+		Update("wrapup_time", seconds).Error
+}
+
 // Delete deletes a chat session
 func (r *chatSessionRepository) Delete(ctx context.Context, id int64) error {
 	return r.db.WithContext(ctx).Where("id = ?", id).Delete(&chat.ChatSession{}).Error
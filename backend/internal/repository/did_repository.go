@@ -18,6 +18,7 @@ type DIDRepository interface {
 	Delete(ctx context.Context, id int64) error
 	FindByStatus(ctx context.Context, tenantID string, status common.DIDStatus) ([]asterisk.DID, error)
 	FindAvailable(ctx context.Context) ([]asterisk.DID, error)
+	FindAll(ctx context.Context) ([]asterisk.DID, error)
 }
 
 // didRepository implements DIDRepository
@@ -104,3 +105,12 @@ func (r *didRepository) FindAvailable(ctx context.Context) ([]asterisk.DID, erro
 		Find(&dids).Error
 	return dids, err
 }
+
+// FindAll finds every DID across all tenants, ordered by tenant and number
+func (r *didRepository) FindAll(ctx context.Context) ([]asterisk.DID, error) {
+	var dids []asterisk.DID
+	err := r.db.WithContext(ctx).
+		Order("tenant_id, number").
+		Find(&dids).Error
+	return dids, err
+}
@@ -0,0 +1,157 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/psschand/callcenter/internal/asterisk"
+	"gorm.io/gorm"
+)
+
+// IVRMenuRepository defines the interface for IVR menu and option data access
+type IVRMenuRepository interface {
+	Create(ctx context.Context, menu *asterisk.IVRMenu) error
+	FindByID(ctx context.Context, id int64) (*asterisk.IVRMenu, error)
+	FindWithOptions(ctx context.Context, id int64) (*asterisk.IVRMenu, error)
+	FindByName(ctx context.Context, tenantID, name string) (*asterisk.IVRMenu, error)
+	FindByTenant(ctx context.Context, tenantID string, page, pageSize int) ([]asterisk.IVRMenu, int64, error)
+	Update(ctx context.Context, menu *asterisk.IVRMenu) error
+	Delete(ctx context.Context, id int64) error
+
+	CreateOption(ctx context.Context, option *asterisk.IVROption) error
+	FindOptionByID(ctx context.Context, id int64) (*asterisk.IVROption, error)
+	FindOptionByDigit(ctx context.Context, menuID int64, digit string) (*asterisk.IVROption, error)
+	FindOptions(ctx context.Context, menuID int64) ([]asterisk.IVROption, error)
+	UpdateOption(ctx context.Context, option *asterisk.IVROption) error
+	DeleteOption(ctx context.Context, id int64) error
+}
+
+// ivrMenuRepository implements IVRMenuRepository
+type ivrMenuRepository struct {
+	db *gorm.DB
+}
+
+// NewIVRMenuRepository creates a new IVR menu repository
+func NewIVRMenuRepository(db *gorm.DB) IVRMenuRepository {
+	return &ivrMenuRepository{db: db}
+}
+
+// Create creates a new IVR menu
+func (r *ivrMenuRepository) Create(ctx context.Context, menu *asterisk.IVRMenu) error {
+	return r.db.WithContext(ctx).Create(menu).Error
+}
+
+// FindByID finds an IVR menu by ID
+func (r *ivrMenuRepository) FindByID(ctx context.Context, id int64) (*asterisk.IVRMenu, error) {
+	var menu asterisk.IVRMenu
+	err := r.db.WithContext(ctx).Where("id = ?", id).First(&menu).Error
+	if err != nil {
+		return nil, err
+	}
+	return &menu, nil
+}
+
+// FindWithOptions finds an IVR menu with its options
+func (r *ivrMenuRepository) FindWithOptions(ctx context.Context, id int64) (*asterisk.IVRMenu, error) {
+	var menu asterisk.IVRMenu
+	err := r.db.WithContext(ctx).
+		Preload("Options", func(db *gorm.DB) *gorm.DB {
+			return db.Order("sort_order ASC, digit ASC")
+		}).
+		Where("id = ?", id).
+		First(&menu).Error
+	if err != nil {
+		return nil, err
+	}
+	return &menu, nil
+}
+
+// FindByName finds an IVR menu by tenant and name
+func (r *ivrMenuRepository) FindByName(ctx context.Context, tenantID, name string) (*asterisk.IVRMenu, error) {
+	var menu asterisk.IVRMenu
+	err := r.db.WithContext(ctx).
+		Where("tenant_id = ? AND name = ?", tenantID, name).
+		First(&menu).Error
+	if err != nil {
+		return nil, err
+	}
+	return &menu, nil
+}
+
+// FindByTenant finds all IVR menus for a tenant with pagination
+func (r *ivrMenuRepository) FindByTenant(ctx context.Context, tenantID string, page, pageSize int) ([]asterisk.IVRMenu, int64, error) {
+	var menus []asterisk.IVRMenu
+	var total int64
+
+	// Count total
+	if err := r.db.WithContext(ctx).Model(&asterisk.IVRMenu{}).Where("tenant_id = ?", tenantID).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	// Get paginated results
+	offset := (page - 1) * pageSize
+	err := r.db.WithContext(ctx).
+		Where("tenant_id = ?", tenantID).
+		Offset(offset).
+		Limit(pageSize).
+		Order("name ASC").
+		Find(&menus).Error
+
+	return menus, total, err
+}
+
+// Update updates an IVR menu
+func (r *ivrMenuRepository) Update(ctx context.Context, menu *asterisk.IVRMenu) error {
+	return r.db.WithContext(ctx).Omit("Options", "Tenant").Save(menu).Error
+}
+
+// Delete deletes an IVR menu (options are removed by the foreign key cascade)
+func (r *ivrMenuRepository) Delete(ctx context.Context, id int64) error {
+	return r.db.WithContext(ctx).Where("id = ?", id).Delete(&asterisk.IVRMenu{}).Error
+}
+
+// CreateOption creates a new IVR option
+func (r *ivrMenuRepository) CreateOption(ctx context.Context, option *asterisk.IVROption) error {
+	return r.db.WithContext(ctx).Create(option).Error
+}
+
+// FindOptionByID finds an IVR option by ID
+func (r *ivrMenuRepository) FindOptionByID(ctx context.Context, id int64) (*asterisk.IVROption, error) {
+	var option asterisk.IVROption
+	err := r.db.WithContext(ctx).Where("id = ?", id).First(&option).Error
+	if err != nil {
+		return nil, err
+	}
+	return &option, nil
+}
+
+// FindOptionByDigit finds the option of a menu for a digit
+func (r *ivrMenuRepository) FindOptionByDigit(ctx context.Context, menuID int64, digit string) (*asterisk.IVROption, error) {
+	var option asterisk.IVROption
+	err := r.db.WithContext(ctx).
+		Where("ivr_menu_id = ? AND digit = ?", menuID, digit).
+		First(&option).Error
+	if err != nil {
+		return nil, err
+	}
+	return &option, nil
+}
+
+// FindOptions finds all options of a menu
+func (r *ivrMenuRepository) FindOptions(ctx context.Context, menuID int64) ([]asterisk.IVROption, error) {
+	var options []asterisk.IVROption
+	err := r.db.WithContext(ctx).
+		Where("ivr_menu_id = ?", menuID).
+		Order("sort_order ASC, digit ASC").
+		Find(&options).Error
+	return options, err
+}
+
+// UpdateOption updates an IVR option
+func (r *ivrMenuRepository) UpdateOption(ctx context.Context, option *asterisk.IVROption) error {
+	return r.db.WithContext(ctx).Save(option).Error
+}
+
+// DeleteOption deletes an IVR option
+func (r *ivrMenuRepository) DeleteOption(ctx context.Context, id int64) error {
+	return r.db.WithContext(ctx).Where("id = ?", id).Delete(&asterisk.IVROption{}).Error
+}
@@ -0,0 +1,115 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/psschand/callcenter/internal/asterisk"
+	"gorm.io/gorm"
+)
+
+// MonitorSessionRepository defines the interface for supervisor monitor session data access
+type MonitorSessionRepository interface {
+	Create(ctx context.Context, session *asterisk.MonitorSession) error
+	FindByID(ctx context.Context, id int64) (*asterisk.MonitorSession, error)
+	FindWithEvents(ctx context.Context, id int64) (*asterisk.MonitorSession, error)
+	FindByTenant(ctx context.Context, tenantID string, supervisorID int64, page, pageSize int) ([]asterisk.MonitorSession, int64, error)
+	FindOpen(ctx context.Context) ([]asterisk.MonitorSession, error)
+	Update(ctx context.Context, session *asterisk.MonitorSession) error
+	UpdateMode(ctx context.Context, id int64, mode string) error
+	CreateEvent(ctx context.Context, event *asterisk.MonitorSessionEvent) error
+}
+
+// monitorSessionRepository implements MonitorSessionRepository
+type monitorSessionRepository struct {
+	db *gorm.DB
+}
+
+// NewMonitorSessionRepository creates a new monitor session repository
+func NewMonitorSessionRepository(db *gorm.DB) MonitorSessionRepository {
+	return &monitorSessionRepository{db: db}
+}
+
+// Create creates a new monitor session
+func (r *monitorSessionRepository) Create(ctx context.Context, session *asterisk.MonitorSession) error {
+	return r.db.WithContext(ctx).Create(session).Error
+}
+
+// FindByID finds a monitor session by ID
+func (r *monitorSessionRepository) FindByID(ctx context.Context, id int64) (*asterisk.MonitorSession, error) {
+	var session asterisk.MonitorSession
+	err := r.db.WithContext(ctx).Where("id = ?", id).First(&session).Error
+	if err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+// FindWithEvents finds a monitor session with its audit trail
+func (r *monitorSessionRepository) FindWithEvents(ctx context.Context, id int64) (*asterisk.MonitorSession, error) {
+	var session asterisk.MonitorSession
+	err := r.db.WithContext(ctx).
+		Preload("Supervisor").
+		Preload("Events", func(db *gorm.DB) *gorm.DB {
+			return db.Order("created_at ASC, id ASC")
+		}).
+		Where("id = ?", id).
+		First(&session).Error
+	if err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+// FindByTenant finds monitor sessions for a tenant with pagination, optionally for one supervisor
+func (r *monitorSessionRepository) FindByTenant(ctx context.Context, tenantID string, supervisorID int64, page, pageSize int) ([]asterisk.MonitorSession, int64, error) {
+	var sessions []asterisk.MonitorSession
+	var total int64
+
+	query := r.db.WithContext(ctx).Model(&asterisk.MonitorSession{}).Where("tenant_id = ?", tenantID)
+	if supervisorID != 0 {
+		query = query.Where("supervisor_id = ?", supervisorID)
+	}
+
+	// Count total
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	// Get paginated results
+	offset := (page - 1) * pageSize
+	err := query.
+		Preload("Supervisor").
+		Offset(offset).
+		Limit(pageSize).
+		Order("started_at DESC").
+		Find(&sessions).Error
+
+	return sessions, total, err
+}
+
+// FindOpen finds sessions that have not ended
+func (r *monitorSessionRepository) FindOpen(ctx context.Context) ([]asterisk.MonitorSession, error) {
+	var sessions []asterisk.MonitorSession
+	err := r.db.WithContext(ctx).
+		Where("status <> ?", asterisk.MonitorStatusEnded).
+		Find(&sessions).Error
+	return sessions, err
+}
+
+// Update updates a monitor session
+func (r *monitorSessionRepository) Update(ctx context.Context, session *asterisk.MonitorSession) error {
+	return r.db.WithContext(ctx).Omit("Tenant", "Supervisor", "Events").Save(session).Error
+}
+
+// UpdateMode updates only the mode of a monitor session, leaving its status to the call engine
+func (r *monitorSessionRepository) UpdateMode(ctx context.Context, id int64, mode string) error {
+	return r.db.WithContext(ctx).
+		Model(&asterisk.MonitorSession{}).
+		Where("id = ?", id).
+		Update("mode", mode).Error
+}
+
+// CreateEvent appends an event to a monitor session's audit trail
+func (r *monitorSessionRepository) CreateEvent(ctx context.Context, event *asterisk.MonitorSessionEvent) error {
+	return r.db.WithContext(ctx).Create(event).Error
+}
@@ -0,0 +1,94 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/psschand/callcenter/internal/asterisk"
+	"gorm.io/gorm"
+)
+
+// OutboundRouteRepository defines the interface for outbound route data access
+type OutboundRouteRepository interface {
+	Create(ctx context.Context, route *asterisk.OutboundRoute) error
+	FindByID(ctx context.Context, id int64) (*asterisk.OutboundRoute, error)
+	FindByTenant(ctx context.Context, tenantID string) ([]asterisk.OutboundRoute, error)
+	FindForDialing(ctx context.Context, tenantID string) ([]asterisk.OutboundRoute, error)
+	CountByTrunk(ctx context.Context, trunkID int64) (int64, error)
+	Update(ctx context.Context, route *asterisk.OutboundRoute) error
+	Delete(ctx context.Context, id int64) error
+}
+
+// outboundRouteRepository implements OutboundRouteRepository
+type outboundRouteRepository struct {
+	db *gorm.DB
+}
+
+// NewOutboundRouteRepository creates a new outbound route repository
+func NewOutboundRouteRepository(db *gorm.DB) OutboundRouteRepository {
+	return &outboundRouteRepository{db: db}
+}
+
+// Create creates a new outbound route
+func (r *outboundRouteRepository) Create(ctx context.Context, route *asterisk.OutboundRoute) error {
+	return r.db.WithContext(ctx).Omit("Tenant", "Trunk", "FailoverTrunk").Create(route).Error
+}
+
+// FindByID finds an outbound route by ID with its trunks
+func (r *outboundRouteRepository) FindByID(ctx context.Context, id int64) (*asterisk.OutboundRoute, error) {
+	var route asterisk.OutboundRoute
+	err := r.db.WithContext(ctx).
+		Preload("Trunk").
+		Preload("FailoverTrunk").
+		Where("id = ?", id).
+		First(&route).Error
+	if err != nil {
+		return nil, err
+	}
+	return &route, nil
+}
+
+// FindByTenant finds the outbound routes that apply to a tenant, its own and
+// the platform's, with their trunks
+func (r *outboundRouteRepository) FindByTenant(ctx context.Context, tenantID string) ([]asterisk.OutboundRoute, error) {
+	var routes []asterisk.OutboundRoute
+	err := r.db.WithContext(ctx).
+		Preload("Trunk").
+		Preload("FailoverTrunk").
+		Where("tenant_id = ? OR tenant_id IS NULL", tenantID).
+		Order("tenant_id IS NULL, LENGTH(prefix) DESC, priority ASC").
+		Find(&routes).Error
+	return routes, err
+}
+
+// FindForDialing finds the active outbound routes a tenant's calls may take,
+// with their trunks
+func (r *outboundRouteRepository) FindForDialing(ctx context.Context, tenantID string) ([]asterisk.OutboundRoute, error) {
+	var routes []asterisk.OutboundRoute
+	err := r.db.WithContext(ctx).
+		Preload("Trunk").
+		Preload("FailoverTrunk").
+		Where("(tenant_id = ? OR tenant_id IS NULL) AND is_active = ?", tenantID, true).
+		Find(&routes).Error
+	return routes, err
+}
+
+// CountByTrunk counts the outbound routes that send calls through a trunk,
+// as their primary or failover trunk
+func (r *outboundRouteRepository) CountByTrunk(ctx context.Context, trunkID int64) (int64, error) {
+	var count int64
+	err := r.db.WithContext(ctx).
+		Model(&asterisk.OutboundRoute{}).
+		Where("trunk_id = ? OR failover_trunk_id = ?", trunkID, trunkID).
+		Count(&count).Error
+	return count, err
+}
+
+// Update updates an outbound route
+func (r *outboundRouteRepository) Update(ctx context.Context, route *asterisk.OutboundRoute) error {
+	return r.db.WithContext(ctx).Omit("Tenant", "Trunk", "FailoverTrunk").Save(route).Error
+}
+
+// Delete deletes an outbound route
+func (r *outboundRouteRepository) Delete(ctx context.Context, id int64) error {
+	return r.db.WithContext(ctx).Delete(&asterisk.OutboundRoute{}, id).Error
+}
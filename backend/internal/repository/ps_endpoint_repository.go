@@ -15,6 +15,12 @@ type PsEndpointRepository interface {
 	Update(ctx context.Context, endpoint *asterisk.PsEndpoint) error
 	Delete(ctx context.Context, id string) error
 	FindWithAuthAndAor(ctx context.Context, id string) (*asterisk.PsEndpoint, error)
+	CreateWithAuthAndAor(ctx context.Context, endpoint *asterisk.PsEndpoint, auth *asterisk.PsAuth, aor *asterisk.PsAor) error
+	DeleteWithAuthAndAor(ctx context.Context, id string) error
+	SaveTrunk(ctx context.Context, endpoint *asterisk.PsEndpoint, aor *asterisk.PsAor, auth *asterisk.PsAuth, registration *asterisk.PsRegistration, identify *asterisk.PsEndpointIdentify) error
+	DisableTrunk(ctx context.Context, id string, auth *asterisk.PsAuth) error
+	DeleteTrunk(ctx context.Context, id string) error
+	FindTrunkAuth(ctx context.Context, id string) (*asterisk.PsAuth, error)
 }
 
 // psEndpointRepository implements PsEndpointRepository
@@ -74,3 +80,143 @@ func (r *psEndpointRepository) FindWithAuthAndAor(ctx context.Context, id string
 	}
 	return &endpoint, nil
 }
+
+// CreateWithAuthAndAor creates an endpoint with its auth and AOR, so Asterisk
+// never sees an endpoint it cannot authenticate
+func (r *psEndpointRepository) CreateWithAuthAndAor(ctx context.Context, endpoint *asterisk.PsEndpoint, auth *asterisk.PsAuth, aor *asterisk.PsAor) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit("Tenant").Create(auth).Error; err != nil {
+			return err
+		}
+		if err := tx.Omit("Tenant").Create(aor).Error; err != nil {
+			return err
+		}
+		return tx.Omit("Tenant").Create(endpoint).Error
+	})
+}
+
+// DeleteWithAuthAndAor deletes an endpoint with its auth, AOR and registered contacts
+func (r *psEndpointRepository) DeleteWithAuthAndAor(ctx context.Context, id string) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var endpoint asterisk.PsEndpoint
+		if err := tx.Where("id = ?", id).First(&endpoint).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("endpoint = ?", id).Delete(&asterisk.PsContact{}).Error; err != nil {
+			return err
+		}
+		if endpoint.Auth != nil {
+			if err := tx.Where("id = ?", *endpoint.Auth).Delete(&asterisk.PsAuth{}).Error; err != nil {
+				return err
+			}
+		}
+		if endpoint.Aors != nil {
+			if err := tx.Where("id = ?", *endpoint.Aors).Delete(&asterisk.PsAor{}).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Where("id = ?", id).Delete(&asterisk.PsEndpoint{}).Error
+	})
+}
+
+// SaveTrunk writes a trunk's endpoint with its AOR and optional auth,
+// outbound registration and identify rule, replacing whatever the trunk had
+func (r *psEndpointRepository) SaveTrunk(ctx context.Context, endpoint *asterisk.PsEndpoint, aor *asterisk.PsAor, auth *asterisk.PsAuth, registration *asterisk.PsRegistration, identify *asterisk.PsEndpointIdentify) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := deleteTrunkRows(tx, endpoint.ID); err != nil {
+			return err
+		}
+		if auth != nil {
+			if err := tx.Omit("Tenant").Create(auth).Error; err != nil {
+				return err
+			}
+		}
+		if err := tx.Omit("Tenant").Create(aor).Error; err != nil {
+			return err
+		}
+		if registration != nil {
+			if err := tx.Create(registration).Error; err != nil {
+				return err
+			}
+		}
+		if identify != nil {
+			if err := tx.Create(identify).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Omit("Tenant").Create(endpoint).Error
+	})
+}
+
+// DisableTrunk deletes a trunk's endpoint with its AOR, outbound registration
+// and identify rule, keeping only its auth: without an endpoint Asterisk never
+// uses it, and the trunk is enabled again with the same credentials
+func (r *psEndpointRepository) DisableTrunk(ctx context.Context, id string, auth *asterisk.PsAuth) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := deleteTrunkRows(tx, id); err != nil {
+			return err
+		}
+		if auth == nil {
+			return nil
+		}
+		return tx.Omit("Tenant").Create(auth).Error
+	})
+}
+
+// FindTrunkAuth finds the auth of an enabled or disabled trunk endpoint
+func (r *psEndpointRepository) FindTrunkAuth(ctx context.Context, id string) (*asterisk.PsAuth, error) {
+	var auth asterisk.PsAuth
+	err := r.db.WithContext(ctx).Where("id = ?", asterisk.TrunkAuthID(id)).First(&auth).Error
+	if err != nil {
+		return nil, err
+	}
+	return &auth, nil
+}
+
+// DeleteTrunk deletes a trunk's endpoint with its AOR, auth, outbound
+// registration and identify rule
+func (r *psEndpointRepository) DeleteTrunk(ctx context.Context, id string) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return deleteTrunkRows(tx, id)
+	})
+}
+
+// deleteTrunkRows deletes the ARA rows of a trunk endpoint, if there are any,
+// and the auth a disabled trunk keeps
+func deleteTrunkRows(tx *gorm.DB, id string) error {
+	if err := tx.Where("id = ?", asterisk.TrunkAuthID(id)).Delete(&asterisk.PsAuth{}).Error; err != nil {
+		return err
+	}
+	if err := tx.Where("endpoint = ?", id).Delete(&asterisk.PsEndpointIdentify{}).Error; err != nil {
+		return err
+	}
+	if err := tx.Where("endpoint = ?", id).Delete(&asterisk.PsRegistration{}).Error; err != nil {
+		return err
+	}
+	if err := tx.Where("endpoint = ?", id).Delete(&asterisk.PsContact{}).Error; err != nil {
+		return err
+	}
+
+	var endpoints []asterisk.PsEndpoint
+	if err := tx.Where("id = ?", id).Limit(1).Find(&endpoints).Error; err != nil {
+		return err
+	}
+	if len(endpoints) == 0 {
+		return nil
+	}
+	endpoint := endpoints[0]
+
+	for _, authID := range []*string{endpoint.Auth, endpoint.OutboundAuth} {
+		if authID != nil {
+			if err := tx.Where("id = ?", *authID).Delete(&asterisk.PsAuth{}).Error; err != nil {
+				return err
+			}
+		}
+	}
+	if endpoint.Aors != nil {
+		if err := tx.Where("id = ?", *endpoint.Aors).Delete(&asterisk.PsAor{}).Error; err != nil {
+			return err
+		}
+	}
+	return tx.Where("id = ?", id).Delete(&asterisk.PsEndpoint{}).Error
+}
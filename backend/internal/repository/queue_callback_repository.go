@@ -0,0 +1,105 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/psschand/callcenter/internal/asterisk"
+	"gorm.io/gorm"
+)
+
+// QueueCallbackFilter narrows the callbacks listed. Zero fields match everything.
+type QueueCallbackFilter struct {
+	QueueName string
+	Status    string
+	Start     time.Time
+	End       time.Time
+}
+
+// QueueCallbackRepository defines the interface for queue callback data access
+type QueueCallbackRepository interface {
+	Create(ctx context.Context, callback *asterisk.QueueCallback) error
+	FindByID(ctx context.Context, id int64) (*asterisk.QueueCallback, error)
+	FindByTenant(ctx context.Context, tenantID string, filter QueueCallbackFilter, page, pageSize int) ([]asterisk.QueueCallback, int64, error)
+	FindOpen(ctx context.Context) ([]asterisk.QueueCallback, error)
+	Update(ctx context.Context, callback *asterisk.QueueCallback) error
+}
+
+// queueCallbackRepository implements QueueCallbackRepository
+type queueCallbackRepository struct {
+	db *gorm.DB
+}
+
+// NewQueueCallbackRepository creates a new queue callback repository
+func NewQueueCallbackRepository(db *gorm.DB) QueueCallbackRepository {
+	return &queueCallbackRepository{db: db}
+}
+
+// Create creates a new callback
+func (r *queueCallbackRepository) Create(ctx context.Context, callback *asterisk.QueueCallback) error {
+	return r.db.WithContext(ctx).Omit("Tenant").Create(callback).Error
+}
+
+// FindByID finds a callback by ID
+func (r *queueCallbackRepository) FindByID(ctx context.Context, id int64) (*asterisk.QueueCallback, error) {
+	var callback asterisk.QueueCallback
+	err := r.db.WithContext(ctx).Where("id = ?", id).First(&callback).Error
+	if err != nil {
+		return nil, err
+	}
+	return &callback, nil
+}
+
+// FindByTenant finds a tenant's callbacks with pagination, newest first
+func (r *queueCallbackRepository) FindByTenant(ctx context.Context, tenantID string, filter QueueCallbackFilter, page, pageSize int) ([]asterisk.QueueCallback, int64, error) {
+	var callbacks []asterisk.QueueCallback
+	var total int64
+
+	scope := func(db *gorm.DB) *gorm.DB {
+		db = db.Where("tenant_id = ?", tenantID)
+		if filter.QueueName != "" {
+			db = db.Where("queue_name = ?", filter.QueueName)
+		}
+		if filter.Status != "" {
+			db = db.Where("status = ?", filter.Status)
+		}
+		if !filter.Start.IsZero() {
+			db = db.Where("requested_at >= ?", filter.Start)
+		}
+		if !filter.End.IsZero() {
+			db = db.Where("requested_at <= ?", filter.End)
+		}
+		return db
+	}
+
+	// Count total
+	if err := r.db.WithContext(ctx).Model(&asterisk.QueueCallback{}).Scopes(scope).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	// Get paginated results
+	offset := (page - 1) * pageSize
+	err := r.db.WithContext(ctx).
+		Scopes(scope).
+		Offset(offset).
+		Limit(pageSize).
+		Order("requested_at DESC, id DESC").
+		Find(&callbacks).Error
+
+	return callbacks, total, err
+}
+
+// FindOpen finds callbacks that have yet to be completed, failed or cancelled, oldest first
+func (r *queueCallbackRepository) FindOpen(ctx context.Context) ([]asterisk.QueueCallback, error) {
+	var callbacks []asterisk.QueueCallback
+	err := r.db.WithContext(ctx).
+		Where("status IN ?", []string{asterisk.CallbackStatusPending, asterisk.CallbackStatusDialing, asterisk.CallbackStatusConnected}).
+		Order("requested_at ASC, id ASC").
+		Find(&callbacks).Error
+	return callbacks, err
+}
+
+// Update updates a callback
+func (r *queueCallbackRepository) Update(ctx context.Context, callback *asterisk.QueueCallback) error {
+	return r.db.WithContext(ctx).Omit("Tenant").Save(callback).Error
+}
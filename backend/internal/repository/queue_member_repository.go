@@ -17,6 +17,7 @@ type QueueMemberRepository interface {
 	Delete(ctx context.Context, id int64) error
 	FindActiveByQueue(ctx context.Context, queueID int64) ([]asterisk.QueueMember, error)
 	RemoveUserFromQueue(ctx context.Context, queueID, userID int64) error
+	FindByQueueName(ctx context.Context, tenantID, queueName string) ([]asterisk.QueueMember, error)
 }
 
 // queueMemberRepository implements QueueMemberRepository
@@ -96,3 +97,13 @@ func (r *queueMemberRepository) RemoveUserFromQueue(ctx context.Context, queueID
 		Where("queue_id = ? AND user_id = ?", queueID, userID).
 		Delete(&asterisk.QueueMember{}).Error
 }
+
+// FindByQueueName finds all members of a tenant's queue in insertion order
+func (r *queueMemberRepository) FindByQueueName(ctx context.Context, tenantID, queueName string) ([]asterisk.QueueMember, error) {
+	var members []asterisk.QueueMember
+	err := r.db.WithContext(ctx).
+		Where("tenant_id = ? AND queue_name = ?", tenantID, queueName).
+		Order("uniqueid ASC").
+		Find(&members).Error
+	return members, err
+}
@@ -0,0 +1,63 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/psschand/callcenter/internal/core"
+	"gorm.io/gorm"
+)
+
+// ScheduleRepository defines the interface for schedule data access
+type ScheduleRepository interface {
+	Create(ctx context.Context, schedule *core.Schedule) error
+	FindByID(ctx context.Context, id int64) (*core.Schedule, error)
+	FindByTenant(ctx context.Context, tenantID string) ([]core.Schedule, error)
+	Update(ctx context.Context, schedule *core.Schedule) error
+	Delete(ctx context.Context, id int64) error
+}
+
+// scheduleRepository implements ScheduleRepository
+type scheduleRepository struct {
+	db *gorm.DB
+}
+
+// NewScheduleRepository creates a new schedule repository
+func NewScheduleRepository(db *gorm.DB) ScheduleRepository {
+	return &scheduleRepository{db: db}
+}
+
+// Create creates a new schedule
+func (r *scheduleRepository) Create(ctx context.Context, schedule *core.Schedule) error {
+	return r.db.WithContext(ctx).Omit("Tenant").Create(schedule).Error
+}
+
+// FindByID finds a schedule by ID
+func (r *scheduleRepository) FindByID(ctx context.Context, id int64) (*core.Schedule, error) {
+	var schedule core.Schedule
+	err := r.db.WithContext(ctx).Where("id = ?", id).First(&schedule).Error
+	if err != nil {
+		return nil, err
+	}
+	return &schedule, nil
+}
+
+// FindByTenant finds all schedules of a tenant
+func (r *scheduleRepository) FindByTenant(ctx context.Context, tenantID string) ([]core.Schedule, error) {
+	var schedules []core.Schedule
+	err := r.db.WithContext(ctx).
+		Where("tenant_id = ?", tenantID).
+		Order("name ASC").
+		Find(&schedules).Error
+	return schedules, err
+}
+
+// Update updates a schedule
+func (r *scheduleRepository) Update(ctx context.Context, schedule *core.Schedule) error {
+	return r.db.WithContext(ctx).Omit("Tenant").Save(schedule).Error
+}
+
+// Delete deletes a schedule; DIDs, queues and chat widgets using it are
+// left without one
+func (r *scheduleRepository) Delete(ctx context.Context, id int64) error {
+	return r.db.WithContext(ctx).Delete(&core.Schedule{}, id).Error
+}
@@ -0,0 +1,81 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/psschand/callcenter/internal/asterisk"
+	"gorm.io/gorm"
+)
+
+// SIPTrunkRepository defines the interface for SIP trunk data access
+type SIPTrunkRepository interface {
+	Create(ctx context.Context, trunk *asterisk.SIPTrunk) error
+	FindByID(ctx context.Context, id int64) (*asterisk.SIPTrunk, error)
+	FindByEndpointID(ctx context.Context, endpointID string) (*asterisk.SIPTrunk, error)
+	FindByTenant(ctx context.Context, tenantID string) ([]asterisk.SIPTrunk, error)
+	FindAll(ctx context.Context) ([]asterisk.SIPTrunk, error)
+	Update(ctx context.Context, trunk *asterisk.SIPTrunk) error
+	Delete(ctx context.Context, id int64) error
+}
+
+// sipTrunkRepository implements SIPTrunkRepository
+type sipTrunkRepository struct {
+	db *gorm.DB
+}
+
+// NewSIPTrunkRepository creates a new SIP trunk repository
+func NewSIPTrunkRepository(db *gorm.DB) SIPTrunkRepository {
+	return &sipTrunkRepository{db: db}
+}
+
+// Create creates a new trunk
+func (r *sipTrunkRepository) Create(ctx context.Context, trunk *asterisk.SIPTrunk) error {
+	return r.db.WithContext(ctx).Omit("Tenant").Create(trunk).Error
+}
+
+// FindByID finds a trunk by ID
+func (r *sipTrunkRepository) FindByID(ctx context.Context, id int64) (*asterisk.SIPTrunk, error) {
+	var trunk asterisk.SIPTrunk
+	err := r.db.WithContext(ctx).Where("id = ?", id).First(&trunk).Error
+	if err != nil {
+		return nil, err
+	}
+	return &trunk, nil
+}
+
+// FindByEndpointID finds the trunk provisioned as a PJSIP endpoint
+func (r *sipTrunkRepository) FindByEndpointID(ctx context.Context, endpointID string) (*asterisk.SIPTrunk, error) {
+	var trunk asterisk.SIPTrunk
+	err := r.db.WithContext(ctx).Where("endpoint_id = ?", endpointID).First(&trunk).Error
+	if err != nil {
+		return nil, err
+	}
+	return &trunk, nil
+}
+
+// FindByTenant finds the trunks a tenant may use: its own and the platform's
+func (r *sipTrunkRepository) FindByTenant(ctx context.Context, tenantID string) ([]asterisk.SIPTrunk, error) {
+	var trunks []asterisk.SIPTrunk
+	err := r.db.WithContext(ctx).
+		Where("tenant_id = ? OR tenant_id IS NULL", tenantID).
+		Order("tenant_id IS NULL, name ASC").
+		Find(&trunks).Error
+	return trunks, err
+}
+
+// FindAll finds every tenant's trunks and the platform's
+func (r *sipTrunkRepository) FindAll(ctx context.Context) ([]asterisk.SIPTrunk, error) {
+	var trunks []asterisk.SIPTrunk
+	err := r.db.WithContext(ctx).Order("id ASC").Find(&trunks).Error
+	return trunks, err
+}
+
+// Update updates a trunk
+func (r *sipTrunkRepository) Update(ctx context.Context, trunk *asterisk.SIPTrunk) error {
+	return r.db.WithContext(ctx).Omit("Tenant").Save(trunk).Error
+}
+
+// Delete deletes a trunk
+func (r *sipTrunkRepository) Delete(ctx context.Context, id int64) error {
+	return r.db.WithContext(ctx).Delete(&asterisk.SIPTrunk{}, id).Error
+}
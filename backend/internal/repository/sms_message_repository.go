@@ -0,0 +1,101 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/psschand/callcenter/internal/asterisk"
+	"github.com/psschand/callcenter/internal/common"
+	"gorm.io/gorm"
+)
+
+// SMSMessageFilter narrows the SMS messages listed; empty fields match all
+type SMSMessageFilter struct {
+	Direction common.SMSDirection
+	Status    common.SMSStatus
+	Number    string // sender or recipient
+}
+
+// SMSMessageRepository defines the interface for SMS message data access
+type SMSMessageRepository interface {
+	Create(ctx context.Context, message *asterisk.SMSMessage) error
+	FindByID(ctx context.Context, id int64) (*asterisk.SMSMessage, error)
+	FindByProviderID(ctx context.Context, provider, providerMessageID string) (*asterisk.SMSMessage, error)
+	FindByTenant(ctx context.Context, tenantID string, filter SMSMessageFilter, page, pageSize int) ([]asterisk.SMSMessage, int64, error)
+	Update(ctx context.Context, message *asterisk.SMSMessage) error
+}
+
+// smsMessageRepository implements SMSMessageRepository
+type smsMessageRepository struct {
+	db *gorm.DB
+}
+
+// NewSMSMessageRepository creates a new SMS message repository
+func NewSMSMessageRepository(db *gorm.DB) SMSMessageRepository {
+	return &smsMessageRepository{db: db}
+}
+
+// Create creates a new SMS message
+func (r *smsMessageRepository) Create(ctx context.Context, message *asterisk.SMSMessage) error {
+	return r.db.WithContext(ctx).Omit("Tenant", "DID", "User").Create(message).Error
+}
+
+// FindByID finds an SMS message by ID
+func (r *smsMessageRepository) FindByID(ctx context.Context, id int64) (*asterisk.SMSMessage, error) {
+	var message asterisk.SMSMessage
+	err := r.db.WithContext(ctx).Where("id = ?", id).First(&message).Error
+	if err != nil {
+		return nil, err
+	}
+	return &message, nil
+}
+
+// FindByProviderID finds an SMS message by the ID its provider gave it
+func (r *smsMessageRepository) FindByProviderID(ctx context.Context, provider, providerMessageID string) (*asterisk.SMSMessage, error) {
+	var message asterisk.SMSMessage
+	err := r.db.WithContext(ctx).
+		Where("provider = ? AND provider_message_id = ?", provider, providerMessageID).
+		First(&message).Error
+	if err != nil {
+		return nil, err
+	}
+	return &message, nil
+}
+
+// FindByTenant finds a tenant's SMS messages, latest first, with pagination
+func (r *smsMessageRepository) FindByTenant(ctx context.Context, tenantID string, filter SMSMessageFilter, page, pageSize int) ([]asterisk.SMSMessage, int64, error) {
+	var messages []asterisk.SMSMessage
+	var total int64
+
+	query := r.db.WithContext(ctx).
+		Model(&asterisk.SMSMessage{}).
+		Where("tenant_id = ?", tenantID)
+	if filter.Direction != "" {
+		query = query.Where("direction = ?", filter.Direction)
+	}
+	if filter.Status != "" {
+		query = query.Where("status = ?", filter.Status)
+	}
+	if filter.Number != "" {
+		query = query.Where("sender = ? OR recipient = ?", filter.Number, filter.Number)
+	}
+
+	// Count total
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	// Get paginated results
+	offset := (page - 1) * pageSize
+	err := query.
+		Offset(offset).
+		Limit(pageSize).
+		Order("created_at DESC, id DESC").
+		Find(&messages).Error
+
+	return messages, total, err
+}
+
+// Update updates an SMS message
+func (r *smsMessageRepository) Update(ctx context.Context, message *asterisk.SMSMessage) error {
+	return r.db.WithContext(ctx).Omit("Tenant", "DID", "User").Save(message).Error
+}
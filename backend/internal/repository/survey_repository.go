@@ -0,0 +1,175 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/psschand/callcenter/internal/asterisk"
+	"gorm.io/gorm"
+)
+
+// SurveyResponseFilter narrows the survey responses listed or scored. Zero
+// fields match everything.
+type SurveyResponseFilter struct {
+	SurveyID  int64
+	UserID    int64
+	QueueName string
+	Start     time.Time
+	End       time.Time
+}
+
+// SurveyScoreTotal totals the survey scores of one agent or queue. UserID
+// is set when grouping by agent and QueueName when grouping by queue; nil
+// collects the responses without one.
+type SurveyScoreTotal struct {
+	UserID        *int64
+	QueueName     *string
+	Responses     int64
+	CSATResponses int64
+	CSATSum       int64
+	CSATSatisfied int64
+	NPSResponses  int64
+	Promoters     int64
+	Detractors    int64
+}
+
+// SurveyRepository defines the interface for post-call survey data access
+type SurveyRepository interface {
+	Create(ctx context.Context, survey *asterisk.CallSurvey) error
+	FindByID(ctx context.Context, id int64) (*asterisk.CallSurvey, error)
+	FindByTenant(ctx context.Context, tenantID string) ([]asterisk.CallSurvey, error)
+	Update(ctx context.Context, survey *asterisk.CallSurvey) error
+	Delete(ctx context.Context, id int64) error
+	CreateResponse(ctx context.Context, response *asterisk.SurveyResponse) error
+	FindResponses(ctx context.Context, tenantID string, filter SurveyResponseFilter, page, pageSize int) ([]asterisk.SurveyResponse, int64, error)
+	ScoresByAgent(ctx context.Context, tenantID string, filter SurveyResponseFilter) ([]SurveyScoreTotal, error)
+	ScoresByQueue(ctx context.Context, tenantID string, filter SurveyResponseFilter) ([]SurveyScoreTotal, error)
+}
+
+// surveyRepository implements SurveyRepository
+type surveyRepository struct {
+	db *gorm.DB
+}
+
+// NewSurveyRepository creates a new survey repository
+func NewSurveyRepository(db *gorm.DB) SurveyRepository {
+	return &surveyRepository{db: db}
+}
+
+// Create creates a new survey
+func (r *surveyRepository) Create(ctx context.Context, survey *asterisk.CallSurvey) error {
+	return r.db.WithContext(ctx).Omit("Tenant").Create(survey).Error
+}
+
+// FindByID finds a survey by ID
+func (r *surveyRepository) FindByID(ctx context.Context, id int64) (*asterisk.CallSurvey, error) {
+	var survey asterisk.CallSurvey
+	err := r.db.WithContext(ctx).Where("id = ?", id).First(&survey).Error
+	if err != nil {
+		return nil, err
+	}
+	return &survey, nil
+}
+
+// FindByTenant finds all surveys of a tenant
+func (r *surveyRepository) FindByTenant(ctx context.Context, tenantID string) ([]asterisk.CallSurvey, error) {
+	var surveys []asterisk.CallSurvey
+	err := r.db.WithContext(ctx).
+		Where("tenant_id = ?", tenantID).
+		Order("name ASC").
+		Find(&surveys).Error
+	return surveys, err
+}
+
+// Update updates a survey
+func (r *surveyRepository) Update(ctx context.Context, survey *asterisk.CallSurvey) error {
+	return r.db.WithContext(ctx).Omit("Tenant").Save(survey).Error
+}
+
+// Delete deletes a survey with its responses; DIDs and queues using it are
+// left without one
+func (r *surveyRepository) Delete(ctx context.Context, id int64) error {
+	return r.db.WithContext(ctx).Delete(&asterisk.CallSurvey{}, id).Error
+}
+
+// CreateResponse stores a caller's survey answers
+func (r *surveyRepository) CreateResponse(ctx context.Context, response *asterisk.SurveyResponse) error {
+	return r.db.WithContext(ctx).Create(response).Error
+}
+
+// FindResponses finds a tenant's survey responses with pagination, newest first
+func (r *surveyRepository) FindResponses(ctx context.Context, tenantID string, filter SurveyResponseFilter, page, pageSize int) ([]asterisk.SurveyResponse, int64, error) {
+	var responses []asterisk.SurveyResponse
+	var total int64
+
+	scope := responseScope(tenantID, filter)
+
+	// Count total
+	if err := r.db.WithContext(ctx).Model(&asterisk.SurveyResponse{}).Scopes(scope).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	// Get paginated results
+	offset := (page - 1) * pageSize
+	err := r.db.WithContext(ctx).
+		Scopes(scope).
+		Offset(offset).
+		Limit(pageSize).
+		Order("completed_at DESC, id DESC").
+		Find(&responses).Error
+
+	return responses, total, err
+}
+
+// ScoresByAgent totals the survey scores of each agent
+func (r *surveyRepository) ScoresByAgent(ctx context.Context, tenantID string, filter SurveyResponseFilter) ([]SurveyScoreTotal, error) {
+	return r.scores(ctx, tenantID, filter, "user_id")
+}
+
+// ScoresByQueue totals the survey scores of each queue
+func (r *surveyRepository) ScoresByQueue(ctx context.Context, tenantID string, filter SurveyResponseFilter) ([]SurveyScoreTotal, error) {
+	return r.scores(ctx, tenantID, filter, "queue_name")
+}
+
+// scores totals survey scores grouped by a column. NPS promoters rate 9 or
+// 10 and detractors 0 to 6.
+func (r *surveyRepository) scores(ctx context.Context, tenantID string, filter SurveyResponseFilter, column string) ([]SurveyScoreTotal, error) {
+	var totals []SurveyScoreTotal
+	err := r.db.WithContext(ctx).
+		Model(&asterisk.SurveyResponse{}).
+		Scopes(responseScope(tenantID, filter)).
+		Select(column + `, COUNT(*) AS responses,
+			COUNT(csat_score) AS csat_responses,
+			COALESCE(SUM(csat_score), 0) AS csat_sum,
+			COALESCE(SUM(csat_satisfied), 0) AS csat_satisfied,
+			COUNT(nps_score) AS nps_responses,
+			COALESCE(SUM(nps_score >= 9), 0) AS promoters,
+			COALESCE(SUM(nps_score <= 6), 0) AS detractors`).
+		Group(column).
+		Order(column).
+		Scan(&totals).Error
+	return totals, err
+}
+
+// responseScope selects a tenant's survey responses matching a filter
+func responseScope(tenantID string, filter SurveyResponseFilter) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		db = db.Where("tenant_id = ?", tenantID)
+		if filter.SurveyID != 0 {
+			db = db.Where("survey_id = ?", filter.SurveyID)
+		}
+		if filter.UserID != 0 {
+			db = db.Where("user_id = ?", filter.UserID)
+		}
+		if filter.QueueName != "" {
+			db = db.Where("queue_name = ?", filter.QueueName)
+		}
+		if !filter.Start.IsZero() {
+			db = db.Where("completed_at >= ?", filter.Start)
+		}
+		if !filter.End.IsZero() {
+			db = db.Where("completed_at <= ?", filter.End)
+		}
+		return db
+	}
+}
@@ -0,0 +1,135 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/psschand/callcenter/internal/asterisk"
+	"github.com/psschand/callcenter/internal/common"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// TranscriptRepository defines the interface for transcript data access
+type TranscriptRepository interface {
+	Save(ctx context.Context, transcript *asterisk.Transcript) error
+	FindBySource(ctx context.Context, sourceType common.TranscriptSource, sourceID int64) (*asterisk.Transcript, error)
+	DeleteBySource(ctx context.Context, sourceType common.TranscriptSource, sourceID int64) error
+	Search(ctx context.Context, tenantID, query string, sourceType common.TranscriptSource, voicemailOwner int64, page, pageSize int) ([]asterisk.Transcript, int64, error)
+	FindMatchingSegments(ctx context.Context, transcriptIDs []int64, query string) ([]asterisk.TranscriptSegment, error)
+}
+
+// transcriptRepository implements TranscriptRepository
+type transcriptRepository struct {
+	db *gorm.DB
+}
+
+// NewTranscriptRepository creates a new transcript repository
+func NewTranscriptRepository(db *gorm.DB) TranscriptRepository {
+	return &transcriptRepository{db: db}
+}
+
+// Save stores a transcript with its segments, replacing any earlier transcript of the same source
+func (r *transcriptRepository) Save(ctx context.Context, transcript *asterisk.Transcript) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := deleteTranscripts(tx, transcript.SourceType, transcript.SourceID); err != nil {
+			return err
+		}
+
+		segments := transcript.Segments
+		if err := tx.Omit("Tenant", "Segments").Create(transcript).Error; err != nil {
+			return err
+		}
+		if len(segments) == 0 {
+			return nil
+		}
+		for i := range segments {
+			segments[i].TranscriptID = transcript.ID
+			segments[i].Sequence = i
+		}
+		return tx.CreateInBatches(segments, 500).Error
+	})
+}
+
+// FindBySource finds the transcript of a recording or voicemail with its segments in order
+func (r *transcriptRepository) FindBySource(ctx context.Context, sourceType common.TranscriptSource, sourceID int64) (*asterisk.Transcript, error) {
+	var transcript asterisk.Transcript
+	err := r.db.WithContext(ctx).
+		Preload("Segments", func(db *gorm.DB) *gorm.DB {
+			return db.Order("sequence ASC")
+		}).
+		Where("source_type = ? AND source_id = ?", sourceType, sourceID).
+		First(&transcript).Error
+	if err != nil {
+		return nil, err
+	}
+	return &transcript, nil
+}
+
+// DeleteBySource deletes the transcript of a recording or voicemail
+func (r *transcriptRepository) DeleteBySource(ctx context.Context, sourceType common.TranscriptSource, sourceID int64) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return deleteTranscripts(tx, sourceType, sourceID)
+	})
+}
+
+// Search finds a tenant's transcripts matching a full-text query, best
+// match first, with pagination. sourceType limits it to recordings or
+// voicemails; a non-zero voicemailOwner limits voicemails to that user's.
+func (r *transcriptRepository) Search(ctx context.Context, tenantID, query string, sourceType common.TranscriptSource, voicemailOwner int64, page, pageSize int) ([]asterisk.Transcript, int64, error) {
+	var transcripts []asterisk.Transcript
+	var total int64
+
+	q := r.db.WithContext(ctx).
+		Model(&asterisk.Transcript{}).
+		Where("tenant_id = ?", tenantID).
+		Where("MATCH(text) AGAINST (? IN NATURAL LANGUAGE MODE)", query)
+	if sourceType != "" {
+		q = q.Where("source_type = ?", sourceType)
+	}
+	if voicemailOwner != 0 {
+		q = q.Where("(source_type = ? OR user_id = ?)", common.TranscriptSourceRecording, voicemailOwner)
+	}
+
+	// Count total
+	if err := q.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	// Get paginated results
+	offset := (page - 1) * pageSize
+	err := q.
+		Offset(offset).
+		Limit(pageSize).
+		Order(clause.OrderBy{Expression: clause.Expr{
+			SQL:  "MATCH(text) AGAINST (? IN NATURAL LANGUAGE MODE) DESC, created_at DESC",
+			Vars: []interface{}{query},
+		}}).
+		Find(&transcripts).Error
+
+	return transcripts, total, err
+}
+
+// FindMatchingSegments finds the segments of the given transcripts matching a full-text query, in order
+func (r *transcriptRepository) FindMatchingSegments(ctx context.Context, transcriptIDs []int64, query string) ([]asterisk.TranscriptSegment, error) {
+	var segments []asterisk.TranscriptSegment
+	if len(transcriptIDs) == 0 {
+		return segments, nil
+	}
+	err := r.db.WithContext(ctx).
+		Where("transcript_id IN ?", transcriptIDs).
+		Where("MATCH(text) AGAINST (? IN NATURAL LANGUAGE MODE)", query).
+		Order("transcript_id ASC, sequence ASC").
+		Find(&segments).Error
+	return segments, err
+}
+
+// deleteTranscripts deletes a source's transcript and its segments within a transaction
+func deleteTranscripts(tx *gorm.DB, sourceType common.TranscriptSource, sourceID int64) error {
+	ids := tx.Model(&asterisk.Transcript{}).
+		Select("id").
+		Where("source_type = ? AND source_id = ?", sourceType, sourceID)
+	if err := tx.Where("transcript_id IN (?)", ids).Delete(&asterisk.TranscriptSegment{}).Error; err != nil {
+		return err
+	}
+	return tx.Where("source_type = ? AND source_id = ?", sourceType, sourceID).Delete(&asterisk.Transcript{}).Error
+}
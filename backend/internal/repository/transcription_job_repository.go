@@ -0,0 +1,159 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/psschand/callcenter/internal/asterisk"
+	"github.com/psschand/callcenter/internal/common"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// TranscriptionJobRepository defines the interface for transcription job data access
+type TranscriptionJobRepository interface {
+	Enqueue(ctx context.Context, tenantID string, sourceType common.TranscriptSource, sourceID int64) (*asterisk.TranscriptionJob, error)
+	FindBySource(ctx context.Context, sourceType common.TranscriptSource, sourceID int64) (*asterisk.TranscriptionJob, error)
+	ClaimNext(ctx context.Context) (*asterisk.TranscriptionJob, error)
+	Complete(ctx context.Context, id int64) error
+	Retry(ctx context.Context, id int64, message string, at time.Time) error
+	Fail(ctx context.Context, id int64, message string) error
+	ResetProcessing(ctx context.Context) (int64, error)
+}
+
+// transcriptionJobRepository implements TranscriptionJobRepository
+type transcriptionJobRepository struct {
+	db *gorm.DB
+}
+
+// NewTranscriptionJobRepository creates a new transcription job repository
+func NewTranscriptionJobRepository(db *gorm.DB) TranscriptionJobRepository {
+	return &transcriptionJobRepository{db: db}
+}
+
+// Enqueue queues a source for transcription, resetting its job if it already has one
+func (r *transcriptionJobRepository) Enqueue(ctx context.Context, tenantID string, sourceType common.TranscriptSource, sourceID int64) (*asterisk.TranscriptionJob, error) {
+	now := time.Now()
+	job := &asterisk.TranscriptionJob{
+		TenantID:      tenantID,
+		SourceType:    sourceType,
+		SourceID:      sourceID,
+		Status:        common.TranscriptionStatusPending,
+		NextAttemptAt: now,
+	}
+
+	err := r.db.WithContext(ctx).
+		Omit("Tenant").
+		Clauses(clause.OnConflict{
+			Columns: []clause.Column{{Name: "source_type"}, {Name: "source_id"}},
+			DoUpdates: clause.Assignments(map[string]interface{}{
+				"status":          common.TranscriptionStatusPending,
+				"attempts":        0,
+				"error":           nil,
+				"next_attempt_at": now,
+				"started_at":      nil,
+				"completed_at":    nil,
+			}),
+		}).
+		Create(job).Error
+	if err != nil {
+		return nil, err
+	}
+	return r.FindBySource(ctx, sourceType, sourceID)
+}
+
+// FindBySource finds the job of a recording or voicemail
+func (r *transcriptionJobRepository) FindBySource(ctx context.Context, sourceType common.TranscriptSource, sourceID int64) (*asterisk.TranscriptionJob, error) {
+	var job asterisk.TranscriptionJob
+	err := r.db.WithContext(ctx).
+		Where("source_type = ? AND source_id = ?", sourceType, sourceID).
+		First(&job).Error
+	if err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+// ClaimNext marks the longest-waiting pending job that is due as processing
+// and returns it, or nil when no job is due. Jobs locked by another worker
+// are skipped.
+func (r *transcriptionJobRepository) ClaimNext(ctx context.Context) (*asterisk.TranscriptionJob, error) {
+	var jobs []asterisk.TranscriptionJob
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("status = ? AND next_attempt_at <= ?", common.TranscriptionStatusPending, now).
+			Order("next_attempt_at ASC, id ASC").
+			Limit(1).
+			Find(&jobs).Error
+		if err != nil || len(jobs) == 0 {
+			return err
+		}
+
+		job := &jobs[0]
+
+		job.Status = common.TranscriptionStatusProcessing
+		job.Attempts++
+		job.StartedAt = &now
+		return tx.Model(&asterisk.TranscriptionJob{}).
+			Where("id = ?", job.ID).
+			Updates(map[string]interface{}{
+				"status":     job.Status,
+				"attempts":   job.Attempts,
+				"started_at": now,
+			}).Error
+	})
+	if err != nil || len(jobs) == 0 {
+		return nil, err
+	}
+	return &jobs[0], nil
+}
+
+// Complete marks a processing job completed. A job requeued while it was
+// being processed stays pending.
+func (r *transcriptionJobRepository) Complete(ctx context.Context, id int64) error {
+	return r.db.WithContext(ctx).
+		Model(&asterisk.TranscriptionJob{}).
+		Where("id = ? AND status = ?", id, common.TranscriptionStatusProcessing).
+		Updates(map[string]interface{}{
+			"status":       common.TranscriptionStatusCompleted,
+			"error":        nil,
+			"completed_at": time.Now(),
+		}).Error
+}
+
+// Retry puts a processing job that failed back in the queue until the given time
+func (r *transcriptionJobRepository) Retry(ctx context.Context, id int64, message string, at time.Time) error {
+	return r.db.WithContext(ctx).
+		Model(&asterisk.TranscriptionJob{}).
+		Where("id = ? AND status = ?", id, common.TranscriptionStatusProcessing).
+		Updates(map[string]interface{}{
+			"status":          common.TranscriptionStatusPending,
+			"error":           message,
+			"next_attempt_at": at,
+		}).Error
+}
+
+// Fail marks a processing job failed for good
+func (r *transcriptionJobRepository) Fail(ctx context.Context, id int64, message string) error {
+	return r.db.WithContext(ctx).
+		Model(&asterisk.TranscriptionJob{}).
+		Where("id = ? AND status = ?", id, common.TranscriptionStatusProcessing).
+		Updates(map[string]interface{}{
+			"status":       common.TranscriptionStatusFailed,
+			"error":        message,
+			"completed_at": time.Now(),
+		}).Error
+}
+
+// ResetProcessing puts jobs left processing, by a worker that stopped, back in the queue
+func (r *transcriptionJobRepository) ResetProcessing(ctx context.Context) (int64, error) {
+	result := r.db.WithContext(ctx).
+		Model(&asterisk.TranscriptionJob{}).
+		Where("status = ?", common.TranscriptionStatusProcessing).
+		Updates(map[string]interface{}{
+			"status":          common.TranscriptionStatusPending,
+			"next_attempt_at": time.Now(),
+		})
+	return result.RowsAffected, result.Error
+}
@@ -18,6 +18,7 @@ type UserRoleRepository interface {
 	Delete(ctx context.Context, id int64) error
 	HasRole(ctx context.Context, userID int64, tenantID string, role common.UserRole) (bool, error)
 	FindByTenantAndRole(ctx context.Context, tenantID string, role common.UserRole) ([]core.UserRole, error)
+	FindByEndpoint(ctx context.Context, tenantID, endpointID string) (*core.UserRole, error)
 }
 
 // userRoleRepository implements UserRoleRepository
@@ -95,3 +96,15 @@ func (r *userRoleRepository) FindByTenantAndRole(ctx context.Context, tenantID s
 		Find(&roles).Error
 	return roles, err
 }
+
+// FindByEndpoint finds the user role assigned a SIP endpoint in a tenant
+func (r *userRoleRepository) FindByEndpoint(ctx context.Context, tenantID, endpointID string) (*core.UserRole, error) {
+	var role core.UserRole
+	err := r.db.WithContext(ctx).
+		Where("tenant_id = ? AND endpoint_id = ?", tenantID, endpointID).
+		First(&role).Error
+	if err != nil {
+		return nil, err
+	}
+	return &role, nil
+}
@@ -0,0 +1,100 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/psschand/callcenter/internal/asterisk"
+	"gorm.io/gorm"
+)
+
+// VoicemailMailboxRepository defines the interface for voicemail mailbox data access
+type VoicemailMailboxRepository interface {
+	Create(ctx context.Context, mailbox *asterisk.VoicemailMailbox) error
+	FindByID(ctx context.Context, id int64) (*asterisk.VoicemailMailbox, error)
+	FindByUser(ctx context.Context, tenantID string, userID int64) (*asterisk.VoicemailMailbox, error)
+	FindByDID(ctx context.Context, didID int64) (*asterisk.VoicemailMailbox, error)
+	FindByEndpoint(ctx context.Context, tenantID, endpoint string) (*asterisk.VoicemailMailbox, error)
+	FindByTenant(ctx context.Context, tenantID string) ([]asterisk.VoicemailMailbox, error)
+	Update(ctx context.Context, mailbox *asterisk.VoicemailMailbox) error
+	Delete(ctx context.Context, id int64) error
+}
+
+// voicemailMailboxRepository implements VoicemailMailboxRepository
+type voicemailMailboxRepository struct {
+	db *gorm.DB
+}
+
+// NewVoicemailMailboxRepository creates a new voicemail mailbox repository
+func NewVoicemailMailboxRepository(db *gorm.DB) VoicemailMailboxRepository {
+	return &voicemailMailboxRepository{db: db}
+}
+
+// Create creates a new mailbox
+func (r *voicemailMailboxRepository) Create(ctx context.Context, mailbox *asterisk.VoicemailMailbox) error {
+	return r.db.WithContext(ctx).Omit("Tenant", "User", "DID").Create(mailbox).Error
+}
+
+// FindByID finds a mailbox by ID
+func (r *voicemailMailboxRepository) FindByID(ctx context.Context, id int64) (*asterisk.VoicemailMailbox, error) {
+	var mailbox asterisk.VoicemailMailbox
+	err := r.db.WithContext(ctx).Where("id = ?", id).First(&mailbox).Error
+	if err != nil {
+		return nil, err
+	}
+	return &mailbox, nil
+}
+
+// FindByUser finds a user's mailbox in a tenant
+func (r *voicemailMailboxRepository) FindByUser(ctx context.Context, tenantID string, userID int64) (*asterisk.VoicemailMailbox, error) {
+	var mailbox asterisk.VoicemailMailbox
+	err := r.db.WithContext(ctx).
+		Where("tenant_id = ? AND user_id = ?", tenantID, userID).
+		First(&mailbox).Error
+	if err != nil {
+		return nil, err
+	}
+	return &mailbox, nil
+}
+
+// FindByDID finds a DID's mailbox
+func (r *voicemailMailboxRepository) FindByDID(ctx context.Context, didID int64) (*asterisk.VoicemailMailbox, error) {
+	var mailbox asterisk.VoicemailMailbox
+	err := r.db.WithContext(ctx).Where("did_id = ?", didID).First(&mailbox).Error
+	if err != nil {
+		return nil, err
+	}
+	return &mailbox, nil
+}
+
+// FindByEndpoint finds the mailbox of the user an endpoint is assigned to in a tenant
+func (r *voicemailMailboxRepository) FindByEndpoint(ctx context.Context, tenantID, endpoint string) (*asterisk.VoicemailMailbox, error) {
+	var mailbox asterisk.VoicemailMailbox
+	err := r.db.WithContext(ctx).
+		Joins("JOIN user_roles ON user_roles.user_id = voicemail_mailboxes.user_id AND user_roles.tenant_id = voicemail_mailboxes.tenant_id").
+		Where("voicemail_mailboxes.tenant_id = ? AND user_roles.endpoint_id = ?", tenantID, endpoint).
+		First(&mailbox).Error
+	if err != nil {
+		return nil, err
+	}
+	return &mailbox, nil
+}
+
+// FindByTenant finds a tenant's mailboxes
+func (r *voicemailMailboxRepository) FindByTenant(ctx context.Context, tenantID string) ([]asterisk.VoicemailMailbox, error) {
+	var mailboxes []asterisk.VoicemailMailbox
+	err := r.db.WithContext(ctx).
+		Where("tenant_id = ?", tenantID).
+		Order("name ASC").
+		Find(&mailboxes).Error
+	return mailboxes, err
+}
+
+// Update updates a mailbox
+func (r *voicemailMailboxRepository) Update(ctx context.Context, mailbox *asterisk.VoicemailMailbox) error {
+	return r.db.WithContext(ctx).Omit("Tenant", "User", "DID").Save(mailbox).Error
+}
+
+// Delete deletes a mailbox. Its voicemails are kept.
+func (r *voicemailMailboxRepository) Delete(ctx context.Context, id int64) error {
+	return r.db.WithContext(ctx).Delete(&asterisk.VoicemailMailbox{}, id).Error
+}
@@ -0,0 +1,135 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/psschand/callcenter/internal/asterisk"
+	"gorm.io/gorm"
+)
+
+// VoicemailRepository defines the interface for voicemail data access
+type VoicemailRepository interface {
+	Create(ctx context.Context, voicemail *asterisk.Voicemail) error
+	FindByID(ctx context.Context, id int64) (*asterisk.Voicemail, error)
+	FindByMailboxes(ctx context.Context, tenantID string, mailboxIDs []int64, unreadOnly bool, page, pageSize int) ([]asterisk.Voicemail, int64, error)
+	CountUnread(ctx context.Context, tenantID string, mailboxID int64) (int64, error)
+	FindUnarchived(ctx context.Context, afterID int64, limit int) ([]asterisk.Voicemail, error)
+	FindExpired(ctx context.Context, tenantID string, before time.Time, limit int) ([]asterisk.Voicemail, error)
+	Update(ctx context.Context, voicemail *asterisk.Voicemail) error
+	SetStorage(ctx context.Context, id int64, backend, key string) error
+	SetTranscription(ctx context.Context, id int64, text string) error
+}
+
+// voicemailRepository implements VoicemailRepository
+type voicemailRepository struct {
+	db *gorm.DB
+}
+
+// NewVoicemailRepository creates a new voicemail repository
+func NewVoicemailRepository(db *gorm.DB) VoicemailRepository {
+	return &voicemailRepository{db: db}
+}
+
+// Create creates a new voicemail
+func (r *voicemailRepository) Create(ctx context.Context, voicemail *asterisk.Voicemail) error {
+	return r.db.WithContext(ctx).Omit("Tenant", "Mailbox", "DID", "User").Create(voicemail).Error
+}
+
+// FindByID finds a voicemail by ID
+func (r *voicemailRepository) FindByID(ctx context.Context, id int64) (*asterisk.Voicemail, error) {
+	var voicemail asterisk.Voicemail
+	err := r.db.WithContext(ctx).Where("id = ?", id).First(&voicemail).Error
+	if err != nil {
+		return nil, err
+	}
+	return &voicemail, nil
+}
+
+// FindByMailboxes finds the voicemails of a tenant's mailboxes that have not
+// been deleted, latest first, with pagination. No mailboxIDs means all of them.
+func (r *voicemailRepository) FindByMailboxes(ctx context.Context, tenantID string, mailboxIDs []int64, unreadOnly bool, page, pageSize int) ([]asterisk.Voicemail, int64, error) {
+	var voicemails []asterisk.Voicemail
+	var total int64
+
+	query := r.db.WithContext(ctx).
+		Model(&asterisk.Voicemail{}).
+		Where("tenant_id = ? AND is_deleted = ?", tenantID, false)
+	if len(mailboxIDs) > 0 {
+		query = query.Where("mailbox_id IN ?", mailboxIDs)
+	}
+	if unreadOnly {
+		query = query.Where("is_read = ?", false)
+	}
+
+	// Count total
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	// Get paginated results
+	offset := (page - 1) * pageSize
+	err := query.
+		Offset(offset).
+		Limit(pageSize).
+		Order("created_at DESC, id DESC").
+		Find(&voicemails).Error
+
+	return voicemails, total, err
+}
+
+// CountUnread counts a mailbox's unread voicemails
+func (r *voicemailRepository) CountUnread(ctx context.Context, tenantID string, mailboxID int64) (int64, error) {
+	var count int64
+	err := r.db.WithContext(ctx).
+		Model(&asterisk.Voicemail{}).
+		Where("tenant_id = ? AND mailbox_id = ? AND is_read = ? AND is_deleted = ?", tenantID, mailboxID, false, false).
+		Count(&count).Error
+	return count, err
+}
+
+// FindUnarchived finds voicemails still in the Asterisk spool with an ID above afterID, oldest first
+func (r *voicemailRepository) FindUnarchived(ctx context.Context, afterID int64, limit int) ([]asterisk.Voicemail, error) {
+	var voicemails []asterisk.Voicemail
+	err := r.db.WithContext(ctx).
+		Where("id > ? AND is_deleted = ? AND storage_key IS NULL", afterID, false).
+		Order("id ASC").
+		Limit(limit).
+		Find(&voicemails).Error
+	return voicemails, err
+}
+
+// FindExpired finds a tenant's voicemails created before the given time that have not been deleted
+func (r *voicemailRepository) FindExpired(ctx context.Context, tenantID string, before time.Time, limit int) ([]asterisk.Voicemail, error) {
+	var voicemails []asterisk.Voicemail
+	err := r.db.WithContext(ctx).
+		Where("tenant_id = ? AND created_at < ? AND is_deleted = ?", tenantID, before, false).
+		Order("id ASC").
+		Limit(limit).
+		Find(&voicemails).Error
+	return voicemails, err
+}
+
+// Update updates a voicemail
+func (r *voicemailRepository) Update(ctx context.Context, voicemail *asterisk.Voicemail) error {
+	return r.db.WithContext(ctx).Omit("Tenant", "Mailbox", "DID", "User").Save(voicemail).Error
+}
+
+// SetStorage records where a voicemail has been archived to
+func (r *voicemailRepository) SetStorage(ctx context.Context, id int64, backend, key string) error {
+	return r.db.WithContext(ctx).
+		Model(&asterisk.Voicemail{}).
+		Where("id = ?", id).
+		Updates(map[string]interface{}{
+			"storage_backend": backend,
+			"storage_key":     key,
+		}).Error
+}
+
+// SetTranscription sets the text of a voicemail
+func (r *voicemailRepository) SetTranscription(ctx context.Context, id int64, text string) error {
+	return r.db.WithContext(ctx).
+		Model(&asterisk.Voicemail{}).
+		Where("id = ?", id).
+		Update("transcription", text).Error
+}
@@ -0,0 +1,305 @@
+package service
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/psschand/callcenter/internal/asterisk"
+	"github.com/psschand/callcenter/internal/common"
+	"github.com/psschand/callcenter/internal/dto"
+	"github.com/psschand/callcenter/internal/repository"
+	"github.com/psschand/callcenter/pkg/errors"
+)
+
+// AgentReportService reports on how agents spend their time from their state history
+type AgentReportService interface {
+	GetHistory(ctx context.Context, tenantID string, requesterID, userID int64, start, end time.Time, page, pageSize int) ([]dto.AgentStateHistoryResponse, int64, error)
+	GetTimeInState(ctx context.Context, tenantID string, requesterID int64, req *dto.AgentTimeReportRequest) (*dto.AgentTimeReportResponse, error)
+}
+
+type agentReportService struct {
+	historyRepo     repository.AgentStateHistoryRepository
+	breakReasonRepo repository.AgentBreakReasonRepository
+	userRepo        repository.UserRepository
+	roleRepo        repository.UserRoleRepository
+	chatAgentRepo   repository.ChatAgentRepository
+}
+
+// NewAgentReportService creates a new agent report service
+func NewAgentReportService(
+	historyRepo repository.AgentStateHistoryRepository,
+	breakReasonRepo repository.AgentBreakReasonRepository,
+	userRepo repository.UserRepository,
+	roleRepo repository.UserRoleRepository,
+	chatAgentRepo repository.ChatAgentRepository,
+) AgentReportService {
+	return &agentReportService{
+		historyRepo:     historyRepo,
+		breakReasonRepo: breakReasonRepo,
+		userRepo:        userRepo,
+		roleRepo:        roleRepo,
+		chatAgentRepo:   chatAgentRepo,
+	}
+}
+
+// GetHistory lists an agent's state changes within a time range. Agents can
+// see their own; anyone else's needs permission to view reports.
+func (s *agentReportService) GetHistory(ctx context.Context, tenantID string, requesterID, userID int64, start, end time.Time, page, pageSize int) ([]dto.AgentStateHistoryResponse, int64, error) {
+	if userID != requesterID {
+		if err := s.checkViewReports(ctx, tenantID, requesterID); err != nil {
+			return nil, 0, err
+		}
+	}
+	if end.Before(start) {
+		return nil, 0, errors.NewValidation(map[string]string{"end_date": "must not be before start_date"})
+	}
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	entries, total, err := s.historyRepo.FindByUser(ctx, tenantID, userID, start, end, page, pageSize)
+	if err != nil {
+		return nil, 0, errors.Wrap(err, "failed to get agent state history")
+	}
+
+	responses := make([]dto.AgentStateHistoryResponse, len(entries))
+	for i := range entries {
+		responses[i] = toAgentStateHistoryResponse(&entries[i])
+	}
+	return responses, total, nil
+}
+
+// GetTimeInState reports the time agents spent in each state within a date
+// range, with their login time, occupancy and break adherence. Periods
+// running over the range's edges count only for the part inside it; breaks
+// count toward adherence in the range they started in.
+func (s *agentReportService) GetTimeInState(ctx context.Context, tenantID string, requesterID int64, req *dto.AgentTimeReportRequest) (*dto.AgentTimeReportResponse, error) {
+	if req.UserID != requesterID || req.Team != "" {
+		if err := s.checkViewReports(ctx, tenantID, requesterID); err != nil {
+			return nil, err
+		}
+	}
+	if req.EndDate.Before(req.StartDate) {
+		return nil, errors.NewValidation(map[string]string{"end_date": "must not be before start_date"})
+	}
+
+	result := &dto.AgentTimeReportResponse{
+		StartDate: req.StartDate,
+		EndDate:   req.EndDate,
+		Team:      req.Team,
+		Agents:    []dto.AgentTimeReport{},
+		Totals:    dto.AgentTimeReport{BreakReasons: []dto.AgentBreakReport{}},
+	}
+
+	userIDs, err := s.reportUsers(ctx, tenantID, req)
+	if err != nil {
+		return nil, err
+	}
+	if userIDs != nil && len(userIDs) == 0 {
+		finishAgentTimeReport(&result.Totals)
+		return result, nil
+	}
+
+	totals, err := s.historyRepo.SumByState(ctx, tenantID, userIDs, req.StartDate, req.EndDate)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to total agent state history")
+	}
+	breaks, err := s.historyRepo.FindByState(ctx, tenantID, userIDs, common.AgentStatusBreak, req.StartDate, req.EndDate)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get agent breaks")
+	}
+	reasons, err := s.breakReasonRepo.FindByTenant(ctx, tenantID, false)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get break reasons")
+	}
+	reasonsByCode := make(map[string]*asterisk.AgentBreakReason, len(reasons))
+	for i := range reasons {
+		reasonsByCode[reasons[i].Code] = &reasons[i]
+	}
+
+	agents := make(map[int64]*agentTimeTally)
+	tally := func(userID int64) *agentTimeTally {
+		if agents[userID] == nil {
+			agents[userID] = newAgentTimeTally(userID)
+		}
+		return agents[userID]
+	}
+	all := newAgentTimeTally(0)
+
+	for _, total := range totals {
+		tally(total.UserID).addTime(total.State, total.ReasonCode, total.Seconds, reasonsByCode)
+		all.addTime(total.State, total.ReasonCode, total.Seconds, reasonsByCode)
+	}
+	for i := range breaks {
+		seconds := historyDuration(&breaks[i])
+		code := ""
+		if breaks[i].ReasonCode != nil {
+			code = *breaks[i].ReasonCode
+		}
+		tally(breaks[i].UserID).addBreak(code, seconds, reasonsByCode)
+		all.addBreak(code, seconds, reasonsByCode)
+	}
+
+	for userID, agent := range agents {
+		if user, err := s.userRepo.FindByID(ctx, userID); err == nil {
+			agent.report.UserName = user.GetFullName()
+		}
+		result.Agents = append(result.Agents, agent.finish())
+	}
+	sort.Slice(result.Agents, func(i, j int) bool {
+		return result.Agents[i].UserName < result.Agents[j].UserName
+	})
+	result.Totals = all.finish()
+
+	return result, nil
+}
+
+// reportUsers resolves the agents a report covers: nil for all of the tenant's agents
+func (s *agentReportService) reportUsers(ctx context.Context, tenantID string, req *dto.AgentTimeReportRequest) ([]int64, error) {
+	if req.Team == "" {
+		if req.UserID != 0 {
+			return []int64{req.UserID}, nil
+		}
+		return nil, nil
+	}
+
+	members, err := s.chatAgentRepo.FindByTeam(ctx, tenantID, req.Team)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get team members")
+	}
+	userIDs := []int64{}
+	for _, member := range members {
+		if req.UserID == 0 || member.UserID == req.UserID {
+			userIDs = append(userIDs, member.UserID)
+		}
+	}
+	return userIDs, nil
+}
+
+// checkViewReports checks that a user may view the tenant's reports
+func (s *agentReportService) checkViewReports(ctx context.Context, tenantID string, userID int64) error {
+	role, err := s.roleRepo.FindByUserAndTenant(ctx, userID, tenantID)
+	if err != nil {
+		return errors.NewForbidden("no role in this tenant")
+	}
+	if !role.Permissions.CanViewReports {
+		return errors.NewForbidden("not allowed to view reports")
+	}
+	return nil
+}
+
+// agentTimeTally accumulates an agent's time-in-state report
+type agentTimeTally struct {
+	report  dto.AgentTimeReport
+	reasons map[string]*dto.AgentBreakReport
+}
+
+func newAgentTimeTally(userID int64) *agentTimeTally {
+	return &agentTimeTally{
+		report:  dto.AgentTimeReport{UserID: userID},
+		reasons: make(map[string]*dto.AgentBreakReport),
+	}
+}
+
+// addTime adds time spent in a state
+func (t *agentTimeTally) addTime(state common.AgentStatus, reasonCode string, seconds int64, reasons map[string]*asterisk.AgentBreakReason) {
+	switch state {
+	case common.AgentStatusAvailable:
+		t.report.AvailableTime += seconds
+	case common.AgentStatusBusy:
+		t.report.BusyTime += seconds
+	case common.AgentStatusWrapup:
+		t.report.WrapupTime += seconds
+	case common.AgentStatusAway:
+		t.report.AwayTime += seconds
+	case common.AgentStatusBreak:
+		t.report.BreakTime += seconds
+		t.reason(reasonCode, reasons).TotalTime += seconds
+	case common.AgentStatusDND:
+		t.report.DNDTime += seconds
+	}
+}
+
+// addBreak counts a break, checking it against its reason's maximum
+func (t *agentTimeTally) addBreak(reasonCode string, seconds int, reasons map[string]*asterisk.AgentBreakReason) {
+	report := t.reason(reasonCode, reasons)
+	report.Breaks++
+	t.report.Breaks++
+	if reason, ok := reasons[reasonCode]; ok && reason.Exceeds(seconds) {
+		report.OverMax++
+		t.report.BreaksOverMax++
+	}
+}
+
+// reason returns the running totals of a break reason
+func (t *agentTimeTally) reason(code string, reasons map[string]*asterisk.AgentBreakReason) *dto.AgentBreakReport {
+	report, ok := t.reasons[code]
+	if !ok {
+		report = &dto.AgentBreakReport{ReasonCode: code}
+		if reason, ok := reasons[code]; ok {
+			report.Name = reason.Name
+			report.MaxDuration = reason.MaxDuration
+		}
+		t.reasons[code] = report
+	}
+	return report
+}
+
+// finish works out the report's derived figures
+func (t *agentTimeTally) finish() dto.AgentTimeReport {
+	t.report.BreakReasons = make([]dto.AgentBreakReport, 0, len(t.reasons))
+	for _, report := range t.reasons {
+		t.report.BreakReasons = append(t.report.BreakReasons, *report)
+	}
+	sort.Slice(t.report.BreakReasons, func(i, j int) bool {
+		return t.report.BreakReasons[i].ReasonCode < t.report.BreakReasons[j].ReasonCode
+	})
+	finishAgentTimeReport(&t.report)
+	return t.report
+}
+
+// finishAgentTimeReport works out login time, occupancy and break adherence from the time in each state
+func finishAgentTimeReport(report *dto.AgentTimeReport) {
+	report.LoginTime = report.AvailableTime + report.BusyTime + report.WrapupTime +
+		report.AwayTime + report.BreakTime + report.DNDTime
+
+	handling := report.BusyTime + report.WrapupTime
+	if staffed := report.AvailableTime + handling; staffed > 0 {
+		report.Occupancy = float64(handling) / float64(staffed) * 100
+	}
+
+	report.BreakAdherence = 100
+	if report.Breaks > 0 {
+		report.BreakAdherence = float64(report.Breaks-report.BreaksOverMax) / float64(report.Breaks) * 100
+	}
+}
+
+// historyDuration is how long a period lasted, or has lasted so far if it is still open
+func historyDuration(entry *asterisk.AgentStateHistory) int {
+	if entry.EndedAt == nil {
+		return int(time.Since(entry.StartedAt).Seconds())
+	}
+	if entry.Duration != nil {
+		return *entry.Duration
+	}
+	return int(entry.EndedAt.Sub(entry.StartedAt).Seconds())
+}
+
+// toAgentStateHistoryResponse converts an AgentStateHistory model to response DTO
+func toAgentStateHistoryResponse(entry *asterisk.AgentStateHistory) dto.AgentStateHistoryResponse {
+	return dto.AgentStateHistoryResponse{
+		ID:         entry.ID,
+		UserID:     entry.UserID,
+		State:      entry.State,
+		ReasonCode: entry.ReasonCode,
+		Reason:     entry.Reason,
+		CallID:     entry.CallID,
+		StartedAt:  entry.StartedAt,
+		EndedAt:    entry.EndedAt,
+		Duration:   historyDuration(entry),
+	}
+}
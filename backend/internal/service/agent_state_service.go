@@ -2,40 +2,97 @@ package service
 
 import (
 	"context"
+	"log"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/psschand/callcenter/internal/asterisk"
 	"github.com/psschand/callcenter/internal/common"
 	"github.com/psschand/callcenter/internal/dto"
 	"github.com/psschand/callcenter/internal/repository"
+	ws "github.com/psschand/callcenter/internal/websocket"
 	"github.com/psschand/callcenter/pkg/errors"
 )
 
+// Reasons recorded with call-driven state changes
+const (
+	agentReasonOnCall         = "On call"
+	agentReasonCallEnded      = "Call ended"
+	agentReasonWrapupEnded    = "Wrap-up ended"
+	agentReasonWrapupFinished = "Wrap-up finished"
+)
+
+// Longest an agent can extend wrap-up by at a time
+const maxWrapupExtension = time.Hour
+
 // AgentStateService handles agent state operations
 type AgentStateService interface {
 	GetState(ctx context.Context, tenantID string, userID int64) (*dto.AgentStateResponse, error)
-	UpdateState(ctx context.Context, tenantID string, userID int64, state common.AgentState, reason string) error
+	UpdateState(ctx context.Context, tenantID string, userID int64, state common.AgentState, reasonCode, reason string) error
 	GetByTenant(ctx context.Context, tenantID string) ([]dto.AgentStateResponse, error)
 	GetAvailableAgents(ctx context.Context, tenantID string) ([]dto.AgentStateResponse, error)
 	GetAgentsByState(ctx context.Context, tenantID string, state common.AgentState) ([]dto.AgentStateResponse, error)
-	StartBreak(ctx context.Context, tenantID string, userID int64, reason string) error
+	StartBreak(ctx context.Context, tenantID string, userID int64, reasonCode, reason string) error
 	EndBreak(ctx context.Context, tenantID string, userID int64) error
 	SetAway(ctx context.Context, tenantID string, userID int64, reason string) error
 	SetAvailable(ctx context.Context, tenantID string, userID int64) error
+	StartCall(ctx context.Context, tenantID string, userID int64, callID string) error
+	EndCall(ctx context.Context, tenantID string, userID int64, callID, queueName string) error
+	EndChat(ctx context.Context, tenantID string, userID, sessionID int64) error
+	ExtendWrapup(ctx context.Context, tenantID string, userID int64, seconds int) (*dto.AgentStateResponse, error)
+	FinishWrapup(ctx context.Context, tenantID string, userID int64) error
+	ResumeWrapups(ctx context.Context) error
+	ListBreakReasons(ctx context.Context, tenantID string, activeOnly bool) ([]dto.AgentBreakReasonResponse, error)
+	CreateBreakReason(ctx context.Context, tenantID string, userID int64, req *dto.CreateAgentBreakReasonRequest) (*dto.AgentBreakReasonResponse, error)
+	UpdateBreakReason(ctx context.Context, tenantID string, userID, id int64, req *dto.UpdateAgentBreakReasonRequest) (*dto.AgentBreakReasonResponse, error)
+	DeleteBreakReason(ctx context.Context, tenantID string, userID, id int64) error
 }
 
 type agentStateService struct {
-	agentStateRepo repository.AgentStateRepository
-	userRepo       repository.UserRepository
+	agentStateRepo  repository.AgentStateRepository
+	historyRepo     repository.AgentStateHistoryRepository
+	breakReasonRepo repository.AgentBreakReasonRepository
+	userRepo        repository.UserRepository
+	roleRepo        repository.UserRoleRepository
+	queueRepo       repository.QueueRepository
+	queueMemberRepo repository.QueueMemberRepository
+	tenantRepo      repository.TenantRepository
+	cdrRepo         repository.CDRRepository
+	chatSessionRepo repository.ChatSessionRepository
+	broadcaster     *ws.EventBroadcaster
+
+	wrapupMu     sync.Mutex
+	wrapupTimers map[int64]*time.Timer // agent state ID -> end of wrap-up
 }
 
 // NewAgentStateService creates a new agent state service
 func NewAgentStateService(
 	agentStateRepo repository.AgentStateRepository,
+	historyRepo repository.AgentStateHistoryRepository,
+	breakReasonRepo repository.AgentBreakReasonRepository,
 	userRepo repository.UserRepository,
+	roleRepo repository.UserRoleRepository,
+	queueRepo repository.QueueRepository,
+	queueMemberRepo repository.QueueMemberRepository,
+	tenantRepo repository.TenantRepository,
+	cdrRepo repository.CDRRepository,
+	chatSessionRepo repository.ChatSessionRepository,
+	broadcaster *ws.EventBroadcaster,
 ) AgentStateService {
 	return &agentStateService{
-		agentStateRepo: agentStateRepo,
-		userRepo:       userRepo,
+		agentStateRepo:  agentStateRepo,
+		historyRepo:     historyRepo,
+		breakReasonRepo: breakReasonRepo,
+		userRepo:        userRepo,
+		roleRepo:        roleRepo,
+		queueRepo:       queueRepo,
+		queueMemberRepo: queueMemberRepo,
+		tenantRepo:      tenantRepo,
+		cdrRepo:         cdrRepo,
+		chatSessionRepo: chatSessionRepo,
+		broadcaster:     broadcaster,
+		wrapupTimers:    make(map[int64]*time.Timer),
 	}
 }
 
@@ -62,33 +119,332 @@ func (s *agentStateService) GetState(ctx context.Context, tenantID string, userI
 	return s.toAgentStateResponse(state), nil
 }
 
-// UpdateState updates agent state
-func (s *agentStateService) UpdateState(ctx context.Context, tenantID string, userID int64, state common.AgentState, reason string) error {
+// UpdateState updates agent state. Breaks take a reason code, which tenants
+// that configure break reasons require.
+func (s *agentStateService) UpdateState(ctx context.Context, tenantID string, userID int64, state common.AgentState, reasonCode, reason string) error {
+	if state == common.AgentStateBreak {
+		breakReason, err := s.breakReason(ctx, tenantID, reasonCode)
+		if err != nil {
+			return err
+		}
+		if breakReason != nil && reason == "" {
+			reason = breakReason.Name
+		}
+	} else if reasonCode != "" {
+		return errors.NewValidation(map[string]string{
+			"reason_code": "reason codes are only given for breaks",
+		})
+	}
+
+	reasonCodePtr := &reasonCode
+	if reasonCode == "" {
+		reasonCodePtr = nil
+	}
+	reasonPtr := &reason
+	if reason == "" {
+		reasonPtr = nil
+	}
+
 	// Get existing state or create new one
 	existingState, err := s.agentStateRepo.FindByUser(ctx, tenantID, userID)
 	if err != nil {
 		// Create new state
-		reasonPtr := &reason
-		if reason == "" {
-			reasonPtr = nil
+		newState := &asterisk.AgentState{
+			TenantID:   tenantID,
+			UserID:     userID,
+			State:      state,
+			ReasonCode: reasonCodePtr,
+			Reason:     reasonPtr,
 		}
 
-		newState := &asterisk.AgentState{
-			TenantID: tenantID,
-			UserID:   userID,
-			State:    state,
-			Reason:   reasonPtr,
+		if err := s.agentStateRepo.Create(ctx, newState); err != nil {
+			return err
 		}
+		s.stateChanged(ctx, tenantID, userID, state, reasonCode, reason, nil)
+		return nil
+	}
 
-		return s.agentStateRepo.Create(ctx, newState)
+	// Leaving wrap-up by hand still records the time spent in it
+	if existingState.IsInWrapup() {
+		return s.finishWrapup(ctx, existingState, state, reasonCode, reason)
 	}
 
 	// Update existing state
-	reasonPtr := &reason
-	if reason == "" {
-		reasonPtr = nil
+	if err := s.agentStateRepo.UpdateState(ctx, existingState.ID, state, reasonCodePtr, reasonPtr); err != nil {
+		return err
+	}
+	s.stateChanged(ctx, tenantID, userID, state, reasonCode, reason, nil)
+	return nil
+}
+
+// StartCall marks an agent busy on a call. Agents without a state are not
+// tracked and are left alone.
+func (s *agentStateService) StartCall(ctx context.Context, tenantID string, userID int64, callID string) error {
+	state, err := s.agentStateRepo.FindByUser(ctx, tenantID, userID)
+	if err != nil {
+		return nil
+	}
+	if state.IsOnCall() && *state.CurrentCallID == callID {
+		return nil
+	}
+	if state.IsInWrapup() {
+		// A new call cuts the previous one's wrap-up short
+		if err := s.finishWrapup(ctx, state, common.AgentStateBusy, "", agentReasonOnCall); err != nil {
+			return err
+		}
+	}
+
+	if err := s.agentStateRepo.UpdateCallState(ctx, state.ID, common.AgentStateBusy, agentReasonOnCall, &callID); err != nil {
+		return errors.Wrap(err, "failed to mark agent busy")
+	}
+	s.stateChanged(ctx, tenantID, userID, common.AgentStateBusy, "", agentReasonOnCall, &callID)
+	return nil
+}
+
+// EndCall puts an agent in wrap-up once the call it is busy on ends, for as
+// long as the queue the call came from configures, and makes it available
+// afterwards. An agent who has since moved on to another call is left alone.
+func (s *agentStateService) EndCall(ctx context.Context, tenantID string, userID int64, callID, queueName string) error {
+	state, err := s.agentStateRepo.FindByUser(ctx, tenantID, userID)
+	if err != nil || !state.IsOnCall() || *state.CurrentCallID != callID {
+		return nil
+	}
+
+	wrapup := s.wrapupTime(ctx, tenantID, state.EndpointID, queueName)
+	return s.startWrapup(ctx, state, &callID, nil, wrapup, agentReasonCallEnded)
+}
+
+// EndChat puts an available agent in wrap-up after a chat. Agents on a call,
+// on a break or already in wrap-up are left alone.
+func (s *agentStateService) EndChat(ctx context.Context, tenantID string, userID, sessionID int64) error {
+	state, err := s.agentStateRepo.FindByUser(ctx, tenantID, userID)
+	if err != nil || !state.IsAvailable() {
+		return nil
+	}
+
+	wrapup := s.wrapupTime(ctx, tenantID, state.EndpointID, "")
+	if wrapup <= 0 {
+		return nil
+	}
+	return s.startWrapup(ctx, state, nil, &sessionID, wrapup, "")
+}
+
+// ExtendWrapup gives an agent in wrap-up more time
+func (s *agentStateService) ExtendWrapup(ctx context.Context, tenantID string, userID int64, seconds int) (*dto.AgentStateResponse, error) {
+	extension := time.Duration(seconds) * time.Second
+	if extension <= 0 || extension > maxWrapupExtension {
+		return nil, errors.NewValidation(map[string]string{
+			"seconds": "must be between 1 and 3600",
+		})
+	}
+
+	state, err := s.agentStateRepo.FindByUser(ctx, tenantID, userID)
+	if err != nil {
+		return nil, errors.NewNotFound("agent state not found")
+	}
+	if !state.IsInWrapup() {
+		return nil, errors.NewConflict("agent is not in wrap-up")
+	}
+
+	endsAt := *state.WrapupEndsAt
+	if now := time.Now(); endsAt.Before(now) {
+		endsAt = now
+	}
+	endsAt = endsAt.Add(extension)
+
+	if err := s.agentStateRepo.SetWrapupEnd(ctx, state.ID, endsAt); err != nil {
+		return nil, errors.Wrap(err, "failed to extend wrap-up")
+	}
+	s.scheduleWrapup(state.TenantID, state.UserID, state.ID, endsAt)
+
+	state.WrapupEndsAt = &endsAt
+	return s.toAgentStateResponse(state), nil
+}
+
+// FinishWrapup ends an agent's wrap-up early and makes it available
+func (s *agentStateService) FinishWrapup(ctx context.Context, tenantID string, userID int64) error {
+	state, err := s.agentStateRepo.FindByUser(ctx, tenantID, userID)
+	if err != nil {
+		return errors.NewNotFound("agent state not found")
+	}
+	if !state.IsInWrapup() {
+		return errors.NewConflict("agent is not in wrap-up")
+	}
+	return s.finishWrapup(ctx, state, common.AgentStateAvailable, "", agentReasonWrapupFinished)
+}
+
+// ResumeWrapups restarts the countdown of agents left in wrap-up by a
+// restart; those whose wrap-up has run out are made available
+func (s *agentStateService) ResumeWrapups(ctx context.Context) error {
+	states, err := s.agentStateRepo.FindInWrapup(ctx)
+	if err != nil {
+		return err
+	}
+	for _, state := range states {
+		endsAt := time.Now()
+		if state.WrapupEndsAt != nil {
+			endsAt = *state.WrapupEndsAt
+		}
+		s.scheduleWrapup(state.TenantID, state.UserID, state.ID, endsAt)
+	}
+	return nil
+}
+
+// startWrapup puts an agent in wrap-up after a call or chat, or makes it
+// available straight away when there is no wrap-up
+func (s *agentStateService) startWrapup(ctx context.Context, state *asterisk.AgentState, callID *string, chatID *int64, wrapup time.Duration, reason string) error {
+	if wrapup <= 0 {
+		if err := s.agentStateRepo.UpdateCallState(ctx, state.ID, common.AgentStateAvailable, reason, nil); err != nil {
+			return errors.Wrap(err, "failed to mark agent available")
+		}
+		s.stateChanged(ctx, state.TenantID, state.UserID, common.AgentStateAvailable, "", reason, nil)
+		return nil
+	}
+
+	endsAt := time.Now().Add(wrapup)
+	if err := s.agentStateRepo.StartWrapup(ctx, state.ID, callID, chatID, endsAt); err != nil {
+		return errors.Wrap(err, "failed to start wrap-up")
+	}
+	s.scheduleWrapup(state.TenantID, state.UserID, state.ID, endsAt)
+	s.stateChanged(ctx, state.TenantID, state.UserID, common.AgentStateWrapup, "", "Wrap-up", callID)
+	return nil
+}
+
+// finishWrapup moves an agent out of wrap-up and records the time it spent
+// in it against the call or chat it was wrapping up
+func (s *agentStateService) finishWrapup(ctx context.Context, state *asterisk.AgentState, next common.AgentState, reasonCode, reason string) error {
+	s.cancelWrapup(state.ID)
+
+	reasonCodePtr := &reasonCode
+	if reasonCode == "" {
+		reasonCodePtr = nil
+	}
+	ended, err := s.agentStateRepo.EndWrapup(ctx, state.ID, next, reasonCodePtr, reason)
+	if err != nil {
+		return errors.Wrap(err, "failed to end wrap-up")
+	}
+	if !ended {
+		return nil
+	}
+
+	if state.WrapupStartedAt != nil {
+		seconds := int(time.Since(*state.WrapupStartedAt).Seconds())
+		switch {
+		case state.WrapupChatID != nil:
+			err = s.chatSessionRepo.SetWrapupTime(ctx, *state.WrapupChatID, seconds)
+		case state.CurrentCallID != nil:
+			err = s.cdrRepo.SetWrapupTime(ctx, state.TenantID, *state.CurrentCallID, state.UserID, seconds)
+		}
+		if err != nil {
+			log.Printf("Error recording wrap-up time of agent %d: %v", state.UserID, err)
+		}
+	}
+
+	s.stateChanged(ctx, state.TenantID, state.UserID, next, reasonCode, reason, nil)
+	return nil
+}
+
+// scheduleWrapup (re)starts the countdown to the end of an agent's wrap-up
+func (s *agentStateService) scheduleWrapup(tenantID string, userID, stateID int64, endsAt time.Time) {
+	s.wrapupMu.Lock()
+	defer s.wrapupMu.Unlock()
+
+	if timer, ok := s.wrapupTimers[stateID]; ok {
+		timer.Stop()
+	}
+	s.wrapupTimers[stateID] = time.AfterFunc(time.Until(endsAt), func() {
+		s.onWrapupTimer(tenantID, userID, stateID)
+	})
+}
+
+// cancelWrapup stops an agent's wrap-up countdown
+func (s *agentStateService) cancelWrapup(stateID int64) {
+	s.wrapupMu.Lock()
+	defer s.wrapupMu.Unlock()
+
+	if timer, ok := s.wrapupTimers[stateID]; ok {
+		timer.Stop()
+		delete(s.wrapupTimers, stateID)
+	}
+}
+
+// onWrapupTimer makes an agent available once its wrap-up has run out
+func (s *agentStateService) onWrapupTimer(tenantID string, userID, stateID int64) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	state, err := s.agentStateRepo.FindByUser(ctx, tenantID, userID)
+	if err != nil || state.ID != stateID || !state.IsInWrapup() {
+		s.cancelWrapup(stateID)
+		return
+	}
+	if time.Now().Before(*state.WrapupEndsAt) {
+		// Extended since the timer was set
+		s.scheduleWrapup(tenantID, userID, stateID, *state.WrapupEndsAt)
+		return
+	}
+
+	if err := s.finishWrapup(ctx, state, common.AgentStateAvailable, "", agentReasonWrapupEnded); err != nil {
+		log.Printf("Error ending wrap-up of agent %d: %v", userID, err)
+	}
+}
+
+// wrapupTime is how long an agent wraps up after a call from a queue: the
+// agent's queue membership setting, else the queue's. Calls outside queues and
+// chats use the tenant's setting.
+func (s *agentStateService) wrapupTime(ctx context.Context, tenantID, endpointID, queueName string) time.Duration {
+	if queueName != "" {
+		endpoint := strings.TrimPrefix(endpointID, "PJSIP/")
+		if members, err := s.queueMemberRepo.FindByQueueName(ctx, tenantID, queueName); err == nil {
+			for _, member := range members {
+				if strings.TrimPrefix(member.Interface, "PJSIP/") == endpoint && member.WrapupTime > 0 {
+					return time.Duration(member.WrapupTime) * time.Second
+				}
+			}
+		}
+		if queue, err := s.queueRepo.FindByName(ctx, tenantID, queueName); err == nil && queue.WrapupTime > 0 {
+			return time.Duration(queue.WrapupTime) * time.Second
+		}
+	}
+
+	tenant, err := s.tenantRepo.FindByID(ctx, tenantID)
+	if err != nil {
+		return 0
+	}
+	return time.Duration(tenant.Settings.WrapupTime) * time.Second
+}
+
+// stateChanged records an agent's new state in its history and tells the
+// tenant's dashboards about it
+func (s *agentStateService) stateChanged(ctx context.Context, tenantID string, userID int64, state common.AgentState, reasonCode, reason string, callID *string) {
+	entry := &asterisk.AgentStateHistory{
+		TenantID:  tenantID,
+		UserID:    userID,
+		State:     state,
+		CallID:    callID,
+		StartedAt: time.Now(),
+	}
+	if reasonCode != "" {
+		entry.ReasonCode = &reasonCode
+	}
+	if reason != "" {
+		entry.Reason = &reason
+	}
+	if err := s.historyRepo.Record(ctx, entry); err != nil {
+		log.Printf("Error recording state history of agent %d: %v", userID, err)
+	}
+
+	if s.broadcaster == nil {
+		return
+	}
+
+	username := ""
+	if user, err := s.userRepo.FindByID(ctx, userID); err == nil {
+		username = user.GetFullName()
+	}
+	if err := s.broadcaster.AgentStateChange(tenantID, userID, username, string(state), reason); err != nil {
+		log.Printf("Error broadcasting state of agent %d: %v", userID, err)
 	}
-	return s.agentStateRepo.UpdateState(ctx, existingState.ID, state, reasonPtr)
 }
 
 // GetByTenant gets all agent states for a tenant
@@ -137,16 +493,16 @@ func (s *agentStateService) GetAgentsByState(ctx context.Context, tenantID strin
 }
 
 // StartBreak starts an agent break
-func (s *agentStateService) StartBreak(ctx context.Context, tenantID string, userID int64, reason string) error {
-	if reason == "" {
+func (s *agentStateService) StartBreak(ctx context.Context, tenantID string, userID int64, reasonCode, reason string) error {
+	if reason == "" && reasonCode == "" {
 		reason = "Break"
 	}
-	return s.UpdateState(ctx, tenantID, userID, common.AgentStateBreak, reason)
+	return s.UpdateState(ctx, tenantID, userID, common.AgentStateBreak, reasonCode, reason)
 }
 
 // EndBreak ends an agent break and sets to available
 func (s *agentStateService) EndBreak(ctx context.Context, tenantID string, userID int64) error {
-	return s.UpdateState(ctx, tenantID, userID, common.AgentStateAvailable, "Break ended")
+	return s.UpdateState(ctx, tenantID, userID, common.AgentStateAvailable, "", "Break ended")
 }
 
 // SetAway sets agent as away
@@ -154,23 +510,175 @@ func (s *agentStateService) SetAway(ctx context.Context, tenantID string, userID
 	if reason == "" {
 		reason = "Away"
 	}
-	return s.UpdateState(ctx, tenantID, userID, common.AgentStateAway, reason)
+	return s.UpdateState(ctx, tenantID, userID, common.AgentStateAway, "", reason)
 }
 
 // SetAvailable sets agent as available
 func (s *agentStateService) SetAvailable(ctx context.Context, tenantID string, userID int64) error {
-	return s.UpdateState(ctx, tenantID, userID, common.AgentStateAvailable, "Available")
+	return s.UpdateState(ctx, tenantID, userID, common.AgentStateAvailable, "", "Available")
+}
+
+// breakReason resolves the reason code an agent goes on break with. Tenants
+// that configure break reasons require one of their active ones; others take
+// breaks without a code.
+func (s *agentStateService) breakReason(ctx context.Context, tenantID, code string) (*asterisk.AgentBreakReason, error) {
+	reasons, err := s.breakReasonRepo.FindByTenant(ctx, tenantID, true)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get break reasons")
+	}
+	if len(reasons) == 0 && code == "" {
+		return nil, nil
+	}
+
+	for i := range reasons {
+		if reasons[i].Code == code {
+			return &reasons[i], nil
+		}
+	}
+	if code == "" {
+		return nil, errors.NewValidation(map[string]string{"reason_code": "a break reason is required"})
+	}
+	return nil, errors.NewValidation(map[string]string{"reason_code": "unknown break reason"})
+}
+
+// ListBreakReasons lists a tenant's break reasons
+func (s *agentStateService) ListBreakReasons(ctx context.Context, tenantID string, activeOnly bool) ([]dto.AgentBreakReasonResponse, error) {
+	reasons, err := s.breakReasonRepo.FindByTenant(ctx, tenantID, activeOnly)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get break reasons")
+	}
+
+	responses := make([]dto.AgentBreakReasonResponse, len(reasons))
+	for i := range reasons {
+		responses[i] = *toAgentBreakReasonResponse(&reasons[i])
+	}
+	return responses, nil
+}
+
+// CreateBreakReason adds a break reason code to a tenant
+func (s *agentStateService) CreateBreakReason(ctx context.Context, tenantID string, userID int64, req *dto.CreateAgentBreakReasonRequest) (*dto.AgentBreakReasonResponse, error) {
+	if err := s.checkManageAgents(ctx, tenantID, userID); err != nil {
+		return nil, err
+	}
+
+	code := strings.TrimSpace(req.Code)
+	if code == "" {
+		return nil, errors.NewValidation(map[string]string{"code": "is required"})
+	}
+	if _, err := s.breakReasonRepo.FindByCode(ctx, tenantID, code); err == nil {
+		return nil, errors.NewConflict("break reason code already exists")
+	}
+
+	reason := &asterisk.AgentBreakReason{
+		TenantID:    tenantID,
+		Code:        code,
+		Name:        req.Name,
+		MaxDuration: req.MaxDuration,
+		IsActive:    true,
+	}
+	if req.IsActive != nil {
+		reason.IsActive = *req.IsActive
+	}
+
+	if err := s.breakReasonRepo.Create(ctx, reason); err != nil {
+		return nil, errors.Wrap(err, "failed to create break reason")
+	}
+	return toAgentBreakReasonResponse(reason), nil
+}
+
+// UpdateBreakReason updates a break reason. Its code is kept so that past
+// breaks stay attributed to it.
+func (s *agentStateService) UpdateBreakReason(ctx context.Context, tenantID string, userID, id int64, req *dto.UpdateAgentBreakReasonRequest) (*dto.AgentBreakReasonResponse, error) {
+	if err := s.checkManageAgents(ctx, tenantID, userID); err != nil {
+		return nil, err
+	}
+
+	reason, err := s.breakReasonRepo.FindByID(ctx, id)
+	if err != nil || reason.TenantID != tenantID {
+		return nil, errors.NewNotFound("break reason not found")
+	}
+
+	if req.Name != nil {
+		reason.Name = *req.Name
+	}
+	if req.MaxDuration != nil {
+		reason.MaxDuration = *req.MaxDuration
+	}
+	if req.IsActive != nil {
+		reason.IsActive = *req.IsActive
+	}
+
+	if err := s.breakReasonRepo.Update(ctx, reason); err != nil {
+		return nil, errors.Wrap(err, "failed to update break reason")
+	}
+	return toAgentBreakReasonResponse(reason), nil
+}
+
+// DeleteBreakReason removes a break reason code from a tenant
+func (s *agentStateService) DeleteBreakReason(ctx context.Context, tenantID string, userID, id int64) error {
+	if err := s.checkManageAgents(ctx, tenantID, userID); err != nil {
+		return err
+	}
+
+	reason, err := s.breakReasonRepo.FindByID(ctx, id)
+	if err != nil || reason.TenantID != tenantID {
+		return errors.NewNotFound("break reason not found")
+	}
+
+	if err := s.breakReasonRepo.Delete(ctx, id); err != nil {
+		return errors.Wrap(err, "failed to delete break reason")
+	}
+	return nil
+}
+
+// checkManageAgents checks that a user may manage the tenant's agents
+func (s *agentStateService) checkManageAgents(ctx context.Context, tenantID string, userID int64) error {
+	role, err := s.roleRepo.FindByUserAndTenant(ctx, userID, tenantID)
+	if err != nil {
+		return errors.NewForbidden("no role in this tenant")
+	}
+	if !role.CanManageAgents() {
+		return errors.NewForbidden("not allowed to manage agents")
+	}
+	return nil
+}
+
+// toAgentBreakReasonResponse converts an AgentBreakReason model to response DTO
+func toAgentBreakReasonResponse(reason *asterisk.AgentBreakReason) *dto.AgentBreakReasonResponse {
+	return &dto.AgentBreakReasonResponse{
+		ID:          reason.ID,
+		TenantID:    reason.TenantID,
+		Code:        reason.Code,
+		Name:        reason.Name,
+		MaxDuration: reason.MaxDuration,
+		IsActive:    reason.IsActive,
+		CreatedAt:   reason.CreatedAt,
+		UpdatedAt:   reason.UpdatedAt,
+	}
 }
 
 // toAgentStateResponse converts AgentState model to response DTO
 func (s *agentStateService) toAgentStateResponse(state *asterisk.AgentState) *dto.AgentStateResponse {
-	return &dto.AgentStateResponse{
-		ID:         state.ID,
-		TenantID:   state.TenantID,
-		UserID:     state.UserID,
-		EndpointID: state.EndpointID,
-		State:      state.State,
-		Reason:     state.Reason,
-		ChangedAt:  state.ChangedAt,
+	response := &dto.AgentStateResponse{
+		ID:            state.ID,
+		TenantID:      state.TenantID,
+		UserID:        state.UserID,
+		EndpointID:    state.EndpointID,
+		State:         state.State,
+		ReasonCode:    state.ReasonCode,
+		Reason:        state.Reason,
+		CurrentCallID: state.CurrentCallID,
+		ChangedAt:     state.ChangedAt,
+	}
+	if state.IsInWrapup() {
+		remaining := int(time.Until(*state.WrapupEndsAt).Seconds())
+		if remaining < 0 {
+			remaining = 0
+		}
+		response.WrapupStartedAt = state.WrapupStartedAt
+		response.WrapupEndsAt = state.WrapupEndsAt
+		response.WrapupRemaining = &remaining
+		response.WrapupChatID = state.WrapupChatID
 	}
+	return response
 }
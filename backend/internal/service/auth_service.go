@@ -2,6 +2,7 @@ package service
 
 import (
 	"context"
+	"log"
 
 	"github.com/psschand/callcenter/internal/common"
 	"github.com/psschand/callcenter/internal/core"
@@ -28,6 +29,7 @@ type authService struct {
 	tenantRepo repository.TenantRepository
 	roleRepo   repository.UserRoleRepository
 	jwtService jwt.JWTService
+	softphones SoftphoneService
 }
 
 // NewAuthService creates a new authentication service
@@ -36,12 +38,14 @@ func NewAuthService(
 	tenantRepo repository.TenantRepository,
 	roleRepo repository.UserRoleRepository,
 	jwtService jwt.JWTService,
+	softphones SoftphoneService,
 ) AuthService {
 	return &authService{
 		userRepo:   userRepo,
 		tenantRepo: tenantRepo,
 		roleRepo:   roleRepo,
 		jwtService: jwtService,
+		softphones: softphones,
 	}
 }
 
@@ -100,6 +104,10 @@ func (s *authService) Register(ctx context.Context, req *dto.RegisterRequest) (*
 		return nil, errors.Wrap(err, "failed to assign role")
 	}
 
+	if err := s.softphones.Provision(ctx, userRole); err != nil {
+		log.Printf("Error provisioning softphone for user %d: %v", user.ID, err)
+	}
+
 	// Generate tokens
 	accessToken, err := s.jwtService.GenerateAccessToken(user.ID, req.TenantID, user.Email, role)
 	if err != nil {
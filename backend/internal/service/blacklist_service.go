@@ -0,0 +1,474 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/psschand/callcenter/internal/asterisk"
+	"github.com/psschand/callcenter/internal/common"
+	"github.com/psschand/callcenter/internal/core"
+	"github.com/psschand/callcenter/internal/dto"
+	"github.com/psschand/callcenter/internal/repository"
+	"github.com/psschand/callcenter/pkg/errors"
+)
+
+// Numbers and patterns that may be blacklisted: digits with an optional
+// leading +, ? for any digit and an optional trailing *. At least one digit
+// is required so a pattern cannot block every caller.
+var blacklistPattern = regexp.MustCompile(`^\+?[0-9?]*[0-9][0-9?]*\*?$`)
+
+// BlacklistService manages the numbers a tenant blocks calls and SMS from and
+// screens inbound calls and SMS against them
+type BlacklistService interface {
+	List(ctx context.Context, tenantID string, userID int64, includeExpired bool, page, pageSize int) ([]*dto.BlacklistEntryResponse, int64, error)
+	Get(ctx context.Context, tenantID string, userID, id int64) (*dto.BlacklistEntryResponse, error)
+	Create(ctx context.Context, tenantID string, userID int64, req *dto.CreateBlacklistEntryRequest) (*dto.BlacklistEntryResponse, error)
+	Update(ctx context.Context, tenantID string, userID, id int64, req *dto.UpdateBlacklistEntryRequest) (*dto.BlacklistEntryResponse, error)
+	Delete(ctx context.Context, tenantID string, userID, id int64) error
+	Import(ctx context.Context, tenantID string, userID int64, req *dto.ImportBlacklistRequest) (*dto.ImportBlacklistResponse, error)
+	ListAttempts(ctx context.Context, tenantID string, userID int64, page, pageSize int) ([]*dto.BlockedAttemptResponse, int64, error)
+
+	ScreenCall(did *asterisk.DID, callerNumber string) *asterisk.BlacklistEntry
+	ScreenSMS(did *asterisk.DID, sender string) bool
+}
+
+type blacklistService struct {
+	blacklistRepo repository.BlacklistRepository
+	roleRepo      repository.UserRoleRepository
+}
+
+// NewBlacklistService creates a new blacklist service
+func NewBlacklistService(
+	blacklistRepo repository.BlacklistRepository,
+	roleRepo repository.UserRoleRepository,
+) BlacklistService {
+	return &blacklistService{
+		blacklistRepo: blacklistRepo,
+		roleRepo:      roleRepo,
+	}
+}
+
+// List lists the tenant's blacklist, newest first
+func (s *blacklistService) List(ctx context.Context, tenantID string, userID int64, includeExpired bool, page, pageSize int) ([]*dto.BlacklistEntryResponse, int64, error) {
+	if err := s.checkManageBlacklist(ctx, tenantID, userID); err != nil {
+		return nil, 0, err
+	}
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	entries, total, err := s.blacklistRepo.FindByTenant(ctx, tenantID, includeExpired, page, pageSize)
+	if err != nil {
+		return nil, 0, errors.Wrap(err, "failed to list blacklist")
+	}
+
+	now := time.Now()
+	responses := make([]*dto.BlacklistEntryResponse, len(entries))
+	for i := range entries {
+		responses[i] = toBlacklistEntryResponse(&entries[i], now)
+	}
+	return responses, total, nil
+}
+
+// Get gets one of the tenant's blacklist entries
+func (s *blacklistService) Get(ctx context.Context, tenantID string, userID, id int64) (*dto.BlacklistEntryResponse, error) {
+	if err := s.checkManageBlacklist(ctx, tenantID, userID); err != nil {
+		return nil, err
+	}
+
+	entry, err := s.findEntry(ctx, tenantID, id)
+	if err != nil {
+		return nil, err
+	}
+	return toBlacklistEntryResponse(entry, time.Now()), nil
+}
+
+// Create blocks a number or pattern
+func (s *blacklistService) Create(ctx context.Context, tenantID string, userID int64, req *dto.CreateBlacklistEntryRequest) (*dto.BlacklistEntryResponse, error) {
+	if err := s.checkManageBlacklist(ctx, tenantID, userID); err != nil {
+		return nil, err
+	}
+
+	entry := newBlacklistEntry(tenantID, userID, req)
+	if err := validateBlacklistEntry(entry, time.Now()); err != nil {
+		return nil, err
+	}
+	if _, err := s.blacklistRepo.FindByPhoneNumber(ctx, tenantID, entry.PhoneNumber); err == nil {
+		return nil, errors.NewConflict("number is already blacklisted")
+	}
+
+	if err := s.blacklistRepo.Create(ctx, entry); err != nil {
+		return nil, errors.Wrap(err, "failed to create blacklist entry")
+	}
+
+	log.Printf("User %d blacklisted %s for tenant %s", userID, entry.PhoneNumber, tenantID)
+	return toBlacklistEntryResponse(entry, time.Now()), nil
+}
+
+// Update updates one of the tenant's blacklist entries
+func (s *blacklistService) Update(ctx context.Context, tenantID string, userID, id int64, req *dto.UpdateBlacklistEntryRequest) (*dto.BlacklistEntryResponse, error) {
+	if err := s.checkManageBlacklist(ctx, tenantID, userID); err != nil {
+		return nil, err
+	}
+
+	entry, err := s.findEntry(ctx, tenantID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.PhoneNumber != nil {
+		entry.PhoneNumber = normalizeBlacklistNumber(*req.PhoneNumber)
+	}
+	if req.Reason != nil {
+		entry.Reason = optionalString(*req.Reason)
+	}
+	if req.BlockCalls != nil {
+		entry.BlockCalls = *req.BlockCalls
+	}
+	if req.BlockSMS != nil {
+		entry.BlockSMS = *req.BlockSMS
+	}
+	if req.Treatment != nil {
+		entry.Treatment = *req.Treatment
+	}
+	if req.TreatmentTarget != nil {
+		entry.TreatmentTarget = optionalString(*req.TreatmentTarget)
+	}
+	if req.NeverExpires {
+		entry.ExpiresAt = nil
+	} else if req.ExpiresAt != nil {
+		entry.ExpiresAt = req.ExpiresAt
+	}
+
+	if err := validateBlacklistEntry(entry, time.Now()); err != nil {
+		return nil, err
+	}
+	if existing, err := s.blacklistRepo.FindByPhoneNumber(ctx, tenantID, entry.PhoneNumber); err == nil && existing.ID != entry.ID {
+		return nil, errors.NewConflict("number is already blacklisted")
+	}
+
+	if err := s.blacklistRepo.Update(ctx, entry); err != nil {
+		return nil, errors.Wrap(err, "failed to update blacklist entry")
+	}
+	return toBlacklistEntryResponse(entry, time.Now()), nil
+}
+
+// Delete unblocks one of the tenant's blacklist entries
+func (s *blacklistService) Delete(ctx context.Context, tenantID string, userID, id int64) error {
+	if err := s.checkManageBlacklist(ctx, tenantID, userID); err != nil {
+		return err
+	}
+
+	entry, err := s.findEntry(ctx, tenantID, id)
+	if err != nil {
+		return err
+	}
+
+	if err := s.blacklistRepo.Delete(ctx, entry.ID); err != nil {
+		return errors.Wrap(err, "failed to delete blacklist entry")
+	}
+
+	log.Printf("User %d removed %s from the blacklist of tenant %s", userID, entry.PhoneNumber, tenantID)
+	return nil
+}
+
+// Import blocks numbers in bulk. Entries for numbers already blacklisted
+// replace them; invalid entries are reported and skipped.
+func (s *blacklistService) Import(ctx context.Context, tenantID string, userID int64, req *dto.ImportBlacklistRequest) (*dto.ImportBlacklistResponse, error) {
+	if err := s.checkManageBlacklist(ctx, tenantID, userID); err != nil {
+		return nil, err
+	}
+
+	result := &dto.ImportBlacklistResponse{}
+	fail := func(i int, number string, reason string) {
+		result.TotalFailed++
+		result.Errors = append(result.Errors, fmt.Sprintf("entry %d (%s): %s", i+1, number, reason))
+	}
+
+	now := time.Now()
+	for i := range req.Entries {
+		entry := newBlacklistEntry(tenantID, userID, &req.Entries[i])
+		if problems := blacklistEntryProblems(entry, now); len(problems) > 0 {
+			fields := make([]string, 0, len(problems))
+			for field := range problems {
+				fields = append(fields, field)
+			}
+			sort.Strings(fields)
+			for j, field := range fields {
+				fields[j] = fmt.Sprintf("%s %s", field, problems[field])
+			}
+			fail(i, req.Entries[i].PhoneNumber, strings.Join(fields, "; "))
+			continue
+		}
+
+		if existing, err := s.blacklistRepo.FindByPhoneNumber(ctx, tenantID, entry.PhoneNumber); err == nil {
+			entry.ID = existing.ID
+			entry.CreatedAt = existing.CreatedAt
+			if err := s.blacklistRepo.Update(ctx, entry); err != nil {
+				fail(i, entry.PhoneNumber, "failed to update")
+				continue
+			}
+			result.TotalUpdated++
+			continue
+		}
+
+		if err := s.blacklistRepo.Create(ctx, entry); err != nil {
+			fail(i, entry.PhoneNumber, "failed to create")
+			continue
+		}
+		result.TotalImported++
+	}
+
+	log.Printf("User %d imported %d blacklist entries for tenant %s (%d updated, %d failed)", userID, result.TotalImported, tenantID, result.TotalUpdated, result.TotalFailed)
+	return result, nil
+}
+
+// ListAttempts lists the calls and SMS the tenant's blacklist refused,
+// latest first
+func (s *blacklistService) ListAttempts(ctx context.Context, tenantID string, userID int64, page, pageSize int) ([]*dto.BlockedAttemptResponse, int64, error) {
+	if err := s.checkManageBlacklist(ctx, tenantID, userID); err != nil {
+		return nil, 0, err
+	}
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	attempts, total, err := s.blacklistRepo.FindAttempts(ctx, tenantID, page, pageSize)
+	if err != nil {
+		return nil, 0, errors.Wrap(err, "failed to list blocked attempts")
+	}
+
+	responses := make([]*dto.BlockedAttemptResponse, len(attempts))
+	for i := range attempts {
+		responses[i] = toBlockedAttemptResponse(&attempts[i])
+	}
+	return responses, total, nil
+}
+
+// ScreenCall returns the entry blocking a call to a DID, logging the
+// attempt, or nil if the caller is not blocked
+func (s *blacklistService) ScreenCall(did *asterisk.DID, callerNumber string) *asterisk.BlacklistEntry {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	entry := s.match(ctx, did.TenantID, callerNumber, common.BlockedSourceCall)
+	if entry != nil {
+		treatment := entry.Treatment
+		s.logAttempt(ctx, did, entry, common.BlockedSourceCall, callerNumber, &treatment)
+	}
+	return entry
+}
+
+// ScreenSMS checks if a sender is blocked from messaging a DID, logging the
+// attempt if so
+func (s *blacklistService) ScreenSMS(did *asterisk.DID, sender string) bool {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	entry := s.match(ctx, did.TenantID, sender, common.BlockedSourceSMS)
+	if entry == nil {
+		return false
+	}
+	s.logAttempt(ctx, did, entry, common.BlockedSourceSMS, sender, nil)
+	return true
+}
+
+// match finds the most specific unexpired entry blocking a number from
+// reaching the tenant by call or SMS. Calls and SMS are let through when the
+// blacklist cannot be read.
+func (s *blacklistService) match(ctx context.Context, tenantID, number string, source common.BlockedSource) *asterisk.BlacklistEntry {
+	entries, err := s.blacklistRepo.FindActive(ctx, tenantID, time.Now())
+	if err != nil {
+		log.Printf("Error loading blacklist of tenant %s: %v", tenantID, err)
+		return nil
+	}
+
+	var best *asterisk.BlacklistEntry
+	for i := range entries {
+		entry := &entries[i]
+		if source == common.BlockedSourceCall && !entry.BlockCalls {
+			continue
+		}
+		if source == common.BlockedSourceSMS && !entry.BlockSMS {
+			continue
+		}
+		if !entry.Matches(number) {
+			continue
+		}
+		if best == nil || blacklistMoreSpecific(entry, best) {
+			best = entry
+		}
+	}
+	return best
+}
+
+// logAttempt records a blocked call or SMS
+func (s *blacklistService) logAttempt(ctx context.Context, did *asterisk.DID, entry *asterisk.BlacklistEntry, source common.BlockedSource, number string, treatment *common.BlockTreatment) {
+	attempt := &asterisk.BlockedAttempt{
+		TenantID:         did.TenantID,
+		BlacklistEntryID: &entry.ID,
+		Pattern:          entry.PhoneNumber,
+		Source:           source,
+		CallerNumber:     number,
+		DIDID:            &did.ID,
+		DIDNumber:        did.Number,
+		Treatment:        treatment,
+	}
+	if err := s.blacklistRepo.CreateAttempt(ctx, attempt); err != nil {
+		log.Printf("Error logging blocked %s from %s to %s: %v", source, number, did.Number, err)
+	}
+}
+
+// findEntry loads one of the tenant's blacklist entries
+func (s *blacklistService) findEntry(ctx context.Context, tenantID string, id int64) (*asterisk.BlacklistEntry, error) {
+	entry, err := s.blacklistRepo.FindByID(ctx, id)
+	if err != nil || entry.TenantID != tenantID {
+		return nil, errors.NewNotFound("blacklist entry not found")
+	}
+	return entry, nil
+}
+
+// checkManageBlacklist checks the user may see and change the tenant's
+// blacklist
+func (s *blacklistService) checkManageBlacklist(ctx context.Context, tenantID string, userID int64) error {
+	role, err := s.roleRepo.FindByUserAndTenant(ctx, userID, tenantID)
+	if err != nil {
+		return errors.NewForbidden("no role in this tenant")
+	}
+	if !canManageBlacklist(role) {
+		return errors.NewForbidden("not allowed to manage the blacklist")
+	}
+	return nil
+}
+
+// canManageBlacklist reports whether a role can manage its tenant's blacklist
+func canManageBlacklist(role *core.UserRole) bool {
+	return role.IsAdmin() || role.IsSupervisor() || role.Permissions.CanManageDIDs
+}
+
+// newBlacklistEntry builds the entry a create or import request describes
+func newBlacklistEntry(tenantID string, userID int64, req *dto.CreateBlacklistEntryRequest) *asterisk.BlacklistEntry {
+	entry := &asterisk.BlacklistEntry{
+		TenantID:    tenantID,
+		PhoneNumber: normalizeBlacklistNumber(req.PhoneNumber),
+		BlockCalls:  true,
+		BlockSMS:    true,
+		Treatment:   req.Treatment,
+		AddedBy:     &userID,
+		ExpiresAt:   req.ExpiresAt,
+	}
+	if req.Reason != nil {
+		entry.Reason = optionalString(*req.Reason)
+	}
+	if req.BlockCalls != nil {
+		entry.BlockCalls = *req.BlockCalls
+	}
+	if req.BlockSMS != nil {
+		entry.BlockSMS = *req.BlockSMS
+	}
+	if entry.Treatment == "" {
+		entry.Treatment = common.BlockTreatmentReject
+	}
+	if req.TreatmentTarget != nil {
+		entry.TreatmentTarget = optionalString(*req.TreatmentTarget)
+	}
+	return entry
+}
+
+// normalizeBlacklistNumber drops the spaces and punctuation numbers are
+// often written with, so the same number is stored once
+func normalizeBlacklistNumber(number string) string {
+	number = strings.TrimSpace(number)
+	if strings.EqualFold(number, asterisk.BlacklistAnonymous) {
+		return asterisk.BlacklistAnonymous
+	}
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case ' ', '-', '.', '(', ')':
+			return -1
+		}
+		return r
+	}, number)
+}
+
+// validateBlacklistEntry checks an entry about to be saved
+func validateBlacklistEntry(entry *asterisk.BlacklistEntry, now time.Time) error {
+	if fields := blacklistEntryProblems(entry, now); len(fields) > 0 {
+		return errors.NewValidation(fields)
+	}
+	return nil
+}
+
+// blacklistEntryProblems returns what is wrong with an entry, by field
+func blacklistEntryProblems(entry *asterisk.BlacklistEntry, now time.Time) map[string]string {
+	fields := map[string]string{}
+	if entry.PhoneNumber != asterisk.BlacklistAnonymous && !blacklistPattern.MatchString(entry.PhoneNumber) {
+		fields["phone_number"] = "must be a number, a pattern using ? and a trailing *, or anonymous"
+	}
+	if !entry.BlockCalls && !entry.BlockSMS {
+		fields["block_calls"] = "must be set unless block_sms is"
+	}
+	switch entry.Treatment {
+	case common.BlockTreatmentReject, common.BlockTreatmentBusy, common.BlockTreatmentMessage, common.BlockTreatmentVoicemail:
+	default:
+		fields["treatment"] = "must be reject, busy, message or voicemail"
+	}
+	if entry.ExpiresAt != nil && !entry.ExpiresAt.After(now) {
+		fields["expires_at"] = "must be in the future"
+	}
+	return fields
+}
+
+// blacklistMoreSpecific checks if entry a is a closer match than entry b:
+// exact numbers before patterns, then longer patterns
+func blacklistMoreSpecific(a, b *asterisk.BlacklistEntry) bool {
+	aExact := !strings.ContainsAny(a.PhoneNumber, "?*")
+	bExact := !strings.ContainsAny(b.PhoneNumber, "?*")
+	if aExact != bExact {
+		return aExact
+	}
+	return len(a.PhoneNumber) > len(b.PhoneNumber)
+}
+
+func toBlacklistEntryResponse(entry *asterisk.BlacklistEntry, now time.Time) *dto.BlacklistEntryResponse {
+	return &dto.BlacklistEntryResponse{
+		ID:              entry.ID,
+		TenantID:        entry.TenantID,
+		PhoneNumber:     entry.PhoneNumber,
+		Reason:          entry.Reason,
+		BlockCalls:      entry.BlockCalls,
+		BlockSMS:        entry.BlockSMS,
+		Treatment:       entry.Treatment,
+		TreatmentTarget: entry.TreatmentTarget,
+		AddedBy:         entry.AddedBy,
+		ExpiresAt:       entry.ExpiresAt,
+		Expired:         entry.IsExpired(now),
+		CreatedAt:       entry.CreatedAt,
+		UpdatedAt:       entry.UpdatedAt,
+	}
+}
+
+func toBlockedAttemptResponse(attempt *asterisk.BlockedAttempt) *dto.BlockedAttemptResponse {
+	return &dto.BlockedAttemptResponse{
+		ID:           attempt.ID,
+		BlacklistID:  attempt.BlacklistEntryID,
+		Pattern:      attempt.Pattern,
+		Source:       attempt.Source,
+		CallerNumber: attempt.CallerNumber,
+		DIDID:        attempt.DIDID,
+		DIDNumber:    attempt.DIDNumber,
+		Treatment:    attempt.Treatment,
+		CreatedAt:    attempt.CreatedAt,
+	}
+}
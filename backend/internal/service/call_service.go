@@ -0,0 +1,544 @@
+package service
+
+import (
+	"context"
+	stderrors "errors"
+	"fmt"
+	"log"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/psschand/callcenter/internal/asterisk"
+	"github.com/psschand/callcenter/internal/common"
+	"github.com/psschand/callcenter/internal/core"
+	"github.com/psschand/callcenter/internal/dto"
+	"github.com/psschand/callcenter/internal/repository"
+	ws "github.com/psschand/callcenter/internal/websocket"
+	"github.com/psschand/callcenter/pkg/errors"
+)
+
+// phoneNumberPattern matches dialable numbers once separators are stripped
+var phoneNumberPattern = regexp.MustCompile(`^\+?[0-9]{3,20}$`)
+
+// CallService handles live call operations
+type CallService interface {
+	Originate(ctx context.Context, tenantID string, userID int64, req *dto.OriginateCallRequest) (*dto.OriginateCallResponse, error)
+	OnOutboundCall(call asterisk.OutboundCall)
+	OnCallEvent(event asterisk.CallEvent)
+	Hold(ctx context.Context, tenantID string, userID int64, channelID string, req *dto.HoldCallRequest) (*dto.CallControlResponse, error)
+	Unhold(ctx context.Context, tenantID string, userID int64, channelID string) (*dto.CallControlResponse, error)
+	Mute(ctx context.Context, tenantID string, userID int64, channelID string, req *dto.MuteCallRequest) (*dto.CallControlResponse, error)
+	Unmute(ctx context.Context, tenantID string, userID int64, channelID string, req *dto.MuteCallRequest) (*dto.CallControlResponse, error)
+	Transfer(ctx context.Context, tenantID string, userID int64, channelID string, req *dto.TransferCallRequest) (*dto.CallControlResponse, error)
+	CompleteTransfer(ctx context.Context, tenantID string, userID int64, channelID string) (*dto.CallControlResponse, error)
+	CancelTransfer(ctx context.Context, tenantID string, userID int64, channelID string) (*dto.CallControlResponse, error)
+	Hangup(ctx context.Context, tenantID string, userID int64, channelID string, req *dto.HangupCallRequest) (*dto.CallControlResponse, error)
+}
+
+type callService struct {
+	callHandler  *asterisk.CallHandler
+	userRepo     repository.UserRepository
+	userRoleRepo repository.UserRoleRepository
+	didRepo      repository.DIDRepository
+	queueRepo    repository.QueueRepository
+	cdrRepo      repository.CDRRepository
+	recordings   RecordingService
+	agentStates  AgentStateService
+	broadcaster  *ws.EventBroadcaster
+}
+
+// NewCallService creates a new call service
+func NewCallService(
+	callHandler *asterisk.CallHandler,
+	userRepo repository.UserRepository,
+	userRoleRepo repository.UserRoleRepository,
+	didRepo repository.DIDRepository,
+	queueRepo repository.QueueRepository,
+	cdrRepo repository.CDRRepository,
+	recordings RecordingService,
+	agentStates AgentStateService,
+	broadcaster *ws.EventBroadcaster,
+) CallService {
+	return &callService{
+		callHandler:  callHandler,
+		userRepo:     userRepo,
+		userRoleRepo: userRoleRepo,
+		didRepo:      didRepo,
+		queueRepo:    queueRepo,
+		cdrRepo:      cdrRepo,
+		recordings:   recordings,
+		agentStates:  agentStates,
+		broadcaster:  broadcaster,
+	}
+}
+
+// Originate places a click-to-call from the agent's endpoint to an external number
+func (s *callService) Originate(ctx context.Context, tenantID string, userID int64, req *dto.OriginateCallRequest) (*dto.OriginateCallResponse, error) {
+	role, err := s.userRoleRepo.FindByUserAndTenant(ctx, userID, tenantID)
+	if err != nil {
+		return nil, errors.NewForbidden("no role in this tenant")
+	}
+	if !role.Permissions.CanMakeCalls {
+		return nil, errors.NewForbidden("not allowed to make calls")
+	}
+	if role.EndpointID == nil || *role.EndpointID == "" {
+		return nil, errors.NewValidation(map[string]string{"endpoint": "no phone endpoint is assigned to this user"})
+	}
+
+	destination := normalizePhoneNumber(req.ToNumber)
+	if !phoneNumberPattern.MatchString(destination) {
+		return nil, errors.NewValidation(map[string]string{"to_number": "invalid phone number"})
+	}
+
+	did, err := s.selectCallerID(ctx, tenantID, req.CallerID)
+	if err != nil {
+		return nil, err
+	}
+
+	call, err := s.callHandler.Originate(asterisk.OriginateRequest{
+		TenantID:      tenantID,
+		UserID:        userID,
+		AgentEndpoint: *role.EndpointID,
+		Destination:   destination,
+		CallerID:      did.Number,
+		DIDID:         did.ID,
+		Timeout:       req.Timeout,
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to originate call")
+	}
+
+	return &dto.OriginateCallResponse{
+		ChannelID: call.AgentChannelID,
+		CallID:    call.ID,
+		Status:    call.State,
+		Endpoint:  call.AgentEndpoint,
+		ToNumber:  call.Destination,
+		CallerID:  call.CallerID,
+		DIDID:     call.DIDID,
+		CreatedAt: call.CreatedAt,
+	}, nil
+}
+
+// selectCallerID picks the DID presented to the called party
+func (s *callService) selectCallerID(ctx context.Context, tenantID string, number *string) (*asterisk.DID, error) {
+	if number != nil && *number != "" {
+		did, err := s.didRepo.FindByNumber(ctx, normalizePhoneNumber(*number))
+		if err != nil || did.TenantID != tenantID {
+			return nil, errors.NewValidation(map[string]string{"caller_id": "caller ID must be one of the tenant's DIDs"})
+		}
+		if !did.IsActive() {
+			return nil, errors.NewValidation(map[string]string{"caller_id": "caller ID DID is not active"})
+		}
+		return did, nil
+	}
+
+	dids, err := s.didRepo.FindByStatus(ctx, tenantID, common.DIDStatusActive)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to load DIDs")
+	}
+	if len(dids) == 0 {
+		return nil, errors.NewValidation(map[string]string{"caller_id": "tenant has no active DID to use as caller ID"})
+	}
+	return &dids[0], nil
+}
+
+// OnOutboundCall broadcasts outbound call progress and writes the CDR once the call ends
+func (s *callService) OnOutboundCall(call asterisk.OutboundCall) {
+	agentName := ""
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if user, err := s.userRepo.FindByID(ctx, call.UserID); err == nil {
+		agentName = user.GetFullName()
+	}
+
+	if s.broadcaster != nil {
+		var err error
+		switch call.State {
+		case asterisk.OutboundStateRingingAgent:
+			err = s.broadcaster.CallOutgoing(call.TenantID, call.ID, call.CallerID, call.Destination, call.UserID, agentName)
+		case asterisk.OutboundStateAnswered:
+			err = s.broadcaster.CallAnswered(call.TenantID, call.ID, call.UserID, agentName)
+		case asterisk.OutboundStateEnded:
+			err = s.broadcaster.CallEnded(call.TenantID, call.ID, billableSeconds(call))
+		}
+		if err != nil {
+			log.Printf("Error broadcasting call %s: %v", call.ID, err)
+		}
+	}
+
+	if s.agentStates != nil {
+		var err error
+		switch call.State {
+		case asterisk.OutboundStateRingingAgent:
+			err = s.agentStates.StartCall(ctx, call.TenantID, call.UserID, call.ID)
+		case asterisk.OutboundStateEnded:
+			err = s.agentStates.EndCall(ctx, call.TenantID, call.UserID, call.ID, "")
+		}
+		if err != nil {
+			log.Printf("Error updating state of agent %d for call %s: %v", call.UserID, call.ID, err)
+		}
+	}
+
+	if call.State != asterisk.OutboundStateEnded {
+		return
+	}
+
+	cdr := toOutboundCDR(call, agentName)
+	if err := s.cdrRepo.Create(ctx, cdr); err != nil {
+		log.Printf("Error writing CDR for call %s: %v", call.ID, err)
+		return
+	}
+	if s.recordings != nil {
+		s.recordings.LinkCDR(ctx, cdr)
+	}
+}
+
+// OnCallEvent broadcasts the progress of calls handled by the ARI application
+// and keeps the state of the agents taking them up to date
+func (s *callService) OnCallEvent(event asterisk.CallEvent) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	// The agent the caller is, or was until this event, connected to
+	var agentID int64
+	agentName := ""
+	if event.AgentEndpoint != "" {
+		if role, err := s.userRoleRepo.FindByEndpoint(ctx, event.TenantID, event.AgentEndpoint); err == nil {
+			agentID = role.UserID
+			if user, err := s.userRepo.FindByID(ctx, role.UserID); err == nil {
+				agentName = user.GetFullName()
+			}
+		}
+	}
+
+	if s.broadcaster != nil {
+		callerID := event.CallerNumber
+		if event.CallerName != "" && event.CallerName != event.CallerNumber {
+			callerID = fmt.Sprintf("\"%s\" <%s>", event.CallerName, event.CallerNumber)
+		}
+
+		var err error
+		switch event.Type {
+		case asterisk.CallEventIncoming:
+			err = s.broadcaster.CallIncoming(event.TenantID, event.ChannelID, callerID, event.Destination, event.QueueName)
+		case asterisk.CallEventQueued:
+			err = s.broadcaster.QueueJoined(event.TenantID, event.ChannelID, callerID, event.QueueName)
+		case asterisk.CallEventQueueLeft:
+			err = s.broadcaster.QueueLeft(event.TenantID, event.ChannelID, event.QueueName)
+		case asterisk.CallEventAnswered:
+			err = s.broadcaster.CallAnswered(event.TenantID, event.ChannelID, agentID, agentName)
+		case asterisk.CallEventTransferred:
+			err = s.broadcaster.CallTransferred(event.TenantID, event.ChannelID, event.TransferredTo)
+		case asterisk.CallEventEnded:
+			err = s.broadcaster.CallEnded(event.TenantID, event.ChannelID, event.Duration)
+		}
+		if err != nil {
+			log.Printf("Error broadcasting %s event of call %s: %v", event.Type, event.ChannelID, err)
+		}
+	}
+
+	if s.agentStates == nil || agentID == 0 {
+		return
+	}
+
+	var err error
+	switch event.Type {
+	case asterisk.CallEventAnswered:
+		err = s.agentStates.StartCall(ctx, event.TenantID, agentID, event.ChannelID)
+	case asterisk.CallEventTransferred, asterisk.CallEventEnded:
+		err = s.agentStates.EndCall(ctx, event.TenantID, agentID, event.ChannelID, event.QueueName)
+	}
+	if err != nil {
+		log.Printf("Error updating state of agent %d for call %s: %v", agentID, event.ChannelID, err)
+	}
+}
+
+// Hold puts a call on hold
+func (s *callService) Hold(ctx context.Context, tenantID string, userID int64, channelID string, req *dto.HoldCallRequest) (*dto.CallControlResponse, error) {
+	if err := s.checkControl(ctx, tenantID, userID, channelID); err != nil {
+		return nil, err
+	}
+
+	mohClass := ""
+	if req.MusicOnHold != nil {
+		mohClass = *req.MusicOnHold
+	}
+	if err := s.callHandler.Hold(channelID, mohClass); err != nil {
+		return nil, callControlError(err, "failed to hold call")
+	}
+
+	if s.broadcaster != nil {
+		s.broadcaster.CallHold(tenantID, channelID)
+	}
+	return controlResponse(channelID, "hold"), nil
+}
+
+// Unhold resumes a held call
+func (s *callService) Unhold(ctx context.Context, tenantID string, userID int64, channelID string) (*dto.CallControlResponse, error) {
+	if err := s.checkControl(ctx, tenantID, userID, channelID); err != nil {
+		return nil, err
+	}
+
+	if err := s.callHandler.Unhold(channelID); err != nil {
+		return nil, callControlError(err, "failed to unhold call")
+	}
+
+	if s.broadcaster != nil {
+		s.broadcaster.CallUnhold(tenantID, channelID)
+	}
+	return controlResponse(channelID, "unhold"), nil
+}
+
+// Mute mutes a call
+func (s *callService) Mute(ctx context.Context, tenantID string, userID int64, channelID string, req *dto.MuteCallRequest) (*dto.CallControlResponse, error) {
+	if err := s.checkControl(ctx, tenantID, userID, channelID); err != nil {
+		return nil, err
+	}
+
+	if err := s.callHandler.Mute(channelID, muteDirection(req)); err != nil {
+		return nil, callControlError(err, "failed to mute call")
+	}
+	return controlResponse(channelID, "mute"), nil
+}
+
+// Unmute unmutes a call
+func (s *callService) Unmute(ctx context.Context, tenantID string, userID int64, channelID string, req *dto.MuteCallRequest) (*dto.CallControlResponse, error) {
+	if err := s.checkControl(ctx, tenantID, userID, channelID); err != nil {
+		return nil, err
+	}
+
+	if err := s.callHandler.Unmute(channelID, muteDirection(req)); err != nil {
+		return nil, callControlError(err, "failed to unmute call")
+	}
+	return controlResponse(channelID, "unmute"), nil
+}
+
+// Transfer blind transfers a call, or starts an attended transfer
+func (s *callService) Transfer(ctx context.Context, tenantID string, userID int64, channelID string, req *dto.TransferCallRequest) (*dto.CallControlResponse, error) {
+	if err := s.checkControl(ctx, tenantID, userID, channelID); err != nil {
+		return nil, err
+	}
+
+	routeType, target, err := s.resolveTransferTarget(ctx, tenantID, req)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Type == "attended" {
+		if routeType == common.RouteTypeQueue {
+			return nil, errors.NewValidation(map[string]string{"target_type": "attended transfer to a queue is not supported"})
+		}
+		if err := s.callHandler.StartAttendedTransfer(channelID, routeType, target); err != nil {
+			return nil, callControlError(err, "failed to start transfer")
+		}
+		return controlResponse(channelID, "transfer_started"), nil
+	}
+
+	if err := s.callHandler.BlindTransfer(channelID, routeType, target); err != nil {
+		return nil, callControlError(err, "failed to transfer call")
+	}
+
+	if s.broadcaster != nil {
+		s.broadcaster.CallTransferred(tenantID, channelID, req.Target)
+	}
+	return controlResponse(channelID, "transfer"), nil
+}
+
+// CompleteTransfer hands an attended transfer over to the consulted party
+func (s *callService) CompleteTransfer(ctx context.Context, tenantID string, userID int64, channelID string) (*dto.CallControlResponse, error) {
+	if err := s.checkControl(ctx, tenantID, userID, channelID); err != nil {
+		return nil, err
+	}
+
+	if err := s.callHandler.CompleteAttendedTransfer(channelID); err != nil {
+		return nil, callControlError(err, "failed to complete transfer")
+	}
+
+	if s.broadcaster != nil {
+		s.broadcaster.CallTransferred(tenantID, channelID, "")
+	}
+	return controlResponse(channelID, "transfer_completed"), nil
+}
+
+// CancelTransfer abandons an attended transfer and reconnects the original parties
+func (s *callService) CancelTransfer(ctx context.Context, tenantID string, userID int64, channelID string) (*dto.CallControlResponse, error) {
+	if err := s.checkControl(ctx, tenantID, userID, channelID); err != nil {
+		return nil, err
+	}
+
+	if err := s.callHandler.CancelAttendedTransfer(channelID); err != nil {
+		return nil, callControlError(err, "failed to cancel transfer")
+	}
+	return controlResponse(channelID, "transfer_cancelled"), nil
+}
+
+// Hangup hangs up a call
+func (s *callService) Hangup(ctx context.Context, tenantID string, userID int64, channelID string, req *dto.HangupCallRequest) (*dto.CallControlResponse, error) {
+	if err := s.checkControl(ctx, tenantID, userID, channelID); err != nil {
+		return nil, err
+	}
+
+	reason := ""
+	if req.Reason != nil {
+		reason = *req.Reason
+	}
+	if err := s.callHandler.Hangup(channelID, reason); err != nil {
+		return nil, callControlError(err, "failed to hang up call")
+	}
+	return controlResponse(channelID, "hangup"), nil
+}
+
+// checkControl checks the user may control a call. Calls of other tenants
+// are indistinguishable from calls that do not exist.
+func (s *callService) checkControl(ctx context.Context, tenantID string, userID int64, channelID string) error {
+	owner, ok := s.callHandler.ChannelTenant(channelID)
+	if !ok || owner != tenantID {
+		return errors.NewNotFound("call not found")
+	}
+
+	role, err := s.userRoleRepo.FindByUserAndTenant(ctx, userID, tenantID)
+	if err != nil {
+		return errors.NewForbidden("no role in this tenant")
+	}
+	return canControlCall(role, s.callHandler.ChannelParties(channelID))
+}
+
+// canControlCall lets supervisors and admins control any of the tenant's
+// calls, and everyone else only calls their own endpoint takes part in
+func canControlCall(role *core.UserRole, parties []string) error {
+	if role.IsAdmin() || role.IsSupervisor() {
+		return nil
+	}
+	if role.EndpointID != nil && *role.EndpointID != "" {
+		for _, endpoint := range parties {
+			if endpoint == *role.EndpointID {
+				return nil
+			}
+		}
+	}
+	return errors.NewForbidden("not a party to this call")
+}
+
+// resolveTransferTarget turns a transfer target into a route
+func (s *callService) resolveTransferTarget(ctx context.Context, tenantID string, req *dto.TransferCallRequest) (common.RouteType, string, error) {
+	switch req.TargetType {
+	case "user":
+		userID, err := strconv.ParseInt(req.Target, 10, 64)
+		if err != nil {
+			return "", "", errors.NewValidation(map[string]string{"target": "invalid user ID"})
+		}
+		role, err := s.userRoleRepo.FindByUserAndTenant(ctx, userID, tenantID)
+		if err != nil {
+			return "", "", errors.NewNotFound("user not found")
+		}
+		if role.EndpointID == nil || *role.EndpointID == "" {
+			return "", "", errors.NewValidation(map[string]string{"target": "user has no phone endpoint"})
+		}
+		return common.RouteTypeEndpoint, *role.EndpointID, nil
+
+	case "queue":
+		queue, err := s.queueRepo.FindByName(ctx, tenantID, req.Target)
+		if err != nil {
+			return "", "", errors.NewNotFound("queue not found")
+		}
+		if !queue.IsActive() {
+			return "", "", errors.NewValidation(map[string]string{"target": "queue is not active"})
+		}
+		return common.RouteTypeQueue, queue.Name, nil
+
+	default:
+		number := normalizePhoneNumber(req.Target)
+		if !phoneNumberPattern.MatchString(number) {
+			return "", "", errors.NewValidation(map[string]string{"target": "invalid phone number"})
+		}
+		return common.RouteTypeExternal, number, nil
+	}
+}
+
+// callControlError maps call handler errors to API errors
+func callControlError(err error, message string) error {
+	switch {
+	case stderrors.Is(err, asterisk.ErrCallNotFound):
+		return errors.NewNotFound("call not found")
+	case stderrors.Is(err, asterisk.ErrCallNotBridged),
+		stderrors.Is(err, asterisk.ErrTransferInProgress),
+		stderrors.Is(err, asterisk.ErrNoTransfer),
+		stderrors.Is(err, asterisk.ErrTransferNotAnswered):
+		return errors.NewConflict(err.Error())
+	default:
+		return errors.Wrap(err, message)
+	}
+}
+
+// muteDirection defaults an empty mute direction to both ways
+func muteDirection(req *dto.MuteCallRequest) string {
+	if req == nil || req.Direction == "" {
+		return asterisk.MuteDirectionBoth
+	}
+	return req.Direction
+}
+
+// controlResponse builds the response of a successful call control action
+func controlResponse(channelID, action string) *dto.CallControlResponse {
+	return &dto.CallControlResponse{
+		ChannelID: channelID,
+		Action:    action,
+		Status:    "ok",
+	}
+}
+
+// toOutboundCDR builds the CDR of a finished click-to-call
+func toOutboundCDR(call asterisk.OutboundCall, agentName string) *asterisk.CDR {
+	endedAt := time.Now()
+	if call.EndedAt != nil {
+		endedAt = *call.EndedAt
+	}
+
+	clid := call.CallerID
+	if agentName != "" {
+		clid = fmt.Sprintf("\"%s\" <%s>", agentName, call.CallerID)
+	}
+
+	userID := call.UserID
+	didID := call.DIDID
+	return &asterisk.CDR{
+		TenantID:    call.TenantID,
+		CallDate:    call.CreatedAt,
+		CLID:        clid,
+		Src:         call.CallerID,
+		Dst:         call.Destination,
+		DContext:    "click-to-call",
+		Channel:     call.AgentChannel,
+		DstChannel:  call.DestChannel,
+		LastApp:     "Dial",
+		LastData:    call.DialString,
+		Duration:    int(endedAt.Sub(call.CreatedAt).Seconds()),
+		BillSec:     billableSeconds(call),
+		Disposition: call.Disposition,
+		AccountCode: call.TenantID,
+		UniqueID:    call.AgentChannelID,
+		LinkedID:    call.ID,
+		DIDID:       &didID,
+		UserID:      &userID,
+		Metadata: common.JSONMap{
+			"direction": "outbound",
+			"call_id":   call.ID,
+		},
+	}
+}
+
+// billableSeconds is the time from answer to hangup
+func billableSeconds(call asterisk.OutboundCall) int {
+	if call.AnsweredAt == nil || call.EndedAt == nil {
+		return 0
+	}
+	return int(call.EndedAt.Sub(*call.AnsweredAt).Seconds())
+}
+
+// normalizePhoneNumber strips the separators people type into phone numbers
+func normalizePhoneNumber(number string) string {
+	return strings.NewReplacer(" ", "", "-", "", "(", "", ")", "", ".", "").Replace(strings.TrimSpace(number))
+}
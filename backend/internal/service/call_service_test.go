@@ -0,0 +1,45 @@
+package service
+
+import (
+	stderrors "errors"
+	"testing"
+
+	"github.com/psschand/callcenter/internal/common"
+	"github.com/psschand/callcenter/internal/core"
+	"github.com/psschand/callcenter/pkg/errors"
+)
+
+func TestCanControlCall(t *testing.T) {
+	endpoint := func(id string) *string { return &id }
+	parties := []string{"twilio_trunk", "acme-agent1"}
+
+	cases := []struct {
+		name    string
+		role    core.UserRole
+		allowed bool
+	}{
+		{"agent on the call", core.UserRole{Role: common.RoleAgent, EndpointID: endpoint("acme-agent1")}, true},
+		{"supervisor", core.UserRole{Role: common.RoleSupervisor}, true},
+		{"tenant admin", core.UserRole{Role: common.RoleTenantAdmin}, true},
+		{"other agent", core.UserRole{Role: common.RoleAgent, EndpointID: endpoint("acme-agent2")}, false},
+		{"agent without an endpoint", core.UserRole{Role: common.RoleAgent}, false},
+		{"agent with an empty endpoint", core.UserRole{Role: common.RoleAgent, EndpointID: endpoint("")}, false},
+		{"viewer", core.UserRole{Role: common.RoleViewer}, false},
+		{"viewer with another endpoint", core.UserRole{Role: common.RoleViewer, EndpointID: endpoint("acme-agent3")}, false},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := canControlCall(&tc.role, parties)
+			if tc.allowed && err != nil {
+				t.Errorf("canControlCall = %v, want nil", err)
+			}
+			if !tc.allowed && !stderrors.Is(err, errors.ErrForbidden) {
+				t.Errorf("canControlCall = %v, want a forbidden error", err)
+			}
+		})
+	}
+
+	if err := canControlCall(&core.UserRole{Role: common.RoleAgent, EndpointID: endpoint("acme-agent1")}, nil); !stderrors.Is(err, errors.ErrForbidden) {
+		t.Errorf("canControlCall with no parties = %v, want a forbidden error", err)
+	}
+}
@@ -2,9 +2,12 @@ package service
 
 import (
 	"context"
+	"fmt"
+	"log"
 	"time"
 
 	"github.com/psschand/callcenter/internal/asterisk"
+	"github.com/psschand/callcenter/internal/common"
 	"github.com/psschand/callcenter/internal/dto"
 	"github.com/psschand/callcenter/internal/repository"
 	"github.com/psschand/callcenter/pkg/errors"
@@ -19,18 +22,107 @@ type CDRService interface {
 	GetByQueue(ctx context.Context, tenantID string, queueName string, page, pageSize int) ([]dto.CDRResponse, int64, error)
 	GetStats(ctx context.Context, tenantID string, start, end time.Time) (*dto.CDRStatsResponse, error)
 	GetCallVolumeByHour(ctx context.Context, tenantID string, date time.Time) ([]dto.CallVolumeResponse, error)
+	OnCallDetail(detail asterisk.CallDetail)
 }
 
 type cdrService struct {
-	cdrRepo  repository.CDRRepository
-	userRepo repository.UserRepository
+	cdrRepo    repository.CDRRepository
+	userRepo   repository.UserRepository
+	roleRepo   repository.UserRoleRepository
+	recordings RecordingService
 }
 
 // NewCDRService creates a new CDR service
-func NewCDRService(cdrRepo repository.CDRRepository, userRepo repository.UserRepository) CDRService {
+func NewCDRService(
+	cdrRepo repository.CDRRepository,
+	userRepo repository.UserRepository,
+	roleRepo repository.UserRoleRepository,
+	recordings RecordingService,
+) CDRService {
 	return &cdrService{
-		cdrRepo:  cdrRepo,
-		userRepo: userRepo,
+		cdrRepo:    cdrRepo,
+		userRepo:   userRepo,
+		roleRepo:   roleRepo,
+		recordings: recordings,
+	}
+}
+
+// OnCallDetail writes the CDR of a finished call segment and links
+// it with the call's recording
+func (s *cdrService) OnCallDetail(detail asterisk.CallDetail) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var userID *int64
+	if detail.AgentEndpoint != "" {
+		if role, err := s.roleRepo.FindByEndpoint(ctx, detail.TenantID, detail.AgentEndpoint); err == nil {
+			userID = &role.UserID
+		}
+	}
+
+	cdr := toCallDetailCDR(detail, userID)
+	if err := s.cdrRepo.Create(ctx, cdr); err != nil {
+		log.Printf("Error writing CDR for call %s segment %d: %v", detail.LinkedID, detail.Sequence, err)
+		return
+	}
+	if s.recordings != nil {
+		s.recordings.LinkCDR(ctx, cdr)
+	}
+}
+
+// toCallDetailCDR builds the CDR of a finished call segment
+func toCallDetailCDR(detail asterisk.CallDetail, userID *int64) *asterisk.CDR {
+	clid := detail.CallerNumber
+	if detail.CallerName != "" && detail.CallerName != detail.CallerNumber {
+		clid = fmt.Sprintf("\"%s\" <%s>", detail.CallerName, detail.CallerNumber)
+	}
+
+	billSec := 0
+	if detail.AnsweredAt != nil {
+		billSec = int(detail.EndedAt.Sub(*detail.AnsweredAt).Seconds())
+	}
+
+	var queueName *string
+	if detail.QueueName != "" {
+		queueName = &detail.QueueName
+	}
+
+	direction := "inbound"
+	if detail.Outbound {
+		direction = "outbound"
+	}
+	metadata := common.JSONMap{"direction": direction}
+	if detail.AgentEndpoint != "" {
+		metadata["agent_endpoint"] = detail.AgentEndpoint
+	}
+	if detail.Transfer != "" {
+		metadata["transfer"] = detail.Transfer
+		metadata["transferred_to"] = detail.TransferredTo
+	}
+
+	return &asterisk.CDR{
+		TenantID:      detail.TenantID,
+		CallDate:      detail.StartedAt,
+		CLID:          clid,
+		Src:           detail.CallerNumber,
+		Dst:           detail.Destination,
+		DContext:      detail.Context,
+		Channel:       detail.Channel,
+		DstChannel:    detail.DstChannel,
+		LastApp:       detail.LastApp,
+		LastData:      detail.LastData,
+		Duration:      int(detail.EndedAt.Sub(detail.StartedAt).Seconds()),
+		BillSec:       billSec,
+		Disposition:   detail.Disposition,
+		AccountCode:   detail.TenantID,
+		UniqueID:      detail.UniqueID,
+		LinkedID:      detail.LinkedID,
+		Sequence:      detail.Sequence,
+		DIDID:         detail.DIDID,
+		UserID:        userID,
+		QueueName:     queueName,
+		QueueWaitTime: detail.QueueWaitTime,
+		Metadata:      metadata,
 	}
 }
 
@@ -84,7 +176,10 @@ func (s *cdrService) toCDRResponse(cdr *asterisk.CDR) (*dto.CDRResponse, error)
 		RecordingFile: cdr.RecordingFile,
 		QueueName:     cdr.QueueName,
 		QueueWaitTime: cdr.QueueWaitTime,
+		WrapupTime:    cdr.WrapupTime,
 		AgentName:     agentName,
+		LinkedID:      cdr.LinkedID,
+		Sequence:      cdr.Sequence,
 		Metadata:      cdr.Metadata,
 	}, nil
 }
@@ -164,6 +259,7 @@ func (s *cdrService) GetStats(ctx context.Context, tenantID string, start, end t
 		BusyCalls:       busyCalls,
 		AverageDuration: stats["avg_duration"].(float64),
 		AverageWaitTime: 0, // TODO: Add if available
+		AverageWrapup:   stats["avg_wrapup_time"].(float64),
 		TotalDuration:   0, // TODO: Calculate from stats
 		AnswerRate:      stats["answer_rate"].(float64),
 	}, nil
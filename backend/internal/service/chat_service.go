@@ -3,8 +3,10 @@ package service
 import (
 	"context"
 	"fmt"
+	"log"
 	"time"
 
+	"github.com/psschand/callcenter/internal/asterisk"
 	"github.com/psschand/callcenter/internal/chat"
 	"github.com/psschand/callcenter/internal/common"
 	"github.com/psschand/callcenter/internal/dto"
@@ -48,6 +50,10 @@ type ChatService interface {
 	// Statistics
 	GetChatStats(ctx context.Context, tenantID string, start, end time.Time) (*dto.ChatStatsResponse, error)
 
+	// SMS conversations
+	OnInboundSMS(message *asterisk.SMSMessage)
+	OnSMSStatus(message *asterisk.SMSMessage)
+
 	// WebSocket
 	SetWebSocketHub(hub WebSocketHub)
 }
@@ -59,6 +65,10 @@ type chatService struct {
 	agentRepo    repository.ChatAgentRepository
 	transferRepo repository.ChatTransferRepository
 	userRepo     repository.UserRepository
+	scheduleRepo repository.ScheduleRepository
+	agentStates  AgentStateService
+	sms          SMSService
+	aiAgent      *chat.AIAgentService
 	wsHub        WebSocketHub
 }
 
@@ -76,6 +86,10 @@ func NewChatService(
 	agentRepo repository.ChatAgentRepository,
 	transferRepo repository.ChatTransferRepository,
 	userRepo repository.UserRepository,
+	scheduleRepo repository.ScheduleRepository,
+	agentStates AgentStateService,
+	sms SMSService,
+	aiAgent *chat.AIAgentService,
 ) ChatService {
 	return &chatService{
 		widgetRepo:   widgetRepo,
@@ -84,6 +98,10 @@ func NewChatService(
 		agentRepo:    agentRepo,
 		transferRepo: transferRepo,
 		userRepo:     userRepo,
+		scheduleRepo: scheduleRepo,
+		agentStates:  agentStates,
+		sms:          sms,
+		aiAgent:      aiAgent,
 		wsHub:        nil, // Will be set via SetWebSocketHub
 	}
 }
@@ -111,13 +129,21 @@ func (s *chatService) CreateWidget(ctx context.Context, tenantID string, req *dt
 		IsEnabled:        true,
 		CreatedAt:        now,
 		UpdatedAt:        now,
+
+		BusinessHoursEnabled: req.BusinessHoursEnabled,
+		ScheduleID:           req.ScheduleID,
+		OfflineMessage:       req.OfflineMessage,
+	}
+
+	if err := s.validateWidgetSchedule(ctx, widget); err != nil {
+		return nil, err
 	}
 
 	if err := s.widgetRepo.Create(ctx, widget); err != nil {
 		return nil, errors.Wrap(err, "failed to create widget")
 	}
 
-	return s.toWidgetResponse(widget), nil
+	return s.toWidgetResponse(ctx, widget), nil
 }
 
 // GetWidget gets a widget by ID
@@ -127,7 +153,7 @@ func (s *chatService) GetWidget(ctx context.Context, id int64) (*dto.ChatWidgetR
 		return nil, errors.NewNotFound("widget not found")
 	}
 
-	return s.toWidgetResponse(widget), nil
+	return s.toWidgetResponse(ctx, widget), nil
 }
 
 // GetWidgetByKey gets a widget by key
@@ -137,7 +163,7 @@ func (s *chatService) GetWidgetByKey(ctx context.Context, widgetKey string) (*dt
 		return nil, errors.NewNotFound("widget not found")
 	}
 
-	return s.toWidgetResponse(widget), nil
+	return s.toWidgetResponse(ctx, widget), nil
 }
 
 // UpdateWidget updates a widget
@@ -199,6 +225,18 @@ func (s *chatService) UpdateWidget(ctx context.Context, id int64, req *dto.Updat
 	if req.OfflineMessage != nil {
 		widget.OfflineMessage = req.OfflineMessage
 	}
+	if req.BusinessHoursEnabled != nil {
+		widget.BusinessHoursEnabled = *req.BusinessHoursEnabled
+	}
+	if req.ScheduleID != nil {
+		widget.ScheduleID = req.ScheduleID
+		if *req.ScheduleID == 0 {
+			widget.ScheduleID = nil
+		}
+	}
+	if err := s.validateWidgetSchedule(ctx, widget); err != nil {
+		return nil, err
+	}
 
 	// Store extended configuration in settings JSON
 	if widget.Metadata == nil {
@@ -316,7 +354,7 @@ func (s *chatService) UpdateWidget(ctx context.Context, id int64, req *dto.Updat
 		return nil, errors.Wrap(err, "failed to update widget")
 	}
 
-	return s.toWidgetResponse(widget), nil
+	return s.toWidgetResponse(ctx, widget), nil
 }
 
 // DeleteWidget deletes a widget
@@ -340,8 +378,9 @@ func (s *chatService) CreateSession(ctx context.Context, req *dto.CreateChatSess
 	now := time.Now()
 	session := &chat.ChatSession{
 		TenantID:     widget.TenantID,
-		WidgetID:     widget.ID,
+		WidgetID:     &widget.ID,
 		SessionKey:   s.generateSessionKey(),
+		Channel:      common.ChatChannelWeb,
 		VisitorName:  req.VisitorName,
 		VisitorEmail: req.VisitorEmail,
 		Status:       common.ChatSessionStatusQueued,
@@ -354,16 +393,32 @@ func (s *chatService) CreateSession(ctx context.Context, req *dto.CreateChatSess
 		return nil, errors.Wrap(err, "failed to create session")
 	}
 
-	// Try to assign to available agent
-	agents, _ := s.agentRepo.FindAvailable(ctx, widget.TenantID)
-	if len(agents) > 0 {
-		// Assign to agent with least current chats
-		s.AssignSession(ctx, session.ID, agents[0].ID)
+	// Outside business hours the chat waits in the queue for the next agent
+	// to pick it up
+	if online, _ := s.widgetOnline(ctx, widget, now); online {
+		s.assignAvailableAgent(ctx, session)
+	} else {
+		log.Printf("Widget %s is outside business hours, leaving session %d queued", widget.WidgetKey, session.ID)
 	}
 
 	return s.toSessionResponse(session), nil
 }
 
+// assignAvailableAgent assigns a queued session to the available agent with
+// the fewest current chats, if there is one
+func (s *chatService) assignAvailableAgent(ctx context.Context, session *chat.ChatSession) bool {
+	agents, _ := s.agentRepo.FindAvailable(ctx, session.TenantID)
+	if len(agents) == 0 {
+		return false
+	}
+	if err := s.AssignSession(ctx, session.ID, agents[0].ID); err != nil {
+		return false
+	}
+	session.AssignedToID = &agents[0].UserID
+	session.Status = common.ChatSessionStatusActive
+	return true
+}
+
 // GetSession gets a session by ID
 func (s *chatService) GetSession(ctx context.Context, id int64) (*dto.ChatSessionResponse, error) {
 	session, err := s.sessionRepo.FindByID(ctx, id)
@@ -558,6 +613,12 @@ func (s *chatService) EndSession(ctx context.Context, sessionID int64, rating *i
 			agent.UpdatedAt = time.Now()
 			s.agentRepo.Update(ctx, agent)
 		}
+
+		if s.agentStates != nil {
+			if err := s.agentStates.EndChat(ctx, session.TenantID, *session.AssignedToID, session.ID); err != nil {
+				log.Printf("Error starting wrap-up of agent %d after chat %d: %v", *session.AssignedToID, session.ID, err)
+			}
+		}
 	}
 
 	return nil
@@ -584,6 +645,13 @@ func (s *chatService) SendMessage(ctx context.Context, sessionID int64, senderID
 		CreatedAt:   now,
 	}
 
+	// Replies in an SMS session go out to the visitor's phone
+	if session.IsSMS() && senderType != "visitor" {
+		if err := s.sendSMS(ctx, session, senderID, message); err != nil {
+			return nil, err
+		}
+	}
+
 	if err := s.messageRepo.Create(ctx, message); err != nil {
 		return nil, errors.Wrap(err, "failed to send message")
 	}
@@ -596,21 +664,196 @@ func (s *chatService) SendMessage(ctx context.Context, sessionID int64, senderID
 		s.sessionRepo.Update(ctx, session)
 	}
 
-	// Broadcast new message via WebSocket
+	s.broadcastMessage(session, message)
+
+	return s.toMessageResponse(message), nil
+}
+
+// broadcastMessage broadcasts a new message via WebSocket
+func (s *chatService) broadcastMessage(session *chat.ChatSession, message *chat.ChatMessage) {
+	if s.wsHub == nil {
+		return
+	}
+	payload := map[string]interface{}{
+		"session_id":   session.ID,
+		"message_id":   message.ID,
+		"sender_type":  message.SenderType,
+		"sender_name":  message.SenderName,
+		"message_type": message.MessageType,
+		"body":         message.Body,
+		"timestamp":    message.CreatedAt,
+	}
+	if message.DeliveryStatus != nil {
+		payload["delivery_status"] = *message.DeliveryStatus
+	}
+	s.wsHub.BroadcastToTenant(session.TenantID, "chat.message.new", payload)
+}
+
+// sendSMS sends a reply in an SMS session to the visitor's phone, recording
+// the SMS message on the chat message. A message the provider refuses is
+// still kept, marked failed.
+func (s *chatService) sendSMS(ctx context.Context, session *chat.ChatSession, senderID *int64, message *chat.ChatMessage) error {
+	if s.sms == nil || session.DIDID == nil || session.VisitorPhone == nil {
+		return errors.NewConflict("session cannot be replied to by SMS")
+	}
+	if message.Body == nil || *message.Body == "" {
+		return errors.NewValidation(map[string]string{"body": "SMS replies must have a body"})
+	}
+	if message.MessageType != "" && message.MessageType != common.ChatMessageTypeText {
+		return errors.NewValidation(map[string]string{"message_type": "SMS replies can only be text"})
+	}
+
+	sent, err := s.sms.Reply(ctx, session.TenantID, *session.DIDID, senderID, *session.VisitorPhone, *message.Body)
+	if err != nil {
+		return err
+	}
+	message.SMSMessageID = &sent.ID
+	message.DeliveryStatus = &sent.Status
+	return nil
+}
+
+// OnInboundSMS threads an SMS received on a DID into the open session with
+// the sender's number, starting a queued session if there is none. Sessions
+// no agent has taken are answered by the AI agent until it hands off.
+func (s *chatService) OnInboundSMS(msg *asterisk.SMSMessage) {
+	if msg.DIDID == nil || msg.Body == nil {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	session, err := s.sessionRepo.FindOpenSMS(ctx, msg.TenantID, *msg.DIDID, msg.Sender)
+	if err != nil {
+		session, err = s.createSMSSession(ctx, msg)
+		if err != nil {
+			log.Printf("Error creating chat session for SMS %d: %v", msg.ID, err)
+			return
+		}
+	}
+
+	status := common.SMSStatusReceived
+	message := &chat.ChatMessage{
+		SessionID:      session.ID,
+		SenderType:     "visitor",
+		SenderName:     msg.Sender,
+		MessageType:    common.ChatMessageTypeText,
+		Body:           msg.Body,
+		SMSMessageID:   &msg.ID,
+		DeliveryStatus: &status,
+		CreatedAt:      time.Now(),
+	}
+	if session.VisitorName != nil {
+		message.SenderName = *session.VisitorName
+	}
+	if err := s.messageRepo.Create(ctx, message); err != nil {
+		log.Printf("Error storing SMS %d in chat session %d: %v", msg.ID, session.ID, err)
+		return
+	}
+	s.broadcastMessage(session, message)
+
+	if session.AssignedToID == nil && s.aiAgent != nil && session.Metadata["handoff_reason"] == nil {
+		go s.answerSMS(session, *msg.Body)
+	}
+}
+
+// createSMSSession starts a queued session with the sender of an SMS
+func (s *chatService) createSMSSession(ctx context.Context, msg *asterisk.SMSMessage) (*chat.ChatSession, error) {
+	now := time.Now()
+	phone := msg.Sender
+	session := &chat.ChatSession{
+		TenantID:     msg.TenantID,
+		SessionKey:   s.generateSessionKey(),
+		Channel:      common.ChatChannelSMS,
+		DIDID:        msg.DIDID,
+		VisitorName:  &phone,
+		VisitorPhone: &phone,
+		Status:       common.ChatSessionStatusQueued,
+		QueuedAt:     &now,
+		CreatedAt:    now,
+		UpdatedAt:    now,
+	}
+	if err := s.sessionRepo.Create(ctx, session); err != nil {
+		return nil, err
+	}
+
 	if s.wsHub != nil {
-		payload := map[string]interface{}{
-			"session_id":   sessionID,
-			"message_id":   message.ID,
-			"sender_type":  senderType,
-			"sender_name":  senderName,
-			"message_type": message.MessageType,
-			"body":         message.Body,
-			"timestamp":    message.CreatedAt,
+		s.wsHub.BroadcastToTenant(session.TenantID, "chat.session.started", s.toSessionResponse(session))
+	}
+
+	// With the AI agent on, agents only take the session once it hands off
+	if s.aiAgent == nil {
+		s.assignAvailableAgent(ctx, session)
+	}
+	return session, nil
+}
+
+// answerSMS has the AI agent answer a visitor's SMS, handing the session to
+// an agent when it cannot
+func (s *chatService) answerSMS(session *chat.ChatSession, body string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	reply, err := s.aiAgent.ProcessMessage(ctx, session.TenantID, session.ID, body)
+	if err != nil {
+		log.Printf("Error getting AI reply for chat session %d: %v", session.ID, err)
+		reply = &chat.AIResponse{Action: "handoff", HandoffReason: "AI agent unavailable"}
+	}
+
+	if reply.Action != "handoff" {
+		content := reply.Content
+		if _, err := s.SendMessage(ctx, session.ID, nil, "bot", "AI Assistant", &dto.SendChatMessageRequest{Body: &content}); err != nil {
+			log.Printf("Error sending AI reply in chat session %d: %v", session.ID, err)
 		}
-		s.wsHub.BroadcastToTenant(session.TenantID, "chat.message.new", payload)
+		return
 	}
 
-	return s.toMessageResponse(message), nil
+	content := reply.Content
+	if content == "" {
+		content = "I'd like to connect you with one of our specialists who can better assist you."
+	}
+	if _, err := s.SendMessage(ctx, session.ID, nil, "system", "AI Assistant", &dto.SendChatMessageRequest{Body: &content}); err != nil {
+		log.Printf("Error sending handoff message in chat session %d: %v", session.ID, err)
+	}
+
+	session, err = s.sessionRepo.FindByID(ctx, session.ID)
+	if err != nil {
+		return
+	}
+	if session.Metadata == nil {
+		session.Metadata = common.JSONMap{}
+	}
+	session.Metadata["handoff_reason"] = reply.HandoffReason
+	session.UpdatedAt = time.Now()
+	if err := s.sessionRepo.Update(ctx, session); err != nil {
+		log.Printf("Error recording handoff of chat session %d: %v", session.ID, err)
+	}
+	if session.AssignedToID == nil {
+		s.assignAvailableAgent(ctx, session)
+	}
+}
+
+// OnSMSStatus reflects the delivery status of an SMS reply on its chat message
+func (s *chatService) OnSMSStatus(msg *asterisk.SMSMessage) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	message, err := s.messageRepo.FindBySMSMessage(ctx, msg.ID)
+	if err != nil {
+		return
+	}
+	if err := s.messageRepo.SetDeliveryStatus(ctx, message.ID, msg.Status); err != nil {
+		log.Printf("Error updating delivery status of chat message %d: %v", message.ID, err)
+		return
+	}
+
+	if s.wsHub != nil {
+		s.wsHub.BroadcastToTenant(msg.TenantID, "chat.message.status", map[string]interface{}{
+			"session_id":      message.SessionID,
+			"message_id":      message.ID,
+			"delivery_status": msg.Status,
+		})
+	}
 }
 
 // GetMessages gets messages for a session
@@ -831,7 +1074,30 @@ func (s *chatService) generateSessionKey() string {
 	return fmt.Sprintf("session-%d", time.Now().UnixNano())
 }
 
-func (s *chatService) toWidgetResponse(widget *chat.ChatWidget) *dto.ChatWidgetResponse {
+// validateWidgetSchedule checks the widget's schedule belongs to its tenant
+func (s *chatService) validateWidgetSchedule(ctx context.Context, widget *chat.ChatWidget) error {
+	if widget.ScheduleID == nil {
+		return nil
+	}
+	if _, err := findTenantSchedule(ctx, s.scheduleRepo, widget.TenantID, *widget.ScheduleID); err != nil {
+		return errors.NewValidation("schedule not found")
+	}
+	return nil
+}
+
+// widgetOnline reports whether a widget takes chats at a moment and, when
+// it is only offline for business hours, when it comes back online
+func (s *chatService) widgetOnline(ctx context.Context, widget *chat.ChatWidget, at time.Time) (bool, *time.Time) {
+	if !widget.IsEnabled {
+		return false, nil
+	}
+	if !widget.BusinessHoursEnabled {
+		return true, nil
+	}
+	return scheduleStatus(ctx, s.scheduleRepo, widget.TenantID, widget.ScheduleID, at)
+}
+
+func (s *chatService) toWidgetResponse(ctx context.Context, widget *chat.ChatWidget) *dto.ChatWidgetResponse {
 	response := &dto.ChatWidgetResponse{
 		ID:               widget.ID,
 		TenantID:         widget.TenantID,
@@ -853,7 +1119,12 @@ func (s *chatService) toWidgetResponse(widget *chat.ChatWidget) *dto.ChatWidgetR
 		IsEnabled:        widget.IsEnabled,
 		CreatedAt:        widget.CreatedAt,
 		UpdatedAt:        widget.UpdatedAt,
+
+		BusinessHoursEnabled: widget.BusinessHoursEnabled,
+		ScheduleID:           widget.ScheduleID,
+		OfflineMessage:       widget.OfflineMessage,
 	}
+	response.IsOnline, response.NextOpenAt = s.widgetOnline(ctx, widget, time.Now())
 
 	// Flatten metadata into main response for frontend compatibility
 	if widget.Metadata != nil {
@@ -893,8 +1164,11 @@ func (s *chatService) toSessionResponse(session *chat.ChatSession) *dto.ChatSess
 		TenantID:          session.TenantID,
 		WidgetID:          session.WidgetID,
 		SessionKey:        session.SessionKey,
+		Channel:           session.Channel,
+		DIDID:             session.DIDID,
 		VisitorName:       session.VisitorName,
 		VisitorEmail:      session.VisitorEmail,
+		VisitorPhone:      session.VisitorPhone,
 		Status:            session.Status,
 		AssignedToID:      session.AssignedToID,
 		AssignedToName:    assignedToName,
@@ -902,6 +1176,7 @@ func (s *chatService) toSessionResponse(session *chat.ChatSession) *dto.ChatSess
 		MessageCount:      messageCount,
 		FirstResponseTime: session.FirstResponseTime,
 		Duration:          session.Duration,
+		WrapupTime:        session.WrapupTime,
 		Rating:            session.Rating,
 		CreatedAt:         session.CreatedAt,
 		UpdatedAt:         session.UpdatedAt,
@@ -919,6 +1194,7 @@ func (s *chatService) toMessageResponse(message *chat.ChatMessage) *dto.ChatMess
 		AttachmentURL:  message.AttachmentURL,
 		AttachmentName: message.AttachmentName,
 		IsRead:         message.IsRead,
+		DeliveryStatus: message.DeliveryStatus,
 		CreatedAt:      message.CreatedAt,
 	}
 }
@@ -0,0 +1,232 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/psschand/callcenter/internal/asterisk"
+	"github.com/psschand/callcenter/internal/common"
+	"github.com/psschand/callcenter/internal/dto"
+	"github.com/psschand/callcenter/internal/repository"
+	"github.com/psschand/callcenter/pkg/errors"
+)
+
+// DialplanConfig configures where the DID dialplan is generated
+type DialplanConfig struct {
+	Path    string // file extensions.conf includes, shared with Asterisk
+	AppName string // Stasis application calls are handed to
+}
+
+// DialplanService generates the dialplan that brings calls to each tenant's
+// DIDs into the call center application, so DIDs need no hand edits to
+// extensions.conf
+type DialplanService interface {
+	Preview(ctx context.Context, tenantID string, userID int64) (*dto.DialplanResponse, error)
+	Apply(ctx context.Context, tenantID string, userID int64) (*dto.DialplanResponse, error)
+	OnDIDChanged(did *asterisk.DID)
+}
+
+type dialplanService struct {
+	didRepo     repository.DIDRepository
+	roleRepo    repository.UserRoleRepository
+	callHandler *asterisk.CallHandler
+	config      DialplanConfig
+
+	// Serializes writes of the file
+	mu sync.Mutex
+}
+
+// NewDialplanService creates a new dialplan service
+func NewDialplanService(
+	didRepo repository.DIDRepository,
+	roleRepo repository.UserRoleRepository,
+	callHandler *asterisk.CallHandler,
+	config DialplanConfig,
+) DialplanService {
+	return &dialplanService{
+		didRepo:     didRepo,
+		roleRepo:    roleRepo,
+		callHandler: callHandler,
+		config:      config,
+	}
+}
+
+// Preview renders the dialplan from the database and diffs it against the
+// file Asterisk reads, without changing anything
+func (s *dialplanService) Preview(ctx context.Context, tenantID string, userID int64) (*dto.DialplanResponse, error) {
+	if err := s.checkManageDialplan(ctx, tenantID, userID); err != nil {
+		return nil, err
+	}
+
+	content, skipped, err := s.render(ctx)
+	if err != nil {
+		return nil, err
+	}
+	current, err := s.readCurrent()
+	if err != nil {
+		return nil, err
+	}
+
+	return &dto.DialplanResponse{
+		Path:    s.config.Path,
+		Changed: content != current,
+		Diff:    asterisk.DiffDialplan(s.config.Path, current, content),
+		Content: content,
+		Skipped: skipped,
+	}, nil
+}
+
+// Apply writes the dialplan rendered from the database and reloads it in
+// Asterisk. The reload happens even when the file is unchanged, so Apply
+// also recovers from an earlier failed reload.
+func (s *dialplanService) Apply(ctx context.Context, tenantID string, userID int64) (*dto.DialplanResponse, error) {
+	if err := s.checkManageDialplan(ctx, tenantID, userID); err != nil {
+		return nil, err
+	}
+
+	resp, err := s.regenerate(ctx, true)
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("User %d applied the DID dialplan (changed: %t)", userID, resp.Changed)
+	return resp, nil
+}
+
+// OnDIDChanged regenerates the dialplan after a DID is created, changed or
+// deleted, reloading Asterisk if the file changed
+func (s *dialplanService) OnDIDChanged(did *asterisk.DID) {
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		if _, err := s.regenerate(ctx, false); err != nil {
+			log.Printf("Error regenerating dialplan after DID %s changed: %v", did.Number, err)
+		}
+	}()
+}
+
+// regenerate renders the dialplan and writes it if it changed, reloading
+// Asterisk when it was written or force is set
+func (s *dialplanService) regenerate(ctx context.Context, force bool) (*dto.DialplanResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	content, skipped, err := s.render(ctx)
+	if err != nil {
+		return nil, err
+	}
+	current, err := s.readCurrent()
+	if err != nil {
+		return nil, err
+	}
+
+	changed := content != current
+	resp := &dto.DialplanResponse{
+		Path:    s.config.Path,
+		Changed: changed,
+		Diff:    asterisk.DiffDialplan(s.config.Path, current, content),
+		Skipped: skipped,
+	}
+	if !changed && !force {
+		return resp, nil
+	}
+
+	if changed {
+		if err := writeFileAtomic(s.config.Path, []byte(content)); err != nil {
+			return nil, errors.Wrap(err, "failed to write dialplan")
+		}
+		log.Printf("Wrote DID dialplan to %s", s.config.Path)
+	}
+	if err := s.callHandler.ReloadDialplan(); err != nil {
+		return nil, errors.Wrap(err, "failed to reload dialplan")
+	}
+
+	resp.Applied = true
+	return resp, nil
+}
+
+// render renders the dialplan for every DID, returning the DIDs left out
+func (s *dialplanService) render(ctx context.Context) (string, []dto.DialplanSkippedDID, error) {
+	dids, err := s.didRepo.FindAll(ctx)
+	if err != nil {
+		return "", nil, errors.Wrap(err, "failed to load DIDs")
+	}
+
+	skipped := []dto.DialplanSkippedDID{}
+	for i := range dids {
+		if reason := asterisk.DialplanSkipReason(&dids[i]); reason != "" {
+			skipped = append(skipped, dto.DialplanSkippedDID{
+				ID:       dids[i].ID,
+				TenantID: dids[i].TenantID,
+				Number:   dids[i].Number,
+				Reason:   reason,
+			})
+		}
+	}
+
+	return asterisk.RenderDialplan(s.config.AppName, dids), skipped, nil
+}
+
+// readCurrent reads the dialplan file, which is empty before the first write
+func (s *dialplanService) readCurrent() (string, error) {
+	data, err := os.ReadFile(s.config.Path)
+	if os.IsNotExist(err) {
+		return "", nil
+	}
+	if err != nil {
+		return "", errors.Wrap(err, "failed to read dialplan")
+	}
+	return string(data), nil
+}
+
+// checkManageDialplan checks the user may change the dialplan, which is
+// shared by every tenant
+func (s *dialplanService) checkManageDialplan(ctx context.Context, tenantID string, userID int64) error {
+	role, err := s.roleRepo.FindByUserAndTenant(ctx, userID, tenantID)
+	if err != nil {
+		return errors.NewForbidden("no role in this tenant")
+	}
+	if role.Role != common.RoleSuperAdmin {
+		return errors.NewForbidden("only superadmins may manage the dialplan")
+	}
+	return nil
+}
+
+// writeFileAtomic replaces a file through a temporary file in the same
+// directory, so readers see either the old or the new contents in full
+func writeFileAtomic(path string, data []byte) error {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to replace %s: %w", path, err)
+	}
+	return nil
+}
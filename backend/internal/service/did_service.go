@@ -2,6 +2,7 @@ package service
 
 import (
 	"context"
+	"strconv"
 	"time"
 
 	"github.com/psschand/callcenter/internal/asterisk"
@@ -21,13 +22,22 @@ type DIDService interface {
 	Delete(ctx context.Context, id int64) error
 	UpdateRouting(ctx context.Context, id int64, req *dto.UpdateDIDRoutingRequest) (*dto.DIDResponse, error)
 	GetAvailable(ctx context.Context) ([]dto.DIDResponse, error)
+	SetChangeListener(listener DIDListener)
 }
 
+// DIDListener is told of a DID once it has been created or deleted
+type DIDListener func(did *asterisk.DID)
+
 type didService struct {
-	didRepo    repository.DIDRepository
-	tenantRepo repository.TenantRepository
-	queueRepo  repository.QueueRepository
-	userRepo   repository.UserRepository
+	didRepo      repository.DIDRepository
+	tenantRepo   repository.TenantRepository
+	queueRepo    repository.QueueRepository
+	userRepo     repository.UserRepository
+	ivrRepo      repository.IVRMenuRepository
+	scheduleRepo repository.ScheduleRepository
+	surveyRepo   repository.SurveyRepository
+
+	changeListener DIDListener
 }
 
 // NewDIDService creates a new DID service
@@ -36,12 +46,18 @@ func NewDIDService(
 	tenantRepo repository.TenantRepository,
 	queueRepo repository.QueueRepository,
 	userRepo repository.UserRepository,
+	ivrRepo repository.IVRMenuRepository,
+	scheduleRepo repository.ScheduleRepository,
+	surveyRepo repository.SurveyRepository,
 ) DIDService {
 	return &didService{
-		didRepo:    didRepo,
-		tenantRepo: tenantRepo,
-		queueRepo:  queueRepo,
-		userRepo:   userRepo,
+		didRepo:      didRepo,
+		tenantRepo:   tenantRepo,
+		queueRepo:    queueRepo,
+		userRepo:     userRepo,
+		ivrRepo:      ivrRepo,
+		scheduleRepo: scheduleRepo,
+		surveyRepo:   surveyRepo,
 	}
 }
 
@@ -86,17 +102,34 @@ func (s *didService) Create(ctx context.Context, tenantID string, req *dto.Creat
 		Status:        common.DIDStatusActive,
 		RouteType:     req.RouteType,
 		RouteTarget:   req.RouteTarget,
+		ScheduleID:    req.ScheduleID,
+		SurveyID:      req.SurveyID,
 		SMSEnabled:    req.SMSEnabled,
 		SMSWebhookURL: req.SMSWebhookURL,
 		Metadata:      req.Metadata,
 		CreatedAt:     now,
 		UpdatedAt:     now,
 	}
+	if req.AfterHoursRouteType != nil && *req.AfterHoursRouteType != "" {
+		did.AfterHoursRouteType = req.AfterHoursRouteType
+		did.AfterHoursRouteTarget = req.AfterHoursRouteTarget
+	}
+
+	if err := s.validateAfterHours(ctx, did); err != nil {
+		return nil, err
+	}
+	if err := validateSurveyLink(ctx, s.surveyRepo, did.TenantID, did.SurveyID); err != nil {
+		return nil, err
+	}
 
 	if err := s.didRepo.Create(ctx, did); err != nil {
 		return nil, errors.Wrap(err, "failed to create DID")
 	}
 
+	if s.changeListener != nil {
+		s.changeListener(did)
+	}
+
 	return s.toDIDResponse(did), nil
 }
 
@@ -165,6 +198,34 @@ func (s *didService) Update(ctx context.Context, id int64, req *dto.UpdateDIDReq
 	if req.Metadata != nil {
 		did.Metadata = req.Metadata
 	}
+	if req.ScheduleID != nil {
+		did.ScheduleID = req.ScheduleID
+		if *req.ScheduleID == 0 {
+			did.ScheduleID = nil
+		}
+	}
+	if req.AfterHoursRouteType != nil {
+		did.AfterHoursRouteType = req.AfterHoursRouteType
+		if *req.AfterHoursRouteType == "" {
+			did.AfterHoursRouteType = nil
+		}
+	}
+	if req.AfterHoursRouteTarget != nil {
+		did.AfterHoursRouteTarget = req.AfterHoursRouteTarget
+	}
+	if req.SurveyID != nil {
+		did.SurveyID = req.SurveyID
+		if *req.SurveyID == 0 {
+			did.SurveyID = nil
+		}
+	}
+
+	if err := s.validateAfterHours(ctx, did); err != nil {
+		return nil, err
+	}
+	if err := validateSurveyLink(ctx, s.surveyRepo, did.TenantID, did.SurveyID); err != nil {
+		return nil, err
+	}
 
 	did.UpdatedAt = time.Now()
 
@@ -178,7 +239,7 @@ func (s *didService) Update(ctx context.Context, id int64, req *dto.UpdateDIDReq
 // Delete deletes a DID
 func (s *didService) Delete(ctx context.Context, id int64) error {
 	// Check if DID exists
-	_, err := s.didRepo.FindByID(ctx, id)
+	did, err := s.didRepo.FindByID(ctx, id)
 	if err != nil {
 		return errors.NewNotFound("DID not found")
 	}
@@ -187,6 +248,10 @@ func (s *didService) Delete(ctx context.Context, id int64) error {
 		return errors.Wrap(err, "failed to delete DID")
 	}
 
+	if s.changeListener != nil {
+		s.changeListener(did)
+	}
+
 	return nil
 }
 
@@ -230,6 +295,11 @@ func (s *didService) GetAvailable(ctx context.Context) ([]dto.DIDResponse, error
 	return responses, nil
 }
 
+// SetChangeListener sets the listener told of each DID created or deleted
+func (s *didService) SetChangeListener(listener DIDListener) {
+	s.changeListener = listener
+}
+
 // validateRouting validates routing configuration
 func (s *didService) validateRouting(ctx context.Context, tenantID, routeType, routeDestination string) error {
 	switch routeType {
@@ -242,18 +312,35 @@ func (s *didService) validateRouting(ctx context.Context, tenantID, routeType, r
 		if err != nil || queue == nil {
 			return errors.NewValidation("queue not found")
 		}
-	case "extension":
-		// Validate extension exists
+	case "endpoint":
+		// Validate endpoint exists
 		if routeDestination == "" {
-			return errors.NewValidation("extension is required for extension routing")
+			return errors.NewValidation("endpoint is required for endpoint routing")
+		}
+		// TODO: Validate endpoint exists in tenant
+	case "external":
+		if routeDestination == "" {
+			return errors.NewValidation("phone number is required for external routing")
+		}
+	case "webhook":
+		if err := asterisk.ValidateWebhookURL(routeDestination); err != nil {
+			return errors.NewValidation(err.Error())
 		}
-		// TODO: Validate extension exists in tenant
 	case "ivr":
-		// Validate IVR exists
+		// Validate IVR exists (by ID or name)
 		if routeDestination == "" {
 			return errors.NewValidation("IVR name is required for IVR routing")
 		}
-		// TODO: Validate IVR exists
+		var menu *asterisk.IVRMenu
+		var err error
+		if id, parseErr := strconv.ParseInt(routeDestination, 10, 64); parseErr == nil {
+			menu, err = s.ivrRepo.FindByID(ctx, id)
+		} else {
+			menu, err = s.ivrRepo.FindByName(ctx, tenantID, routeDestination)
+		}
+		if err != nil || menu == nil || menu.TenantID != tenantID {
+			return errors.NewValidation("IVR menu not found")
+		}
 	case "voicemail":
 		// Validate voicemail box exists
 		if routeDestination == "" {
@@ -268,21 +355,45 @@ func (s *didService) validateRouting(ctx context.Context, tenantID, routeType, r
 	return nil
 }
 
+// validateAfterHours validates a DID's schedule and the route calls take
+// while it is closed
+func (s *didService) validateAfterHours(ctx context.Context, did *asterisk.DID) error {
+	if did.ScheduleID == nil {
+		return nil
+	}
+	if _, err := findTenantSchedule(ctx, s.scheduleRepo, did.TenantID, *did.ScheduleID); err != nil {
+		return errors.NewValidation("schedule not found")
+	}
+	if did.AfterHoursRouteType == nil {
+		return errors.NewValidation("an after-hours route is required with a schedule")
+	}
+
+	target := ""
+	if did.AfterHoursRouteTarget != nil {
+		target = *did.AfterHoursRouteTarget
+	}
+	return s.validateRouting(ctx, did.TenantID, string(*did.AfterHoursRouteType), target)
+}
+
 // toDIDResponse converts DID model to response DTO
 func (s *didService) toDIDResponse(did *asterisk.DID) *dto.DIDResponse {
 	return &dto.DIDResponse{
-		ID:            did.ID,
-		TenantID:      did.TenantID,
-		Number:        did.Number,
-		CountryCode:   did.CountryCode,
-		FriendlyName:  did.FriendlyName,
-		Status:        did.Status,
-		RouteType:     did.RouteType,
-		RouteTarget:   did.RouteTarget,
-		SMSEnabled:    did.SMSEnabled,
-		SMSWebhookURL: did.SMSWebhookURL,
-		Metadata:      did.Metadata,
-		CreatedAt:     did.CreatedAt,
-		UpdatedAt:     did.UpdatedAt,
+		ID:                    did.ID,
+		TenantID:              did.TenantID,
+		Number:                did.Number,
+		CountryCode:           did.CountryCode,
+		FriendlyName:          did.FriendlyName,
+		Status:                did.Status,
+		RouteType:             did.RouteType,
+		RouteTarget:           did.RouteTarget,
+		ScheduleID:            did.ScheduleID,
+		AfterHoursRouteType:   did.AfterHoursRouteType,
+		AfterHoursRouteTarget: did.AfterHoursRouteTarget,
+		SurveyID:              did.SurveyID,
+		SMSEnabled:            did.SMSEnabled,
+		SMSWebhookURL:         did.SMSWebhookURL,
+		Metadata:              did.Metadata,
+		CreatedAt:             did.CreatedAt,
+		UpdatedAt:             did.UpdatedAt,
 	}
 }
@@ -0,0 +1,401 @@
+package service
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/psschand/callcenter/internal/asterisk"
+	"github.com/psschand/callcenter/internal/dto"
+	"github.com/psschand/callcenter/internal/repository"
+	"github.com/psschand/callcenter/pkg/errors"
+)
+
+// IVRService handles IVR menu operations
+type IVRService interface {
+	Create(ctx context.Context, tenantID string, req *dto.CreateIVRMenuRequest) (*dto.IVRMenuResponse, error)
+	GetByID(ctx context.Context, tenantID string, id int64) (*dto.IVRMenuResponse, error)
+	GetByTenant(ctx context.Context, tenantID string, page, pageSize int) ([]dto.IVRMenuResponse, int64, error)
+	Update(ctx context.Context, tenantID string, id int64, req *dto.UpdateIVRMenuRequest) (*dto.IVRMenuResponse, error)
+	Delete(ctx context.Context, tenantID string, id int64) error
+	AddOption(ctx context.Context, tenantID string, menuID int64, req *dto.CreateIVROptionRequest) (*dto.IVROptionResponse, error)
+	UpdateOption(ctx context.Context, tenantID string, menuID, optionID int64, req *dto.UpdateIVROptionRequest) (*dto.IVROptionResponse, error)
+	DeleteOption(ctx context.Context, tenantID string, menuID, optionID int64) error
+}
+
+type ivrService struct {
+	ivrRepo    repository.IVRMenuRepository
+	tenantRepo repository.TenantRepository
+	queueRepo  repository.QueueRepository
+}
+
+// NewIVRService creates a new IVR service
+func NewIVRService(
+	ivrRepo repository.IVRMenuRepository,
+	tenantRepo repository.TenantRepository,
+	queueRepo repository.QueueRepository,
+) IVRService {
+	return &ivrService{
+		ivrRepo:    ivrRepo,
+		tenantRepo: tenantRepo,
+		queueRepo:  queueRepo,
+	}
+}
+
+// Create creates a new IVR menu with its options
+func (s *ivrService) Create(ctx context.Context, tenantID string, req *dto.CreateIVRMenuRequest) (*dto.IVRMenuResponse, error) {
+	// Validate tenant exists
+	if _, err := s.tenantRepo.FindByID(ctx, tenantID); err != nil {
+		return nil, errors.NewNotFound("tenant not found")
+	}
+
+	// Check if menu name already exists
+	existing, _ := s.ivrRepo.FindByName(ctx, tenantID, req.Name)
+	if existing != nil {
+		return nil, errors.NewValidation("IVR menu with this name already exists")
+	}
+
+	// Validate options before creating anything
+	seen := make(map[string]bool)
+	for _, opt := range req.Options {
+		if seen[opt.Digit] {
+			return nil, errors.NewValidation("duplicate option digit: " + opt.Digit)
+		}
+		seen[opt.Digit] = true
+
+		if err := s.validateOption(ctx, tenantID, 0, opt.Digit, opt.Action, opt.ActionData); err != nil {
+			return nil, err
+		}
+	}
+
+	now := time.Now()
+	menu := &asterisk.IVRMenu{
+		TenantID:         tenantID,
+		Name:             req.Name,
+		Description:      req.Description,
+		GreetingAudioURL: req.GreetingAudioURL,
+		GreetingText:     req.GreetingText,
+		Timeout:          req.Timeout,
+		MaxAttempts:      req.MaxAttempts,
+		InvalidAudioURL:  req.InvalidAudioURL,
+		TimeoutAudioURL:  req.TimeoutAudioURL,
+		IsActive:         true,
+		CreatedAt:        now,
+		UpdatedAt:        now,
+	}
+
+	// Set defaults if not provided
+	if menu.Timeout == 0 {
+		menu.Timeout = 5
+	}
+	if menu.MaxAttempts == 0 {
+		menu.MaxAttempts = 3
+	}
+
+	for _, opt := range req.Options {
+		menu.Options = append(menu.Options, asterisk.IVROption{
+			Digit:       opt.Digit,
+			Action:      opt.Action,
+			ActionData:  opt.ActionData,
+			Description: opt.Description,
+			SortOrder:   opt.SortOrder,
+			CreatedAt:   now,
+			UpdatedAt:   now,
+		})
+	}
+
+	if err := s.ivrRepo.Create(ctx, menu); err != nil {
+		return nil, errors.Wrap(err, "failed to create IVR menu")
+	}
+
+	return s.toIVRMenuResponse(menu), nil
+}
+
+// GetByID gets an IVR menu with its options
+func (s *ivrService) GetByID(ctx context.Context, tenantID string, id int64) (*dto.IVRMenuResponse, error) {
+	menu, err := s.findMenu(ctx, tenantID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.toIVRMenuResponse(menu), nil
+}
+
+// GetByTenant gets all IVR menus for a tenant
+func (s *ivrService) GetByTenant(ctx context.Context, tenantID string, page, pageSize int) ([]dto.IVRMenuResponse, int64, error) {
+	menus, total, err := s.ivrRepo.FindByTenant(ctx, tenantID, page, pageSize)
+	if err != nil {
+		return nil, 0, errors.Wrap(err, "failed to get IVR menus")
+	}
+
+	responses := make([]dto.IVRMenuResponse, len(menus))
+	for i, menu := range menus {
+		responses[i] = *s.toIVRMenuResponse(&menu)
+	}
+
+	return responses, total, nil
+}
+
+// Update updates an IVR menu
+func (s *ivrService) Update(ctx context.Context, tenantID string, id int64, req *dto.UpdateIVRMenuRequest) (*dto.IVRMenuResponse, error) {
+	menu, err := s.findMenu(ctx, tenantID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	// Update fields
+	if req.Name != nil && *req.Name != menu.Name {
+		existing, _ := s.ivrRepo.FindByName(ctx, tenantID, *req.Name)
+		if existing != nil {
+			return nil, errors.NewValidation("IVR menu with this name already exists")
+		}
+		menu.Name = *req.Name
+	}
+	if req.Description != nil {
+		menu.Description = req.Description
+	}
+	if req.GreetingAudioURL != nil {
+		menu.GreetingAudioURL = req.GreetingAudioURL
+	}
+	if req.GreetingText != nil {
+		menu.GreetingText = req.GreetingText
+	}
+	if req.Timeout != nil {
+		menu.Timeout = *req.Timeout
+	}
+	if req.MaxAttempts != nil {
+		menu.MaxAttempts = *req.MaxAttempts
+	}
+	if req.InvalidAudioURL != nil {
+		menu.InvalidAudioURL = req.InvalidAudioURL
+	}
+	if req.TimeoutAudioURL != nil {
+		menu.TimeoutAudioURL = req.TimeoutAudioURL
+	}
+	if req.IsActive != nil {
+		menu.IsActive = *req.IsActive
+	}
+
+	menu.UpdatedAt = time.Now()
+
+	if err := s.ivrRepo.Update(ctx, menu); err != nil {
+		return nil, errors.Wrap(err, "failed to update IVR menu")
+	}
+
+	return s.toIVRMenuResponse(menu), nil
+}
+
+// Delete deletes an IVR menu and its options
+func (s *ivrService) Delete(ctx context.Context, tenantID string, id int64) error {
+	if _, err := s.findMenu(ctx, tenantID, id); err != nil {
+		return err
+	}
+
+	if err := s.ivrRepo.Delete(ctx, id); err != nil {
+		return errors.Wrap(err, "failed to delete IVR menu")
+	}
+
+	return nil
+}
+
+// AddOption adds an option to an IVR menu
+func (s *ivrService) AddOption(ctx context.Context, tenantID string, menuID int64, req *dto.CreateIVROptionRequest) (*dto.IVROptionResponse, error) {
+	if _, err := s.findMenu(ctx, tenantID, menuID); err != nil {
+		return nil, err
+	}
+
+	existing, _ := s.ivrRepo.FindOptionByDigit(ctx, menuID, req.Digit)
+	if existing != nil {
+		return nil, errors.NewValidation("option for this digit already exists")
+	}
+
+	if err := s.validateOption(ctx, tenantID, menuID, req.Digit, req.Action, req.ActionData); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	option := &asterisk.IVROption{
+		IVRMenuID:   menuID,
+		Digit:       req.Digit,
+		Action:      req.Action,
+		ActionData:  req.ActionData,
+		Description: req.Description,
+		SortOrder:   req.SortOrder,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+
+	if err := s.ivrRepo.CreateOption(ctx, option); err != nil {
+		return nil, errors.Wrap(err, "failed to create IVR option")
+	}
+
+	return s.toIVROptionResponse(option), nil
+}
+
+// UpdateOption updates an IVR menu option
+func (s *ivrService) UpdateOption(ctx context.Context, tenantID string, menuID, optionID int64, req *dto.UpdateIVROptionRequest) (*dto.IVROptionResponse, error) {
+	option, err := s.findOption(ctx, tenantID, menuID, optionID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Update fields
+	if req.Digit != nil && *req.Digit != option.Digit {
+		existing, _ := s.ivrRepo.FindOptionByDigit(ctx, menuID, *req.Digit)
+		if existing != nil {
+			return nil, errors.NewValidation("option for this digit already exists")
+		}
+		option.Digit = *req.Digit
+	}
+	if req.Action != nil {
+		option.Action = *req.Action
+	}
+	if req.ActionData != nil {
+		option.ActionData = req.ActionData
+	}
+	if req.Description != nil {
+		option.Description = req.Description
+	}
+	if req.SortOrder != nil {
+		option.SortOrder = *req.SortOrder
+	}
+
+	if err := s.validateOption(ctx, tenantID, menuID, option.Digit, option.Action, option.ActionData); err != nil {
+		return nil, err
+	}
+
+	option.UpdatedAt = time.Now()
+
+	if err := s.ivrRepo.UpdateOption(ctx, option); err != nil {
+		return nil, errors.Wrap(err, "failed to update IVR option")
+	}
+
+	return s.toIVROptionResponse(option), nil
+}
+
+// DeleteOption deletes an IVR menu option
+func (s *ivrService) DeleteOption(ctx context.Context, tenantID string, menuID, optionID int64) error {
+	if _, err := s.findOption(ctx, tenantID, menuID, optionID); err != nil {
+		return err
+	}
+
+	if err := s.ivrRepo.DeleteOption(ctx, optionID); err != nil {
+		return errors.Wrap(err, "failed to delete IVR option")
+	}
+
+	return nil
+}
+
+// findMenu loads a menu with its options, hiding menus of other tenants
+func (s *ivrService) findMenu(ctx context.Context, tenantID string, id int64) (*asterisk.IVRMenu, error) {
+	menu, err := s.ivrRepo.FindWithOptions(ctx, id)
+	if err != nil || menu.TenantID != tenantID {
+		return nil, errors.NewNotFound("IVR menu not found")
+	}
+	return menu, nil
+}
+
+// findOption loads an option of a menu owned by the tenant
+func (s *ivrService) findOption(ctx context.Context, tenantID string, menuID, optionID int64) (*asterisk.IVROption, error) {
+	if _, err := s.findMenu(ctx, tenantID, menuID); err != nil {
+		return nil, err
+	}
+
+	option, err := s.ivrRepo.FindOptionByID(ctx, optionID)
+	if err != nil || option.IVRMenuID != menuID {
+		return nil, errors.NewNotFound("IVR option not found")
+	}
+	return option, nil
+}
+
+// validateOption validates an option's digit and action target
+func (s *ivrService) validateOption(ctx context.Context, tenantID string, menuID int64, digit, action string, actionData *string) error {
+	if digit != asterisk.IVRDigitTimeout && digit != asterisk.IVRDigitInvalid {
+		if strings.Trim(digit, "0123456789*#") != "" {
+			return errors.NewValidation("digit must be DTMF digits (0-9, *, #) or t/i")
+		}
+	}
+
+	data := ""
+	if actionData != nil {
+		data = *actionData
+	}
+
+	switch action {
+	case asterisk.IVRActionHangup:
+		return nil
+	case asterisk.IVRActionQueue:
+		if data == "" {
+			return errors.NewValidation("queue name is required for queue option")
+		}
+		queue, err := s.queueRepo.FindByName(ctx, tenantID, data)
+		if err != nil || queue == nil {
+			return errors.NewValidation("queue not found")
+		}
+	case asterisk.IVRActionSubmenu:
+		id, err := strconv.ParseInt(data, 10, 64)
+		if err != nil {
+			return errors.NewValidation("submenu option requires the IVR menu ID")
+		}
+		if id == menuID {
+			return errors.NewValidation("submenu cannot point to its own menu")
+		}
+		submenu, err := s.ivrRepo.FindByID(ctx, id)
+		if err != nil || submenu.TenantID != tenantID {
+			return errors.NewValidation("submenu not found")
+		}
+	case asterisk.IVRActionWebhook:
+		if err := asterisk.ValidateWebhookURL(data); err != nil {
+			return errors.NewValidation(err.Error())
+		}
+	case asterisk.IVRActionEndpoint, asterisk.IVRActionVoicemail, asterisk.IVRActionExternal:
+		if data == "" {
+			return errors.NewValidation("action_data is required for " + action + " option")
+		}
+	default:
+		return errors.NewValidation("invalid IVR action")
+	}
+
+	return nil
+}
+
+// toIVRMenuResponse converts IVR menu model to response DTO
+func (s *ivrService) toIVRMenuResponse(menu *asterisk.IVRMenu) *dto.IVRMenuResponse {
+	resp := &dto.IVRMenuResponse{
+		ID:               menu.ID,
+		TenantID:         menu.TenantID,
+		Name:             menu.Name,
+		Description:      menu.Description,
+		GreetingAudioURL: menu.GreetingAudioURL,
+		GreetingText:     menu.GreetingText,
+		Timeout:          menu.Timeout,
+		MaxAttempts:      menu.MaxAttempts,
+		InvalidAudioURL:  menu.InvalidAudioURL,
+		TimeoutAudioURL:  menu.TimeoutAudioURL,
+		IsActive:         menu.IsActive,
+		CreatedAt:        menu.CreatedAt,
+		UpdatedAt:        menu.UpdatedAt,
+	}
+
+	for i := range menu.Options {
+		resp.Options = append(resp.Options, *s.toIVROptionResponse(&menu.Options[i]))
+	}
+
+	return resp
+}
+
+// toIVROptionResponse converts IVR option model to response DTO
+func (s *ivrService) toIVROptionResponse(option *asterisk.IVROption) *dto.IVROptionResponse {
+	return &dto.IVROptionResponse{
+		ID:          option.ID,
+		IVRMenuID:   option.IVRMenuID,
+		Digit:       option.Digit,
+		Action:      option.Action,
+		ActionData:  option.ActionData,
+		Description: option.Description,
+		SortOrder:   option.SortOrder,
+		CreatedAt:   option.CreatedAt,
+		UpdatedAt:   option.UpdatedAt,
+	}
+}
@@ -0,0 +1,321 @@
+package service
+
+import (
+	"context"
+	stderrors "errors"
+	"log"
+	"time"
+
+	"github.com/psschand/callcenter/internal/asterisk"
+	"github.com/psschand/callcenter/internal/core"
+	"github.com/psschand/callcenter/internal/dto"
+	"github.com/psschand/callcenter/internal/repository"
+	"github.com/psschand/callcenter/pkg/errors"
+)
+
+// MonitorService handles supervisors listening to, whispering on and barging into live calls
+type MonitorService interface {
+	Start(ctx context.Context, tenantID string, userID int64, req *dto.StartMonitorRequest) (*dto.MonitorSessionResponse, error)
+	SetMode(ctx context.Context, tenantID string, userID, id int64, req *dto.UpdateMonitorModeRequest) (*dto.MonitorSessionResponse, error)
+	Stop(ctx context.Context, tenantID string, userID, id int64) (*dto.MonitorSessionResponse, error)
+	GetByID(ctx context.Context, tenantID string, id int64) (*dto.MonitorSessionResponse, error)
+	GetByTenant(ctx context.Context, tenantID string, supervisorID int64, page, pageSize int) ([]*dto.MonitorSessionResponse, int64, error)
+	OnMonitorSession(sessionID int64, status, reason string)
+	CloseOrphaned(ctx context.Context) error
+}
+
+type monitorService struct {
+	callHandler  *asterisk.CallHandler
+	monitorRepo  repository.MonitorSessionRepository
+	userRoleRepo repository.UserRoleRepository
+}
+
+// NewMonitorService creates a new monitor service
+func NewMonitorService(
+	callHandler *asterisk.CallHandler,
+	monitorRepo repository.MonitorSessionRepository,
+	userRoleRepo repository.UserRoleRepository,
+) MonitorService {
+	return &monitorService{
+		callHandler:  callHandler,
+		monitorRepo:  monitorRepo,
+		userRoleRepo: userRoleRepo,
+	}
+}
+
+// Start rings the supervisor's phone and connects it to a live call
+func (s *monitorService) Start(ctx context.Context, tenantID string, userID int64, req *dto.StartMonitorRequest) (*dto.MonitorSessionResponse, error) {
+	mode := req.Mode
+	if mode == "" {
+		mode = asterisk.MonitorModeListen
+	}
+
+	role, err := s.checkPermission(ctx, tenantID, userID, mode)
+	if err != nil {
+		return nil, err
+	}
+	if role.EndpointID == nil || *role.EndpointID == "" {
+		return nil, errors.NewValidation(map[string]string{"endpoint": "no phone endpoint is assigned to this user"})
+	}
+
+	owner, ok := s.callHandler.ChannelTenant(req.ChannelID)
+	if !ok || owner != tenantID {
+		return nil, errors.NewNotFound("call not found")
+	}
+	target, ok := s.callHandler.MonitorTarget(req.ChannelID)
+	if !ok {
+		return nil, errors.NewNotFound("call not found")
+	}
+
+	// The session is logged before the supervisor's phone rings so that every
+	// attempt leaves a trace, including ones that fail
+	session := &asterisk.MonitorSession{
+		TenantID:           tenantID,
+		SupervisorID:       userID,
+		SupervisorEndpoint: *role.EndpointID,
+		TargetChannelID:    target.ID,
+		TargetChannel:      &target.Name,
+		Mode:               mode,
+		Status:             asterisk.MonitorStatusRinging,
+		StartedAt:          time.Now(),
+	}
+	if target.Caller.Number != "" {
+		session.CallerNumber = &target.Caller.Number
+	}
+	if err := s.monitorRepo.Create(ctx, session); err != nil {
+		return nil, errors.Wrap(err, "failed to create monitor session")
+	}
+	s.logEvent(ctx, session.ID, asterisk.MonitorEventStarted, &mode, &userID)
+
+	if err := s.callHandler.StartMonitor(session.ID, req.ChannelID, *role.EndpointID, mode); err != nil {
+		s.end(ctx, session, asterisk.MonitorEndFailed)
+		if stderrors.Is(err, asterisk.ErrCallNotFound) {
+			return nil, errors.NewNotFound("call not found")
+		}
+		return nil, errors.Wrap(err, "failed to start monitoring")
+	}
+
+	return toMonitorSessionResponse(session), nil
+}
+
+// SetMode switches a live monitor session between listen, whisper and barge
+func (s *monitorService) SetMode(ctx context.Context, tenantID string, userID, id int64, req *dto.UpdateMonitorModeRequest) (*dto.MonitorSessionResponse, error) {
+	session, err := s.findSession(ctx, tenantID, id)
+	if err != nil {
+		return nil, err
+	}
+	if session.IsEnded() {
+		return nil, errors.NewConflict("monitor session has ended")
+	}
+	if _, err := s.checkPermission(ctx, tenantID, userID, req.Mode); err != nil {
+		return nil, err
+	}
+	if session.Mode == req.Mode {
+		return toMonitorSessionResponse(session), nil
+	}
+
+	if err := s.callHandler.SetMonitorMode(session.ID, req.Mode); err != nil {
+		return nil, monitorError(err, "failed to switch monitor mode")
+	}
+
+	session.Mode = req.Mode
+	if err := s.monitorRepo.UpdateMode(ctx, session.ID, req.Mode); err != nil {
+		return nil, errors.Wrap(err, "failed to update monitor session")
+	}
+	s.logEvent(ctx, session.ID, asterisk.MonitorEventModeChanged, &req.Mode, &userID)
+
+	return toMonitorSessionResponse(session), nil
+}
+
+// Stop ends a monitor session
+func (s *monitorService) Stop(ctx context.Context, tenantID string, userID, id int64) (*dto.MonitorSessionResponse, error) {
+	session, err := s.findSession(ctx, tenantID, id)
+	if err != nil {
+		return nil, err
+	}
+	if session.IsEnded() {
+		return toMonitorSessionResponse(session), nil
+	}
+	if session.SupervisorID != userID {
+		if _, err := s.checkPermission(ctx, tenantID, userID, asterisk.MonitorModeBarge); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := s.callHandler.StopMonitor(session.ID); err != nil && !stderrors.Is(err, asterisk.ErrMonitorNotFound) {
+		return nil, errors.Wrap(err, "failed to stop monitoring")
+	}
+	s.end(ctx, session, asterisk.MonitorEndStopped)
+
+	return toMonitorSessionResponse(session), nil
+}
+
+// GetByID gets a monitor session with its audit trail
+func (s *monitorService) GetByID(ctx context.Context, tenantID string, id int64) (*dto.MonitorSessionResponse, error) {
+	session, err := s.monitorRepo.FindWithEvents(ctx, id)
+	if err != nil || session.TenantID != tenantID {
+		return nil, errors.NewNotFound("monitor session not found")
+	}
+	return toMonitorSessionResponse(session), nil
+}
+
+// GetByTenant lists monitor sessions, optionally for one supervisor
+func (s *monitorService) GetByTenant(ctx context.Context, tenantID string, supervisorID int64, page, pageSize int) ([]*dto.MonitorSessionResponse, int64, error) {
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	sessions, total, err := s.monitorRepo.FindByTenant(ctx, tenantID, supervisorID, page, pageSize)
+	if err != nil {
+		return nil, 0, errors.Wrap(err, "failed to get monitor sessions")
+	}
+
+	responses := make([]*dto.MonitorSessionResponse, len(sessions))
+	for i := range sessions {
+		responses[i] = toMonitorSessionResponse(&sessions[i])
+	}
+	return responses, total, nil
+}
+
+// OnMonitorSession records the supervisor answering or the session ending
+func (s *monitorService) OnMonitorSession(sessionID int64, status, reason string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	session, err := s.monitorRepo.FindByID(ctx, sessionID)
+	if err != nil {
+		log.Printf("Error loading monitor session %d: %v", sessionID, err)
+		return
+	}
+	if session.IsEnded() {
+		return
+	}
+
+	switch status {
+	case asterisk.MonitorStatusActive:
+		now := time.Now()
+		session.Status = asterisk.MonitorStatusActive
+		session.AnsweredAt = &now
+		if err := s.monitorRepo.Update(ctx, session); err != nil {
+			log.Printf("Error updating monitor session %d: %v", sessionID, err)
+		}
+		s.logEvent(ctx, sessionID, asterisk.MonitorEventAnswered, &session.Mode, nil)
+	case asterisk.MonitorStatusEnded:
+		s.end(ctx, session, reason)
+	}
+}
+
+// CloseOrphaned ends sessions left open by a restart; the calls they monitored are gone
+func (s *monitorService) CloseOrphaned(ctx context.Context) error {
+	sessions, err := s.monitorRepo.FindOpen(ctx)
+	if err != nil {
+		return err
+	}
+	for i := range sessions {
+		s.end(ctx, &sessions[i], "restart")
+	}
+	return nil
+}
+
+// checkPermission checks the user may monitor in a mode: listening needs
+// can_listen_calls, talking to the agent or the caller needs can_coach_agents
+func (s *monitorService) checkPermission(ctx context.Context, tenantID string, userID int64, mode string) (*core.UserRole, error) {
+	role, err := s.userRoleRepo.FindByUserAndTenant(ctx, userID, tenantID)
+	if err != nil {
+		return nil, errors.NewForbidden("no role in this tenant")
+	}
+
+	switch mode {
+	case asterisk.MonitorModeListen:
+		if !role.Permissions.CanListenCalls {
+			return nil, errors.NewForbidden("not allowed to listen to calls")
+		}
+	case asterisk.MonitorModeWhisper, asterisk.MonitorModeBarge:
+		if !role.Permissions.CanCoachAgents {
+			return nil, errors.NewForbidden("not allowed to coach agents")
+		}
+	default:
+		return nil, errors.NewValidation(map[string]string{"mode": "must be listen, whisper or barge"})
+	}
+	return role, nil
+}
+
+// findSession loads a tenant's monitor session
+func (s *monitorService) findSession(ctx context.Context, tenantID string, id int64) (*asterisk.MonitorSession, error) {
+	session, err := s.monitorRepo.FindByID(ctx, id)
+	if err != nil || session.TenantID != tenantID {
+		return nil, errors.NewNotFound("monitor session not found")
+	}
+	return session, nil
+}
+
+// end marks a session ended and logs it
+func (s *monitorService) end(ctx context.Context, session *asterisk.MonitorSession, reason string) {
+	now := time.Now()
+	session.Status = asterisk.MonitorStatusEnded
+	session.EndedAt = &now
+	session.EndReason = &reason
+	if err := s.monitorRepo.Update(ctx, session); err != nil {
+		log.Printf("Error ending monitor session %d: %v", session.ID, err)
+		return
+	}
+	s.logEvent(ctx, session.ID, asterisk.MonitorEventEnded, nil, nil)
+}
+
+// logEvent appends to a session's audit trail
+func (s *monitorService) logEvent(ctx context.Context, sessionID int64, event string, mode *string, userID *int64) {
+	entry := &asterisk.MonitorSessionEvent{
+		SessionID: sessionID,
+		Event:     event,
+		Mode:      mode,
+		UserID:    userID,
+	}
+	if err := s.monitorRepo.CreateEvent(ctx, entry); err != nil {
+		log.Printf("Error logging monitor session %d %s: %v", sessionID, event, err)
+	}
+}
+
+// monitorError maps monitor engine errors to API errors
+func monitorError(err error, message string) error {
+	switch {
+	case stderrors.Is(err, asterisk.ErrMonitorNotFound):
+		return errors.NewConflict("monitor session has ended")
+	case stderrors.Is(err, asterisk.ErrInvalidMonitorMode):
+		return errors.NewValidation(map[string]string{"mode": "must be listen, whisper or barge"})
+	default:
+		return errors.Wrap(err, message)
+	}
+}
+
+// toMonitorSessionResponse converts a monitor session to its response
+func toMonitorSessionResponse(session *asterisk.MonitorSession) *dto.MonitorSessionResponse {
+	resp := &dto.MonitorSessionResponse{
+		ID:                 session.ID,
+		SupervisorID:       session.SupervisorID,
+		SupervisorEndpoint: session.SupervisorEndpoint,
+		TargetChannelID:    session.TargetChannelID,
+		TargetChannel:      session.TargetChannel,
+		CallerNumber:       session.CallerNumber,
+		Mode:               session.Mode,
+		Status:             session.Status,
+		EndReason:          session.EndReason,
+		StartedAt:          session.StartedAt,
+		AnsweredAt:         session.AnsweredAt,
+		EndedAt:            session.EndedAt,
+	}
+	if session.Supervisor != nil {
+		resp.SupervisorName = session.Supervisor.GetFullName()
+	}
+	for _, event := range session.Events {
+		resp.Events = append(resp.Events, dto.MonitorSessionEventResponse{
+			Event:     event.Event,
+			Mode:      event.Mode,
+			UserID:    event.UserID,
+			CreatedAt: event.CreatedAt,
+		})
+	}
+	return resp
+}
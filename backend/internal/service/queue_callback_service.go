@@ -0,0 +1,240 @@
+package service
+
+import (
+	"context"
+	stderrors "errors"
+	"log"
+	"time"
+
+	"github.com/psschand/callcenter/internal/asterisk"
+	"github.com/psschand/callcenter/internal/dto"
+	"github.com/psschand/callcenter/internal/repository"
+	"github.com/psschand/callcenter/pkg/errors"
+)
+
+// QueueCallbackService lets supervisors follow and cancel the callbacks
+// callers requested instead of waiting on hold
+type QueueCallbackService interface {
+	List(ctx context.Context, tenantID string, userID int64, query *dto.QueueCallbackQuery, page, pageSize int) ([]dto.QueueCallbackResponse, int64, error)
+	Get(ctx context.Context, tenantID string, userID, id int64) (*dto.QueueCallbackResponse, error)
+	Cancel(ctx context.Context, tenantID string, userID, id int64) (*dto.QueueCallbackResponse, error)
+	RestoreOrphaned(ctx context.Context) error
+}
+
+type queueCallbackService struct {
+	callbackRepo repository.QueueCallbackRepository
+	didRepo      repository.DIDRepository
+	roleRepo     repository.UserRoleRepository
+	callHandler  *asterisk.CallHandler
+}
+
+// NewQueueCallbackService creates a new queue callback service
+func NewQueueCallbackService(
+	callbackRepo repository.QueueCallbackRepository,
+	didRepo repository.DIDRepository,
+	roleRepo repository.UserRoleRepository,
+	callHandler *asterisk.CallHandler,
+) QueueCallbackService {
+	return &queueCallbackService{
+		callbackRepo: callbackRepo,
+		didRepo:      didRepo,
+		roleRepo:     roleRepo,
+		callHandler:  callHandler,
+	}
+}
+
+// List lists the tenant's callbacks, newest first
+func (s *queueCallbackService) List(ctx context.Context, tenantID string, userID int64, query *dto.QueueCallbackQuery, page, pageSize int) ([]dto.QueueCallbackResponse, int64, error) {
+	if err := s.checkCallbacks(ctx, tenantID, userID, false); err != nil {
+		return nil, 0, err
+	}
+	if query.Status != "" && !asterisk.IsValidCallbackStatus(query.Status) {
+		return nil, 0, errors.NewValidation(map[string]string{"status": "must be pending, dialing, connected, completed, failed or cancelled"})
+	}
+	if !query.StartDate.IsZero() && !query.EndDate.IsZero() && query.EndDate.Before(query.StartDate) {
+		return nil, 0, errors.NewValidation(map[string]string{"end_date": "must not be before start_date"})
+	}
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	filter := repository.QueueCallbackFilter{
+		QueueName: query.QueueName,
+		Status:    query.Status,
+		Start:     query.StartDate,
+		End:       query.EndDate,
+	}
+	callbacks, total, err := s.callbackRepo.FindByTenant(ctx, tenantID, filter, page, pageSize)
+	if err != nil {
+		return nil, 0, errors.Wrap(err, "failed to get callbacks")
+	}
+
+	result := make([]dto.QueueCallbackResponse, len(callbacks))
+	for i := range callbacks {
+		result[i] = *toQueueCallbackResponse(&callbacks[i])
+	}
+	return result, total, nil
+}
+
+// Get gets one of the tenant's callbacks
+func (s *queueCallbackService) Get(ctx context.Context, tenantID string, userID, id int64) (*dto.QueueCallbackResponse, error) {
+	if err := s.checkCallbacks(ctx, tenantID, userID, false); err != nil {
+		return nil, err
+	}
+	callback, err := s.findCallback(ctx, tenantID, id)
+	if err != nil {
+		return nil, err
+	}
+	return toQueueCallbackResponse(callback), nil
+}
+
+// Cancel takes a callback out of its queue before it is dialed
+func (s *queueCallbackService) Cancel(ctx context.Context, tenantID string, userID, id int64) (*dto.QueueCallbackResponse, error) {
+	if err := s.checkCallbacks(ctx, tenantID, userID, true); err != nil {
+		return nil, err
+	}
+	callback, err := s.findCallback(ctx, tenantID, id)
+	if err != nil {
+		return nil, err
+	}
+	if !callback.IsOpen() {
+		return nil, errors.NewConflict("callback has already ended")
+	}
+
+	// A callback missing from the queues, such as one that could not be
+	// restored after a restart, only has its record left to close
+	err = s.callHandler.CancelQueueCallback(tenantID, id)
+	if stderrors.Is(err, asterisk.ErrCallbackInProgress) {
+		return nil, errors.NewConflict("callback is already being dialed")
+	}
+	if err != nil && !stderrors.Is(err, asterisk.ErrCallbackNotQueued) {
+		return nil, errors.Wrap(err, "failed to cancel callback")
+	}
+
+	now := time.Now()
+	callback.Status = asterisk.CallbackStatusCancelled
+	callback.NextAttemptAt = nil
+	callback.EndedAt = &now
+	if err := s.callbackRepo.Update(ctx, callback); err != nil {
+		return nil, errors.Wrap(err, "failed to cancel callback")
+	}
+
+	return toQueueCallbackResponse(callback), nil
+}
+
+// RestoreOrphaned puts the callbacks left open by a restart back in their
+// queues, oldest first, so that their callers keep their place. Callbacks
+// that were connected are completed, and those whose queue no longer exists
+// fail.
+func (s *queueCallbackService) RestoreOrphaned(ctx context.Context) error {
+	callbacks, err := s.callbackRepo.FindOpen(ctx)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	restored := 0
+	for i := range callbacks {
+		callback := &callbacks[i]
+		if callback.Status == asterisk.CallbackStatusConnected {
+			callback.Status = asterisk.CallbackStatusCompleted
+			callback.EndedAt = &now
+			s.saveOrphaned(ctx, callback)
+			continue
+		}
+
+		// Saved before it is queued: from then on the ACD owns the record
+		callback.Status = asterisk.CallbackStatusPending
+		callback.NextAttemptAt = nil
+		s.saveOrphaned(ctx, callback)
+
+		if err := s.callHandler.RestoreQueueCallback(callback, s.callbackDID(ctx, callback)); err != nil {
+			reason := "queue no longer exists"
+			callback.Status = asterisk.CallbackStatusFailed
+			callback.LastError = &reason
+			callback.EndedAt = &now
+			s.saveOrphaned(ctx, callback)
+			continue
+		}
+		restored++
+	}
+
+	if len(callbacks) > 0 {
+		log.Printf("Restored %d of %d open queue callbacks", restored, len(callbacks))
+	}
+	return nil
+}
+
+// saveOrphaned saves a callback left open by a restart
+func (s *queueCallbackService) saveOrphaned(ctx context.Context, callback *asterisk.QueueCallback) {
+	if err := s.callbackRepo.Update(ctx, callback); err != nil {
+		log.Printf("Error saving orphaned callback %d: %v", callback.ID, err)
+	}
+}
+
+// callbackDID loads the DID a callback's caller dialed; the customer is
+// called back from its number
+func (s *queueCallbackService) callbackDID(ctx context.Context, callback *asterisk.QueueCallback) *asterisk.DID {
+	if callback.DIDID != nil {
+		if did, err := s.didRepo.FindByID(ctx, *callback.DIDID); err == nil {
+			return did
+		}
+	}
+	return &asterisk.DID{TenantID: callback.TenantID}
+}
+
+// checkCallbacks checks the user may see the tenant's callbacks, or with
+// cancel also cancel them; either takes a supervisor or admin, though
+// anyone allowed to view reports may see them
+func (s *queueCallbackService) checkCallbacks(ctx context.Context, tenantID string, userID int64, cancel bool) error {
+	role, err := s.roleRepo.FindByUserAndTenant(ctx, userID, tenantID)
+	if err != nil {
+		return errors.NewForbidden("no role in this tenant")
+	}
+	if role.IsAdmin() || role.IsSupervisor() {
+		return nil
+	}
+	if cancel {
+		return errors.NewForbidden("not allowed to cancel callbacks")
+	}
+	if !role.Permissions.CanViewReports {
+		return errors.NewForbidden("not allowed to view callbacks")
+	}
+	return nil
+}
+
+// findCallback loads one of a tenant's callbacks
+func (s *queueCallbackService) findCallback(ctx context.Context, tenantID string, id int64) (*asterisk.QueueCallback, error) {
+	callback, err := s.callbackRepo.FindByID(ctx, id)
+	if err != nil || callback.TenantID != tenantID {
+		return nil, errors.NewNotFound("callback not found")
+	}
+	return callback, nil
+}
+
+// toQueueCallbackResponse converts a callback to its response
+func toQueueCallbackResponse(callback *asterisk.QueueCallback) *dto.QueueCallbackResponse {
+	return &dto.QueueCallbackResponse{
+		ID:             callback.ID,
+		QueueName:      callback.QueueName,
+		UniqueID:       callback.UniqueID,
+		DIDID:          callback.DIDID,
+		CallerNumber:   callback.CallerNumber,
+		CallerName:     callback.CallerName,
+		CallbackNumber: callback.CallbackNumber,
+		Status:         callback.Status,
+		Attempts:       callback.Attempts,
+		MaxAttempts:    callback.MaxAttempts,
+		WaitTime:       callback.WaitTime,
+		AgentEndpoint:  callback.AgentEndpoint,
+		LastError:      callback.LastError,
+		RequestedAt:    callback.RequestedAt,
+		LastAttemptAt:  callback.LastAttemptAt,
+		NextAttemptAt:  callback.NextAttemptAt,
+		ConnectedAt:    callback.ConnectedAt,
+		EndedAt:        callback.EndedAt,
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"time"
 
 	"github.com/psschand/callcenter/internal/asterisk"
+	"github.com/psschand/callcenter/internal/common"
 	"github.com/psschand/callcenter/internal/dto"
 	"github.com/psschand/callcenter/internal/repository"
 	"github.com/psschand/callcenter/pkg/errors"
@@ -29,6 +30,8 @@ type queueService struct {
 	tenantRepo      repository.TenantRepository
 	userRepo        repository.UserRepository
 	userRoleRepo    repository.UserRoleRepository
+	scheduleRepo    repository.ScheduleRepository
+	surveyRepo      repository.SurveyRepository
 }
 
 // NewQueueService creates a new queue service
@@ -38,6 +41,8 @@ func NewQueueService(
 	tenantRepo repository.TenantRepository,
 	userRepo repository.UserRepository,
 	userRoleRepo repository.UserRoleRepository,
+	scheduleRepo repository.ScheduleRepository,
+	surveyRepo repository.SurveyRepository,
 ) QueueService {
 	return &queueService{
 		queueRepo:       queueRepo,
@@ -45,6 +50,8 @@ func NewQueueService(
 		tenantRepo:      tenantRepo,
 		userRepo:        userRepo,
 		userRoleRepo:    userRoleRepo,
+		scheduleRepo:    scheduleRepo,
+		surveyRepo:      surveyRepo,
 	}
 }
 
@@ -75,21 +82,32 @@ func (s *queueService) Create(ctx context.Context, tenantID string, req *dto.Cre
 	// Create queue with defaults
 	now := time.Now()
 	queue := &asterisk.Queue{
-		TenantID:          tenantID,
-		Name:              req.Name,
-		DisplayName:       req.DisplayName,
-		Strategy:          req.Strategy,
-		Timeout:           req.Timeout,
-		Retry:             req.Retry,
-		MaxWaitTime:       req.MaxWaitTime,
-		MaxLen:            req.MaxLen,
-		AnnounceFrequency: req.AnnounceFrequency,
-		AnnounceHoldTime:  req.AnnounceHoldTime,
-		MusicOnHold:       req.MusicOnHold,
-		Status:            "active",
-		Metadata:          req.Metadata,
-		CreatedAt:         now,
-		UpdatedAt:         now,
+		TenantID:            tenantID,
+		Name:                req.Name,
+		DisplayName:         req.DisplayName,
+		Strategy:            req.Strategy,
+		Timeout:             req.Timeout,
+		Retry:               req.Retry,
+		WrapupTime:          req.WrapupTime,
+		MaxWaitTime:         req.MaxWaitTime,
+		MaxLen:              req.MaxLen,
+		AnnounceFrequency:   req.AnnounceFrequency,
+		AnnounceHoldTime:    req.AnnounceHoldTime,
+		MusicOnHold:         req.MusicOnHold,
+		FallbackRouteType:   req.FallbackRouteType,
+		FallbackRouteTarget: req.FallbackRouteTarget,
+		ScheduleID:          req.ScheduleID,
+		SurveyID:            req.SurveyID,
+		CallbackEnabled:     req.CallbackEnabled,
+		CallbackOfferAfter:  req.CallbackOfferAfter,
+		CallbackPrompt:      req.CallbackPrompt,
+		CallbackMaxAttempts: req.CallbackMaxAttempts,
+		CallbackRetry:       req.CallbackRetry,
+		RecordingPolicy:     req.RecordingPolicy,
+		Status:              "active",
+		Metadata:            req.Metadata,
+		CreatedAt:           now,
+		UpdatedAt:           now,
 	}
 
 	// Set defaults if not provided
@@ -105,6 +123,31 @@ func (s *queueService) Create(ctx context.Context, tenantID string, req *dto.Cre
 	if queue.MaxWaitTime == 0 {
 		queue.MaxWaitTime = 300
 	}
+	if queue.RecordingPolicy == "" {
+		queue.RecordingPolicy = asterisk.QueueRecordingInherit
+	}
+	if queue.CallbackOfferAfter == 0 {
+		queue.CallbackOfferAfter = 60
+	}
+	if queue.CallbackMaxAttempts == 0 {
+		queue.CallbackMaxAttempts = 3
+	}
+	if queue.CallbackRetry == 0 {
+		queue.CallbackRetry = 300
+	}
+
+	if err := validateQueueFallback(queue); err != nil {
+		return nil, err
+	}
+	if err := validateQueueCallbacks(queue); err != nil {
+		return nil, err
+	}
+	if err := s.validateSchedule(ctx, queue); err != nil {
+		return nil, err
+	}
+	if err := validateSurveyLink(ctx, s.surveyRepo, queue.TenantID, queue.SurveyID); err != nil {
+		return nil, err
+	}
 
 	if err := s.queueRepo.Create(ctx, queue); err != nil {
 		return nil, errors.Wrap(err, "failed to create queue")
@@ -159,6 +202,9 @@ func (s *queueService) Update(ctx context.Context, id int64, req *dto.UpdateQueu
 	if req.Retry != nil {
 		queue.Retry = *req.Retry
 	}
+	if req.WrapupTime != nil {
+		queue.WrapupTime = *req.WrapupTime
+	}
 	if req.MaxWaitTime != nil {
 		queue.MaxWaitTime = *req.MaxWaitTime
 	}
@@ -174,6 +220,48 @@ func (s *queueService) Update(ctx context.Context, id int64, req *dto.UpdateQueu
 	if req.MusicOnHold != nil {
 		queue.MusicOnHold = *req.MusicOnHold
 	}
+	if req.FallbackRouteType != nil {
+		// An empty route type clears the fallback
+		if *req.FallbackRouteType == "" {
+			queue.FallbackRouteType = nil
+			queue.FallbackRouteTarget = nil
+		} else {
+			queue.FallbackRouteType = req.FallbackRouteType
+		}
+	}
+	if req.FallbackRouteTarget != nil && queue.FallbackRouteType != nil {
+		queue.FallbackRouteTarget = req.FallbackRouteTarget
+	}
+	if req.ScheduleID != nil {
+		queue.ScheduleID = req.ScheduleID
+		if *req.ScheduleID == 0 {
+			queue.ScheduleID = nil
+		}
+	}
+	if req.SurveyID != nil {
+		queue.SurveyID = req.SurveyID
+		if *req.SurveyID == 0 {
+			queue.SurveyID = nil
+		}
+	}
+	if req.CallbackEnabled != nil {
+		queue.CallbackEnabled = *req.CallbackEnabled
+	}
+	if req.CallbackOfferAfter != nil {
+		queue.CallbackOfferAfter = *req.CallbackOfferAfter
+	}
+	if req.CallbackPrompt != nil {
+		queue.CallbackPrompt = optionalString(*req.CallbackPrompt)
+	}
+	if req.CallbackMaxAttempts != nil {
+		queue.CallbackMaxAttempts = *req.CallbackMaxAttempts
+	}
+	if req.CallbackRetry != nil {
+		queue.CallbackRetry = *req.CallbackRetry
+	}
+	if req.RecordingPolicy != nil {
+		queue.RecordingPolicy = *req.RecordingPolicy
+	}
 	if req.Status != nil {
 		queue.Status = *req.Status
 	}
@@ -181,6 +269,19 @@ func (s *queueService) Update(ctx context.Context, id int64, req *dto.UpdateQueu
 		queue.Metadata = req.Metadata
 	}
 
+	if err := validateQueueFallback(queue); err != nil {
+		return nil, err
+	}
+	if err := validateQueueCallbacks(queue); err != nil {
+		return nil, err
+	}
+	if err := s.validateSchedule(ctx, queue); err != nil {
+		return nil, err
+	}
+	if err := validateSurveyLink(ctx, s.surveyRepo, queue.TenantID, queue.SurveyID); err != nil {
+		return nil, err
+	}
+
 	queue.UpdatedAt = time.Now()
 
 	if err := s.queueRepo.Update(ctx, queue); err != nil {
@@ -340,21 +441,84 @@ func (s *queueService) UpdateMember(ctx context.Context, memberID int64, req *dt
 // toQueueResponse converts Queue model to response DTO
 func (s *queueService) toQueueResponse(queue *asterisk.Queue) *dto.QueueResponse {
 	return &dto.QueueResponse{
-		ID:                queue.ID,
-		TenantID:          queue.TenantID,
-		Name:              queue.Name,
-		DisplayName:       queue.DisplayName,
-		Strategy:          queue.Strategy,
-		Timeout:           queue.Timeout,
-		Retry:             queue.Retry,
-		MaxWaitTime:       queue.MaxWaitTime,
-		MaxLen:            queue.MaxLen,
-		AnnounceFrequency: queue.AnnounceFrequency,
-		AnnounceHoldTime:  queue.AnnounceHoldTime,
-		MusicOnHold:       queue.MusicOnHold,
-		Status:            queue.Status,
-		Metadata:          queue.Metadata,
-		CreatedAt:         queue.CreatedAt,
-		UpdatedAt:         queue.UpdatedAt,
+		ID:                  queue.ID,
+		TenantID:            queue.TenantID,
+		Name:                queue.Name,
+		DisplayName:         queue.DisplayName,
+		Strategy:            queue.Strategy,
+		Timeout:             queue.Timeout,
+		Retry:               queue.Retry,
+		WrapupTime:          queue.WrapupTime,
+		MaxWaitTime:         queue.MaxWaitTime,
+		MaxLen:              queue.MaxLen,
+		AnnounceFrequency:   queue.AnnounceFrequency,
+		AnnounceHoldTime:    queue.AnnounceHoldTime,
+		MusicOnHold:         queue.MusicOnHold,
+		FallbackRouteType:   queue.FallbackRouteType,
+		FallbackRouteTarget: queue.FallbackRouteTarget,
+		ScheduleID:          queue.ScheduleID,
+		SurveyID:            queue.SurveyID,
+		CallbackEnabled:     queue.CallbackEnabled,
+		CallbackOfferAfter:  queue.CallbackOfferAfter,
+		CallbackPrompt:      queue.CallbackPrompt,
+		CallbackMaxAttempts: queue.CallbackMaxAttempts,
+		CallbackRetry:       queue.CallbackRetry,
+		RecordingPolicy:     queue.RecordingPolicy,
+		Status:              queue.Status,
+		Metadata:            queue.Metadata,
+		CreatedAt:           queue.CreatedAt,
+		UpdatedAt:           queue.UpdatedAt,
+	}
+}
+
+// validateSchedule checks the queue's schedule belongs to its tenant
+func (s *queueService) validateSchedule(ctx context.Context, queue *asterisk.Queue) error {
+	if queue.ScheduleID == nil {
+		return nil
+	}
+	if _, err := findTenantSchedule(ctx, s.scheduleRepo, queue.TenantID, *queue.ScheduleID); err != nil {
+		return errors.NewValidation("schedule not found")
+	}
+	return nil
+}
+
+// validateQueueFallback checks where callers go when the queue is full or closed, or they exceed the max wait time
+func validateQueueFallback(queue *asterisk.Queue) error {
+	if queue.FallbackRouteType == nil {
+		return nil
+	}
+
+	target := ""
+	if queue.FallbackRouteTarget != nil {
+		target = *queue.FallbackRouteTarget
 	}
+
+	switch *queue.FallbackRouteType {
+	case common.RouteTypeQueue:
+		if target == queue.Name {
+			return errors.NewValidation("queue cannot fall back to itself")
+		}
+	case common.RouteTypeEndpoint, common.RouteTypeIVR, common.RouteTypeWebhook,
+		common.RouteTypeExternal, common.RouteTypeVoicemail:
+	default:
+		return errors.NewValidation("invalid fallback route type")
+	}
+
+	if target == "" {
+		return errors.NewValidation("fallback route target is required")
+	}
+	if *queue.FallbackRouteType == common.RouteTypeWebhook {
+		if err := asterisk.ValidateWebhookURL(target); err != nil {
+			return errors.NewValidation(err.Error())
+		}
+	}
+	return nil
+}
+
+// validateQueueCallbacks checks a queue offering callbacks has a prompt to offer them with
+func validateQueueCallbacks(queue *asterisk.Queue) error {
+	if queue.CallbackEnabled && (queue.CallbackPrompt == nil || *queue.CallbackPrompt == "") {
+		return errors.NewValidation("callback prompt is required when callbacks are enabled")
+	}
+	return nil
 }
@@ -0,0 +1,422 @@
+package service
+
+import (
+	"context"
+	stderrors "errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/psschand/callcenter/internal/asterisk"
+	"github.com/psschand/callcenter/internal/common"
+	"github.com/psschand/callcenter/internal/dto"
+	"github.com/psschand/callcenter/internal/repository"
+	"github.com/psschand/callcenter/internal/storage"
+	"github.com/psschand/callcenter/pkg/errors"
+)
+
+// RecordingContent is a recording file opened for streaming
+type RecordingContent struct {
+	Filename    string
+	ContentType string
+	ModTime     time.Time
+	Content     io.ReadSeekCloser
+}
+
+// RecordingService handles call recording persistence and playback
+type RecordingService interface {
+	ShouldRecord(tenantID, queueName string) bool
+	OnRecording(event asterisk.RecordingEvent)
+	LinkCDR(ctx context.Context, cdr *asterisk.CDR)
+	GetByTenant(ctx context.Context, tenantID string, page, pageSize int) ([]*dto.CallRecordingResponse, int64, error)
+	GetByID(ctx context.Context, tenantID string, id int64) (*dto.CallRecordingResponse, error)
+	Open(ctx context.Context, tenantID string, id int64) (*RecordingContent, error)
+	SignedURL(ctx context.Context, tenantID string, id int64, expiresIn time.Duration) (*dto.SignedURLResponse, error)
+	Delete(ctx context.Context, tenantID string, userID, id int64) error
+}
+
+type recordingService struct {
+	recordingRepo repository.CallRecordingRepository
+	cdrRepo       repository.CDRRepository
+	tenantRepo    repository.TenantRepository
+	queueRepo     repository.QueueRepository
+	roleRepo      repository.UserRoleRepository
+	store         storage.Storage
+	transcripts   TranscriptionService
+	recordingPath string
+	urlExpiry     time.Duration
+}
+
+// Bounds of the lifetime of signed recording download URLs
+const (
+	minSignedURLExpiry = time.Minute
+	maxSignedURLExpiry = 7 * 24 * time.Hour
+)
+
+// NewRecordingService creates a new recording service. recordingPath is the
+// directory Asterisk writes call recordings to; completed recordings are
+// archived from there to store. urlExpiry is the default lifetime of signed
+// download URLs. Completed recordings are queued for transcription.
+func NewRecordingService(
+	recordingRepo repository.CallRecordingRepository,
+	cdrRepo repository.CDRRepository,
+	tenantRepo repository.TenantRepository,
+	queueRepo repository.QueueRepository,
+	roleRepo repository.UserRoleRepository,
+	store storage.Storage,
+	transcripts TranscriptionService,
+	recordingPath string,
+	urlExpiry time.Duration,
+) RecordingService {
+	return &recordingService{
+		recordingRepo: recordingRepo,
+		cdrRepo:       cdrRepo,
+		tenantRepo:    tenantRepo,
+		queueRepo:     queueRepo,
+		roleRepo:      roleRepo,
+		store:         store,
+		transcripts:   transcripts,
+		recordingPath: recordingPath,
+		urlExpiry:     urlExpiry,
+	}
+}
+
+// ShouldRecord applies the queue's recording policy, falling back to the tenant's call recording setting
+func (s *recordingService) ShouldRecord(tenantID, queueName string) bool {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if queueName != "" {
+		if queue, err := s.queueRepo.FindByName(ctx, tenantID, queueName); err == nil {
+			switch queue.RecordingPolicy {
+			case asterisk.QueueRecordingAlways:
+				return true
+			case asterisk.QueueRecordingNever:
+				return false
+			}
+		}
+	}
+
+	tenant, err := s.tenantRepo.FindByID(ctx, tenantID)
+	if err != nil {
+		log.Printf("Error loading tenant %s for recording policy: %v", tenantID, err)
+		return false
+	}
+	return tenant.Settings.CallRecording
+}
+
+// OnRecording persists a call recording as it starts, finishes or fails
+func (s *recordingService) OnRecording(event asterisk.RecordingEvent) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	filename := fmt.Sprintf("%s.%s", event.Name, event.Format)
+	recording, err := s.recordingRepo.FindByFilename(ctx, event.TenantID, filename)
+	if err != nil || recording.Status == common.RecordingStatusDeleted || recording.IsArchived() {
+		// An archived recording is never appended to; a call bridged again
+		// afterwards is recorded anew
+		recording = &asterisk.CallRecording{
+			TenantID: event.TenantID,
+			UniqueID: event.UniqueID,
+			Filename: filename,
+			FilePath: filepath.Join(s.recordingPath, filename),
+			Format:   event.Format,
+		}
+	}
+
+	switch event.State {
+	case asterisk.RecordingStateRecording:
+		// A call bridged again after a transfer appends to its recording
+		recording.Status = common.RecordingStatusRecording
+	case asterisk.RecordingStateDone:
+		recording.Status = common.RecordingStatusCompleted
+		recording.Duration = event.Duration
+		if info, err := os.Stat(recording.FilePath); err == nil {
+			recording.FileSize = info.Size()
+		}
+	default:
+		log.Printf("Recording %s failed: %s", filename, event.Cause)
+		recording.Status = common.RecordingStatusFailed
+	}
+
+	if recording.ID == 0 {
+		err = s.recordingRepo.Create(ctx, recording)
+	} else {
+		err = s.recordingRepo.Update(ctx, recording)
+	}
+	if err != nil {
+		log.Printf("Error saving recording %s: %v", filename, err)
+		return
+	}
+
+	if !recording.IsCompleted() {
+		return
+	}
+
+	// The CDR may already have been written when the recording finishes
+	if recording.CDRID == nil {
+		if cdr, err := s.cdrRepo.FindByUniqueID(ctx, event.TenantID, event.UniqueID); err == nil {
+			s.link(ctx, recording, cdr)
+		}
+	}
+	s.transcripts.Enqueue(ctx, recording.TenantID, common.TranscriptSourceRecording, recording.ID)
+}
+
+// LinkCDR links a newly written CDR with the recording of its call, if there is one
+func (s *recordingService) LinkCDR(ctx context.Context, cdr *asterisk.CDR) {
+	recording, err := s.recordingRepo.FindByUniqueID(ctx, cdr.TenantID, cdr.UniqueID)
+	if err != nil || recording.Status == common.RecordingStatusFailed {
+		return
+	}
+	if recording.CDRID != nil && *recording.CDRID != cdr.ID {
+		// A later segment of a transferred call: the recording stays linked
+		// to the first, but every segment points at the recording
+		cdr.RecordingFile = &recording.Filename
+		if err := s.cdrRepo.SetRecordingFile(ctx, cdr.ID, cdr.RecordingFile); err != nil {
+			log.Printf("Error setting recording file of CDR %d: %v", cdr.ID, err)
+		}
+		return
+	}
+	s.link(ctx, recording, cdr)
+}
+
+// link points a recording and its CDR at each other
+func (s *recordingService) link(ctx context.Context, recording *asterisk.CallRecording, cdr *asterisk.CDR) {
+	recording.CDRID = &cdr.ID
+	if err := s.recordingRepo.Update(ctx, recording); err != nil {
+		log.Printf("Error linking recording %d to CDR %d: %v", recording.ID, cdr.ID, err)
+		return
+	}
+
+	cdr.RecordingFile = &recording.Filename
+	if err := s.cdrRepo.SetRecordingFile(ctx, cdr.ID, cdr.RecordingFile); err != nil {
+		log.Printf("Error setting recording file of CDR %d: %v", cdr.ID, err)
+	}
+}
+
+// GetByTenant lists a tenant's recordings
+func (s *recordingService) GetByTenant(ctx context.Context, tenantID string, page, pageSize int) ([]*dto.CallRecordingResponse, int64, error) {
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	recordings, total, err := s.recordingRepo.FindByTenant(ctx, tenantID, page, pageSize)
+	if err != nil {
+		return nil, 0, errors.Wrap(err, "failed to get recordings")
+	}
+
+	responses := make([]*dto.CallRecordingResponse, len(recordings))
+	for i := range recordings {
+		responses[i] = toCallRecordingResponse(&recordings[i])
+	}
+	return responses, total, nil
+}
+
+// GetByID gets a recording
+func (s *recordingService) GetByID(ctx context.Context, tenantID string, id int64) (*dto.CallRecordingResponse, error) {
+	recording, err := s.findRecording(ctx, tenantID, id)
+	if err != nil {
+		return nil, err
+	}
+	return toCallRecordingResponse(recording), nil
+}
+
+// Open opens a completed recording for streaming
+func (s *recordingService) Open(ctx context.Context, tenantID string, id int64) (*RecordingContent, error) {
+	recording, err := s.findRecording(ctx, tenantID, id)
+	if err != nil {
+		return nil, err
+	}
+	if !recording.IsCompleted() {
+		return nil, errors.NewConflict("recording is not available")
+	}
+
+	if recording.IsArchived() {
+		object, err := s.store.Open(ctx, *recording.StorageKey)
+		if err != nil {
+			if stderrors.Is(err, storage.ErrNotFound) {
+				return nil, errors.NewNotFound("recording file not found")
+			}
+			return nil, errors.Wrap(err, "failed to open recording")
+		}
+		return &RecordingContent{
+			Filename:    recording.Filename,
+			ContentType: recordingContentType(recording.Format),
+			ModTime:     object.ModTime,
+			Content:     object.Content,
+		}, nil
+	}
+
+	file, err := os.Open(recording.FilePath)
+	if err != nil {
+		if stderrors.Is(err, os.ErrNotExist) {
+			return nil, errors.NewNotFound("recording file not found")
+		}
+		return nil, errors.Wrap(err, "failed to open recording")
+	}
+
+	modTime := recording.CreatedAt
+	if info, err := file.Stat(); err == nil {
+		modTime = info.ModTime()
+	}
+
+	return &RecordingContent{
+		Filename:    recording.Filename,
+		ContentType: recordingContentType(recording.Format),
+		ModTime:     modTime,
+		Content:     file,
+	}, nil
+}
+
+// SignedURL returns a URL the recording can be downloaded from without
+// authentication until it expires. Only archived recordings can be shared.
+func (s *recordingService) SignedURL(ctx context.Context, tenantID string, id int64, expiresIn time.Duration) (*dto.SignedURLResponse, error) {
+	if expiresIn == 0 {
+		expiresIn = s.urlExpiry
+	}
+	if expiresIn < minSignedURLExpiry || expiresIn > maxSignedURLExpiry {
+		return nil, errors.NewValidation(map[string]string{
+			"expires_in": fmt.Sprintf("must be between %d and %d seconds", int(minSignedURLExpiry.Seconds()), int(maxSignedURLExpiry.Seconds())),
+		})
+	}
+
+	recording, err := s.findRecording(ctx, tenantID, id)
+	if err != nil {
+		return nil, err
+	}
+	if !recording.IsCompleted() {
+		return nil, errors.NewConflict("recording is not available")
+	}
+	if !recording.IsArchived() {
+		return nil, errors.NewConflict("recording has not been archived yet")
+	}
+
+	expiresAt := time.Now().Add(expiresIn)
+	url, err := s.store.SignedURL(ctx, *recording.StorageKey, expiresAt)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to sign recording URL")
+	}
+
+	return &dto.SignedURLResponse{URL: url, ExpiresAt: expiresAt}, nil
+}
+
+// Delete removes a recording's file and unlinks it from its CDR. The row is
+// kept, marked deleted, as a trace of the recording having existed. Only
+// supervisors and admins may delete recordings.
+func (s *recordingService) Delete(ctx context.Context, tenantID string, userID, id int64) error {
+	if err := s.checkDeleteRecordings(ctx, tenantID, userID); err != nil {
+		return err
+	}
+
+	recording, err := s.findRecording(ctx, tenantID, id)
+	if err != nil {
+		return err
+	}
+	if recording.Status == common.RecordingStatusRecording {
+		return errors.NewConflict("call is still being recorded")
+	}
+
+	if err := deleteRecording(ctx, s.store, s.recordingRepo, s.cdrRepo, s.transcripts, recording); err != nil {
+		return errors.Wrap(err, "failed to delete recording")
+	}
+	return nil
+}
+
+// deleteRecording removes a recording's audio from storage and the spool
+// with its transcript, marks it deleted and unlinks it from its CDR
+func deleteRecording(
+	ctx context.Context,
+	store storage.Storage,
+	recordingRepo repository.CallRecordingRepository,
+	cdrRepo repository.CDRRepository,
+	transcripts TranscriptionService,
+	recording *asterisk.CallRecording,
+) error {
+	if recording.IsArchived() {
+		if err := store.Delete(ctx, *recording.StorageKey); err != nil {
+			return err
+		}
+	}
+	if err := os.Remove(recording.FilePath); err != nil && !stderrors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	if err := transcripts.DeleteTranscript(ctx, common.TranscriptSourceRecording, recording.ID); err != nil {
+		return err
+	}
+
+	recording.Status = common.RecordingStatusDeleted
+	if err := recordingRepo.Update(ctx, recording); err != nil {
+		return err
+	}
+
+	if recording.CDRID != nil {
+		if err := cdrRepo.SetRecordingFile(ctx, *recording.CDRID, nil); err != nil {
+			log.Printf("Error unlinking recording %d from CDR %d: %v", recording.ID, *recording.CDRID, err)
+		}
+	}
+	return nil
+}
+
+// checkDeleteRecordings checks the user may delete the tenant's recordings,
+// which takes a supervisor or admin
+func (s *recordingService) checkDeleteRecordings(ctx context.Context, tenantID string, userID int64) error {
+	role, err := s.roleRepo.FindByUserAndTenant(ctx, userID, tenantID)
+	if err != nil {
+		return errors.NewForbidden("no role in this tenant")
+	}
+	if !role.IsAdmin() && !role.IsSupervisor() {
+		return errors.NewForbidden("not allowed to delete recordings")
+	}
+	return nil
+}
+
+// findRecording loads a tenant's recording that has not been deleted
+func (s *recordingService) findRecording(ctx context.Context, tenantID string, id int64) (*asterisk.CallRecording, error) {
+	recording, err := s.recordingRepo.FindByID(ctx, id)
+	if err != nil || recording.TenantID != tenantID || recording.Status == common.RecordingStatusDeleted {
+		return nil, errors.NewNotFound("recording not found")
+	}
+	return recording, nil
+}
+
+// recordingContentType returns the MIME type of a recording format
+func recordingContentType(format string) string {
+	switch format {
+	case "wav":
+		return "audio/wav"
+	case "mp3":
+		return "audio/mpeg"
+	case "gsm":
+		return "audio/x-gsm"
+	case "ogg":
+		return "audio/ogg"
+	default:
+		return "application/octet-stream"
+	}
+}
+
+// toCallRecordingResponse converts a call recording to its response
+func toCallRecordingResponse(recording *asterisk.CallRecording) *dto.CallRecordingResponse {
+	resp := &dto.CallRecordingResponse{
+		ID:        recording.ID,
+		CDRID:     recording.CDRID,
+		UniqueID:  recording.UniqueID,
+		Filename:  recording.Filename,
+		FileSize:  recording.FileSize,
+		Duration:  recording.Duration,
+		Format:    recording.Format,
+		Status:    recording.Status,
+		Storage:   recording.StorageBackend,
+		CreatedAt: recording.CreatedAt,
+	}
+	if recording.IsCompleted() {
+		resp.StreamURL = fmt.Sprintf("/api/v1/recordings/%d/stream", recording.ID)
+	}
+	return resp
+}
@@ -0,0 +1,324 @@
+package service
+
+import (
+	"context"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/psschand/callcenter/internal/common"
+	"github.com/psschand/callcenter/internal/core"
+	"github.com/psschand/callcenter/internal/dto"
+	"github.com/psschand/callcenter/internal/repository"
+	"github.com/psschand/callcenter/pkg/errors"
+)
+
+// ScheduleService manages the business hours and holiday schedules DIDs,
+// queues and chat widgets follow
+type ScheduleService interface {
+	List(ctx context.Context, tenantID string, userID int64) ([]*dto.ScheduleResponse, error)
+	Get(ctx context.Context, tenantID string, userID, id int64) (*dto.ScheduleResponse, error)
+	Create(ctx context.Context, tenantID string, userID int64, req *dto.CreateScheduleRequest) (*dto.ScheduleResponse, error)
+	Update(ctx context.Context, tenantID string, userID, id int64, req *dto.UpdateScheduleRequest) (*dto.ScheduleResponse, error)
+	Delete(ctx context.Context, tenantID string, userID, id int64) error
+	Status(ctx context.Context, tenantID string, userID, id int64, at time.Time) (*dto.ScheduleStatusResponse, error)
+}
+
+type scheduleService struct {
+	scheduleRepo repository.ScheduleRepository
+	roleRepo     repository.UserRoleRepository
+}
+
+// NewScheduleService creates a new schedule service
+func NewScheduleService(scheduleRepo repository.ScheduleRepository, roleRepo repository.UserRoleRepository) ScheduleService {
+	return &scheduleService{
+		scheduleRepo: scheduleRepo,
+		roleRepo:     roleRepo,
+	}
+}
+
+// List lists the tenant's schedules
+func (s *scheduleService) List(ctx context.Context, tenantID string, userID int64) ([]*dto.ScheduleResponse, error) {
+	if err := s.checkSchedules(ctx, tenantID, userID, false); err != nil {
+		return nil, err
+	}
+
+	schedules, err := s.scheduleRepo.FindByTenant(ctx, tenantID)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list schedules")
+	}
+
+	responses := make([]*dto.ScheduleResponse, len(schedules))
+	for i := range schedules {
+		responses[i] = toScheduleResponse(&schedules[i])
+	}
+	return responses, nil
+}
+
+// Get gets one of the tenant's schedules
+func (s *scheduleService) Get(ctx context.Context, tenantID string, userID, id int64) (*dto.ScheduleResponse, error) {
+	if err := s.checkSchedules(ctx, tenantID, userID, false); err != nil {
+		return nil, err
+	}
+
+	schedule, err := findTenantSchedule(ctx, s.scheduleRepo, tenantID, id)
+	if err != nil {
+		return nil, err
+	}
+	return toScheduleResponse(schedule), nil
+}
+
+// Create creates a schedule
+func (s *scheduleService) Create(ctx context.Context, tenantID string, userID int64, req *dto.CreateScheduleRequest) (*dto.ScheduleResponse, error) {
+	if err := s.checkSchedules(ctx, tenantID, userID, true); err != nil {
+		return nil, err
+	}
+
+	schedule := &core.Schedule{
+		TenantID:    tenantID,
+		Name:        req.Name,
+		Description: req.Description,
+		Timezone:    req.Timezone,
+		Type:        req.ScheduleType,
+		Rules:       fromScheduleRules(req.Rules),
+		IsActive:    true,
+	}
+	if schedule.Timezone == "" {
+		schedule.Timezone = "UTC"
+	}
+	if schedule.Type == "" {
+		schedule.Type = common.ScheduleTypeBusinessHours
+	}
+	if req.IsActive != nil {
+		schedule.IsActive = *req.IsActive
+	}
+
+	if err := validateSchedule(schedule); err != nil {
+		return nil, err
+	}
+
+	if err := s.scheduleRepo.Create(ctx, schedule); err != nil {
+		return nil, errors.Wrap(err, "failed to create schedule")
+	}
+
+	log.Printf("User %d created schedule %d (%s) for tenant %s", userID, schedule.ID, schedule.Name, tenantID)
+	return toScheduleResponse(schedule), nil
+}
+
+// Update updates one of the tenant's schedules
+func (s *scheduleService) Update(ctx context.Context, tenantID string, userID, id int64, req *dto.UpdateScheduleRequest) (*dto.ScheduleResponse, error) {
+	if err := s.checkSchedules(ctx, tenantID, userID, true); err != nil {
+		return nil, err
+	}
+
+	schedule, err := findTenantSchedule(ctx, s.scheduleRepo, tenantID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Name != nil {
+		schedule.Name = *req.Name
+	}
+	if req.Description != nil {
+		schedule.Description = optionalString(*req.Description)
+	}
+	if req.Timezone != nil {
+		schedule.Timezone = *req.Timezone
+	}
+	if req.ScheduleType != nil {
+		schedule.Type = *req.ScheduleType
+	}
+	if req.Rules != nil {
+		schedule.Rules = fromScheduleRules(*req.Rules)
+	}
+	if req.IsActive != nil {
+		schedule.IsActive = *req.IsActive
+	}
+
+	if err := validateSchedule(schedule); err != nil {
+		return nil, err
+	}
+
+	if err := s.scheduleRepo.Update(ctx, schedule); err != nil {
+		return nil, errors.Wrap(err, "failed to update schedule")
+	}
+	return toScheduleResponse(schedule), nil
+}
+
+// Delete deletes one of the tenant's schedules. DIDs, queues and chat
+// widgets that used it are always open afterwards.
+func (s *scheduleService) Delete(ctx context.Context, tenantID string, userID, id int64) error {
+	if err := s.checkSchedules(ctx, tenantID, userID, true); err != nil {
+		return err
+	}
+
+	schedule, err := findTenantSchedule(ctx, s.scheduleRepo, tenantID, id)
+	if err != nil {
+		return err
+	}
+
+	if err := s.scheduleRepo.Delete(ctx, schedule.ID); err != nil {
+		return errors.Wrap(err, "failed to delete schedule")
+	}
+
+	log.Printf("User %d deleted schedule %d (%s) of tenant %s", userID, schedule.ID, schedule.Name, tenantID)
+	return nil
+}
+
+// Status reports whether one of the tenant's schedules is open at a moment,
+// and when it next opens or closes
+func (s *scheduleService) Status(ctx context.Context, tenantID string, userID, id int64, at time.Time) (*dto.ScheduleStatusResponse, error) {
+	if err := s.checkSchedules(ctx, tenantID, userID, false); err != nil {
+		return nil, err
+	}
+
+	schedule, err := findTenantSchedule(ctx, s.scheduleRepo, tenantID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	at = at.In(schedule.Location())
+	resp := &dto.ScheduleStatusResponse{
+		ScheduleID: schedule.ID,
+		At:         at,
+		IsOpen:     schedule.IsOpen(at),
+	}
+	if resp.IsOpen {
+		if next, ok := schedule.NextClose(at); ok {
+			resp.NextClose = &next
+		}
+	} else if next, ok := schedule.NextOpen(at); ok {
+		resp.NextOpen = &next
+	}
+	return resp, nil
+}
+
+// checkSchedules checks the user may see the tenant's schedules, and change
+// them if manage is set
+func (s *scheduleService) checkSchedules(ctx context.Context, tenantID string, userID int64, manage bool) error {
+	role, err := s.roleRepo.FindByUserAndTenant(ctx, userID, tenantID)
+	if err != nil {
+		return errors.NewForbidden("no role in this tenant")
+	}
+	if manage && !role.IsAdmin() && !role.Permissions.CanManageSettings {
+		return errors.NewForbidden("not allowed to manage schedules")
+	}
+	return nil
+}
+
+// findTenantSchedule loads one of a tenant's schedules
+func findTenantSchedule(ctx context.Context, repo repository.ScheduleRepository, tenantID string, id int64) (*core.Schedule, error) {
+	schedule, err := repo.FindByID(ctx, id)
+	if err != nil || schedule.TenantID != tenantID {
+		return nil, errors.NewNotFound("schedule not found")
+	}
+	return schedule, nil
+}
+
+// scheduleStatus reports whether a tenant's schedule is open at a moment
+// and, if it is closed, when it next opens. No schedule, or one that is
+// missing or inactive, is always open.
+func scheduleStatus(ctx context.Context, repo repository.ScheduleRepository, tenantID string, scheduleID *int64, at time.Time) (bool, *time.Time) {
+	if scheduleID == nil {
+		return true, nil
+	}
+	schedule, err := findTenantSchedule(ctx, repo, tenantID, *scheduleID)
+	if err != nil || !schedule.IsActive {
+		return true, nil
+	}
+	if schedule.IsOpen(at) {
+		return true, nil
+	}
+	if next, ok := schedule.NextOpen(at); ok {
+		return false, &next
+	}
+	return false, nil
+}
+
+// validateSchedule checks a schedule about to be saved
+func validateSchedule(schedule *core.Schedule) error {
+	fields := map[string]string{}
+	if strings.TrimSpace(schedule.Name) == "" {
+		fields["name"] = "is required"
+	}
+	if _, err := time.LoadLocation(schedule.Timezone); err != nil {
+		fields["timezone"] = "must be an IANA time zone such as America/New_York"
+	}
+	switch schedule.Type {
+	case common.ScheduleTypeBusinessHours, common.ScheduleTypeHolidays:
+	default:
+		fields["schedule_type"] = "must be business_hours or holidays"
+	}
+	if err := schedule.Rules.Validate(); err != nil {
+		fields["rules"] = err.Error()
+	}
+
+	if len(fields) > 0 {
+		return errors.NewValidation(fields)
+	}
+	return nil
+}
+
+// fromScheduleRules converts rules from a request
+func fromScheduleRules(rules dto.ScheduleRules) core.ScheduleRules {
+	result := core.ScheduleRules{
+		Weekly:     fromScheduleHours(rules.Weekly),
+		Exceptions: make([]core.ScheduleException, len(rules.Exceptions)),
+	}
+	for i, exception := range rules.Exceptions {
+		result.Exceptions[i] = core.ScheduleException{
+			Date:   exception.Date,
+			Name:   exception.Name,
+			Closed: exception.Closed,
+			Hours:  fromScheduleHours(exception.Hours),
+		}
+	}
+	return result
+}
+
+// fromScheduleHours converts opening hours from a request
+func fromScheduleHours(hours []dto.ScheduleHours) []core.ScheduleHours {
+	result := make([]core.ScheduleHours, len(hours))
+	for i, h := range hours {
+		result[i] = core.ScheduleHours{Day: strings.ToLower(h.Day), Open: h.Open, Close: h.Close}
+	}
+	return result
+}
+
+// toScheduleHours converts opening hours for a response
+func toScheduleHours(hours []core.ScheduleHours) []dto.ScheduleHours {
+	result := make([]dto.ScheduleHours, len(hours))
+	for i, h := range hours {
+		result[i] = dto.ScheduleHours{Day: h.Day, Open: h.Open, Close: h.Close}
+	}
+	return result
+}
+
+// toScheduleResponse converts a schedule to its response
+func toScheduleResponse(schedule *core.Schedule) *dto.ScheduleResponse {
+	rules := dto.ScheduleRules{
+		Weekly:     toScheduleHours(schedule.Rules.Weekly),
+		Exceptions: make([]dto.ScheduleException, len(schedule.Rules.Exceptions)),
+	}
+	for i, exception := range schedule.Rules.Exceptions {
+		rules.Exceptions[i] = dto.ScheduleException{
+			Date:   exception.Date,
+			Name:   exception.Name,
+			Closed: exception.Closed,
+			Hours:  toScheduleHours(exception.Hours),
+		}
+	}
+
+	return &dto.ScheduleResponse{
+		ID:           schedule.ID,
+		TenantID:     schedule.TenantID,
+		Name:         schedule.Name,
+		Description:  schedule.Description,
+		Timezone:     schedule.Timezone,
+		ScheduleType: schedule.Type,
+		Rules:        rules,
+		IsActive:     schedule.IsActive,
+		CreatedAt:    schedule.CreatedAt,
+		UpdatedAt:    schedule.UpdatedAt,
+	}
+}
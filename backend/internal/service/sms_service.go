@@ -0,0 +1,425 @@
+package service
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/psschand/callcenter/internal/asterisk"
+	"github.com/psschand/callcenter/internal/common"
+	"github.com/psschand/callcenter/internal/dto"
+	"github.com/psschand/callcenter/internal/repository"
+	"github.com/psschand/callcenter/internal/sms"
+	ws "github.com/psschand/callcenter/internal/websocket"
+	"github.com/psschand/callcenter/pkg/errors"
+)
+
+// Delivery settings of inbound messages forwarded to a DID's SMS webhook
+const (
+	smsWebhookRetries = 3
+	smsWebhookTimeout = 10 * time.Second
+)
+
+// SMSService sends SMS messages from the tenant's numbers and records the
+// messages they receive and the delivery status of those sent
+type SMSService interface {
+	Send(ctx context.Context, tenantID string, userID int64, req *dto.SendSMSRequest) (*dto.SMSResponse, error)
+	List(ctx context.Context, tenantID string, userID int64, direction common.SMSDirection, status common.SMSStatus, number string, page, pageSize int) ([]*dto.SMSResponse, int64, error)
+	GetByID(ctx context.Context, tenantID string, userID int64, id int64) (*dto.SMSResponse, error)
+	Reply(ctx context.Context, tenantID string, didID int64, userID *int64, recipient, body string) (*dto.SMSResponse, error)
+	ReceiveInbound(ctx context.Context, msg *sms.InboundMessage) error
+	UpdateStatus(ctx context.Context, update *sms.StatusUpdate) error
+	SetInboundListener(listener SMSListener)
+	SetStatusListener(listener SMSListener)
+	SetSenderScreen(screen SMSScreen)
+}
+
+// SMSListener is told of SMS messages received, or of the delivery status
+// of messages sent, once stored
+type SMSListener func(message *asterisk.SMSMessage)
+
+// SMSScreen checks if a sender is blocked from messaging a DID
+type SMSScreen func(did *asterisk.DID, sender string) bool
+
+type smsService struct {
+	smsRepo     repository.SMSMessageRepository
+	didRepo     repository.DIDRepository
+	tenantRepo  repository.TenantRepository
+	roleRepo    repository.UserRoleRepository
+	provider    sms.Provider
+	segmentCost float64
+	broadcaster *ws.EventBroadcaster
+	webhooks    *ws.WebhookManager
+
+	inboundListener SMSListener
+	statusListener  SMSListener
+	senderScreen    SMSScreen
+}
+
+// NewSMSService creates a new SMS service. provider is nil when SMS is
+// turned off; segmentCost prices the segments of messages whose provider
+// does not report a price.
+func NewSMSService(
+	smsRepo repository.SMSMessageRepository,
+	didRepo repository.DIDRepository,
+	tenantRepo repository.TenantRepository,
+	roleRepo repository.UserRoleRepository,
+	provider sms.Provider,
+	segmentCost float64,
+	broadcaster *ws.EventBroadcaster,
+	webhooks *ws.WebhookManager,
+) SMSService {
+	return &smsService{
+		smsRepo:     smsRepo,
+		didRepo:     didRepo,
+		tenantRepo:  tenantRepo,
+		roleRepo:    roleRepo,
+		provider:    provider,
+		segmentCost: segmentCost,
+		broadcaster: broadcaster,
+		webhooks:    webhooks,
+	}
+}
+
+// Send sends a message from one of the tenant's SMS-enabled numbers
+func (s *smsService) Send(ctx context.Context, tenantID string, userID int64, req *dto.SendSMSRequest) (*dto.SMSResponse, error) {
+	if s.provider == nil {
+		return nil, errors.NewConflict("SMS is not configured")
+	}
+
+	role, err := s.roleRepo.FindByUserAndTenant(ctx, userID, tenantID)
+	if err != nil {
+		return nil, errors.NewForbidden("no role in this tenant")
+	}
+	if !role.IsAdmin() && !role.Permissions.CanSendSMS {
+		return nil, errors.NewForbidden("not allowed to send SMS")
+	}
+
+	tenant, err := s.tenantRepo.FindByID(ctx, tenantID)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to load tenant")
+	}
+	if !tenant.HasFeature("sms") {
+		return nil, errors.NewForbidden("SMS is not enabled for this tenant")
+	}
+
+	recipient := normalizePhoneNumber(req.Recipient)
+	if !phoneNumberPattern.MatchString(recipient) {
+		return nil, errors.NewValidation(map[string]string{"recipient": "invalid phone number"})
+	}
+
+	did, err := s.didRepo.FindByNumber(ctx, normalizePhoneNumber(req.Sender))
+	if err != nil || did.TenantID != tenantID {
+		return nil, errors.NewValidation(map[string]string{"sender": "sender must be one of the tenant's DIDs"})
+	}
+	if !did.IsActive() || !did.SMSEnabled {
+		return nil, errors.NewValidation(map[string]string{"sender": "sender DID is not active or not enabled for SMS"})
+	}
+
+	message, err := s.deliver(ctx, did, &userID, recipient, req.Body)
+	if err != nil {
+		return nil, err
+	}
+	return toSMSResponse(message), nil
+}
+
+// Reply sends a message from a DID in an SMS conversation. It does not check
+// the permissions Send does; the caller has checked the user may reply.
+func (s *smsService) Reply(ctx context.Context, tenantID string, didID int64, userID *int64, recipient, body string) (*dto.SMSResponse, error) {
+	if s.provider == nil {
+		return nil, errors.NewConflict("SMS is not configured")
+	}
+
+	did, err := s.didRepo.FindByID(ctx, didID)
+	if err != nil || did.TenantID != tenantID {
+		return nil, errors.NewNotFound("DID not found")
+	}
+	if !did.IsActive() || !did.SMSEnabled {
+		return nil, errors.NewConflict("DID is not active or not enabled for SMS")
+	}
+
+	message, err := s.deliver(ctx, did, userID, recipient, body)
+	if err != nil {
+		return nil, err
+	}
+	return toSMSResponse(message), nil
+}
+
+// deliver stores a message from a DID as pending and sends it, so it is
+// kept as failed when the provider refuses it
+func (s *smsService) deliver(ctx context.Context, did *asterisk.DID, userID *int64, recipient, body string) (*asterisk.SMSMessage, error) {
+	segments := sms.CountSegments(body)
+	message := &asterisk.SMSMessage{
+		TenantID:  did.TenantID,
+		DIDID:     &did.ID,
+		Direction: common.SMSDirectionOutbound,
+		Sender:    did.Number,
+		Recipient: recipient,
+		Body:      &body,
+		Status:    common.SMSStatusPending,
+		Segments:  segments,
+		Cost:      s.cost(segments, 0),
+		UserID:    userID,
+		Provider:  s.provider.Name(),
+	}
+	if err := s.smsRepo.Create(ctx, message); err != nil {
+		return nil, errors.Wrap(err, "failed to create SMS message")
+	}
+
+	result, err := s.provider.Send(ctx, &sms.Message{From: did.Number, To: recipient, Body: body})
+	if err != nil {
+		log.Printf("Error sending SMS %d: %v", message.ID, err)
+		errorMessage := err.Error()
+		message.Status = common.SMSStatusFailed
+		message.ErrorMessage = &errorMessage
+	} else {
+		message.ProviderMessageID = &result.MessageID
+		if message.CanTransitionTo(result.Status) {
+			message.Status = result.Status
+		}
+		if result.Segments > 0 {
+			message.Segments = result.Segments
+		}
+		message.Cost = s.cost(message.Segments, result.Cost)
+	}
+
+	if err := s.smsRepo.Update(ctx, message); err != nil {
+		return nil, errors.Wrap(err, "failed to update SMS message")
+	}
+	return message, nil
+}
+
+// List lists the tenant's messages, latest first; empty filters match all
+func (s *smsService) List(ctx context.Context, tenantID string, userID int64, direction common.SMSDirection, status common.SMSStatus, number string, page, pageSize int) ([]*dto.SMSResponse, int64, error) {
+	if err := s.checkAccess(ctx, tenantID, userID); err != nil {
+		return nil, 0, err
+	}
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	filter := repository.SMSMessageFilter{
+		Direction: direction,
+		Status:    status,
+		Number:    normalizePhoneNumber(number),
+	}
+	messages, total, err := s.smsRepo.FindByTenant(ctx, tenantID, filter, page, pageSize)
+	if err != nil {
+		return nil, 0, errors.Wrap(err, "failed to list SMS messages")
+	}
+
+	responses := make([]*dto.SMSResponse, len(messages))
+	for i := range messages {
+		responses[i] = toSMSResponse(&messages[i])
+	}
+	return responses, total, nil
+}
+
+// GetByID gets one of the tenant's messages
+func (s *smsService) GetByID(ctx context.Context, tenantID string, userID int64, id int64) (*dto.SMSResponse, error) {
+	if err := s.checkAccess(ctx, tenantID, userID); err != nil {
+		return nil, err
+	}
+
+	message, err := s.smsRepo.FindByID(ctx, id)
+	if err != nil || message.TenantID != tenantID {
+		return nil, errors.NewNotFound("SMS message not found")
+	}
+	return toSMSResponse(message), nil
+}
+
+// ReceiveInbound stores a message received by an SMS-enabled DID, notifies
+// its tenant and forwards it to the DID's SMS webhook. Messages the provider
+// posts again are only stored once.
+func (s *smsService) ReceiveInbound(ctx context.Context, msg *sms.InboundMessage) error {
+	if s.provider == nil {
+		return errors.NewConflict("SMS is not configured")
+	}
+
+	did, err := s.didRepo.FindByNumber(ctx, normalizePhoneNumber(msg.To))
+	if err != nil || !did.IsActive() || !did.SMSEnabled {
+		return errors.NewNotFound("no SMS-enabled DID for this number")
+	}
+
+	if _, err := s.smsRepo.FindByProviderID(ctx, s.provider.Name(), msg.MessageID); err == nil {
+		return nil
+	}
+
+	// Blocked messages are dropped; the provider is still told they arrived
+	if s.senderScreen != nil && s.senderScreen(did, msg.From) {
+		log.Printf("Dropped SMS from blocked sender %s to DID %s", msg.From, did.Number)
+		return nil
+	}
+
+	body := msg.Body
+	messageID := msg.MessageID
+	segments := msg.Segments
+	if segments < 1 {
+		segments = sms.CountSegments(body)
+	}
+	message := &asterisk.SMSMessage{
+		TenantID:          did.TenantID,
+		DIDID:             &did.ID,
+		Direction:         common.SMSDirectionInbound,
+		Sender:            msg.From,
+		Recipient:         did.Number,
+		Body:              &body,
+		Status:            common.SMSStatusReceived,
+		Segments:          segments,
+		Provider:          s.provider.Name(),
+		ProviderMessageID: &messageID,
+	}
+	if err := s.smsRepo.Create(ctx, message); err != nil {
+		return errors.Wrap(err, "failed to store SMS message")
+	}
+
+	if s.broadcaster != nil {
+		if err := s.broadcaster.SMSReceived(did.TenantID, toSMSPayload(message)); err != nil {
+			log.Printf("Error broadcasting SMS %d: %v", message.ID, err)
+		}
+	}
+
+	if s.webhooks != nil && did.SMSWebhookURL != nil && *did.SMSWebhookURL != "" {
+		// Deliver blocks while the delivery queue is full; the provider is not kept waiting
+		go s.webhooks.Deliver(&ws.WebhookDelivery{
+			URL:        *did.SMSWebhookURL,
+			Event:      ws.MessageTypeSMSReceived,
+			Payload:    toSMSResponse(message),
+			Attempt:    1,
+			MaxRetries: smsWebhookRetries,
+			Timeout:    smsWebhookTimeout,
+		})
+	}
+
+	if s.inboundListener != nil {
+		s.inboundListener(message)
+	}
+
+	log.Printf("Received SMS %d for DID %s", message.ID, did.Number)
+	return nil
+}
+
+// UpdateStatus applies a delivery status callback to the message sent.
+// Statuses older than the message's, as when callbacks arrive out of order,
+// are ignored.
+func (s *smsService) UpdateStatus(ctx context.Context, update *sms.StatusUpdate) error {
+	if s.provider == nil {
+		return errors.NewConflict("SMS is not configured")
+	}
+
+	message, err := s.smsRepo.FindByProviderID(ctx, s.provider.Name(), update.MessageID)
+	if err != nil {
+		return errors.NewNotFound("SMS message not found")
+	}
+	if !message.CanTransitionTo(update.Status) {
+		return nil
+	}
+
+	message.Status = update.Status
+	if update.Status == common.SMSStatusFailed && update.ErrorMessage != "" {
+		errorMessage := update.ErrorMessage
+		message.ErrorMessage = &errorMessage
+	}
+	if err := s.smsRepo.Update(ctx, message); err != nil {
+		return errors.Wrap(err, "failed to update SMS message")
+	}
+
+	if s.broadcaster != nil {
+		userID := int64(0)
+		if message.UserID != nil {
+			userID = *message.UserID
+		}
+		if err := s.broadcaster.SMSStatus(message.TenantID, userID, toSMSPayload(message)); err != nil {
+			log.Printf("Error broadcasting SMS %d status: %v", message.ID, err)
+		}
+	}
+
+	if s.statusListener != nil {
+		s.statusListener(message)
+	}
+	return nil
+}
+
+// SetInboundListener sets the listener told of each message received
+func (s *smsService) SetInboundListener(listener SMSListener) {
+	s.inboundListener = listener
+}
+
+// SetStatusListener sets the listener told of each delivery status applied
+func (s *smsService) SetStatusListener(listener SMSListener) {
+	s.statusListener = listener
+}
+
+// SetSenderScreen sets the screen messages from blocked senders are caught by
+func (s *smsService) SetSenderScreen(screen SMSScreen) {
+	s.senderScreen = screen
+}
+
+// checkAccess checks the user may read the tenant's messages
+func (s *smsService) checkAccess(ctx context.Context, tenantID string, userID int64) error {
+	role, err := s.roleRepo.FindByUserAndTenant(ctx, userID, tenantID)
+	if err != nil {
+		return errors.NewForbidden("no role in this tenant")
+	}
+	if !role.IsAdmin() && !role.IsSupervisor() && !role.Permissions.CanSendSMS {
+		return errors.NewForbidden("not allowed to view SMS messages")
+	}
+	return nil
+}
+
+// cost returns the provider's price of a message, or the configured price
+// of its segments when the provider does not report one
+func (s *smsService) cost(segments int, providerCost float64) float64 {
+	if providerCost > 0 {
+		return providerCost
+	}
+	return float64(segments) * s.segmentCost
+}
+
+// toSMSResponse converts an SMS message to its response
+func toSMSResponse(message *asterisk.SMSMessage) *dto.SMSResponse {
+	return &dto.SMSResponse{
+		ID:                message.ID,
+		TenantID:          message.TenantID,
+		DIDID:             message.DIDID,
+		UserID:            message.UserID,
+		Direction:         message.Direction,
+		Sender:            message.Sender,
+		Recipient:         message.Recipient,
+		Body:              message.Body,
+		Status:            message.Status,
+		ErrorMessage:      message.ErrorMessage,
+		Segments:          message.Segments,
+		Cost:              message.Cost,
+		Provider:          message.Provider,
+		ProviderMessageID: message.ProviderMessageID,
+		CreatedAt:         message.CreatedAt,
+		UpdatedAt:         message.UpdatedAt,
+	}
+}
+
+// toSMSPayload converts an SMS message to its event payload
+func toSMSPayload(message *asterisk.SMSMessage) *ws.SMSPayload {
+	payload := &ws.SMSPayload{
+		MessageID: message.ID,
+		Direction: string(message.Direction),
+		Sender:    message.Sender,
+		Recipient: message.Recipient,
+		Status:    string(message.Status),
+		Segments:  message.Segments,
+		Cost:      message.Cost,
+	}
+	if message.DIDID != nil {
+		payload.DIDID = *message.DIDID
+	}
+	if message.Body != nil {
+		payload.Body = *message.Body
+	}
+	if message.ErrorMessage != nil {
+		payload.ErrorMessage = *message.ErrorMessage
+	}
+	return payload
+}
@@ -0,0 +1,301 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"strings"
+
+	"github.com/psschand/callcenter/internal/asterisk"
+	"github.com/psschand/callcenter/internal/common"
+	"github.com/psschand/callcenter/internal/core"
+	"github.com/psschand/callcenter/internal/dto"
+	"github.com/psschand/callcenter/internal/repository"
+	"github.com/psschand/callcenter/pkg/errors"
+)
+
+// Random bytes in a generated SIP password
+const sipPasswordBytes = 18
+
+// SoftphoneConfig configures the endpoints provisioned for softphones and
+// the settings softphones are given
+type SoftphoneConfig struct {
+	WSSURL         string   // WebSocket URL softphones register over
+	Domain         string   // SIP domain of softphone URIs
+	Transport      string   // PJSIP transport of the endpoints
+	Context        string   // dialplan context of calls from the endpoints
+	Codecs         string   // codecs the endpoints allow
+	MaxContacts    int      // registrations per endpoint
+	ICEServers     []string // STUN and TURN URLs
+	TURNUsername   string
+	TURNCredential string
+}
+
+// SoftphoneService provisions the PJSIP endpoints agents' WebRTC softphones
+// register with, in the Asterisk realtime (ARA) tables
+type SoftphoneService interface {
+	Provision(ctx context.Context, role *core.UserRole) error
+	Deprovision(ctx context.Context, role *core.UserRole) error
+	GetConfig(ctx context.Context, tenantID string, userID int64) (*dto.SoftphoneConfigResponse, error)
+	RotatePassword(ctx context.Context, tenantID string, actorID, userID int64) (*dto.SoftphoneConfigResponse, error)
+}
+
+type softphoneService struct {
+	endpointRepo repository.PsEndpointRepository
+	authRepo     repository.PsAuthRepository
+	userRepo     repository.UserRepository
+	roleRepo     repository.UserRoleRepository
+	cfg          SoftphoneConfig
+}
+
+// NewSoftphoneService creates a new softphone service
+func NewSoftphoneService(
+	endpointRepo repository.PsEndpointRepository,
+	authRepo repository.PsAuthRepository,
+	userRepo repository.UserRepository,
+	roleRepo repository.UserRoleRepository,
+	cfg SoftphoneConfig,
+) SoftphoneService {
+	if cfg.MaxContacts < 1 {
+		cfg.MaxContacts = 1
+	}
+	return &softphoneService{
+		endpointRepo: endpointRepo,
+		authRepo:     authRepo,
+		userRepo:     userRepo,
+		roleRepo:     roleRepo,
+		cfg:          cfg,
+	}
+}
+
+// takesCalls reports whether a role needs a softphone: agents take calls
+// and supervisors listen in on them
+func takesCalls(role common.UserRole) bool {
+	return role == common.RoleAgent || role == common.RoleSupervisor
+}
+
+// Provision creates an endpoint, auth and AOR for an active user whose role
+// takes calls and records it on the role. Endpoint IDs are derived from the
+// tenant and user, so a user provisioned again after deactivation gets the
+// same endpoint back, and the queues it was a member of still reach it.
+func (s *softphoneService) Provision(ctx context.Context, role *core.UserRole) error {
+	if !takesCalls(role.Role) {
+		return nil
+	}
+	if role.EndpointID != nil && *role.EndpointID != "" {
+		if _, err := s.endpointRepo.FindByID(ctx, *role.EndpointID); err == nil {
+			return nil
+		}
+	}
+
+	user, err := s.userRepo.FindByID(ctx, role.UserID)
+	if err != nil {
+		return errors.NewNotFound("user not found")
+	}
+	if !user.IsActive() {
+		return nil
+	}
+
+	password, err := generateSIPPassword()
+	if err != nil {
+		return errors.Wrap(err, "failed to generate SIP password")
+	}
+
+	id := softphoneEndpointID(role.TenantID, role.UserID)
+	authID := id + "-auth"
+	displayName := user.GetFullName()
+	callerID := fmt.Sprintf("\"%s\" <%s>", strings.ReplaceAll(displayName, "\"", ""), id)
+	yes, no := "yes", "no"
+	authType := "userpass"
+	disallow := "all"
+	dtmfMode := "rfc4733"
+	expiration, qualify := 3600, 60
+
+	endpoint := &asterisk.PsEndpoint{
+		ID:             id,
+		TenantID:       role.TenantID,
+		DisplayName:    &displayName,
+		Transport:      &s.cfg.Transport,
+		Aors:           &id,
+		Auth:           &authID,
+		Context:        &s.cfg.Context,
+		Disallow:       &disallow,
+		Allow:          &s.cfg.Codecs,
+		DirectMedia:    &no,
+		DtmfMode:       &dtmfMode,
+		ForceRport:     &yes,
+		IceSupport:     &yes,
+		RtpSymmetric:   &yes,
+		RewriteContact: &yes,
+		Callerid:       &callerID,
+		// webrtc=yes also turns on AVPF, DTLS-SRTP with a generated
+		// certificate and RTCP multiplexing
+		Webrtc: &yes,
+	}
+	auth := &asterisk.PsAuth{
+		ID:       authID,
+		TenantID: role.TenantID,
+		AuthType: &authType,
+		Username: &id,
+		Password: &password,
+	}
+	aor := &asterisk.PsAor{
+		ID:                  id,
+		TenantID:            role.TenantID,
+		DefaultExpiration:   &expiration,
+		MaxContacts:         &s.cfg.MaxContacts,
+		QualifyFrequency:    &qualify,
+		AuthenticateQualify: &no,
+		RemoveExisting:      &yes,
+	}
+
+	// An endpoint left behind by an earlier role is replaced
+	if _, err := s.endpointRepo.FindByID(ctx, id); err == nil {
+		if err := s.endpointRepo.DeleteWithAuthAndAor(ctx, id); err != nil {
+			return errors.Wrap(err, "failed to replace softphone endpoint")
+		}
+	}
+	if err := s.endpointRepo.CreateWithAuthAndAor(ctx, endpoint, auth, aor); err != nil {
+		return errors.Wrap(err, "failed to create softphone endpoint")
+	}
+
+	role.EndpointID = &id
+	if err := s.roleRepo.Update(ctx, role); err != nil {
+		return errors.Wrap(err, "failed to record softphone endpoint")
+	}
+	return nil
+}
+
+// Deprovision deletes a role's endpoint, auth, AOR and registrations
+func (s *softphoneService) Deprovision(ctx context.Context, role *core.UserRole) error {
+	if role.EndpointID == nil || *role.EndpointID == "" {
+		return nil
+	}
+
+	if _, err := s.endpointRepo.FindByID(ctx, *role.EndpointID); err == nil {
+		if err := s.endpointRepo.DeleteWithAuthAndAor(ctx, *role.EndpointID); err != nil {
+			return errors.Wrap(err, "failed to delete softphone endpoint")
+		}
+	}
+
+	role.EndpointID = nil
+	if err := s.roleRepo.Update(ctx, role); err != nil {
+		return errors.Wrap(err, "failed to clear softphone endpoint")
+	}
+	return nil
+}
+
+// GetConfig gets the settings a user's softphone registers with
+func (s *softphoneService) GetConfig(ctx context.Context, tenantID string, userID int64) (*dto.SoftphoneConfigResponse, error) {
+	endpoint, auth, err := s.findEndpoint(ctx, tenantID, userID)
+	if err != nil {
+		return nil, err
+	}
+	return s.toConfigResponse(endpoint, auth), nil
+}
+
+// RotatePassword gives a user's endpoint a new random SIP password. Users
+// rotate their own; admins may rotate anyone's in the tenant. Softphones
+// registered with the old password fail on their next registration.
+func (s *softphoneService) RotatePassword(ctx context.Context, tenantID string, actorID, userID int64) (*dto.SoftphoneConfigResponse, error) {
+	if actorID != userID {
+		actor, err := s.roleRepo.FindByUserAndTenant(ctx, actorID, tenantID)
+		if err != nil {
+			return nil, errors.NewForbidden("no role in this tenant")
+		}
+		if !actor.IsAdmin() {
+			return nil, errors.NewForbidden("not allowed to manage other users' softphones")
+		}
+	}
+
+	endpoint, auth, err := s.findEndpoint(ctx, tenantID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	password, err := generateSIPPassword()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to generate SIP password")
+	}
+	auth.Password = &password
+	if err := s.authRepo.Update(ctx, auth); err != nil {
+		return nil, errors.Wrap(err, "failed to update SIP password")
+	}
+
+	return s.toConfigResponse(endpoint, auth), nil
+}
+
+// findEndpoint finds the endpoint and auth provisioned for a user's role in a tenant
+func (s *softphoneService) findEndpoint(ctx context.Context, tenantID string, userID int64) (*asterisk.PsEndpoint, *asterisk.PsAuth, error) {
+	role, err := s.roleRepo.FindByUserAndTenant(ctx, userID, tenantID)
+	if err != nil {
+		return nil, nil, errors.NewNotFound("user not found")
+	}
+	if role.EndpointID == nil || *role.EndpointID == "" {
+		return nil, nil, errors.NewNotFound("no softphone is provisioned for this user")
+	}
+
+	endpoint, err := s.endpointRepo.FindByID(ctx, *role.EndpointID)
+	if err != nil || endpoint.TenantID != tenantID || endpoint.Auth == nil {
+		return nil, nil, errors.NewNotFound("softphone endpoint not found")
+	}
+	auth, err := s.authRepo.FindByID(ctx, *endpoint.Auth)
+	if err != nil {
+		return nil, nil, errors.NewNotFound("softphone credentials not found")
+	}
+	return endpoint, auth, nil
+}
+
+// toConfigResponse converts an endpoint and its auth to softphone settings
+func (s *softphoneService) toConfigResponse(endpoint *asterisk.PsEndpoint, auth *asterisk.PsAuth) *dto.SoftphoneConfigResponse {
+	resp := &dto.SoftphoneConfigResponse{
+		EndpointID:  endpoint.ID,
+		Username:    endpoint.ID,
+		Domain:      s.cfg.Domain,
+		URI:         fmt.Sprintf("sip:%s@%s", endpoint.ID, s.cfg.Domain),
+		DisplayName: endpoint.DisplayName,
+		WSSURL:      s.cfg.WSSURL,
+		ICEServers:  []dto.ICEServerResponse{},
+	}
+	if auth.Username != nil {
+		resp.Username = *auth.Username
+	}
+	if auth.Password != nil {
+		resp.Password = *auth.Password
+	}
+
+	var stun []string
+	for _, url := range s.cfg.ICEServers {
+		url = strings.TrimSpace(url)
+		switch {
+		case url == "":
+		case strings.HasPrefix(url, "turn:") || strings.HasPrefix(url, "turns:"):
+			resp.ICEServers = append(resp.ICEServers, dto.ICEServerResponse{
+				URLs:       []string{url},
+				Username:   s.cfg.TURNUsername,
+				Credential: s.cfg.TURNCredential,
+			})
+		default:
+			stun = append(stun, url)
+		}
+	}
+	if len(stun) > 0 {
+		resp.ICEServers = append([]dto.ICEServerResponse{{URLs: stun}}, resp.ICEServers...)
+	}
+	return resp
+}
+
+// softphoneEndpointID returns the tenant-prefixed endpoint ID of a user's softphone
+func softphoneEndpointID(tenantID string, userID int64) string {
+	return fmt.Sprintf("%s-agent%d", tenantID, userID)
+}
+
+// generateSIPPassword returns a random password safe to use in SIP digest auth
+func generateSIPPassword() (string, error) {
+	buf := make([]byte, sipPasswordBytes)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
@@ -0,0 +1,270 @@
+package service
+
+import (
+	"context"
+	stderrors "errors"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/psschand/callcenter/internal/asterisk"
+	"github.com/psschand/callcenter/internal/common"
+	"github.com/psschand/callcenter/internal/repository"
+	"github.com/psschand/callcenter/internal/storage"
+)
+
+// Number of rows the archiver loads at a time
+const archiveBatchSize = 100
+
+// StorageArchiverConfig configures the storage archiver
+type StorageArchiverConfig struct {
+	UploadInterval    time.Duration // how often the spool is swept for recordings and voicemails to upload
+	RetentionInterval time.Duration // how often tenants' retention rules are applied
+	PurgeSpool        bool          // delete spool files once uploaded
+}
+
+// StorageArchiver moves recordings and voicemails from the Asterisk spool into
+// storage and deletes them once they are older than their tenant keeps them
+type StorageArchiver struct {
+	recordingRepo repository.CallRecordingRepository
+	voicemailRepo repository.VoicemailRepository
+	cdrRepo       repository.CDRRepository
+	tenantRepo    repository.TenantRepository
+	store         storage.Storage
+	transcripts   TranscriptionService
+	cfg           StorageArchiverConfig
+}
+
+// NewStorageArchiver creates a new storage archiver
+func NewStorageArchiver(
+	recordingRepo repository.CallRecordingRepository,
+	voicemailRepo repository.VoicemailRepository,
+	cdrRepo repository.CDRRepository,
+	tenantRepo repository.TenantRepository,
+	store storage.Storage,
+	transcripts TranscriptionService,
+	cfg StorageArchiverConfig,
+) *StorageArchiver {
+	return &StorageArchiver{
+		recordingRepo: recordingRepo,
+		voicemailRepo: voicemailRepo,
+		cdrRepo:       cdrRepo,
+		tenantRepo:    tenantRepo,
+		store:         store,
+		transcripts:   transcripts,
+		cfg:           cfg,
+	}
+}
+
+// Start runs the archiver in the background until ctx is cancelled
+func (a *StorageArchiver) Start(ctx context.Context) {
+	go a.run(ctx, a.cfg.UploadInterval, a.archive)
+	go a.run(ctx, a.cfg.RetentionInterval, a.applyRetention)
+	log.Printf("Storage archiver started (%s backend)", a.store.Backend())
+}
+
+// run calls sweep immediately and then every interval
+func (a *StorageArchiver) run(ctx context.Context, interval time.Duration, sweep func(ctx context.Context)) {
+	if interval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		sweep(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// archive uploads completed recordings and voicemails still in the spool
+func (a *StorageArchiver) archive(ctx context.Context) {
+	var afterID int64
+	for {
+		recordings, err := a.recordingRepo.FindUnarchived(ctx, afterID, archiveBatchSize)
+		if err != nil {
+			log.Printf("Error finding recordings to archive: %v", err)
+			break
+		}
+		for i := range recordings {
+			afterID = recordings[i].ID
+			if err := a.archiveRecording(ctx, &recordings[i]); err != nil {
+				log.Printf("Error archiving recording %d: %v", recordings[i].ID, err)
+			}
+		}
+		if len(recordings) < archiveBatchSize {
+			break
+		}
+	}
+
+	afterID = 0
+	for {
+		voicemails, err := a.voicemailRepo.FindUnarchived(ctx, afterID, archiveBatchSize)
+		if err != nil {
+			log.Printf("Error finding voicemails to archive: %v", err)
+			break
+		}
+		for i := range voicemails {
+			afterID = voicemails[i].ID
+			if err := a.archiveVoicemail(ctx, &voicemails[i]); err != nil {
+				log.Printf("Error archiving voicemail %d: %v", voicemails[i].ID, err)
+			}
+		}
+		if len(voicemails) < archiveBatchSize {
+			break
+		}
+	}
+}
+
+// archiveRecording uploads a recording. A recording whose spool file has
+// disappeared can never be played, so it is marked failed.
+func (a *StorageArchiver) archiveRecording(ctx context.Context, recording *asterisk.CallRecording) error {
+	key := fmt.Sprintf("recordings/%s/%s/%s", recording.TenantID, recording.CreatedAt.Format("2006/01/02"), recording.Filename)
+
+	err := a.upload(ctx, recording.FilePath, key, recordingContentType(recording.Format))
+	if stderrors.Is(err, os.ErrNotExist) {
+		log.Printf("Recording %d has no spool file %s, marking it failed", recording.ID, recording.FilePath)
+		recording.Status = common.RecordingStatusFailed
+		return a.recordingRepo.Update(ctx, recording)
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := a.recordingRepo.SetStorage(ctx, recording.ID, a.store.Backend(), key); err != nil {
+		a.store.Delete(ctx, key)
+		return err
+	}
+	a.purge(recording.FilePath)
+	return nil
+}
+
+// archiveVoicemail uploads a voicemail
+func (a *StorageArchiver) archiveVoicemail(ctx context.Context, voicemail *asterisk.Voicemail) error {
+	key := fmt.Sprintf("voicemails/%s/%s/%d.%s", voicemail.TenantID, voicemail.CreatedAt.Format("2006/01/02"), voicemail.ID, voicemail.Format)
+
+	if err := a.upload(ctx, voicemail.FilePath, key, recordingContentType(voicemail.Format)); err != nil {
+		return err
+	}
+
+	if err := a.voicemailRepo.SetStorage(ctx, voicemail.ID, a.store.Backend(), key); err != nil {
+		a.store.Delete(ctx, key)
+		return err
+	}
+	a.purge(voicemail.FilePath)
+	return nil
+}
+
+// upload copies a spool file to storage
+func (a *StorageArchiver) upload(ctx context.Context, path, key, contentType string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return err
+	}
+
+	return a.store.Put(ctx, key, file, info.Size(), contentType)
+}
+
+// purge removes an uploaded spool file, if the archiver is configured to
+func (a *StorageArchiver) purge(path string) {
+	if !a.cfg.PurgeSpool {
+		return
+	}
+	if err := os.Remove(path); err != nil && !stderrors.Is(err, os.ErrNotExist) {
+		log.Printf("Error purging spool file %s: %v", path, err)
+	}
+}
+
+// applyRetention deletes the recordings and voicemails of each tenant that
+// are older than the tenant's retention settings allow
+func (a *StorageArchiver) applyRetention(ctx context.Context) {
+	for page := 1; ; page++ {
+		tenants, _, err := a.tenantRepo.FindAll(ctx, page, archiveBatchSize)
+		if err != nil {
+			log.Printf("Error loading tenants for retention: %v", err)
+			return
+		}
+
+		for _, tenant := range tenants {
+			if days := tenant.Settings.RecordingRetentionDays; days > 0 {
+				a.expireRecordings(ctx, tenant.ID, time.Now().AddDate(0, 0, -days))
+			}
+			if days := tenant.Settings.VoicemailRetentionDays; days > 0 {
+				a.expireVoicemails(ctx, tenant.ID, time.Now().AddDate(0, 0, -days))
+			}
+		}
+
+		if len(tenants) < archiveBatchSize {
+			return
+		}
+	}
+}
+
+// expireRecordings deletes a tenant's recordings created before cutoff
+func (a *StorageArchiver) expireRecordings(ctx context.Context, tenantID string, cutoff time.Time) {
+	deleted := 0
+	defer func() {
+		if deleted > 0 {
+			log.Printf("Deleted %d recordings of tenant %s past retention", deleted, tenantID)
+		}
+	}()
+
+	for {
+		recordings, err := a.recordingRepo.FindExpired(ctx, tenantID, cutoff, archiveBatchSize)
+		if err != nil {
+			log.Printf("Error finding expired recordings of tenant %s: %v", tenantID, err)
+			return
+		}
+		for i := range recordings {
+			if err := deleteRecording(ctx, a.store, a.recordingRepo, a.cdrRepo, a.transcripts, &recordings[i]); err != nil {
+				// Left for the next run rather than retried in a loop
+				log.Printf("Error deleting expired recording %d: %v", recordings[i].ID, err)
+				return
+			}
+			deleted++
+		}
+		if len(recordings) < archiveBatchSize {
+			return
+		}
+	}
+}
+
+// expireVoicemails deletes a tenant's voicemails created before cutoff
+func (a *StorageArchiver) expireVoicemails(ctx context.Context, tenantID string, cutoff time.Time) {
+	deleted := 0
+	defer func() {
+		if deleted > 0 {
+			log.Printf("Deleted %d voicemails of tenant %s past retention", deleted, tenantID)
+		}
+	}()
+
+	for {
+		voicemails, err := a.voicemailRepo.FindExpired(ctx, tenantID, cutoff, archiveBatchSize)
+		if err != nil {
+			log.Printf("Error finding expired voicemails of tenant %s: %v", tenantID, err)
+			return
+		}
+		for i := range voicemails {
+			if err := deleteVoicemail(ctx, a.store, a.voicemailRepo, a.transcripts, &voicemails[i]); err != nil {
+				log.Printf("Error deleting expired voicemail %d: %v", voicemails[i].ID, err)
+				return
+			}
+			deleted++
+		}
+		if len(voicemails) < archiveBatchSize {
+			return
+		}
+	}
+}
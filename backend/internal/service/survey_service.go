@@ -0,0 +1,478 @@
+package service
+
+import (
+	"context"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/psschand/callcenter/internal/asterisk"
+	"github.com/psschand/callcenter/internal/common"
+	"github.com/psschand/callcenter/internal/dto"
+	"github.com/psschand/callcenter/internal/repository"
+	"github.com/psschand/callcenter/pkg/errors"
+)
+
+// SurveyService manages post-call surveys, stores callers' answers and
+// reports CSAT and NPS scores per agent and queue
+type SurveyService interface {
+	List(ctx context.Context, tenantID string, userID int64) ([]*dto.SurveyResponse, error)
+	Get(ctx context.Context, tenantID string, userID, id int64) (*dto.SurveyResponse, error)
+	Create(ctx context.Context, tenantID string, userID int64, req *dto.CreateSurveyRequest) (*dto.SurveyResponse, error)
+	Update(ctx context.Context, tenantID string, userID, id int64, req *dto.UpdateSurveyRequest) (*dto.SurveyResponse, error)
+	Delete(ctx context.Context, tenantID string, userID, id int64) error
+	ListResponses(ctx context.Context, tenantID string, userID int64, query *dto.SurveyResponseQuery, page, pageSize int) ([]dto.SurveyAnswersResponse, int64, error)
+	Report(ctx context.Context, tenantID string, userID int64, query *dto.SurveyResponseQuery) (*dto.SurveyReportResponse, error)
+	OnSurveyResult(result asterisk.SurveyResult)
+}
+
+type surveyService struct {
+	surveyRepo repository.SurveyRepository
+	cdrRepo    repository.CDRRepository
+	userRepo   repository.UserRepository
+	roleRepo   repository.UserRoleRepository
+}
+
+// NewSurveyService creates a new survey service
+func NewSurveyService(
+	surveyRepo repository.SurveyRepository,
+	cdrRepo repository.CDRRepository,
+	userRepo repository.UserRepository,
+	roleRepo repository.UserRoleRepository,
+) SurveyService {
+	return &surveyService{
+		surveyRepo: surveyRepo,
+		cdrRepo:    cdrRepo,
+		userRepo:   userRepo,
+		roleRepo:   roleRepo,
+	}
+}
+
+// List lists the tenant's surveys
+func (s *surveyService) List(ctx context.Context, tenantID string, userID int64) ([]*dto.SurveyResponse, error) {
+	if err := s.checkSurveys(ctx, tenantID, userID, false); err != nil {
+		return nil, err
+	}
+
+	surveys, err := s.surveyRepo.FindByTenant(ctx, tenantID)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list surveys")
+	}
+
+	responses := make([]*dto.SurveyResponse, len(surveys))
+	for i := range surveys {
+		responses[i] = toSurveyResponse(&surveys[i])
+	}
+	return responses, nil
+}
+
+// Get gets one of the tenant's surveys
+func (s *surveyService) Get(ctx context.Context, tenantID string, userID, id int64) (*dto.SurveyResponse, error) {
+	if err := s.checkSurveys(ctx, tenantID, userID, false); err != nil {
+		return nil, err
+	}
+
+	survey, err := findTenantSurvey(ctx, s.surveyRepo, tenantID, id)
+	if err != nil {
+		return nil, err
+	}
+	return toSurveyResponse(survey), nil
+}
+
+// Create creates a survey
+func (s *surveyService) Create(ctx context.Context, tenantID string, userID int64, req *dto.CreateSurveyRequest) (*dto.SurveyResponse, error) {
+	if err := s.checkSurveys(ctx, tenantID, userID, true); err != nil {
+		return nil, err
+	}
+
+	survey := &asterisk.CallSurvey{
+		TenantID:       tenantID,
+		Name:           req.Name,
+		Description:    req.Description,
+		Questions:      fromSurveyQuestions(req.Questions),
+		TriggerOn:      asterisk.SurveyTriggerCallEnd,
+		OptInPrompt:    req.OptInPrompt,
+		IntroPrompt:    req.IntroPrompt,
+		ThankYouPrompt: req.ThankYouPrompt,
+		IsActive:       true,
+	}
+	if req.IsActive != nil {
+		survey.IsActive = *req.IsActive
+	}
+
+	if err := validateSurvey(survey); err != nil {
+		return nil, err
+	}
+
+	if err := s.surveyRepo.Create(ctx, survey); err != nil {
+		return nil, errors.Wrap(err, "failed to create survey")
+	}
+
+	log.Printf("User %d created survey %d (%s) for tenant %s", userID, survey.ID, survey.Name, tenantID)
+	return toSurveyResponse(survey), nil
+}
+
+// Update updates one of the tenant's surveys
+func (s *surveyService) Update(ctx context.Context, tenantID string, userID, id int64, req *dto.UpdateSurveyRequest) (*dto.SurveyResponse, error) {
+	if err := s.checkSurveys(ctx, tenantID, userID, true); err != nil {
+		return nil, err
+	}
+
+	survey, err := findTenantSurvey(ctx, s.surveyRepo, tenantID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Name != nil {
+		survey.Name = *req.Name
+	}
+	if req.Description != nil {
+		survey.Description = optionalString(*req.Description)
+	}
+	if req.Questions != nil {
+		survey.Questions = fromSurveyQuestions(req.Questions)
+	}
+	if req.OptInPrompt != nil {
+		survey.OptInPrompt = *req.OptInPrompt
+	}
+	if req.IntroPrompt != nil {
+		survey.IntroPrompt = optionalString(*req.IntroPrompt)
+	}
+	if req.ThankYouPrompt != nil {
+		survey.ThankYouPrompt = optionalString(*req.ThankYouPrompt)
+	}
+	if req.IsActive != nil {
+		survey.IsActive = *req.IsActive
+	}
+
+	if err := validateSurvey(survey); err != nil {
+		return nil, err
+	}
+
+	if err := s.surveyRepo.Update(ctx, survey); err != nil {
+		return nil, errors.Wrap(err, "failed to update survey")
+	}
+	return toSurveyResponse(survey), nil
+}
+
+// Delete deletes one of the tenant's surveys and its responses. DIDs and
+// queues that used it stop offering a survey.
+func (s *surveyService) Delete(ctx context.Context, tenantID string, userID, id int64) error {
+	if err := s.checkSurveys(ctx, tenantID, userID, true); err != nil {
+		return err
+	}
+
+	survey, err := findTenantSurvey(ctx, s.surveyRepo, tenantID, id)
+	if err != nil {
+		return err
+	}
+
+	if err := s.surveyRepo.Delete(ctx, survey.ID); err != nil {
+		return errors.Wrap(err, "failed to delete survey")
+	}
+
+	log.Printf("User %d deleted survey %d (%s) of tenant %s", userID, survey.ID, survey.Name, tenantID)
+	return nil
+}
+
+// ListResponses lists callers' survey answers within a time range, newest first
+func (s *surveyService) ListResponses(ctx context.Context, tenantID string, userID int64, query *dto.SurveyResponseQuery, page, pageSize int) ([]dto.SurveyAnswersResponse, int64, error) {
+	if err := s.checkViewReports(ctx, tenantID, userID); err != nil {
+		return nil, 0, err
+	}
+	if query.EndDate.Before(query.StartDate) {
+		return nil, 0, errors.NewValidation(map[string]string{"end_date": "must not be before start_date"})
+	}
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	responses, total, err := s.surveyRepo.FindResponses(ctx, tenantID, toSurveyResponseFilter(query), page, pageSize)
+	if err != nil {
+		return nil, 0, errors.Wrap(err, "failed to get survey responses")
+	}
+
+	result := make([]dto.SurveyAnswersResponse, len(responses))
+	for i := range responses {
+		result[i] = toSurveyAnswersResponse(&responses[i])
+	}
+	return result, total, nil
+}
+
+// Report reports CSAT and NPS scores per agent and per queue within a time range
+func (s *surveyService) Report(ctx context.Context, tenantID string, userID int64, query *dto.SurveyResponseQuery) (*dto.SurveyReportResponse, error) {
+	if err := s.checkViewReports(ctx, tenantID, userID); err != nil {
+		return nil, err
+	}
+	if query.EndDate.Before(query.StartDate) {
+		return nil, errors.NewValidation(map[string]string{"end_date": "must not be before start_date"})
+	}
+
+	filter := toSurveyResponseFilter(query)
+	byAgent, err := s.surveyRepo.ScoresByAgent(ctx, tenantID, filter)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to total survey scores by agent")
+	}
+	byQueue, err := s.surveyRepo.ScoresByQueue(ctx, tenantID, filter)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to total survey scores by queue")
+	}
+
+	result := &dto.SurveyReportResponse{
+		StartDate: query.StartDate,
+		EndDate:   query.EndDate,
+		Agents:    make([]dto.SurveyScores, len(byAgent)),
+		Queues:    make([]dto.SurveyScores, len(byQueue)),
+	}
+	if query.SurveyID != 0 {
+		result.SurveyID = &query.SurveyID
+	}
+
+	var all repository.SurveyScoreTotal
+	for i, total := range byAgent {
+		result.Agents[i] = toSurveyScores(total)
+		if total.UserID != nil {
+			if user, err := s.userRepo.FindByID(ctx, *total.UserID); err == nil {
+				result.Agents[i].AgentName = user.GetFullName()
+			}
+		}
+
+		all.Responses += total.Responses
+		all.CSATResponses += total.CSATResponses
+		all.CSATSum += total.CSATSum
+		all.CSATSatisfied += total.CSATSatisfied
+		all.NPSResponses += total.NPSResponses
+		all.Promoters += total.Promoters
+		all.Detractors += total.Detractors
+	}
+	for i, total := range byQueue {
+		result.Queues[i] = toSurveyScores(total)
+	}
+	sort.SliceStable(result.Agents, func(i, j int) bool {
+		return result.Agents[i].AgentName < result.Agents[j].AgentName
+	})
+	result.Totals = toSurveyScores(all)
+
+	return result, nil
+}
+
+// OnSurveyResult stores a caller's survey answers, linked to the CDR of
+// their call and the agent who took it
+func (s *surveyService) OnSurveyResult(result asterisk.SurveyResult) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	response := &asterisk.SurveyResponse{
+		TenantID:     result.TenantID,
+		SurveyID:     result.SurveyID,
+		UniqueID:     result.UniqueID,
+		LinkedID:     result.LinkedID,
+		CallerNumber: result.CallerNumber,
+		DIDID:        result.DIDID,
+		QueueName:    optionalString(result.QueueName),
+		Responses:    asterisk.SurveyAnswers(result.Answers),
+		Completed:    result.Completed,
+	}
+	response.SetScores()
+
+	if result.AgentEndpoint != "" {
+		response.AgentEndpoint = &result.AgentEndpoint
+		if role, err := s.roleRepo.FindByEndpoint(ctx, result.TenantID, result.AgentEndpoint); err == nil {
+			response.UserID = &role.UserID
+		}
+	}
+	// The CDR is written as the survey starts, so it is normally there by now
+	if cdr, err := s.cdrRepo.FindByUniqueID(ctx, result.TenantID, result.UniqueID); err == nil {
+		response.CDRID = &cdr.ID
+	}
+
+	if err := s.surveyRepo.CreateResponse(ctx, response); err != nil {
+		log.Printf("Error storing survey %d response for call %s: %v", result.SurveyID, result.UniqueID, err)
+	}
+}
+
+// checkSurveys checks the user may see the tenant's surveys, and change
+// them if manage is set
+func (s *surveyService) checkSurveys(ctx context.Context, tenantID string, userID int64, manage bool) error {
+	role, err := s.roleRepo.FindByUserAndTenant(ctx, userID, tenantID)
+	if err != nil {
+		return errors.NewForbidden("no role in this tenant")
+	}
+	if manage && !role.IsAdmin() && !role.Permissions.CanManageSettings {
+		return errors.NewForbidden("not allowed to manage surveys")
+	}
+	return nil
+}
+
+// checkViewReports checks the user may see survey responses and scores
+func (s *surveyService) checkViewReports(ctx context.Context, tenantID string, userID int64) error {
+	role, err := s.roleRepo.FindByUserAndTenant(ctx, userID, tenantID)
+	if err != nil {
+		return errors.NewForbidden("no role in this tenant")
+	}
+	if !role.Permissions.CanViewReports {
+		return errors.NewForbidden("not allowed to view reports")
+	}
+	return nil
+}
+
+// findTenantSurvey loads one of a tenant's surveys
+func findTenantSurvey(ctx context.Context, repo repository.SurveyRepository, tenantID string, id int64) (*asterisk.CallSurvey, error) {
+	survey, err := repo.FindByID(ctx, id)
+	if err != nil || survey.TenantID != tenantID {
+		return nil, errors.NewNotFound("survey not found")
+	}
+	return survey, nil
+}
+
+// validateSurveyLink checks the survey a DID or queue offers belongs to its tenant
+func validateSurveyLink(ctx context.Context, repo repository.SurveyRepository, tenantID string, surveyID *int64) error {
+	if surveyID == nil {
+		return nil
+	}
+	if _, err := findTenantSurvey(ctx, repo, tenantID, *surveyID); err != nil {
+		return errors.NewValidation("survey not found")
+	}
+	return nil
+}
+
+// validateSurvey checks a survey about to be saved
+func validateSurvey(survey *asterisk.CallSurvey) error {
+	fields := map[string]string{}
+	if strings.TrimSpace(survey.Name) == "" {
+		fields["name"] = "is required"
+	}
+	if strings.TrimSpace(survey.OptInPrompt) == "" {
+		fields["opt_in_prompt"] = "is required"
+	}
+	if err := survey.Questions.Validate(); err != nil {
+		fields["questions"] = err.Error()
+	}
+
+	if len(fields) > 0 {
+		return errors.NewValidation(fields)
+	}
+	return nil
+}
+
+// fromSurveyQuestions converts questions from a request; yes/no questions
+// need no range
+func fromSurveyQuestions(questions []dto.SurveyQuestion) asterisk.SurveyQuestions {
+	result := make(asterisk.SurveyQuestions, len(questions))
+	for i, q := range questions {
+		result[i] = asterisk.SurveyQuestion{
+			Label:  q.Label,
+			Prompt: q.Prompt,
+			Type:   q.Type,
+			Min:    q.Min,
+			Max:    q.Max,
+			Metric: q.Metric,
+		}
+		if q.Type != common.SurveyQuestionRating {
+			result[i].Min, result[i].Max = 0, 0
+		}
+	}
+	return result
+}
+
+// toSurveyResponse converts a survey to its response
+func toSurveyResponse(survey *asterisk.CallSurvey) *dto.SurveyResponse {
+	questions := make([]dto.SurveyQuestion, len(survey.Questions))
+	for i, q := range survey.Questions {
+		questions[i] = dto.SurveyQuestion{
+			Label:  q.Label,
+			Prompt: q.Prompt,
+			Type:   q.Type,
+			Min:    q.Min,
+			Max:    q.Max,
+			Metric: q.Metric,
+		}
+	}
+
+	return &dto.SurveyResponse{
+		ID:             survey.ID,
+		TenantID:       survey.TenantID,
+		Name:           survey.Name,
+		Description:    survey.Description,
+		Questions:      questions,
+		TriggerOn:      survey.TriggerOn,
+		OptInPrompt:    survey.OptInPrompt,
+		IntroPrompt:    survey.IntroPrompt,
+		ThankYouPrompt: survey.ThankYouPrompt,
+		IsActive:       survey.IsActive,
+		CreatedAt:      survey.CreatedAt,
+		UpdatedAt:      survey.UpdatedAt,
+	}
+}
+
+// toSurveyAnswersResponse converts a stored survey response
+func toSurveyAnswersResponse(response *asterisk.SurveyResponse) dto.SurveyAnswersResponse {
+	answers := make([]dto.SurveyAnswer, len(response.Responses))
+	for i, answer := range response.Responses {
+		answers[i] = dto.SurveyAnswer{
+			Question: answer.Question,
+			Label:    answer.Label,
+			Type:     answer.Type,
+			Metric:   answer.Metric,
+			Value:    answer.Value,
+		}
+	}
+
+	return dto.SurveyAnswersResponse{
+		ID:            response.ID,
+		SurveyID:      response.SurveyID,
+		CDRID:         response.CDRID,
+		UniqueID:      response.UniqueID,
+		CallerNumber:  response.CallerNumber,
+		DIDID:         response.DIDID,
+		QueueName:     response.QueueName,
+		AgentEndpoint: response.AgentEndpoint,
+		UserID:        response.UserID,
+		Answers:       answers,
+		OverallRating: response.OverallRating,
+		CSATScore:     response.CSATScore,
+		NPSScore:      response.NPSScore,
+		Completed:     response.Completed,
+		CompletedAt:   response.CompletedAt,
+	}
+}
+
+// toSurveyResponseFilter converts a response query for the repository
+func toSurveyResponseFilter(query *dto.SurveyResponseQuery) repository.SurveyResponseFilter {
+	return repository.SurveyResponseFilter{
+		SurveyID:  query.SurveyID,
+		UserID:    query.UserID,
+		QueueName: query.QueueName,
+		Start:     query.StartDate,
+		End:       query.EndDate,
+	}
+}
+
+// toSurveyScores works out the CSAT and NPS scores of a total
+func toSurveyScores(total repository.SurveyScoreTotal) dto.SurveyScores {
+	scores := dto.SurveyScores{
+		UserID:        total.UserID,
+		QueueName:     total.QueueName,
+		Responses:     total.Responses,
+		CSATResponses: total.CSATResponses,
+		NPSResponses:  total.NPSResponses,
+		Promoters:     total.Promoters,
+		Passives:      total.NPSResponses - total.Promoters - total.Detractors,
+		Detractors:    total.Detractors,
+	}
+	if total.CSATResponses > 0 {
+		average := float64(total.CSATSum) / float64(total.CSATResponses)
+		csat := float64(total.CSATSatisfied) / float64(total.CSATResponses) * 100
+		scores.CSATAverage = &average
+		scores.CSAT = &csat
+	}
+	if total.NPSResponses > 0 {
+		nps := float64(total.Promoters-total.Detractors) / float64(total.NPSResponses) * 100
+		scores.NPS = &nps
+	}
+	return scores
+}
@@ -0,0 +1,289 @@
+package service
+
+import (
+	"context"
+	stderrors "errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/psschand/callcenter/internal/asterisk"
+	"github.com/psschand/callcenter/internal/common"
+	"github.com/psschand/callcenter/internal/repository"
+	"github.com/psschand/callcenter/internal/storage"
+	"github.com/psschand/callcenter/internal/transcription"
+)
+
+// errTranscriptSourceGone is returned for jobs whose recording or voicemail
+// has been deleted or never completed; they are not retried
+var errTranscriptSourceGone = stderrors.New("recording or voicemail is no longer available")
+
+// TranscriberConfig configures the transcriber
+type TranscriberConfig struct {
+	Workers      int           // jobs transcribed at once
+	PollInterval time.Duration // how often the queue is checked for jobs when idle
+	MaxAttempts  int           // attempts before a job is marked failed
+	Language     string        // language of tenants without one set; empty lets the provider detect it
+}
+
+// Transcriber works through the transcription queue in the background,
+// transcribing recordings and voicemails with the configured provider
+type Transcriber struct {
+	jobRepo        repository.TranscriptionJobRepository
+	transcriptRepo repository.TranscriptRepository
+	recordingRepo  repository.CallRecordingRepository
+	voicemailRepo  repository.VoicemailRepository
+	tenantRepo     repository.TenantRepository
+	provider       transcription.Provider
+	store          storage.Storage
+	cfg            TranscriberConfig
+}
+
+// transcriptAudio is the audio of a recording or voicemail to transcribe
+type transcriptAudio struct {
+	filename   string
+	format     string
+	filePath   string
+	storageKey *string
+	duration   int
+	userID     *int64
+}
+
+// NewTranscriber creates a new transcriber
+func NewTranscriber(
+	jobRepo repository.TranscriptionJobRepository,
+	transcriptRepo repository.TranscriptRepository,
+	recordingRepo repository.CallRecordingRepository,
+	voicemailRepo repository.VoicemailRepository,
+	tenantRepo repository.TenantRepository,
+	provider transcription.Provider,
+	store storage.Storage,
+	cfg TranscriberConfig,
+) *Transcriber {
+	if cfg.Workers < 1 {
+		cfg.Workers = 1
+	}
+	if cfg.MaxAttempts < 1 {
+		cfg.MaxAttempts = 1
+	}
+	return &Transcriber{
+		jobRepo:        jobRepo,
+		transcriptRepo: transcriptRepo,
+		recordingRepo:  recordingRepo,
+		voicemailRepo:  voicemailRepo,
+		tenantRepo:     tenantRepo,
+		provider:       provider,
+		store:          store,
+		cfg:            cfg,
+	}
+}
+
+// Start runs the transcriber's workers in the background until ctx is
+// cancelled. Jobs left processing when the server last stopped are queued again.
+func (t *Transcriber) Start(ctx context.Context) {
+	if count, err := t.jobRepo.ResetProcessing(ctx); err != nil {
+		log.Printf("Error requeueing interrupted transcription jobs: %v", err)
+	} else if count > 0 {
+		log.Printf("Requeued %d interrupted transcription jobs", count)
+	}
+
+	for i := 0; i < t.cfg.Workers; i++ {
+		go t.run(ctx)
+	}
+	log.Printf("Transcriber started (%s provider, %d workers)", t.provider.Name(), t.cfg.Workers)
+}
+
+// run transcribes queued jobs until none is due, then waits for the next poll
+func (t *Transcriber) run(ctx context.Context) {
+	if t.cfg.PollInterval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(t.cfg.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		for ctx.Err() == nil {
+			job, err := t.jobRepo.ClaimNext(ctx)
+			if err != nil {
+				log.Printf("Error claiming transcription job: %v", err)
+				break
+			}
+			if job == nil {
+				break
+			}
+			t.process(ctx, job)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// process transcribes a job's recording or voicemail. Failures are retried
+// with a growing delay until the job runs out of attempts.
+func (t *Transcriber) process(ctx context.Context, job *asterisk.TranscriptionJob) {
+	err := t.transcribe(ctx, job)
+	if err == nil {
+		if err := t.jobRepo.Complete(ctx, job.ID); err != nil {
+			log.Printf("Error completing transcription job %d: %v", job.ID, err)
+		}
+		return
+	}
+	if ctx.Err() != nil {
+		// Shutting down: the job is requeued on the next start
+		return
+	}
+
+	log.Printf("Error transcribing %s %d (attempt %d): %v", job.SourceType, job.SourceID, job.Attempts, err)
+	permanent := stderrors.Is(err, errTranscriptSourceGone) || stderrors.Is(err, transcription.ErrUnsupportedAudio)
+	if permanent || job.Attempts >= t.cfg.MaxAttempts {
+		err = t.jobRepo.Fail(ctx, job.ID, err.Error())
+	} else {
+		retryAt := time.Now().Add(time.Duration(job.Attempts*job.Attempts) * time.Minute)
+		err = t.jobRepo.Retry(ctx, job.ID, err.Error(), retryAt)
+	}
+	if err != nil {
+		log.Printf("Error updating transcription job %d: %v", job.ID, err)
+	}
+}
+
+// transcribe transcribes a job's audio and stores the transcript
+func (t *Transcriber) transcribe(ctx context.Context, job *asterisk.TranscriptionJob) error {
+	audio, err := t.loadAudio(ctx, job)
+	if err != nil {
+		return err
+	}
+
+	content, err := t.openAudio(ctx, audio)
+	if err != nil {
+		return err
+	}
+	defer content.Close()
+
+	result, err := t.provider.Transcribe(ctx, content, transcription.Options{
+		Filename: audio.filename,
+		Format:   audio.format,
+		Language: t.language(ctx, job.TenantID),
+	})
+	if err != nil {
+		return err
+	}
+
+	transcript := &asterisk.Transcript{
+		TenantID:   job.TenantID,
+		SourceType: job.SourceType,
+		SourceID:   job.SourceID,
+		UserID:     audio.userID,
+		Provider:   t.provider.Name(),
+		Text:       result.Text,
+		Duration:   int(result.Duration.Seconds()),
+		Segments:   make([]asterisk.TranscriptSegment, 0, len(result.Segments)),
+	}
+	if transcript.Duration == 0 {
+		transcript.Duration = audio.duration
+	}
+	if result.Language != "" {
+		transcript.Language = &result.Language
+	}
+	for _, segment := range result.Segments {
+		stored := asterisk.TranscriptSegment{
+			StartMs: segment.Start.Milliseconds(),
+			EndMs:   segment.End.Milliseconds(),
+			Text:    segment.Text,
+		}
+		if segment.Speaker != "" {
+			speaker := segment.Speaker
+			stored.Speaker = &speaker
+		}
+		transcript.Segments = append(transcript.Segments, stored)
+	}
+
+	if err := t.transcriptRepo.Save(ctx, transcript); err != nil {
+		return fmt.Errorf("failed to save transcript: %w", err)
+	}
+
+	if job.SourceType == common.TranscriptSourceVoicemail {
+		if err := t.voicemailRepo.SetTranscription(ctx, job.SourceID, result.Text); err != nil {
+			log.Printf("Error setting transcription of voicemail %d: %v", job.SourceID, err)
+		}
+	}
+
+	log.Printf("Transcribed %s %d (%d segments)", job.SourceType, job.SourceID, len(transcript.Segments))
+	return nil
+}
+
+// loadAudio finds the audio of a job's recording or voicemail
+func (t *Transcriber) loadAudio(ctx context.Context, job *asterisk.TranscriptionJob) (*transcriptAudio, error) {
+	switch job.SourceType {
+	case common.TranscriptSourceRecording:
+		recording, err := t.recordingRepo.FindByID(ctx, job.SourceID)
+		if err != nil || recording.TenantID != job.TenantID || !recording.IsCompleted() {
+			return nil, errTranscriptSourceGone
+		}
+		return &transcriptAudio{
+			filename:   recording.Filename,
+			format:     recording.Format,
+			filePath:   recording.FilePath,
+			storageKey: recording.StorageKey,
+			duration:   recording.Duration,
+		}, nil
+	case common.TranscriptSourceVoicemail:
+		voicemail, err := t.voicemailRepo.FindByID(ctx, job.SourceID)
+		if err != nil || voicemail.TenantID != job.TenantID || voicemail.IsDeleted {
+			return nil, errTranscriptSourceGone
+		}
+		return &transcriptAudio{
+			filename:   fmt.Sprintf("voicemail-%d.%s", voicemail.ID, voicemail.Format),
+			format:     voicemail.Format,
+			filePath:   voicemail.FilePath,
+			storageKey: voicemail.StorageKey,
+			duration:   voicemail.Duration,
+			userID:     voicemail.UserID,
+		}, nil
+	default:
+		return nil, errTranscriptSourceGone
+	}
+}
+
+// openAudio opens audio from storage once archived, or from the Asterisk spool before
+func (t *Transcriber) openAudio(ctx context.Context, audio *transcriptAudio) (io.ReadCloser, error) {
+	if audio.storageKey != nil {
+		object, err := t.store.Open(ctx, *audio.storageKey)
+		if stderrors.Is(err, storage.ErrNotFound) {
+			return nil, errTranscriptSourceGone
+		}
+		if err != nil {
+			return nil, err
+		}
+		return object.Content, nil
+	}
+
+	file, err := os.Open(audio.filePath)
+	if stderrors.Is(err, os.ErrNotExist) {
+		return nil, errTranscriptSourceGone
+	}
+	if err != nil {
+		return nil, err
+	}
+	return file, nil
+}
+
+// language returns the ISO-639-1 code of the language a tenant's audio is transcribed in
+func (t *Transcriber) language(ctx context.Context, tenantID string) string {
+	language := t.cfg.Language
+	if tenant, err := t.tenantRepo.FindByID(ctx, tenantID); err == nil && tenant.Settings.Language != "" {
+		language = tenant.Settings.Language
+	}
+	// Tenant settings may carry a locale such as en-US
+	if i := strings.IndexAny(language, "-_"); i > 0 {
+		language = language[:i]
+	}
+	return strings.ToLower(language)
+}
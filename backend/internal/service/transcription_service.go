@@ -0,0 +1,217 @@
+package service
+
+import (
+	"context"
+	"log"
+	"strings"
+
+	"github.com/psschand/callcenter/internal/asterisk"
+	"github.com/psschand/callcenter/internal/common"
+	"github.com/psschand/callcenter/internal/dto"
+	"github.com/psschand/callcenter/internal/repository"
+	"github.com/psschand/callcenter/internal/transcription"
+	"github.com/psschand/callcenter/pkg/errors"
+)
+
+// Most matching segments returned with each search result
+const maxSearchMatches = 3
+
+// TranscriptionService queues call recordings and voicemails for
+// speech-to-text and serves their transcripts. Callers check the user may
+// access a recording or voicemail before asking for its transcript.
+type TranscriptionService interface {
+	Enqueue(ctx context.Context, tenantID string, sourceType common.TranscriptSource, sourceID int64)
+	Transcribe(ctx context.Context, tenantID string, sourceType common.TranscriptSource, sourceID int64) (*dto.TranscriptResponse, error)
+	GetTranscript(ctx context.Context, tenantID string, sourceType common.TranscriptSource, sourceID int64) (*dto.TranscriptResponse, error)
+	Search(ctx context.Context, tenantID string, userID int64, query string, sourceType common.TranscriptSource, page, pageSize int) ([]*dto.TranscriptSearchResult, int64, error)
+	DeleteTranscript(ctx context.Context, sourceType common.TranscriptSource, sourceID int64) error
+}
+
+type transcriptionService struct {
+	jobRepo        repository.TranscriptionJobRepository
+	transcriptRepo repository.TranscriptRepository
+	tenantRepo     repository.TenantRepository
+	roleRepo       repository.UserRoleRepository
+	provider       transcription.Provider
+}
+
+// NewTranscriptionService creates a new transcription service. provider is
+// nil when transcription is turned off.
+func NewTranscriptionService(
+	jobRepo repository.TranscriptionJobRepository,
+	transcriptRepo repository.TranscriptRepository,
+	tenantRepo repository.TenantRepository,
+	roleRepo repository.UserRoleRepository,
+	provider transcription.Provider,
+) TranscriptionService {
+	return &transcriptionService{
+		jobRepo:        jobRepo,
+		transcriptRepo: transcriptRepo,
+		tenantRepo:     tenantRepo,
+		roleRepo:       roleRepo,
+		provider:       provider,
+	}
+}
+
+// Enqueue queues a new recording or voicemail for transcription if its
+// tenant has transcription turned on
+func (s *transcriptionService) Enqueue(ctx context.Context, tenantID string, sourceType common.TranscriptSource, sourceID int64) {
+	if s.provider == nil {
+		return
+	}
+
+	tenant, err := s.tenantRepo.FindByID(ctx, tenantID)
+	if err != nil {
+		log.Printf("Error loading tenant %s for transcription: %v", tenantID, err)
+		return
+	}
+	if !tenant.Settings.Transcription {
+		return
+	}
+
+	if _, err := s.jobRepo.Enqueue(ctx, tenantID, sourceType, sourceID); err != nil {
+		log.Printf("Error queueing %s %d for transcription: %v", sourceType, sourceID, err)
+	}
+}
+
+// Transcribe queues a recording or voicemail for transcription on request,
+// whatever its tenant's setting, replacing any transcript it already has
+// once done
+func (s *transcriptionService) Transcribe(ctx context.Context, tenantID string, sourceType common.TranscriptSource, sourceID int64) (*dto.TranscriptResponse, error) {
+	if s.provider == nil {
+		return nil, errors.NewConflict("transcription is not configured")
+	}
+
+	job, err := s.jobRepo.Enqueue(ctx, tenantID, sourceType, sourceID)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to queue transcription")
+	}
+
+	transcript, err := s.transcriptRepo.FindBySource(ctx, sourceType, sourceID)
+	if err != nil {
+		transcript = nil
+	}
+	return toTranscriptResponse(sourceType, sourceID, job, transcript), nil
+}
+
+// GetTranscript gets the transcript of a recording or voicemail with the
+// state of its transcription
+func (s *transcriptionService) GetTranscript(ctx context.Context, tenantID string, sourceType common.TranscriptSource, sourceID int64) (*dto.TranscriptResponse, error) {
+	job, err := s.jobRepo.FindBySource(ctx, sourceType, sourceID)
+	if err != nil || job.TenantID != tenantID {
+		job = nil
+	}
+	transcript, err := s.transcriptRepo.FindBySource(ctx, sourceType, sourceID)
+	if err != nil || transcript.TenantID != tenantID {
+		transcript = nil
+	}
+	if job == nil && transcript == nil {
+		return nil, errors.NewNotFound("transcript not found")
+	}
+	return toTranscriptResponse(sourceType, sourceID, job, transcript), nil
+}
+
+// Search finds the transcripts matching a full-text query, with the segments
+// that match. Users who cannot manage mailboxes only find voicemails left
+// in their own.
+func (s *transcriptionService) Search(ctx context.Context, tenantID string, userID int64, query string, sourceType common.TranscriptSource, page, pageSize int) ([]*dto.TranscriptSearchResult, int64, error) {
+	query = strings.TrimSpace(query)
+	if query == "" {
+		return nil, 0, errors.NewValidation(map[string]string{"q": "search query is required"})
+	}
+	if sourceType != "" && sourceType != common.TranscriptSourceRecording && sourceType != common.TranscriptSourceVoicemail {
+		return nil, 0, errors.NewValidation(map[string]string{"source": "must be recording or voicemail"})
+	}
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	role, err := s.roleRepo.FindByUserAndTenant(ctx, userID, tenantID)
+	if err != nil {
+		return nil, 0, errors.NewForbidden("no role in this tenant")
+	}
+	voicemailOwner := int64(0)
+	if !canManageMailboxes(role) {
+		voicemailOwner = userID
+	}
+
+	transcripts, total, err := s.transcriptRepo.Search(ctx, tenantID, query, sourceType, voicemailOwner, page, pageSize)
+	if err != nil {
+		return nil, 0, errors.Wrap(err, "failed to search transcripts")
+	}
+
+	ids := make([]int64, len(transcripts))
+	for i := range transcripts {
+		ids[i] = transcripts[i].ID
+	}
+	segments, err := s.transcriptRepo.FindMatchingSegments(ctx, ids, query)
+	if err != nil {
+		return nil, 0, errors.Wrap(err, "failed to search transcript segments")
+	}
+	matches := make(map[int64][]dto.TranscriptSegmentResponse)
+	for i := range segments {
+		if len(matches[segments[i].TranscriptID]) < maxSearchMatches {
+			matches[segments[i].TranscriptID] = append(matches[segments[i].TranscriptID], toTranscriptSegmentResponse(&segments[i]))
+		}
+	}
+
+	results := make([]*dto.TranscriptSearchResult, len(transcripts))
+	for i := range transcripts {
+		result := &dto.TranscriptSearchResult{
+			SourceType: transcripts[i].SourceType,
+			SourceID:   transcripts[i].SourceID,
+			Language:   transcripts[i].Language,
+			Duration:   transcripts[i].Duration,
+			Matches:    matches[transcripts[i].ID],
+			CreatedAt:  transcripts[i].CreatedAt,
+		}
+		if result.Matches == nil {
+			result.Matches = []dto.TranscriptSegmentResponse{}
+		}
+		results[i] = result
+	}
+	return results, total, nil
+}
+
+// DeleteTranscript deletes the transcript of a recording or voicemail whose audio is being deleted
+func (s *transcriptionService) DeleteTranscript(ctx context.Context, sourceType common.TranscriptSource, sourceID int64) error {
+	return s.transcriptRepo.DeleteBySource(ctx, sourceType, sourceID)
+}
+
+// toTranscriptResponse converts a transcript and its job to their response; either may be nil
+func toTranscriptResponse(sourceType common.TranscriptSource, sourceID int64, job *asterisk.TranscriptionJob, transcript *asterisk.Transcript) *dto.TranscriptResponse {
+	resp := &dto.TranscriptResponse{
+		SourceType: sourceType,
+		SourceID:   sourceID,
+		Status:     common.TranscriptionStatusCompleted,
+		Segments:   []dto.TranscriptSegmentResponse{},
+	}
+	if job != nil {
+		resp.Status = job.Status
+		resp.Error = job.Error
+		resp.CompletedAt = job.CompletedAt
+	}
+	if transcript != nil {
+		resp.Provider = transcript.Provider
+		resp.Language = transcript.Language
+		resp.Text = transcript.Text
+		resp.Duration = transcript.Duration
+		for i := range transcript.Segments {
+			resp.Segments = append(resp.Segments, toTranscriptSegmentResponse(&transcript.Segments[i]))
+		}
+	}
+	return resp
+}
+
+// toTranscriptSegmentResponse converts a transcript segment to its response
+func toTranscriptSegmentResponse(segment *asterisk.TranscriptSegment) dto.TranscriptSegmentResponse {
+	return dto.TranscriptSegmentResponse{
+		Speaker: segment.Speaker,
+		StartMs: segment.StartMs,
+		EndMs:   segment.EndMs,
+		Text:    segment.Text,
+	}
+}
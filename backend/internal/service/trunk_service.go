@@ -0,0 +1,906 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net"
+	"regexp"
+	"strings"
+
+	"github.com/psschand/callcenter/internal/asterisk"
+	"github.com/psschand/callcenter/internal/common"
+	"github.com/psschand/callcenter/internal/dto"
+	"github.com/psschand/callcenter/internal/repository"
+	"github.com/psschand/callcenter/pkg/errors"
+)
+
+// Longest name part of a trunk's endpoint ID
+const trunkSlugLength = 40
+
+// Broadest networks a trunk's identify rule may match
+const (
+	trunkMinIPv4Prefix = 24
+	trunkMinIPv6Prefix = 48
+)
+
+// Dialplan context names a trunk may send its calls to
+var trunkContextPattern = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// Outbound registration settings of trunks that register
+const (
+	trunkRegistrationExpiration     = 3600
+	trunkRegistrationRetryInterval  = 60
+	trunkRegistrationForbiddenRetry = 300
+	trunkRegistrationMaxRetries     = 10000
+	trunkQualifyFrequency           = 60
+)
+
+// TrunkService manages SIP trunks and the outbound routes that pick them
+type TrunkService interface {
+	ListTrunks(ctx context.Context, tenantID string, userID int64) ([]*dto.TrunkResponse, error)
+	GetTrunk(ctx context.Context, tenantID string, userID, id int64) (*dto.TrunkResponse, error)
+	CreateTrunk(ctx context.Context, tenantID string, userID int64, req *dto.CreateTrunkRequest) (*dto.TrunkResponse, error)
+	UpdateTrunk(ctx context.Context, tenantID string, userID, id int64, req *dto.UpdateTrunkRequest) (*dto.TrunkResponse, error)
+	DeleteTrunk(ctx context.Context, tenantID string, userID, id int64) error
+	GetTrunkStatus(ctx context.Context, tenantID string, userID, id int64) (*dto.TrunkStatusResponse, error)
+
+	ListRoutes(ctx context.Context, tenantID string, userID int64) ([]*dto.OutboundRouteResponse, error)
+	GetRoute(ctx context.Context, tenantID string, userID, id int64) (*dto.OutboundRouteResponse, error)
+	CreateRoute(ctx context.Context, tenantID string, userID int64, req *dto.CreateOutboundRouteRequest) (*dto.OutboundRouteResponse, error)
+	UpdateRoute(ctx context.Context, tenantID string, userID, id int64, req *dto.UpdateOutboundRouteRequest) (*dto.OutboundRouteResponse, error)
+	DeleteRoute(ctx context.Context, tenantID string, userID, id int64) error
+}
+
+type trunkService struct {
+	trunkRepo    repository.SIPTrunkRepository
+	routeRepo    repository.OutboundRouteRepository
+	endpointRepo repository.PsEndpointRepository
+	roleRepo     repository.UserRoleRepository
+	callHandler  *asterisk.CallHandler
+}
+
+// NewTrunkService creates a new trunk service
+func NewTrunkService(
+	trunkRepo repository.SIPTrunkRepository,
+	routeRepo repository.OutboundRouteRepository,
+	endpointRepo repository.PsEndpointRepository,
+	roleRepo repository.UserRoleRepository,
+	callHandler *asterisk.CallHandler,
+) TrunkService {
+	return &trunkService{
+		trunkRepo:    trunkRepo,
+		routeRepo:    routeRepo,
+		endpointRepo: endpointRepo,
+		roleRepo:     roleRepo,
+		callHandler:  callHandler,
+	}
+}
+
+// ListTrunks lists the tenant's trunks and the platform's
+func (s *trunkService) ListTrunks(ctx context.Context, tenantID string, userID int64) ([]*dto.TrunkResponse, error) {
+	if err := s.checkManageTrunks(ctx, tenantID, userID, false); err != nil {
+		return nil, err
+	}
+
+	trunks, err := s.trunkRepo.FindByTenant(ctx, tenantID)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list trunks")
+	}
+
+	responses := make([]*dto.TrunkResponse, len(trunks))
+	for i := range trunks {
+		responses[i] = toTrunkResponse(&trunks[i])
+	}
+	return responses, nil
+}
+
+// GetTrunk gets one of the tenant's or the platform's trunks
+func (s *trunkService) GetTrunk(ctx context.Context, tenantID string, userID, id int64) (*dto.TrunkResponse, error) {
+	if err := s.checkManageTrunks(ctx, tenantID, userID, false); err != nil {
+		return nil, err
+	}
+	trunk, err := s.findTrunk(ctx, tenantID, id)
+	if err != nil {
+		return nil, err
+	}
+	return toTrunkResponse(trunk), nil
+}
+
+// CreateTrunk creates a trunk and provisions its endpoint in Asterisk
+func (s *trunkService) CreateTrunk(ctx context.Context, tenantID string, userID int64, req *dto.CreateTrunkRequest) (*dto.TrunkResponse, error) {
+	if err := s.checkManageTrunks(ctx, tenantID, userID, req.Platform); err != nil {
+		return nil, err
+	}
+
+	trunk := &asterisk.SIPTrunk{
+		Name:        req.Name,
+		Host:        req.Host,
+		Port:        req.Port,
+		Transport:   req.Transport,
+		Username:    req.Username,
+		Password:    req.Password,
+		Register:    req.Register,
+		FromUser:    req.FromUser,
+		FromDomain:  req.FromDomain,
+		Codecs:      req.Codecs,
+		Context:     req.Context,
+		MaxChannels: req.MaxChannels,
+		Status:      common.TrunkStatusActive,
+	}
+	if !req.Platform {
+		trunk.TenantID = &tenantID
+	}
+	if trunk.Port == 0 {
+		trunk.Port = asterisk.TrunkDefaultPort
+	}
+	if trunk.Transport == "" {
+		trunk.Transport = asterisk.TrunkDefaultTransport
+	}
+	if trunk.Codecs == "" {
+		trunk.Codecs = asterisk.TrunkDefaultCodecs
+	}
+	if trunk.Context == "" {
+		trunk.Context = asterisk.TrunkDefaultContext
+	}
+	setIdentifyMatch(trunk, req.IdentifyMatch)
+
+	if err := validateTrunk(trunk); err != nil {
+		return nil, err
+	}
+	if err := s.checkTrunkContext(ctx, tenantID, userID, trunk.Context, asterisk.TrunkDefaultContext); err != nil {
+		return nil, err
+	}
+	if err := s.checkIdentifyOverlap(ctx, trunk); err != nil {
+		return nil, err
+	}
+
+	endpointID, err := trunkEndpointID(trunk)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := s.trunkRepo.FindByEndpointID(ctx, endpointID); err == nil {
+		return nil, errors.NewConflict("a trunk with this name already exists")
+	}
+	if _, err := s.endpointRepo.FindByID(ctx, endpointID); err == nil {
+		return nil, errors.NewConflict("an endpoint with this trunk's name already exists")
+	}
+	trunk.EndpointID = endpointID
+
+	if err := s.trunkRepo.Create(ctx, trunk); err != nil {
+		return nil, errors.Wrap(err, "failed to create trunk")
+	}
+	if err := s.provision(ctx, trunk, false); err != nil {
+		if err := s.trunkRepo.Delete(ctx, trunk.ID); err != nil {
+			log.Printf("Failed to remove trunk %d after provisioning failed: %v", trunk.ID, err)
+		}
+		return nil, err
+	}
+
+	return toTrunkResponse(trunk), nil
+}
+
+// UpdateTrunk updates a trunk and reprovisions its endpoint. Disabling a
+// trunk removes its endpoint and registration from Asterisk.
+func (s *trunkService) UpdateTrunk(ctx context.Context, tenantID string, userID, id int64, req *dto.UpdateTrunkRequest) (*dto.TrunkResponse, error) {
+	trunk, err := s.findTrunk(ctx, tenantID, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkManageTrunks(ctx, tenantID, userID, trunk.IsPlatform()); err != nil {
+		return nil, err
+	}
+	wasRegistered := trunk.IsActive() && trunk.Register
+	previousContext := trunk.Context
+
+	// The password is only kept in the trunk's auth: an update without a new
+	// one keeps it
+	if trunk.Username != nil {
+		if auth, err := s.endpointRepo.FindTrunkAuth(ctx, trunk.EndpointID); err == nil {
+			trunk.Password = auth.Password
+		}
+	}
+
+	if req.Name != nil {
+		trunk.Name = *req.Name
+	}
+	if req.Host != nil {
+		trunk.Host = *req.Host
+	}
+	if req.Port != nil {
+		trunk.Port = *req.Port
+	}
+	if req.Transport != nil {
+		trunk.Transport = *req.Transport
+	}
+	if req.Username != nil {
+		trunk.Username = optionalString(*req.Username)
+	}
+	if req.Password != nil {
+		trunk.Password = optionalString(*req.Password)
+	}
+	if req.Register != nil {
+		trunk.Register = *req.Register
+	}
+	if req.FromUser != nil {
+		trunk.FromUser = optionalString(*req.FromUser)
+	}
+	if req.FromDomain != nil {
+		trunk.FromDomain = optionalString(*req.FromDomain)
+	}
+	if req.Codecs != nil {
+		trunk.Codecs = *req.Codecs
+	}
+	if req.Context != nil {
+		trunk.Context = *req.Context
+	}
+	if req.IdentifyMatch != nil {
+		setIdentifyMatch(trunk, req.IdentifyMatch)
+	}
+	if req.MaxChannels != nil {
+		trunk.MaxChannels = *req.MaxChannels
+	}
+	if req.Status != nil {
+		trunk.Status = common.TrunkStatus(*req.Status)
+	}
+
+	if err := validateTrunk(trunk); err != nil {
+		return nil, err
+	}
+	if err := s.checkTrunkContext(ctx, tenantID, userID, trunk.Context, previousContext); err != nil {
+		return nil, err
+	}
+	if err := s.checkIdentifyOverlap(ctx, trunk); err != nil {
+		return nil, err
+	}
+
+	if err := s.trunkRepo.Update(ctx, trunk); err != nil {
+		return nil, errors.Wrap(err, "failed to update trunk")
+	}
+	if err := s.provision(ctx, trunk, wasRegistered); err != nil {
+		return nil, err
+	}
+
+	return toTrunkResponse(trunk), nil
+}
+
+// DeleteTrunk deletes a trunk no outbound route uses and removes its endpoint
+// from Asterisk
+func (s *trunkService) DeleteTrunk(ctx context.Context, tenantID string, userID, id int64) error {
+	trunk, err := s.findTrunk(ctx, tenantID, id)
+	if err != nil {
+		return err
+	}
+	if err := s.checkManageTrunks(ctx, tenantID, userID, trunk.IsPlatform()); err != nil {
+		return err
+	}
+
+	count, err := s.routeRepo.CountByTrunk(ctx, trunk.ID)
+	if err != nil {
+		return errors.Wrap(err, "failed to check trunk usage")
+	}
+	if count > 0 {
+		return errors.NewConflict(fmt.Sprintf("trunk is used by %d outbound routes", count))
+	}
+
+	if err := s.endpointRepo.DeleteTrunk(ctx, trunk.EndpointID); err != nil {
+		return errors.Wrap(err, "failed to remove trunk endpoint")
+	}
+	if err := s.trunkRepo.Delete(ctx, trunk.ID); err != nil {
+		return errors.Wrap(err, "failed to delete trunk")
+	}
+	if trunk.IsActive() && trunk.Register {
+		s.reloadRegistrations()
+	}
+	return nil
+}
+
+// GetTrunkStatus gets a trunk's state and channel usage from Asterisk
+func (s *trunkService) GetTrunkStatus(ctx context.Context, tenantID string, userID, id int64) (*dto.TrunkStatusResponse, error) {
+	if err := s.checkManageTrunks(ctx, tenantID, userID, false); err != nil {
+		return nil, err
+	}
+	trunk, err := s.findTrunk(ctx, tenantID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &dto.TrunkStatusResponse{
+		TrunkID:     trunk.ID,
+		EndpointID:  trunk.EndpointID,
+		Status:      string(trunk.Status),
+		State:       asterisk.EndpointStateUnknown,
+		MaxChannels: trunk.MaxChannels,
+	}
+	if !trunk.IsActive() {
+		return resp, nil
+	}
+
+	state, err := s.callHandler.TrunkState(trunk.EndpointID)
+	if err != nil {
+		log.Printf("Failed to get state of trunk %s: %v", trunk.EndpointID, err)
+	} else {
+		resp.State = state.State
+		resp.ActiveChannels = len(state.ChannelIDs)
+	}
+	resp.Available = resp.State != asterisk.EndpointStateOffline && trunk.HasCapacity(resp.ActiveChannels)
+	return resp, nil
+}
+
+// ListRoutes lists the outbound routes that apply to the tenant, its own
+// before the platform's
+func (s *trunkService) ListRoutes(ctx context.Context, tenantID string, userID int64) ([]*dto.OutboundRouteResponse, error) {
+	if err := s.checkManageTrunks(ctx, tenantID, userID, false); err != nil {
+		return nil, err
+	}
+
+	routes, err := s.routeRepo.FindByTenant(ctx, tenantID)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list outbound routes")
+	}
+
+	responses := make([]*dto.OutboundRouteResponse, len(routes))
+	for i := range routes {
+		responses[i] = toOutboundRouteResponse(&routes[i])
+	}
+	return responses, nil
+}
+
+// GetRoute gets one of the tenant's or the platform's outbound routes
+func (s *trunkService) GetRoute(ctx context.Context, tenantID string, userID, id int64) (*dto.OutboundRouteResponse, error) {
+	if err := s.checkManageTrunks(ctx, tenantID, userID, false); err != nil {
+		return nil, err
+	}
+	route, err := s.findRoute(ctx, tenantID, id)
+	if err != nil {
+		return nil, err
+	}
+	return toOutboundRouteResponse(route), nil
+}
+
+// CreateRoute creates an outbound route
+func (s *trunkService) CreateRoute(ctx context.Context, tenantID string, userID int64, req *dto.CreateOutboundRouteRequest) (*dto.OutboundRouteResponse, error) {
+	if err := s.checkManageTrunks(ctx, tenantID, userID, req.Platform); err != nil {
+		return nil, err
+	}
+
+	route := &asterisk.OutboundRoute{
+		Name:            req.Name,
+		Prefix:          req.Prefix,
+		Priority:        req.Priority,
+		TrunkID:         req.TrunkID,
+		FailoverTrunkID: req.FailoverTrunkID,
+		StripDigits:     req.StripDigits,
+		AddPrefix:       req.AddPrefix,
+		IsActive:        true,
+	}
+	if !req.Platform {
+		route.TenantID = &tenantID
+	}
+	if err := s.validateRoute(ctx, tenantID, route); err != nil {
+		return nil, err
+	}
+
+	if err := s.routeRepo.Create(ctx, route); err != nil {
+		return nil, errors.Wrap(err, "failed to create outbound route")
+	}
+	return s.reloadRoute(ctx, tenantID, route.ID)
+}
+
+// UpdateRoute updates an outbound route
+func (s *trunkService) UpdateRoute(ctx context.Context, tenantID string, userID, id int64, req *dto.UpdateOutboundRouteRequest) (*dto.OutboundRouteResponse, error) {
+	route, err := s.findRoute(ctx, tenantID, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkManageTrunks(ctx, tenantID, userID, route.TenantID == nil); err != nil {
+		return nil, err
+	}
+
+	if req.Name != nil {
+		route.Name = *req.Name
+	}
+	if req.Prefix != nil {
+		route.Prefix = *req.Prefix
+	}
+	if req.Priority != nil {
+		route.Priority = *req.Priority
+	}
+	if req.TrunkID != nil {
+		route.TrunkID = *req.TrunkID
+	}
+	if req.FailoverTrunkID != nil {
+		route.FailoverTrunkID = req.FailoverTrunkID
+		if *req.FailoverTrunkID == 0 {
+			route.FailoverTrunkID = nil
+		}
+	}
+	if req.StripDigits != nil {
+		route.StripDigits = *req.StripDigits
+	}
+	if req.AddPrefix != nil {
+		route.AddPrefix = *req.AddPrefix
+	}
+	if req.IsActive != nil {
+		route.IsActive = *req.IsActive
+	}
+	if err := s.validateRoute(ctx, tenantID, route); err != nil {
+		return nil, err
+	}
+
+	if err := s.routeRepo.Update(ctx, route); err != nil {
+		return nil, errors.Wrap(err, "failed to update outbound route")
+	}
+	return s.reloadRoute(ctx, tenantID, route.ID)
+}
+
+// DeleteRoute deletes an outbound route
+func (s *trunkService) DeleteRoute(ctx context.Context, tenantID string, userID, id int64) error {
+	route, err := s.findRoute(ctx, tenantID, id)
+	if err != nil {
+		return err
+	}
+	if err := s.checkManageTrunks(ctx, tenantID, userID, route.TenantID == nil); err != nil {
+		return err
+	}
+
+	if err := s.routeRepo.Delete(ctx, route.ID); err != nil {
+		return errors.Wrap(err, "failed to delete outbound route")
+	}
+	return nil
+}
+
+// provision writes an active trunk's endpoint, AOR, auth, registration and
+// identify rule to the ARA tables, or removes all but its auth for a disabled
+// trunk. Registrations are only read when Asterisk reloads them, so the module
+// is reloaded when the trunk registers now or did before.
+func (s *trunkService) provision(ctx context.Context, trunk *asterisk.SIPTrunk, wasRegistered bool) error {
+	id := trunk.EndpointID
+	// Platform trunks are not any one tenant's
+	araTenant := ""
+	if trunk.TenantID != nil {
+		araTenant = *trunk.TenantID
+	}
+
+	var auth *asterisk.PsAuth
+	if trunk.Username != nil && trunk.Password != nil {
+		authType := "userpass"
+		auth = &asterisk.PsAuth{
+			ID:       asterisk.TrunkAuthID(id),
+			TenantID: araTenant,
+			AuthType: &authType,
+			Username: trunk.Username,
+			Password: trunk.Password,
+		}
+	}
+
+	if !trunk.IsActive() {
+		if err := s.endpointRepo.DisableTrunk(ctx, id, auth); err != nil {
+			return errors.Wrap(err, "failed to remove trunk endpoint")
+		}
+		if wasRegistered {
+			s.reloadRegistrations()
+		}
+		return nil
+	}
+
+	server := fmt.Sprintf("sip:%s:%d", trunk.Host, trunk.Port)
+	yes, no := "yes", "no"
+	disallow := "all"
+	dtmfMode := "rfc4733"
+	identifyBy := "ip"
+	qualify := trunkQualifyFrequency
+
+	endpoint := &asterisk.PsEndpoint{
+		ID:             id,
+		TenantID:       araTenant,
+		DisplayName:    &trunk.Name,
+		Transport:      &trunk.Transport,
+		Aors:           &id,
+		Context:        &trunk.Context,
+		Disallow:       &disallow,
+		Allow:          &trunk.Codecs,
+		DirectMedia:    &no,
+		DtmfMode:       &dtmfMode,
+		ForceRport:     &yes,
+		RtpSymmetric:   &yes,
+		RewriteContact: &yes,
+		FromUser:       trunk.FromUser,
+		FromDomain:     trunk.FromDomain,
+		IdentifyBy:     &identifyBy,
+	}
+	aor := &asterisk.PsAor{
+		ID:                  id,
+		TenantID:            araTenant,
+		Contact:             &server,
+		QualifyFrequency:    &qualify,
+		AuthenticateQualify: &no,
+	}
+
+	if auth != nil {
+		endpoint.OutboundAuth = &auth.ID
+	}
+
+	var registration *asterisk.PsRegistration
+	if trunk.Register {
+		client := fmt.Sprintf("sip:%s@%s:%d", *trunk.Username, trunk.Host, trunk.Port)
+		expiration := trunkRegistrationExpiration
+		retry := trunkRegistrationRetryInterval
+		forbiddenRetry := trunkRegistrationForbiddenRetry
+		maxRetries := trunkRegistrationMaxRetries
+		registration = &asterisk.PsRegistration{
+			ID:                     id,
+			TenantID:               araTenant,
+			ServerURI:              &server,
+			ClientURI:              &client,
+			ContactUser:            trunk.Username,
+			Endpoint:               &id,
+			OutboundAuth:           endpoint.OutboundAuth,
+			Transport:              &trunk.Transport,
+			Line:                   &yes,
+			Expiration:             &expiration,
+			RetryInterval:          &retry,
+			ForbiddenRetryInterval: &forbiddenRetry,
+			MaxRetries:             &maxRetries,
+			AuthRejectionPermanent: &no,
+		}
+	}
+
+	// Without explicit addresses, requests from the provider's host are matched
+	matches := trunk.IdentifyMatches()
+	if len(matches) == 0 {
+		matches = []string{trunk.Host}
+	}
+	identify := &asterisk.PsEndpointIdentify{
+		ID:       id + "-identify",
+		Endpoint: id,
+		Match:    strings.Join(matches, ","),
+	}
+
+	if err := s.endpointRepo.SaveTrunk(ctx, endpoint, aor, auth, registration, identify); err != nil {
+		return errors.Wrap(err, "failed to provision trunk endpoint")
+	}
+	if trunk.Register || wasRegistered {
+		s.reloadRegistrations()
+	}
+	return nil
+}
+
+// reloadRegistrations asks Asterisk to pick up changed outbound
+// registrations. A failure is logged: the change applies on the next reload.
+func (s *trunkService) reloadRegistrations() {
+	if err := s.callHandler.ReloadTrunkRegistrations(); err != nil {
+		log.Printf("Failed to reload trunk registrations: %v", err)
+	}
+}
+
+// validateRoute checks a route's trunks exist and may be used by it: a
+// tenant's routes may use its own trunks and the platform's, platform routes
+// only platform trunks
+func (s *trunkService) validateRoute(ctx context.Context, tenantID string, route *asterisk.OutboundRoute) error {
+	if route.FailoverTrunkID != nil && *route.FailoverTrunkID == route.TrunkID {
+		return errors.NewValidation(map[string]string{"failover_trunk_id": "must differ from trunk_id"})
+	}
+
+	trunks := []struct {
+		field string
+		id    *int64
+	}{
+		{"trunk_id", &route.TrunkID},
+		{"failover_trunk_id", route.FailoverTrunkID},
+	}
+	for _, t := range trunks {
+		if t.id == nil {
+			continue
+		}
+		trunk, err := s.findTrunk(ctx, tenantID, *t.id)
+		if err != nil {
+			return errors.NewValidation(map[string]string{t.field: "trunk not found"})
+		}
+		if route.TenantID == nil && !trunk.IsPlatform() {
+			return errors.NewValidation(map[string]string{t.field: "platform routes can only use platform trunks"})
+		}
+	}
+	return nil
+}
+
+// reloadRoute loads a saved route with its trunks
+func (s *trunkService) reloadRoute(ctx context.Context, tenantID string, id int64) (*dto.OutboundRouteResponse, error) {
+	route, err := s.findRoute(ctx, tenantID, id)
+	if err != nil {
+		return nil, err
+	}
+	return toOutboundRouteResponse(route), nil
+}
+
+// findTrunk finds a trunk that is the tenant's or the platform's
+func (s *trunkService) findTrunk(ctx context.Context, tenantID string, id int64) (*asterisk.SIPTrunk, error) {
+	trunk, err := s.trunkRepo.FindByID(ctx, id)
+	if err != nil || (trunk.TenantID != nil && *trunk.TenantID != tenantID) {
+		return nil, errors.NewNotFound("trunk not found")
+	}
+	return trunk, nil
+}
+
+// findRoute finds an outbound route that is the tenant's or the platform's
+func (s *trunkService) findRoute(ctx context.Context, tenantID string, id int64) (*asterisk.OutboundRoute, error) {
+	route, err := s.routeRepo.FindByID(ctx, id)
+	if err != nil || (route.TenantID != nil && *route.TenantID != tenantID) {
+		return nil, errors.NewNotFound("outbound route not found")
+	}
+	return route, nil
+}
+
+// checkManageTrunks checks that a user may manage the tenant's trunks and
+// outbound routes, or the platform's when platform is set
+func (s *trunkService) checkManageTrunks(ctx context.Context, tenantID string, userID int64, platform bool) error {
+	role, err := s.roleRepo.FindByUserAndTenant(ctx, userID, tenantID)
+	if err != nil {
+		return errors.NewForbidden("no role in this tenant")
+	}
+	if platform && role.Role != common.RoleSuperAdmin {
+		return errors.NewForbidden("only superadmins may manage platform trunks and routes")
+	}
+	if !role.IsAdmin() {
+		return errors.NewForbidden("not allowed to manage trunks")
+	}
+	return nil
+}
+
+// checkTrunkContext checks that a user may send a trunk's calls to a dialplan
+// context. Tenant trunks stay in the default inbound context, which only
+// reaches the numbers routed by DID; only superadmins may pick another.
+func (s *trunkService) checkTrunkContext(ctx context.Context, tenantID string, userID int64, trunkContext, previous string) error {
+	if trunkContext == previous || trunkContext == asterisk.TrunkDefaultContext {
+		return nil
+	}
+	role, err := s.roleRepo.FindByUserAndTenant(ctx, userID, tenantID)
+	if err != nil || role.Role != common.RoleSuperAdmin {
+		return errors.NewForbidden("only superadmins may change a trunk's context")
+	}
+	return nil
+}
+
+// checkIdentifyOverlap checks that a trunk's identify rule matches none of the
+// addresses of another tenant's trunk or, for a tenant trunk, the platform's.
+// Asterisk picks one endpoint for a request from an address matched twice, so
+// an overlap would hand another tenant's inbound calls to this trunk.
+func (s *trunkService) checkIdentifyOverlap(ctx context.Context, trunk *asterisk.SIPTrunk) error {
+	if len(trunkNetworks(trunk)) == 0 {
+		return nil
+	}
+
+	trunks, err := s.trunkRepo.FindAll(ctx)
+	if err != nil {
+		return errors.Wrap(err, "failed to check trunk addresses")
+	}
+	for i := range trunks {
+		other := &trunks[i]
+		if other.ID == trunk.ID || sameTrunkOwner(other, trunk) {
+			continue
+		}
+		if network := overlappingNetwork(trunk, other); network != nil {
+			return errors.NewValidation(map[string]string{
+				"identify_match": fmt.Sprintf("%s overlaps the addresses of another tenant's trunk", network),
+			})
+		}
+	}
+	return nil
+}
+
+// validateTrunk checks the settings of a trunk about to be saved
+func validateTrunk(trunk *asterisk.SIPTrunk) error {
+	fields := map[string]string{}
+	if strings.TrimSpace(trunk.Host) == "" {
+		fields["host"] = "is required"
+	} else if len(trunk.IdentifyMatches()) == 0 {
+		// Requests from the host itself are matched
+		if ip := net.ParseIP(trunk.Host); ip != nil && !isProviderAddr(ip) {
+			fields["host"] = "is not a provider address"
+		}
+	}
+	if !trunkContextPattern.MatchString(trunk.Context) {
+		fields["context"] = "must only contain letters, digits, dashes and underscores"
+	}
+	if trunk.Register && (trunk.Username == nil || trunk.Password == nil) {
+		fields["register"] = "needs a username and password"
+	}
+	if (trunk.Username == nil) != (trunk.Password == nil) {
+		fields["password"] = "username and password must be set together"
+	}
+	for _, match := range trunk.IdentifyMatches() {
+		if reason := checkIdentifyMatch(match); reason != "" {
+			fields["identify_match"] = fmt.Sprintf("%q %s", match, reason)
+			break
+		}
+	}
+	if len(fields) > 0 {
+		return errors.NewValidation(fields)
+	}
+	return nil
+}
+
+// checkIdentifyMatch checks that an identify address or network only covers
+// a provider's own servers, returning why it does not
+func checkIdentifyMatch(match string) string {
+	network := parseIdentifyMatch(match)
+	if network == nil {
+		return "is not an IP address or network"
+	}
+	if !isProviderAddr(network.IP) {
+		return "is not a provider address"
+	}
+	ones, bits := network.Mask.Size()
+	if bits == 8*net.IPv4len && ones < trunkMinIPv4Prefix {
+		return fmt.Sprintf("is too broad, IPv4 networks must be /%d or narrower", trunkMinIPv4Prefix)
+	}
+	if bits == 8*net.IPv6len && ones < trunkMinIPv6Prefix {
+		return fmt.Sprintf("is too broad, IPv6 networks must be /%d or narrower", trunkMinIPv6Prefix)
+	}
+	return ""
+}
+
+// parseIdentifyMatch parses an identify address as a single address network,
+// or an identify network, returning nil for anything else
+func parseIdentifyMatch(match string) *net.IPNet {
+	if ip := net.ParseIP(match); ip != nil {
+		if ip4 := ip.To4(); ip4 != nil {
+			return &net.IPNet{IP: ip4, Mask: net.CIDRMask(8*net.IPv4len, 8*net.IPv4len)}
+		}
+		return &net.IPNet{IP: ip, Mask: net.CIDRMask(8*net.IPv6len, 8*net.IPv6len)}
+	}
+	_, network, err := net.ParseCIDR(match)
+	if err != nil {
+		return nil
+	}
+	return network
+}
+
+// isProviderAddr checks that an address can belong to a provider's server
+func isProviderAddr(ip net.IP) bool {
+	return !ip.IsUnspecified() && !ip.IsLoopback() && !ip.IsMulticast() &&
+		!ip.IsLinkLocalUnicast() && !ip.IsLinkLocalMulticast()
+}
+
+// trunkNetworks returns the networks a trunk's identify rule matches. A trunk
+// matched by a host name is left out: its addresses are only known to DNS.
+func trunkNetworks(trunk *asterisk.SIPTrunk) []*net.IPNet {
+	matches := trunk.IdentifyMatches()
+	if len(matches) == 0 {
+		matches = []string{trunk.Host}
+	}
+	var networks []*net.IPNet
+	for _, match := range matches {
+		if network := parseIdentifyMatch(match); network != nil {
+			networks = append(networks, network)
+		}
+	}
+	return networks
+}
+
+// overlappingNetwork returns the first of a trunk's networks that shares
+// addresses with another trunk's, or nil when none does
+func overlappingNetwork(trunk, other *asterisk.SIPTrunk) *net.IPNet {
+	theirs := trunkNetworks(other)
+	for _, ours := range trunkNetworks(trunk) {
+		for _, network := range theirs {
+			if ours.Contains(network.IP) || network.Contains(ours.IP) {
+				return ours
+			}
+		}
+	}
+	return nil
+}
+
+// sameTrunkOwner checks if two trunks are the same tenant's, or both the
+// platform's
+func sameTrunkOwner(a, b *asterisk.SIPTrunk) bool {
+	if a.TenantID == nil || b.TenantID == nil {
+		return a.TenantID == nil && b.TenantID == nil
+	}
+	return *a.TenantID == *b.TenantID
+}
+
+// setIdentifyMatch stores a trunk's identify addresses
+func setIdentifyMatch(trunk *asterisk.SIPTrunk, matches []string) {
+	var kept []string
+	for _, match := range matches {
+		if match = strings.TrimSpace(match); match != "" {
+			kept = append(kept, match)
+		}
+	}
+	trunk.IdentifyMatch = nil
+	if len(kept) > 0 {
+		joined := strings.Join(kept, ",")
+		trunk.IdentifyMatch = &joined
+	}
+}
+
+// trunkEndpointID derives a new trunk's endpoint ID from its name, prefixed
+// with its tenant. It is not changed when the trunk is renamed.
+func trunkEndpointID(trunk *asterisk.SIPTrunk) (string, error) {
+	var b strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(trunk.Name) {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
+			b.WriteRune(r)
+			dash = false
+		} else if !dash && b.Len() > 0 {
+			b.WriteByte('-')
+			dash = true
+		}
+	}
+	slug := strings.Trim(b.String(), "-")
+	if len(slug) > trunkSlugLength {
+		slug = strings.Trim(slug[:trunkSlugLength], "-")
+	}
+	if slug == "" {
+		return "", errors.NewValidation(map[string]string{"name": "must contain letters or digits"})
+	}
+
+	if trunk.TenantID == nil {
+		return "trunk-" + slug, nil
+	}
+	return fmt.Sprintf("%s-trunk-%s", *trunk.TenantID, slug), nil
+}
+
+// optionalString returns nil for an empty string, which clears an optional field
+func optionalString(value string) *string {
+	if value == "" {
+		return nil
+	}
+	return &value
+}
+
+// toTrunkResponse converts a trunk to a response. Its password is never
+// returned, and is set whenever its username is.
+func toTrunkResponse(trunk *asterisk.SIPTrunk) *dto.TrunkResponse {
+	return &dto.TrunkResponse{
+		ID:            trunk.ID,
+		TenantID:      trunk.TenantID,
+		Platform:      trunk.IsPlatform(),
+		Name:          trunk.Name,
+		EndpointID:    trunk.EndpointID,
+		Host:          trunk.Host,
+		Port:          trunk.Port,
+		Transport:     trunk.Transport,
+		Username:      trunk.Username,
+		HasPassword:   trunk.Username != nil,
+		Register:      trunk.Register,
+		FromUser:      trunk.FromUser,
+		FromDomain:    trunk.FromDomain,
+		Codecs:        trunk.Codecs,
+		Context:       trunk.Context,
+		IdentifyMatch: trunk.IdentifyMatches(),
+		MaxChannels:   trunk.MaxChannels,
+		Status:        string(trunk.Status),
+		CreatedAt:     trunk.CreatedAt,
+		UpdatedAt:     trunk.UpdatedAt,
+	}
+}
+
+// toOutboundRouteResponse converts an outbound route to a response
+func toOutboundRouteResponse(route *asterisk.OutboundRoute) *dto.OutboundRouteResponse {
+	resp := &dto.OutboundRouteResponse{
+		ID:              route.ID,
+		TenantID:        route.TenantID,
+		Platform:        route.TenantID == nil,
+		Name:            route.Name,
+		Prefix:          route.Prefix,
+		Priority:        route.Priority,
+		TrunkID:         route.TrunkID,
+		FailoverTrunkID: route.FailoverTrunkID,
+		StripDigits:     route.StripDigits,
+		AddPrefix:       route.AddPrefix,
+		IsActive:        route.IsActive,
+		CreatedAt:       route.CreatedAt,
+		UpdatedAt:       route.UpdatedAt,
+	}
+	if route.Trunk != nil {
+		resp.TrunkName = route.Trunk.Name
+	}
+	if route.FailoverTrunk != nil {
+		resp.FailoverTrunkName = route.FailoverTrunk.Name
+	}
+	return resp
+}
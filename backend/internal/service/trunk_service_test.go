@@ -0,0 +1,95 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/psschand/callcenter/internal/asterisk"
+)
+
+func TestCheckIdentifyMatch(t *testing.T) {
+	cases := []struct {
+		match   string
+		allowed bool
+	}{
+		{"203.0.113.10", true},
+		{"203.0.113.0/24", true},
+		{"203.0.113.8/30", true},
+		{"10.20.0.5", true},
+		{"2001:db8:1::/48", true},
+		{"2001:db8::10", true},
+		{"sip.example.com", false},
+		{"0.0.0.0/0", false},
+		{"0.0.0.0", false},
+		{"::/0", false},
+		{"203.0.0.0/16", false},
+		{"10.0.0.0/8", false},
+		{"2001:db8::/32", false},
+		{"127.0.0.1", false},
+		{"::1", false},
+		{"169.254.0.0/24", false},
+		{"224.0.0.1", false},
+	}
+	for _, tc := range cases {
+		reason := checkIdentifyMatch(tc.match)
+		if tc.allowed && reason != "" {
+			t.Errorf("checkIdentifyMatch(%q) = %q, want it allowed", tc.match, reason)
+		}
+		if !tc.allowed && reason == "" {
+			t.Errorf("checkIdentifyMatch(%q) allowed it", tc.match)
+		}
+	}
+}
+
+func TestValidateTrunkContext(t *testing.T) {
+	for context, valid := range map[string]bool{
+		asterisk.TrunkDefaultContext: true,
+		"from-carrier_2":             true,
+		"":                           false,
+		"from-trunk,s,1":             false,
+		"callcenter-dids\ninclude":   false,
+	} {
+		trunk := &asterisk.SIPTrunk{Host: "sip.example.com", Context: context}
+		err := validateTrunk(trunk)
+		if valid && err != nil {
+			t.Errorf("context %q: %v, want it valid", context, err)
+		}
+		if !valid && err == nil {
+			t.Errorf("context %q was accepted", context)
+		}
+	}
+}
+
+func TestOverlappingNetwork(t *testing.T) {
+	str := func(s string) *string { return &s }
+	acme := &asterisk.SIPTrunk{TenantID: str("acme-corp"), Host: "sip.example.com", IdentifyMatch: str("203.0.113.0/24")}
+	globex := &asterisk.SIPTrunk{TenantID: str("globex"), Host: "203.0.113.77"}
+	disjoint := &asterisk.SIPTrunk{TenantID: str("globex"), Host: "sip.other.example", IdentifyMatch: str("198.51.100.0/24")}
+	byName := &asterisk.SIPTrunk{TenantID: str("globex"), Host: "sip.example.com"}
+
+	if network := overlappingNetwork(acme, globex); network == nil || network.String() != "203.0.113.0/24" {
+		t.Errorf("overlappingNetwork(acme, globex) = %v, want 203.0.113.0/24", network)
+	}
+	if network := overlappingNetwork(globex, acme); network == nil || network.String() != "203.0.113.77/32" {
+		t.Errorf("overlappingNetwork(globex, acme) = %v, want 203.0.113.77/32", network)
+	}
+	if network := overlappingNetwork(acme, disjoint); network != nil {
+		t.Errorf("overlappingNetwork(acme, disjoint) = %v, want nil", network)
+	}
+	if network := overlappingNetwork(acme, byName); network != nil {
+		t.Errorf("a trunk matched by host name overlapped %v", network)
+	}
+}
+
+func TestSameTrunkOwner(t *testing.T) {
+	str := func(s string) *string { return &s }
+	acme := &asterisk.SIPTrunk{TenantID: str("acme-corp")}
+	globex := &asterisk.SIPTrunk{TenantID: str("globex")}
+	platform := &asterisk.SIPTrunk{}
+
+	if sameTrunkOwner(acme, globex) || sameTrunkOwner(acme, platform) || sameTrunkOwner(platform, globex) {
+		t.Error("trunks of different owners were grouped together")
+	}
+	if !sameTrunkOwner(acme, &asterisk.SIPTrunk{TenantID: str("acme-corp")}) || !sameTrunkOwner(platform, &asterisk.SIPTrunk{}) {
+		t.Error("trunks of the same owner were told apart")
+	}
+}
@@ -2,6 +2,7 @@ package service
 
 import (
 	"context"
+	"log"
 	"time"
 
 	"github.com/psschand/callcenter/internal/common"
@@ -29,6 +30,7 @@ type userService struct {
 	userRepo   repository.UserRepository
 	roleRepo   repository.UserRoleRepository
 	tenantRepo repository.TenantRepository
+	softphones SoftphoneService
 }
 
 // NewUserService creates a new user service
@@ -36,11 +38,13 @@ func NewUserService(
 	userRepo repository.UserRepository,
 	roleRepo repository.UserRoleRepository,
 	tenantRepo repository.TenantRepository,
+	softphones SoftphoneService,
 ) UserService {
 	return &userService{
 		userRepo:   userRepo,
 		roleRepo:   roleRepo,
 		tenantRepo: tenantRepo,
+		softphones: softphones,
 	}
 }
 
@@ -108,6 +112,12 @@ func (s *userService) Create(ctx context.Context, tenantID string, req *dto.Crea
 		return nil, errors.Wrap(err, "failed to assign role")
 	}
 
+	// The user exists either way; a softphone that fails to provision is
+	// provisioned again when the role is next set
+	if err := s.softphones.Provision(ctx, userRole); err != nil {
+		log.Printf("Error provisioning softphone for user %d: %v", user.ID, err)
+	}
+
 	return &dto.UserResponse{
 		ID:            user.ID,
 		Email:         user.Email,
@@ -119,7 +129,7 @@ func (s *userService) Create(ctx context.Context, tenantID string, req *dto.Crea
 		Avatar:        user.Avatar,
 		Timezone:      user.Timezone,
 		Language:      user.Language,
-		Roles:         []dto.UserRoleResponse{{TenantID: tenantID, Role: userRole.Role}},
+		Roles:         []dto.UserRoleResponse{{ID: userRole.ID, TenantID: tenantID, Role: userRole.Role, EndpointID: userRole.EndpointID}},
 		CreatedAt:     user.CreatedAt,
 		UpdatedAt:     user.UpdatedAt,
 	}, nil
@@ -266,9 +276,13 @@ func (s *userService) Delete(ctx context.Context, id int64) error {
 		return errors.NewNotFound("user not found")
 	}
 
-	// Delete all user roles
+	// Delete all user roles with their softphones
 	userRoles, _ := s.roleRepo.FindByUser(ctx, user.ID)
-	for _, userRole := range userRoles {
+	for i := range userRoles {
+		userRole := &userRoles[i]
+		if err := s.softphones.Deprovision(ctx, userRole); err != nil {
+			return err
+		}
 		if err := s.roleRepo.Delete(ctx, userRole.ID); err != nil {
 			return errors.Wrap(err, "failed to delete user role")
 		}
@@ -350,7 +364,11 @@ func (s *userService) UpdateRole(ctx context.Context, userID int64, tenantID, ro
 		return errors.Wrap(err, "failed to update role")
 	}
 
-	return nil
+	// Roles that take calls get a softphone, others lose theirs
+	if takesCalls(userRole.Role) {
+		return s.softphones.Provision(ctx, userRole)
+	}
+	return s.softphones.Deprovision(ctx, userRole)
 }
 
 // ActivateUser activates a user
@@ -367,6 +385,13 @@ func (s *userService) ActivateUser(ctx context.Context, id int64) error {
 		return errors.Wrap(err, "failed to activate user")
 	}
 
+	userRoles, _ := s.roleRepo.FindByUser(ctx, user.ID)
+	for i := range userRoles {
+		if err := s.softphones.Provision(ctx, &userRoles[i]); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
@@ -384,5 +409,12 @@ func (s *userService) DeactivateUser(ctx context.Context, id int64) error {
 		return errors.Wrap(err, "failed to deactivate user")
 	}
 
+	userRoles, _ := s.roleRepo.FindByUser(ctx, user.ID)
+	for i := range userRoles {
+		if err := s.softphones.Deprovision(ctx, &userRoles[i]); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
@@ -0,0 +1,574 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	stderrors "errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/psschand/callcenter/internal/asterisk"
+	"github.com/psschand/callcenter/internal/common"
+	"github.com/psschand/callcenter/internal/core"
+	"github.com/psschand/callcenter/internal/dto"
+	"github.com/psschand/callcenter/internal/repository"
+	"github.com/psschand/callcenter/internal/storage"
+	ws "github.com/psschand/callcenter/internal/websocket"
+	"github.com/psschand/callcenter/pkg/errors"
+)
+
+// Largest greeting that can be uploaded
+const maxGreetingSize = 10 << 20
+
+// VoicemailService handles voicemail mailboxes and the messages left in them
+type VoicemailService interface {
+	OnVoicemail(message asterisk.VoicemailMessage)
+	List(ctx context.Context, tenantID string, userID, mailboxID int64, unreadOnly bool, page, pageSize int) ([]*dto.VoicemailResponse, int64, error)
+	GetByID(ctx context.Context, tenantID string, userID, id int64) (*dto.VoicemailResponse, error)
+	Open(ctx context.Context, tenantID string, userID, id int64) (*RecordingContent, error)
+	MarkRead(ctx context.Context, tenantID string, userID, id int64, read bool) (*dto.VoicemailResponse, error)
+	Delete(ctx context.Context, tenantID string, userID, id int64) error
+
+	ListMailboxes(ctx context.Context, tenantID string, userID int64) ([]*dto.VoicemailMailboxResponse, error)
+	GetMailbox(ctx context.Context, tenantID string, userID, id int64) (*dto.VoicemailMailboxResponse, error)
+	CreateMailbox(ctx context.Context, tenantID string, userID int64, req *dto.CreateVoicemailMailboxRequest) (*dto.VoicemailMailboxResponse, error)
+	UpdateMailbox(ctx context.Context, tenantID string, userID, id int64, req *dto.UpdateVoicemailMailboxRequest) (*dto.VoicemailMailboxResponse, error)
+	DeleteMailbox(ctx context.Context, tenantID string, userID, id int64) error
+	UploadGreeting(ctx context.Context, tenantID string, userID, id int64, content io.Reader) (*dto.VoicemailMailboxResponse, error)
+	DeleteGreeting(ctx context.Context, tenantID string, userID, id int64) (*dto.VoicemailMailboxResponse, error)
+}
+
+type voicemailService struct {
+	voicemailRepo repository.VoicemailRepository
+	mailboxRepo   repository.VoicemailMailboxRepository
+	didRepo       repository.DIDRepository
+	roleRepo      repository.UserRoleRepository
+	store         storage.Storage
+	transcripts   TranscriptionService
+	recordingPath string
+	broadcaster   *ws.EventBroadcaster
+}
+
+// NewVoicemailService creates a new voicemail service. recordingPath is the
+// directory Asterisk records messages to and plays uploaded greetings from.
+// New messages are queued for transcription.
+func NewVoicemailService(
+	voicemailRepo repository.VoicemailRepository,
+	mailboxRepo repository.VoicemailMailboxRepository,
+	didRepo repository.DIDRepository,
+	roleRepo repository.UserRoleRepository,
+	store storage.Storage,
+	transcripts TranscriptionService,
+	recordingPath string,
+	broadcaster *ws.EventBroadcaster,
+) VoicemailService {
+	return &voicemailService{
+		voicemailRepo: voicemailRepo,
+		mailboxRepo:   mailboxRepo,
+		didRepo:       didRepo,
+		roleRepo:      roleRepo,
+		store:         store,
+		transcripts:   transcripts,
+		recordingPath: recordingPath,
+		broadcaster:   broadcaster,
+	}
+}
+
+// OnVoicemail persists a message left in a mailbox and notifies its owner,
+// or the whole tenant when the mailbox belongs to a DID
+func (s *voicemailService) OnVoicemail(message asterisk.VoicemailMessage) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	mailbox, err := s.mailboxRepo.FindByID(ctx, message.MailboxID)
+	if err != nil {
+		log.Printf("Error loading mailbox %d for voicemail %s: %v", message.MailboxID, message.Name, err)
+		return
+	}
+
+	mailboxID := mailbox.ID
+	voicemail := &asterisk.Voicemail{
+		TenantID:  message.TenantID,
+		MailboxID: &mailboxID,
+		DIDID:     mailbox.DIDID,
+		UserID:    mailbox.UserID,
+		CallerID:  message.CallerNumber,
+		Duration:  message.Duration,
+		FilePath:  filepath.Join(s.recordingPath, message.Name+"."+message.Format),
+		Format:    message.Format,
+	}
+	if voicemail.CallerID == "" {
+		voicemail.CallerID = "unknown"
+	}
+	if message.CallerName != "" {
+		voicemail.CallerName = &message.CallerName
+	}
+	if info, err := os.Stat(voicemail.FilePath); err == nil {
+		voicemail.FileSize = info.Size()
+	}
+
+	if err := s.voicemailRepo.Create(ctx, voicemail); err != nil {
+		log.Printf("Error saving voicemail %s: %v", message.Name, err)
+		return
+	}
+	s.transcripts.Enqueue(ctx, voicemail.TenantID, common.TranscriptSourceVoicemail, voicemail.ID)
+
+	if s.broadcaster == nil {
+		return
+	}
+	unread, _ := s.voicemailRepo.CountUnread(ctx, mailbox.TenantID, mailbox.ID)
+	owner := int64(0)
+	if mailbox.UserID != nil {
+		owner = *mailbox.UserID
+	}
+	payload := &ws.VoicemailPayload{
+		VoicemailID: voicemail.ID,
+		MailboxID:   mailbox.ID,
+		MailboxName: mailbox.Name,
+		CallerID:    voicemail.CallerID,
+		CallerName:  message.CallerName,
+		Duration:    voicemail.Duration,
+		UnreadCount: unread,
+	}
+	if err := s.broadcaster.VoicemailNew(mailbox.TenantID, owner, payload); err != nil {
+		log.Printf("Error broadcasting voicemail %d: %v", voicemail.ID, err)
+	}
+}
+
+// List lists the voicemails of the mailboxes a user can access, or of one of them
+func (s *voicemailService) List(ctx context.Context, tenantID string, userID, mailboxID int64, unreadOnly bool, page, pageSize int) ([]*dto.VoicemailResponse, int64, error) {
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	var mailboxIDs []int64
+	if mailboxID != 0 {
+		if _, err := s.findMailbox(ctx, tenantID, userID, mailboxID); err != nil {
+			return nil, 0, err
+		}
+		mailboxIDs = []int64{mailboxID}
+	} else {
+		role, err := s.roleRepo.FindByUserAndTenant(ctx, userID, tenantID)
+		if err != nil {
+			return nil, 0, errors.NewForbidden("no role in this tenant")
+		}
+		if !canManageMailboxes(role) {
+			mailbox, err := s.mailboxRepo.FindByUser(ctx, tenantID, userID)
+			if err != nil {
+				return []*dto.VoicemailResponse{}, 0, nil
+			}
+			mailboxIDs = []int64{mailbox.ID}
+		}
+	}
+
+	voicemails, total, err := s.voicemailRepo.FindByMailboxes(ctx, tenantID, mailboxIDs, unreadOnly, page, pageSize)
+	if err != nil {
+		return nil, 0, errors.Wrap(err, "failed to get voicemails")
+	}
+
+	responses := make([]*dto.VoicemailResponse, len(voicemails))
+	for i := range voicemails {
+		responses[i] = toVoicemailResponse(&voicemails[i])
+	}
+	return responses, total, nil
+}
+
+// GetByID gets a voicemail
+func (s *voicemailService) GetByID(ctx context.Context, tenantID string, userID, id int64) (*dto.VoicemailResponse, error) {
+	voicemail, err := s.findVoicemail(ctx, tenantID, userID, id)
+	if err != nil {
+		return nil, err
+	}
+	return toVoicemailResponse(voicemail), nil
+}
+
+// Open opens a voicemail's audio for streaming
+func (s *voicemailService) Open(ctx context.Context, tenantID string, userID, id int64) (*RecordingContent, error) {
+	voicemail, err := s.findVoicemail(ctx, tenantID, userID, id)
+	if err != nil {
+		return nil, err
+	}
+	filename := fmt.Sprintf("voicemail-%d.%s", voicemail.ID, voicemail.Format)
+
+	if voicemail.StorageKey != nil {
+		object, err := s.store.Open(ctx, *voicemail.StorageKey)
+		if err != nil {
+			if stderrors.Is(err, storage.ErrNotFound) {
+				return nil, errors.NewNotFound("voicemail file not found")
+			}
+			return nil, errors.Wrap(err, "failed to open voicemail")
+		}
+		return &RecordingContent{
+			Filename:    filename,
+			ContentType: recordingContentType(voicemail.Format),
+			ModTime:     object.ModTime,
+			Content:     object.Content,
+		}, nil
+	}
+
+	file, err := os.Open(voicemail.FilePath)
+	if err != nil {
+		if stderrors.Is(err, os.ErrNotExist) {
+			return nil, errors.NewNotFound("voicemail file not found")
+		}
+		return nil, errors.Wrap(err, "failed to open voicemail")
+	}
+
+	modTime := voicemail.CreatedAt
+	if info, err := file.Stat(); err == nil {
+		modTime = info.ModTime()
+	}
+
+	return &RecordingContent{
+		Filename:    filename,
+		ContentType: recordingContentType(voicemail.Format),
+		ModTime:     modTime,
+		Content:     file,
+	}, nil
+}
+
+// MarkRead marks a voicemail as read or unread
+func (s *voicemailService) MarkRead(ctx context.Context, tenantID string, userID, id int64, read bool) (*dto.VoicemailResponse, error) {
+	voicemail, err := s.findVoicemail(ctx, tenantID, userID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if read && !voicemail.IsRead {
+		voicemail.MarkAsRead()
+	} else if !read {
+		voicemail.IsRead = false
+		voicemail.ReadAt = nil
+	}
+	if err := s.voicemailRepo.Update(ctx, voicemail); err != nil {
+		return nil, errors.Wrap(err, "failed to update voicemail")
+	}
+	return toVoicemailResponse(voicemail), nil
+}
+
+// Delete removes a voicemail's audio; the row is kept, marked deleted
+func (s *voicemailService) Delete(ctx context.Context, tenantID string, userID, id int64) error {
+	voicemail, err := s.findVoicemail(ctx, tenantID, userID, id)
+	if err != nil {
+		return err
+	}
+	if err := deleteVoicemail(ctx, s.store, s.voicemailRepo, s.transcripts, voicemail); err != nil {
+		return errors.Wrap(err, "failed to delete voicemail")
+	}
+	return nil
+}
+
+// ListMailboxes lists the mailboxes a user can access
+func (s *voicemailService) ListMailboxes(ctx context.Context, tenantID string, userID int64) ([]*dto.VoicemailMailboxResponse, error) {
+	role, err := s.roleRepo.FindByUserAndTenant(ctx, userID, tenantID)
+	if err != nil {
+		return nil, errors.NewForbidden("no role in this tenant")
+	}
+
+	var mailboxes []asterisk.VoicemailMailbox
+	if canManageMailboxes(role) {
+		mailboxes, err = s.mailboxRepo.FindByTenant(ctx, tenantID)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to get mailboxes")
+		}
+	} else if mailbox, err := s.mailboxRepo.FindByUser(ctx, tenantID, userID); err == nil {
+		mailboxes = []asterisk.VoicemailMailbox{*mailbox}
+	}
+
+	responses := make([]*dto.VoicemailMailboxResponse, len(mailboxes))
+	for i := range mailboxes {
+		responses[i] = s.toMailboxResponse(ctx, &mailboxes[i])
+	}
+	return responses, nil
+}
+
+// GetMailbox gets a mailbox
+func (s *voicemailService) GetMailbox(ctx context.Context, tenantID string, userID, id int64) (*dto.VoicemailMailboxResponse, error) {
+	mailbox, err := s.findMailbox(ctx, tenantID, userID, id)
+	if err != nil {
+		return nil, err
+	}
+	return s.toMailboxResponse(ctx, mailbox), nil
+}
+
+// CreateMailbox creates a mailbox for a user or a DID
+func (s *voicemailService) CreateMailbox(ctx context.Context, tenantID string, userID int64, req *dto.CreateVoicemailMailboxRequest) (*dto.VoicemailMailboxResponse, error) {
+	if err := s.checkManageMailboxes(ctx, tenantID, userID); err != nil {
+		return nil, err
+	}
+
+	if (req.UserID == nil) == (req.DIDID == nil) {
+		return nil, errors.NewValidation(map[string]string{"user_id": "exactly one of user_id and did_id is required"})
+	}
+	if req.UserID != nil {
+		if _, err := s.roleRepo.FindByUserAndTenant(ctx, *req.UserID, tenantID); err != nil {
+			return nil, errors.NewValidation(map[string]string{"user_id": "user is not a member of this tenant"})
+		}
+		if _, err := s.mailboxRepo.FindByUser(ctx, tenantID, *req.UserID); err == nil {
+			return nil, errors.NewConflict("user already has a mailbox")
+		}
+	}
+	if req.DIDID != nil {
+		did, err := s.didRepo.FindByID(ctx, *req.DIDID)
+		if err != nil || did.TenantID != tenantID {
+			return nil, errors.NewValidation(map[string]string{"did_id": "DID not found"})
+		}
+		if _, err := s.mailboxRepo.FindByDID(ctx, *req.DIDID); err == nil {
+			return nil, errors.NewConflict("DID already has a mailbox")
+		}
+	}
+
+	mailbox := &asterisk.VoicemailMailbox{
+		TenantID:  tenantID,
+		Name:      req.Name,
+		UserID:    req.UserID,
+		DIDID:     req.DIDID,
+		Greeting:  req.Greeting,
+		MaxLength: req.MaxLength,
+		IsActive:  true,
+	}
+	if mailbox.MaxLength == 0 {
+		mailbox.MaxLength = asterisk.VoicemailDefaultMaxLength
+	}
+
+	if err := s.mailboxRepo.Create(ctx, mailbox); err != nil {
+		return nil, errors.Wrap(err, "failed to create mailbox")
+	}
+	return s.toMailboxResponse(ctx, mailbox), nil
+}
+
+// UpdateMailbox updates a mailbox. Owners may change their own mailbox's
+// greeting and message length; anything else needs permission to manage mailboxes.
+func (s *voicemailService) UpdateMailbox(ctx context.Context, tenantID string, userID, id int64, req *dto.UpdateVoicemailMailboxRequest) (*dto.VoicemailMailboxResponse, error) {
+	mailbox, err := s.findMailbox(ctx, tenantID, userID, id)
+	if err != nil {
+		return nil, err
+	}
+	if req.Name != nil || req.IsActive != nil {
+		if err := s.checkManageMailboxes(ctx, tenantID, userID); err != nil {
+			return nil, err
+		}
+	}
+
+	if req.Name != nil {
+		mailbox.Name = *req.Name
+	}
+	if req.Greeting != nil {
+		s.removeGreeting(mailbox)
+		mailbox.Greeting = req.Greeting
+		if *req.Greeting == "" {
+			mailbox.Greeting = nil
+		}
+	}
+	if req.MaxLength != nil {
+		mailbox.MaxLength = *req.MaxLength
+	}
+	if req.IsActive != nil {
+		mailbox.IsActive = *req.IsActive
+	}
+
+	if err := s.mailboxRepo.Update(ctx, mailbox); err != nil {
+		return nil, errors.Wrap(err, "failed to update mailbox")
+	}
+	return s.toMailboxResponse(ctx, mailbox), nil
+}
+
+// DeleteMailbox deletes a mailbox and its uploaded greeting. Its voicemails are kept.
+func (s *voicemailService) DeleteMailbox(ctx context.Context, tenantID string, userID, id int64) error {
+	if err := s.checkManageMailboxes(ctx, tenantID, userID); err != nil {
+		return err
+	}
+	mailbox, err := s.findMailbox(ctx, tenantID, userID, id)
+	if err != nil {
+		return err
+	}
+
+	if err := s.mailboxRepo.Delete(ctx, mailbox.ID); err != nil {
+		return errors.Wrap(err, "failed to delete mailbox")
+	}
+	s.removeGreeting(mailbox)
+	return nil
+}
+
+// UploadGreeting stores a WAV greeting where Asterisk can play it and makes
+// it the mailbox's greeting
+func (s *voicemailService) UploadGreeting(ctx context.Context, tenantID string, userID, id int64, content io.Reader) (*dto.VoicemailMailboxResponse, error) {
+	mailbox, err := s.findMailbox(ctx, tenantID, userID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := io.ReadAll(io.LimitReader(content, maxGreetingSize+1))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read greeting")
+	}
+	if len(data) > maxGreetingSize {
+		return nil, errors.NewValidation(map[string]string{"file": fmt.Sprintf("must not be larger than %d MB", maxGreetingSize>>20)})
+	}
+	if len(data) < 12 || !bytes.Equal(data[0:4], []byte("RIFF")) || !bytes.Equal(data[8:12], []byte("WAVE")) {
+		return nil, errors.NewValidation(map[string]string{"file": "must be a WAV file"})
+	}
+
+	name := asterisk.GreetingName(mailbox.TenantID, mailbox.ID)
+	path := filepath.Join(s.recordingPath, name+".wav")
+	tmp := path + ".upload"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return nil, errors.Wrap(err, "failed to save greeting")
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return nil, errors.Wrap(err, "failed to save greeting")
+	}
+
+	greeting := "recording:" + name
+	mailbox.Greeting = &greeting
+	if err := s.mailboxRepo.Update(ctx, mailbox); err != nil {
+		return nil, errors.Wrap(err, "failed to update mailbox")
+	}
+	return s.toMailboxResponse(ctx, mailbox), nil
+}
+
+// DeleteGreeting goes back to the default greeting
+func (s *voicemailService) DeleteGreeting(ctx context.Context, tenantID string, userID, id int64) (*dto.VoicemailMailboxResponse, error) {
+	mailbox, err := s.findMailbox(ctx, tenantID, userID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	s.removeGreeting(mailbox)
+	mailbox.Greeting = nil
+	if err := s.mailboxRepo.Update(ctx, mailbox); err != nil {
+		return nil, errors.Wrap(err, "failed to update mailbox")
+	}
+	return s.toMailboxResponse(ctx, mailbox), nil
+}
+
+// removeGreeting deletes a mailbox's uploaded greeting file, if it has one
+func (s *voicemailService) removeGreeting(mailbox *asterisk.VoicemailMailbox) {
+	if mailbox.Greeting == nil || !strings.HasPrefix(*mailbox.Greeting, "recording:") {
+		return
+	}
+	path := filepath.Join(s.recordingPath, asterisk.GreetingName(mailbox.TenantID, mailbox.ID)+".wav")
+	if err := os.Remove(path); err != nil && !stderrors.Is(err, os.ErrNotExist) {
+		log.Printf("Error removing greeting of mailbox %d: %v", mailbox.ID, err)
+	}
+}
+
+// findVoicemail loads a voicemail that has not been deleted from a mailbox the user can access
+func (s *voicemailService) findVoicemail(ctx context.Context, tenantID string, userID, id int64) (*asterisk.Voicemail, error) {
+	voicemail, err := s.voicemailRepo.FindByID(ctx, id)
+	if err != nil || voicemail.TenantID != tenantID || voicemail.IsDeleted {
+		return nil, errors.NewNotFound("voicemail not found")
+	}
+
+	if voicemail.UserID != nil && *voicemail.UserID == userID {
+		return voicemail, nil
+	}
+	role, err := s.roleRepo.FindByUserAndTenant(ctx, userID, tenantID)
+	if err != nil || !canManageMailboxes(role) {
+		return nil, errors.NewNotFound("voicemail not found")
+	}
+	return voicemail, nil
+}
+
+// findMailbox loads a tenant's mailbox the user owns or may manage
+func (s *voicemailService) findMailbox(ctx context.Context, tenantID string, userID, id int64) (*asterisk.VoicemailMailbox, error) {
+	mailbox, err := s.mailboxRepo.FindByID(ctx, id)
+	if err != nil || mailbox.TenantID != tenantID {
+		return nil, errors.NewNotFound("mailbox not found")
+	}
+
+	if mailbox.UserID != nil && *mailbox.UserID == userID {
+		return mailbox, nil
+	}
+	role, err := s.roleRepo.FindByUserAndTenant(ctx, userID, tenantID)
+	if err != nil || !canManageMailboxes(role) {
+		return nil, errors.NewNotFound("mailbox not found")
+	}
+	return mailbox, nil
+}
+
+// checkManageMailboxes checks that a user may manage the tenant's mailboxes
+func (s *voicemailService) checkManageMailboxes(ctx context.Context, tenantID string, userID int64) error {
+	role, err := s.roleRepo.FindByUserAndTenant(ctx, userID, tenantID)
+	if err != nil {
+		return errors.NewForbidden("no role in this tenant")
+	}
+	if !canManageMailboxes(role) {
+		return errors.NewForbidden("not allowed to manage mailboxes")
+	}
+	return nil
+}
+
+// canManageMailboxes reports whether a role can manage all of its tenant's
+// mailboxes and listen to their messages
+func canManageMailboxes(role *core.UserRole) bool {
+	return role.IsAdmin() || role.IsSupervisor() || role.Permissions.CanManageDIDs
+}
+
+// deleteVoicemail removes a voicemail's audio from storage and the spool with
+// its transcript and marks it deleted
+func deleteVoicemail(ctx context.Context, store storage.Storage, voicemailRepo repository.VoicemailRepository, transcripts TranscriptionService, voicemail *asterisk.Voicemail) error {
+	if voicemail.StorageKey != nil {
+		if err := store.Delete(ctx, *voicemail.StorageKey); err != nil {
+			return err
+		}
+	}
+	if err := os.Remove(voicemail.FilePath); err != nil && !stderrors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	if err := transcripts.DeleteTranscript(ctx, common.TranscriptSourceVoicemail, voicemail.ID); err != nil {
+		return err
+	}
+
+	voicemail.IsDeleted = true
+	voicemail.Transcription = nil
+	return voicemailRepo.Update(ctx, voicemail)
+}
+
+// toMailboxResponse converts a mailbox to its response with its unread count
+func (s *voicemailService) toMailboxResponse(ctx context.Context, mailbox *asterisk.VoicemailMailbox) *dto.VoicemailMailboxResponse {
+	unread, _ := s.voicemailRepo.CountUnread(ctx, mailbox.TenantID, mailbox.ID)
+	return &dto.VoicemailMailboxResponse{
+		ID:          mailbox.ID,
+		TenantID:    mailbox.TenantID,
+		Name:        mailbox.Name,
+		UserID:      mailbox.UserID,
+		DIDID:       mailbox.DIDID,
+		Greeting:    mailbox.Greeting,
+		MaxLength:   mailbox.MessageLength(),
+		IsActive:    mailbox.IsActive,
+		UnreadCount: unread,
+		CreatedAt:   mailbox.CreatedAt,
+		UpdatedAt:   mailbox.UpdatedAt,
+	}
+}
+
+// toVoicemailResponse converts a voicemail to its response
+func toVoicemailResponse(voicemail *asterisk.Voicemail) *dto.VoicemailResponse {
+	return &dto.VoicemailResponse{
+		ID:            voicemail.ID,
+		TenantID:      voicemail.TenantID,
+		MailboxID:     voicemail.MailboxID,
+		DIDID:         voicemail.DIDID,
+		UserID:        voicemail.UserID,
+		CallerID:      voicemail.CallerID,
+		CallerName:    voicemail.CallerName,
+		Duration:      voicemail.Duration,
+		FileSize:      voicemail.FileSize,
+		Format:        voicemail.Format,
+		IsRead:        voicemail.IsRead,
+		Transcription: voicemail.Transcription,
+		StreamURL:     fmt.Sprintf("/api/v1/voicemails/%d/stream", voicemail.ID),
+		CreatedAt:     voicemail.CreatedAt,
+		ReadAt:        voicemail.ReadAt,
+	}
+}
@@ -0,0 +1,65 @@
+package sms
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+
+	"github.com/psschand/callcenter/internal/common"
+)
+
+// FakeProvider is an in-memory provider for development and tests. Messages
+// sent are kept rather than delivered, and its callbacks are unsigned form
+// posts with the same fields as Twilio's.
+type FakeProvider struct {
+	mu   sync.Mutex
+	sent []Message
+}
+
+// NewFakeProvider creates the in-memory provider
+func NewFakeProvider() *FakeProvider {
+	return &FakeProvider{}
+}
+
+// Name returns ProviderFake
+func (p *FakeProvider) Name() string {
+	return ProviderFake
+}
+
+// Send keeps the message and reports it sent
+func (p *FakeProvider) Send(ctx context.Context, msg *Message) (*SendResult, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.sent = append(p.sent, *msg)
+	return &SendResult{
+		MessageID: fmt.Sprintf("fake-%d", len(p.sent)),
+		Status:    common.SMSStatusSent,
+		Segments:  CountSegments(msg.Body),
+	}, nil
+}
+
+// Sent returns the messages sent so far
+func (p *FakeProvider) Sent() []Message {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return append([]Message(nil), p.sent...)
+}
+
+// ParseInbound parses an inbound message callback
+func (p *FakeProvider) ParseInbound(r *http.Request) (*InboundMessage, error) {
+	if err := r.ParseForm(); err != nil {
+		return nil, ErrInvalidCallback
+	}
+	return parseInboundForm(r.PostForm)
+}
+
+// ParseStatus parses a delivery status callback
+func (p *FakeProvider) ParseStatus(r *http.Request) (*StatusUpdate, error) {
+	if err := r.ParseForm(); err != nil {
+		return nil, ErrInvalidCallback
+	}
+	return parseStatusForm(r.PostForm)
+}
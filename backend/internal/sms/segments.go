@@ -0,0 +1,48 @@
+package sms
+
+import (
+	"strings"
+	"unicode/utf16"
+)
+
+// Characters per segment: a single message holds more than each part of a
+// concatenated one, which gives up room to the header joining the parts
+const (
+	gsmSingleLength  = 160
+	gsmPartLength    = 153
+	ucs2SingleLength = 70
+	ucs2PartLength   = 67
+)
+
+// gsmBasic is the GSM 03.38 basic character set; each takes one septet
+const gsmBasic = "@£$¥èéùìòÇ\nØø\rÅåΔ_ΦΓΛΩΠΨΣΘΞÆæßÉ !\"#¤%&'()*+,-./0123456789:;<=>?" +
+	"¡ABCDEFGHIJKLMNOPQRSTUVWXYZÄÖÑÜ§¿abcdefghijklmnopqrstuvwxyzäöñüà"
+
+// gsmExtended is the GSM 03.38 extension table; each takes an escape septet and its own
+const gsmExtended = "^{}\\[~]|€\f"
+
+// CountSegments returns the number of segments a message body is sent in.
+// Bodies that fit the GSM 7-bit alphabet are sent as such; any other
+// character makes the whole message UCS-2.
+func CountSegments(body string) int {
+	septets := 0
+	for _, r := range body {
+		switch {
+		case strings.ContainsRune(gsmBasic, r):
+			septets++
+		case strings.ContainsRune(gsmExtended, r):
+			septets += 2
+		default:
+			return segments(len(utf16.Encode([]rune(body))), ucs2SingleLength, ucs2PartLength)
+		}
+	}
+	return segments(septets, gsmSingleLength, gsmPartLength)
+}
+
+// segments returns the parts length characters are split into
+func segments(length, single, part int) int {
+	if length <= single {
+		return 1
+	}
+	return (length + part - 1) / part
+}
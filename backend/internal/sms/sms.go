@@ -0,0 +1,154 @@
+// Package sms sends and receives text messages through a pluggable provider:
+// a Twilio-compatible messaging API, or an in-memory fake for development
+// and tests.
+package sms
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/psschand/callcenter/internal/common"
+	"github.com/psschand/callcenter/internal/config"
+)
+
+// SMS providers
+const (
+	ProviderNone   = "none"
+	ProviderFake   = "fake"
+	ProviderTwilio = "twilio"
+)
+
+// ErrInvalidSignature is returned for callbacks that cannot be shown to come from the provider
+var ErrInvalidSignature = errors.New("sms: invalid callback signature")
+
+// ErrInvalidCallback is returned for callbacks missing required fields
+var ErrInvalidCallback = errors.New("sms: invalid callback")
+
+// Message is a message to send
+type Message struct {
+	From string
+	To   string
+	Body string
+}
+
+// SendResult is the provider's answer to a message sent
+type SendResult struct {
+	MessageID string
+	Status    common.SMSStatus
+	Segments  int     // 0 when the provider does not say
+	Cost      float64 // 0 when the provider does not say
+}
+
+// InboundMessage is a message received by one of the tenant's numbers
+type InboundMessage struct {
+	MessageID string
+	From      string
+	To        string
+	Body      string
+	Segments  int // 0 when the provider does not say
+}
+
+// StatusUpdate is a delivery status callback for a message sent
+type StatusUpdate struct {
+	MessageID    string
+	Status       common.SMSStatus
+	ErrorMessage string
+}
+
+// Provider sends messages and parses the provider's callbacks
+type Provider interface {
+	// Name returns the name of the provider, e.g. ProviderTwilio
+	Name() string
+	// Send sends a message
+	Send(ctx context.Context, msg *Message) (*SendResult, error)
+	// ParseInbound authenticates and parses an inbound message callback
+	ParseInbound(r *http.Request) (*InboundMessage, error)
+	// ParseStatus authenticates and parses a delivery status callback
+	ParseStatus(r *http.Request) (*StatusUpdate, error)
+}
+
+// New creates the provider selected by the configuration; it returns nil
+// when SMS is turned off
+func New(cfg config.SMSConfig) (Provider, error) {
+	switch cfg.Provider {
+	case ProviderNone, "":
+		return nil, nil
+	case ProviderFake:
+		return NewFakeProvider(), nil
+	case ProviderTwilio:
+		return NewTwilioProvider(TwilioOptions{
+			APIURL:      cfg.APIURL,
+			AccountSID:  cfg.AccountSID,
+			AuthToken:   cfg.AuthToken,
+			CallbackURL: cfg.CallbackURL,
+		})
+	default:
+		return nil, fmt.Errorf("unknown sms provider %q", cfg.Provider)
+	}
+}
+
+// parseInboundForm reads an inbound message from Twilio's callback fields
+func parseInboundForm(form url.Values) (*InboundMessage, error) {
+	msg := &InboundMessage{
+		MessageID: firstOf(form, "MessageSid", "SmsSid"),
+		From:      form.Get("From"),
+		To:        form.Get("To"),
+		Body:      form.Get("Body"),
+	}
+	if msg.MessageID == "" || msg.From == "" || msg.To == "" {
+		return nil, ErrInvalidCallback
+	}
+	msg.Segments, _ = strconv.Atoi(form.Get("NumSegments"))
+	return msg, nil
+}
+
+// parseStatusForm reads a delivery status from Twilio's callback fields
+func parseStatusForm(form url.Values) (*StatusUpdate, error) {
+	update := &StatusUpdate{
+		MessageID: firstOf(form, "MessageSid", "SmsSid"),
+		Status:    providerStatus(firstOf(form, "MessageStatus", "SmsStatus")),
+	}
+	if update.MessageID == "" || update.Status == "" {
+		return nil, ErrInvalidCallback
+	}
+	if code := form.Get("ErrorCode"); code != "" {
+		update.ErrorMessage = "error " + code
+	}
+	if message := form.Get("ErrorMessage"); message != "" {
+		update.ErrorMessage = message
+	}
+	return update, nil
+}
+
+// providerStatus maps a Twilio message status to ours; it is empty for statuses we do not know
+func providerStatus(status string) common.SMSStatus {
+	switch strings.ToLower(status) {
+	case "accepted", "scheduled", "queued":
+		return common.SMSStatusQueued
+	case "sending", "sent":
+		return common.SMSStatusSent
+	case "delivered", "read":
+		return common.SMSStatusDelivered
+	case "undelivered", "failed", "canceled":
+		return common.SMSStatusFailed
+	case "receiving", "received":
+		return common.SMSStatusReceived
+	default:
+		return ""
+	}
+}
+
+// firstOf returns the first of the given form fields that is set
+func firstOf(form url.Values, keys ...string) string {
+	for _, key := range keys {
+		if value := form.Get(key); value != "" {
+			return value
+		}
+	}
+	return ""
+}
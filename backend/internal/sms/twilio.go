@@ -0,0 +1,218 @@
+package sms
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/psschand/callcenter/internal/common"
+)
+
+// twilioSignatureHeader carries the signature of Twilio's callbacks
+const twilioSignatureHeader = "X-Twilio-Signature"
+
+// TwilioOptions configures the Twilio-compatible provider
+type TwilioOptions struct {
+	APIURL     string // base URL of the API, e.g. https://api.twilio.com
+	AccountSID string
+	AuthToken  string
+	// CallbackURL is the public URL of the SMS webhooks, which the provider
+	// posts delivery statuses to and which callback signatures are checked
+	// against. Empty leaves status callbacks to the number's configuration.
+	CallbackURL string
+	Timeout     time.Duration
+}
+
+// twilioProvider sends messages through Twilio's Messages API, or any
+// service compatible with it
+type twilioProvider struct {
+	opts     TwilioOptions
+	callback *url.URL
+	client   *http.Client
+}
+
+// twilioMessage is a message resource returned by the API
+type twilioMessage struct {
+	SID          string          `json:"sid"`
+	Status       string          `json:"status"`
+	NumSegments  json.RawMessage `json:"num_segments"`
+	Price        json.RawMessage `json:"price"`
+	ErrorMessage *string         `json:"error_message"`
+}
+
+// twilioError is an error returned by the API
+type twilioError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// NewTwilioProvider creates a provider for a Twilio-compatible messaging API
+func NewTwilioProvider(opts TwilioOptions) (Provider, error) {
+	if opts.APIURL == "" || opts.AccountSID == "" || opts.AuthToken == "" {
+		return nil, errors.New("twilio sms provider requires an API URL, account SID and auth token")
+	}
+	opts.APIURL = strings.TrimRight(opts.APIURL, "/")
+	if opts.Timeout <= 0 {
+		opts.Timeout = 30 * time.Second
+	}
+
+	p := &twilioProvider{
+		opts:   opts,
+		client: &http.Client{Timeout: opts.Timeout},
+	}
+	if opts.CallbackURL != "" {
+		callback, err := url.Parse(strings.TrimRight(opts.CallbackURL, "/"))
+		if err != nil || callback.Scheme == "" || callback.Host == "" {
+			return nil, fmt.Errorf("invalid sms callback URL %q", opts.CallbackURL)
+		}
+		p.callback = callback
+	}
+	return p, nil
+}
+
+// Name returns ProviderTwilio
+func (p *twilioProvider) Name() string {
+	return ProviderTwilio
+}
+
+// Send sends a message, asking for its delivery statuses to be posted to the status webhook
+func (p *twilioProvider) Send(ctx context.Context, msg *Message) (*SendResult, error) {
+	form := url.Values{}
+	form.Set("From", msg.From)
+	form.Set("To", msg.To)
+	form.Set("Body", msg.Body)
+	if p.callback != nil {
+		form.Set("StatusCallback", p.callback.String()+"/status")
+	}
+
+	endpoint := fmt.Sprintf("%s/2010-04-01/Accounts/%s/Messages.json", p.opts.APIURL, url.PathEscape(p.opts.AccountSID))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	req.SetBasicAuth(p.opts.AccountSID, p.opts.AuthToken)
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= http.StatusMultipleChoices {
+		var apiErr twilioError
+		if err := json.NewDecoder(resp.Body).Decode(&apiErr); err != nil || apiErr.Message == "" {
+			return nil, fmt.Errorf("sms: provider returned %s", resp.Status)
+		}
+		return nil, fmt.Errorf("sms: %s (code %d)", apiErr.Message, apiErr.Code)
+	}
+
+	var message twilioMessage
+	if err := json.NewDecoder(resp.Body).Decode(&message); err != nil {
+		return nil, fmt.Errorf("sms: invalid provider response: %w", err)
+	}
+
+	result := &SendResult{
+		MessageID: message.SID,
+		Status:    providerStatus(message.Status),
+		Segments:  int(jsonNumber(message.NumSegments)),
+		// Prices are reported as negative amounts charged, and often only once sent
+		Cost: math.Abs(jsonNumber(message.Price)),
+	}
+	if result.Status == "" {
+		result.Status = common.SMSStatusQueued
+	}
+	if result.Status == common.SMSStatusFailed && message.ErrorMessage != nil {
+		return nil, fmt.Errorf("sms: %s", *message.ErrorMessage)
+	}
+	return result, nil
+}
+
+// ParseInbound checks the signature of an inbound message callback and parses it
+func (p *twilioProvider) ParseInbound(r *http.Request) (*InboundMessage, error) {
+	if err := p.verify(r); err != nil {
+		return nil, err
+	}
+	return parseInboundForm(r.PostForm)
+}
+
+// ParseStatus checks the signature of a delivery status callback and parses it
+func (p *twilioProvider) ParseStatus(r *http.Request) (*StatusUpdate, error) {
+	if err := p.verify(r); err != nil {
+		return nil, err
+	}
+	return parseStatusForm(r.PostForm)
+}
+
+// verify checks a callback's signature: a base64 HMAC-SHA1, keyed with the
+// auth token, of the URL it was posted to followed by each form field's
+// name and value, sorted by name
+func (p *twilioProvider) verify(r *http.Request) error {
+	if err := r.ParseForm(); err != nil {
+		return ErrInvalidCallback
+	}
+	signature := r.Header.Get(twilioSignatureHeader)
+	if signature == "" {
+		return ErrInvalidSignature
+	}
+
+	var payload strings.Builder
+	payload.WriteString(p.requestURL(r))
+	keys := make([]string, 0, len(r.PostForm))
+	for key := range r.PostForm {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		values := append([]string(nil), r.PostForm[key]...)
+		sort.Strings(values)
+		for _, value := range values {
+			payload.WriteString(key)
+			payload.WriteString(value)
+		}
+	}
+
+	mac := hmac.New(sha1.New, []byte(p.opts.AuthToken))
+	mac.Write([]byte(payload.String()))
+	expected := base64.StdEncoding.EncodeToString(mac.Sum(nil))
+	if !hmac.Equal([]byte(signature), []byte(expected)) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+// requestURL returns the URL the provider posted a callback to. Behind a
+// proxy the request's own host and scheme are not the public ones, so the
+// callback URL's are used when configured.
+func (p *twilioProvider) requestURL(r *http.Request) string {
+	if p.callback != nil {
+		return p.callback.Scheme + "://" + p.callback.Host + r.URL.RequestURI()
+	}
+	scheme := "http"
+	if r.TLS != nil || r.Header.Get("X-Forwarded-Proto") == "https" {
+		scheme = "https"
+	}
+	return scheme + "://" + r.Host + r.URL.RequestURI()
+}
+
+// jsonNumber reads a number the API may send as a number, a string or null
+func jsonNumber(raw json.RawMessage) float64 {
+	text := strings.Trim(string(raw), `"`)
+	value, err := strconv.ParseFloat(text, 64)
+	if err != nil {
+		return 0
+	}
+	return value
+}
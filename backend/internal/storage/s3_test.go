@@ -0,0 +1,361 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+const (
+	testAccessKey = "minio-access"
+	testSecretKey = "minio-secret"
+	testRegion    = "us-east-1"
+	testBucket    = "recordings"
+)
+
+// fakeS3 is a MinIO-style stand-in that keeps objects in memory and rejects
+// requests whose Signature Version 4 does not verify
+type fakeS3 struct {
+	t         *testing.T
+	secretKey string
+
+	mu      sync.Mutex
+	objects map[string][]byte
+	types   map[string]string
+}
+
+func newFakeS3(t *testing.T) (*fakeS3, *httptest.Server) {
+	f := &fakeS3{
+		t:         t,
+		secretKey: testSecretKey,
+		objects:   make(map[string][]byte),
+		types:     make(map[string]string),
+	}
+	server := httptest.NewServer(f)
+	t.Cleanup(server.Close)
+	return f, server
+}
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if err := f.verify(r); err != nil {
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+
+	path, _, _ := strings.Cut(r.RequestURI, "?")
+	key, ok := strings.CutPrefix(path, "/"+testBucket+"/")
+	if !ok {
+		http.Error(w, "no such bucket", http.StatusNotFound)
+		return
+	}
+	key, _ = url.PathUnescape(key)
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	switch r.Method {
+	case http.MethodPut:
+		body, _ := io.ReadAll(r.Body)
+		f.objects[key] = body
+		f.types[key] = r.Header.Get("Content-Type")
+	case http.MethodHead, http.MethodGet:
+		body, ok := f.objects[key]
+		if !ok {
+			http.Error(w, "no such key", http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Last-Modified", time.Now().UTC().Format(http.TimeFormat))
+		if rng := r.Header.Get("Range"); rng != "" {
+			start, err := strconv.Atoi(strings.TrimSuffix(strings.TrimPrefix(rng, "bytes="), "-"))
+			if err != nil || start > len(body) {
+				http.Error(w, "bad range", http.StatusRequestedRangeNotSatisfiable)
+				return
+			}
+			body = body[start:]
+			w.Header().Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", start, len(f.objects[key])-1, len(f.objects[key])))
+			w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+			w.WriteHeader(http.StatusPartialContent)
+		} else {
+			w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+		}
+		if r.Method == http.MethodGet {
+			w.Write(body)
+		}
+	case http.MethodDelete:
+		delete(f.objects, key)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// verify checks a request's Authorization header, or its presigned query
+func (f *fakeS3) verify(r *http.Request) error {
+	path, rawQuery, _ := strings.Cut(r.RequestURI, "?")
+	query, err := url.ParseQuery(rawQuery)
+	if err != nil {
+		return err
+	}
+
+	var credential, signedHeaders, signature, amzDate, payloadHash string
+	if query.Get("X-Amz-Algorithm") != "" {
+		if query.Get("X-Amz-Algorithm") != "AWS4-HMAC-SHA256" {
+			return errors.New("unexpected algorithm")
+		}
+		credential = query.Get("X-Amz-Credential")
+		signedHeaders = query.Get("X-Amz-SignedHeaders")
+		signature = query.Get("X-Amz-Signature")
+		amzDate = query.Get("X-Amz-Date")
+		payloadHash = "UNSIGNED-PAYLOAD"
+
+		date, err := time.Parse("20060102T150405Z", amzDate)
+		if err != nil {
+			return err
+		}
+		expires, err := strconv.Atoi(query.Get("X-Amz-Expires"))
+		if err != nil || time.Now().After(date.Add(time.Duration(expires)*time.Second)) {
+			return errors.New("presigned URL expired")
+		}
+		query.Del("X-Amz-Signature")
+	} else {
+		auth := r.Header.Get("Authorization")
+		rest, ok := strings.CutPrefix(auth, "AWS4-HMAC-SHA256 ")
+		if !ok {
+			return fmt.Errorf("missing SigV4 authorization: %q", auth)
+		}
+		for _, part := range strings.Split(rest, ", ") {
+			name, value, _ := strings.Cut(part, "=")
+			switch name {
+			case "Credential":
+				credential = value
+			case "SignedHeaders":
+				signedHeaders = value
+			case "Signature":
+				signature = value
+			}
+		}
+		amzDate = r.Header.Get("X-Amz-Date")
+		payloadHash = r.Header.Get("X-Amz-Content-Sha256")
+		if payloadHash == "" {
+			return errors.New("missing X-Amz-Content-Sha256")
+		}
+	}
+
+	accessKey, scope, _ := strings.Cut(credential, "/")
+	if accessKey != testAccessKey {
+		return fmt.Errorf("unknown access key %q", accessKey)
+	}
+	if len(amzDate) < 8 || scope != amzDate[:8]+"/"+testRegion+"/s3/aws4_request" {
+		return fmt.Errorf("bad credential scope %q", scope)
+	}
+	if !strings.Contains(signedHeaders, "host") {
+		return errors.New("host is not signed")
+	}
+
+	var canonicalHeaders strings.Builder
+	for _, name := range strings.Split(signedHeaders, ";") {
+		value := r.Header.Get(name)
+		if name == "host" {
+			value = r.Host
+		}
+		canonicalHeaders.WriteString(name + ":" + strings.TrimSpace(value) + "\n")
+	}
+
+	names := make([]string, 0, len(query))
+	for name := range query {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var params []string
+	for _, name := range names {
+		for _, value := range query[name] {
+			params = append(params, awsEscape(name)+"="+awsEscape(value))
+		}
+	}
+
+	canonicalRequest := strings.Join([]string{
+		r.Method,
+		path,
+		strings.Join(params, "&"),
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+	hash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := strings.Join([]string{"AWS4-HMAC-SHA256", amzDate, scope, hex.EncodeToString(hash[:])}, "\n")
+
+	key := testHMAC([]byte("AWS4"+f.secretKey), amzDate[:8])
+	key = testHMAC(key, testRegion)
+	key = testHMAC(key, "s3")
+	key = testHMAC(key, "aws4_request")
+	expected := hex.EncodeToString(testHMAC(key, stringToSign))
+	if !hmac.Equal([]byte(expected), []byte(signature)) {
+		return errors.New("signature does not match")
+	}
+	return nil
+}
+
+func testHMAC(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// awsEscape is url.QueryEscape with spaces as %20, as Signature Version 4 expects
+func awsEscape(s string) string {
+	return strings.ReplaceAll(url.QueryEscape(s), "+", "%20")
+}
+
+func newTestS3(t *testing.T, endpoint, secretKey string) Storage {
+	t.Helper()
+	store, err := NewS3Storage(S3Options{
+		Endpoint:  endpoint,
+		Region:    testRegion,
+		Bucket:    testBucket,
+		AccessKey: testAccessKey,
+		SecretKey: secretKey,
+		PathStyle: true,
+	})
+	if err != nil {
+		t.Fatalf("NewS3Storage: %v", err)
+	}
+	return store
+}
+
+func TestS3PutOpenDelete(t *testing.T) {
+	fake, server := newFakeS3(t)
+	store := newTestS3(t, server.URL, testSecretKey)
+	ctx := context.Background()
+
+	key := "acme-corp/recordings/2026/call 1+2.wav"
+	content := []byte("RIFF....WAVEfmt recorded audio")
+	if err := store.Put(ctx, key, bytes.NewReader(content), int64(len(content)), "audio/wav"); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	if got := fake.types[key]; got != "audio/wav" {
+		t.Errorf("stored content type = %q, want audio/wav", got)
+	}
+
+	object, err := store.Open(ctx, key)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer object.Content.Close()
+	if object.Size != int64(len(content)) {
+		t.Errorf("Size = %d, want %d", object.Size, len(content))
+	}
+	got, err := io.ReadAll(object.Content)
+	if err != nil {
+		t.Fatalf("reading object: %v", err)
+	}
+	if !bytes.Equal(got, content) {
+		t.Errorf("content = %q, want %q", got, content)
+	}
+
+	// Seeking fetches the rest of the object with a signed range request
+	if _, err := object.Content.Seek(12, io.SeekStart); err != nil {
+		t.Fatalf("Seek: %v", err)
+	}
+	got, err = io.ReadAll(object.Content)
+	if err != nil {
+		t.Fatalf("reading after seek: %v", err)
+	}
+	if !bytes.Equal(got, content[12:]) {
+		t.Errorf("content after seek = %q, want %q", got, content[12:])
+	}
+
+	if err := store.Delete(ctx, key); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if _, err := store.Open(ctx, key); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Open after Delete: err = %v, want ErrNotFound", err)
+	}
+	if err := store.Delete(ctx, key); err != nil {
+		t.Errorf("Delete of a missing key: %v", err)
+	}
+}
+
+func TestS3EmptyObject(t *testing.T) {
+	_, server := newFakeS3(t)
+	store := newTestS3(t, server.URL, testSecretKey)
+	ctx := context.Background()
+
+	if err := store.Put(ctx, "empty.wav", bytes.NewReader(nil), 0, ""); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	object, err := store.Open(ctx, "empty.wav")
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer object.Content.Close()
+	if got, _ := io.ReadAll(object.Content); len(got) != 0 {
+		t.Errorf("content = %q, want empty", got)
+	}
+}
+
+func TestS3RejectedSignature(t *testing.T) {
+	_, server := newFakeS3(t)
+	store := newTestS3(t, server.URL, "wrong-secret")
+
+	err := store.Put(context.Background(), "a.wav", strings.NewReader("x"), 1, "audio/wav")
+	if err == nil || !strings.Contains(err.Error(), "403") {
+		t.Errorf("Put with the wrong secret: err = %v, want 403", err)
+	}
+}
+
+func TestS3SignedURL(t *testing.T) {
+	_, server := newFakeS3(t)
+	store := newTestS3(t, server.URL, testSecretKey)
+	ctx := context.Background()
+
+	key := "acme-corp/voicemail/message 1.wav"
+	if err := store.Put(ctx, key, strings.NewReader("hello"), 5, "audio/wav"); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+
+	signed, err := store.SignedURL(ctx, key, time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatalf("SignedURL: %v", err)
+	}
+	resp, err := http.Get(signed)
+	if err != nil {
+		t.Fatalf("GET signed URL: %v", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || string(body) != "hello" {
+		t.Fatalf("GET signed URL = %d %q, want 200 \"hello\"", resp.StatusCode, body)
+	}
+
+	// Tampering with the presigned query invalidates it
+	tampered := strings.Replace(signed, "X-Amz-Expires=3600", "X-Amz-Expires=7200", 1)
+	resp, err = http.Get(tampered)
+	if err != nil {
+		t.Fatalf("GET tampered URL: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusForbidden {
+		t.Errorf("GET tampered URL = %d, want 403", resp.StatusCode)
+	}
+
+	if _, err := store.SignedURL(ctx, key, time.Now().Add(8*24*time.Hour)); err == nil {
+		t.Error("SignedURL beyond the S3 maximum expiry succeeded")
+	}
+	if _, err := store.SignedURL(ctx, key, time.Now().Add(-time.Minute)); err == nil {
+		t.Error("SignedURL in the past succeeded")
+	}
+}
@@ -0,0 +1,69 @@
+package storage
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// FilesPath is the API path signed download URLs of locally stored objects point at
+const FilesPath = "/api/v1/files/"
+
+// ErrInvalidSignature is returned for download URLs that are forged or have expired
+var ErrInvalidSignature = errors.New("storage: invalid or expired signature")
+
+// URLSigner signs time-limited download URLs served by the API itself
+type URLSigner struct {
+	secret  []byte
+	baseURL string
+}
+
+// NewURLSigner creates a URL signer. baseURL is the public address of the API;
+// when empty, signed URLs are relative.
+func NewURLSigner(secret, baseURL string) *URLSigner {
+	return &URLSigner{
+		secret:  []byte(secret),
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+	}
+}
+
+// Sign returns a URL for downloading key until expires
+func (s *URLSigner) Sign(key string, expires time.Time) string {
+	segments := strings.Split(key, "/")
+	for i, segment := range segments {
+		segments[i] = url.PathEscape(segment)
+	}
+
+	query := url.Values{}
+	query.Set("expires", strconv.FormatInt(expires.Unix(), 10))
+	query.Set("signature", s.signature(key, expires.Unix()))
+
+	return fmt.Sprintf("%s%s%s?%s", s.baseURL, FilesPath, strings.Join(segments, "/"), query.Encode())
+}
+
+// Verify checks the expiry and signature of a download URL for key
+func (s *URLSigner) Verify(key, expires, signature string) error {
+	unix, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil || time.Now().Unix() > unix {
+		return ErrInvalidSignature
+	}
+
+	expected := s.signature(key, unix)
+	if !hmac.Equal([]byte(expected), []byte(signature)) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+// signature computes the HMAC of a key and its expiry
+func (s *URLSigner) signature(key string, expires int64) string {
+	mac := hmac.New(sha256.New, s.secret)
+	fmt.Fprintf(mac, "%s\n%d", key, expires)
+	return hex.EncodeToString(mac.Sum(nil))
+}
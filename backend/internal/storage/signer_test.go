@@ -0,0 +1,78 @@
+package storage
+
+import (
+	"errors"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// signedParts splits a signed URL into the key and query Verify takes
+func signedParts(t *testing.T, signed string) (key, expires, signature string) {
+	t.Helper()
+	u, err := url.Parse(signed)
+	if err != nil {
+		t.Fatalf("parsing signed URL %q: %v", signed, err)
+	}
+	key, ok := strings.CutPrefix(u.Path, FilesPath)
+	if !ok {
+		t.Fatalf("signed URL %q is not under %s", signed, FilesPath)
+	}
+	return key, u.Query().Get("expires"), u.Query().Get("signature")
+}
+
+func TestURLSignerVerify(t *testing.T) {
+	signer := NewURLSigner("signing-key", "https://api.example.com/")
+	key := "acme-corp/recordings/call 1.wav"
+
+	signed := signer.Sign(key, time.Now().Add(time.Minute))
+	if !strings.HasPrefix(signed, "https://api.example.com"+FilesPath) {
+		t.Errorf("signed URL %q does not start with the base URL", signed)
+	}
+
+	gotKey, expires, signature := signedParts(t, signed)
+	if gotKey != key {
+		t.Errorf("key = %q, want %q", gotKey, key)
+	}
+	if err := signer.Verify(gotKey, expires, signature); err != nil {
+		t.Errorf("Verify: %v", err)
+	}
+}
+
+func TestURLSignerRejectsExpired(t *testing.T) {
+	signer := NewURLSigner("signing-key", "")
+
+	key, expires, signature := signedParts(t, signer.Sign("a.wav", time.Now().Add(-time.Second)))
+	if err := signer.Verify(key, expires, signature); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("Verify of an expired URL: err = %v, want ErrInvalidSignature", err)
+	}
+}
+
+func TestURLSignerRejectsTampering(t *testing.T) {
+	signer := NewURLSigner("signing-key", "")
+	key, expires, signature := signedParts(t, signer.Sign("acme-corp/a.wav", time.Now().Add(time.Minute)))
+
+	later := time.Now().Add(time.Hour).Unix()
+	cases := []struct {
+		name                    string
+		key, expires, signature string
+		signer                  *URLSigner
+	}{
+		{"other key", "acme-corp/b.wav", expires, signature, signer},
+		{"other tenant", "other-corp/a.wav", expires, signature, signer},
+		{"extended expiry", key, strconv.FormatInt(later, 10), signature, signer},
+		{"bad expiry", key, "soon", signature, signer},
+		{"altered signature", key, expires, strings.Repeat("0", len(signature)), signer},
+		{"missing signature", key, expires, "", signer},
+		{"other secret", key, expires, signature, NewURLSigner("other-key", "")},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if err := tc.signer.Verify(tc.key, tc.expires, tc.signature); !errors.Is(err, ErrInvalidSignature) {
+				t.Errorf("Verify: err = %v, want ErrInvalidSignature", err)
+			}
+		})
+	}
+}
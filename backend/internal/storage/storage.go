@@ -0,0 +1,80 @@
+// Package storage stores call recordings and voicemails outside the Asterisk
+// spool, on the local filesystem or in an S3-compatible object store.
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/psschand/callcenter/internal/config"
+)
+
+// Storage backends
+const (
+	BackendLocal = "local"
+	BackendS3    = "s3"
+)
+
+// ErrNotFound is returned when a key does not exist in storage
+var ErrNotFound = errors.New("storage: object not found")
+
+// ErrInvalidKey is returned for keys that are empty or escape the storage root
+var ErrInvalidKey = errors.New("storage: invalid key")
+
+// Object is a stored object opened for reading
+type Object struct {
+	Content io.ReadSeekCloser
+	Size    int64
+	ModTime time.Time
+}
+
+// Storage stores objects under slash-separated keys
+type Storage interface {
+	// Backend returns the name of the backend, BackendLocal or BackendS3
+	Backend() string
+	// Put stores size bytes read from body under key, replacing any existing object
+	Put(ctx context.Context, key string, body io.Reader, size int64, contentType string) error
+	// Open opens the object stored under key
+	Open(ctx context.Context, key string) (*Object, error)
+	// Delete deletes the object stored under key; deleting a missing key is not an error
+	Delete(ctx context.Context, key string) error
+	// SignedURL returns a URL the object can be downloaded from without
+	// authentication until expires
+	SignedURL(ctx context.Context, key string, expires time.Time) (string, error)
+}
+
+// New creates the storage backend selected by the configuration
+func New(cfg config.StorageConfig, signer *URLSigner) (Storage, error) {
+	switch cfg.Backend {
+	case BackendLocal:
+		return NewLocalStorage(cfg.LocalPath, signer)
+	case BackendS3:
+		return NewS3Storage(S3Options{
+			Endpoint:  cfg.S3Endpoint,
+			Region:    cfg.S3Region,
+			Bucket:    cfg.S3Bucket,
+			AccessKey: cfg.S3AccessKey,
+			SecretKey: cfg.S3SecretKey,
+			PathStyle: cfg.S3PathStyle,
+		})
+	default:
+		return nil, fmt.Errorf("unknown storage backend %q", cfg.Backend)
+	}
+}
+
+// validateKey rejects keys that are empty, absolute or contain relative segments
+func validateKey(key string) error {
+	if key == "" || strings.HasPrefix(key, "/") || strings.Contains(key, "\\") {
+		return ErrInvalidKey
+	}
+	for _, segment := range strings.Split(key, "/") {
+		if segment == "" || segment == "." || segment == ".." {
+			return ErrInvalidKey
+		}
+	}
+	return nil
+}
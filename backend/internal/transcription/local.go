@@ -0,0 +1,236 @@
+package transcription
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Voice activity detection settings of the local provider
+const (
+	localFrameLength = 20 * time.Millisecond
+	localThreshold   = 0.02                   // RMS level, relative to full scale, that counts as speech
+	localMaxPause    = 500 * time.Millisecond // shorter pauses do not end a segment
+	localMinSpeech   = 250 * time.Millisecond // shorter bursts are treated as noise
+	localSpeechText  = "[speech]"
+)
+
+// ErrUnsupportedAudio is returned for audio the local provider cannot decode
+var ErrUnsupportedAudio = errors.New("transcription: audio is not 8 or 16-bit PCM WAV")
+
+// localProvider is an offline stand-in for a speech-to-text service. It does
+// not recognise words: it finds where each channel has speech and returns a
+// placeholder segment for every stretch, so the transcription pipeline can be
+// run in development and tests without an external service.
+type localProvider struct{}
+
+// NewLocalProvider creates the offline provider
+func NewLocalProvider() Provider {
+	return &localProvider{}
+}
+
+// Name returns ProviderLocal
+func (p *localProvider) Name() string {
+	return ProviderLocal
+}
+
+// Transcribe finds the stretches of speech in PCM WAV audio. Each channel of
+// a multi-channel recording is taken to be a separate speaker.
+func (p *localProvider) Transcribe(ctx context.Context, audio io.Reader, opts Options) (*Result, error) {
+	format, data, err := readWAV(audio)
+	if err != nil {
+		return nil, err
+	}
+
+	frameSamples := int(int64(format.sampleRate) * int64(localFrameLength) / int64(time.Second))
+	if frameSamples < 1 {
+		frameSamples = 1
+	}
+	buf := make([]byte, frameSamples*format.blockAlign)
+	trackers := make([]speechTracker, format.channels)
+
+	var elapsed time.Duration
+	for {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		n, readErr := io.ReadFull(data, buf)
+		if frames := n / format.blockAlign; frames > 0 {
+			length := time.Duration(frames) * time.Second / time.Duration(format.sampleRate)
+			for channel, level := range format.levels(buf[:frames*format.blockAlign]) {
+				trackers[channel].add(elapsed, elapsed+length, level >= localThreshold)
+			}
+			elapsed += length
+		}
+		if readErr == io.EOF || readErr == io.ErrUnexpectedEOF {
+			break
+		}
+		if readErr != nil {
+			return nil, readErr
+		}
+	}
+
+	result := &Result{Language: opts.Language, Duration: elapsed, Segments: []Segment{}}
+	for channel := range trackers {
+		speaker := ""
+		if format.channels > 1 {
+			speaker = fmt.Sprintf("speaker_%d", channel+1)
+		}
+		for _, segment := range trackers[channel].finish() {
+			segment.Speaker = speaker
+			result.Segments = append(result.Segments, segment)
+		}
+	}
+	sort.SliceStable(result.Segments, func(i, j int) bool {
+		return result.Segments[i].Start < result.Segments[j].Start
+	})
+
+	texts := make([]string, len(result.Segments))
+	for i, segment := range result.Segments {
+		texts[i] = segment.Text
+	}
+	result.Text = strings.Join(texts, " ")
+
+	return result, nil
+}
+
+// speechTracker joins one channel's speech frames into segments
+type speechTracker struct {
+	segments []Segment
+	active   bool
+	start    time.Duration
+	end      time.Duration
+}
+
+// add records whether a frame had speech
+func (t *speechTracker) add(start, end time.Duration, speech bool) {
+	if t.active && start-t.end >= localMaxPause {
+		t.flush()
+	}
+	if !speech {
+		return
+	}
+	if !t.active {
+		t.active = true
+		t.start = start
+	}
+	t.end = end
+}
+
+// flush ends the current segment, keeping it if it is long enough to be speech
+func (t *speechTracker) flush() {
+	if t.active && t.end-t.start >= localMinSpeech {
+		t.segments = append(t.segments, Segment{Start: t.start, End: t.end, Text: localSpeechText})
+	}
+	t.active = false
+}
+
+// finish ends any open segment and returns the channel's segments
+func (t *speechTracker) finish() []Segment {
+	t.flush()
+	return t.segments
+}
+
+// wavFormat is the sample format of a PCM WAV file
+type wavFormat struct {
+	channels      int
+	sampleRate    int
+	bitsPerSample int
+	blockAlign    int
+}
+
+// levels returns the RMS level of each channel over whole frames of samples, from 0 to 1
+func (f wavFormat) levels(frames []byte) []float64 {
+	sums := make([]float64, f.channels)
+	bytesPerSample := f.bitsPerSample / 8
+	count := len(frames) / f.blockAlign
+
+	for i := 0; i < count; i++ {
+		frame := frames[i*f.blockAlign:]
+		for channel := 0; channel < f.channels; channel++ {
+			var sample float64
+			if bytesPerSample == 1 {
+				sample = (float64(frame[channel]) - 128) / 128
+			} else {
+				sample = float64(int16(binary.LittleEndian.Uint16(frame[channel*2:]))) / 32768
+			}
+			sums[channel] += sample * sample
+		}
+	}
+
+	for channel := range sums {
+		sums[channel] = math.Sqrt(sums[channel] / float64(count))
+	}
+	return sums
+}
+
+// readWAV reads a WAV header up to the sample data, returning the sample
+// format and a reader of the samples
+func readWAV(r io.Reader) (wavFormat, io.Reader, error) {
+	var format wavFormat
+
+	header := make([]byte, 12)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return format, nil, ErrUnsupportedAudio
+	}
+	if string(header[0:4]) != "RIFF" || string(header[8:12]) != "WAVE" {
+		return format, nil, ErrUnsupportedAudio
+	}
+
+	chunk := make([]byte, 8)
+	for {
+		if _, err := io.ReadFull(r, chunk); err != nil {
+			return format, nil, ErrUnsupportedAudio
+		}
+		id := string(chunk[0:4])
+		size := int64(binary.LittleEndian.Uint32(chunk[4:8]))
+
+		switch id {
+		case "fmt ":
+			if size < 16 {
+				return format, nil, ErrUnsupportedAudio
+			}
+			body := make([]byte, size+size%2)
+			if _, err := io.ReadFull(r, body); err != nil {
+				return format, nil, ErrUnsupportedAudio
+			}
+			audioFormat := binary.LittleEndian.Uint16(body[0:2])
+			format = wavFormat{
+				channels:      int(binary.LittleEndian.Uint16(body[2:4])),
+				sampleRate:    int(binary.LittleEndian.Uint32(body[4:8])),
+				blockAlign:    int(binary.LittleEndian.Uint16(body[12:14])),
+				bitsPerSample: int(binary.LittleEndian.Uint16(body[14:16])),
+			}
+			// 1 is PCM; 0xFFFE is WAVE_FORMAT_EXTENSIBLE, which carries PCM too
+			if audioFormat != 1 && audioFormat != 0xFFFE {
+				return format, nil, ErrUnsupportedAudio
+			}
+			if format.bitsPerSample != 8 && format.bitsPerSample != 16 {
+				return format, nil, ErrUnsupportedAudio
+			}
+			if format.channels < 1 || format.sampleRate < 1 || format.blockAlign < format.channels*format.bitsPerSample/8 {
+				return format, nil, ErrUnsupportedAudio
+			}
+		case "data":
+			if format.channels == 0 {
+				return format, nil, ErrUnsupportedAudio
+			}
+			// Writers that stream WAV leave the size at 0 or the maximum until they finish
+			if size == 0 || size == math.MaxUint32 {
+				return format, r, nil
+			}
+			return format, io.LimitReader(r, size), nil
+		default:
+			if _, err := io.CopyN(io.Discard, r, size+size%2); err != nil {
+				return format, nil, ErrUnsupportedAudio
+			}
+		}
+	}
+}
@@ -0,0 +1,71 @@
+// Package transcription turns call recordings and voicemails into text with a
+// pluggable speech-to-text provider: an OpenAI-compatible transcription API,
+// or an offline stand-in that only detects where speech is.
+package transcription
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/psschand/callcenter/internal/config"
+)
+
+// Speech-to-text providers
+const (
+	ProviderNone    = "none"
+	ProviderLocal   = "local"
+	ProviderWhisper = "whisper"
+)
+
+// Segment is a stretch of speech by one speaker
+type Segment struct {
+	Speaker string // empty when the provider cannot tell speakers apart
+	Start   time.Duration
+	End     time.Duration
+	Text    string
+}
+
+// Result is the transcript of a recording
+type Result struct {
+	Language string
+	Text     string
+	Duration time.Duration
+	Segments []Segment
+}
+
+// Options describes the audio to transcribe
+type Options struct {
+	Filename string // e.g. "call-123.wav"; some providers detect the format from its extension
+	Format   string // e.g. "wav"
+	Language string // ISO-639-1 code; empty lets the provider detect it
+}
+
+// Provider transcribes audio
+type Provider interface {
+	// Name returns the name of the provider, e.g. ProviderWhisper
+	Name() string
+	// Transcribe transcribes the audio read from audio
+	Transcribe(ctx context.Context, audio io.Reader, opts Options) (*Result, error)
+}
+
+// New creates the provider selected by the configuration; it returns nil
+// when transcription is turned off
+func New(cfg config.TranscriptionConfig) (Provider, error) {
+	switch cfg.Provider {
+	case ProviderNone, "":
+		return nil, nil
+	case ProviderLocal:
+		return NewLocalProvider(), nil
+	case ProviderWhisper:
+		return NewWhisperProvider(WhisperOptions{
+			URL:     cfg.URL,
+			APIKey:  cfg.APIKey,
+			Model:   cfg.Model,
+			Timeout: cfg.Timeout,
+		})
+	default:
+		return nil, fmt.Errorf("unknown transcription provider %q", cfg.Provider)
+	}
+}
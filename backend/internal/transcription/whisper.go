@@ -0,0 +1,177 @@
+package transcription
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// whisperMaxError is how much of an error response is read for its message
+const whisperMaxError = 4096
+
+// WhisperOptions configures an OpenAI-compatible transcription API
+type WhisperOptions struct {
+	URL     string // API base, e.g. https://api.openai.com/v1 or a self-hosted whisper server
+	APIKey  string
+	Model   string
+	Timeout time.Duration
+}
+
+// whisperProvider transcribes through the /audio/transcriptions endpoint of
+// an OpenAI-compatible API
+type whisperProvider struct {
+	opts   WhisperOptions
+	client *http.Client
+}
+
+// whisperResponse is the verbose_json transcription response. Speaker is
+// only set by servers that diarize.
+type whisperResponse struct {
+	Text     string  `json:"text"`
+	Language string  `json:"language"`
+	Duration float64 `json:"duration"`
+	Segments []struct {
+		Start   float64 `json:"start"`
+		End     float64 `json:"end"`
+		Text    string  `json:"text"`
+		Speaker string  `json:"speaker"`
+	} `json:"segments"`
+}
+
+// NewWhisperProvider creates a provider for an OpenAI-compatible transcription API
+func NewWhisperProvider(opts WhisperOptions) (Provider, error) {
+	if opts.URL == "" {
+		return nil, errors.New("transcription URL is required")
+	}
+	if opts.Model == "" {
+		opts.Model = "whisper-1"
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = 10 * time.Minute
+	}
+	opts.URL = strings.TrimSuffix(opts.URL, "/")
+
+	return &whisperProvider{
+		opts:   opts,
+		client: &http.Client{Timeout: opts.Timeout},
+	}, nil
+}
+
+// Name returns ProviderWhisper
+func (p *whisperProvider) Name() string {
+	return ProviderWhisper
+}
+
+// Transcribe uploads the audio and returns the transcript with segment timestamps
+func (p *whisperProvider) Transcribe(ctx context.Context, audio io.Reader, opts Options) (*Result, error) {
+	filename := opts.Filename
+	if filename == "" {
+		filename = "audio." + opts.Format
+	}
+
+	// Stream the upload rather than buffering a long recording in memory
+	body, writer := io.Pipe()
+	form := multipart.NewWriter(writer)
+	go func() {
+		writer.CloseWithError(p.writeForm(form, audio, filename, opts.Language))
+	}()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.opts.URL+"/audio/transcriptions", body)
+	if err != nil {
+		body.Close()
+		return nil, err
+	}
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	if p.opts.APIKey != "" {
+		req.Header.Set("Authorization", "Bearer "+p.opts.APIKey)
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		body.Close()
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, whisperError(resp)
+	}
+
+	var decoded whisperResponse
+	if err := json.NewDecoder(resp.Body).Decode(&decoded); err != nil {
+		return nil, fmt.Errorf("transcription: invalid response: %w", err)
+	}
+
+	result := &Result{
+		Language: decoded.Language,
+		Text:     strings.TrimSpace(decoded.Text),
+		Duration: seconds(decoded.Duration),
+		Segments: make([]Segment, 0, len(decoded.Segments)),
+	}
+	if result.Language == "" {
+		result.Language = opts.Language
+	}
+	for _, segment := range decoded.Segments {
+		result.Segments = append(result.Segments, Segment{
+			Speaker: segment.Speaker,
+			Start:   seconds(segment.Start),
+			End:     seconds(segment.End),
+			Text:    strings.TrimSpace(segment.Text),
+		})
+	}
+
+	return result, nil
+}
+
+// writeForm writes the multipart request body
+func (p *whisperProvider) writeForm(form *multipart.Writer, audio io.Reader, filename, language string) error {
+	fields := [][2]string{
+		{"model", p.opts.Model},
+		{"response_format", "verbose_json"},
+		{"timestamp_granularities[]", "segment"},
+	}
+	if language != "" {
+		fields = append(fields, [2]string{"language", language})
+	}
+	for _, field := range fields {
+		if err := form.WriteField(field[0], field[1]); err != nil {
+			return err
+		}
+	}
+
+	part, err := form.CreateFormFile("file", filename)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(part, audio); err != nil {
+		return err
+	}
+	return form.Close()
+}
+
+// whisperError builds an error from an error response
+func whisperError(resp *http.Response) error {
+	raw, _ := io.ReadAll(io.LimitReader(resp.Body, whisperMaxError))
+
+	var decoded struct {
+		Error struct {
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	message := strings.TrimSpace(string(raw))
+	if json.Unmarshal(raw, &decoded) == nil && decoded.Error.Message != "" {
+		message = decoded.Error.Message
+	}
+	return fmt.Errorf("transcription: %s: %s", resp.Status, message)
+}
+
+// seconds converts seconds to a duration
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
@@ -72,6 +72,18 @@ func (eb *EventBroadcaster) CallIncoming(tenantID, uniqueID, callerID, destinati
 	})
 }
 
+// CallOutgoing broadcasts outbound call event
+func (eb *EventBroadcaster) CallOutgoing(tenantID, uniqueID, callerID, destination string, agentID int64, agentName string) error {
+	return eb.hub.BroadcastCallEvent(tenantID, MessageTypeCallOutgoing, &CallEventPayload{
+		UniqueID:    uniqueID,
+		CallerID:    callerID,
+		Destination: destination,
+		AgentID:     agentID,
+		AgentName:   agentName,
+		Direction:   "outbound",
+	})
+}
+
 // CallAnswered broadcasts call answered event
 func (eb *EventBroadcaster) CallAnswered(tenantID, uniqueID string, agentID int64, agentName string) error {
 	return eb.hub.BroadcastCallEvent(tenantID, MessageTypeCallAnswered, &CallEventPayload{
@@ -246,6 +258,58 @@ func (eb *EventBroadcaster) ChatTransferred(tenantID string, sessionID, toAgentI
 	})
 }
 
+// Voicemail Events
+
+// VoicemailNew notifies a mailbox's owner of a new voicemail, or the whole
+// tenant when the mailbox has no owner (userID 0)
+func (eb *EventBroadcaster) VoicemailNew(tenantID string, userID int64, payload *VoicemailPayload) error {
+	payload.Timestamp = time.Now().Format(time.RFC3339)
+	msg, err := NewMessage(MessageTypeVoicemailNew, payload)
+	if err != nil {
+		return err
+	}
+	msg.TenantID = tenantID
+	if userID == 0 {
+		eb.hub.BroadcastToTenant(tenantID, msg)
+		return nil
+	}
+	msg.UserID = userID
+	eb.hub.BroadcastToUser(tenantID, userID, msg)
+	return nil
+}
+
+// SMS Events
+
+// SMSReceived notifies a tenant of an SMS message received by one of its numbers
+func (eb *EventBroadcaster) SMSReceived(tenantID string, payload *SMSPayload) error {
+	payload.Timestamp = time.Now().Format(time.RFC3339)
+	msg, err := NewMessage(MessageTypeSMSReceived, payload)
+	if err != nil {
+		return err
+	}
+	msg.TenantID = tenantID
+	eb.hub.BroadcastToTenant(tenantID, msg)
+	return nil
+}
+
+// SMSStatus notifies a message's sender of its delivery status, or the whole
+// tenant when it was not sent by a user (userID 0)
+func (eb *EventBroadcaster) SMSStatus(tenantID string, userID int64, payload *SMSPayload) error {
+	payload.Timestamp = time.Now().Format(time.RFC3339)
+	msg, err := NewMessage(MessageTypeSMSStatus, payload)
+	if err != nil {
+		return err
+	}
+	msg.TenantID = tenantID
+	if userID == 0 {
+		eb.hub.BroadcastToTenant(tenantID, msg)
+		return nil
+	}
+	msg.UserID = userID
+	eb.hub.BroadcastToUser(tenantID, userID, msg)
+	return nil
+}
+
 // Notification Events
 
 // SendNotification sends a notification to a specific user
@@ -16,6 +16,7 @@ const (
 
 	// Call Events
 	MessageTypeCallIncoming    MessageType = "call.incoming"
+	MessageTypeCallOutgoing    MessageType = "call.outgoing"
 	MessageTypeCallAnswered    MessageType = "call.answered"
 	MessageTypeCallEnded       MessageType = "call.ended"
 	MessageTypeCallTransferred MessageType = "call.transferred"
@@ -35,12 +36,20 @@ const (
 	MessageTypeChatSessionStarted  MessageType = "chat.session.started"
 	MessageTypeChatMessage         MessageType = "chat.message"
 	MessageTypeChatMessageNew      MessageType = "chat.message.new"
+	MessageTypeChatMessageStatus   MessageType = "chat.message.status"
 	MessageTypeChatSessionEnded    MessageType = "chat.session.ended"
 	MessageTypeChatSessionAssigned MessageType = "chat.session.assigned"
 	MessageTypeChatTransferred     MessageType = "chat.transferred"
 	MessageTypeChatTyping          MessageType = "chat.typing"
 	MessageTypeChatAgentJoined     MessageType = "chat.agent.joined"
 
+	// Voicemail Events
+	MessageTypeVoicemailNew MessageType = "voicemail.new"
+
+	// SMS Events
+	MessageTypeSMSReceived MessageType = "sms.received"
+	MessageTypeSMSStatus   MessageType = "sms.status"
+
 	// Notification Events
 	MessageTypeNotification MessageType = "notification"
 	MessageTypeAlert        MessageType = "alert"
@@ -138,6 +147,33 @@ type ChatTypingPayload struct {
 	IsTyping   bool   `json:"is_typing"`
 }
 
+// VoicemailPayload represents a new voicemail
+type VoicemailPayload struct {
+	VoicemailID int64  `json:"voicemail_id"`
+	MailboxID   int64  `json:"mailbox_id"`
+	MailboxName string `json:"mailbox_name"`
+	CallerID    string `json:"callerid"`
+	CallerName  string `json:"callername,omitempty"`
+	Duration    int    `json:"duration"`
+	UnreadCount int64  `json:"unread_count"`
+	Timestamp   string `json:"timestamp"`
+}
+
+// SMSPayload represents an SMS message received or the delivery status of one sent
+type SMSPayload struct {
+	MessageID    int64   `json:"message_id"`
+	DIDID        int64   `json:"did_id,omitempty"`
+	Direction    string  `json:"direction"`
+	Sender       string  `json:"sender"`
+	Recipient    string  `json:"recipient"`
+	Body         string  `json:"body,omitempty"`
+	Status       string  `json:"status"`
+	Segments     int     `json:"segments"`
+	Cost         float64 `json:"cost"`
+	ErrorMessage string  `json:"error_message,omitempty"`
+	Timestamp    string  `json:"timestamp"`
+}
+
 // NotificationPayload represents notification data
 type NotificationPayload struct {
 	ID      int64                  `json:"id"`
@@ -0,0 +1,6 @@
+-- Migration: Add fallback destination to queues
+-- Description: Where callers go when they exceed max_wait_time (queue, endpoint, ivr, voicemail, external)
+
+ALTER TABLE queues
+ADD COLUMN fallback_route_type VARCHAR(20) NULL AFTER music_on_hold,
+ADD COLUMN fallback_route_target VARCHAR(255) NULL AFTER fallback_route_type;
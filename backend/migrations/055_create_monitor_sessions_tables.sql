@@ -0,0 +1,41 @@
+-- Migration: Create monitor_sessions and monitor_session_events tables
+-- Description: Compliance log of supervisors listening to, whispering on or barging into live calls
+
+CREATE TABLE IF NOT EXISTS monitor_sessions (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    tenant_id VARCHAR(64) NOT NULL,
+    supervisor_id BIGINT NOT NULL,
+    supervisor_endpoint VARCHAR(128) NOT NULL,
+    target_channel_id VARCHAR(150) NOT NULL,
+    target_channel VARCHAR(128) NULL,
+    caller_number VARCHAR(80) NULL,
+    mode ENUM('listen', 'whisper', 'barge') NOT NULL DEFAULT 'listen',
+    status ENUM('ringing', 'active', 'ended') NOT NULL DEFAULT 'ringing',
+    end_reason VARCHAR(64) NULL,
+    started_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    answered_at TIMESTAMP NULL,
+    ended_at TIMESTAMP NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+
+    INDEX idx_tenant_started (tenant_id, started_at),
+    INDEX idx_supervisor (supervisor_id),
+    INDEX idx_target_channel (target_channel_id),
+    INDEX idx_status (status),
+
+    FOREIGN KEY (tenant_id) REFERENCES tenants(id) ON DELETE CASCADE,
+    FOREIGN KEY (supervisor_id) REFERENCES users(id) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+CREATE TABLE IF NOT EXISTS monitor_session_events (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    session_id BIGINT NOT NULL,
+    event VARCHAR(32) NOT NULL,
+    mode ENUM('listen', 'whisper', 'barge') NULL,
+    user_id BIGINT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    INDEX idx_session (session_id),
+
+    FOREIGN KEY (session_id) REFERENCES monitor_sessions(id) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
@@ -0,0 +1,5 @@
+-- Migration: Add recording policy to queues
+-- Description: Per-queue override of the tenant's call recording setting (inherit, always, never)
+
+ALTER TABLE queues
+ADD COLUMN recording_policy ENUM('inherit', 'always', 'never') NOT NULL DEFAULT 'inherit' AFTER fallback_route_target;
@@ -0,0 +1,10 @@
+-- Migration: Add storage location to recordings and voicemails
+-- Description: Backend and key of recordings and voicemails archived from the Asterisk spool to local or S3-compatible storage
+
+ALTER TABLE call_recordings
+ADD COLUMN storage_backend VARCHAR(16) NULL AFTER file_path,
+ADD COLUMN storage_key VARCHAR(1024) NULL AFTER storage_backend;
+
+ALTER TABLE voicemails
+ADD COLUMN storage_backend VARCHAR(16) NULL AFTER file_path,
+ADD COLUMN storage_key VARCHAR(1024) NULL AFTER storage_backend;
@@ -0,0 +1,7 @@
+-- Migration: Add linked call ID to CDRs
+-- Description: Links the CDR segments of a transferred call, one per party that handled it, in order
+
+ALTER TABLE cdr
+ADD COLUMN linkedid VARCHAR(150) NOT NULL DEFAULT '' AFTER uniqueid,
+ADD COLUMN sequence INT NOT NULL DEFAULT 0 AFTER linkedid,
+ADD INDEX idx_linkedid (linkedid);
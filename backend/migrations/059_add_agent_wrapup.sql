@@ -0,0 +1,17 @@
+-- Migration: Add agent wrap-up
+-- Description: Wrap-up (after-call work) agent state with its countdown, queue wrap-up times, and the time spent in wrap-up after each call and chat
+
+ALTER TABLE agent_states
+MODIFY COLUMN state ENUM('available','busy','wrapup','away','break','offline','dnd') DEFAULT 'offline',
+ADD COLUMN wrapup_started_at TIMESTAMP NULL,
+ADD COLUMN wrapup_ends_at TIMESTAMP NULL,
+ADD COLUMN wrapup_chat_id BIGINT NULL;
+
+ALTER TABLE queues
+ADD COLUMN wrapup_time INT NOT NULL DEFAULT 0 AFTER retry;
+
+ALTER TABLE cdr
+ADD COLUMN wrapup_time INT NOT NULL DEFAULT 0 AFTER queue_wait_time;
+
+ALTER TABLE chat_sessions
+ADD COLUMN wrapup_time INT NULL;
@@ -0,0 +1,40 @@
+-- Migration: Create agent state history and break reasons
+-- Description: Every agent state change with its reason code, for time-in-state reporting, and the break reason codes tenants configure with their maximum durations
+
+CREATE TABLE IF NOT EXISTS agent_state_history (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    tenant_id VARCHAR(64) NOT NULL,
+    user_id BIGINT NOT NULL,
+    state ENUM('available','busy','wrapup','away','break','offline','dnd') NOT NULL,
+    reason_code VARCHAR(64) NULL,
+    reason VARCHAR(255) NULL,
+    call_id VARCHAR(150) NULL,
+    started_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    ended_at TIMESTAMP NULL,
+    duration INT NULL,
+
+    INDEX idx_tenant_started (tenant_id, started_at),
+    INDEX idx_user_started (user_id, started_at),
+    INDEX idx_user_open (tenant_id, user_id, ended_at),
+
+    FOREIGN KEY (tenant_id) REFERENCES tenants(id) ON DELETE CASCADE,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+CREATE TABLE IF NOT EXISTS agent_break_reasons (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    tenant_id VARCHAR(64) NOT NULL,
+    code VARCHAR(64) NOT NULL,
+    name VARCHAR(100) NOT NULL,
+    max_duration INT NOT NULL DEFAULT 0,
+    is_active BOOLEAN DEFAULT TRUE,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+
+    UNIQUE KEY unique_tenant_code (tenant_id, code),
+
+    FOREIGN KEY (tenant_id) REFERENCES tenants(id) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+ALTER TABLE agent_states
+ADD COLUMN reason_code VARCHAR(64) NULL AFTER state;
@@ -0,0 +1,27 @@
+-- Migration: Create voicemail mailboxes
+-- Description: Per-user and per-DID mailboxes with their greeting and maximum message length, and the mailbox each voicemail was left in
+
+CREATE TABLE IF NOT EXISTS voicemail_mailboxes (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    tenant_id VARCHAR(64) NOT NULL,
+    name VARCHAR(100) NOT NULL,
+    user_id BIGINT NULL,
+    did_id BIGINT NULL,
+    greeting VARCHAR(512) NULL,
+    max_length INT NOT NULL DEFAULT 180,
+    is_active BOOLEAN DEFAULT TRUE,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+
+    UNIQUE KEY unique_tenant_user (tenant_id, user_id),
+    UNIQUE KEY unique_did (did_id),
+    INDEX idx_tenant (tenant_id),
+
+    FOREIGN KEY (tenant_id) REFERENCES tenants(id) ON DELETE CASCADE,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (did_id) REFERENCES dids(id) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+ALTER TABLE voicemails
+ADD COLUMN mailbox_id BIGINT NULL AFTER tenant_id,
+ADD INDEX idx_mailbox (mailbox_id);
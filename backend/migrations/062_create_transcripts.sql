@@ -0,0 +1,60 @@
+-- Migration: Create transcription jobs and transcripts
+-- Description: Speech-to-text job queue for call recordings and voicemails, and the resulting transcripts with timed per-speaker segments, indexed for full-text search
+
+CREATE TABLE IF NOT EXISTS transcription_jobs (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    tenant_id VARCHAR(64) NOT NULL,
+    source_type ENUM('recording','voicemail') NOT NULL,
+    source_id BIGINT NOT NULL,
+    status ENUM('pending','processing','completed','failed') DEFAULT 'pending',
+    attempts INT NOT NULL DEFAULT 0,
+    error TEXT NULL,
+    next_attempt_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    started_at TIMESTAMP NULL,
+    completed_at TIMESTAMP NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+
+    UNIQUE KEY unique_source (source_type, source_id),
+    INDEX idx_tenant (tenant_id),
+    INDEX idx_status_next (status, next_attempt_at),
+
+    FOREIGN KEY (tenant_id) REFERENCES tenants(id) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+CREATE TABLE IF NOT EXISTS transcripts (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    tenant_id VARCHAR(64) NOT NULL,
+    source_type ENUM('recording','voicemail') NOT NULL,
+    source_id BIGINT NOT NULL,
+    user_id BIGINT NULL,
+    provider VARCHAR(32) NOT NULL,
+    language VARCHAR(32) NULL,
+    text MEDIUMTEXT NOT NULL,
+    duration INT DEFAULT 0,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    UNIQUE KEY unique_source (source_type, source_id),
+    INDEX idx_tenant (tenant_id),
+    INDEX idx_user (user_id),
+    INDEX idx_created (created_at),
+    FULLTEXT INDEX ft_text (text),
+
+    FOREIGN KEY (tenant_id) REFERENCES tenants(id) ON DELETE CASCADE,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE SET NULL
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+CREATE TABLE IF NOT EXISTS transcript_segments (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    transcript_id BIGINT NOT NULL,
+    sequence INT NOT NULL,
+    speaker VARCHAR(64) NULL,
+    start_ms BIGINT NOT NULL,
+    end_ms BIGINT NOT NULL,
+    text TEXT NOT NULL,
+
+    INDEX idx_transcript_sequence (transcript_id, sequence),
+    FULLTEXT INDEX ft_text (text),
+
+    FOREIGN KEY (transcript_id) REFERENCES transcripts(id) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
@@ -0,0 +1,19 @@
+-- Migration: Align sms_messages with the SMS message model
+-- Description: Provider-neutral column names, the DID a message was sent from or to, the sending provider and delivery status enums used by the SMS send and receive path
+
+ALTER TABLE sms_messages
+CHANGE COLUMN message_sid provider_message_id VARCHAR(255) NULL,
+CHANGE COLUMN from_number sender VARCHAR(64) NOT NULL,
+CHANGE COLUMN to_number recipient VARCHAR(64) NOT NULL,
+CHANGE COLUMN num_segments segments INT NOT NULL DEFAULT 1,
+CHANGE COLUMN price cost DECIMAL(10, 4) DEFAULT 0,
+MODIFY COLUMN body TEXT NULL,
+MODIFY COLUMN direction ENUM('inbound','outbound') NOT NULL,
+MODIFY COLUMN status ENUM('pending','queued','sent','delivered','failed','received') DEFAULT 'pending',
+ADD COLUMN did_id BIGINT NULL AFTER tenant_id,
+ADD COLUMN provider VARCHAR(64) DEFAULT 'internal' AFTER user_id,
+ADD COLUMN metadata JSON NULL AFTER provider,
+ADD COLUMN updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP AFTER created_at,
+ADD INDEX idx_did (did_id),
+ADD INDEX idx_direction_status (direction, status),
+ADD FOREIGN KEY (did_id) REFERENCES dids(id) ON DELETE SET NULL;
@@ -0,0 +1,17 @@
+-- Migration: Add SMS conversations to chat sessions
+-- Description: Chat sessions held by SMS on a DID, threaded by the customer's number, and the SMS message and delivery status of each chat message sent or received by SMS
+
+ALTER TABLE chat_sessions
+MODIFY COLUMN widget_id BIGINT NULL,
+ADD COLUMN channel ENUM('web','sms') NOT NULL DEFAULT 'web' AFTER widget_id,
+ADD COLUMN did_id BIGINT NULL AFTER channel,
+ADD COLUMN visitor_phone VARCHAR(32) NULL AFTER visitor_email,
+ADD INDEX idx_channel_status (tenant_id, channel, status),
+ADD INDEX idx_did_visitor_phone (did_id, visitor_phone),
+ADD FOREIGN KEY (did_id) REFERENCES dids(id) ON DELETE SET NULL;
+
+ALTER TABLE chat_messages
+ADD COLUMN sms_message_id BIGINT NULL AFTER read_at,
+ADD COLUMN delivery_status ENUM('pending','queued','sent','delivered','failed','received') NULL AFTER sms_message_id,
+ADD INDEX idx_sms_message (sms_message_id),
+ADD FOREIGN KEY (sms_message_id) REFERENCES sms_messages(id) ON DELETE SET NULL;
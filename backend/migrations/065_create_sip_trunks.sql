@@ -0,0 +1,74 @@
+-- Migration: Create SIP trunks and outbound routes
+-- Description: Tenant and platform SIP trunks provisioned as ARA endpoints, outbound routes picking a trunk (with a failover trunk) by dialed prefix, the ARA outbound registration table, and identify rules wide enough for trunk endpoint IDs and address lists
+
+CREATE TABLE IF NOT EXISTS sip_trunks (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    tenant_id VARCHAR(64) NULL,
+    name VARCHAR(128) NOT NULL,
+    endpoint_id VARCHAR(128) NOT NULL,
+    host VARCHAR(255) NOT NULL,
+    port INT NOT NULL DEFAULT 5060,
+    transport VARCHAR(40) NOT NULL DEFAULT 'transport-udp',
+    username VARCHAR(128) NULL,
+    register BOOLEAN NOT NULL DEFAULT FALSE,
+    from_user VARCHAR(128) NULL,
+    from_domain VARCHAR(128) NULL,
+    codecs VARCHAR(256) NOT NULL DEFAULT 'ulaw,alaw',
+    context VARCHAR(128) NOT NULL DEFAULT 'from-trunk',
+    identify_match VARCHAR(255) NULL,
+    max_channels INT NOT NULL DEFAULT 0,
+    status ENUM('active','disabled') NOT NULL DEFAULT 'active',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    UNIQUE KEY uk_endpoint_id (endpoint_id),
+    INDEX idx_tenant (tenant_id),
+    INDEX idx_status (status),
+    FOREIGN KEY (tenant_id) REFERENCES tenants(id) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+CREATE TABLE IF NOT EXISTS outbound_routes (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    tenant_id VARCHAR(64) NULL,
+    name VARCHAR(128) NOT NULL,
+    prefix VARCHAR(32) NOT NULL DEFAULT '',
+    priority INT NOT NULL DEFAULT 0,
+    trunk_id BIGINT NOT NULL,
+    failover_trunk_id BIGINT NULL,
+    strip_digits INT NOT NULL DEFAULT 0,
+    add_prefix VARCHAR(32) NOT NULL DEFAULT '',
+    is_active BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    INDEX idx_tenant_prefix (tenant_id, prefix),
+    INDEX idx_trunk (trunk_id),
+    INDEX idx_failover_trunk (failover_trunk_id),
+    INDEX idx_is_active (is_active),
+    FOREIGN KEY (tenant_id) REFERENCES tenants(id) ON DELETE CASCADE,
+    FOREIGN KEY (trunk_id) REFERENCES sip_trunks(id),
+    FOREIGN KEY (failover_trunk_id) REFERENCES sip_trunks(id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+CREATE TABLE IF NOT EXISTS ps_registrations (
+    id VARCHAR(128) NOT NULL,
+    tenant_id VARCHAR(64) NOT NULL DEFAULT '',
+    server_uri VARCHAR(255) NULL,
+    client_uri VARCHAR(255) NULL,
+    contact_user VARCHAR(40) NULL,
+    endpoint VARCHAR(128) NULL,
+    outbound_auth VARCHAR(128) NULL,
+    transport VARCHAR(40) NULL,
+    line VARCHAR(10) NULL,
+    expiration INT NULL,
+    retry_interval INT NULL,
+    forbidden_retry_interval INT NULL,
+    max_retries INT NULL,
+    auth_rejection_permanent VARCHAR(10) NULL,
+    PRIMARY KEY (id),
+    INDEX idx_tenant_registration (tenant_id, id),
+    INDEX idx_endpoint (endpoint)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+ALTER TABLE ps_endpoint_id_ips
+MODIFY COLUMN id VARCHAR(128) NOT NULL,
+MODIFY COLUMN endpoint VARCHAR(128) NOT NULL,
+MODIFY COLUMN `match` VARCHAR(255) NOT NULL;
@@ -0,0 +1,27 @@
+-- Migration: Extend blacklist with patterns and treatments
+-- Description: Per-entry call/SMS blocking and call treatment for blacklist numbers and patterns, and a log of blocked calls and SMS
+
+ALTER TABLE blacklist
+ADD COLUMN block_calls BOOLEAN NOT NULL DEFAULT TRUE AFTER reason,
+ADD COLUMN block_sms BOOLEAN NOT NULL DEFAULT TRUE AFTER block_calls,
+ADD COLUMN treatment ENUM('reject','busy','message','voicemail') NOT NULL DEFAULT 'reject' AFTER block_sms,
+ADD COLUMN treatment_target VARCHAR(255) NULL AFTER treatment,
+ADD COLUMN updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP AFTER created_at;
+
+CREATE TABLE IF NOT EXISTS blacklist_attempts (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    tenant_id VARCHAR(64) NOT NULL,
+    blacklist_id BIGINT NULL,
+    pattern VARCHAR(50) NOT NULL,
+    source ENUM('call','sms') NOT NULL,
+    caller_number VARCHAR(50) NOT NULL,
+    did_id BIGINT NULL,
+    did_number VARCHAR(32) NOT NULL,
+    treatment VARCHAR(20) NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    INDEX idx_tenant_created (tenant_id, created_at),
+    INDEX idx_blacklist (blacklist_id),
+    FOREIGN KEY (tenant_id) REFERENCES tenants(id) ON DELETE CASCADE,
+    FOREIGN KEY (blacklist_id) REFERENCES blacklist(id) ON DELETE SET NULL,
+    FOREIGN KEY (did_id) REFERENCES dids(id) ON DELETE SET NULL
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
@@ -0,0 +1,18 @@
+-- Migration: Add schedule-based routing
+-- Description: Business hours schedules for DIDs, queues and chat widgets, with an after-hours route for DIDs
+
+ALTER TABLE dids
+ADD COLUMN schedule_id BIGINT NULL AFTER route_target,
+ADD COLUMN after_hours_route_type VARCHAR(20) NULL AFTER schedule_id,
+ADD COLUMN after_hours_route_target VARCHAR(255) NULL AFTER after_hours_route_type,
+ADD INDEX idx_schedule (schedule_id),
+ADD FOREIGN KEY (schedule_id) REFERENCES schedules(id) ON DELETE SET NULL;
+
+ALTER TABLE queues
+ADD COLUMN schedule_id BIGINT NULL AFTER fallback_route_target,
+ADD INDEX idx_schedule (schedule_id),
+ADD FOREIGN KEY (schedule_id) REFERENCES schedules(id) ON DELETE SET NULL;
+
+ALTER TABLE chat_widgets
+ADD COLUMN schedule_id BIGINT NULL AFTER offline_message,
+ADD FOREIGN KEY (schedule_id) REFERENCES schedules(id) ON DELETE SET NULL;
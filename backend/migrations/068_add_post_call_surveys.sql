@@ -0,0 +1,37 @@
+-- Migration: Add post-call surveys
+-- Description: Survey prompts, surveys on DIDs and queues, and responses linked to the call, agent and queue with their scores
+
+ALTER TABLE call_surveys
+ADD COLUMN opt_in_prompt VARCHAR(255) NOT NULL DEFAULT '' AFTER trigger_on,
+ADD COLUMN intro_prompt VARCHAR(255) NULL AFTER opt_in_prompt,
+ADD COLUMN thank_you_prompt VARCHAR(255) NULL AFTER intro_prompt;
+
+ALTER TABLE survey_responses
+ADD COLUMN tenant_id VARCHAR(36) NOT NULL AFTER id,
+ADD COLUMN uniqueid VARCHAR(150) NOT NULL DEFAULT '' AFTER cdr_id,
+ADD COLUMN linkedid VARCHAR(150) NOT NULL DEFAULT '' AFTER uniqueid,
+ADD COLUMN caller_number VARCHAR(80) NOT NULL DEFAULT '' AFTER linkedid,
+ADD COLUMN did_id BIGINT NULL AFTER caller_number,
+ADD COLUMN queue_name VARCHAR(128) NULL AFTER did_id,
+ADD COLUMN agent_endpoint VARCHAR(128) NULL AFTER queue_name,
+ADD COLUMN user_id BIGINT NULL AFTER agent_endpoint,
+ADD COLUMN csat_score INT NULL AFTER overall_rating,
+ADD COLUMN csat_satisfied BOOLEAN NULL AFTER csat_score,
+ADD COLUMN nps_score INT NULL AFTER csat_satisfied,
+ADD COLUMN completed BOOLEAN NOT NULL DEFAULT TRUE AFTER nps_score,
+ADD INDEX idx_tenant_completed (tenant_id, completed_at),
+ADD INDEX idx_uniqueid (uniqueid),
+ADD INDEX idx_queue_name (queue_name),
+ADD INDEX idx_user_id (user_id),
+ADD FOREIGN KEY (tenant_id) REFERENCES tenants(id) ON DELETE CASCADE,
+ADD FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE SET NULL;
+
+ALTER TABLE dids
+ADD COLUMN survey_id BIGINT NULL AFTER after_hours_route_target,
+ADD INDEX idx_survey (survey_id),
+ADD FOREIGN KEY (survey_id) REFERENCES call_surveys(id) ON DELETE SET NULL;
+
+ALTER TABLE queues
+ADD COLUMN survey_id BIGINT NULL AFTER schedule_id,
+ADD INDEX idx_survey (survey_id),
+ADD FOREIGN KEY (survey_id) REFERENCES call_surveys(id) ON DELETE SET NULL;
@@ -0,0 +1,42 @@
+-- Migration: Create queue_callbacks table
+-- Description: Callback (virtual hold) settings on queues and the callbacks callers request instead of waiting on hold
+
+ALTER TABLE queues
+ADD COLUMN callback_enabled BOOLEAN NOT NULL DEFAULT FALSE AFTER survey_id,
+ADD COLUMN callback_offer_after INT NOT NULL DEFAULT 60 AFTER callback_enabled,
+ADD COLUMN callback_prompt VARCHAR(255) NULL AFTER callback_offer_after,
+ADD COLUMN callback_max_attempts INT NOT NULL DEFAULT 3 AFTER callback_prompt,
+ADD COLUMN callback_retry INT NOT NULL DEFAULT 300 AFTER callback_max_attempts;
+
+CREATE TABLE IF NOT EXISTS queue_callbacks (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    tenant_id VARCHAR(64) NOT NULL,
+    queue_name VARCHAR(128) NOT NULL,
+    uniqueid VARCHAR(150) NOT NULL,
+    linkedid VARCHAR(150) NOT NULL,
+    did_id BIGINT NULL,
+    caller_number VARCHAR(80) NOT NULL DEFAULT '',
+    caller_name VARCHAR(80) NOT NULL DEFAULT '',
+    callback_number VARCHAR(80) NOT NULL,
+    status ENUM('pending', 'dialing', 'connected', 'completed', 'failed', 'cancelled') NOT NULL DEFAULT 'pending',
+    attempts INT NOT NULL DEFAULT 0,
+    max_attempts INT NOT NULL,
+    wait_time INT NOT NULL DEFAULT 0,
+    agent_endpoint VARCHAR(128) NULL,
+    last_error VARCHAR(255) NULL,
+    requested_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    last_attempt_at TIMESTAMP NULL,
+    next_attempt_at TIMESTAMP NULL,
+    connected_at TIMESTAMP NULL,
+    ended_at TIMESTAMP NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+
+    INDEX idx_tenant_requested (tenant_id, requested_at),
+    INDEX idx_queue_name (queue_name),
+    INDEX idx_uniqueid (uniqueid),
+    INDEX idx_status (status),
+
+    FOREIGN KEY (tenant_id) REFERENCES tenants(id) ON DELETE CASCADE,
+    FOREIGN KEY (did_id) REFERENCES dids(id) ON DELETE SET NULL
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
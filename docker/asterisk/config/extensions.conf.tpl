@@ -8,18 +8,13 @@ exten => s,1,NoOp(Default context)
  same => n,Hangup()
 
 [from-twilio]
-; Route the Twilio DID to extension 100 (Zoiper)
-exten => +19863334949,1,NoOp(Inbound Twilio call for ${EXTEN})
- same => n,Goto(internal,100,1)
+; Calls to DIDs go to the tenant contexts generated from the DIDs table
+; (/api/v1/dialplan); unknown numbers are rejected by the application
+include => callcenter-dids
 
-; Fallback: match 10-digit version of the DID
-exten => 9863334949,1,NoOp(Inbound Twilio 10-digit match ${EXTEN})
- same => n,Goto(internal,100,1)
-
-; Catch-all to avoid call loops
-exten => _X.,1,NoOp(Unhandled Twilio DID ${EXTEN})
- same => n,Playback(silence/1&invalid)
- same => n,Hangup()
+[from-trunk]
+; Calls from SIP trunks managed through the API (sip_trunks)
+include => callcenter-dids
 
 [outbound]
 ; Dial out via Twilio SIP trunk
@@ -44,3 +39,6 @@ exten => 101,1,NoOp(Ringing extension 101)
  same => n,Hangup()
 
 include => outbound
+
+; Per-tenant DID contexts and callcenter-dids, written by the API
+#include generated/extensions_callcenter.conf